@@ -14,6 +14,7 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -21,7 +22,9 @@ import (
 	"time"
 
 	"github.com/basket/go-claw/internal/agent"
+	"github.com/basket/go-claw/internal/approval"
 	"github.com/basket/go-claw/internal/audit"
+	"github.com/basket/go-claw/internal/budget"
 	"github.com/basket/go-claw/internal/bus"
 	"github.com/basket/go-claw/internal/channels"
 	"github.com/basket/go-claw/internal/config"
@@ -30,9 +33,12 @@ import (
 	"github.com/basket/go-claw/internal/engine"
 	"github.com/basket/go-claw/internal/gateway"
 	"github.com/basket/go-claw/internal/mcp"
+	"github.com/basket/go-claw/internal/memory"
 	otelPkg "github.com/basket/go-claw/internal/otel"
 	"github.com/basket/go-claw/internal/persistence"
 	"github.com/basket/go-claw/internal/policy"
+	"github.com/basket/go-claw/internal/pricing"
+	"github.com/basket/go-claw/internal/sandbox/native"
 	"github.com/basket/go-claw/internal/sandbox/wasm"
 	"github.com/basket/go-claw/internal/skills"
 	"github.com/basket/go-claw/internal/telemetry"
@@ -88,6 +94,9 @@ EXAMPLES:
 }
 
 func main() {
+	// Sandboxed commands re-execute this binary; native.Init takes over
+	// before anything else runs.
+	native.Init()
 	loadDotEnv(".env")
 
 	interactive := isatty.IsTerminal(os.Stdout.Fd()) && os.Getenv("GOCLAW_NO_TUI") == ""
@@ -212,6 +221,8 @@ func main() {
 		}
 	}
 
+	pricing.SetOverrides(pricingOverrides(cfg.Pricing))
+
 	// Create event bus early so it can be passed to the store.
 	eventBus := bus.New()
 
@@ -320,6 +331,37 @@ The system runs this checklist periodically to ensure health.
 	// Create AgentRegistry (replaces single brain+engine).
 	registry := agent.NewRegistry(store, eventBus, pol, wasmHost, cfg.APIKeys)
 
+	// Tool calls listed under require_approval park on the broker until a
+	// human answers from the TUI, Telegram or approval.respond.
+	approvals := approval.NewBroker(store, eventBus)
+	approvals.Start(ctx)
+	registry.SetApprovalBroker(approvals)
+
+	// Spend budgets are checked on task intake, before a task runs and
+	// before each model call.
+	budgets := budget.New(store, eventBus)
+	budgets.Configure(cfg)
+	registry.SetBudget(budgets)
+
+	// Each turn injects the memories relevant to the prompt, ranked by the
+	// full-text index and, with an embeddings backend, by similarity too.
+	registry.SetMemory(memory.NewRetriever(store, memoryEmbedder(cfg, logger)), cfg.Memory.RetrievalTokens)
+
+	// Agents write plans with create_plan; approved ones start through the
+	// gateway, which is created further down.
+	var gwRef atomic.Pointer[gateway.Server] // atomic so hot-reload goroutine can safely call UpdatePlans
+	registry.SetPlanner(&tools.Planner{
+		Gate:            cfg.PlanGeneration.Gate,
+		ApprovalTimeout: cfg.ApprovalTimeout(),
+		Agents:          registry.PlanAgents,
+		Runner:          gatewayPlanRunner{gw: &gwRef},
+	})
+	switch cfg.PlanGeneration.Gate {
+	case config.PlanGateAuto, config.PlanGateApprove, config.PlanGateReview:
+	default:
+		logger.Warn("unknown plan_generation.gate, asking for approval", "gate", cfg.PlanGeneration.Gate)
+	}
+
 	// Create default agent from global config (backward compat).
 	defaultCfg := agent.AgentConfig{
 		AgentID:              "default",
@@ -332,6 +374,7 @@ The system runs this checklist periodically to ensure health.
 		WorkerCount:          cfg.WorkerCount,
 		TaskTimeoutSeconds:   cfg.TaskTimeoutSeconds,
 		MaxQueueDepth:        cfg.MaxQueueDepth,
+		MaxToolTurns:         cfg.MaxToolTurns,
 		PreferredSearch:      cfg.PreferredSearch,
 		OpenAICompatProvider: cfg.LLM.OpenAICompatibleProvider,
 		OpenAICompatBaseURL:  cfg.LLM.OpenAICompatibleBaseURL,
@@ -390,10 +433,13 @@ The system runs this checklist periodically to ensure health.
 			WorkerCount:          acfg.WorkerCount,
 			TaskTimeoutSeconds:   acfg.TaskTimeoutSeconds,
 			MaxQueueDepth:        acfg.MaxQueueDepth,
+			MaxToolTurns:         agentMaxToolTurns(cfg.MaxToolTurns, acfg),
 			SkillsFilter:         acfg.SkillsFilter,
+			PolicyProfile:        acfg.PolicyProfile,
 			PreferredSearch:      acfg.PreferredSearch,
 			OpenAICompatProvider: agentCompatProvider,
 			OpenAICompatBaseURL:  agentCompatBaseURL,
+			Capabilities:         acfg.Capabilities,
 		}); err != nil {
 			logger.Error("failed to create agent from config", "agent_id", acfg.AgentID, "error", err)
 		}
@@ -415,26 +461,39 @@ The system runs this checklist periodically to ensure health.
 		logger.Info("plans loaded from config", "count", len(planSummaries))
 	}
 
-	// Configure shell executor (Host vs Docker)
-	var shellSandbox *tools.DockerSandbox
-	if cfg.Tools.Shell.Sandbox {
+	// Configure shell executor (host, Docker or native sandbox).
+	var shellSandbox tools.Executor
+	shellCfg := cfg.Tools.Shell
+	switch shellCfg.Sandbox {
+	case config.SandboxDocker:
 		sb, err := tools.NewDockerSandbox(
-			cfg.Tools.Shell.SandboxImage,
-			cfg.Tools.Shell.SandboxMemory,
-			cfg.Tools.Shell.SandboxNetwork,
+			shellCfg.SandboxImage,
+			shellCfg.SandboxMemory,
+			shellCfg.SandboxNetwork,
 			filepath.Join(cfg.HomeDir, "workspace"),
 		)
 		if err != nil {
 			logger.Warn("failed to init docker sandbox, falling back to host", "error", err)
 		} else {
 			shellSandbox = sb
-			for _, ra := range registry.ListRunningAgents() {
-				if ra.Brain != nil {
-					ra.Brain.Registry().ShellExecutor = shellSandbox
-				}
+			defer sb.Close()
+			logger.Info("shell sandbox enabled", "mode", "docker", "image", shellCfg.SandboxImage)
+		}
+	case config.SandboxNative:
+		// Native mode is chosen for isolation, so running commands on the
+		// host instead would silently drop it.
+		sb, err := native.New(nativeSandboxConfig(cfg))
+		if err != nil {
+			fatalStartup(logger, "E_SANDBOX_INIT", fmt.Errorf("native sandbox: %w", err))
+		}
+		shellSandbox = sb
+		logger.Info("shell sandbox enabled", "mode", "native")
+	}
+	if shellSandbox != nil {
+		for _, ra := range registry.ListRunningAgents() {
+			if ra.Brain != nil {
+				ra.Brain.Registry().ShellExecutor = shellSandbox
 			}
-			defer shellSandbox.Close()
-			logger.Info("shell sandbox enabled", "image", cfg.Tools.Shell.SandboxImage)
 		}
 	}
 
@@ -442,11 +501,15 @@ The system runs this checklist periodically to ensure health.
 	var mcpConfigs []mcp.ServerConfig
 	for _, s := range cfg.MCP.Servers {
 		mcpConfigs = append(mcpConfigs, mcp.ServerConfig{
-			Name:    s.Name,
-			Command: s.Command,
-			Args:    s.Args,
-			Env:     s.Env,
-			Enabled: s.Enabled,
+			Name:      s.Name,
+			Command:   s.Command,
+			Args:      s.Args,
+			Env:       s.Env,
+			URL:       s.URL,
+			Transport: s.Transport,
+			Headers:   s.Headers,
+			Timeout:   s.Timeout,
+			Enabled:   s.Enabled,
 		})
 	}
 
@@ -455,6 +518,19 @@ The system runs this checklist periodically to ensure health.
 		logger.Warn("MCP manager start failed", "error", err)
 	}
 	defer func() { _ = mcpManager.Stop() }()
+	// MCP rules come from each agent's policy profile when it has one.
+	mcpManager.SetPolicyResolver(registry.AgentPolicy)
+	// Re-register tool sets when a server reports tools/list_changed.
+	mcpManager.SetEventBus(eventBus)
+	mcpManager.OnToolsChanged(func(serverName string, agentIDs []string) {
+		for _, agentID := range agentIDs {
+			ra := registry.GetAgent(agentID)
+			if ra == nil || ra.Brain == nil {
+				continue
+			}
+			_ = ra.Brain.RegisterMCPTools(ctx, agentID, mcpManager)
+		}
+	})
 
 	// Register MCP tools on all agents and set delegation config (Phase 1.4 per-agent MCP).
 	for _, ra := range registry.ListRunningAgents() {
@@ -473,16 +549,19 @@ The system runs this checklist periodically to ensure health.
 						Env:       ref.Env,
 						Transport: ref.Transport,
 						URL:       ref.URL,
+						Headers:   ref.Headers,
 						Timeout:   ref.Timeout,
+						Enabled:   true,
 					})
 				}
 				if err := mcpManager.ConnectAgentServers(ctx, agentID, serverConfigs); err != nil {
 					logger.Warn("failed to connect per-agent MCP servers", "agent_id", agentID, "error", err)
 				}
+				restoreMCPResourcePins(ctx, mcpManager, store, agentID, logger)
 			}
 
 			// Register MCP tools for this agent via the new per-agent bridge.
-			_ = tools.RegisterMCPTools(ra.Brain.Genkit(), agentID, mcpManager)
+			_ = ra.Brain.RegisterMCPTools(ctx, agentID, mcpManager)
 		}
 		// Set delegation max hops from config.
 		if ra.Brain != nil && cfg.DelegationMaxHops > 0 {
@@ -750,16 +829,19 @@ The system runs this checklist periodically to ensure health.
 						Env:       ref.Env,
 						Transport: ref.Transport,
 						URL:       ref.URL,
+						Headers:   ref.Headers,
 						Timeout:   ref.Timeout,
+						Enabled:   true,
 					})
 				}
 				if err := mcpManager.ConnectAgentServers(ctx, agentID, serverConfigs); err != nil {
 					logger.Warn("failed to connect per-agent MCP servers on hot-reload", "agent_id", agentID, "error", err)
 				}
+				restoreMCPResourcePins(ctx, mcpManager, store, agentID, logger)
 			}
 
 			// Register MCP tools for this agent via the new per-agent bridge.
-			_ = tools.RegisterMCPTools(ra.Brain.Genkit(), agentID, mcpManager)
+			_ = ra.Brain.RegisterMCPTools(ctx, agentID, mcpManager)
 		}
 
 		// Delegation max hops from config.
@@ -800,7 +882,6 @@ The system runs this checklist periodically to ensure health.
 	if err := confWatcher.Start(ctx); err != nil {
 		fatalStartup(logger, "E_CONFIG_WATCHER_START", err)
 	}
-	var gwRef atomic.Pointer[gateway.Server] // atomic so hot-reload goroutine can safely call UpdatePlans
 	go func() {
 		for ev := range confWatcher.Events() {
 			logger.Info("config hot-reload event", "path", ev.Path, "op", ev.Op.String())
@@ -829,6 +910,20 @@ The system runs this checklist periodically to ensure health.
 					newVer := pol.PolicyVersion()
 					_ = store.RecordPolicyVersion(context.Background(), newVer, newVer, ev.Path)
 					logger.Info("policy.yaml hot-reloaded", "policy_version", newVer)
+					snapshot := pol.Snapshot()
+					for _, ra := range registry.ListRunningAgents() {
+						if name := ra.Config.PolicyProfile; name != "" {
+							if _, err := snapshot.ResolveProfile(name); err != nil {
+								logger.Warn("agent policy profile no longer resolves; agent is denied all capabilities", "agent_id", ra.Config.AgentID, "error", err)
+							}
+						}
+					}
+					// Re-register MCP tool sets so changed MCP rules reach the model's tool list.
+					for _, ra := range registry.ListRunningAgents() {
+						if ra.Brain != nil {
+							_ = ra.Brain.RegisterMCPTools(ctx, ra.Config.AgentID, mcpManager)
+						}
+					}
 				}
 			case "config.yaml":
 				newCfg, err := config.Load()
@@ -841,6 +936,8 @@ The system runs this checklist periodically to ensure health.
 				// GC-SPEC-CFR-004: Agent hot-reload on config change.
 				reconcileAgents(ctx, registry, newCfg.Agents, cfg.Agents, &newCfg, logger)
 				cfg.Agents = newCfg.Agents
+				pricing.SetOverrides(pricingOverrides(newCfg.Pricing))
+				budgets.Configure(newCfg)
 
 				// Reload plans from updated config.
 				// GC-SPEC-PDR-v4-Phase-4: Plan hot-reload on config change.
@@ -996,6 +1093,7 @@ The system runs this checklist periodically to ensure health.
 	// Create plan executor (GC-SPEC-PDR-v4-Phase-4: Plan execution engine).
 	waiter := coordinator.NewWaiter(eventBus, store)
 	executor := coordinator.NewExecutor(registry, waiter, store, eventBus)
+	executor.SetOutputValidator(engine.ValidateOutput)
 
 	// GC-SPEC-PDR-v4-Phase-3: Resume crashed plans in background
 	go func() {
@@ -1031,6 +1129,39 @@ The system runs this checklist periodically to ensure health.
 		}
 	}()
 
+	// The webhook channel is created before the gateway, which mounts its
+	// inbound endpoints; it is started with the other channels below.
+	var webhooks *channels.WebhookChannel
+	var webhookHandler http.Handler
+	if cfg.Channels.Webhook.Enabled {
+		webhooks, err = channels.NewWebhookChannel(cfg.Channels.Webhook, registry, store, logger, eventBus)
+		if err != nil {
+			logger.Error("webhook channel disabled", "error", err)
+		} else {
+			webhookHandler = webhooks.Handler()
+		}
+	}
+
+	// Likewise the Telegram channel, whose webhook the gateway serves in
+	// webhook mode.
+	var tg *channels.TelegramChannel
+	var telegramHandler http.Handler
+	if tc := cfg.Channels.Telegram; tc.Enabled {
+		switch {
+		case tc.Token == "":
+			logger.Warn("telegram channel enabled but token is missing")
+		case tc.WebhookURL != "" && tc.WebhookSecret == "":
+			logger.Error("telegram channel disabled: webhook_url requires webhook_secret")
+		default:
+			tg = channels.NewTelegramChannel(tc.Token, tc.AllowedIDs, registry, store, logger, eventBus)
+			tg.SetAgentDirectory(channelAgentDirectory{reg: registry})
+			if tc.WebhookURL != "" {
+				tg.SetWebhook(tc.WebhookURL, tc.WebhookSecret)
+				telegramHandler = tg.Handler()
+			}
+		}
+	}
+
 	gw := gateway.New(gateway.Config{
 		Store:             store,
 		Registry:          registry,
@@ -1040,6 +1171,8 @@ The system runs this checklist periodically to ensure health.
 		AllowOrigins:      cfg.AllowOrigins,
 		ConfigFingerprint: cfg.Fingerprint(),
 		ToolsUpdated:      toolsUpdated,
+		MCP:               mcpManager,
+		Approvals:         approvals,
 		TinygoStatus:      wasmWatcher.TinygoStatus,
 		SkillsStatus:      skillsStatusFn,
 		Plans:             planSummaries,
@@ -1050,6 +1183,9 @@ The system runs this checklist periodically to ensure health.
 		HomeDir:           cfg.HomeDir,
 		Cfg:               &cfg,
 		GatewaySecurity:   cfg.Gateway,
+		Budget:            budgets,
+		Webhooks:          webhookHandler,
+		TelegramWebhook:   telegramHandler,
 	})
 	gwRef.Store(gw) // publish to hot-reload goroutine (atomic, race-free)
 
@@ -1096,25 +1232,54 @@ The system runs this checklist periodically to ensure health.
 	heartbeat.Start(ctx)
 
 	// Channels
-	if cfg.Channels.Telegram.Enabled {
-		if cfg.Channels.Telegram.Token == "" {
-			logger.Warn("telegram channel enabled but token is missing")
+	if tg != nil {
+		tg.SetPlanStarter(gw)
+
+		// GC-SPEC-PDR-v7-Phase-3: Subscribe to plan execution and HITL events
+		tg.SubscribeToEvents()
+
+		go func() {
+			if err := tg.Start(ctx); err != nil {
+				logger.Error("telegram channel failed", "error", err)
+			}
+		}()
+	}
+	if cfg.Channels.Slack.Enabled {
+		if cfg.Channels.Slack.BotToken == "" || cfg.Channels.Slack.AppToken == "" {
+			logger.Warn("slack channel enabled but bot_token or app_token is missing")
 		} else {
-			tg := channels.NewTelegramChannel(
-				cfg.Channels.Telegram.Token,
-				cfg.Channels.Telegram.AllowedIDs,
-				registry,
-				store,
-				logger,
-				eventBus,
-			)
-
-			// GC-SPEC-PDR-v7-Phase-3: Subscribe to plan execution and HITL events
-			tg.SubscribeToEvents()
+			sl := channels.NewSlackChannel(channels.SlackOptions{
+				BotToken:       cfg.Channels.Slack.BotToken,
+				AppToken:       cfg.Channels.Slack.AppToken,
+				AllowedUserIDs: cfg.Channels.Slack.AllowedUserIDs,
+				NotifyChannel:  cfg.Channels.Slack.NotifyChannel,
+				APIURL:         cfg.Channels.Slack.APIURL,
+			}, registry, store, logger, eventBus)
+			sl.SetPlanStarter(gw)
+			sl.SubscribeToEvents()
 
 			go func() {
-				if err := tg.Start(ctx); err != nil {
-					logger.Error("telegram channel failed", "error", err)
+				if err := sl.Start(ctx); err != nil {
+					logger.Error("slack channel failed", "error", err)
+				}
+			}()
+		}
+	}
+	if webhooks != nil {
+		go func() {
+			if err := webhooks.Start(ctx); err != nil {
+				logger.Error("webhook channel failed", "error", err)
+			}
+		}()
+	}
+	if cfg.Channels.Email.Enabled {
+		em, err := channels.NewEmailChannel(cfg.Channels.Email, registry, store, logger, eventBus)
+		if err != nil {
+			logger.Error("email channel disabled", "error", err)
+		} else {
+			go func() {
+				if err := em.Start(ctx); err != nil {
+					logger.Error("email channel failed", "error", err)
 				}
 			}()
 		}
@@ -1211,6 +1376,8 @@ The system runs this checklist periodically to ensure health.
 				EventBus:     eventBus,
 				BindAddr:     cfg.BindAddr,
 				AuthToken:    authToken,
+				MCP:          mcpManager,
+				Approvals:    approvals,
 			}); err != nil && ctx.Err() == nil {
 				logger.Error("chat exited with error", "error", err)
 			}
@@ -1294,6 +1461,18 @@ func loadPlans(planConfigs []config.PlanConfig, agentIDs []string, logger *slog.
 		logger.Warn("failed to load plans from config", "error", err)
 		return summaries, plansMap
 	}
+	// Output schemas are compiled here: the coordinator only checks that they are JSON.
+	for name, p := range plans {
+		for _, step := range p.Steps {
+			if len(step.OutputSchema) == 0 {
+				continue
+			}
+			if _, err := engine.NewStructuredValidator(step.OutputSchema, 0, true); err != nil {
+				logger.Warn("failed to load plans from config", "error", fmt.Errorf("plan %s step %s: output_schema: %w", name, step.ID, err))
+				return summaries, plansMap
+			}
+		}
+	}
 	for name, p := range plans {
 		planCopy := p
 		plansMap[name] = &planCopy
@@ -1310,6 +1489,7 @@ func loadPlans(planConfigs []config.PlanConfig, agentIDs []string, logger *slog.
 			Name:      name,
 			StepCount: len(p.Steps),
 			AgentIDs:  agentList,
+			Inputs:    p.Inputs,
 		}
 	}
 	return summaries, plansMap
@@ -1355,13 +1535,62 @@ func buildAgentConfig(acfg config.AgentConfigEntry, globalCfg *config.Config) ag
 		WorkerCount:          acfg.WorkerCount,
 		TaskTimeoutSeconds:   acfg.TaskTimeoutSeconds,
 		MaxQueueDepth:        acfg.MaxQueueDepth,
+		MaxToolTurns:         agentMaxToolTurns(globalCfg.MaxToolTurns, acfg),
 		SkillsFilter:         acfg.SkillsFilter,
+		PolicyProfile:        acfg.PolicyProfile,
 		PreferredSearch:      acfg.PreferredSearch,
 		OpenAICompatProvider: agentCompatProvider,
 		OpenAICompatBaseURL:  agentCompatBaseURL,
+		Capabilities:         acfg.Capabilities,
+	}
+}
+
+// pricingOverrides converts the pricing section of config.yaml into the
+// pricing package's table.
+func pricingOverrides(prices map[string]map[string]config.ModelPrice) map[string]map[string]pricing.ModelPricing {
+	out := make(map[string]map[string]pricing.ModelPricing, len(prices))
+	for provider, models := range prices {
+		out[provider] = make(map[string]pricing.ModelPricing, len(models))
+		for model, p := range models {
+			out[provider][model] = pricing.ModelPricing{
+				PromptPer1M:       p.Input,
+				CompletionPer1M:   p.Output,
+				CachedPromptPer1M: p.CachedInput,
+			}
+		}
+	}
+	return out
+}
+
+// memoryEmbedder returns the embeddings backend configured under
+// memory.embeddings, or nil for keyword-only memory ranking.
+func memoryEmbedder(cfg config.Config, logger *slog.Logger) memory.Embedder {
+	e := cfg.Memory.Embeddings
+	if e == nil || e.Provider == "" {
+		return nil
+	}
+	switch strings.ToLower(e.Provider) {
+	case "ollama":
+		baseURL := e.BaseURL
+		if baseURL == "" {
+			baseURL = cfg.Providers["ollama"].BaseURL
+		}
+		return memory.NewOllamaEmbedder(baseURL, e.Model)
+	default:
+		logger.Warn("unknown memory.embeddings.provider, using keyword ranking only", "provider", e.Provider)
+		return nil
 	}
 }
 
+// agentMaxToolTurns returns the agent's tool-call turn budget, falling back
+// to the global max_tool_turns.
+func agentMaxToolTurns(global int, acfg config.AgentConfigEntry) int {
+	if acfg.MaxToolTurns > 0 {
+		return acfg.MaxToolTurns
+	}
+	return global
+}
+
 // agentConfigEqual compares two AgentConfigEntry values for equality.
 func agentConfigEqual(a, b config.AgentConfigEntry) bool {
 	return a.AgentID == b.AgentID &&
@@ -1374,7 +1603,58 @@ func agentConfigEqual(a, b config.AgentConfigEntry) bool {
 		a.WorkerCount == b.WorkerCount &&
 		a.TaskTimeoutSeconds == b.TaskTimeoutSeconds &&
 		a.MaxQueueDepth == b.MaxQueueDepth &&
-		a.PreferredSearch == b.PreferredSearch
+		a.MaxToolTurns == b.MaxToolTurns &&
+		a.PreferredSearch == b.PreferredSearch &&
+		a.PolicyProfile == b.PolicyProfile &&
+		slices.Equal(a.Capabilities, b.Capabilities)
+}
+
+// gatewayPlanRunner starts plans for the create_plan tool through the
+// gateway once it exists.
+type gatewayPlanRunner struct {
+	gw *atomic.Pointer[gateway.Server]
+}
+
+func (r gatewayPlanRunner) StartGeneratedPlan(ctx context.Context, plan *persistence.GeneratedPlan, sessionID string, inputs map[string]any) (string, string, error) {
+	gw := r.gw.Load()
+	if gw == nil {
+		return "", "", fmt.Errorf("gateway not started")
+	}
+	return gw.StartGeneratedPlan(ctx, plan, sessionID, inputs)
+}
+
+func (r gatewayPlanRunner) IsConfiguredPlan(name string) bool {
+	gw := r.gw.Load()
+	return gw != nil && gw.IsConfiguredPlan(name)
+}
+
+// channelAgentDirectory adapts agent.Registry for the channels.AgentDirectory
+// interface behind the chat channels' operator commands.
+type channelAgentDirectory struct {
+	reg *agent.Registry
+}
+
+func (d channelAgentDirectory) ListAgents() []channels.AgentInfo {
+	configs := d.reg.ListAgents()
+	infos := make([]channels.AgentInfo, len(configs))
+	for i, c := range configs {
+		infos[i] = channels.AgentInfo{
+			ID:          c.AgentID,
+			DisplayName: c.DisplayName,
+			Emoji:       c.AgentEmoji,
+			Provider:    c.Provider,
+			Model:       c.Model,
+		}
+	}
+	return infos
+}
+
+func (d channelAgentDirectory) AgentStatus(agentID string) (*engine.Status, error) {
+	return d.reg.AgentStatus(agentID)
+}
+
+func (d channelAgentDirectory) AbortTask(ctx context.Context, taskID string) (bool, error) {
+	return d.reg.AbortTask(ctx, taskID)
 }
 
 // tuiAgentSwitcher adapts agent.Registry for the tui.AgentSwitcher interface.
@@ -1404,10 +1684,11 @@ func (s *tuiAgentSwitcher) ListAgentInfo() []tui.AgentInfo {
 	infos := make([]tui.AgentInfo, len(configs))
 	for i, c := range configs {
 		infos[i] = tui.AgentInfo{
-			ID:          c.AgentID,
-			DisplayName: c.DisplayName,
-			Emoji:       c.AgentEmoji,
-			Model:       c.Model,
+			ID:            c.AgentID,
+			DisplayName:   c.DisplayName,
+			Emoji:         c.AgentEmoji,
+			Model:         c.Model,
+			PolicyProfile: c.PolicyProfile,
 		}
 	}
 	return infos
@@ -1603,6 +1884,34 @@ func printDaemonSubcommandUsage(w io.Writer) {
 	fmt.Fprintln(w, "Runs GoClaw in daemon mode (no interactive chat TUI).")
 }
 
+// restoreMCPResourcePins re-subscribes an agent's pinned MCP resources after
+// its servers connect, refreshing content that changed while offline.
+func restoreMCPResourcePins(ctx context.Context, mgr *mcp.Manager, store *persistence.Store, agentID string, logger *slog.Logger) {
+	pins := memory.NewPinManager(store)
+	sources, err := pins.ResourcePinSources(ctx, agentID)
+	if err != nil {
+		logger.Warn("failed to list pinned mcp resources", "agent_id", agentID, "error", err)
+		return
+	}
+	if len(sources) > 0 {
+		mgr.RestoreResourcePins(ctx, agentID, sources, pins)
+	}
+}
+
+// nativeSandboxConfig maps tools.shell to the native sandbox's settings.
+func nativeSandboxConfig(cfg config.Config) native.Config {
+	shell := cfg.Tools.Shell
+	return native.Config{
+		Workspace:     filepath.Join(cfg.HomeDir, "workspace"),
+		ReadOnlyPaths: shell.SandboxReadOnlyPaths,
+		Network:       shell.SandboxNetwork,
+		MemoryMB:      shell.SandboxMemory,
+		CPUs:          shell.SandboxCPUs,
+		Pids:          shell.SandboxPids,
+		Cgroup:        shell.SandboxCgroup,
+	}
+}
+
 // findAgentConfig finds an agent config by ID in the agents list.
 func findAgentConfig(agents []config.AgentConfigEntry, agentID string) *config.AgentConfigEntry {
 	for i := range agents {
@@ -5,15 +5,19 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/basket/go-claw/internal/budget"
 	"github.com/basket/go-claw/internal/bus"
 	"github.com/basket/go-claw/internal/engine"
+	"github.com/basket/go-claw/internal/memory"
 	"github.com/basket/go-claw/internal/persistence"
 	"github.com/basket/go-claw/internal/policy"
 	"github.com/basket/go-claw/internal/sandbox/wasm"
+	"github.com/basket/go-claw/internal/tools"
 )
 
 // AgentConfig holds the configuration needed to create and run an agent.
@@ -29,11 +33,14 @@ type AgentConfig struct {
 	WorkerCount          int
 	TaskTimeoutSeconds   int
 	MaxQueueDepth        int
+	MaxToolTurns         int      // tool-call rounds per response; 0 = engine default
 	SkillsFilter         []string // empty = all skills
 	PolicyOverrides      *policy.Policy
+	PolicyProfile        string // named profile from policy.yaml; empty = global policy
 	PreferredSearch      string
 	OpenAICompatProvider string
 	OpenAICompatBaseURL  string
+	Capabilities         []string // what the agent is good at; generated plans pick agents by these
 }
 
 // RunningAgent holds a running agent's brain, engine, and lifecycle state.
@@ -41,6 +48,7 @@ type RunningAgent struct {
 	Config    AgentConfig
 	Engine    *engine.Engine
 	Brain     *engine.GenkitBrain
+	Policy    policy.Checker // checker handed to the brain and engine
 	cancel    context.CancelFunc
 	startedAt time.Time
 }
@@ -55,6 +63,11 @@ type Registry struct {
 	wasm           *wasm.Host
 	apiKeys        map[string]string      // shared tool API keys (brave, perplexity, etc.)
 	onAgentCreated func(ra *RunningAgent) // optional provisioning callback for runtime-created agents
+	approvals      tools.ApprovalBroker   // optional: human approval for require_approval tools
+	planner        *tools.Planner         // optional: enables the create_plan tool
+	budget         *budget.Enforcer       // optional: spend budgets
+	memory         *memory.Retriever      // optional: memory ranking (nil = keyword only)
+	memoryTokens   int                    // token budget for memories injected per turn
 }
 
 // RegisterTestAgent registers a pre-built engine as a named agent.
@@ -93,6 +106,50 @@ func (r *Registry) SetOnAgentCreated(fn func(ra *RunningAgent)) {
 	r.onAgentCreated = fn
 }
 
+// SetApprovalBroker sets the broker that agents created afterwards use for
+// require_approval tool gates.
+func (r *Registry) SetApprovalBroker(b tools.ApprovalBroker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.approvals = b
+}
+
+// SetPlanner sets the create_plan configuration for agents created afterwards.
+func (r *Registry) SetPlanner(p *tools.Planner) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.planner = p
+}
+
+// SetBudget sets the spend budget enforcer for agents created afterwards.
+func (r *Registry) SetBudget(b *budget.Enforcer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.budget = b
+}
+
+// SetMemory sets the memory retriever and the per-turn memory token budget
+// for agents created afterwards.
+func (r *Registry) SetMemory(m *memory.Retriever, tokenBudget int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.memory = m
+	r.memoryTokens = tokenBudget
+}
+
+// PlanAgents lists the running agents and their capabilities, ordered by ID,
+// for the create_plan tool.
+func (r *Registry) PlanAgents(ctx context.Context) []tools.PlanAgent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	agents := make([]tools.PlanAgent, 0, len(r.agents))
+	for id, a := range r.agents {
+		agents = append(agents, tools.PlanAgent{ID: id, Capabilities: a.Config.Capabilities})
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	return agents
+}
+
 // CreateAgent validates, initializes, and starts an agent, persisting it to DB.
 func (r *Registry) CreateAgent(ctx context.Context, cfg AgentConfig) error {
 	if cfg.AgentID == "" {
@@ -120,14 +177,19 @@ func (r *Registry) CreateAgent(ctx context.Context, cfg AgentConfig) error {
 		apiKey = os.Getenv(cfg.APIKeyEnv)
 	}
 
-	// Resolve policy: per-agent overrides or global.
-	var agentPolicy policy.Checker
-	if cfg.PolicyOverrides != nil {
-		agentPolicy = policy.NewLivePolicy(*cfg.PolicyOverrides, "")
-	} else {
-		agentPolicy = r.policy
+	// Resolve policy: per-agent overrides, a named profile, or global.
+	agentPolicy, err := r.resolvePolicy(cfg)
+	if err != nil {
+		return err
 	}
 
+	r.mu.RLock()
+	approvals := r.approvals
+	planner := r.planner
+	enforcer := r.budget
+	retriever, memoryTokens := r.memory, r.memoryTokens
+	r.mu.RUnlock()
+
 	// Create GenkitBrain.
 	brain := engine.NewGenkitBrain(ctx, r.store, engine.BrainConfig{
 		Provider:                 cfg.Provider,
@@ -139,8 +201,14 @@ func (r *Registry) CreateAgent(ctx context.Context, cfg AgentConfig) error {
 		Policy:                   agentPolicy,
 		APIKeys:                  r.apiKeys,
 		PreferredSearch:          cfg.PreferredSearch,
+		MaxToolTurns:             cfg.MaxToolTurns,
 		OpenAICompatibleProvider: cfg.OpenAICompatProvider,
 		OpenAICompatibleBaseURL:  cfg.OpenAICompatBaseURL,
+		Approvals:                approvals,
+		Planner:                  planner,
+		Budget:                   enforcer,
+		Memory:                   retriever,
+		MemoryTokens:             memoryTokens,
 	})
 
 	// Set WASM host if available.
@@ -156,6 +224,7 @@ func (r *Registry) CreateAgent(ctx context.Context, cfg AgentConfig) error {
 		TaskTimeout:   time.Duration(cfg.TaskTimeoutSeconds) * time.Second,
 		MaxQueueDepth: cfg.MaxQueueDepth,
 		Bus:           r.bus,
+		Budget:        enforcer,
 	}, agentPolicy)
 
 	// Start engine.
@@ -203,6 +272,7 @@ func (r *Registry) CreateAgent(ctx context.Context, cfg AgentConfig) error {
 		Config:    cfg,
 		Engine:    eng,
 		Brain:     brain,
+		Policy:    agentPolicy,
 		cancel:    cancel,
 		startedAt: time.Now(),
 	}
@@ -221,6 +291,36 @@ func (r *Registry) CreateAgent(ctx context.Context, cfg AgentConfig) error {
 	return nil
 }
 
+// resolvePolicy picks the checker for an agent config.
+func (r *Registry) resolvePolicy(cfg AgentConfig) (policy.Checker, error) {
+	if cfg.PolicyOverrides != nil {
+		return policy.NewLivePolicy(*cfg.PolicyOverrides, ""), nil
+	}
+	if cfg.PolicyProfile == "" {
+		return r.policy, nil
+	}
+	resolver, ok := r.policy.(policy.ProfileResolver)
+	if !ok {
+		return nil, fmt.Errorf("agent %q: policy profiles are not supported by the configured policy", cfg.AgentID)
+	}
+	pol, err := resolver.ForProfile(cfg.PolicyProfile)
+	if err != nil {
+		return nil, fmt.Errorf("agent %q: %w", cfg.AgentID, err)
+	}
+	return pol, nil
+}
+
+// AgentPolicy returns the checker in effect for a running agent, or nil if
+// the agent is not running.
+func (r *Registry) AgentPolicy(agentID string) policy.Checker {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if ra, ok := r.agents[agentID]; ok {
+		return ra.Policy
+	}
+	return nil
+}
+
 // RemoveAgent stops and removes a non-default agent, draining its engine.
 func (r *Registry) RemoveAgent(ctx context.Context, agentID string, drainTimeout time.Duration) error {
 	if agentID == "default" {
@@ -321,6 +421,19 @@ func (r *Registry) StreamChatTask(ctx context.Context, agentID, sessionID, conte
 	return agent.Engine.StreamChatTaskForAgent(ctx, agentID, sessionID, content, onChunk)
 }
 
+// SupportsMedia reports whether the agent's model accepts image and PDF
+// attachments. Unknown agents and agents without a brain report true, so
+// the caller's usual not-found handling applies.
+func (r *Registry) SupportsMedia(agentID string) bool {
+	r.mu.RLock()
+	agent, ok := r.agents[agentID]
+	r.mu.RUnlock()
+	if !ok || agent.Brain == nil {
+		return true
+	}
+	return agent.Brain.SupportsMedia()
+}
+
 // AbortTask finds the agent owning a task and aborts it.
 func (r *Registry) AbortTask(ctx context.Context, taskID string) (bool, error) {
 	task, err := r.store.GetTask(ctx, taskID)
@@ -442,3 +442,45 @@ func TestDefaultWorkerCount(t *testing.T) {
 		t.Errorf("default TaskTimeoutSeconds = %d, want 600", agent.Config.TaskTimeoutSeconds)
 	}
 }
+
+func TestCreateAgentPolicyProfile(t *testing.T) {
+	eventBus := bus.New()
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "test.db"), eventBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	livePol := policy.NewLivePolicy(policy.Policy{
+		AllowCapabilities: []string{"tools.exec", "tools.read_url"},
+		Profiles: map[string]policy.Profile{
+			"research": {AllowCapabilities: []string{"tools.read_url"}},
+		},
+	}, "")
+	reg := NewRegistry(store, eventBus, livePol, nil, nil)
+	ctx := context.Background()
+
+	if err := reg.CreateAgent(ctx, AgentConfig{AgentID: "researcher", Provider: "google", PolicyProfile: "research"}); err != nil {
+		t.Fatalf("CreateAgent: %v", err)
+	}
+	if err := reg.CreateAgent(ctx, AgentConfig{AgentID: "coder", Provider: "google"}); err != nil {
+		t.Fatalf("CreateAgent: %v", err)
+	}
+	t.Cleanup(func() { reg.DrainAll(2 * time.Second) })
+
+	research := reg.AgentPolicy("researcher")
+	if research == nil || research.AllowCapability("tools.exec") || !research.AllowCapability("tools.read_url") {
+		t.Fatalf("researcher should only hold tools.read_url, got %#v", research)
+	}
+	if coder := reg.AgentPolicy("coder"); coder == nil || !coder.AllowCapability("tools.exec") {
+		t.Fatal("coder should use the global policy")
+	}
+	if reg.AgentPolicy("missing") != nil {
+		t.Fatal("unknown agent should have no policy")
+	}
+
+	err = reg.CreateAgent(ctx, AgentConfig{AgentID: "typo", Provider: "google", PolicyProfile: "reserch"})
+	if err == nil || reg.GetAgent("typo") != nil {
+		t.Fatalf("expected unknown profile to fail creation, got err=%v", err)
+	}
+}
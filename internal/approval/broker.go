@@ -0,0 +1,399 @@
+// Package approval parks sensitive tool calls until a human approves or
+// denies them. Requests are stored in the approvals table so a task that is
+// waiting when the daemon restarts resumes waiting on the same request when
+// it re-runs, and a decision made while nothing was waiting is still applied.
+package approval
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basket/go-claw/internal/audit"
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/shared"
+)
+
+// Responder actions.
+const (
+	ActionApprove        = "approve"
+	ActionApproveSession = "approve_session"
+	ActionDeny           = "deny"
+)
+
+// ErrNotFound is returned by Respond for an unknown approval ID.
+var ErrNotFound = errors.New("approval request not found")
+
+// maxRenderedArgs bounds the argument text shown to approvers.
+const maxRenderedArgs = 4000
+
+// Request describes a tool call that needs a human decision.
+type Request struct {
+	TaskID    string
+	RunID     string // current execution of TaskID
+	SessionID string
+	AgentID   string
+	Tool      string
+	Args      any // rendered as JSON for approvers
+	Timeout   time.Duration
+}
+
+// Decision is the outcome handed back to the gated tool.
+type Decision struct {
+	Approved   bool
+	Status     string // persistence.Approval* status
+	Reason     string
+	ApprovalID string // empty when a session grant applied
+}
+
+// Broker creates, waits on and resolves tool approvals.
+type Broker struct {
+	store *persistence.Store
+	bus   *bus.Bus
+
+	// claimMu serializes matching a call to a stored request, so a re-run
+	// adopting one record can't race another call superseding it.
+	claimMu sync.Mutex
+
+	mu      sync.Mutex
+	waiters map[string]chan struct{}
+}
+
+// NewBroker returns a broker backed by store. eventBus may be nil, in which
+// case approvals can only be answered through Respond.
+func NewBroker(store *persistence.Store, eventBus *bus.Bus) *Broker {
+	return &Broker{store: store, bus: eventBus, waiters: make(map[string]chan struct{})}
+}
+
+// Start applies approval responses published on the bus (Telegram buttons,
+// TUI) until ctx is cancelled. Responses for plan-step approvals share the
+// topic and are ignored.
+func (b *Broker) Start(ctx context.Context) {
+	if b.bus == nil {
+		return
+	}
+	sub := b.bus.Subscribe(bus.TopicHITLApprovalResponse)
+	go func() {
+		defer b.bus.Unsubscribe(sub)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-sub.Ch():
+				if !ok {
+					return
+				}
+				resp, ok := ev.Payload.(bus.HITLApprovalResponse)
+				if !ok {
+					continue
+				}
+				if _, err := b.Respond(ctx, resp.RequestID, resp.Action, resp.Reason); err != nil && !errors.Is(err, ErrNotFound) {
+					slog.Warn("tool approval response rejected", "approval_id", resp.RequestID, "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Request blocks until the call is approved, denied or times out. An
+// existing unconsumed request for the same task, tool and arguments is
+// reused, so a task re-run after a restart does not ask twice. If ctx is
+// cancelled the request stays pending for the next attempt. When a re-run
+// makes a call that matches nothing, the requests still pending from
+// earlier runs of the task are expired: that run is gone and will not
+// resume waiting on them.
+func (b *Broker) Request(ctx context.Context, req Request) (Decision, error) {
+	if req.SessionID != "" {
+		ok, err := b.store.HasSessionToolApproval(ctx, req.SessionID, req.AgentID, req.Tool)
+		if err != nil {
+			return Decision{}, err
+		}
+		if ok {
+			audit.Record("allow", "approval.tool", "session_approved", "", subject(req.AgentID, req.Tool))
+			return Decision{Approved: true, Status: persistence.ApprovalApprovedSession, Reason: "approved for this session"}, nil
+		}
+	}
+
+	rec, err := b.claim(ctx, req)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	if rec.Status == persistence.ApprovalPending {
+		if err := b.wait(ctx, rec); err != nil {
+			return Decision{}, err
+		}
+		latest, err := b.store.GetToolApproval(ctx, rec.ID)
+		if err != nil {
+			return Decision{}, fmt.Errorf("reload tool approval: %w", err)
+		}
+		rec = latest
+	}
+	if err := b.store.ConsumeToolApproval(ctx, rec.ID); err != nil {
+		return Decision{}, err
+	}
+	return decisionFor(rec), nil
+}
+
+// claim returns the stored request req waits on: an unconsumed one for the
+// same call, adopted by the current run, or a new one.
+func (b *Broker) claim(ctx context.Context, req Request) (*persistence.ToolApproval, error) {
+	b.claimMu.Lock()
+	defer b.claimMu.Unlock()
+
+	rendered, hash := renderArgs(req.Args)
+	if req.TaskID != "" {
+		rec, err := b.store.FindToolApproval(ctx, req.TaskID, req.Tool, hash)
+		if err != nil {
+			return nil, err
+		}
+		if rec != nil {
+			if req.RunID != "" && rec.RunID != req.RunID {
+				if err := b.store.SetToolApprovalRun(ctx, rec.ID, req.RunID); err != nil {
+					return nil, err
+				}
+				rec.RunID = req.RunID
+			}
+			return rec, nil
+		}
+		if req.RunID != "" {
+			if err := b.supersede(ctx, req.TaskID, req.RunID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	rec := &persistence.ToolApproval{
+		TaskID:    req.TaskID,
+		RunID:     req.RunID,
+		SessionID: req.SessionID,
+		AgentID:   req.AgentID,
+		Tool:      req.Tool,
+		Args:      rendered,
+		ArgsHash:  hash,
+		ExpiresAt: time.Now().UTC().Add(timeout),
+	}
+	if err := b.store.CreateToolApproval(ctx, rec); err != nil {
+		return nil, err
+	}
+	audit.Record("pending", "approval.tool", "approval_requested", "", rec.ID)
+	return rec, nil
+}
+
+// supersede expires taskID's pending requests left by runs other than runID.
+func (b *Broker) supersede(ctx context.Context, taskID, runID string) error {
+	pending, err := b.store.ListPendingTaskToolApprovals(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	const reason = "superseded by a re-run of the task"
+	for _, rec := range pending {
+		if rec.RunID == runID {
+			continue
+		}
+		ok, err := b.store.ResolveToolApproval(ctx, rec.ID, persistence.ApprovalExpired, reason)
+		if err != nil {
+			return err
+		}
+		if ok {
+			audit.Record("deny", "approval.tool", "approval_superseded", "", rec.ID)
+			b.publishResolved(rec, persistence.ApprovalExpired, reason)
+			b.signal(rec.ID)
+		}
+	}
+	return nil
+}
+
+// wait parks the caller on rec until it is resolved or expires.
+func (b *Broker) wait(ctx context.Context, rec *persistence.ToolApproval) error {
+	ch := b.register(rec.ID)
+	defer b.unregister(rec.ID)
+
+	b.publish(bus.TopicToolApprovalRequested, bus.ToolApprovalRequest{
+		RequestID: rec.ID,
+		TaskID:    rec.TaskID,
+		SessionID: rec.SessionID,
+		AgentID:   rec.AgentID,
+		Tool:      rec.Tool,
+		Args:      rec.Args,
+		ExpiresAt: rec.ExpiresAt,
+	})
+
+	timer := time.NewTimer(time.Until(rec.ExpiresAt))
+	defer timer.Stop()
+	select {
+	case <-ch:
+		return nil
+	case <-timer.C:
+		b.expire(ctx, rec)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Broker) expire(ctx context.Context, rec *persistence.ToolApproval) {
+	ok, err := b.store.ResolveToolApproval(ctx, rec.ID, persistence.ApprovalExpired, "approval timed out")
+	if err != nil {
+		slog.Warn("expire tool approval failed", "approval_id", rec.ID, "error", err)
+		return
+	}
+	if ok {
+		audit.Record("deny", "approval.tool", "approval_timeout_default_deny", "", rec.ID)
+		b.publishResolved(rec, persistence.ApprovalExpired, "approval timed out")
+	}
+}
+
+// Respond records a decision. action is approve, approve_session or deny
+// ("reject" is accepted for deny); reason is returned to the model on deny.
+func (b *Broker) Respond(ctx context.Context, id, action, reason string) (*persistence.ToolApproval, error) {
+	var status string
+	switch strings.ToLower(strings.TrimSpace(action)) {
+	case ActionApprove:
+		status = persistence.ApprovalApproved
+	case ActionApproveSession:
+		status = persistence.ApprovalApprovedSession
+	case ActionDeny, "reject":
+		status = persistence.ApprovalDenied
+	default:
+		return nil, fmt.Errorf("decision must be %s, %s or %s", ActionApprove, ActionApproveSession, ActionDeny)
+	}
+
+	rec, err := b.store.GetToolApproval(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && rec.ArgsHash == "") {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get tool approval: %w", err)
+	}
+	if rec.Status == persistence.ApprovalPending && !rec.ExpiresAt.IsZero() && time.Now().After(rec.ExpiresAt) {
+		b.expire(ctx, rec)
+		return nil, fmt.Errorf("approval request %s expired", id)
+	}
+	reason = strings.TrimSpace(reason)
+	ok, err := b.store.ResolveToolApproval(ctx, id, status, reason)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("approval request %s already resolved", id)
+	}
+	rec.Status, rec.Reason = status, reason
+
+	decision := "allow"
+	if status == persistence.ApprovalDenied {
+		decision = "deny"
+	}
+	audit.Record(decision, "approval.tool", strings.ToLower(status), "", id)
+	b.publishResolved(rec, status, reason)
+	b.signal(id)
+	return rec, nil
+}
+
+// Pending returns approvals that are still waiting for a decision.
+func (b *Broker) Pending(ctx context.Context) ([]*persistence.ToolApproval, error) {
+	all, err := b.store.ListPendingToolApprovals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := all[:0]
+	for _, a := range all {
+		if !a.ExpiresAt.IsZero() && now.After(a.ExpiresAt) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func (b *Broker) register(id string) chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch, ok := b.waiters[id]
+	if !ok {
+		ch = make(chan struct{})
+		b.waiters[id] = ch
+	}
+	return ch
+}
+
+func (b *Broker) unregister(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.waiters, id)
+}
+
+func (b *Broker) signal(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ch, ok := b.waiters[id]; ok {
+		close(ch)
+		delete(b.waiters, id)
+	}
+}
+
+func (b *Broker) publish(topic string, payload any) {
+	if b.bus != nil {
+		b.bus.Publish(topic, payload)
+	}
+}
+
+func (b *Broker) publishResolved(rec *persistence.ToolApproval, status, reason string) {
+	b.publish(bus.TopicToolApprovalResolved, bus.ToolApprovalResolved{
+		RequestID: rec.ID,
+		AgentID:   rec.AgentID,
+		Tool:      rec.Tool,
+		Status:    status,
+		Reason:    reason,
+	})
+}
+
+func decisionFor(rec *persistence.ToolApproval) Decision {
+	d := Decision{Status: rec.Status, Reason: rec.Reason, ApprovalID: rec.ID}
+	switch rec.Status {
+	case persistence.ApprovalApproved, persistence.ApprovalApprovedSession:
+		d.Approved = true
+	case persistence.ApprovalExpired:
+		if d.Reason == "" {
+			d.Reason = "approval timed out"
+		}
+	default:
+		if d.Reason == "" {
+			d.Reason = "denied by operator"
+		}
+	}
+	return d
+}
+
+// renderArgs returns the redacted JSON shown to approvers and a hash of the
+// unredacted arguments used to match a re-run call to its request.
+func renderArgs(args any) (string, string) {
+	raw, err := json.Marshal(args)
+	if err != nil {
+		raw = []byte(fmt.Sprintf("%v", args))
+	}
+	sum := sha256.Sum256(raw)
+	rendered := shared.Redact(string(raw))
+	if len(rendered) > maxRenderedArgs {
+		rendered = rendered[:maxRenderedArgs] + "…"
+	}
+	return rendered, hex.EncodeToString(sum[:])
+}
+
+func subject(agentID, tool string) string {
+	return agentID + ":" + tool
+}
@@ -0,0 +1,264 @@
+package approval
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+func openTestStore(t *testing.T, path string) *persistence.Store {
+	t.Helper()
+	store, err := persistence.Open(path, nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+// requestAsync runs Request in the background and returns the published
+// request plus a channel with the outcome.
+func requestAsync(t *testing.T, b *Broker, sub *bus.Subscription, req Request) (bus.ToolApprovalRequest, <-chan Decision) {
+	t.Helper()
+	done := make(chan Decision, 1)
+	go func() {
+		d, err := b.Request(context.Background(), req)
+		if err != nil {
+			t.Errorf("Request: %v", err)
+		}
+		done <- d
+	}()
+	select {
+	case ev := <-sub.Ch():
+		return ev.Payload.(bus.ToolApprovalRequest), done
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for approval request event")
+		return bus.ToolApprovalRequest{}, nil
+	}
+}
+
+func waitDecision(t *testing.T, done <-chan Decision) Decision {
+	t.Helper()
+	select {
+	case d := <-done:
+		return d
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for decision")
+		return Decision{}
+	}
+}
+
+func TestBroker_Decisions(t *testing.T) {
+	tests := []struct {
+		action       string
+		reason       string
+		wantApproved bool
+		wantStatus   string
+		wantReason   string
+	}{
+		{ActionApprove, "", true, persistence.ApprovalApproved, ""},
+		{ActionDeny, "use git status instead", false, persistence.ApprovalDenied, "use git status instead"},
+		{"reject", "", false, persistence.ApprovalDenied, "denied by operator"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			eventBus := bus.New()
+			sub := eventBus.Subscribe(bus.TopicToolApprovalRequested)
+			defer eventBus.Unsubscribe(sub)
+			b := NewBroker(openTestStore(t, filepath.Join(t.TempDir(), "goclaw.db")), eventBus)
+
+			req, done := requestAsync(t, b, sub, Request{TaskID: "t1", AgentID: "coder", Tool: "exec", Args: map[string]string{"command": "rm -rf build"}, Timeout: time.Minute})
+			if req.Tool != "exec" || req.AgentID != "coder" || req.Args != `{"command":"rm -rf build"}` {
+				t.Fatalf("unexpected request event: %+v", req)
+			}
+			if _, err := b.Respond(context.Background(), req.RequestID, tt.action, tt.reason); err != nil {
+				t.Fatalf("Respond: %v", err)
+			}
+			d := waitDecision(t, done)
+			if d.Approved != tt.wantApproved || d.Status != tt.wantStatus || d.Reason != tt.wantReason {
+				t.Errorf("decision = %+v, want approved=%v status=%s reason=%q", d, tt.wantApproved, tt.wantStatus, tt.wantReason)
+			}
+			if _, err := b.Respond(context.Background(), req.RequestID, ActionApprove, ""); err == nil {
+				t.Error("expected error responding twice")
+			}
+		})
+	}
+}
+
+func TestBroker_Timeout(t *testing.T) {
+	b := NewBroker(openTestStore(t, filepath.Join(t.TempDir(), "goclaw.db")), nil)
+	d, err := b.Request(context.Background(), Request{TaskID: "t1", Tool: "exec", Args: "ls", Timeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Request: %v", err)
+	}
+	if d.Approved || d.Status != persistence.ApprovalExpired {
+		t.Errorf("decision = %+v, want expired denial", d)
+	}
+}
+
+func TestBroker_SessionGrant(t *testing.T) {
+	eventBus := bus.New()
+	sub := eventBus.Subscribe(bus.TopicToolApprovalRequested)
+	defer eventBus.Unsubscribe(sub)
+	b := NewBroker(openTestStore(t, filepath.Join(t.TempDir(), "goclaw.db")), eventBus)
+	ctx := context.Background()
+
+	req, done := requestAsync(t, b, sub, Request{TaskID: "t1", SessionID: "s1", AgentID: "coder", Tool: "write_file", Args: "a", Timeout: time.Minute})
+	if _, err := b.Respond(ctx, req.RequestID, ActionApproveSession, ""); err != nil {
+		t.Fatalf("Respond: %v", err)
+	}
+	waitDecision(t, done)
+
+	d, err := b.Request(ctx, Request{TaskID: "t2", SessionID: "s1", AgentID: "coder", Tool: "write_file", Args: "b"})
+	if err != nil || !d.Approved || d.ApprovalID != "" {
+		t.Fatalf("same session: decision = %+v, err %v; want session grant", d, err)
+	}
+
+	// Other sessions, agents and tools still ask.
+	for _, r := range []Request{
+		{SessionID: "s2", AgentID: "coder", Tool: "write_file"},
+		{SessionID: "s1", AgentID: "other", Tool: "write_file"},
+		{SessionID: "s1", AgentID: "coder", Tool: "exec"},
+	} {
+		r.Timeout = 10 * time.Millisecond
+		if d, _ := b.Request(ctx, r); d.Approved {
+			t.Errorf("%+v: unexpectedly approved by session grant", r)
+		}
+	}
+}
+
+// A task re-run after a restart resumes waiting on its pending request, and
+// a decision made while nothing was waiting is picked up by the next attempt.
+func TestBroker_SurvivesRestart(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "goclaw.db")
+	req := Request{TaskID: "t1", RunID: "run1", SessionID: "s1", AgentID: "coder", Tool: "exec", Args: map[string]string{"command": "make deploy"}, Timeout: time.Minute}
+
+	first := NewBroker(openTestStore(t, dbPath), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := first.Request(ctx, req)
+		errc <- err
+	}()
+	var id string
+	deadline := time.Now().Add(2 * time.Second)
+	for id == "" && time.Now().Before(deadline) {
+		pending, _ := first.Pending(context.Background())
+		if len(pending) == 1 {
+			id = pending[0].ID
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if id == "" {
+		t.Fatal("request was not persisted")
+	}
+	cancel() // daemon stops while the task is parked
+	if err := <-errc; err == nil {
+		t.Fatal("expected context error from interrupted request")
+	}
+
+	// The operator answers after the restart, before the task re-runs.
+	second := NewBroker(openTestStore(t, dbPath), nil)
+	if _, err := second.Respond(context.Background(), id, ActionDeny, "not on a Friday"); err != nil {
+		t.Fatalf("Respond after restart: %v", err)
+	}
+	req.RunID = "run2"
+	d, err := second.Request(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Request after restart: %v", err)
+	}
+	if d.Approved || d.ApprovalID != id || d.Reason != "not on a Friday" {
+		t.Errorf("decision = %+v, want the stored denial of %s", d, id)
+	}
+
+	// The decision is consumed; a further identical call asks again.
+	if d, _ := second.Request(context.Background(), Request{TaskID: "t1", Tool: "exec", Args: req.Args, Timeout: 10 * time.Millisecond}); d.ApprovalID == id {
+		t.Error("consumed decision was reused")
+	}
+}
+
+func TestBroker_RestartSupersedesOrphanedRequest(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "goclaw.db")
+	store := openTestStore(t, dbPath)
+	eventBus := bus.New()
+	sub := eventBus.Subscribe(bus.TopicToolApprovalRequested)
+	defer eventBus.Unsubscribe(sub)
+	b := NewBroker(store, eventBus)
+
+	// The first run parks on a call, then the daemon stops.
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := b.Request(ctx, Request{TaskID: "t1", RunID: "run1", Tool: "exec", Args: map[string]string{"command": "make deploy"}, Timeout: time.Minute})
+		errc <- err
+	}()
+	var orphan bus.ToolApprovalRequest
+	select {
+	case ev := <-sub.Ch():
+		orphan = ev.Payload.(bus.ToolApprovalRequest)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for approval request event")
+	}
+	cancel()
+	<-errc
+
+	// The re-run asks for a different call; the old request can no longer
+	// be answered and drops out of the pending list.
+	restarted := NewBroker(openTestStore(t, dbPath), eventBus)
+	ev, done := requestAsync(t, restarted, sub, Request{TaskID: "t1", RunID: "run2", Tool: "exec", Args: map[string]string{"command": "make deploy --dry-run"}, Timeout: time.Minute})
+	pending, err := restarted.Pending(context.Background())
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != ev.RequestID {
+		t.Fatalf("pending = %+v, want only %s", pending, ev.RequestID)
+	}
+	old, err := store.GetToolApproval(context.Background(), orphan.RequestID)
+	if err != nil {
+		t.Fatalf("GetToolApproval: %v", err)
+	}
+	if old.Status != persistence.ApprovalExpired || old.Reason != "superseded by a re-run of the task" {
+		t.Errorf("orphaned request = %s (%s), want EXPIRED as superseded", old.Status, old.Reason)
+	}
+	if _, err := restarted.Respond(context.Background(), orphan.RequestID, ActionApprove, ""); err == nil {
+		t.Error("approving the orphaned request should fail")
+	}
+	if _, err := restarted.Respond(context.Background(), ev.RequestID, ActionApprove, ""); err != nil {
+		t.Fatalf("Respond: %v", err)
+	}
+	if d := waitDecision(t, done); !d.Approved {
+		t.Errorf("decision = %+v, want approved", d)
+	}
+}
+
+func TestBroker_BusResponses(t *testing.T) {
+	eventBus := bus.New()
+	sub := eventBus.Subscribe(bus.TopicToolApprovalRequested)
+	defer eventBus.Unsubscribe(sub)
+	b := NewBroker(openTestStore(t, filepath.Join(t.TempDir(), "goclaw.db")), eventBus)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b.Start(ctx)
+
+	req, done := requestAsync(t, b, sub, Request{TaskID: "t1", Tool: "delegate_task", Args: "x", Timeout: time.Minute})
+	// Plan-step responses share the topic and must be ignored.
+	eventBus.Publish(bus.TopicHITLApprovalResponse, bus.HITLApprovalResponse{RequestID: "plan-step", Action: "approve"})
+	eventBus.Publish(bus.TopicHITLApprovalResponse, bus.HITLApprovalResponse{RequestID: req.RequestID, Action: "approve_session", Reason: "via Telegram (ops)"})
+	if d := waitDecision(t, done); !d.Approved || d.Status != persistence.ApprovalApprovedSession {
+		t.Errorf("decision = %+v, want session approval", d)
+	}
+}
+
+func TestBroker_RespondErrors(t *testing.T) {
+	b := NewBroker(openTestStore(t, filepath.Join(t.TempDir(), "goclaw.db")), nil)
+	if _, err := b.Respond(context.Background(), "missing", ActionApprove, ""); err != ErrNotFound {
+		t.Errorf("unknown id: err = %v, want ErrNotFound", err)
+	}
+	if _, err := b.Respond(context.Background(), "missing", "maybe", ""); err == nil {
+		t.Error("expected error for unknown decision")
+	}
+}
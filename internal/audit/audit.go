@@ -20,6 +20,7 @@ type entry struct {
 	Reason        string `json:"reason"`
 	PolicyVersion string `json:"policy_version"`
 	Subject       string `json:"subject,omitempty"`
+	Rule          string `json:"rule,omitempty"`
 }
 
 var (
@@ -71,6 +72,12 @@ func DenyCount() int64 {
 }
 
 func Record(decision, capability, reason, policyVersion, subject string) {
+	RecordRule(decision, capability, reason, policyVersion, subject, "")
+}
+
+// RecordRule is Record for decisions made by a named policy rule. The rule is
+// written to the JSONL entry and appended to the audit_log reason.
+func RecordRule(decision, capability, reason, policyVersion, subject, rule string) {
 	if decision == "deny" {
 		denyCount.Add(1)
 	}
@@ -91,6 +98,7 @@ func Record(decision, capability, reason, policyVersion, subject string) {
 			Reason:        reason,
 			PolicyVersion: policyVersion,
 			Subject:       subject,
+			Rule:          rule,
 		}
 		b, err := json.Marshal(ev)
 		if err == nil {
@@ -100,6 +108,9 @@ func Record(decision, capability, reason, policyVersion, subject string) {
 
 	// Write to audit_log table (GC-SPEC-OBS-003).
 	if db != nil {
+		if rule != "" {
+			reason += " rule=" + rule
+		}
 		_, _ = db.ExecContext(context.Background(), `
 			INSERT INTO audit_log (trace_id, subject, action, decision, reason, policy_version)
 			VALUES (?, ?, ?, ?, ?, ?);
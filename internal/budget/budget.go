@@ -0,0 +1,291 @@
+// Package budget enforces spend budgets per agent, gateway API key and session.
+//
+// Spend is read from the store (task_metrics for finished tasks, tasks for
+// unfinished ones), so budgets hold across restarts. Budgets are checked when
+// a task is submitted, before a claimed task runs and before each model call.
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/config"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/shared"
+)
+
+// ErrExceeded is wrapped by the errors returned once a budget is exhausted.
+var ErrExceeded = errors.New("budget exceeded")
+
+// Budget periods, in UTC calendar days and months.
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// Subject identifies what a task or model call is charged to. Empty fields
+// are not checked.
+type Subject struct {
+	AgentID   string
+	SessionID string
+	APIKeyID  string
+}
+
+// SubjectFromContext builds the subject of a model call from the agent,
+// session and API key IDs on the context.
+func SubjectFromContext(ctx context.Context) Subject {
+	agentID := shared.AgentID(ctx)
+	if agentID == "" {
+		agentID = shared.DefaultAgentID
+	}
+	return Subject{
+		AgentID:   agentID,
+		SessionID: shared.SessionID(ctx),
+		APIKeyID:  shared.APIKeyID(ctx),
+	}
+}
+
+// ExceededError reports the budget that was hit.
+type ExceededError struct {
+	Scope  persistence.SpendScope
+	ID     string
+	Period string
+	// Tokens is set when the token limit was hit rather than the USD one.
+	Tokens bool
+	Used   float64
+	Limit  float64
+}
+
+func (e *ExceededError) Error() string {
+	if e.Tokens {
+		return fmt.Sprintf("budget exceeded: %s %s used %.0f of %.0f %s tokens", e.Scope, e.ID, e.Used, e.Limit, e.Period)
+	}
+	return fmt.Sprintf("budget exceeded: %s %s spent $%.4f of $%.2f %s budget", e.Scope, e.ID, e.Used, e.Limit, e.Period)
+}
+
+func (e *ExceededError) Unwrap() error { return ErrExceeded }
+
+// Usage is the current spend against one configured budget period.
+type Usage struct {
+	Scope       persistence.SpendScope `json:"scope"`
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name,omitempty"` // API key description
+	Period      string                 `json:"period"`
+	ResetsAt    time.Time              `json:"resets_at"`
+	UsedUSD     float64                `json:"used_usd"`
+	LimitUSD    float64                `json:"limit_usd,omitempty"`
+	UsedTokens  int                    `json:"used_tokens"`
+	LimitTokens int                    `json:"limit_tokens,omitempty"`
+	Exceeded    bool                   `json:"exceeded"`
+}
+
+type keyBudget struct {
+	name string
+	cfg  config.BudgetConfig
+}
+
+// Enforcer checks spend against the configured budgets. A nil Enforcer
+// enforces nothing.
+type Enforcer struct {
+	store *persistence.Store
+	bus   *bus.Bus
+	now   func() time.Time
+
+	mu      sync.RWMutex
+	agents  map[string]config.BudgetConfig
+	keys    map[string]keyBudget // by config.APIKeyEntry.ID
+	session *config.BudgetConfig
+
+	alertMu sync.Mutex
+	alerted map[string]time.Time // budget → reset time of the period last alerted
+}
+
+// New creates an enforcer without budgets; call Configure to load them.
+func New(store *persistence.Store, b *bus.Bus) *Enforcer {
+	return &Enforcer{
+		store:   store,
+		bus:     b,
+		now:     time.Now,
+		agents:  map[string]config.BudgetConfig{},
+		keys:    map[string]keyBudget{},
+		alerted: map[string]time.Time{},
+	}
+}
+
+// Configure replaces the budgets with those of cfg. It is called at startup
+// and on config reload.
+func (e *Enforcer) Configure(cfg config.Config) {
+	agents := map[string]config.BudgetConfig{}
+	for _, a := range cfg.Agents {
+		if a.Budget != nil {
+			agents[a.AgentID] = *a.Budget
+		}
+	}
+	keys := map[string]keyBudget{}
+	for _, k := range cfg.Gateway.Auth.Keys {
+		if k.Budget != nil {
+			keys[k.ID()] = keyBudget{name: k.Description, cfg: *k.Budget}
+		}
+	}
+	var session *config.BudgetConfig
+	if cfg.SessionBudget != nil {
+		b := *cfg.SessionBudget
+		session = &b
+	}
+
+	e.mu.Lock()
+	e.agents, e.keys, e.session = agents, keys, session
+	e.mu.Unlock()
+}
+
+// Check returns an error wrapping ErrExceeded when a budget of the subject is
+// exhausted. pending is usage not yet recorded in the store, such as the
+// earlier model calls of a running task. The first time a budget is hit in a
+// period, an alert is published on bus.TopicAgentAlert.
+func (e *Enforcer) Check(ctx context.Context, s Subject, pending shared.TokenUsage) error {
+	if e == nil {
+		return nil
+	}
+	for _, b := range e.budgetsFor(s) {
+		for _, u := range e.periodUsage(ctx, b, pending) {
+			if !u.Exceeded {
+				continue
+			}
+			err := &ExceededError{Scope: u.Scope, ID: u.ID, Period: u.Period, Used: u.UsedUSD, Limit: u.LimitUSD}
+			if u.LimitTokens > 0 && u.UsedTokens >= u.LimitTokens {
+				err.Tokens = true
+				err.Used, err.Limit = float64(u.UsedTokens), float64(u.LimitTokens)
+			}
+			e.alert(u, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Usage reports the spend against every agent and API key budget, and
+// against the session budget for sessionID when it is non-empty.
+func (e *Enforcer) Usage(ctx context.Context, sessionID string) []Usage {
+	if e == nil {
+		return []Usage{}
+	}
+	var subjects []scopedBudget
+	e.mu.RLock()
+	for id, cfg := range e.agents {
+		subjects = append(subjects, scopedBudget{scope: persistence.SpendScopeAgent, id: id, cfg: cfg})
+	}
+	for id, k := range e.keys {
+		subjects = append(subjects, scopedBudget{scope: persistence.SpendScopeAPIKey, id: id, name: k.name, cfg: k.cfg})
+	}
+	if e.session != nil && sessionID != "" {
+		subjects = append(subjects, scopedBudget{scope: persistence.SpendScopeSession, id: sessionID, cfg: *e.session})
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(subjects, func(i, j int) bool {
+		if subjects[i].scope != subjects[j].scope {
+			return subjects[i].scope < subjects[j].scope
+		}
+		return subjects[i].id < subjects[j].id
+	})
+	out := []Usage{}
+	for _, b := range subjects {
+		out = append(out, e.periodUsage(ctx, b, shared.TokenUsage{})...)
+	}
+	return out
+}
+
+type scopedBudget struct {
+	scope persistence.SpendScope
+	id    string
+	name  string
+	cfg   config.BudgetConfig
+}
+
+func (e *Enforcer) budgetsFor(s Subject) []scopedBudget {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var out []scopedBudget
+	if cfg, ok := e.agents[s.AgentID]; ok && s.AgentID != "" {
+		out = append(out, scopedBudget{scope: persistence.SpendScopeAgent, id: s.AgentID, cfg: cfg})
+	}
+	if k, ok := e.keys[s.APIKeyID]; ok && s.APIKeyID != "" {
+		out = append(out, scopedBudget{scope: persistence.SpendScopeAPIKey, id: s.APIKeyID, name: k.name, cfg: k.cfg})
+	}
+	if e.session != nil && s.SessionID != "" {
+		out = append(out, scopedBudget{scope: persistence.SpendScopeSession, id: s.SessionID, cfg: *e.session})
+	}
+	return out
+}
+
+// periodUsage reads the daily and monthly spend of a budget, for the periods
+// it sets limits for. Store errors are logged and the period is skipped, so a
+// failing read never blocks work.
+func (e *Enforcer) periodUsage(ctx context.Context, b scopedBudget, pending shared.TokenUsage) []Usage {
+	now := e.now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periods := []struct {
+		name       string
+		start, end time.Time
+		usd        float64
+		tokens     int
+	}{
+		{PeriodDaily, day, day.AddDate(0, 0, 1), b.cfg.DailyUSD, b.cfg.DailyTokens},
+		{PeriodMonthly, month, month.AddDate(0, 1, 0), b.cfg.MonthlyUSD, b.cfg.MonthlyTokens},
+	}
+
+	var out []Usage
+	for _, p := range periods {
+		if p.usd <= 0 && p.tokens <= 0 {
+			continue
+		}
+		spent, err := e.store.Spend(ctx, b.scope, b.id, p.start)
+		if err != nil {
+			slog.Warn("budget: read spend failed", "scope", b.scope, "id", b.id, "error", err)
+			continue
+		}
+		u := Usage{
+			Scope:       b.scope,
+			ID:          b.id,
+			Name:        b.name,
+			Period:      p.name,
+			ResetsAt:    p.end,
+			UsedUSD:     spent.CostUSD + pending.CostUSD,
+			LimitUSD:    p.usd,
+			UsedTokens:  spent.TotalTokens() + pending.InputTokens + pending.OutputTokens,
+			LimitTokens: p.tokens,
+		}
+		u.Exceeded = (u.LimitUSD > 0 && u.UsedUSD >= u.LimitUSD) ||
+			(u.LimitTokens > 0 && u.UsedTokens >= u.LimitTokens)
+		out = append(out, u)
+	}
+	return out
+}
+
+// alert publishes an operator alert once per budget and period.
+func (e *Enforcer) alert(u Usage, err error) {
+	key := fmt.Sprintf("%s/%s/%s", u.Scope, u.ID, u.Period)
+	e.alertMu.Lock()
+	if e.alerted[key].Equal(u.ResetsAt) {
+		e.alertMu.Unlock()
+		return
+	}
+	e.alerted[key] = u.ResetsAt
+	e.alertMu.Unlock()
+
+	slog.Warn("budget exceeded", "scope", u.Scope, "id", u.ID, "period", u.Period,
+		"used_usd", u.UsedUSD, "limit_usd", u.LimitUSD, "used_tokens", u.UsedTokens, "limit_tokens", u.LimitTokens)
+	if e.bus != nil {
+		e.bus.Publish(bus.TopicAgentAlert, bus.AgentAlert{
+			Severity: "error",
+			Message:  err.Error() + "; new work is refused until " + u.ResetsAt.Format(time.RFC3339),
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/config"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/shared"
+)
+
+const (
+	s1 = "11111111-1111-4111-8111-111111111111"
+	s2 = "22222222-2222-4222-8222-222222222222"
+)
+
+func openStore(t *testing.T) *persistence.Store {
+	t.Helper()
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "goclaw.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+// spend records a task for the agent and session, submitted with keyID,
+// that used the given tokens and cost. Finished tasks are snapshotted into
+// task_metrics.
+func spend(t *testing.T, store *persistence.Store, agentID, sessionID, keyID string, tokens int, cost float64, finished bool) {
+	t.Helper()
+	ctx := shared.WithAPIKeyID(context.Background(), keyID)
+	if err := store.EnsureSession(ctx, sessionID); err != nil {
+		t.Fatalf("ensure session: %v", err)
+	}
+	taskID, err := store.CreateTaskForAgent(ctx, agentID, sessionID, `{"content":"hi"}`)
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if err := store.UpdateTaskTokens(ctx, taskID, persistence.TaskUsage{PromptTokens: tokens, CostUSD: cost}); err != nil {
+		t.Fatalf("update tokens: %v", err)
+	}
+	if finished {
+		if err := store.RecordTaskMetrics(ctx, taskID); err != nil {
+			t.Fatalf("record metrics: %v", err)
+		}
+	}
+}
+
+func TestEnforcer_Check(t *testing.T) {
+	store := openStore(t)
+	key := config.APIKeyEntry{Key: "sk-team", Description: "team", Budget: &config.BudgetConfig{MonthlyTokens: 5000}}
+	spend(t, store, "coder", s1, key.ID(), 1000, 4.00, true)
+	spend(t, store, "coder", s2, key.ID(), 3000, 0.50, false)
+	spend(t, store, "writer", s2, "", 500, 0.10, true)
+
+	e := New(store, nil)
+	e.Configure(config.Config{
+		Agents: []config.AgentConfigEntry{
+			{AgentID: "coder", Budget: &config.BudgetConfig{DailyUSD: 5}},
+			{AgentID: "writer"},
+		},
+		Gateway:       config.GatewaySecurityConfig{Auth: config.AuthConfig{Keys: []config.APIKeyEntry{key}}},
+		SessionBudget: &config.BudgetConfig{DailyTokens: 3400},
+	})
+
+	tests := []struct {
+		name      string
+		subject   Subject
+		pending   shared.TokenUsage
+		wantScope persistence.SpendScope // empty = within budget
+	}{
+		{"agent within budget", Subject{AgentID: "coder"}, shared.TokenUsage{}, ""},
+		{"agent pending usage exceeds", Subject{AgentID: "coder"}, shared.TokenUsage{CostUSD: 0.50}, persistence.SpendScopeAgent},
+		{"agent without budget", Subject{AgentID: "writer"}, shared.TokenUsage{CostUSD: 100}, ""},
+		{"session within budget", Subject{SessionID: s1}, shared.TokenUsage{}, ""},
+		{"session over token budget", Subject{SessionID: s2}, shared.TokenUsage{}, persistence.SpendScopeSession},
+		{"api key within budget", Subject{APIKeyID: key.ID()}, shared.TokenUsage{InputTokens: 900}, ""},
+		{"api key over token budget", Subject{APIKeyID: key.ID()}, shared.TokenUsage{InputTokens: 1000}, persistence.SpendScopeAPIKey},
+		{"unknown api key", Subject{APIKeyID: "key-unknown"}, shared.TokenUsage{InputTokens: 1e6}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := e.Check(context.Background(), tt.subject, tt.pending)
+			if tt.wantScope == "" {
+				if err != nil {
+					t.Fatalf("Check() = %v, want nil", err)
+				}
+				return
+			}
+			var exceeded *ExceededError
+			if !errors.As(err, &exceeded) || !errors.Is(err, ErrExceeded) {
+				t.Fatalf("Check() = %v, want an ExceededError", err)
+			}
+			if exceeded.Scope != tt.wantScope {
+				t.Errorf("scope = %q, want %q", exceeded.Scope, tt.wantScope)
+			}
+		})
+	}
+
+	var nilEnforcer *Enforcer
+	if err := nilEnforcer.Check(context.Background(), Subject{AgentID: "coder"}, shared.TokenUsage{}); err != nil {
+		t.Errorf("nil enforcer Check() = %v, want nil", err)
+	}
+}
+
+func TestEnforcer_AlertsOncePerPeriod(t *testing.T) {
+	store := openStore(t)
+	spend(t, store, "coder", s1, "", 100, 2.00, true)
+
+	eventBus := bus.New()
+	sub := eventBus.Subscribe(bus.TopicAgentAlert)
+	defer eventBus.Unsubscribe(sub)
+
+	e := New(store, eventBus)
+	e.Configure(config.Config{Agents: []config.AgentConfigEntry{
+		{AgentID: "coder", Budget: &config.BudgetConfig{DailyUSD: 1}},
+	}})
+	for i := 0; i < 3; i++ {
+		if err := e.Check(context.Background(), Subject{AgentID: "coder"}, shared.TokenUsage{}); !errors.Is(err, ErrExceeded) {
+			t.Fatalf("Check() = %v, want ErrExceeded", err)
+		}
+	}
+
+	select {
+	case ev := <-sub.Ch():
+		alert, ok := ev.Payload.(bus.AgentAlert)
+		if !ok || alert.Severity != "error" {
+			t.Fatalf("alert = %#v, want an error AgentAlert", ev.Payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no alert published")
+	}
+	select {
+	case ev := <-sub.Ch():
+		t.Fatalf("unexpected second alert: %#v", ev.Payload)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The daily budget resets the next day.
+	e.now = func() time.Time { return time.Now().Add(24 * time.Hour) }
+	if err := e.Check(context.Background(), Subject{AgentID: "coder"}, shared.TokenUsage{}); err != nil {
+		t.Errorf("Check() the next day = %v, want nil", err)
+	}
+}
+
+func TestEnforcer_Usage(t *testing.T) {
+	store := openStore(t)
+	spend(t, store, "coder", s1, "", 1200, 0.75, true)
+
+	e := New(store, nil)
+	e.Configure(config.Config{
+		Agents:        []config.AgentConfigEntry{{AgentID: "coder", Budget: &config.BudgetConfig{DailyUSD: 1, MonthlyTokens: 1000}}},
+		SessionBudget: &config.BudgetConfig{DailyUSD: 10},
+	})
+
+	got := e.Usage(context.Background(), s1)
+	if len(got) != 3 {
+		t.Fatalf("Usage() returned %d entries, want 3: %+v", len(got), got)
+	}
+	daily, monthly, session := got[0], got[1], got[2]
+	if daily.Scope != persistence.SpendScopeAgent || daily.Period != PeriodDaily || daily.UsedUSD != 0.75 || daily.Exceeded {
+		t.Errorf("agent daily usage = %+v", daily)
+	}
+	if monthly.Period != PeriodMonthly || monthly.UsedTokens != 1200 || !monthly.Exceeded {
+		t.Errorf("agent monthly usage = %+v", monthly)
+	}
+	if session.Scope != persistence.SpendScopeSession || session.ID != s1 || session.LimitUSD != 10 {
+		t.Errorf("session usage = %+v", session)
+	}
+	if !daily.ResetsAt.After(time.Now()) {
+		t.Errorf("resets_at = %v, want a future time", daily.ResetsAt)
+	}
+
+	if got := e.Usage(context.Background(), ""); len(got) != 2 {
+		t.Errorf("Usage() without a session returned %d entries, want 2", len(got))
+	}
+}
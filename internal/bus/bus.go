@@ -38,6 +38,12 @@ const (
 	TopicPlanExecutionCompleted = "plan.execution.completed"
 	TopicPlanStepStarted        = "plan.step.started"
 	TopicPlanStepCompleted      = "plan.step.completed"
+
+	// Execution controls: pause, resume, cancel and re-run from a step.
+	TopicPlanExecutionPaused   = "plan.execution.paused"
+	TopicPlanExecutionResumed  = "plan.execution.resumed"
+	TopicPlanExecutionCanceled = "plan.execution.canceled"
+	TopicPlanExecutionRerun    = "plan.execution.rerun"
 )
 
 // Streaming event topics.
@@ -87,9 +93,12 @@ type TaskMetricsEvent struct {
 
 // TaskTokensEvent is published when task token counts are updated.
 type TaskTokensEvent struct {
-	TaskID           string // Task ID
-	PromptTokens     int    // Prompt tokens
-	CompletionTokens int    // Completion tokens
+	TaskID           string  // Task ID
+	PromptTokens     int     // Prompt tokens
+	CompletionTokens int     // Completion tokens
+	CachedTokens     int     // Prompt tokens served from cache
+	ReasoningTokens  int     // Completion tokens spent on reasoning
+	CostUSD          float64 // Cost in USD
 }
 
 // Subscription represents an active subscription.
@@ -1,5 +1,7 @@
 package bus
 
+import "time"
+
 // Additional plan step event topics.
 // GC-SPEC-PDR-v7-Phase-3: Event contract for plan execution (TopicPlanStepStarted/Completed defined in bus.go).
 const (
@@ -13,6 +15,13 @@ const (
 	TopicHITLApprovalResponse  = "hitl.approval.response"
 )
 
+// Tool-call approval topics. Responses arrive on TopicHITLApprovalResponse
+// with Action "approve", "approve_session" or "deny" ("reject" is accepted).
+const (
+	TopicToolApprovalRequested = "hitl.tool_approval.requested"
+	TopicToolApprovalResolved  = "hitl.tool_approval.resolved"
+)
+
 // Agent alert topic.
 // GC-SPEC-PDR-v7-Phase-3: Agent alert notifications.
 const (
@@ -67,3 +76,36 @@ type AgentAlert struct {
 	Severity    string // "info", "warning", or "error"
 	Message     string // Alert message
 }
+
+// MCP event topics.
+const (
+	TopicMCPToolsChanged = "mcp.tools_changed"
+)
+
+// MCPToolsChangedEvent is published after an MCP server reports that its
+// tool list changed and the new list has been fetched.
+type MCPToolsChangedEvent struct {
+	Server    string   `json:"server"`
+	ToolCount int      `json:"tool_count"`
+	AgentIDs  []string `json:"agent_ids"` // agents bound to the server
+}
+
+// ToolApprovalRequest is published when a tool call waits on a human decision.
+type ToolApprovalRequest struct {
+	RequestID string    `json:"request_id"`
+	TaskID    string    `json:"task_id"`
+	SessionID string    `json:"session_id"`
+	AgentID   string    `json:"agent_id"`
+	Tool      string    `json:"tool"`
+	Args      string    `json:"args"` // rendered arguments
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ToolApprovalResolved is published once a tool approval is decided or expires.
+type ToolApprovalResolved struct {
+	RequestID string `json:"request_id"`
+	AgentID   string `json:"agent_id"`
+	Tool      string `json:"tool"`
+	Status    string `json:"status"` // APPROVED, APPROVED_SESSION, DENIED or EXPIRED
+	Reason    string `json:"reason,omitempty"`
+}
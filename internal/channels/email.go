@@ -0,0 +1,745 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/config"
+	"github.com/basket/go-claw/internal/engine"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultEmailAttachAfter  = 8000
+	defaultEmailPollInterval = 60 * time.Second
+	maxEmailBytes            = 10 << 20
+)
+
+// EmailChannel implements the Channel interface for email. Mail is read by
+// polling an IMAP mailbox and/or on a local SMTP listener, and each reply is
+// sent through SMTP in the same thread. Mail to Address goes to the default
+// agent and mail to its +<agent> subaddress (agents+coder@example.com) to
+// that agent. Threads are followed through Message-ID, In-Reply-To and
+// References, so a reply to the agent's answer continues the same session.
+type EmailChannel struct {
+	cfg      config.EmailConfig
+	local    string            // local part of cfg.Address
+	domain   string            // domain of cfg.Address
+	agents   map[string]string // further address -> agent
+	allowed  map[string]struct{}
+	router   engine.ChatTaskRouter
+	store    *persistence.Store
+	logger   *slog.Logger
+	eventBus *bus.Bus
+
+	mu      sync.Mutex
+	pending map[string]emailThread // taskID -> where to reply
+	ln      net.Listener           // SMTP listener, once started
+}
+
+// emailThread is the message a reply answers.
+type emailThread struct {
+	sessionID  string
+	from       string // the address the message was sent to; replies come from it
+	to         string // the sender
+	subject    string
+	messageID  string
+	references string
+}
+
+// NewEmailChannel creates an email channel. eventBus delivers task results;
+// it must not be nil.
+func NewEmailChannel(cfg config.EmailConfig, router engine.ChatTaskRouter, store *persistence.Store, logger *slog.Logger, eventBus *bus.Bus) (*EmailChannel, error) {
+	addr, err := mail.ParseAddress(cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("email address %q: %w", cfg.Address, err)
+	}
+	local, domain, _ := strings.Cut(strings.ToLower(addr.Address), "@")
+	if cfg.IMAP.Addr == "" && cfg.Listen == "" {
+		return nil, fmt.Errorf("email channel needs imap.addr or listen")
+	}
+	if cfg.SMTP.Addr == "" {
+		return nil, fmt.Errorf("email channel needs smtp.addr to send replies")
+	}
+	if eventBus == nil {
+		return nil, fmt.Errorf("email channel needs an event bus")
+	}
+	cfg.AuthservID = strings.ToLower(strings.TrimSpace(cfg.AuthservID))
+	if cfg.InsecureSkipSenderAuth {
+		cfg.AuthservID = "" // senderVerified skips Authentication-Results
+	} else if cfg.AuthservID == "" {
+		return nil, fmt.Errorf("email channel needs authserv_id (or insecure_skip_sender_auth) to verify senders")
+	}
+	if cfg.DefaultAgent == "" {
+		cfg.DefaultAgent = "default"
+	}
+	if cfg.IMAP.Mailbox == "" {
+		cfg.IMAP.Mailbox = "INBOX"
+	}
+	if cfg.AttachAfter <= 0 {
+		cfg.AttachAfter = defaultEmailAttachAfter
+	}
+	e := &EmailChannel{
+		cfg:      cfg,
+		local:    local,
+		domain:   domain,
+		agents:   make(map[string]string),
+		allowed:  make(map[string]struct{}),
+		router:   router,
+		store:    store,
+		logger:   logger,
+		eventBus: eventBus,
+		pending:  make(map[string]emailThread),
+	}
+	for address, agentID := range cfg.Agents {
+		e.agents[strings.ToLower(strings.TrimSpace(address))] = agentID
+	}
+	for _, sender := range cfg.AllowedSenders {
+		if sender = strings.ToLower(strings.TrimSpace(sender)); sender != "" {
+			e.allowed[sender] = struct{}{}
+		}
+	}
+	return e, nil
+}
+
+func (e *EmailChannel) Name() string {
+	return "email"
+}
+
+// Start polls the IMAP mailbox and serves the SMTP listener, whichever are
+// configured, until ctx is canceled.
+func (e *EmailChannel) Start(ctx context.Context) error {
+	if len(e.allowed) == 0 {
+		e.logger.Warn("email channel has no allowed_senders; all mail will be ignored")
+	}
+	if e.cfg.InsecureSkipSenderAuth {
+		e.logger.Warn("email channel skips Authentication-Results; senders are only checked against the envelope")
+	}
+	go e.monitorCompletions(ctx)
+
+	var wg sync.WaitGroup
+	if e.cfg.Listen != "" {
+		ln, err := net.Listen("tcp", e.cfg.Listen)
+		if err != nil {
+			return fmt.Errorf("email listen: %w", err)
+		}
+		e.mu.Lock()
+		e.ln = ln
+		e.mu.Unlock()
+		srv := &smtpServer{
+			hostname: e.domain,
+			accept:   func(rcpt string) bool { _, ok := e.route(rcpt); return ok },
+			deliver: func(from string, rcpts []string, data []byte) {
+				e.handleMessage(ctx, data, from, rcpts)
+			},
+			logger: e.logger,
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			srv.serve(ctx, ln)
+		}()
+		e.logger.Info("email SMTP listener started", "addr", ln.Addr().String())
+	}
+	if e.cfg.IMAP.Addr != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.pollIMAP(ctx)
+		}()
+	}
+	<-ctx.Done()
+	wg.Wait()
+	return nil
+}
+
+// listenAddr returns the address of the SMTP listener, or "" before it is up.
+func (e *EmailChannel) listenAddr() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.ln == nil {
+		return ""
+	}
+	return e.ln.Addr().String()
+}
+
+// pollIMAP fetches new mail every poll interval.
+func (e *EmailChannel) pollIMAP(ctx context.Context) {
+	interval := defaultEmailPollInterval
+	if e.cfg.IMAP.PollInterval > 0 {
+		interval = time.Duration(e.cfg.IMAP.PollInterval) * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := e.fetchIMAP(ctx); err != nil && ctx.Err() == nil {
+			e.logger.Warn("email IMAP poll failed", "addr", e.cfg.IMAP.Addr, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchIMAP handles the unseen messages in the mailbox and marks them seen.
+func (e *EmailChannel) fetchIMAP(ctx context.Context) error {
+	c, err := dialIMAP(ctx, e.cfg.IMAP.Addr, e.cfg.IMAP.Insecure)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+	if err := c.login(e.cfg.IMAP.Username, e.cfg.IMAP.Password); err != nil {
+		return err
+	}
+	if err := c.selectMailbox(e.cfg.IMAP.Mailbox); err != nil {
+		return err
+	}
+	uids, err := c.searchUnseen()
+	if err != nil {
+		return err
+	}
+	for _, uid := range uids {
+		if ctx.Err() != nil {
+			break
+		}
+		raw, err := c.fetch(uid)
+		if err != nil {
+			return err
+		}
+		e.handleMessage(ctx, raw, "", nil)
+		if err := c.markSeen(uid); err != nil {
+			return err
+		}
+	}
+	return c.logout()
+}
+
+// route returns the agent for a recipient address.
+func (e *EmailChannel) route(address string) (string, bool) {
+	address = strings.ToLower(strings.Trim(strings.TrimSpace(address), "<>"))
+	if agentID, ok := e.agents[address]; ok {
+		return agentID, true
+	}
+	local, domain, ok := strings.Cut(address, "@")
+	if !ok || domain != e.domain {
+		return "", false
+	}
+	base, sub, _ := strings.Cut(local, "+")
+	if base != e.local {
+		return "", false
+	}
+	if sub != "" {
+		return sub, true
+	}
+	return e.cfg.DefaultAgent, true
+}
+
+// isAllowed reports whether mail from address is accepted: the address or
+// its @domain must be listed.
+func (e *EmailChannel) isAllowed(address string) bool {
+	address = strings.ToLower(address)
+	if _, ok := e.allowed[address]; ok {
+		return true
+	}
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		_, ok := e.allowed[address[at:]]
+		return ok
+	}
+	return false
+}
+
+// emailMessageKey is the KV key holding the session of a message in a thread,
+// for the messages received and the replies sent.
+func emailMessageKey(messageID string) string {
+	return "email_msg:" + messageID
+}
+
+// senderVerified reports whether a message really comes from its From
+// address, and why not. The envelope sender must be the same address, and
+// the topmost Authentication-Results must come from authservID, the
+// receiving MTA, and record an SPF, DKIM or DMARC pass for the From domain.
+// Lower ones and those of other servers can be forged by the sender. An
+// empty authservID skips the Authentication-Results check.
+func senderVerified(header mail.Header, envelope, from, authservID string) (bool, string) {
+	envelope = strings.ToLower(strings.Trim(strings.TrimSpace(envelope), "<>"))
+	if envelope == "" {
+		return false, "no envelope sender"
+	}
+	if envelope != from {
+		return false, "envelope sender " + envelope + " differs from From"
+	}
+	if authservID == "" {
+		return true, ""
+	}
+	results := header.Get("Authentication-Results")
+	if results == "" {
+		return false, "no Authentication-Results"
+	}
+	if id := authResultsServer(results); id != authservID {
+		return false, "Authentication-Results from " + id + ", not " + authservID
+	}
+	_, domain, _ := strings.Cut(from, "@")
+	if !authResultsPass(results, domain) {
+		return false, "no SPF, DKIM or DMARC pass for " + domain
+	}
+	return true, ""
+}
+
+// authResultsComment matches RFC 8601 comments, e.g. "(1024-bit key)".
+var authResultsComment = regexp.MustCompile(`\([^()]*\)`)
+
+// authResultsServer returns the authserv-id of an Authentication-Results
+// value, in lower case.
+func authResultsServer(value string) string {
+	head, _, _ := strings.Cut(authResultsComment.ReplaceAllString(value, " "), ";")
+	if fields := strings.Fields(head); len(fields) > 0 {
+		return strings.ToLower(fields[0])
+	}
+	return ""
+}
+
+// authResultsPass reports whether an Authentication-Results value records a
+// DKIM, SPF or DMARC pass for domain or a parent domain of it.
+func authResultsPass(value, domain string) bool {
+	value = strings.ToLower(authResultsComment.ReplaceAllString(value, " "))
+	parts := strings.Split(value, ";")
+	for _, part := range parts[1:] { // parts[0] is the authserv-id
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		method, result, _ := strings.Cut(fields[0], "=")
+		if result != "pass" {
+			continue
+		}
+		props := make(map[string]string)
+		for _, f := range fields[1:] {
+			if k, v, ok := strings.Cut(f, "="); ok {
+				props[k] = strings.Trim(v, `"`)
+			}
+		}
+		var passed string
+		switch method {
+		case "dkim":
+			passed = props["header.d"]
+		case "spf":
+			passed = props["smtp.mailfrom"]
+		case "dmarc":
+			passed = props["header.from"]
+		}
+		if at := strings.LastIndex(passed, "@"); at >= 0 {
+			passed = passed[at+1:]
+		}
+		if passed != "" && (passed == domain || strings.HasSuffix(domain, "."+passed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleMessage turns a received message into a chat task. envelope and
+// rcpts are the SMTP envelope sender and recipients; when rcpts is empty
+// (IMAP) the Return-Path added at final delivery and the To and Cc headers
+// are used.
+func (e *EmailChannel) handleMessage(ctx context.Context, raw []byte, envelope string, rcpts []string) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		e.logger.Warn("email: unreadable message", "error", err)
+		return
+	}
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		e.logger.Warn("email: message without a valid From", "error", err)
+		return
+	}
+	sender := strings.ToLower(from.Address)
+	if _, own := e.route(sender); own {
+		return
+	}
+	// Out-of-office notices and other automatic mail would loop (RFC 3834).
+	if auto := msg.Header.Get("Auto-Submitted"); auto != "" && !strings.EqualFold(auto, "no") {
+		e.logger.Info("email: ignoring automatic message", "from", sender)
+		return
+	}
+	if !e.isAllowed(sender) {
+		e.logger.Warn("email: sender not allowed", "from", sender)
+		return
+	}
+	if len(rcpts) == 0 {
+		envelope = msg.Header.Get("Return-Path")
+	}
+	if ok, reason := senderVerified(msg.Header, envelope, sender, e.cfg.AuthservID); !ok {
+		e.logger.Warn("email: sender not verified", "from", sender, "reason", reason)
+		return
+	}
+
+	if len(rcpts) == 0 {
+		for _, h := range []string{"To", "Cc", "Delivered-To"} {
+			if list, err := msg.Header.AddressList(h); err == nil {
+				for _, a := range list {
+					rcpts = append(rcpts, a.Address)
+				}
+			}
+		}
+	}
+	agentID, replyFrom := e.cfg.DefaultAgent, e.local+"@"+e.domain
+	for _, rcpt := range rcpts {
+		if id, ok := e.route(rcpt); ok {
+			agentID, replyFrom = id, strings.ToLower(strings.Trim(rcpt, "<> "))
+			break
+		}
+	}
+
+	messageID := strings.TrimSpace(msg.Header.Get("Message-ID"))
+	if messageID == "" {
+		messageID = "<" + uuid.NewSHA1(uuid.NameSpaceOID, raw).String() + "@goclaw>"
+	}
+	if seen, err := e.store.KVGet(ctx, emailMessageKey(messageID)); err != nil {
+		e.logger.Warn("email: failed to look up message", "error", err)
+		return
+	} else if seen != "" {
+		return // delivered twice, e.g. by IMAP after a failed STORE
+	}
+
+	body, err := emailText(textproto.MIMEHeader(msg.Header), msg.Body)
+	if err != nil {
+		e.logger.Warn("email: failed to read body", "from", sender, "error", err)
+		return
+	}
+	subject := decodeEmailHeader(msg.Header.Get("Subject"))
+	references := strings.TrimSpace(msg.Header.Get("References"))
+
+	// Continue the session of the message this one answers, if we know it.
+	sessionID := ""
+	parents := strings.Fields(references)
+	if inReplyTo := strings.TrimSpace(msg.Header.Get("In-Reply-To")); inReplyTo != "" {
+		parents = append(parents, inReplyTo)
+	}
+	for i := len(parents) - 1; i >= 0 && sessionID == ""; i-- {
+		sessionID, _ = e.store.KVGet(ctx, emailMessageKey(parents[i]))
+	}
+	content := stripQuotedReply(body)
+	if sessionID == "" {
+		key := fmt.Sprintf("goclaw:email:%s:agent:%s:%s", sender, agentID, messageID)
+		sessionID = uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)).String()
+		if subject != "" {
+			content = subject + "\n\n" + content
+		}
+	}
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+	if err := e.store.KVSet(ctx, emailMessageKey(messageID), sessionID); err != nil {
+		e.logger.Warn("email: failed to record message", "error", err)
+		return
+	}
+
+	taskID, err := e.router.CreateChatTask(ctx, agentID, sessionID, content)
+	if err != nil {
+		e.logger.Error("failed to create email task", "from", sender, "agent", agentID, "error", err)
+		return
+	}
+	e.mu.Lock()
+	e.pending[taskID] = emailThread{
+		sessionID:  sessionID,
+		from:       replyFrom,
+		to:         from.Address,
+		subject:    subject,
+		messageID:  messageID,
+		references: references,
+	}
+	e.mu.Unlock()
+	e.logger.Info("email task created", "task_id", taskID, "from", sender, "agent", agentID)
+}
+
+// monitorCompletions replies to the message of each finished task.
+func (e *EmailChannel) monitorCompletions(ctx context.Context) {
+	sub := e.eventBus.Subscribe("task.")
+	defer e.eventBus.Unsubscribe(sub)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-sub.Ch():
+			payload, ok := ev.Payload.(map[string]string)
+			if !ok || payload["task_id"] == "" {
+				continue
+			}
+			taskID := payload["task_id"]
+			var text string
+			switch ev.Topic {
+			case "task.succeeded":
+				text = "(no reply)"
+				if task, err := e.store.GetTask(ctx, taskID); err != nil {
+					e.logger.Warn("failed to get completed task", "task_id", taskID, "error", err)
+				} else {
+					text = taskReply(task.Result)
+				}
+			case "task.failed":
+				text = "Task failed: details unavailable"
+				if task, err := e.store.GetTask(ctx, taskID); err == nil && task.Error != "" {
+					text = "Task failed: " + task.Error
+				}
+			case "task.canceled":
+				text = "Task was canceled."
+			default:
+				continue
+			}
+
+			e.mu.Lock()
+			thread, pending := e.pending[taskID]
+			delete(e.pending, taskID)
+			e.mu.Unlock()
+			if !pending {
+				continue
+			}
+			if err := e.reply(ctx, thread, text); err != nil {
+				e.logger.Error("failed to send email reply", "task_id", taskID, "to", thread.to, "error", err)
+			}
+		}
+	}
+}
+
+// reply sends text as an answer to thread. Replies longer than AttachAfter
+// carry their beginning in the body and the full text as reply.txt.
+func (e *EmailChannel) reply(ctx context.Context, thread emailThread, text string) error {
+	messageID := "<" + uuid.NewString() + "@" + e.domain + ">"
+	msg, err := composeEmailReply(thread, messageID, text, e.cfg.AttachAfter)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if e.cfg.SMTP.Username != "" {
+		host, _, _ := net.SplitHostPort(e.cfg.SMTP.Addr)
+		auth = smtp.PlainAuth("", e.cfg.SMTP.Username, e.cfg.SMTP.Password, host)
+	}
+	if err := smtp.SendMail(e.cfg.SMTP.Addr, auth, thread.from, []string{thread.to}, msg); err != nil {
+		return fmt.Errorf("smtp send: %w", err)
+	}
+	// A reply to our answer continues the session.
+	if err := e.store.KVSet(ctx, emailMessageKey(messageID), thread.sessionID); err != nil {
+		e.logger.Warn("email: failed to record reply", "error", err)
+	}
+	return nil
+}
+
+// composeEmailReply builds the RFC 5322 message answering thread.
+func composeEmailReply(thread emailThread, messageID, text string, attachAfter int) ([]byte, error) {
+	subject := thread.subject
+	if subject == "" {
+		subject = "(no subject)"
+	}
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+	references := strings.TrimSpace(thread.references + " " + thread.messageID)
+
+	var buf bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&buf, "%s: %s\r\n", k, v) }
+	header("From", thread.from)
+	header("To", thread.to)
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	header("In-Reply-To", thread.messageID)
+	header("References", references)
+	header("Auto-Submitted", "auto-replied")
+	header("MIME-Version", "1.0")
+
+	if len(text) <= attachAfter {
+		header("Content-Type", `text/plain; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	header("Content-Type", `multipart/mixed; boundary="`+mw.Boundary()+`"`)
+	buf.WriteString("\r\n")
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {`text/plain; charset="utf-8"`},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintable(part, truncateText(text, attachAfter)+"\n\n[The full reply is attached as reply.txt.]\n"); err != nil {
+		return nil, err
+	}
+	part, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {`text/plain; charset="utf-8"; name="reply.txt"`},
+		"Content-Disposition":       {`attachment; filename="reply.txt"`},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(text))
+	for len(encoded) > 76 {
+		_, _ = io.WriteString(part, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	_, _ = io.WriteString(part, encoded+"\r\n")
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, strings.ReplaceAll(text, "\n", "\r\n")); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// truncateText cuts text to at most n bytes, at the last line break if there
+// is one in the second half, and never inside a UTF-8 sequence.
+func truncateText(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !isRuneStart(text[n]) {
+		n--
+	}
+	text = text[:n]
+	if i := strings.LastIndexByte(text, '\n'); i > n/2 {
+		text = text[:i]
+	}
+	return text
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// emailText returns the plain text of a message or MIME part: the first
+// text/plain part, or the first text/html part with its tags removed.
+func emailText(header textproto.MIMEHeader, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	body = io.LimitReader(body, maxEmailBytes)
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, newlineStripper{body})
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		html := ""
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return html, nil
+			}
+			if err != nil {
+				return "", fmt.Errorf("read part: %w", err)
+			}
+			if strings.HasPrefix(part.Header.Get("Content-Disposition"), "attachment") {
+				continue
+			}
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			text, err := emailText(part.Header, part)
+			if err != nil {
+				return "", err
+			}
+			switch {
+			case text == "":
+			case partType == "text/html":
+				if html == "" {
+					html = text
+				}
+			default:
+				return text, nil
+			}
+		}
+	}
+	if !strings.HasPrefix(mediaType, "text/") {
+		return "", nil
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", fmt.Errorf("read body: %w", err)
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if mediaType == "text/html" {
+		text = strings.TrimSpace(htmlTag.ReplaceAllString(text, ""))
+	}
+	return text, nil
+}
+
+var htmlTag = regexp.MustCompile(`(?s)<[^>]*>`)
+
+// newlineStripper drops line breaks, which base64.NewDecoder does not skip
+// when they split a quantum across reads.
+type newlineStripper struct{ r io.Reader }
+
+func (n newlineStripper) Read(p []byte) (int, error) {
+	count, err := n.r.Read(p)
+	out := p[:0]
+	for _, b := range p[:count] {
+		if b != '\r' && b != '\n' {
+			out = append(out, b)
+		}
+	}
+	return len(out), err
+}
+
+// replyAttribution matches the line mail clients put above a quoted message,
+// e.g. "On Mon, 1 Jan 2026, Alice <alice@example.com> wrote:".
+var replyAttribution = regexp.MustCompile(`^On .* wrote:$`)
+
+// stripQuotedReply removes the quoted message and the signature from a reply
+// so only the new text becomes the task.
+func stripQuotedReply(body string) string {
+	lines := strings.Split(body, "\n")
+	var out []string
+	for _, line := range lines {
+		trimmed := strings.TrimRight(line, " \r")
+		if trimmed == "--" || replyAttribution.MatchString(trimmed) {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		out = append(out, trimmed)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// decodeEmailHeader decodes RFC 2047 encoded words.
+func decodeEmailHeader(v string) string {
+	dec := new(mime.WordDecoder)
+	if decoded, err := dec.DecodeHeader(v); err == nil {
+		return decoded
+	}
+	return v
+}
@@ -0,0 +1,188 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapClient is the small subset of IMAP4rev1 (RFC 3501) the email channel
+// needs to poll a mailbox: LOGIN, SELECT, UID SEARCH, UID FETCH, UID STORE
+// and LOGOUT.
+type imapClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapLine is an untagged response line with the literals it carried.
+type imapLine struct {
+	text     string
+	literals [][]byte
+}
+
+const imapTimeout = 2 * time.Minute
+
+// dialIMAP connects to addr over TLS, or plain TCP when insecure is set, and
+// reads the server greeting.
+func dialIMAP(ctx context.Context, addr string, insecure bool) (*imapClient, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if insecure {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("imap dial: %w", err)
+	}
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn)}
+	_ = conn.SetDeadline(time.Now().Add(imapTimeout))
+	greeting, err := c.r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("imap greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("imap greeting: %s", strings.TrimSpace(greeting))
+	}
+	return c, nil
+}
+
+func (c *imapClient) close() {
+	_ = c.conn.Close()
+}
+
+// command sends a tagged command and returns the untagged responses, or an
+// error unless the server answers OK.
+func (c *imapClient) command(format string, args ...any) ([]imapLine, error) {
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	_ = c.conn.SetDeadline(time.Now().Add(imapTimeout))
+	if _, err := fmt.Fprintf(c.conn, tag+" "+format+"\r\n", args...); err != nil {
+		return nil, fmt.Errorf("imap write: %w", err)
+	}
+	var lines []imapLine
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, fmt.Errorf("imap read: %w", err)
+		}
+		if rest, ok := strings.CutPrefix(line.text, tag+" "); ok {
+			if !strings.HasPrefix(rest, "OK") {
+				return nil, fmt.Errorf("imap %s: %s", strings.Fields(format)[0], rest)
+			}
+			return lines, nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+// readLine reads one response line, following {n} literals to its end.
+func (c *imapClient) readLine() (imapLine, error) {
+	var line imapLine
+	var text strings.Builder
+	for {
+		s, err := c.r.ReadString('\n')
+		if err != nil {
+			return line, err
+		}
+		s = strings.TrimRight(s, "\r\n")
+		text.WriteString(s)
+		n, ok := imapLiteralSize(s)
+		if !ok {
+			line.text = text.String()
+			return line, nil
+		}
+		if n > maxEmailBytes {
+			return line, fmt.Errorf("literal of %d bytes is too large", n)
+		}
+		lit := make([]byte, n)
+		if _, err := io.ReadFull(c.r, lit); err != nil {
+			return line, err
+		}
+		line.literals = append(line.literals, lit)
+	}
+}
+
+// imapLiteralSize parses the {n} that ends a line announcing a literal.
+func imapLiteralSize(s string) (int, bool) {
+	if !strings.HasSuffix(s, "}") {
+		return 0, false
+	}
+	open := strings.LastIndexByte(s, '{')
+	if open < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[open+1 : len(s)-1])
+	return n, err == nil && n >= 0
+}
+
+// imapQuote quotes s as an IMAP quoted string.
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func (c *imapClient) login(username, password string) error {
+	_, err := c.command("LOGIN %s %s", imapQuote(username), imapQuote(password))
+	return err
+}
+
+func (c *imapClient) selectMailbox(name string) error {
+	_, err := c.command("SELECT %s", imapQuote(name))
+	return err
+}
+
+// searchUnseen returns the UIDs of the unseen messages.
+func (c *imapClient) searchUnseen() ([]uint32, error) {
+	lines, err := c.command("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, line := range lines {
+		rest, ok := strings.CutPrefix(line.text, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, f := range strings.Fields(rest) {
+			if uid, err := strconv.ParseUint(f, 10, 32); err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// fetch returns the full message with uid without marking it seen.
+func (c *imapClient) fetch(uid uint32) ([]byte, error) {
+	lines, err := c.command("UID FETCH %d BODY.PEEK[]", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		if strings.Contains(line.text, "FETCH") && len(line.literals) > 0 {
+			return line.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("imap fetch %d: no message body", uid)
+}
+
+func (c *imapClient) markSeen(uid uint32) error {
+	_, err := c.command(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid)
+	return err
+}
+
+func (c *imapClient) logout() error {
+	_, err := c.command("LOGOUT")
+	return err
+}
@@ -0,0 +1,158 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// smtpServer is a minimal SMTP receiver (RFC 5321) for the email channel's
+// local listener. It has no AUTH or STARTTLS and can't check who a message
+// is from, so it must sit behind an MTA, on a loopback or private address,
+// that verifies senders (SPF, DKIM) and records Authentication-Results;
+// otherwise anyone who reaches it can pose as an allowed sender. Recipients
+// are limited by accept.
+type smtpServer struct {
+	hostname string
+	accept   func(rcpt string) bool
+	deliver  func(from string, rcpts []string, data []byte)
+	logger   *slog.Logger
+}
+
+const smtpCommandTimeout = 5 * time.Minute
+
+// serve accepts connections on ln until ctx is canceled.
+func (s *smtpServer) serve(ctx context.Context, ln net.Listener) {
+	var wg sync.WaitGroup
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				s.logger.Warn("smtp accept failed", "error", err)
+			}
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handle(ctx, conn)
+		}()
+	}
+	wg.Wait()
+}
+
+// handle runs one SMTP session.
+func (s *smtpServer) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	tp := textproto.NewConn(conn)
+	reply := func(code int, msg string) bool {
+		return tp.PrintfLine("%d %s", code, msg) == nil
+	}
+	if !reply(220, s.hostname+" ESMTP goclaw") {
+		return
+	}
+	var from string
+	var rcpts []string
+	for {
+		_ = conn.SetDeadline(time.Now().Add(smtpCommandTimeout))
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			if tp.PrintfLine("250-%s", s.hostname) != nil ||
+				tp.PrintfLine("250-8BITMIME") != nil ||
+				!reply(250, "SIZE "+strconv.Itoa(maxEmailBytes)) {
+				return
+			}
+		case "HELO":
+			reply(250, s.hostname)
+		case "MAIL":
+			addr, ok := smtpPath(arg, "FROM:")
+			if !ok {
+				reply(501, "syntax: MAIL FROM:<address>")
+				continue
+			}
+			from, rcpts = addr, nil
+			reply(250, "OK")
+		case "RCPT":
+			addr, ok := smtpPath(arg, "TO:")
+			switch {
+			case !ok:
+				reply(501, "syntax: RCPT TO:<address>")
+			case !s.accept(addr):
+				reply(550, "no such recipient")
+			default:
+				rcpts = append(rcpts, addr)
+				reply(250, "OK")
+			}
+		case "DATA":
+			if len(rcpts) == 0 {
+				reply(503, "need RCPT first")
+				continue
+			}
+			if !reply(354, "end data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			dot := tp.DotReader()
+			data, err := io.ReadAll(io.LimitReader(dot, maxEmailBytes+1))
+			if err != nil {
+				return
+			}
+			if len(data) > maxEmailBytes {
+				// Drain the rest of the message before answering.
+				_, _ = io.Copy(io.Discard, dot)
+				reply(552, "message too large")
+				from, rcpts = "", nil
+				continue
+			}
+			reply(250, "OK")
+			s.deliver(from, rcpts, data)
+			from, rcpts = "", nil
+		case "RSET":
+			from, rcpts = "", nil
+			reply(250, "OK")
+		case "NOOP":
+			reply(250, "OK")
+		case "VRFY":
+			reply(252, "cannot verify")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			reply(502, "command not implemented")
+		}
+	}
+}
+
+// smtpPath parses "FROM:<address> [params]" or "TO:<address> [params]".
+func smtpPath(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	arg = strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(arg, "<") {
+		return "", false
+	}
+	end := strings.IndexByte(arg, '>')
+	if end < 0 {
+		return "", false
+	}
+	return arg[1:end], true
+}
@@ -0,0 +1,470 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/config"
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+// sentMail is a message received by the SMTP sink.
+type sentMail struct {
+	from  string
+	rcpts []string
+	msg   *mail.Message
+}
+
+// startSMTPSink runs the channel's SMTP server as the outbound mail server
+// and returns its address and the messages it receives.
+func startSMTPSink(t *testing.T) (string, chan sentMail) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	sent := make(chan sentMail, 10)
+	srv := &smtpServer{
+		hostname: "mx.test",
+		accept:   func(string) bool { return true },
+		deliver: func(from string, rcpts []string, data []byte) {
+			msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+			if err != nil {
+				t.Errorf("sink: bad message: %v", err)
+				return
+			}
+			sent <- sentMail{from, rcpts, msg}
+		},
+		logger: slog.Default(),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		srv.serve(ctx, ln)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return ln.Addr().String(), sent
+}
+
+func startEmail(t *testing.T, cfg config.EmailConfig) (*EmailChannel, *fakeChatRouter, *bus.Bus) {
+	t.Helper()
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "test.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	router := &fakeChatRouter{store: store, tasks: make(chan fakeChatTask, 10)}
+	eventBus := bus.New()
+	ec, err := NewEmailChannel(cfg, router, store, slog.Default(), eventBus)
+	if err != nil {
+		t.Fatalf("NewEmailChannel: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ec.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	// Wait for the listener and the completion monitor.
+	deadline := time.Now().Add(5 * time.Second)
+	for (cfg.Listen != "" && ec.listenAddr() == "") || eventBus.SubscriberCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("email channel did not start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return ec, router, eventBus
+}
+
+// finishTask completes a chat task with reply and publishes its success.
+func finishTask(t *testing.T, router *fakeChatRouter, eventBus *bus.Bus, taskID, reply string) {
+	t.Helper()
+	ctx := context.Background()
+	claimed, err := router.store.ClaimNextPendingTask(ctx)
+	if err != nil || claimed == nil || claimed.ID != taskID {
+		t.Fatalf("claim task %s: %v, %v", taskID, claimed, err)
+	}
+	if err := router.store.StartTaskRun(ctx, claimed.ID, claimed.LeaseOwner, ""); err != nil {
+		t.Fatalf("StartTaskRun: %v", err)
+	}
+	if err := router.store.CompleteTask(ctx, taskID, `{"reply":`+strconv.Quote(reply)+`}`); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	eventBus.Publish("task.succeeded", map[string]string{"task_id": taskID})
+}
+
+func expectMail(t *testing.T, sent chan sentMail) sentMail {
+	t.Helper()
+	select {
+	case m := <-sent:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail sent")
+	}
+	return sentMail{}
+}
+
+func mailBody(t *testing.T, m sentMail) string {
+	t.Helper()
+	text, err := emailText(textproto.MIMEHeader(m.msg.Header), m.msg.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return text
+}
+
+func TestEmailChannel_ThreadedConversation(t *testing.T) {
+	smtpAddr, sent := startSMTPSink(t)
+	ec, router, eventBus := startEmail(t, config.EmailConfig{
+		Address:        "Agents <agents@example.com>",
+		AllowedSenders: []string{"alice@example.com", "@trusted.org"},
+		Listen:         "127.0.0.1:0",
+		SMTP:           config.EmailSMTPConfig{Addr: smtpAddr},
+		AuthservID:     "MX.example.com",
+		AttachAfter:    40,
+	})
+	// sendRaw delivers a message as the MTA would relay it; send adds the
+	// MTA's SPF pass on top.
+	sendRaw := func(from, to, headers, body string) error {
+		msg := "From: " + from + "\r\nTo: " + to + "\r\n" + headers + "\r\n" + body
+		return smtp.SendMail(ec.listenAddr(), nil, from, []string{to}, []byte(msg))
+	}
+	send := func(from, to, headers, body string) error {
+		return sendRaw(from, to, "Authentication-Results: mx.example.com; spf=pass smtp.mailfrom="+from+"\r\n"+headers, body)
+	}
+
+	// A new message to a +agent subaddress starts a session with that agent.
+	err := send("alice@example.com", "agents+coder@example.com",
+		"Subject: Build broken\r\nMessage-ID: <m1@example.com>\r\n", "Please fix it.\r\n")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	task := router.next(t)
+	if task.agentID != "coder" || task.content != "Build broken\n\nPlease fix it." {
+		t.Fatalf("task = %+v", task)
+	}
+
+	finishTask(t, router, eventBus, task.id, "Fixed.")
+	reply := expectMail(t, sent)
+	if reply.from != "agents+coder@example.com" || reply.rcpts[0] != "alice@example.com" ||
+		reply.msg.Header.Get("In-Reply-To") != "<m1@example.com>" ||
+		reply.msg.Header.Get("Subject") != "Re: Build broken" {
+		t.Fatalf("reply = %s -> %v, headers %v", reply.from, reply.rcpts, reply.msg.Header)
+	}
+	if body := strings.TrimSpace(mailBody(t, reply)); body != "Fixed." {
+		t.Fatalf("reply body = %q", body)
+	}
+
+	// Answering the reply continues the session, without the quoted text.
+	err = send("alice@example.com", "agents+coder@example.com",
+		"Subject: Re: Build broken\r\nMessage-ID: <m2@example.com>\r\nIn-Reply-To: "+reply.msg.Header.Get("Message-ID")+"\r\n",
+		"And the tests?\r\n\r\nOn Mon, 1 Jan 2026, Agents wrote:\r\n> Fixed.\r\n")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	followUp := router.next(t)
+	if followUp.sessionID != task.sessionID || followUp.content != "And the tests?" {
+		t.Fatalf("follow-up = %+v, want session %s", followUp, task.sessionID)
+	}
+
+	// Long replies carry the full text as an attachment.
+	long := strings.Repeat("All tests pass now.\n", 5)
+	finishTask(t, router, eventBus, followUp.id, long)
+	reply = expectMail(t, sent)
+	if refs := reply.msg.Header.Get("References"); !strings.Contains(refs, "<m2@example.com>") {
+		t.Errorf("References = %q", refs)
+	}
+	_, params, _ := mime.ParseMediaType(reply.msg.Header.Get("Content-Type"))
+	mr := multipart.NewReader(reply.msg.Body, params["boundary"])
+	var attachment string
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		if part.FileName() == "reply.txt" {
+			text, _ := emailText(part.Header, part)
+			attachment = text
+		}
+	}
+	if attachment != long {
+		t.Fatalf("attachment = %q, want the full reply", attachment)
+	}
+
+	// Unknown recipients are refused; mail from other senders and automatic
+	// mail is dropped.
+	if err := send("alice@example.com", "nobody@example.com", "Subject: hi\r\n", "hi\r\n"); err == nil {
+		t.Error("mail to an unknown recipient was accepted")
+	}
+	_ = send("mallory@example.net", "agents@example.com", "Subject: hi\r\n", "hi\r\n")
+	// Mail posing as an allowed sender is dropped: a different envelope
+	// sender, a failed SPF/DKIM check recorded by the MTA, no
+	// Authentication-Results at all, or a pass forged under another
+	// authserv-id or below the MTA's own.
+	_ = smtp.SendMail(ec.listenAddr(), nil, "mallory@example.net", []string{"agents@example.com"},
+		[]byte("From: alice@example.com\r\nTo: agents@example.com\r\nSubject: spoofed\r\n\r\nrm -rf\r\n"))
+	_ = sendRaw("alice@example.com", "agents@example.com",
+		"Authentication-Results: mx.example.com; spf=fail smtp.mailfrom=alice@example.com; dkim=none\r\nSubject: spoofed\r\n", "rm -rf\r\n")
+	_ = sendRaw("alice@example.com", "agents@example.com", "Subject: unauthenticated\r\n", "rm -rf\r\n")
+	_ = sendRaw("alice@example.com", "agents@example.com",
+		"Authentication-Results: mx.evil.example; dkim=pass header.d=example.com\r\nSubject: forged\r\n", "rm -rf\r\n")
+	_ = sendRaw("alice@example.com", "agents@example.com",
+		"Authentication-Results: mx.example.com; spf=fail smtp.mailfrom=alice@example.com\r\n"+
+			"Authentication-Results: mx.example.com; dkim=pass header.d=example.com\r\nSubject: forged\r\n", "rm -rf\r\n")
+	_ = send("alice@example.com", "agents@example.com", "Subject: Out of office\r\nAuto-Submitted: auto-replied\r\n", "away\r\n")
+
+	// Allowed domains route plain Address mail to the default agent.
+	if err := send("bob@trusted.org", "agents@example.com", "Subject: Status\r\n", "How are we doing?\r\n"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	other := router.next(t)
+	if other.agentID != "default" || other.sessionID == task.sessionID {
+		t.Fatalf("task = %+v", other)
+	}
+	select {
+	case extra := <-router.tasks:
+		t.Fatalf("dropped mail created task %+v", extra)
+	default:
+	}
+}
+
+// fakeIMAP serves a mailbox over the subset of IMAP the channel uses.
+type fakeIMAP struct {
+	ln     net.Listener
+	mu     sync.Mutex
+	msgs   map[uint32]string
+	seen   map[uint32]bool
+	logins []string
+}
+
+func newFakeIMAP(t *testing.T) *fakeIMAP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeIMAP{ln: ln, msgs: make(map[uint32]string), seen: make(map[uint32]bool)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeIMAP) add(uid uint32, msg string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.msgs[uid] = msg
+}
+
+func (f *fakeIMAP) isSeen(uid uint32) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seen[uid]
+}
+
+func (f *fakeIMAP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK fake IMAP ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, cmd, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		f.mu.Lock()
+		switch fields := strings.Fields(cmd); {
+		case fields[0] == "LOGIN":
+			f.logins = append(f.logins, fields[1]+" "+fields[2])
+		case fields[0] == "SELECT":
+			fmt.Fprintf(conn, "* %d EXISTS\r\n", len(f.msgs))
+		case cmd == "UID SEARCH UNSEEN":
+			var uids []string
+			for uid := range f.msgs {
+				if !f.seen[uid] {
+					uids = append(uids, strconv.Itoa(int(uid)))
+				}
+			}
+			fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(uids, " "))
+		case strings.HasPrefix(cmd, "UID FETCH "):
+			uid, _ := strconv.Atoi(fields[2])
+			msg := f.msgs[uint32(uid)]
+			fmt.Fprintf(conn, "* 1 FETCH (UID %d BODY[] {%d}\r\n%s)\r\n", uid, len(msg), msg)
+		case strings.HasPrefix(cmd, "UID STORE "):
+			uid, _ := strconv.Atoi(fields[2])
+			f.seen[uint32(uid)] = true
+		case cmd == "LOGOUT":
+			fmt.Fprint(conn, "* BYE\r\n")
+		}
+		f.mu.Unlock()
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+	}
+}
+
+func TestEmailChannel_IMAPPoll(t *testing.T) {
+	smtpAddr, _ := startSMTPSink(t)
+	imap := newFakeIMAP(t)
+	imap.add(1, "Return-Path: <alice@example.com>\r\nAuthentication-Results: mx.example.com; dkim=pass header.d=example.com\r\nFrom: alice@example.com\r\nTo: agents@example.com\r\nSubject: Report\r\nMessage-ID: <r1@example.com>\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nWeekly numbers =E2=80=94 please.\r\n")
+	imap.add(2, "From: mallory@example.net\r\nTo: agents@example.com\r\nSubject: spam\r\n\r\nbuy now\r\n")
+	imap.add(4, "Return-Path: <mallory@example.net>\r\nFrom: alice@example.com\r\nTo: agents@example.com\r\nSubject: spoofed\r\n\r\nrm -rf\r\n")
+
+	_, router, _ := startEmail(t, config.EmailConfig{
+		Address:        "agents@example.com",
+		Agents:         map[string]string{"reports@example.com": "analyst"},
+		AllowedSenders: []string{"alice@example.com"},
+		IMAP:           config.EmailIMAPConfig{Addr: imap.ln.Addr().String(), Username: "bot", Password: `p"w`, PollInterval: 1, Insecure: true},
+		SMTP:           config.EmailSMTPConfig{Addr: smtpAddr},
+		AuthservID:     "mx.example.com",
+	})
+	task := router.next(t)
+	if task.agentID != "default" || task.content != "Report\n\nWeekly numbers — please." {
+		t.Fatalf("task = %+v", task)
+	}
+
+	// The next poll picks up only new mail; the address map routes it.
+	imap.add(3, "Return-Path: <alice@example.com>\r\nAuthentication-Results: mx.example.com; dkim=pass header.d=example.com\r\nFrom: alice@example.com\r\nTo: reports@example.com\r\nSubject: Q3\r\nMessage-ID: <r2@example.com>\r\n\r\nQ3 please.\r\n")
+	next := router.next(t)
+	if next.agentID != "analyst" || next.content != "Q3\n\nQ3 please." {
+		t.Fatalf("task = %+v", next)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !imap.isSeen(3) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	imap.mu.Lock()
+	defer imap.mu.Unlock()
+	if !imap.seen[1] || !imap.seen[2] || !imap.seen[3] || !imap.seen[4] {
+		t.Errorf("seen = %v, want every message marked seen", imap.seen)
+	}
+	if len(imap.logins) == 0 || imap.logins[0] != `"bot" "p\"w"` {
+		t.Errorf("logins = %q", imap.logins)
+	}
+	select {
+	case extra := <-router.tasks:
+		t.Fatalf("unexpected task %+v", extra)
+	default:
+	}
+}
+
+func TestSenderVerified(t *testing.T) {
+	tests := []struct {
+		name     string
+		envelope string
+		results  string
+		want     bool
+	}{
+		{"no results", "<alice@example.com>", "", false},
+		{"null sender", "", "mx.example.com; spf=pass smtp.mailfrom=alice@example.com", false},
+		{"different envelope", "mallory@example.net", "mx.example.com; spf=pass smtp.mailfrom=alice@example.com", false},
+		{"dkim pass", "alice@example.com", "mx.example.com; dkim=pass (2048-bit key) header.d=example.com header.s=s1; spf=softfail", true},
+		{"spf pass", "alice@example.com", "mx.example.com; spf=pass smtp.mailfrom=alice@example.com", true},
+		{"dmarc pass", "alice@example.com", "mx.example.com; dmarc=pass header.from=example.com", true},
+		{"pass for another domain", "alice@example.com", "mx.example.com; dkim=pass header.d=evil.example.net", false},
+		{"all failed", "alice@example.com", "mx.example.com; spf=fail smtp.mailfrom=alice@example.com; dkim=none", false},
+		{"authserv-id only", "alice@example.com", "mx.example.com; none", false},
+		{"untrusted server", "alice@example.com", "mx.evil.example; dkim=pass header.d=example.com", false},
+		{"server with version and comment", "alice@example.com", "(relay) MX.example.com 1; dkim=pass header.d=example.com", true},
+	}
+	for _, tt := range tests {
+		header := mail.Header{}
+		if tt.results != "" {
+			header["Authentication-Results"] = []string{tt.results}
+		}
+		if got, reason := senderVerified(header, tt.envelope, "alice@example.com", "mx.example.com"); got != tt.want {
+			t.Errorf("%s: senderVerified = %v (%s), want %v", tt.name, got, reason, tt.want)
+		}
+	}
+
+	// Without an authserv-id (insecure_skip_sender_auth) only the envelope
+	// is checked.
+	if ok, reason := senderVerified(mail.Header{}, "alice@example.com", "alice@example.com", ""); !ok {
+		t.Errorf("skipped check: senderVerified = false (%s)", reason)
+	}
+}
+
+func TestNewEmailChannel_RequiresAuthservID(t *testing.T) {
+	cfg := config.EmailConfig{Address: "agents@example.com", Listen: "127.0.0.1:0", SMTP: config.EmailSMTPConfig{Addr: "127.0.0.1:25"}}
+	if _, err := NewEmailChannel(cfg, nil, nil, slog.Default(), bus.New()); err == nil || !strings.Contains(err.Error(), "authserv_id") {
+		t.Fatalf("expected authserv_id error, got %v", err)
+	}
+	cfg.InsecureSkipSenderAuth = true
+	if _, err := NewEmailChannel(cfg, nil, nil, slog.Default(), bus.New()); err != nil {
+		t.Fatalf("opt-out: %v", err)
+	}
+}
+
+func TestEmailText(t *testing.T) {
+	tests := []struct {
+		name, contentType, encoding, body, want string
+	}{
+		{"plain", "text/plain", "", "Hello\r\nthere\r\n", "Hello\nthere\n"},
+		{"quoted-printable", "text/plain; charset=utf-8", "quoted-printable", "caf=C3=A9 =\r\nlong line", "café long line"},
+		{"base64", "text/plain", "base64", "SGVs\r\nbG8=\r\n", "Hello"},
+		{"html only", "text/html", "", "<p>Hi <b>there</b></p>", "Hi there"},
+		{
+			"alternative prefers plain", `multipart/alternative; boundary="b"`, "",
+			"--b\r\nContent-Type: text/html\r\n\r\n<p>html</p>\r\n--b\r\nContent-Type: text/plain\r\n\r\nplain\r\n--b--\r\n", "plain",
+		},
+		{
+			"mixed skips attachments", `multipart/mixed; boundary="b"`, "",
+			"--b\r\nContent-Type: text/plain\r\nContent-Disposition: attachment; filename=x.txt\r\n\r\nfile\r\n--b\r\nContent-Type: text/plain\r\n\r\nbody\r\n--b--\r\n", "body",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := textproto.MIMEHeader{"Content-Type": {tt.contentType}}
+			if tt.encoding != "" {
+				header.Set("Content-Transfer-Encoding", tt.encoding)
+			}
+			got, err := emailText(header, strings.NewReader(tt.body))
+			if err != nil || got != tt.want {
+				t.Fatalf("emailText = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripQuotedReply(t *testing.T) {
+	tests := []struct {
+		body, want string
+	}{
+		{"Sounds good.\n\nOn Tue, 2 Jan 2026 at 10:00, Agents <agents@example.com> wrote:\n> earlier\n", "Sounds good."},
+		{"> quoted\nmy answer\n> more\n", "my answer"},
+		{"Thanks\n-- \nAlice\nACME Corp\n", "Thanks"},
+		{"  just text  \n", "just text"},
+	}
+	for _, tt := range tests {
+		if got := stripQuotedReply(tt.body); got != tt.want {
+			t.Errorf("stripQuotedReply(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/basket/go-claw/internal/coordinator"
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+// PlanStarter starts configured plans. *gateway.Server implements it.
+type PlanStarter interface {
+	StartPlan(ctx context.Context, planName, sessionID string, inputs map[string]any) (executionID, session string, err error)
+}
+
+// PlanReviewer reviews plans written by agents with create_plan. A
+// PlanStarter that also implements it enables /plan list, show, approve and
+// reject. *gateway.Server implements it.
+type PlanReviewer interface {
+	ListGeneratedPlans(ctx context.Context) ([]*persistence.GeneratedPlan, error)
+	GeneratedPlan(ctx context.Context, name string) (*persistence.GeneratedPlan, error)
+	ApprovePlan(ctx context.Context, name, sessionID string, inputs map[string]any) (executionID, session string, err error)
+	RejectPlan(ctx context.Context, name string) error
+}
+
+// PlanController controls plan executions. A PlanStarter that also
+// implements it enables /plan pause, resume, cancel and rerun.
+// *gateway.Server implements it.
+type PlanController interface {
+	PausePlanExecution(ctx context.Context, executionID string) error
+	ResumePlanExecution(ctx context.Context, executionID string) error
+	CancelPlanExecution(ctx context.Context, executionID string) error
+	RerunPlanExecution(ctx context.Context, executionID, stepID string) error
+}
+
+// runPlanCommand handles "/plan [run] <name> key=value..." and, when the
+// plan starter can review generated plans, "/plan list", "/plan show <name>",
+// "/plan approve <name> key=value..." and "/plan reject <name>". When it can
+// control executions, "/plan pause|resume|cancel <execution>" and
+// "/plan rerun <execution> <step>" are handled too. Answers go through reply;
+// the ID of the execution the command started, if any, is returned.
+func runPlanCommand(ctx context.Context, plans PlanStarter, content string, reply func(string), logger *slog.Logger) (executionID string) {
+	if plans == nil {
+		reply("Plans are not available.")
+		return ""
+	}
+	sub, rest, err := parsePlanCommand(content)
+	if err != nil {
+		reply("Usage: /plan run <name> [key=value ...]")
+		return ""
+	}
+	reviewer, canReview := plans.(PlanReviewer)
+	controller, canControl := plans.(PlanController)
+	switch sub {
+	case "run":
+	case "list", "show", "approve", "reject":
+		if canReview {
+			break
+		}
+		sub, rest = "run", strings.TrimSpace(sub+" "+rest)
+	case "pause", "resume", "cancel", "rerun":
+		if canControl {
+			runPlanControl(ctx, controller, sub, rest, reply)
+			return ""
+		}
+		fallthrough
+	default:
+		sub, rest = "run", strings.TrimSpace(sub+" "+rest)
+	}
+	if sub == "list" {
+		replyPlanList(ctx, reviewer, reply)
+		return ""
+	}
+	planName, planInput, _ := strings.Cut(rest, " ")
+	if planName == "" {
+		reply("Usage: /plan run <name> [key=value ...]")
+		return ""
+	}
+
+	switch sub {
+	case "show":
+		gp, err := reviewer.GeneratedPlan(ctx, planName)
+		if err != nil {
+			reply(fmt.Sprintf("Error: %v", err))
+			return ""
+		}
+		reply(fmt.Sprintf("Plan %s [%s]\nGoal: %s\n\n%s", gp.Name, gp.Status, gp.Goal, gp.Definition))
+		return ""
+	case "reject":
+		if err := reviewer.RejectPlan(ctx, planName); err != nil {
+			reply(fmt.Sprintf("Error: %v", err))
+			return ""
+		}
+		reply(fmt.Sprintf("Plan %s rejected.", planName))
+		return ""
+	}
+
+	inputs, err := coordinator.ParseInputArgs(planInput)
+	if err == nil {
+		var execID string
+		if sub == "approve" {
+			execID, _, err = reviewer.ApprovePlan(ctx, planName, "", inputs)
+		} else {
+			execID, _, err = plans.StartPlan(ctx, planName, "", inputs)
+		}
+		if err == nil {
+			reply(fmt.Sprintf("Plan %s started (execution %s).", planName, execID))
+			return execID
+		}
+	}
+	logger.Warn("failed to start plan", "plan", planName, "error", err)
+	reply(fmt.Sprintf("Error: could not start plan %s: %v", planName, err))
+	return ""
+}
+
+// runPlanControl pauses, resumes, cancels or re-runs a plan execution.
+func runPlanControl(ctx context.Context, controller PlanController, sub, rest string, reply func(string)) {
+	execID, stepID, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	stepID = strings.TrimSpace(stepID)
+	if execID == "" || (sub == "rerun") != (stepID != "") {
+		reply("Usage: /plan pause|resume|cancel <execution>, /plan rerun <execution> <step>")
+		return
+	}
+	var err error
+	switch sub {
+	case "pause":
+		err = controller.PausePlanExecution(ctx, execID)
+	case "resume":
+		err = controller.ResumePlanExecution(ctx, execID)
+	case "cancel":
+		err = controller.CancelPlanExecution(ctx, execID)
+	case "rerun":
+		err = controller.RerunPlanExecution(ctx, execID, stepID)
+	}
+	if err != nil {
+		reply(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	done := map[string]string{
+		"pause":  "paused",
+		"resume": "resumed",
+		"cancel": "canceled",
+		"rerun":  "re-running from step " + stepID,
+	}[sub]
+	reply(fmt.Sprintf("Execution %s %s.", execID, done))
+}
+
+// replyPlanList lists the plans written by agents and their review status.
+func replyPlanList(ctx context.Context, reviewer PlanReviewer, reply func(string)) {
+	plans, err := reviewer.ListGeneratedPlans(ctx)
+	if err != nil {
+		reply(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	if len(plans) == 0 {
+		reply("No generated plans.")
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("Generated plans:")
+	for _, p := range plans {
+		fmt.Fprintf(&sb, "\n• %s [%s] %s", p.Name, p.Status, p.Goal)
+	}
+	reply(sb.String())
+}
@@ -0,0 +1,986 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/engine"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+)
+
+// SlackOptions configures a Slack channel.
+type SlackOptions struct {
+	BotToken       string   // xoxb- bot token for the Web API
+	AppToken       string   // xapp- app-level token with connections:write, for Socket Mode
+	AllowedUserIDs []string // Slack user IDs allowed to talk to agents; empty denies everyone
+	// NotifyChannel receives alerts, plan progress and approvals that do not
+	// belong to a thread. When empty, alerts and approvals are sent to the
+	// allowed users directly and untracked plan progress is not reported.
+	NotifyChannel string
+	APIURL        string // Web API base URL; default DefaultSlackAPIURL
+}
+
+// SlackChannel implements the Channel interface for Slack. It receives
+// events over Socket Mode, so no public HTTP endpoint is needed, and answers
+// through the Web API. Each conversation is a Slack thread: mentioning the
+// bot in a channel starts one, and later messages in the thread go to the
+// same agent without mentioning it again.
+type SlackChannel struct {
+	opts     SlackOptions
+	allowed  map[string]struct{}
+	api      *slackAPI
+	router   engine.ChatTaskRouter
+	store    *persistence.Store
+	logger   *slog.Logger
+	eventBus *bus.Bus
+
+	botUserID string
+	teamID    string
+
+	mu           sync.Mutex
+	pendingTasks map[string]slackThread            // taskID -> thread to reply in
+	sessions     map[string]slackThread            // sessionID -> thread of its latest message
+	executions   map[string]*slackPlanProgress     // executionID -> progress message
+	approvals    map[string][]slackApprovalMessage // requestID -> undecided approval messages
+
+	// streamMu protects streams for progressive editing.
+	streamMu sync.Mutex
+	streams  map[string]*slackStream // taskID -> streaming state
+
+	eventSubs []*bus.Subscription
+
+	plans PlanStarter // runs /plan commands; nil disables them
+}
+
+// slackThread is where a conversation happens: a channel and the timestamp
+// of its thread's root message. ts is empty at the top level of a DM.
+type slackThread struct {
+	channel string
+	ts      string
+}
+
+// slackStream tracks progressive editing for a streaming task.
+type slackStream struct {
+	thread   slackThread
+	ts       string // the message being edited
+	text     strings.Builder
+	lastEdit time.Time
+}
+
+// slackPlanProgress is the message that reports a plan execution's progress.
+// It is posted when the first step finishes and edited as the rest do.
+type slackPlanProgress struct {
+	thread slackThread // where /plan was run; empty for NotifyChannel
+	ts     string      // the progress message, once posted
+	plan   string
+	total  int
+	steps  []string // one line per finished step
+	state  string   // running, paused, succeeded, failed or canceled
+}
+
+// slackApprovalMessage is an approval request posted with buttons.
+type slackApprovalMessage struct {
+	channel string
+	ts      string
+	text    string
+}
+
+// errSlackReconnect is returned when Slack asks the client to reconnect.
+var errSlackReconnect = errors.New("slack requested reconnect")
+
+// NewSlackChannel creates a new Slack channel. eventBus delivers task
+// results, streamed tokens and plan events; it must not be nil.
+func NewSlackChannel(opts SlackOptions, router engine.ChatTaskRouter, store *persistence.Store, logger *slog.Logger, eventBus *bus.Bus) *SlackChannel {
+	allowed := make(map[string]struct{})
+	for _, id := range opts.AllowedUserIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			allowed[id] = struct{}{}
+		}
+	}
+	return &SlackChannel{
+		opts:         opts,
+		allowed:      allowed,
+		api:          newSlackAPI(opts.APIURL, opts.BotToken, opts.AppToken),
+		router:       router,
+		store:        store,
+		logger:       logger,
+		eventBus:     eventBus,
+		pendingTasks: make(map[string]slackThread),
+		sessions:     make(map[string]slackThread),
+		executions:   make(map[string]*slackPlanProgress),
+		approvals:    make(map[string][]slackApprovalMessage),
+		streams:      make(map[string]*slackStream),
+	}
+}
+
+// SetPlanStarter enables the /plan command.
+func (s *SlackChannel) SetPlanStarter(p PlanStarter) {
+	s.plans = p
+}
+
+func (s *SlackChannel) Name() string {
+	return "slack"
+}
+
+func (s *SlackChannel) Start(ctx context.Context) error {
+	if s.eventBus == nil {
+		return fmt.Errorf("slack init failed: no event bus")
+	}
+	var err error
+	s.botUserID, s.teamID, err = s.api.authTest(ctx)
+	if err != nil {
+		return fmt.Errorf("slack init failed: %w", err)
+	}
+	s.logger.Info("slack bot started", "user_id", s.botUserID, "team_id", s.teamID)
+
+	go s.monitorCompletions(ctx)
+	go s.monitorStreamTokens(ctx)
+
+	// Reconnection loop with exponential backoff.
+	backoff := time.Second
+	const maxBackoff = 30 * time.Second
+
+	for {
+		connected := time.Now()
+		err := s.runSocket(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, errSlackReconnect) {
+			s.logger.Info("slack socket reconnecting on request")
+			backoff = time.Second
+			continue
+		}
+		if time.Since(connected) > maxBackoff {
+			backoff = time.Second
+		}
+		s.logger.Warn("slack socket disconnected, reconnecting", "error", err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// slackEnvelope is a Socket Mode message. Every envelope with an ID must be
+// acknowledged within three seconds or Slack delivers it again.
+type slackEnvelope struct {
+	Type       string          `json:"type"`
+	EnvelopeID string          `json:"envelope_id"`
+	Payload    json.RawMessage `json:"payload"`
+	Reason     string          `json:"reason"`
+}
+
+// runSocket opens a Socket Mode connection and handles its envelopes until
+// it fails, Slack asks for a reconnect or ctx is done.
+func (s *SlackChannel) runSocket(ctx context.Context) error {
+	url, err := s.api.openConnection(ctx)
+	if err != nil {
+		return err
+	}
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		return fmt.Errorf("slack socket dial: %w", err)
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(1 << 20)
+
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return fmt.Errorf("slack socket read: %w", err)
+		}
+		var env slackEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			s.logger.Warn("invalid slack socket message", "error", err)
+			continue
+		}
+		if env.EnvelopeID != "" {
+			ack, _ := json.Marshal(map[string]string{"envelope_id": env.EnvelopeID})
+			if err := conn.Write(ctx, websocket.MessageText, ack); err != nil {
+				return fmt.Errorf("slack socket ack: %w", err)
+			}
+		}
+		switch env.Type {
+		case "hello":
+			s.logger.Info("slack socket connected")
+		case "disconnect":
+			return fmt.Errorf("%w (%s)", errSlackReconnect, env.Reason)
+		case "events_api":
+			var cb slackEventCallback
+			if err := json.Unmarshal(env.Payload, &cb); err != nil {
+				s.logger.Warn("invalid slack event", "error", err)
+				continue
+			}
+			s.handleEvent(ctx, cb.TeamID, cb.Event)
+		case "interactive":
+			var in slackInteraction
+			if err := json.Unmarshal(env.Payload, &in); err != nil {
+				s.logger.Warn("invalid slack interaction", "error", err)
+				continue
+			}
+			s.handleInteraction(ctx, in)
+		case "slash_commands":
+			var cmd slackSlashCommand
+			if err := json.Unmarshal(env.Payload, &cmd); err != nil {
+				s.logger.Warn("invalid slack slash command", "error", err)
+				continue
+			}
+			s.handleSlashCommand(ctx, cmd)
+		}
+	}
+}
+
+// slackEventCallback is the payload of an events_api envelope.
+type slackEventCallback struct {
+	TeamID string     `json:"team_id"`
+	Event  slackEvent `json:"event"`
+}
+
+// slackEvent is a message or app_mention event.
+type slackEvent struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	User        string `json:"user"`
+	BotID       string `json:"bot_id"`
+	Text        string `json:"text"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+}
+
+// handleEvent routes direct messages, mentions of the bot, and messages in
+// threads the bot takes part in. Channel messages that mention the bot also
+// arrive as app_mention events, so only those are used.
+func (s *SlackChannel) handleEvent(ctx context.Context, teamID string, ev slackEvent) {
+	if ev.BotID != "" || ev.Subtype != "" || ev.User == "" || ev.User == s.botUserID {
+		return
+	}
+	mention := "<@" + s.botUserID + ">"
+	var thread slackThread
+	switch ev.Type {
+	case "app_mention":
+		thread = slackThread{channel: ev.Channel, ts: ev.ThreadTS}
+		if thread.ts == "" {
+			thread.ts = ev.TS
+		}
+	case "message":
+		thread = slackThread{channel: ev.Channel, ts: ev.ThreadTS}
+		if ev.ChannelType != "im" {
+			if ev.ThreadTS == "" || strings.Contains(ev.Text, mention) {
+				return
+			}
+			if s.threadAgent(ctx, thread) == "" {
+				return
+			}
+		}
+	default:
+		return
+	}
+	if !s.isAllowed(ev.User) {
+		s.logger.Warn("slack access denied", "user_id", ev.User, "channel", ev.Channel)
+		return
+	}
+	content := strings.TrimSpace(slackUnescape(strings.ReplaceAll(ev.Text, mention, "")))
+	s.handleMessage(ctx, teamID, ev.User, thread, content)
+}
+
+func (s *SlackChannel) handleMessage(ctx context.Context, teamID, userID string, thread slackThread, content string) {
+	if content == "" {
+		return
+	}
+	if content == "/plan" || strings.HasPrefix(content, "/plan ") {
+		s.handlePlanCommand(ctx, thread, content)
+		return
+	}
+
+	// A thread stays with the agent it was started with, unless an @agent
+	// prefix hands it to another one.
+	agentID := "default"
+	if thread.ts != "" {
+		if bound := s.threadAgent(ctx, thread); bound != "" {
+			agentID = bound
+		}
+	}
+	if strings.HasPrefix(content, "@") {
+		parts := strings.SplitN(content, " ", 2)
+		agentID = strings.TrimPrefix(parts[0], "@")
+		content = ""
+		if len(parts) > 1 {
+			content = strings.TrimSpace(parts[1])
+		}
+	}
+	if content == "" {
+		return
+	}
+	if thread.ts != "" {
+		if err := s.store.KVSet(ctx, slackThreadKey(thread), agentID); err != nil {
+			s.logger.Warn("failed to map slack thread to agent", "error", err)
+		}
+	}
+
+	sessionID := slackSessionID(teamID, thread, userID, agentID)
+	taskID, err := s.router.CreateChatTask(ctx, agentID, sessionID, content)
+	if err != nil {
+		s.logger.Error("failed to create slack task", "error", err)
+		s.post(ctx, thread, fmt.Sprintf("Error: could not schedule task: %v", err))
+		return
+	}
+
+	s.mu.Lock()
+	s.pendingTasks[taskID] = thread
+	s.sessions[sessionID] = thread
+	s.mu.Unlock()
+}
+
+// slackThreadKey is the KV key holding the agent a thread talks to.
+func slackThreadKey(thread slackThread) string {
+	return fmt.Sprintf("slack_thread:%s:%s", thread.channel, thread.ts)
+}
+
+// threadAgent returns the agent bound to a thread, or "" for threads the bot
+// takes no part in.
+func (s *SlackChannel) threadAgent(ctx context.Context, thread slackThread) string {
+	agentID, err := s.store.KVGet(ctx, slackThreadKey(thread))
+	if err != nil {
+		s.logger.Warn("failed to look up slack thread", "error", err)
+		return ""
+	}
+	return agentID
+}
+
+// slackSessionID maps a Slack user, thread and agent to a persistent session
+// ID, so each thread keeps its own history per user and agent.
+func slackSessionID(teamID string, thread slackThread, userID, agentID string) string {
+	key := fmt.Sprintf("goclaw:slack:%s:%s:%s:user:%s:agent:%s", teamID, thread.channel, thread.ts, userID, agentID)
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)).String()
+}
+
+func (s *SlackChannel) isAllowed(userID string) bool {
+	_, ok := s.allowed[userID]
+	return ok
+}
+
+// slackSlashCommand is the payload of a slash_commands envelope.
+type slackSlashCommand struct {
+	Command   string `json:"command"`
+	Text      string `json:"text"`
+	UserID    string `json:"user_id"`
+	ChannelID string `json:"channel_id"`
+}
+
+// handleSlashCommand handles the /plan slash command.
+func (s *SlackChannel) handleSlashCommand(ctx context.Context, cmd slackSlashCommand) {
+	if !s.isAllowed(cmd.UserID) {
+		s.logger.Warn("slack command access denied", "user_id", cmd.UserID, "command", cmd.Command)
+		return
+	}
+	if cmd.Command != "/plan" {
+		s.post(ctx, slackThread{channel: cmd.ChannelID}, fmt.Sprintf("Unknown command %s.", cmd.Command))
+		return
+	}
+	s.handlePlanCommand(ctx, slackThread{channel: cmd.ChannelID}, strings.TrimSpace("/plan "+cmd.Text))
+}
+
+// handlePlanCommand runs a /plan command and replies in the thread. A
+// started execution reports its progress in that thread; at the top level,
+// the reply starts a thread for it.
+func (s *SlackChannel) handlePlanCommand(ctx context.Context, thread slackThread, content string) {
+	reply := func(text string) {
+		ts := s.post(ctx, thread, slackEscape(text))
+		if thread.ts == "" {
+			thread.ts = ts
+		}
+	}
+	execID := runPlanCommand(ctx, s.plans, content, reply, s.logger.With("channel", "slack"))
+	if execID == "" || thread.ts == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.executions[execID]
+	if p == nil {
+		p = &slackPlanProgress{state: "running"}
+		s.executions[execID] = p
+	}
+	if p.ts == "" {
+		p.thread = thread
+	}
+}
+
+// slackInteraction is the payload of an interactive envelope.
+type slackInteraction struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Message struct {
+		TS   string `json:"ts"`
+		Text string `json:"text"`
+	} `json:"message"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// handleInteraction handles approval button clicks. The buttons are replaced
+// with the decision, and the response is published for the waiting plan step
+// or tool call.
+func (s *SlackChannel) handleInteraction(ctx context.Context, in slackInteraction) {
+	if in.Type != "block_actions" {
+		return
+	}
+	for _, action := range in.Actions {
+		requestID, decision, err := parseHITLCallback(action.Value)
+		if err != nil {
+			continue
+		}
+		if !s.isAllowed(in.User.ID) {
+			s.logger.Warn("slack approval access denied", "user_id", in.User.ID)
+			return
+		}
+		clicked := slackApprovalMessage{channel: in.Channel.ID, ts: in.Message.TS, text: in.Message.Text}
+		s.resolveApproval(ctx, requestID, fmt.Sprintf("%s by <@%s>", slackDecisionLabel(decision), in.User.ID), clicked)
+
+		name := in.User.Username
+		if name == "" {
+			name = in.User.ID
+		}
+		s.eventBus.Publish(bus.TopicHITLApprovalResponse, bus.HITLApprovalResponse{
+			RequestID: requestID,
+			Action:    decision, // "approve" or "reject"; tool approvals also use "approve_session" and "deny"
+			Reason:    fmt.Sprintf("via Slack (%s)", name),
+		})
+	}
+}
+
+// resolveApproval replaces the buttons of an approval request's messages with
+// outcome. clicked is updated when the request's messages are not known (for
+// example after a restart); pass a zero value to skip it.
+func (s *SlackChannel) resolveApproval(ctx context.Context, requestID, outcome string, clicked slackApprovalMessage) {
+	s.mu.Lock()
+	msgs, ok := s.approvals[requestID]
+	delete(s.approvals, requestID)
+	s.mu.Unlock()
+	if !ok && clicked.ts != "" {
+		msgs = []slackApprovalMessage{clicked}
+	}
+	for _, m := range msgs {
+		text := m.text + "\n\n*" + outcome + "*"
+		err := s.api.updateMessage(ctx, slackPost{
+			Channel: m.channel,
+			TS:      m.ts,
+			Text:    text,
+			Blocks:  []slackBlock{slackSection(text)},
+		})
+		if err != nil {
+			s.logger.Warn("failed to update slack approval message", "request_id", requestID, "error", err)
+		}
+	}
+}
+
+// monitorCompletions subscribes to the event bus for task lifecycle events
+// and replies in the thread of each finished task.
+func (s *SlackChannel) monitorCompletions(ctx context.Context) {
+	sub := s.eventBus.Subscribe("task.")
+	defer s.eventBus.Unsubscribe(sub)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-sub.Ch():
+			payload, ok := ev.Payload.(map[string]string)
+			if !ok {
+				continue
+			}
+			taskID := payload["task_id"]
+			if taskID == "" {
+				continue
+			}
+			switch ev.Topic {
+			case "task.succeeded", "task.failed", "task.canceled":
+			default:
+				continue
+			}
+
+			s.mu.Lock()
+			thread, pending := s.pendingTasks[taskID]
+			delete(s.pendingTasks, taskID)
+			s.mu.Unlock()
+			if !pending {
+				continue
+			}
+
+			s.streamMu.Lock()
+			state := s.streams[taskID]
+			delete(s.streams, taskID)
+			s.streamMu.Unlock()
+
+			switch ev.Topic {
+			case "task.succeeded":
+				text := "(no reply)"
+				if task, err := s.store.GetTask(ctx, taskID); err != nil {
+					s.logger.Warn("failed to get completed task", "task_id", taskID, "error", err)
+				} else {
+					text = taskReply(task.Result)
+				}
+				// If we were streaming this task, do a final edit instead of a new message.
+				if state != nil && state.ts != "" {
+					s.update(ctx, thread.channel, state.ts, slackEscape(text))
+				} else {
+					s.post(ctx, thread, slackEscape(text))
+				}
+
+			case "task.failed":
+				errMsg := "details unavailable"
+				if task, err := s.store.GetTask(ctx, taskID); err == nil && task.Error != "" {
+					errMsg = task.Error
+				}
+				s.post(ctx, thread, slackEscape("Task failed: "+errMsg))
+
+			case "task.canceled":
+				s.post(ctx, thread, "Task was canceled.")
+			}
+		}
+	}
+}
+
+// taskReply extracts the reply text from a chat task's result.
+func taskReply(result string) string {
+	var resMap map[string]string
+	if json.Unmarshal([]byte(result), &resMap) == nil {
+		if val, ok := resMap["reply"]; ok {
+			return val
+		}
+	}
+	return result
+}
+
+// monitorStreamTokens subscribes to stream.token bus events and progressively
+// edits the reply of a pending task as tokens arrive from the LLM.
+func (s *SlackChannel) monitorStreamTokens(ctx context.Context) {
+	sub := s.eventBus.Subscribe(bus.TopicStreamToken)
+	defer s.eventBus.Unsubscribe(sub)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-sub.Ch():
+			var taskID, chunk string
+			switch p := ev.Payload.(type) {
+			case map[string]string:
+				taskID, chunk = p["task_id"], p["chunk"]
+			case bus.StreamTokenEvent:
+				taskID, chunk = p.TaskID, p.Token
+			}
+			if taskID == "" || chunk == "" {
+				continue
+			}
+			s.mu.Lock()
+			thread, pending := s.pendingTasks[taskID]
+			s.mu.Unlock()
+			if !pending {
+				continue
+			}
+			s.streamChunk(ctx, taskID, thread, chunk)
+		}
+	}
+}
+
+// streamChunk posts the first chunk of a reply and edits the message with
+// the text so far at most once per second, keeping within Slack's rate limits.
+func (s *SlackChannel) streamChunk(ctx context.Context, taskID string, thread slackThread, chunk string) {
+	s.streamMu.Lock()
+	state, exists := s.streams[taskID]
+	if !exists {
+		state = &slackStream{thread: thread}
+		state.text.WriteString(chunk)
+		state.ts = s.post(ctx, thread, slackEscape(chunk))
+		state.lastEdit = time.Now()
+		s.streams[taskID] = state
+		s.streamMu.Unlock()
+		return
+	}
+	state.text.WriteString(chunk)
+	if state.ts == "" || time.Since(state.lastEdit) < time.Second {
+		s.streamMu.Unlock()
+		return
+	}
+	text := state.text.String()
+	ts := state.ts
+	state.lastEdit = time.Now()
+	s.streamMu.Unlock()
+
+	s.update(ctx, thread.channel, ts, slackEscape(text))
+}
+
+// SubscribeToEvents subscribes to plan execution, approval and alert events.
+// Called at startup to enable Slack to receive and forward event notifications.
+func (s *SlackChannel) SubscribeToEvents() {
+	if s.eventBus == nil {
+		return
+	}
+	subs := []*bus.Subscription{
+		s.eventBus.Subscribe("plan."),
+		s.eventBus.Subscribe("hitl."),
+		s.eventBus.Subscribe(bus.TopicAgentAlert),
+	}
+	s.eventSubs = subs
+
+	// Each subscription is handled in order, so a plan's progress message is
+	// edited in the order its events happened.
+	for _, sub := range subs {
+		sub := sub
+		go func() {
+			for ev := range sub.Ch() {
+				s.handleBusEvent(context.Background(), ev)
+			}
+		}()
+	}
+}
+
+// handleBusEvent dispatches events to the appropriate handlers.
+func (s *SlackChannel) handleBusEvent(ctx context.Context, ev bus.Event) {
+	switch ev.Topic {
+	case bus.TopicHITLApprovalRequested:
+		s.onHITLRequest(ctx, ev.Payload)
+	case bus.TopicToolApprovalRequested:
+		s.onToolApprovalRequest(ctx, ev.Payload)
+	case bus.TopicToolApprovalResolved:
+		if res, ok := ev.Payload.(bus.ToolApprovalResolved); ok {
+			s.resolveApproval(ctx, res.RequestID, slackDecisionLabel(res.Status), slackApprovalMessage{})
+		}
+	case bus.TopicAgentAlert:
+		s.onAgentAlert(ctx, ev.Payload)
+	default:
+		if strings.HasPrefix(ev.Topic, "plan.") {
+			s.onPlanEvent(ctx, ev)
+		}
+	}
+}
+
+// onPlanEvent updates the progress message of a plan execution.
+func (s *SlackChannel) onPlanEvent(ctx context.Context, ev bus.Event) {
+	var execID, stepID, status, errMsg string
+	switch p := ev.Payload.(type) {
+	case map[string]interface{}:
+		execID, _ = p["execution_id"].(string)
+		stepID, _ = p["step_id"].(string)
+		status, _ = p["status"].(string)
+		errMsg, _ = p["error"].(string)
+	case bus.PlanStepEvent:
+		execID, stepID = p.ExecutionID, p.StepID
+	}
+	if execID == "" {
+		return
+	}
+
+	s.mu.Lock()
+	p := s.executions[execID]
+	if p == nil {
+		p = &slackPlanProgress{state: "running"}
+		s.executions[execID] = p
+	}
+	switch ev.Topic {
+	case bus.TopicPlanExecutionStarted:
+		// Reported together with the first finished step; the /plan reply
+		// already says the plan started.
+		payload, _ := ev.Payload.(map[string]interface{})
+		p.plan, _ = payload["plan_name"].(string)
+		p.total = intValue(payload["total_steps"])
+		s.mu.Unlock()
+		return
+	case bus.TopicPlanStepCompleted, bus.TopicPlanStepFailed:
+		if ev.Topic == bus.TopicPlanStepFailed {
+			status = "failed"
+		}
+		line := "✅ " + slackEscape(stepID)
+		switch status {
+		case "failed":
+			line = "❌ " + slackEscape(stepID)
+			if errMsg != "" {
+				line += ": " + slackEscape(errMsg)
+			}
+		case "skipped":
+			line = "⏭ " + slackEscape(stepID) + " (skipped)"
+		}
+		p.steps = append(p.steps, line)
+	case bus.TopicPlanExecutionPaused:
+		p.state = "paused"
+	case bus.TopicPlanExecutionResumed:
+		p.state = "running"
+	case bus.TopicPlanExecutionCanceled:
+		p.state = "canceled"
+	case bus.TopicPlanExecutionCompleted:
+		if status != "" {
+			p.state = status
+		}
+		delete(s.executions, execID)
+	default:
+		s.mu.Unlock()
+		return
+	}
+	text := p.text(execID)
+	thread, ts := p.thread, p.ts
+	s.mu.Unlock()
+
+	if ts != "" {
+		s.update(ctx, thread.channel, ts, text)
+		return
+	}
+	if thread.channel == "" {
+		if s.opts.NotifyChannel == "" {
+			return
+		}
+		thread = slackThread{channel: s.opts.NotifyChannel}
+	}
+	ts = s.post(ctx, thread, text)
+	s.mu.Lock()
+	p.thread, p.ts = thread, ts
+	s.mu.Unlock()
+}
+
+// text renders the progress message.
+func (p *slackPlanProgress) text(execID string) string {
+	emoji := map[string]string{
+		"running":   "📋",
+		"paused":    "⏸",
+		"canceled":  "⏹",
+		"succeeded": "✅",
+		"failed":    "❌",
+	}[p.state]
+	if emoji == "" {
+		emoji = "📋"
+	}
+	name := p.plan
+	if name == "" {
+		name = "plan"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s *%s* `%s` %s", emoji, slackEscape(name), slackEscape(execID), p.state)
+	if p.total > 0 {
+		fmt.Fprintf(&sb, " (%d/%d steps)", len(p.steps), p.total)
+	}
+	for _, line := range p.steps {
+		sb.WriteString("\n" + line)
+	}
+	return sb.String()
+}
+
+// intValue reads a count from an event payload.
+func intValue(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// onHITLRequest asks for the approval of a plan step, in the thread the plan
+// was started from when known.
+func (s *SlackChannel) onHITLRequest(ctx context.Context, data interface{}) {
+	req, ok := data.(bus.HITLApprovalRequest)
+	if !ok {
+		s.logger.Warn("invalid HITLApprovalRequest payload", "type", fmt.Sprintf("%T", data))
+		return
+	}
+	text := fmt.Sprintf("🔓 *Approval required*\nStep `%s` of execution `%s`:\n```%s```",
+		slackEscape(req.StepID), slackEscape(req.ExecutionID), slackEscape(req.Prompt))
+
+	s.mu.Lock()
+	var thread slackThread
+	if p := s.executions[req.ExecutionID]; p != nil {
+		thread = p.thread
+	}
+	s.mu.Unlock()
+	s.postApproval(ctx, req.RequestID, text, thread, "approve", "reject")
+}
+
+// onToolApprovalRequest asks for a decision on a gated tool call, in the
+// thread of the session that made it when known.
+func (s *SlackChannel) onToolApprovalRequest(ctx context.Context, data interface{}) {
+	req, ok := data.(bus.ToolApprovalRequest)
+	if !ok {
+		s.logger.Warn("invalid ToolApprovalRequest payload", "type", fmt.Sprintf("%T", data))
+		return
+	}
+	text := fmt.Sprintf("🔐 *Tool approval required*\nAgent `%s` wants to run `%s`:\n```%s```",
+		slackEscape(req.AgentID), slackEscape(req.Tool), slackEscape(req.Args))
+
+	s.mu.Lock()
+	thread := s.sessions[req.SessionID]
+	s.mu.Unlock()
+	s.postApproval(ctx, req.RequestID, text, thread, "approve", "approve_session", "deny")
+}
+
+// postApproval posts an approval request with a button per action, in thread
+// or, when it is unknown, where notifications go.
+func (s *SlackChannel) postApproval(ctx context.Context, requestID, text string, thread slackThread, actions ...string) {
+	targets := []slackThread{thread}
+	if thread.channel == "" {
+		targets = s.notifyTargets()
+	}
+	var msgs []slackApprovalMessage
+	for _, target := range targets {
+		ts, err := s.api.postMessage(ctx, slackPost{
+			Channel:  target.channel,
+			ThreadTS: target.ts,
+			Text:     text,
+			Blocks:   slackApprovalBlocks(text, requestID, actions...),
+		})
+		if err != nil {
+			s.logger.Error("failed to send slack approval request", "request_id", requestID, "error", err)
+			continue
+		}
+		msgs = append(msgs, slackApprovalMessage{channel: target.channel, ts: ts, text: text})
+	}
+	if len(msgs) == 0 {
+		return
+	}
+	s.mu.Lock()
+	s.approvals[requestID] = msgs
+	s.mu.Unlock()
+}
+
+// onAgentAlert posts agent alert notifications.
+func (s *SlackChannel) onAgentAlert(ctx context.Context, data interface{}) {
+	alert, ok := data.(bus.AgentAlert)
+	if !ok {
+		s.logger.Warn("invalid AgentAlert payload", "type", fmt.Sprintf("%T", data))
+		return
+	}
+	emoji := "ℹ️"
+	switch alert.Severity {
+	case "warning":
+		emoji = "⚠️"
+	case "error":
+		emoji = "🚨"
+	}
+	text := fmt.Sprintf("%s *%s alert*\n%s", emoji, slackEscape(alert.Severity), slackEscape(alert.Message))
+	for _, target := range s.notifyTargets() {
+		s.post(ctx, target, text)
+	}
+}
+
+// notifyTargets returns where notifications not tied to a thread go: the
+// notify channel, or else each allowed user's DM with the bot.
+func (s *SlackChannel) notifyTargets() []slackThread {
+	if s.opts.NotifyChannel != "" {
+		return []slackThread{{channel: s.opts.NotifyChannel}}
+	}
+	targets := make([]slackThread, 0, len(s.allowed))
+	for userID := range s.allowed {
+		targets = append(targets, slackThread{channel: userID})
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].channel < targets[j].channel })
+	return targets
+}
+
+// slackActionLabels are the button labels of approval actions.
+var slackActionLabels = map[string]string{
+	"approve":         "Approve",
+	"approve_session": "Approve for session",
+	"reject":          "Reject",
+	"deny":            "Deny",
+}
+
+// slackApprovalBlocks renders an approval request as a section and a row of
+// buttons whose values use the "hitl:requestID:action" callback format.
+func slackApprovalBlocks(text, requestID string, actions ...string) []slackBlock {
+	buttons := make([]slackBlock, 0, len(actions))
+	for _, action := range actions {
+		button := slackBlock{
+			"type":      "button",
+			"action_id": "hitl_" + action,
+			"text":      map[string]string{"type": "plain_text", "text": slackActionLabels[action]},
+			"value":     fmt.Sprintf("hitl:%s:%s", requestID, action),
+		}
+		switch action {
+		case "approve":
+			button["style"] = "primary"
+		case "reject", "deny":
+			button["style"] = "danger"
+		}
+		buttons = append(buttons, button)
+	}
+	return []slackBlock{
+		slackSection(text),
+		{"type": "actions", "block_id": "hitl:" + requestID, "elements": buttons},
+	}
+}
+
+func slackSection(text string) slackBlock {
+	return slackBlock{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": text}}
+}
+
+// slackDecisionLabel describes an approval action or resolved status.
+func slackDecisionLabel(decision string) string {
+	switch strings.ToLower(decision) {
+	case "approve", "approved":
+		return "Approved"
+	case "approve_session", "approved_session":
+		return "Approved for this session"
+	case "reject", "rejected":
+		return "Rejected"
+	case "deny", "denied":
+		return "Denied"
+	case "expired":
+		return "Expired"
+	}
+	return decision
+}
+
+// post sends a message in thread and returns its timestamp, or "" on error.
+func (s *SlackChannel) post(ctx context.Context, thread slackThread, text string) string {
+	ts, err := s.api.postMessage(ctx, slackPost{Channel: thread.channel, ThreadTS: thread.ts, Text: text})
+	if err != nil {
+		s.logger.Error("failed to send slack message", "channel", thread.channel, "error", err)
+		return ""
+	}
+	return ts
+}
+
+// update replaces the text of a sent message.
+func (s *SlackChannel) update(ctx context.Context, channel, ts, text string) {
+	if err := s.api.updateMessage(ctx, slackPost{Channel: channel, TS: ts, Text: text}); err != nil {
+		s.logger.Warn("failed to update slack message", "channel", channel, "error", err)
+	}
+}
+
+// slackEscape escapes the characters Slack reserves for its control
+// sequences (mentions and links) in message text.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// slackUnescape reverses slackEscape for text received from Slack.
+func slackUnescape(s string) string {
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&").Replace(s)
+}
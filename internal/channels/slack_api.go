@@ -0,0 +1,137 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultSlackAPIURL is the base URL of the Slack Web API.
+const DefaultSlackAPIURL = "https://slack.com/api/"
+
+// slackAPI is a minimal client for the Slack Web API methods the channel
+// uses. Requests are JSON POSTs authorized with the bot token, except
+// apps.connections.open which takes the app-level token.
+type slackAPI struct {
+	baseURL  string
+	botToken string
+	appToken string
+	client   *http.Client
+}
+
+func newSlackAPI(baseURL, botToken, appToken string) *slackAPI {
+	baseURL = strings.TrimSpace(baseURL)
+	if baseURL == "" {
+		baseURL = DefaultSlackAPIURL
+	}
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &slackAPI{
+		baseURL:  baseURL,
+		botToken: botToken,
+		appToken: appToken,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// slackPost is the body of chat.postMessage and chat.update.
+type slackPost struct {
+	Channel  string       `json:"channel"`
+	TS       string       `json:"ts,omitempty"`        // chat.update only
+	ThreadTS string       `json:"thread_ts,omitempty"` // chat.postMessage only
+	Text     string       `json:"text"`
+	Blocks   []slackBlock `json:"blocks,omitempty"`
+}
+
+// slackBlock is a Block Kit block.
+type slackBlock map[string]any
+
+// call invokes method and decodes the response into out (may be nil). A
+// response with "ok": false is returned as an error naming Slack's error code.
+func (a *slackAPI) call(ctx context.Context, method, token string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("slack %s: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+method, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("slack %s: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("slack %s: read response: %w", method, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack %s: status %d", method, resp.StatusCode)
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("slack %s: decode response: %w", method, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("slack %s: %s", method, status.Error)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("slack %s: decode response: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// authTest returns the bot's user ID and workspace (team) ID.
+func (a *slackAPI) authTest(ctx context.Context) (userID, teamID string, err error) {
+	var out struct {
+		UserID string `json:"user_id"`
+		TeamID string `json:"team_id"`
+	}
+	if err := a.call(ctx, "auth.test", a.botToken, struct{}{}, &out); err != nil {
+		return "", "", err
+	}
+	return out.UserID, out.TeamID, nil
+}
+
+// openConnection returns a Socket Mode WebSocket URL.
+func (a *slackAPI) openConnection(ctx context.Context) (string, error) {
+	var out struct {
+		URL string `json:"url"`
+	}
+	if err := a.call(ctx, "apps.connections.open", a.appToken, struct{}{}, &out); err != nil {
+		return "", err
+	}
+	return out.URL, nil
+}
+
+// postMessage sends a message and returns its timestamp, which identifies it
+// for later updates and threading.
+func (a *slackAPI) postMessage(ctx context.Context, msg slackPost) (string, error) {
+	var out struct {
+		TS string `json:"ts"`
+	}
+	if err := a.call(ctx, "chat.postMessage", a.botToken, msg, &out); err != nil {
+		return "", err
+	}
+	return out.TS, nil
+}
+
+// updateMessage replaces the text and blocks of a sent message.
+func (a *slackAPI) updateMessage(ctx context.Context, msg slackPost) error {
+	return a.call(ctx, "chat.update", a.botToken, msg, nil)
+}
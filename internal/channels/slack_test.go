@@ -0,0 +1,399 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/coder/websocket"
+)
+
+// fakeSlack serves the Slack Web API methods the channel uses and a Socket
+// Mode WebSocket, recording the messages sent and the envelopes acked.
+type fakeSlack struct {
+	srv    *httptest.Server
+	calls  chan fakeSlackCall
+	acks   chan string
+	conns  chan *websocket.Conn
+	mu     sync.Mutex
+	lastTS int
+}
+
+type fakeSlackCall struct {
+	method string
+	body   map[string]any
+	ts     string // of the posted message
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack {
+	t.Helper()
+	f := &fakeSlack{
+		calls: make(chan fakeSlackCall, 100),
+		acks:  make(chan string, 100),
+		conns: make(chan *websocket.Conn, 1),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer x") {
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": "not_authed"})
+			return
+		}
+		method := strings.TrimPrefix(r.URL.Path, "/api/")
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		resp := map[string]any{"ok": true}
+		switch method {
+		case "auth.test":
+			resp["user_id"], resp["team_id"] = "UBOT", "T1"
+		case "apps.connections.open":
+			resp["url"] = "ws" + strings.TrimPrefix(f.srv.URL, "http") + "/socket"
+		case "chat.postMessage":
+			f.mu.Lock()
+			f.lastTS++
+			ts := fmt.Sprintf("2000.%06d", f.lastTS)
+			f.mu.Unlock()
+			resp["ts"] = ts
+			f.calls <- fakeSlackCall{method, body, ts}
+		case "chat.update":
+			f.calls <- fakeSlackCall{method, body, ""}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("/socket", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		_ = conn.Write(r.Context(), websocket.MessageText, []byte(`{"type":"hello"}`))
+		f.conns <- conn
+		for {
+			_, data, err := conn.Read(r.Context())
+			if err != nil {
+				return
+			}
+			var ack struct {
+				EnvelopeID string `json:"envelope_id"`
+			}
+			_ = json.Unmarshal(data, &ack)
+			f.acks <- ack.EnvelopeID
+		}
+	})
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+// send delivers an envelope over the socket and waits for its ack.
+func (f *fakeSlack) send(t *testing.T, conn *websocket.Conn, envelopeID, typ string, payload any) {
+	t.Helper()
+	data, _ := json.Marshal(map[string]any{"envelope_id": envelopeID, "type": typ, "payload": payload})
+	if err := conn.Write(context.Background(), websocket.MessageText, data); err != nil {
+		t.Fatalf("write envelope: %v", err)
+	}
+	select {
+	case id := <-f.acks:
+		if id != envelopeID {
+			t.Fatalf("acked %q, want %q", id, envelopeID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("envelope %s was not acked", envelopeID)
+	}
+}
+
+// expect returns the next Web API call, which must be of method.
+func (f *fakeSlack) expect(t *testing.T, method string) fakeSlackCall {
+	t.Helper()
+	select {
+	case c := <-f.calls:
+		if c.method != method {
+			t.Fatalf("got %s %v, want %s", c.method, c.body, method)
+		}
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no %s call", method)
+	}
+	return fakeSlackCall{}
+}
+
+// expectNone fails if a Web API call is made.
+func (f *fakeSlack) expectNone(t *testing.T) {
+	t.Helper()
+	select {
+	case c := <-f.calls:
+		t.Fatalf("unexpected %s %v", c.method, c.body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// fakeChatRouter creates chat tasks in the store and records them.
+type fakeChatRouter struct {
+	store *persistence.Store
+	tasks chan fakeChatTask
+}
+
+type fakeChatTask struct {
+	id, agentID, sessionID, content string
+}
+
+func (r *fakeChatRouter) CreateChatTask(ctx context.Context, agentID, sessionID, content string) (string, error) {
+	if err := r.store.EnsureSession(ctx, sessionID); err != nil {
+		return "", err
+	}
+	payload, _ := json.Marshal(map[string]string{"content": content})
+	id, err := r.store.CreateTask(ctx, sessionID, string(payload))
+	if err != nil {
+		return "", err
+	}
+	r.tasks <- fakeChatTask{id, agentID, sessionID, content}
+	return id, nil
+}
+
+func (r *fakeChatRouter) CreateMessageTask(ctx context.Context, agentID, sessionID, content string, _ int) (string, error) {
+	return r.CreateChatTask(ctx, agentID, sessionID, content)
+}
+
+func (r *fakeChatRouter) next(t *testing.T) fakeChatTask {
+	t.Helper()
+	select {
+	case task := <-r.tasks:
+		return task
+	case <-time.After(5 * time.Second):
+		t.Fatal("no task created")
+	}
+	return fakeChatTask{}
+}
+
+type fakePlanStarter struct{}
+
+func (fakePlanStarter) StartPlan(_ context.Context, planName, _ string, _ map[string]any) (string, string, error) {
+	return "exec-" + planName, "", nil
+}
+
+// startSlack starts a Slack channel against a fake Slack and returns the
+// socket connection the channel opened.
+func startSlack(t *testing.T, opts SlackOptions) (*SlackChannel, *fakeSlack, *fakeChatRouter, *bus.Bus, *websocket.Conn) {
+	t.Helper()
+	f := newFakeSlack(t)
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "test.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	opts.BotToken, opts.AppToken, opts.APIURL = "xoxb-test", "xapp-test", f.srv.URL+"/api"
+	router := &fakeChatRouter{store: store, tasks: make(chan fakeChatTask, 10)}
+	eventBus := bus.New()
+	sc := NewSlackChannel(opts, router, store, slog.Default(), eventBus)
+	sc.SetPlanStarter(fakePlanStarter{})
+	sc.SubscribeToEvents()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- sc.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	select {
+	case conn := <-f.conns:
+		return sc, f, router, eventBus, conn
+	case err := <-done:
+		t.Fatalf("Start: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel did not connect")
+	}
+	return nil, nil, nil, nil, nil
+}
+
+func slackMessageEvent(fields map[string]any) map[string]any {
+	return map[string]any{"team_id": "T1", "event": fields}
+}
+
+func TestSlackChannel_ThreadedConversation(t *testing.T) {
+	_, f, router, eventBus, conn := startSlack(t, SlackOptions{AllowedUserIDs: []string{"U1"}})
+	ctx := context.Background()
+
+	// A mention in a channel starts a thread with the named agent.
+	f.send(t, conn, "e1", "events_api", slackMessageEvent(map[string]any{
+		"type": "app_mention", "user": "U1", "channel": "C1", "ts": "100.1",
+		"text": "<@UBOT> @coder fix the build &amp; deploy",
+	}))
+	task := router.next(t)
+	if task.agentID != "coder" || task.content != "fix the build & deploy" {
+		t.Fatalf("task = %+v, want coder with the mention stripped", task)
+	}
+
+	// Streamed tokens are posted in the thread, then the final reply edits it.
+	eventBus.Publish(bus.TopicStreamToken, map[string]string{"task_id": task.id, "chunk": "Work"})
+	eventBus.Publish(bus.TopicStreamToken, map[string]string{"task_id": task.id, "chunk": "ing"})
+	first := f.expect(t, "chat.postMessage")
+	if first.body["thread_ts"] != "100.1" || first.body["text"] != "Work" {
+		t.Fatalf("first chunk = %v", first.body)
+	}
+	claimed, err := router.store.ClaimNextPendingTask(ctx)
+	if err != nil || claimed == nil {
+		t.Fatalf("claim task: %v", err)
+	}
+	if err := router.store.StartTaskRun(ctx, claimed.ID, claimed.LeaseOwner, ""); err != nil {
+		t.Fatalf("StartTaskRun: %v", err)
+	}
+	if err := router.store.CompleteTask(ctx, task.id, `{"reply":"Working <done>"}`); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	eventBus.Publish("task.succeeded", map[string]string{"task_id": task.id})
+	final := f.expect(t, "chat.update")
+	if final.body["ts"] != first.ts || final.body["text"] != "Working &lt;done&gt;" {
+		t.Fatalf("final edit = %v", final.body)
+	}
+
+	// Replies in the thread go to the same agent and session without a mention.
+	f.send(t, conn, "e2", "events_api", slackMessageEvent(map[string]any{
+		"type": "message", "channel_type": "channel", "user": "U1", "channel": "C1",
+		"ts": "100.2", "thread_ts": "100.1", "text": "and the tests",
+	}))
+	followUp := router.next(t)
+	if followUp.agentID != "coder" || followUp.sessionID != task.sessionID {
+		t.Fatalf("follow-up = %+v, want the thread's agent and session", followUp)
+	}
+
+	// Ignored: channel messages outside the bot's threads, copies of mentions,
+	// the bot's own messages and users not on the allowlist.
+	ignored := []map[string]any{
+		{"type": "message", "channel_type": "channel", "user": "U1", "channel": "C1", "ts": "100.3", "text": "hi"},
+		{"type": "message", "channel_type": "channel", "user": "U1", "channel": "C1", "ts": "100.4", "text": "<@UBOT> hi"},
+		{"type": "message", "channel_type": "im", "user": "UBOT", "bot_id": "B1", "channel": "D1", "ts": "100.5", "text": "hi"},
+		{"type": "message", "channel_type": "im", "user": "U2", "channel": "D2", "ts": "100.6", "text": "hi"},
+	}
+	for i, ev := range ignored {
+		f.send(t, conn, fmt.Sprintf("ignored-%d", i), "events_api", slackMessageEvent(ev))
+	}
+
+	// A direct message is answered at the top level of the DM.
+	f.send(t, conn, "e3", "events_api", slackMessageEvent(map[string]any{
+		"type": "message", "channel_type": "im", "user": "U1", "channel": "D1", "ts": "100.7", "text": "hello",
+	}))
+	dm := router.next(t)
+	if dm.agentID != "default" || dm.sessionID == task.sessionID {
+		t.Fatalf("dm task = %+v", dm)
+	}
+	select {
+	case extra := <-router.tasks:
+		t.Fatalf("ignored message created task %+v", extra)
+	default:
+	}
+	eventBus.Publish("task.canceled", map[string]string{"task_id": dm.id})
+	canceled := f.expect(t, "chat.postMessage")
+	if canceled.body["channel"] != "D1" || canceled.body["thread_ts"] != nil {
+		t.Errorf("cancel notice = %v, want top level of D1", canceled.body)
+	}
+}
+
+func TestSlackChannel_ApprovalButtons(t *testing.T) {
+	_, f, _, eventBus, conn := startSlack(t, SlackOptions{AllowedUserIDs: []string{"U1"}, NotifyChannel: "COPS"})
+	responses := eventBus.Subscribe(bus.TopicHITLApprovalResponse)
+	defer eventBus.Unsubscribe(responses)
+
+	eventBus.Publish(bus.TopicHITLApprovalRequested, bus.HITLApprovalRequest{
+		RequestID: "req-1", ExecutionID: "exec-1", StepID: "deploy", Prompt: "Ship it?",
+	})
+	posted := f.expect(t, "chat.postMessage")
+	if posted.body["channel"] != "COPS" {
+		t.Fatalf("approval posted to %v, want the notify channel", posted.body["channel"])
+	}
+	blocks, _ := json.Marshal(posted.body["blocks"])
+	for _, want := range []string{`"hitl:req-1:approve"`, `"hitl:req-1:reject"`, `"type":"actions"`} {
+		if !strings.Contains(string(blocks), want) {
+			t.Errorf("blocks %s missing %s", blocks, want)
+		}
+	}
+
+	// A click from a user not on the allowlist is ignored.
+	click := func(id, user string) {
+		f.send(t, conn, id, "interactive", map[string]any{
+			"type":    "block_actions",
+			"user":    map[string]string{"id": user, "username": "ana"},
+			"channel": map[string]string{"id": "COPS"},
+			"message": map[string]string{"ts": posted.ts, "text": "Ship it?"},
+			"actions": []map[string]string{{"action_id": "hitl_approve", "value": "hitl:req-1:approve"}},
+		})
+	}
+	click("i1", "U2")
+	f.expectNone(t)
+
+	click("i2", "U1")
+	select {
+	case ev := <-responses.Ch():
+		resp, _ := ev.Payload.(bus.HITLApprovalResponse)
+		if resp.RequestID != "req-1" || resp.Action != "approve" || resp.Reason != "via Slack (ana)" {
+			t.Errorf("response = %+v", resp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no approval response published")
+	}
+	updated := f.expect(t, "chat.update")
+	blocks, _ = json.Marshal(updated.body["blocks"])
+	if strings.Contains(string(blocks), "actions") || !strings.Contains(updated.body["text"].(string), "Approved by <@U1>") {
+		t.Errorf("resolved message = %v", updated.body)
+	}
+}
+
+func TestSlackChannel_PlanProgressInThread(t *testing.T) {
+	sc, f, _, eventBus, conn := startSlack(t, SlackOptions{AllowedUserIDs: []string{"U1"}})
+
+	f.send(t, conn, "s1", "slash_commands", map[string]string{
+		"command": "/plan", "text": "run release", "user_id": "U1", "channel_id": "C1",
+	})
+	reply := f.expect(t, "chat.postMessage")
+	if !strings.Contains(reply.body["text"].(string), "exec-release") {
+		t.Fatalf("reply = %v", reply.body)
+	}
+	// The execution is tracked once the reply is sent.
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		sc.mu.Lock()
+		p := sc.executions["exec-release"]
+		tracked := p != nil && p.thread.ts == reply.ts
+		sc.mu.Unlock()
+		if tracked {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("execution not tracked in the reply's thread")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	eventBus.Publish(bus.TopicPlanExecutionStarted, map[string]interface{}{
+		"execution_id": "exec-release", "plan_name": "release", "total_steps": 2,
+	})
+	eventBus.Publish(bus.TopicPlanStepCompleted, map[string]interface{}{
+		"execution_id": "exec-release", "step_id": "build", "status": "succeeded",
+	})
+	progress := f.expect(t, "chat.postMessage")
+	if progress.body["thread_ts"] != reply.ts || !strings.Contains(progress.body["text"].(string), "(1/2 steps)\n✅ build") {
+		t.Fatalf("progress = %v", progress.body)
+	}
+
+	eventBus.Publish(bus.TopicPlanStepCompleted, map[string]interface{}{
+		"execution_id": "exec-release", "step_id": "publish", "status": "failed", "error": "registry down",
+	})
+	eventBus.Publish(bus.TopicPlanExecutionCompleted, map[string]interface{}{
+		"execution_id": "exec-release", "status": "failed",
+	})
+	f.expect(t, "chat.update")
+	done := f.expect(t, "chat.update")
+	text := done.body["text"].(string)
+	if done.body["ts"] != progress.ts || !strings.Contains(text, "failed (2/2 steps)") || !strings.Contains(text, "❌ publish: registry down") {
+		t.Errorf("final progress = %v", done.body)
+	}
+}
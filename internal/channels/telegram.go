@@ -2,16 +2,21 @@ package channels
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/basket/go-claw/internal/bus"
 	"github.com/basket/go-claw/internal/engine"
+	"github.com/basket/go-claw/internal/media"
 	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/shared"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
@@ -34,6 +39,35 @@ type TelegramChannel struct {
 
 	// GC-SPEC-PDR-v7-Phase-3: Event subscriptions for plan execution
 	eventSubs []*bus.Subscription // Subscriptions to clean up on shutdown
+
+	plans  PlanStarter    // runs /plan commands; nil disables them
+	agents AgentDirectory // backs the operator commands; nil disables them
+
+	apiEndpoint string // Bot API URL format; tests point it at a fake server
+
+	// Webhook mode: when webhookURL is set, Start registers it with Telegram
+	// and processes the updates Handler receives instead of long polling.
+	webhookURL    string
+	webhookSecret string
+	updates       chan tgbotapi.Update
+}
+
+// SetPlanStarter enables the /plan command.
+func (t *TelegramChannel) SetPlanStarter(p PlanStarter) {
+	t.plans = p
+}
+
+// SetAgentDirectory enables the operator commands that inspect agents:
+// /agents, /status, /model and /cancel.
+func (t *TelegramChannel) SetAgentDirectory(d AgentDirectory) {
+	t.agents = d
+}
+
+// SetWebhook switches the channel to webhook mode. Telegram posts updates to
+// url, which must be routed to Handler; each request has to carry secret.
+func (t *TelegramChannel) SetWebhook(url, secret string) {
+	t.webhookURL = url
+	t.webhookSecret = secret
 }
 
 // streamState tracks progressive editing for a streaming task.
@@ -63,6 +97,8 @@ func NewTelegramChannel(token string, allowedIDs []int64, router engine.ChatTask
 		eventBus:     eb,
 		pendingTasks: make(map[string]int64),
 		streamMsgs:   make(map[string]*streamState),
+		apiEndpoint:  tgbotapi.APIEndpoint,
+		updates:      make(chan tgbotapi.Update, 100),
 	}
 }
 
@@ -72,16 +108,30 @@ func (t *TelegramChannel) Name() string {
 
 func (t *TelegramChannel) Start(ctx context.Context) error {
 	var err error
-	t.bot, err = tgbotapi.NewBotAPI(t.token)
+	t.bot, err = tgbotapi.NewBotAPIWithAPIEndpoint(t.token, t.apiEndpoint)
 	if err != nil {
 		return fmt.Errorf("telegram init failed: %w", err)
 	}
 
-	t.logger.Info("telegram bot started", "user", t.bot.Self.UserName)
+	t.logger.Info("telegram bot started", "user", t.bot.Self.UserName, "webhook", t.webhookURL != "")
+
+	if _, err := t.bot.Request(tgbotapi.NewSetMyCommands(telegramCommands...)); err != nil {
+		t.logger.Warn("failed to register telegram commands", "error", err)
+	}
 
 	// Monitor task completions to send replies via event bus or polling fallback.
 	go t.monitorCompletions(ctx)
 
+	if t.webhookURL != "" {
+		return t.runWebhook(ctx)
+	}
+
+	// getUpdates is refused while a webhook is registered, e.g. after
+	// switching a deployment back from webhook mode.
+	if _, err := t.bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		t.logger.Warn("failed to delete telegram webhook", "error", err)
+	}
+
 	// Reconnection loop with exponential backoff.
 	backoff := time.Second
 	const maxBackoff = 30 * time.Second
@@ -147,26 +197,7 @@ func (t *TelegramChannel) pollUpdates(ctx context.Context, updates tgbotapi.Upda
 				}
 			}
 			timer.Reset(stallTimeout)
-
-			// Handle text messages
-			if update.Message != nil {
-				if _, ok := t.allowedIDs[update.Message.From.ID]; !ok {
-					t.logger.Warn("telegram access denied", "user_id", update.Message.From.ID, "user_name", update.Message.From.UserName)
-					continue
-				}
-				t.handleMessage(ctx, update.Message)
-				continue
-			}
-
-			// Handle inline button callbacks (HITL approvals)
-			if update.CallbackQuery != nil {
-				if _, ok := t.allowedIDs[update.CallbackQuery.From.ID]; !ok {
-					t.logger.Warn("telegram callback access denied", "user_id", update.CallbackQuery.From.ID)
-					continue
-				}
-				t.handleCallbackQuery(ctx, update.CallbackQuery)
-				continue
-			}
+			t.handleUpdate(ctx, update)
 
 		case <-timer.C:
 			return fmt.Errorf("no updates received for %v (possible disconnect)", stallTimeout)
@@ -174,9 +205,114 @@ func (t *TelegramChannel) pollUpdates(ctx context.Context, updates tgbotapi.Upda
 	}
 }
 
+// runWebhook registers the webhook with Telegram and processes the updates
+// Handler receives until ctx is done. The webhook stays registered on
+// shutdown so Telegram holds updates until the next start.
+func (t *TelegramChannel) runWebhook(ctx context.Context) error {
+	params := tgbotapi.Params{"url": t.webhookURL, "secret_token": t.webhookSecret}
+	if err := params.AddInterface("allowed_updates", []string{"message", "callback_query"}); err != nil {
+		return fmt.Errorf("telegram set webhook: %w", err)
+	}
+	if _, err := t.bot.MakeRequest("setWebhook", params); err != nil {
+		return fmt.Errorf("telegram set webhook: %w", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case update := <-t.updates:
+			t.handleUpdate(ctx, update)
+		}
+	}
+}
+
+// TelegramSecretHeader carries the webhook secret on each update Telegram posts.
+const TelegramSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Handler serves the webhook Telegram posts updates to. It answers 404 when
+// the channel is not in webhook mode and 401 when the secret token does not
+// match; a full update queue answers 503 so Telegram redelivers later.
+func (t *TelegramChannel) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t.webhookURL == "" || t.webhookSecret == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(TelegramSecretHeader)), []byte(t.webhookSecret)) != 1 {
+			t.logger.Warn("telegram webhook rejected: bad secret token", "remote", r.RemoteAddr)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var update tgbotapi.Update
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&update); err != nil {
+			http.Error(w, "invalid update", http.StatusBadRequest)
+			return
+		}
+		select {
+		case t.updates <- update:
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// handleUpdate checks the sender against the allowlist and dispatches a
+// message or button callback.
+func (t *TelegramChannel) handleUpdate(ctx context.Context, update tgbotapi.Update) {
+	// Handle text, photo and document messages
+	if msg := update.Message; msg != nil {
+		if msg.From == nil {
+			return
+		}
+		if _, ok := t.allowedIDs[msg.From.ID]; !ok {
+			t.logger.Warn("telegram access denied", "user_id", msg.From.ID, "user_name", msg.From.UserName)
+			return
+		}
+		t.handleMessage(ctx, msg)
+		return
+	}
+
+	// Handle inline button callbacks (HITL approvals)
+	if update.CallbackQuery != nil {
+		if _, ok := t.allowedIDs[update.CallbackQuery.From.ID]; !ok {
+			t.logger.Warn("telegram callback access denied", "user_id", update.CallbackQuery.From.ID)
+			return
+		}
+		t.handleCallbackQuery(ctx, update.CallbackQuery)
+	}
+}
+
 func (t *TelegramChannel) handleMessage(ctx context.Context, msg *tgbotapi.Message) {
 	content := strings.TrimSpace(msg.Text)
 	if content == "" {
+		content = strings.TrimSpace(msg.Caption)
+	}
+	if msg.Chat.IsGroup() || msg.Chat.IsSuperGroup() {
+		// In groups the bot only answers when it is addressed: a mention,
+		// a reply to one of its messages, or a command.
+		var mentioned bool
+		content, mentioned = stripMention(content, t.bot.Self.UserName)
+		repliedTo := msg.ReplyToMessage != nil && msg.ReplyToMessage.From != nil && msg.ReplyToMessage.From.ID == t.bot.Self.ID
+		if !mentioned && !repliedTo && !strings.HasPrefix(content, "/") {
+			return
+		}
+	}
+	if strings.HasPrefix(content, "/") {
+		t.handleCommand(ctx, msg, content)
+		return
+	}
+	atts, err := messageAttachments(ctx, msg, t.downloadFile)
+	if err != nil {
+		t.logger.Warn("failed to read telegram attachment", "error", err)
+		t.reply(msg.Chat.ID, fmt.Sprintf("Error: could not read attachment: %v", err))
+		return
+	}
+	if content == "" && len(atts) == 0 {
 		return
 	}
 
@@ -192,11 +328,21 @@ func (t *TelegramChannel) handleMessage(ctx context.Context, msg *tgbotapi.Messa
 		}
 	}
 	if content == "" {
-		return
+		if len(atts) == 0 {
+			return
+		}
+		content = media.DefaultPrompt
+	}
+	if len(atts) > 0 {
+		if mc, ok := t.router.(mediaChecker); ok && media.NeedsVision(atts) && !mc.SupportsMedia(agentID) {
+			t.reply(msg.Chat.ID, fmt.Sprintf("Agent %q cannot read images or PDFs with its current model. Send text, or pick an agent with a vision-capable model.", agentID))
+			return
+		}
+		ctx = shared.WithAttachments(ctx, atts)
 	}
 
-	// Map Telegram user+agent to a persistent session ID (per-agent isolation).
-	sessionID := fmt.Sprintf("telegram-%d-agent-%s", msg.From.ID, agentID)
+	// Map the chat and agent to a persistent session ID (per-agent isolation).
+	sessionID := t.sessionID(ctx, msg.Chat.ID, agentID)
 
 	// Route through ChatTaskRouter (handles session, history, task creation).
 	taskID, err := t.router.CreateChatTask(ctx, agentID, sessionID, content)
@@ -219,6 +365,82 @@ func (t *TelegramChannel) handleMessage(ctx context.Context, msg *tgbotapi.Messa
 	}
 }
 
+// handlePlanCommand runs a /plan command and replies in the chat.
+func (t *TelegramChannel) handlePlanCommand(ctx context.Context, chatID int64, content string) {
+	runPlanCommand(ctx, t.plans, content, func(text string) { t.reply(chatID, text) }, t.logger.With("channel", "telegram"))
+}
+
+// mediaChecker is implemented by routers that know whether an agent's model
+// accepts image and PDF attachments.
+type mediaChecker interface {
+	SupportsMedia(agentID string) bool
+}
+
+// fileFetcher downloads a file the user sent, by Telegram file ID.
+type fileFetcher func(ctx context.Context, fileID string, size int) ([]byte, error)
+
+// messageAttachments collects the photo and document of a message as
+// attachments. Of a photo's sizes only the largest is used.
+func messageAttachments(ctx context.Context, msg *tgbotapi.Message, fetch fileFetcher) ([]shared.Attachment, error) {
+	var raw []shared.Attachment
+	if n := len(msg.Photo); n > 0 {
+		photo := msg.Photo[n-1]
+		data, err := fetch(ctx, photo.FileID, photo.FileSize)
+		if err != nil {
+			return nil, fmt.Errorf("photo: %w", err)
+		}
+		raw = append(raw, shared.Attachment{MimeType: "image/jpeg", Name: "photo.jpg", Data: data})
+	}
+	if doc := msg.Document; doc != nil {
+		data, err := fetch(ctx, doc.FileID, doc.FileSize)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", doc.FileName, err)
+		}
+		raw = append(raw, shared.Attachment{MimeType: doc.MimeType, Name: doc.FileName, Data: data})
+	}
+	atts := make([]shared.Attachment, 0, len(raw))
+	for _, a := range raw {
+		prepared, err := media.Prepare(a)
+		if err != nil {
+			return nil, err
+		}
+		atts = append(atts, prepared)
+	}
+	return atts, nil
+}
+
+// downloadFile fetches a file from the Telegram file API.
+func (t *TelegramChannel) downloadFile(ctx context.Context, fileID string, size int) ([]byte, error) {
+	if size > media.MaxAttachmentBytes {
+		return nil, fmt.Errorf("file is %d bytes; the limit is %d", size, media.MaxAttachmentBytes)
+	}
+	url, err := t.bot.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("get file url: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build download request: %w", err)
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download file: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download file: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, media.MaxAttachmentBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	if len(data) > media.MaxAttachmentBytes {
+		return nil, fmt.Errorf("file exceeds the %d byte limit", media.MaxAttachmentBytes)
+	}
+	return data, nil
+}
+
 // handleCallbackQuery handles inline button clicks from HITL approval messages.
 func (t *TelegramChannel) handleCallbackQuery(ctx context.Context, query *tgbotapi.CallbackQuery) {
 	// Parse the callback data (format: "hitl:requestID:action")
@@ -238,7 +460,7 @@ func (t *TelegramChannel) handleCallbackQuery(ctx context.Context, query *tgbota
 	if t.eventBus != nil {
 		response := bus.HITLApprovalResponse{
 			RequestID: requestID,
-			Action:    action, // "approve" or "reject"
+			Action:    action, // "approve" or "reject"; tool approvals also use "approve_session" and "deny"
 			Reason:    fmt.Sprintf("via Telegram (%s)", query.From.UserName),
 		}
 		t.eventBus.Publish(bus.TopicHITLApprovalResponse, response)
@@ -494,7 +716,11 @@ func (t *TelegramChannel) SubscribeToEvents() {
 		t.eventBus.Subscribe(bus.TopicPlanStepStarted),
 		t.eventBus.Subscribe(bus.TopicPlanStepCompleted),
 		t.eventBus.Subscribe(bus.TopicPlanStepFailed),
+		t.eventBus.Subscribe(bus.TopicPlanExecutionPaused),
+		t.eventBus.Subscribe(bus.TopicPlanExecutionResumed),
+		t.eventBus.Subscribe(bus.TopicPlanExecutionCanceled),
 		t.eventBus.Subscribe(bus.TopicHITLApprovalRequested),
+		t.eventBus.Subscribe(bus.TopicToolApprovalRequested),
 		t.eventBus.Subscribe(bus.TopicAgentAlert),
 	}
 
@@ -523,8 +749,12 @@ func (t *TelegramChannel) handleEvent(ev *bus.Event) {
 		go t.onPlanStepCompleted(ev.Payload)
 	case bus.TopicPlanStepFailed:
 		go t.onPlanStepFailed(ev.Payload)
+	case bus.TopicPlanExecutionPaused, bus.TopicPlanExecutionResumed, bus.TopicPlanExecutionCanceled:
+		go t.onPlanExecutionControl(ev.Topic, ev.Payload)
 	case bus.TopicHITLApprovalRequested:
 		go t.onHITLRequest(ev.Payload)
+	case bus.TopicToolApprovalRequested:
+		go t.onToolApprovalRequest(ev.Payload)
 	case bus.TopicAgentAlert:
 		go t.onAgentAlert(ev.Payload)
 	}
@@ -601,6 +831,61 @@ func (t *TelegramChannel) onHITLRequest(data interface{}) {
 	}
 }
 
+// onToolApprovalRequest asks allowed chats to decide on a gated tool call.
+// Button presses come back through handleCallbackQuery like plan-step
+// approvals; the approval broker picks them up from the bus.
+func (t *TelegramChannel) onToolApprovalRequest(data interface{}) {
+	req, ok := data.(bus.ToolApprovalRequest)
+	if !ok {
+		t.logger.Warn("invalid ToolApprovalRequest payload", "type", fmt.Sprintf("%T", data))
+		return
+	}
+
+	keyboard := toolApprovalKeyboard(req.RequestID)
+	msg := fmt.Sprintf("🔐 *Tool Approval Required*\n\nAgent: `%s`\nTool: `%s`\n\nArguments:\n```\n%s\n```",
+		escapeMarkdownV2(req.AgentID),
+		escapeMarkdownV2(req.Tool),
+		escapeMarkdownV2(req.Args))
+
+	for chatID := range t.allowedIDs {
+		t.replyMarkdownWithKeyboard(chatID, msg, &keyboard)
+	}
+}
+
+// toolApprovalKeyboard builds the approve / approve-for-session / deny buttons.
+func toolApprovalKeyboard(requestID string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Approve", fmt.Sprintf("hitl:%s:approve", requestID)),
+			tgbotapi.NewInlineKeyboardButtonData("✅ Session", fmt.Sprintf("hitl:%s:approve_session", requestID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Deny", fmt.Sprintf("hitl:%s:deny", requestID)),
+		),
+	)
+}
+
+// onPlanExecutionControl reports a paused, resumed or canceled plan execution.
+func (t *TelegramChannel) onPlanExecutionControl(topic string, data interface{}) {
+	payload, ok := data.(map[string]interface{})
+	if !ok {
+		t.logger.Warn("invalid plan execution payload", "type", fmt.Sprintf("%T", data))
+		return
+	}
+	execID, _ := payload["execution_id"].(string)
+	emoji, verb := "⏸", "paused"
+	switch topic {
+	case bus.TopicPlanExecutionResumed:
+		emoji, verb = "▶️", "resumed"
+	case bus.TopicPlanExecutionCanceled:
+		emoji, verb = "⏹", "canceled"
+	}
+	msg := fmt.Sprintf("%s Execution `%s` %s", emoji, escapeMarkdownV2(execID), verb)
+
+	// Send to all allowed chats
+	for chatID := range t.allowedIDs {
+		t.replyMarkdown(chatID, msg)
+	}
+}
+
 // onAgentAlert handles agent alert notifications.
 func (t *TelegramChannel) onAgentAlert(data interface{}) {
 	alert, ok := data.(bus.AgentAlert)
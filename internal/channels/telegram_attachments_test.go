@@ -0,0 +1,53 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestMessageAttachments(t *testing.T) {
+	files := map[string][]byte{
+		"large": []byte("\xff\xd8\xff\xe0 jpeg"),
+		"doc":   []byte("# Meeting notes"),
+	}
+	var fetched []string
+	fetch := func(_ context.Context, fileID string, _ int) ([]byte, error) {
+		fetched = append(fetched, fileID)
+		if data, ok := files[fileID]; ok {
+			return data, nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	msg := &tgbotapi.Message{
+		Caption:  "summarize",
+		Photo:    []tgbotapi.PhotoSize{{FileID: "small"}, {FileID: "large"}},
+		Document: &tgbotapi.Document{FileID: "doc", FileName: "notes.md", MimeType: "text/markdown"},
+	}
+	atts, err := messageAttachments(context.Background(), msg, fetch)
+	if err != nil {
+		t.Fatalf("messageAttachments: %v", err)
+	}
+	if len(fetched) != 2 || fetched[0] != "large" {
+		t.Errorf("fetched %v, want only the largest photo size and the document", fetched)
+	}
+	if len(atts) != 2 || atts[0].MimeType != "image/jpeg" || atts[1].Name != "notes.md" || atts[1].MimeType != "text/markdown" {
+		t.Errorf("attachments = %+v", atts)
+	}
+
+	if atts, err := messageAttachments(context.Background(), &tgbotapi.Message{Text: "hi"}, fetch); err != nil || len(atts) != 0 {
+		t.Errorf("text message: attachments = %+v, err %v", atts, err)
+	}
+
+	unsupported := &tgbotapi.Message{Document: &tgbotapi.Document{FileID: "doc", FileName: "a.zip", MimeType: "application/zip"}}
+	if _, err := messageAttachments(context.Background(), unsupported, fetch); err == nil {
+		t.Error("expected error for unsupported document type")
+	}
+	missing := &tgbotapi.Message{Photo: []tgbotapi.PhotoSize{{FileID: "gone"}}}
+	if _, err := messageAttachments(context.Background(), missing, fetch); err == nil {
+		t.Error("expected download error")
+	}
+}
@@ -0,0 +1,480 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/basket/go-claw/internal/engine"
+	"github.com/basket/go-claw/internal/persistence"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+)
+
+// AgentDirectory lets chat operator commands inspect and control agents.
+// cmd/goclaw adapts agent.Registry to it.
+type AgentDirectory interface {
+	ListAgents() []AgentInfo
+	AgentStatus(agentID string) (*engine.Status, error)
+	AbortTask(ctx context.Context, taskID string) (bool, error)
+}
+
+// AgentInfo describes an agent for /agents and /model.
+type AgentInfo struct {
+	ID          string
+	DisplayName string
+	Emoji       string
+	Provider    string
+	Model       string
+}
+
+// telegramCommands are registered with setMyCommands so clients offer them
+// in the command menu.
+var telegramCommands = []tgbotapi.BotCommand{
+	{Command: "agents", Description: "List agents"},
+	{Command: "status", Description: "Agent status and this chat's session: /status [@agent]"},
+	{Command: "tasks", Description: "Recent tasks in this chat: /tasks [@agent]"},
+	{Command: "cancel", Description: "Cancel a task: /cancel [@agent] <task>"},
+	{Command: "memory", Description: "Stored facts: /memory [@agent] list|search|delete|clear"},
+	{Command: "pin", Description: "Pinned context: /pin [@agent] [<label> <text>]"},
+	{Command: "unpin", Description: "Remove a pin: /unpin [@agent] <label>"},
+	{Command: "model", Description: "Show an agent's model: /model [@agent]"},
+	{Command: "session", Description: "Show or restart the session: /session [new] [@agent]"},
+	{Command: "plan", Description: "Run and control plans: /plan <name> key=value..."},
+	{Command: "help", Description: "Show commands"},
+}
+
+// telegramTaskListLimit caps the tasks /tasks shows.
+const telegramTaskListLimit = 10
+
+// handleCommand runs a slash command and replies in the chat. Commands
+// addressed to another bot ("/status@otherbot") are ignored, as are unknown
+// commands in groups, where they may belong to another bot.
+func (t *TelegramChannel) handleCommand(ctx context.Context, msg *tgbotapi.Message, content string) {
+	name, args, _ := strings.Cut(content[1:], " ")
+	if i := strings.IndexFunc(name, unicode.IsSpace); i >= 0 {
+		name, args = name[:i], name[i:]+" "+args
+	}
+	name, addressee, _ := strings.Cut(name, "@")
+	if addressee != "" && (t.bot == nil || !strings.EqualFold(addressee, t.bot.Self.UserName)) {
+		return
+	}
+	name, args = strings.ToLower(name), strings.TrimSpace(args)
+	chatID := msg.Chat.ID
+	if name == "plan" {
+		t.handlePlanCommand(ctx, chatID, strings.TrimSpace("/plan "+args))
+		return
+	}
+	text, ok := t.runCommand(ctx, chatID, name, args)
+	if !ok {
+		if msg.Chat.IsPrivate() {
+			t.reply(chatID, fmt.Sprintf("Unknown command /%s. Send /help for the list.", name))
+		}
+		return
+	}
+	t.reply(chatID, text)
+}
+
+// runCommand returns the reply to an operator command, or false when name
+// is not a command.
+func (t *TelegramChannel) runCommand(ctx context.Context, chatID int64, name, args string) (string, bool) {
+	agentID, rest := splitAgentArg(args)
+	switch name {
+	case "start", "help":
+		return telegramHelp(), true
+	case "agents":
+		return t.cmdAgents(), true
+	case "status":
+		return t.cmdStatus(ctx, chatID, agentID), true
+	case "tasks":
+		return t.cmdTasks(ctx, chatID, agentID), true
+	case "cancel":
+		return t.cmdCancel(ctx, chatID, agentID, rest), true
+	case "memory":
+		return t.cmdMemory(ctx, agentID, rest), true
+	case "pin":
+		return t.cmdPin(ctx, agentID, rest), true
+	case "unpin":
+		return t.cmdUnpin(ctx, agentID, rest), true
+	case "model":
+		return t.cmdModel(agentID), true
+	case "session":
+		return t.cmdSession(ctx, chatID, args), true
+	}
+	return "", false
+}
+
+// splitAgentArg splits a leading "@agent" off command arguments; the agent
+// defaults to "default".
+func splitAgentArg(args string) (agentID, rest string) {
+	if !strings.HasPrefix(args, "@") {
+		return "default", args
+	}
+	first, rest, _ := strings.Cut(args, " ")
+	if agentID = strings.TrimPrefix(first, "@"); agentID == "" {
+		agentID = "default"
+	}
+	return agentID, strings.TrimSpace(rest)
+}
+
+func telegramHelp() string {
+	var b strings.Builder
+	b.WriteString("Send a message to talk to the default agent, or start it with @agent to pick another.\n\nCommands:\n")
+	for _, c := range telegramCommands {
+		fmt.Fprintf(&b, "/%s - %s\n", c.Command, c.Description)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (t *TelegramChannel) cmdAgents() string {
+	if t.agents == nil {
+		return "Agent commands are not available."
+	}
+	agents := t.agents.ListAgents()
+	if len(agents) == 0 {
+		return "No agents."
+	}
+	var b strings.Builder
+	b.WriteString("Agents:\n")
+	for _, a := range agents {
+		line := "@" + a.ID
+		if a.Emoji != "" {
+			line = a.Emoji + " " + line
+		}
+		if a.DisplayName != "" && a.DisplayName != a.ID {
+			line += " (" + a.DisplayName + ")"
+		}
+		if a.Model != "" {
+			line += " - " + a.Model
+		}
+		if st, err := t.agents.AgentStatus(a.ID); err == nil && st.ActiveTasks > 0 {
+			line += fmt.Sprintf(", %d active", st.ActiveTasks)
+		}
+		b.WriteString(line + "\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (t *TelegramChannel) cmdStatus(ctx context.Context, chatID int64, agentID string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Agent: @%s\n", agentID)
+	if t.agents != nil {
+		st, err := t.agents.AgentStatus(agentID)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		fmt.Fprintf(&b, "Workers: %d, active tasks: %d\n", st.WorkerCount, st.ActiveTasks)
+		if st.LastError != "" {
+			fmt.Fprintf(&b, "Last error: %s\n", st.LastError)
+		}
+	}
+	fmt.Fprintf(&b, "Session: %s\n", t.sessionID(ctx, chatID, agentID))
+
+	t.pendingMu.Lock()
+	pending := 0
+	for _, id := range t.pendingTasks {
+		if id == chatID {
+			pending++
+		}
+	}
+	t.pendingMu.Unlock()
+	fmt.Fprintf(&b, "Awaiting replies in this chat: %d", pending)
+	return b.String()
+}
+
+func (t *TelegramChannel) cmdTasks(ctx context.Context, chatID int64, agentID string) string {
+	if t.store == nil {
+		return "Store not available."
+	}
+	tasks, err := t.store.ListTasksBySession(ctx, t.sessionID(ctx, chatID, agentID))
+	if err != nil {
+		return fmt.Sprintf("Error loading tasks: %v", err)
+	}
+	if len(tasks) == 0 {
+		return fmt.Sprintf("No tasks in this chat's @%s session.", agentID)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Recent tasks (@%s):\n", agentID)
+	for i := len(tasks) - 1; i >= 0 && i >= len(tasks)-telegramTaskListLimit; i-- {
+		task := tasks[i]
+		fmt.Fprintf(&b, "%s %s %s ago", shortTaskID(task.ID), task.Status, time.Since(task.CreatedAt).Round(time.Second))
+		if prompt := taskPrompt(task); prompt != "" {
+			fmt.Fprintf(&b, ": %s", prompt)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("Cancel one with /cancel <id>.")
+	return b.String()
+}
+
+// shortTaskID is the task ID prefix /tasks shows and /cancel accepts.
+func shortTaskID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// taskPrompt returns the start of a chat task's content.
+func taskPrompt(task persistence.Task) string {
+	var payload struct {
+		Content string `json:"content"`
+	}
+	if json.Unmarshal([]byte(task.Payload), &payload) != nil {
+		return ""
+	}
+	prompt := strings.Join(strings.Fields(payload.Content), " ")
+	if r := []rune(prompt); len(r) > 40 {
+		prompt = string(r[:40]) + "…"
+	}
+	return prompt
+}
+
+// cmdCancel aborts a task of the chat's session by ID or ID prefix, so a
+// chat can only cancel its own tasks.
+func (t *TelegramChannel) cmdCancel(ctx context.Context, chatID int64, agentID, ref string) string {
+	if ref == "" {
+		return "Usage: /cancel [@agent] <task>"
+	}
+	if t.agents == nil || t.store == nil {
+		return "Cancel is not available."
+	}
+	tasks, err := t.store.ListTasksBySession(ctx, t.sessionID(ctx, chatID, agentID))
+	if err != nil {
+		return fmt.Sprintf("Error loading tasks: %v", err)
+	}
+	var matches []string
+	for _, task := range tasks {
+		if task.ID == ref {
+			matches = []string{task.ID}
+			break
+		}
+		if strings.HasPrefix(task.ID, ref) {
+			matches = append(matches, task.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return fmt.Sprintf("No task %s in this chat's @%s session.", ref, agentID)
+	case 1:
+	default:
+		return fmt.Sprintf("%s matches %d tasks; use more of the ID.", ref, len(matches))
+	}
+	aborted, err := t.agents.AbortTask(ctx, matches[0])
+	if err != nil {
+		return fmt.Sprintf("Error canceling %s: %v", shortTaskID(matches[0]), err)
+	}
+	if !aborted {
+		return fmt.Sprintf("Task %s already finished.", shortTaskID(matches[0]))
+	}
+	return fmt.Sprintf("Canceling task %s.", shortTaskID(matches[0]))
+}
+
+// cmdMemory mirrors the TUI's /memory: list, search, delete and clear.
+func (t *TelegramChannel) cmdMemory(ctx context.Context, agentID, args string) string {
+	if t.store == nil {
+		return "Store not available."
+	}
+	sub, arg, _ := strings.Cut(args, " ")
+	arg = strings.TrimSpace(arg)
+	switch strings.ToLower(sub) {
+	case "", "list":
+		memories, err := t.store.ListMemories(ctx, agentID)
+		if err != nil {
+			return fmt.Sprintf("Error loading memories: %v", err)
+		}
+		if len(memories) == 0 {
+			return fmt.Sprintf("No stored facts for @%s.", agentID)
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "Stored facts for @%s (by relevance):\n", agentID)
+		for _, m := range memories {
+			fmt.Fprintf(&b, "• %s: %s\n", m.Key, m.Value)
+		}
+		return strings.TrimSpace(b.String())
+	case "search":
+		if arg == "" {
+			return "Usage: /memory [@agent] search <query>"
+		}
+		results, err := t.store.SearchMemories(ctx, agentID, arg)
+		if err != nil {
+			return fmt.Sprintf("Error searching: %v", err)
+		}
+		if len(results) == 0 {
+			return fmt.Sprintf("No results for '%s'.", arg)
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "Results for '%s':\n", arg)
+		for _, m := range results {
+			fmt.Fprintf(&b, "• %s: %s\n", m.Key, m.Value)
+		}
+		return strings.TrimSpace(b.String())
+	case "delete":
+		if arg == "" {
+			return "Usage: /memory [@agent] delete <key>"
+		}
+		if err := t.store.DeleteMemory(ctx, agentID, arg); err != nil {
+			return fmt.Sprintf("Error deleting: %v", err)
+		}
+		return fmt.Sprintf("Deleted fact: %s", arg)
+	case "clear":
+		if err := t.store.DeleteAgentMemories(ctx, agentID); err != nil {
+			return fmt.Sprintf("Error clearing: %v", err)
+		}
+		return fmt.Sprintf("Cleared all facts for @%s.", agentID)
+	}
+	return "Usage: /memory [@agent] list|search <query>|delete <key>|clear"
+}
+
+// cmdPin lists pins or adds a text pin. File pins read the server's disk, so
+// they are left to the TUI.
+func (t *TelegramChannel) cmdPin(ctx context.Context, agentID, args string) string {
+	if t.store == nil {
+		return "Store not available."
+	}
+	if args == "" {
+		pins, err := t.store.ListPins(ctx, agentID)
+		if err != nil {
+			return fmt.Sprintf("Error loading pins: %v", err)
+		}
+		if len(pins) == 0 {
+			return fmt.Sprintf("No pins for @%s.", agentID)
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "Pins for @%s:\n", agentID)
+		for _, p := range pins {
+			fmt.Fprintf(&b, "• %s [%s, %d tokens]\n", p.Source, p.PinType, p.TokenCount)
+		}
+		return strings.TrimSpace(b.String())
+	}
+	label, content, _ := strings.Cut(args, " ")
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "Usage: /pin [@agent] <label> <text>"
+	}
+	if err := t.store.AddPin(ctx, agentID, "text", label, content, false); err != nil {
+		return fmt.Sprintf("Error pinning: %v", err)
+	}
+	return fmt.Sprintf("Pinned %s for @%s.", label, agentID)
+}
+
+func (t *TelegramChannel) cmdUnpin(ctx context.Context, agentID, label string) string {
+	if t.store == nil {
+		return "Store not available."
+	}
+	if label == "" {
+		return "Usage: /unpin [@agent] <label>"
+	}
+	if err := t.store.RemovePin(ctx, agentID, label); err != nil {
+		return fmt.Sprintf("Error unpinning: %v", err)
+	}
+	return fmt.Sprintf("Unpinned %s.", label)
+}
+
+// cmdModel shows an agent's model. Changing it stays with the config and the
+// TUI, which can validate provider credentials.
+func (t *TelegramChannel) cmdModel(agentID string) string {
+	if t.agents == nil {
+		return "Agent commands are not available."
+	}
+	for _, a := range t.agents.ListAgents() {
+		if a.ID != agentID {
+			continue
+		}
+		model := a.Model
+		if model == "" {
+			model = "the default model"
+		}
+		if a.Provider != "" {
+			return fmt.Sprintf("@%s uses %s (provider: %s).", agentID, model, a.Provider)
+		}
+		return fmt.Sprintf("@%s uses %s.", agentID, model)
+	}
+	return fmt.Sprintf("Unknown agent @%s.", agentID)
+}
+
+// cmdSession shows the chat's session with an agent, or with "new" starts a
+// fresh one; the previous history stays in the store.
+func (t *TelegramChannel) cmdSession(ctx context.Context, chatID int64, args string) string {
+	agentID, sub := "default", ""
+	for _, f := range strings.Fields(args) {
+		if strings.HasPrefix(f, "@") && len(f) > 1 {
+			agentID = f[1:]
+		} else if sub == "" {
+			sub = strings.ToLower(f)
+		}
+	}
+	switch sub {
+	case "":
+		return fmt.Sprintf("Session with @%s: %s", agentID, t.sessionID(ctx, chatID, agentID))
+	case "new":
+		if t.store == nil {
+			return "Store not available."
+		}
+		key := telegramSessionKey(chatID, agentID)
+		gen, err := t.store.KVGet(ctx, key)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		n, _ := strconv.Atoi(gen)
+		if err := t.store.KVSet(ctx, key, strconv.Itoa(n+1)); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return fmt.Sprintf("Started a new session with @%s: %s", agentID, t.sessionID(ctx, chatID, agentID))
+	}
+	return "Usage: /session [new] [@agent]"
+}
+
+// sessionID maps a chat and agent to a persistent session ID. In private
+// chats the chat ID is the user's ID; a group shares one session per agent.
+// /session new bumps a generation stored in the KV store.
+func (t *TelegramChannel) sessionID(ctx context.Context, chatID int64, agentID string) string {
+	gen := ""
+	if t.store != nil {
+		var err error
+		gen, err = t.store.KVGet(ctx, telegramSessionKey(chatID, agentID))
+		if err != nil {
+			t.logger.Warn("failed to look up telegram session", "error", err)
+		}
+	}
+	return telegramSessionID(chatID, agentID, gen)
+}
+
+// telegramSessionID derives the session UUID for a chat, agent and /session
+// generation. The first session of a private chat (positive chat ID) is
+// derived from the per-user key used before group support, so upgrading
+// doesn't move a user to a new session.
+func telegramSessionID(chatID int64, agentID, gen string) string {
+	var key string
+	switch {
+	case gen == "" && chatID > 0:
+		key = fmt.Sprintf("telegram-%d-agent-%s", chatID, agentID)
+	case gen == "":
+		key = fmt.Sprintf("goclaw:telegram:%d:agent:%s", chatID, agentID)
+	default:
+		key = fmt.Sprintf("goclaw:telegram:%d:agent:%s:%s", chatID, agentID, gen)
+	}
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)).String()
+}
+
+func telegramSessionKey(chatID int64, agentID string) string {
+	return fmt.Sprintf("telegram_session:%d:%s", chatID, agentID)
+}
+
+// stripMention removes "@username" mentions of the bot from text and
+// reports whether there were any.
+func stripMention(text, username string) (string, bool) {
+	if username == "" {
+		return text, false
+	}
+	re := regexp.MustCompile(`(?i)(^|\s)@` + regexp.QuoteMeta(username) + `\b[,:]?\s*`)
+	if !re.MatchString(text) {
+		return text, false
+	}
+	return strings.TrimSpace(re.ReplaceAllString(text, "$1")), true
+}
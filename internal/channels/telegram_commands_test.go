@@ -0,0 +1,315 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/engine"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/google/uuid"
+)
+
+// fakeTelegram is a Bot API server that records sent messages and calls.
+type fakeTelegram struct {
+	srv   *httptest.Server
+	sent  chan fakeTelegramMessage
+	calls chan string
+
+	mu      sync.Mutex
+	webhook map[string]string
+}
+
+type fakeTelegramMessage struct {
+	chatID int64
+	text   string
+}
+
+func newFakeTelegram(t *testing.T) *fakeTelegram {
+	t.Helper()
+	f := &fakeTelegram{sent: make(chan fakeTelegramMessage, 100), calls: make(chan string, 100)}
+	f.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		method := r.URL.Path[strings.LastIndexByte(r.URL.Path, '/')+1:]
+		var result any = true
+		switch method {
+		case "getMe":
+			result = map[string]any{"id": 99, "is_bot": true, "first_name": "Claw", "username": "claw_bot"}
+		case "sendMessage":
+			chatID, _ := strconv.ParseInt(r.Form.Get("chat_id"), 10, 64)
+			f.sent <- fakeTelegramMessage{chatID, r.Form.Get("text")}
+			result = map[string]any{"message_id": 1, "date": 0, "chat": map[string]any{"id": chatID}}
+		case "setWebhook":
+			f.mu.Lock()
+			f.webhook = map[string]string{"url": r.Form.Get("url"), "secret_token": r.Form.Get("secret_token")}
+			f.mu.Unlock()
+		}
+		f.calls <- method
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+	}))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeTelegram) waitCall(t *testing.T, method string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case m := <-f.calls:
+			if m == method {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no %s call", method)
+		}
+	}
+}
+
+func (f *fakeTelegram) nextMessage(t *testing.T) fakeTelegramMessage {
+	t.Helper()
+	select {
+	case m := <-f.sent:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message sent")
+	}
+	return fakeTelegramMessage{}
+}
+
+type fakeAgentDirectory struct {
+	aborted chan string
+}
+
+func (fakeAgentDirectory) ListAgents() []AgentInfo {
+	return []AgentInfo{{ID: "default", DisplayName: "Claw", Emoji: "🦀", Provider: "google", Model: "gemini-2.5-flash"}}
+}
+
+func (fakeAgentDirectory) AgentStatus(agentID string) (*engine.Status, error) {
+	return &engine.Status{AgentID: agentID, WorkerCount: 2}, nil
+}
+
+func (d fakeAgentDirectory) AbortTask(_ context.Context, taskID string) (bool, error) {
+	d.aborted <- taskID
+	return true, nil
+}
+
+// telegramUpdate builds an update JSON for a text message.
+func telegramUpdate(fromID, chatID int64, chatType, text string) string {
+	b, _ := json.Marshal(map[string]any{
+		"update_id": 1,
+		"message": map[string]any{
+			"message_id": 1,
+			"date":       0,
+			"from":       map[string]any{"id": fromID, "is_bot": false, "first_name": "Op"},
+			"chat":       map[string]any{"id": chatID, "type": chatType},
+			"text":       text,
+		},
+	})
+	return string(b)
+}
+
+func TestTelegramChannel_WebhookCommandsAndGroups(t *testing.T) {
+	f := newFakeTelegram(t)
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "test.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	router := &fakeChatRouter{store: store, tasks: make(chan fakeChatTask, 10)}
+	dir := fakeAgentDirectory{aborted: make(chan string, 1)}
+
+	tg := NewTelegramChannel("T0K3N", []int64{42}, router, store, slog.Default(), bus.New())
+	tg.apiEndpoint = f.srv.URL + "/bot%s/%s"
+	tg.SetWebhook("https://claw.example.com/telegram/webhook", "s3cret")
+	tg.SetAgentDirectory(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tg.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	f.waitCall(t, "setMyCommands")
+	f.waitCall(t, "setWebhook")
+	f.mu.Lock()
+	if f.webhook["url"] != "https://claw.example.com/telegram/webhook" || f.webhook["secret_token"] != "s3cret" {
+		t.Errorf("setWebhook params = %v", f.webhook)
+	}
+	f.mu.Unlock()
+
+	hook := httptest.NewServer(tg.Handler())
+	defer hook.Close()
+	post := func(secret, body string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, hook.URL, strings.NewReader(body))
+		req.Header.Set(TelegramSecretHeader, secret)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post update: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	send := func(fromID, chatID int64, chatType, text string) {
+		t.Helper()
+		if code := post("s3cret", telegramUpdate(fromID, chatID, chatType, text)); code != http.StatusOK {
+			t.Fatalf("post %q: status %d", text, code)
+		}
+	}
+	expectReply := func(chatID int64, want string) string {
+		t.Helper()
+		m := f.nextMessage(t)
+		if m.chatID != chatID || !strings.Contains(m.text, want) {
+			t.Fatalf("reply = %d %q, want %d containing %q", m.chatID, m.text, chatID, want)
+		}
+		return m.text
+	}
+
+	if code := post("wrong", telegramUpdate(42, 42, "private", "hi")); code != http.StatusUnauthorized {
+		t.Fatalf("bad secret: status %d, want 401", code)
+	}
+	if code := post("s3cret", "not json"); code != http.StatusBadRequest {
+		t.Fatalf("bad body: status %d, want 400", code)
+	}
+
+	// A private message becomes a chat task in the user's legacy session.
+	send(42, 42, "private", "hello")
+	first := router.next(t)
+	if first.sessionID != telegramSessionID(42, "default", "") || first.agentID != "default" || first.content != "hello" {
+		t.Fatalf("first task = %+v", first)
+	}
+
+	send(42, 42, "private", "/agents")
+	expectReply(42, "🦀 @default (Claw) - gemini-2.5-flash")
+	send(42, 42, "private", "/model")
+	expectReply(42, "@default uses gemini-2.5-flash (provider: google).")
+	send(42, 42, "private", "/status")
+	expectReply(42, "Session: "+first.sessionID)
+
+	// /tasks lists the session's tasks and /cancel accepts the short ID.
+	send(42, 42, "private", "/tasks")
+	expectReply(42, shortTaskID(first.id)+" QUEUED")
+	send(42, 42, "private", "/cancel "+shortTaskID(first.id))
+	expectReply(42, "Canceling task "+shortTaskID(first.id))
+	if got := <-dir.aborted; got != first.id {
+		t.Errorf("aborted %s, want %s", got, first.id)
+	}
+	send(42, 42, "private", "/cancel ffffffff")
+	expectReply(42, "No task ffffffff")
+
+	send(42, 42, "private", "/pin groceries milk and eggs")
+	expectReply(42, "Pinned groceries for @default.")
+	pins, err := store.ListPins(context.Background(), "default")
+	if err != nil || len(pins) != 1 || pins[0].Content != "milk and eggs" || pins[0].PinType != "text" {
+		t.Fatalf("pins = %+v, %v", pins, err)
+	}
+	send(42, 42, "private", "/memory")
+	expectReply(42, "No stored facts for @default.")
+
+	// /session new starts a fresh session for the next message.
+	send(42, 42, "private", "/session new")
+	expectReply(42, "Started a new session with @default")
+	send(42, 42, "private", "hello again")
+	second := router.next(t)
+	if second.sessionID == first.sessionID {
+		t.Fatal("/session new kept the old session")
+	}
+
+	// In groups only mentions and commands are handled; senders are still
+	// checked against the allowlist.
+	send(42, -100, "group", "chatting among ourselves")
+	send(7, -100, "group", "@claw_bot do something")
+	send(42, -100, "group", "/status@other_bot")
+	send(42, -100, "group", "@claw_bot summarize the thread")
+	group := router.next(t)
+	if group.content != "summarize the thread" || group.sessionID == first.sessionID || group.sessionID == second.sessionID {
+		t.Fatalf("group task = %+v", group)
+	}
+	send(42, -100, "supergroup", "/help@claw_bot")
+	expectReply(-100, "Commands:")
+	select {
+	case task := <-router.tasks:
+		t.Fatalf("unexpected task %+v", task)
+	case m := <-f.sent:
+		t.Fatalf("unexpected message %+v", m)
+	default:
+	}
+}
+
+func TestTelegramHandler_NotInWebhookMode(t *testing.T) {
+	tg := NewTelegramChannel("T0K3N", nil, nil, nil, slog.Default())
+	rec := httptest.NewRecorder()
+	tg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader("{}")))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status %d, want 404", rec.Code)
+	}
+}
+
+func TestStripMention(t *testing.T) {
+	tests := []struct {
+		text, want string
+		found      bool
+	}{
+		{"@claw_bot hello", "hello", true},
+		{"hey @Claw_Bot, what's up", "hey what's up", true},
+		{"@claw_bot: /status", "/status", true},
+		{"ask @claw_bot_two instead", "ask @claw_bot_two instead", false},
+		{"mail me at x@claw_bot.example", "mail me at x@claw_bot.example", false},
+		{"no mention", "no mention", false},
+	}
+	for _, tt := range tests {
+		got, found := stripMention(tt.text, "claw_bot")
+		if got != tt.want || found != tt.found {
+			t.Errorf("stripMention(%q) = %q, %v; want %q, %v", tt.text, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestSplitAgentArg(t *testing.T) {
+	tests := []struct{ args, agent, rest string }{
+		{"", "default", ""},
+		{"list", "default", "list"},
+		{"@coder", "coder", ""},
+		{"@coder search go modules", "coder", "search go modules"},
+		{"@ list", "default", "list"},
+	}
+	for _, tt := range tests {
+		agent, rest := splitAgentArg(tt.args)
+		if agent != tt.agent || rest != tt.rest {
+			t.Errorf("splitAgentArg(%q) = %q, %q; want %q, %q", tt.args, agent, rest, tt.agent, tt.rest)
+		}
+	}
+}
+
+func TestTelegramSessionID(t *testing.T) {
+	legacy := uuid.NewSHA1(uuid.NameSpaceURL, []byte("telegram-42-agent-default")).String()
+	if legacy != "5e3f1e0e-c512-5d87-b26d-a748cb020c5c" {
+		t.Fatalf("legacy key derivation changed: %s", legacy)
+	}
+	tests := []struct {
+		name   string
+		chatID int64
+		gen    string
+		want   string
+	}{
+		{"private first session keeps legacy key", 42, "", legacy},
+		{"private new session", 42, "1", uuid.NewSHA1(uuid.NameSpaceURL, []byte("goclaw:telegram:42:agent:default:1")).String()},
+		{"group", -100, "", uuid.NewSHA1(uuid.NameSpaceURL, []byte("goclaw:telegram:-100:agent:default")).String()},
+	}
+	for _, tt := range tests {
+		if got := telegramSessionID(tt.chatID, "default", tt.gen); got != tt.want {
+			t.Errorf("%s: telegramSessionID = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
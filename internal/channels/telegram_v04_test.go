@@ -3,6 +3,7 @@ package channels
 import (
 	"context"
 	"log/slog"
+	"strings"
 	"testing"
 
 	"github.com/basket/go-claw/internal/bus"
@@ -334,3 +335,28 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+// TestToolApprovalKeyboard verifies every button round-trips through the
+// callback parser with an action the approval broker understands.
+func TestToolApprovalKeyboard(t *testing.T) {
+	kb := toolApprovalKeyboard("req-42")
+	var actions []string
+	for _, row := range kb.InlineKeyboard {
+		for _, btn := range row {
+			if btn.CallbackData == nil {
+				t.Fatalf("button %q has no callback data", btn.Text)
+			}
+			id, action, err := parseHITLCallback(*btn.CallbackData)
+			if err != nil {
+				t.Fatalf("parse %q: %v", *btn.CallbackData, err)
+			}
+			if id != "req-42" {
+				t.Errorf("request ID = %q, want req-42", id)
+			}
+			actions = append(actions, action)
+		}
+	}
+	if got := strings.Join(actions, ","); got != "approve,approve_session,deny" {
+		t.Errorf("actions = %s", got)
+	}
+}
@@ -0,0 +1,521 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/config"
+	"github.com/basket/go-claw/internal/engine"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/google/uuid"
+)
+
+// Webhook request headers. Signatures are "sha256=" followed by the hex
+// HMAC-SHA256 of the timestamp, the delivery ID and the body joined by dots
+// (see SignWebhook); GitHub's X-Hub-Signature-256 of the body alone is
+// accepted too.
+const (
+	WebhookSignatureHeader = "X-Goclaw-Signature"
+	WebhookTimestampHeader = "X-Goclaw-Timestamp"
+	WebhookEventHeader     = "X-Goclaw-Event"
+	WebhookDeliveryHeader  = "X-Goclaw-Delivery"
+)
+
+const (
+	// defaultWebhookMaxAttempts bounds the attempts of a delivery before it
+	// becomes a dead letter; with the backoff below that is about 20 minutes.
+	defaultWebhookMaxAttempts = 8
+	webhookRetryBase          = 10 * time.Second
+	webhookRetryMax           = time.Hour
+	maxWebhookBodyBytes       = 1 << 20
+	// webhookMaxSkew is how far a signed timestamp may be from now before
+	// the request is rejected as a replay.
+	webhookMaxSkew = 5 * time.Minute
+	// webhookDeliveryTTL is how long a delivery ID is remembered: a request
+	// recorded now carries a timestamp of at most webhookMaxSkew ago, which
+	// is accepted for another webhookMaxSkew after that.
+	webhookDeliveryTTL = 2 * webhookMaxSkew
+)
+
+// KV key prefixes recording the task created for an inbound request. Goclaw
+// deliveries expire with their timestamp window; GitHub requests carry no
+// timestamp, so their keys are kept.
+const (
+	webhookDeliveryPrefix = "webhook_delivery:"
+	webhookGitHubPrefix   = "webhook_github:"
+)
+
+// errWebhookNoDelivery rejects a Goclaw-signed request without a usable
+// delivery ID.
+var errWebhookNoDelivery = errors.New("missing or invalid " + WebhookDeliveryHeader)
+
+// WebhookChannel implements the Channel interface for generic webhooks.
+// Inbound, signed POSTs to /webhooks/<name> become chat tasks for the
+// endpoint's agent; the response carries the task ID, and the result can be
+// picked up through an outbound webhook on task.completed. Outbound, the bus
+// events of the configured topics are queued in the store and POSTed to each
+// endpoint, retried with exponential backoff and kept as dead letters after
+// the last attempt.
+type WebhookChannel struct {
+	inbound     map[string]config.WebhookInboundConfig
+	outbound    []config.WebhookOutboundConfig
+	maxAttempts int
+	retryBase   time.Duration
+	router      engine.ChatTaskRouter
+	store       *persistence.Store
+	logger      *slog.Logger
+	eventBus    *bus.Bus
+	client      *http.Client
+	wake        chan struct{} // signals newly queued deliveries
+	inboundMu   sync.Mutex    // makes the delivery ID check and task creation atomic
+}
+
+// NewWebhookChannel creates a webhook channel. eventBus may be nil when no
+// outbound endpoints are configured.
+func NewWebhookChannel(cfg config.WebhookConfig, router engine.ChatTaskRouter, store *persistence.Store, logger *slog.Logger, eventBus *bus.Bus) (*WebhookChannel, error) {
+	w := &WebhookChannel{
+		inbound:     make(map[string]config.WebhookInboundConfig),
+		maxAttempts: cfg.MaxAttempts,
+		retryBase:   webhookRetryBase,
+		router:      router,
+		store:       store,
+		logger:      logger,
+		eventBus:    eventBus,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		wake:        make(chan struct{}, 1),
+	}
+	if w.maxAttempts <= 0 {
+		w.maxAttempts = defaultWebhookMaxAttempts
+	}
+	for _, in := range cfg.Inbound {
+		if in.Name == "" || strings.Contains(in.Name, "/") {
+			return nil, fmt.Errorf("webhook inbound name %q must be non-empty and contain no '/'", in.Name)
+		}
+		if _, dup := w.inbound[in.Name]; dup {
+			return nil, fmt.Errorf("duplicate webhook inbound name %q", in.Name)
+		}
+		if in.Secret == "" {
+			return nil, fmt.Errorf("webhook inbound %q: secret is required", in.Name)
+		}
+		if in.Agent == "" {
+			in.Agent = "default"
+		}
+		w.inbound[in.Name] = in
+	}
+	names := make(map[string]bool)
+	for _, out := range cfg.Outbound {
+		if out.Name == "" || out.URL == "" || len(out.Topics) == 0 {
+			return nil, fmt.Errorf("webhook outbound %q: name, url and topics are required", out.Name)
+		}
+		if names[out.Name] {
+			return nil, fmt.Errorf("duplicate webhook outbound name %q", out.Name)
+		}
+		names[out.Name] = true
+		w.outbound = append(w.outbound, out)
+	}
+	if len(w.outbound) > 0 && eventBus == nil {
+		return nil, fmt.Errorf("webhook outbound endpoints need an event bus")
+	}
+	return w, nil
+}
+
+func (w *WebhookChannel) Name() string {
+	return "webhook"
+}
+
+// Start queues the events of the outbound topics and delivers them until
+// ctx is canceled. Deliveries left in the store by an earlier run are sent
+// too. Inbound requests are served by Handler; Start prunes their expired
+// delivery IDs.
+func (w *WebhookChannel) Start(ctx context.Context) error {
+	if len(w.inbound) > 0 {
+		go w.pruneLoop(ctx)
+	}
+	if len(w.outbound) == 0 {
+		<-ctx.Done()
+		return nil
+	}
+	sub := w.eventBus.Subscribe("")
+	defer w.eventBus.Unsubscribe(sub)
+	go w.deliverLoop(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-sub.Ch():
+			if !ok {
+				return nil
+			}
+			w.enqueue(ctx, ev)
+		}
+	}
+}
+
+// pruneLoop forgets inbound delivery IDs whose timestamps can no longer be
+// replayed.
+func (w *WebhookChannel) pruneLoop(ctx context.Context) {
+	ticker := time.NewTicker(webhookMaxSkew)
+	defer ticker.Stop()
+	for {
+		if n, err := w.store.KVDeletePrefix(ctx, webhookDeliveryPrefix, webhookDeliveryTTL); err != nil {
+			w.logger.Warn("failed to prune webhook deliveries", "error", err)
+		} else if n > 0 {
+			w.logger.Debug("pruned webhook deliveries", "count", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueue queues ev for each outbound endpoint subscribed to its topic.
+func (w *WebhookChannel) enqueue(ctx context.Context, ev bus.Event) {
+	var payload []byte
+	queued := false
+	for _, out := range w.outbound {
+		if !webhookTopicMatches(out.Topics, ev.Topic) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(ev.Payload); err != nil {
+				w.logger.Warn("webhook event payload is not JSON", "topic", ev.Topic, "error", err)
+				return
+			}
+		}
+		if _, err := w.store.EnqueueWebhookDelivery(ctx, out.Name, ev.Topic, string(payload)); err != nil {
+			w.logger.Error("failed to queue webhook delivery", "endpoint", out.Name, "topic", ev.Topic, "error", err)
+			continue
+		}
+		queued = true
+	}
+	if queued {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// webhookTopicMatches reports whether topic is one of topics, where an entry
+// ending in ".*" matches every topic with that prefix.
+func webhookTopicMatches(topics []string, topic string) bool {
+	for _, t := range topics {
+		if t == topic || (strings.HasSuffix(t, ".*") && strings.HasPrefix(topic, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverLoop sends due deliveries when new ones are queued and every second
+// for retries.
+func (w *WebhookChannel) deliverLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		w.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue sends every delivery that is due, in batches.
+func (w *WebhookChannel) deliverDue(ctx context.Context) {
+	endpoints := make(map[string]config.WebhookOutboundConfig, len(w.outbound))
+	for _, out := range w.outbound {
+		endpoints[out.Name] = out
+	}
+	for ctx.Err() == nil {
+		due, err := w.store.DueWebhookDeliveries(ctx, time.Now(), 50)
+		if err != nil {
+			w.logger.Error("failed to load webhook deliveries", "error", err)
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+		for _, d := range due {
+			out, ok := endpoints[d.Endpoint]
+			if !ok {
+				// The endpoint was removed from the config; keep the event
+				// as a dead letter.
+				w.recordFailure(ctx, d, "endpoint is no longer configured", true)
+				continue
+			}
+			if err := w.send(ctx, out, d); err != nil {
+				w.recordFailure(ctx, d, err.Error(), d.Attempts+1 >= w.maxAttempts)
+				continue
+			}
+			if err := w.store.CompleteWebhookDelivery(ctx, d.ID); err != nil {
+				w.logger.Error("failed to complete webhook delivery", "id", d.ID, "error", err)
+				return
+			}
+		}
+	}
+}
+
+func (w *WebhookChannel) recordFailure(ctx context.Context, d *persistence.WebhookDelivery, errMsg string, dead bool) {
+	backoff := w.retryBase << d.Attempts
+	if backoff <= 0 || backoff > webhookRetryMax {
+		backoff = webhookRetryMax
+	}
+	if dead {
+		w.logger.Warn("webhook delivery dead-lettered", "id", d.ID, "endpoint", d.Endpoint, "topic", d.Topic, "attempts", d.Attempts+1, "error", errMsg)
+	} else {
+		w.logger.Info("webhook delivery failed, will retry", "id", d.ID, "endpoint", d.Endpoint, "retry_in", backoff, "error", errMsg)
+	}
+	if err := w.store.FailWebhookDelivery(ctx, d.ID, errMsg, time.Now().Add(backoff), dead); err != nil {
+		w.logger.Error("failed to record webhook delivery failure", "id", d.ID, "error", err)
+	}
+}
+
+// webhookBody is the JSON body of an outbound webhook.
+type webhookBody struct {
+	ID        string          `json:"id"`
+	Topic     string          `json:"topic"`
+	CreatedAt time.Time       `json:"created_at"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// send POSTs a delivery. Any 2xx response counts as delivered.
+func (w *WebhookChannel) send(ctx context.Context, out config.WebhookOutboundConfig, d *persistence.WebhookDelivery) error {
+	body, err := json.Marshal(webhookBody{ID: d.ID, Topic: d.Topic, CreatedAt: d.CreatedAt.UTC(), Payload: json.RawMessage(d.Payload)})
+	if err != nil {
+		return fmt.Errorf("encode body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, out.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, d.Topic)
+	req.Header.Set(WebhookDeliveryHeader, d.ID)
+	if out.Secret != "" {
+		// Each attempt is signed afresh so retries stay within the receiver's
+		// timestamp window; the delivery ID stays the same for deduplication.
+		ts := time.Now().Unix()
+		req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(ts, 10))
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(out.Secret, ts, d.ID, body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhook returns the signature header value of a request sent at
+// timestamp (Unix seconds, the X-Goclaw-Timestamp value) as deliveryID (the
+// X-Goclaw-Delivery value, which must not contain a dot): "sha256=" and the
+// hex HMAC-SHA256, keyed with secret, of "<timestamp>.<deliveryID>.<body>".
+func SignWebhook(secret string, timestamp int64, deliveryID string, body []byte) string {
+	return signWebhookPayload(secret, append([]byte(strconv.FormatInt(timestamp, 10)+"."+deliveryID+"."), body...))
+}
+
+// signWebhookPayload returns "sha256=" and the hex HMAC-SHA256 of payload.
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyWebhook checks the signature of an inbound request to endpoint and
+// returns the KV key that deduplicates it. Only signed values go into the
+// key. Goclaw signatures cover the delivery ID and a timestamp that must be
+// within webhookMaxSkew of now, so the key is the delivery ID and expires
+// with the window. GitHub signs the body alone and sends no timestamp, so
+// the key is a hash of signature and body and is kept for good.
+func verifyWebhook(secret, endpoint string, header http.Header, body []byte, now time.Time) (string, error) {
+	if sig := header.Get(WebhookSignatureHeader); sig != "" {
+		ts, err := strconv.ParseInt(header.Get(WebhookTimestampHeader), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("missing or invalid %s", WebhookTimestampHeader)
+		}
+		id := header.Get(WebhookDeliveryHeader)
+		if id == "" || strings.Contains(id, ".") {
+			return "", errWebhookNoDelivery
+		}
+		if !hmac.Equal([]byte(sig), []byte(SignWebhook(secret, ts, id, body))) {
+			return "", fmt.Errorf("invalid signature")
+		}
+		if skew := now.Sub(time.Unix(ts, 0)); skew > webhookMaxSkew || skew < -webhookMaxSkew {
+			return "", fmt.Errorf("stale timestamp")
+		}
+		return webhookDeliveryPrefix + endpoint + ":" + id, nil
+	}
+	if sig := header.Get("X-Hub-Signature-256"); sig != "" {
+		if !hmac.Equal([]byte(sig), []byte(signWebhookPayload(secret, body))) {
+			return "", fmt.Errorf("invalid signature")
+		}
+		sum := sha256.Sum256(append([]byte(sig+"."), body...))
+		return webhookGitHubPrefix + endpoint + ":" + hex.EncodeToString(sum[:]), nil
+	}
+	return "", fmt.Errorf("missing signature")
+}
+
+// Handler serves the inbound endpoints under /webhooks/. Requests are
+// authenticated by their signatures, not by gateway API keys.
+func (w *WebhookChannel) Handler() http.Handler {
+	return http.HandlerFunc(w.handleInbound)
+}
+
+func (w *WebhookChannel) handleInbound(rw http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/webhooks/")
+	in, ok := w.inbound[name]
+	if !ok {
+		http.Error(rw, "unknown webhook", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodyBytes+1))
+	if err != nil {
+		http.Error(rw, "could not read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxWebhookBodyBytes {
+		http.Error(rw, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	key, err := verifyWebhook(in.Secret, name, r.Header, body, time.Now())
+	if errors.Is(err, errWebhookNoDelivery) {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		w.logger.Warn("webhook rejected", "endpoint", name, "remote", r.RemoteAddr, "error", err)
+		http.Error(rw, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil && (in.Template != "" || in.Session != "") {
+		http.Error(rw, "body is not JSON", http.StatusBadRequest)
+		return
+	}
+	content := string(body)
+	if in.Template != "" {
+		content = renderWebhookTemplate(in.Template, data)
+	}
+	if strings.TrimSpace(content) == "" {
+		http.Error(rw, "empty task content", http.StatusBadRequest)
+		return
+	}
+	session := renderWebhookTemplate(in.Session, data)
+	sessionID := uuid.NewSHA1(uuid.NameSpaceURL, []byte("goclaw:webhook:"+name+":"+session)).String()
+
+	// A delivery seen before is acknowledged with its task instead of
+	// creating another, so sender retries and replays are both harmless.
+	w.inboundMu.Lock()
+	defer w.inboundMu.Unlock()
+	prev, err := w.store.KVGet(r.Context(), key)
+	if err != nil {
+		w.logger.Error("failed to look up webhook delivery", "endpoint", name, "error", err)
+		http.Error(rw, "could not schedule task", http.StatusInternalServerError)
+		return
+	}
+	if prev != "" {
+		w.logger.Info("duplicate webhook delivery", "endpoint", name, "key", key)
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(rw).Encode(map[string]string{"task_id": prev, "session_id": sessionID, "status": "duplicate"})
+		return
+	}
+
+	taskID, err := w.router.CreateChatTask(r.Context(), in.Agent, sessionID, content)
+	if err != nil {
+		w.logger.Error("failed to create webhook task", "endpoint", name, "error", err)
+		http.Error(rw, "could not schedule task", http.StatusInternalServerError)
+		return
+	}
+	if err := w.store.KVSet(r.Context(), key, taskID); err != nil {
+		w.logger.Warn("failed to record webhook delivery", "endpoint", name, "key", key, "error", err)
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(rw).Encode(map[string]string{"task_id": taskID, "session_id": sessionID})
+}
+
+// webhookPlaceholder matches {{ path }} and {{ $.path }} in templates.
+var webhookPlaceholder = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+// renderWebhookTemplate replaces each {{ path }} in tmpl with the value at
+// path in data. Paths are dot-separated keys with [n] or .n for array
+// indices, optionally prefixed with "$."; missing values render empty,
+// objects and arrays as JSON.
+func renderWebhookTemplate(tmpl string, data any) string {
+	return webhookPlaceholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		path := webhookPlaceholder.FindStringSubmatch(m)[1]
+		return formatWebhookValue(lookupJSONPath(data, path))
+	})
+}
+
+// lookupJSONPath returns the value at path in data, or nil.
+func lookupJSONPath(data any, path string) any {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	if path == "" {
+		return data
+	}
+	cur := data
+	for _, key := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case map[string]any:
+			cur = v[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			cur = v[i]
+		default:
+			return nil
+		}
+	}
+	return cur
+}
+
+func formatWebhookValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,252 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/config"
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+func TestRenderWebhookTemplate(t *testing.T) {
+	var data any
+	_ = json.Unmarshal([]byte(`{"build":{"id":42,"ok":false,"repo":"go-claw"},"commits":[{"msg":"fix"},{"msg":"docs"}],"tags":["a"]}`), &data)
+	tests := []struct {
+		tmpl, want string
+	}{
+		{"Build {{ build.id }} of {{build.repo}}", "Build 42 of go-claw"},
+		{"{{ $.build.ok }}", "false"},
+		{"{{ commits[1].msg }} {{ commits.0.msg }}", "docs fix"},
+		{"{{ tags }}", `["a"]`},
+		{"[{{ missing.field }}] [{{ commits[5].msg }}]", "[] []"},
+		{"no placeholders", "no placeholders"},
+	}
+	for _, tt := range tests {
+		if got := renderWebhookTemplate(tt.tmpl, data); got != tt.want {
+			t.Errorf("renderWebhookTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookChannel_Inbound(t *testing.T) {
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "test.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	router := &fakeChatRouter{store: store, tasks: make(chan fakeChatTask, 10)}
+	wc, err := NewWebhookChannel(config.WebhookConfig{Inbound: []config.WebhookInboundConfig{{
+		Name:     "ci",
+		Secret:   "s3cret",
+		Agent:    "builder",
+		Session:  "{{ build.repo }}",
+		Template: "Build {{ build.id }} of {{ build.repo }} failed: {{ build.error }}",
+	}}}, router, store, slog.Default(), nil)
+	if err != nil {
+		t.Fatalf("NewWebhookChannel: %v", err)
+	}
+	srv := httptest.NewServer(wc.Handler())
+	defer srv.Close()
+
+	var deliveries atomic.Int32
+	post := func(path, body string, header http.Header) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		return resp
+	}
+	// signed returns the headers of a fresh delivery signed with secret at ts.
+	signed := func(secret string, ts time.Time, body string) http.Header {
+		h := http.Header{}
+		h.Set(WebhookTimestampHeader, strconv.FormatInt(ts.Unix(), 10))
+		id := fmt.Sprintf("d%d", deliveries.Add(1))
+		h.Set(WebhookDeliveryHeader, id)
+		h.Set(WebhookSignatureHeader, SignWebhook(secret, ts.Unix(), id, []byte(body)))
+		return h
+	}
+
+	body := `{"build":{"id":7,"repo":"api","error":"tests failed"}}`
+	now := time.Now()
+	noDelivery := signed("s3cret", now, body)
+	noDelivery.Del(WebhookDeliveryHeader)
+	bodyOnly := http.Header{}
+	bodyOnly.Set(WebhookTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	bodyOnly.Set(WebhookDeliveryHeader, "d-body-only")
+	bodyOnly.Set(WebhookSignatureHeader, signWebhookPayload("s3cret", []byte(body)))
+	dottedID := signed("s3cret", now, body)
+	dottedID.Set(WebhookDeliveryHeader, "d.1")
+	tests := []struct {
+		name, path, body string
+		header           http.Header
+		want             int
+	}{
+		{"unknown endpoint", "/webhooks/nope", body, signed("s3cret", now, body), http.StatusNotFound},
+		{"missing signature", "/webhooks/ci", body, http.Header{}, http.StatusUnauthorized},
+		{"wrong secret", "/webhooks/ci", body, signed("other", now, body), http.StatusUnauthorized},
+		{"body-only signature", "/webhooks/ci", body, bodyOnly, http.StatusUnauthorized},
+		{"stale timestamp", "/webhooks/ci", body, signed("s3cret", now.Add(-6*time.Minute), body), http.StatusUnauthorized},
+		{"future timestamp", "/webhooks/ci", body, signed("s3cret", now.Add(6*time.Minute), body), http.StatusUnauthorized},
+		{"missing delivery ID", "/webhooks/ci", body, noDelivery, http.StatusBadRequest},
+		{"dotted delivery ID", "/webhooks/ci", body, dottedID, http.StatusBadRequest},
+		{"not JSON", "/webhooks/ci", "build 7", signed("s3cret", now, "build 7"), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		resp := post(tt.path, tt.body, tt.header)
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+
+	first := signed("s3cret", now, body)
+	resp := post("/webhooks/ci", body, first)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", resp.StatusCode)
+	}
+	var out map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	task := router.next(t)
+	if task.agentID != "builder" || task.content != "Build 7 of api failed: tests failed" || out["task_id"] != task.id {
+		t.Fatalf("task = %+v, response = %v", task, out)
+	}
+
+	// Replaying a delivery returns its task without creating another.
+	replay := post("/webhooks/ci", body, first)
+	var dup map[string]string
+	_ = json.NewDecoder(replay.Body).Decode(&dup)
+	replay.Body.Close()
+	if replay.StatusCode != http.StatusOK || dup["task_id"] != task.id || dup["status"] != "duplicate" {
+		t.Fatalf("replay: status %d, response %v", replay.StatusCode, dup)
+	}
+
+	// The delivery ID is signed, so a replay under a new ID is rejected.
+	renamed := first.Clone()
+	renamed.Set(WebhookDeliveryHeader, "d-renamed")
+	replay = post("/webhooks/ci", body, renamed)
+	replay.Body.Close()
+	if replay.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("renamed replay: status %d, want 401", replay.StatusCode)
+	}
+
+	// The same session template value maps to the same session.
+	other := `{"build":{"id":8,"repo":"api"}}`
+	post("/webhooks/ci", other, signed("s3cret", now, other)).Body.Close()
+	if next := router.next(t); next.sessionID != task.sessionID {
+		t.Fatalf("session = %s, want %s", next.sessionID, task.sessionID)
+	}
+
+	// GitHub signs the body alone, so it is deduplicated on the signed
+	// body whatever its unsigned delivery ID says.
+	github := http.Header{}
+	github.Set("X-Hub-Signature-256", signWebhookPayload("s3cret", []byte(other)))
+	github.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	post("/webhooks/ci", other, github).Body.Close()
+	router.next(t)
+	github.Set("X-GitHub-Delivery", "00000000-0000-0000-0000-000000000000")
+	replay = post("/webhooks/ci", other, github)
+	replay.Body.Close()
+	if replay.StatusCode != http.StatusOK {
+		t.Fatalf("GitHub replay: status %d, want 200", replay.StatusCode)
+	}
+	select {
+	case extra := <-router.tasks:
+		t.Fatalf("replay created task %+v", extra)
+	default:
+	}
+}
+
+func TestWebhookChannel_OutboundRetryAndDeadLetter(t *testing.T) {
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "test.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	var calls atomic.Int32
+	bodies := make(chan *http.Request, 10)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if _, err := verifyWebhook("k", "ops", r.Header, body, time.Now()); err != nil || r.Header.Get(WebhookDeliveryHeader) == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		bodies <- r
+	}))
+	defer ok.Close()
+
+	eventBus := bus.New()
+	wc, err := NewWebhookChannel(config.WebhookConfig{MaxAttempts: 3, Outbound: []config.WebhookOutboundConfig{
+		{Name: "ops", URL: ok.URL, Secret: "k", Topics: []string{"plan.*", bus.TopicAgentAlert}},
+		{Name: "down", URL: failing.URL, Topics: []string{bus.TopicAgentAlert}},
+	}}, nil, store, slog.Default(), eventBus)
+	if err != nil {
+		t.Fatalf("NewWebhookChannel: %v", err)
+	}
+	wc.retryBase = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go wc.Start(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for eventBus.SubscriberCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	eventBus.Publish("task.completed", map[string]string{"task_id": "ignored"})
+	eventBus.Publish(bus.TopicAgentAlert, map[string]string{"message": "disk full"})
+
+	select {
+	case r := <-bodies:
+		var got struct {
+			Topic   string            `json:"topic"`
+			Payload map[string]string `json:"payload"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		if got.Topic != bus.TopicAgentAlert || got.Payload["message"] != "disk full" || r.Header.Get(WebhookEventHeader) != bus.TopicAgentAlert {
+			t.Fatalf("delivered %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery")
+	}
+
+	// The failing endpoint is retried until the delivery is dead-lettered.
+	var dead []*persistence.WebhookDelivery
+	for time.Now().Before(deadline) {
+		dead, _ = store.ListWebhookDeliveries(ctx, persistence.WebhookDeliveryDead, 0)
+		if len(dead) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(dead) != 1 || dead[0].Endpoint != "down" || dead[0].Attempts != 3 || calls.Load() != 3 {
+		t.Fatalf("dead letters = %+v after %d calls", dead, calls.Load())
+	}
+	select {
+	case r := <-bodies:
+		t.Fatalf("unexpected delivery of %s", r.Header.Get(WebhookEventHeader))
+	default:
+	}
+}
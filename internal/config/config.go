@@ -1,6 +1,8 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"hash/fnv"
@@ -62,6 +64,41 @@ type ProviderConfig struct {
 	Models  []string `yaml:"models"`   // user-added models (merged with built-ins)
 }
 
+// ModelPrice is a model's price in USD per million tokens. CachedInput is
+// the rate for prompt tokens served from the provider's cache; 0 charges
+// them at Input.
+type ModelPrice struct {
+	Input       float64 `yaml:"input"`
+	Output      float64 `yaml:"output"`
+	CachedInput float64 `yaml:"cached_input"`
+}
+
+// BudgetConfig caps spend per UTC calendar day and month. Zero limits are not
+// enforced.
+type BudgetConfig struct {
+	DailyUSD      float64 `yaml:"daily_usd,omitempty"`
+	MonthlyUSD    float64 `yaml:"monthly_usd,omitempty"`
+	DailyTokens   int     `yaml:"daily_tokens,omitempty"`
+	MonthlyTokens int     `yaml:"monthly_tokens,omitempty"`
+}
+
+// MemoryConfig controls which agent memories are injected into each turn.
+type MemoryConfig struct {
+	// RetrievalTokens is the token budget for the memories relevant to the
+	// prompt. 0 uses the default (1000).
+	RetrievalTokens int `yaml:"retrieval_tokens,omitempty"`
+	// Embeddings enables hybrid (keyword and semantic) memory ranking.
+	Embeddings *EmbeddingsConfig `yaml:"embeddings,omitempty"`
+}
+
+// EmbeddingsConfig selects a local embeddings backend. Only "ollama" is
+// supported; BaseURL defaults to providers.ollama.base_url.
+type EmbeddingsConfig struct {
+	Provider string `yaml:"provider"`
+	BaseURL  string `yaml:"base_url,omitempty"`
+	Model    string `yaml:"model,omitempty"`
+}
+
 // LLMProviderConfig holds configuration for all LLM providers.
 type LLMProviderConfig struct {
 	// Provider names the active LLM provider: "google", "anthropic", "openai", "openai_compatible".
@@ -98,11 +135,54 @@ type SkillsConfig struct {
 	LegacyMode bool     `yaml:"legacy_mode"`
 }
 
+// Shell sandbox modes.
+const (
+	SandboxNone   SandboxMode = ""       // commands run on the host
+	SandboxDocker SandboxMode = "docker" // a container per command
+	SandboxNative SandboxMode = "native" // Linux namespaces, seccomp, Landlock and cgroups v2
+)
+
+// SandboxMode is tools.shell.sandbox: none, docker or native. Booleans from
+// before native mode existed still work; true means docker.
+type SandboxMode string
+
+func (m *SandboxMode) UnmarshalYAML(n *yaml.Node) error {
+	var on bool
+	if n.Tag == "!!bool" && n.Decode(&on) == nil {
+		*m = SandboxNone
+		if on {
+			*m = SandboxDocker
+		}
+		return nil
+	}
+	var s string
+	if err := n.Decode(&s); err != nil {
+		return err
+	}
+	switch mode := SandboxMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "none", SandboxNone:
+		*m = SandboxNone
+	case SandboxDocker, SandboxNative:
+		*m = mode
+	default:
+		return fmt.Errorf("tools.shell.sandbox: unknown mode %q (want none, docker or native)", s)
+	}
+	return nil
+}
+
 type ShellConfig struct {
-	Sandbox        bool   `yaml:"sandbox"`
-	SandboxImage   string `yaml:"sandbox_image"`
-	SandboxMemory  int64  `yaml:"sandbox_memory_mb"`
-	SandboxNetwork string `yaml:"sandbox_network"`
+	Sandbox        SandboxMode `yaml:"sandbox"`
+	SandboxImage   string      `yaml:"sandbox_image"`     // docker only
+	SandboxMemory  int64       `yaml:"sandbox_memory_mb"` // default 512
+	SandboxNetwork string      `yaml:"sandbox_network"`   // docker: a network mode; native: none or host
+	SandboxCPUs    float64     `yaml:"sandbox_cpus"`      // native only; default 1
+	SandboxPids    int64       `yaml:"sandbox_pids"`      // native only; default 128
+	// SandboxCgroup is the delegated cgroup v2 directory native sandboxes
+	// are created under; empty uses goclaw's own cgroup.
+	SandboxCgroup string `yaml:"sandbox_cgroup"`
+	// SandboxReadOnlyPaths are extra host paths native sandboxes see
+	// read-only, e.g. a toolchain under /home.
+	SandboxReadOnlyPaths []string `yaml:"sandbox_readonly_paths"`
 }
 
 type ToolsConfig struct {
@@ -113,10 +193,104 @@ type TelegramConfig struct {
 	Token      string  `yaml:"token"`
 	AllowedIDs []int64 `yaml:"allowed_ids"`
 	Enabled    bool    `yaml:"enabled"`
+	// WebhookURL switches the channel from long polling to webhook mode:
+	// Telegram posts updates to this public URL, which must reach the
+	// gateway's /telegram/webhook, e.g. through a reverse proxy.
+	WebhookURL    string `yaml:"webhook_url"`
+	WebhookSecret string `yaml:"webhook_secret"` // required with webhook_url; checked against X-Telegram-Bot-Api-Secret-Token
+}
+
+// SlackConfig configures the Slack channel, which connects over Socket Mode.
+type SlackConfig struct {
+	BotToken       string   `yaml:"bot_token"`        // xoxb- token
+	AppToken       string   `yaml:"app_token"`        // xapp- token with connections:write
+	AllowedUserIDs []string `yaml:"allowed_user_ids"` // empty denies everyone
+	NotifyChannel  string   `yaml:"notify_channel"`   // alerts, approvals and plan progress outside threads
+	APIURL         string   `yaml:"api_url"`          // default https://slack.com/api/
+	Enabled        bool     `yaml:"enabled"`
+}
+
+// WebhookConfig configures the webhook channel. Inbound endpoints turn
+// signed POSTs into chat tasks; outbound endpoints receive bus events as
+// signed POSTs.
+type WebhookConfig struct {
+	Enabled     bool                    `yaml:"enabled"`
+	Inbound     []WebhookInboundConfig  `yaml:"inbound"`
+	Outbound    []WebhookOutboundConfig `yaml:"outbound"`
+	MaxAttempts int                     `yaml:"max_attempts"` // per delivery before it is dead-lettered; default 8
+}
+
+// WebhookInboundConfig is an endpoint served at POST /webhooks/<name>.
+// Session and Template are rendered with {{ path }} placeholders, where path
+// selects a field of the JSON body, e.g. {{ build.status }} or {{ commits[0].id }}.
+type WebhookInboundConfig struct {
+	Name     string `yaml:"name"`
+	Secret   string `yaml:"secret"`   // HMAC-SHA256 key; requests carry X-Goclaw-Timestamp, X-Goclaw-Delivery and X-Goclaw-Signature: sha256=<hex of "<timestamp>.<delivery>.<body>">
+	Agent    string `yaml:"agent"`    // default "default"
+	Session  string `yaml:"session"`  // session key template; default: one session per endpoint
+	Template string `yaml:"template"` // task content template; default: the raw body
+}
+
+// WebhookOutboundConfig is a URL that receives the events of Topics.
+type WebhookOutboundConfig struct {
+	Name   string   `yaml:"name"`
+	URL    string   `yaml:"url"`
+	Secret string   `yaml:"secret"` // signs each body like inbound requests; empty sends unsigned
+	Topics []string `yaml:"topics"` // bus topics; "plan.*" matches every topic starting with "plan."
+}
+
+// EmailConfig configures the email channel. Mail arrives by polling an IMAP
+// mailbox, on a local SMTP listener, or both; replies go out through SMTP.
+type EmailConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Address string `yaml:"address"` // the agents' address, e.g. agents@example.com; agents+<agent>@ routes to <agent>
+	// Agents routes further recipient addresses to agents.
+	Agents         map[string]string `yaml:"agents"`
+	DefaultAgent   string            `yaml:"default_agent"`   // default "default"
+	AllowedSenders []string          `yaml:"allowed_senders"` // addresses or @domain; empty denies everyone
+	IMAP           EmailIMAPConfig   `yaml:"imap"`
+	SMTP           EmailSMTPConfig   `yaml:"smtp"`
+	// Listen is a local SMTP listener address, e.g. 127.0.0.1:2525; empty
+	// disables it. It must only be reachable by an MTA that authenticates
+	// senders and adds Authentication-Results.
+	Listen string `yaml:"listen"`
+	// AuthservID is the authserv-id of the receiving MTA, e.g. mx.example.com.
+	// Mail is accepted only when the topmost Authentication-Results carries
+	// it and records an SPF, DKIM or DMARC pass for the sender's domain.
+	AuthservID string `yaml:"authserv_id"`
+	// InsecureSkipSenderAuth accepts mail without checking
+	// Authentication-Results, for MTAs that reject unauthenticated mail
+	// themselves. Required when AuthservID is empty.
+	InsecureSkipSenderAuth bool `yaml:"insecure_skip_sender_auth"`
+	// AttachAfter is the reply length in bytes above which the full reply
+	// is sent as a text attachment; default 8000.
+	AttachAfter int `yaml:"attach_after"`
+}
+
+// EmailIMAPConfig is the mailbox polled for new mail. Unseen messages are
+// processed and marked seen.
+type EmailIMAPConfig struct {
+	Addr         string `yaml:"addr"` // host:port; empty disables polling
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	Mailbox      string `yaml:"mailbox"`       // default INBOX
+	PollInterval int    `yaml:"poll_interval"` // seconds; default 60
+	Insecure     bool   `yaml:"insecure"`      // plain TCP instead of TLS, for local servers
+}
+
+// EmailSMTPConfig is the server replies are sent through. STARTTLS is used
+// when the server offers it.
+type EmailSMTPConfig struct {
+	Addr     string `yaml:"addr"` // host:port
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
 }
 
 type ChannelsConfig struct {
 	Telegram TelegramConfig `yaml:"telegram"`
+	Slack    SlackConfig    `yaml:"slack"`
+	Webhook  WebhookConfig  `yaml:"webhook"`
+	Email    EmailConfig    `yaml:"email"`
 }
 
 type MCPServerConfig struct {
@@ -124,8 +298,9 @@ type MCPServerConfig struct {
 	Command   string            `yaml:"command"`
 	Args      []string          `yaml:"args"`
 	Env       map[string]string `yaml:"env"`
-	URL       string            `yaml:"url,omitempty"`       // SSE endpoint (v0.4)
-	Transport string            `yaml:"transport,omitempty"` // "stdio" (default) or "sse" (v0.4)
+	URL       string            `yaml:"url,omitempty"`       // HTTP endpoint for sse / streamable_http (v0.4)
+	Transport string            `yaml:"transport,omitempty"` // "stdio" (default), "sse", or "streamable_http"
+	Headers   map[string]string `yaml:"headers,omitempty"`   // HTTP headers (e.g. Authorization: "Bearer ${TOKEN}")
 	Timeout   string            `yaml:"timeout,omitempty"`   // e.g. "30s" (v0.4)
 	Enabled   bool              `yaml:"enabled"`
 }
@@ -139,6 +314,7 @@ type AgentMCPRef struct {
 	URL       string            `yaml:"url,omitempty"`
 	Transport string            `yaml:"transport,omitempty"`
 	Env       map[string]string `yaml:"env,omitempty"`
+	Headers   map[string]string `yaml:"headers,omitempty"`
 	Timeout   string            `yaml:"timeout,omitempty"`
 }
 
@@ -196,10 +372,18 @@ type AuthConfig struct {
 
 // APIKeyEntry defines a single API key with optional scoping (v0.5).
 type APIKeyEntry struct {
-	Key         string   `yaml:"key"`
-	Description string   `yaml:"description,omitempty"`
-	AgentIDs    []string `yaml:"agent_ids,omitempty"`
-	RateLimit   int      `yaml:"rate_limit,omitempty"`
+	Key         string        `yaml:"key"`
+	Description string        `yaml:"description,omitempty"`
+	AgentIDs    []string      `yaml:"agent_ids,omitempty"`
+	RateLimit   int           `yaml:"rate_limit,omitempty"`
+	Budget      *BudgetConfig `yaml:"budget,omitempty"` // spend cap for tasks submitted with this key
+}
+
+// ID identifies the key in stored tasks and budget reports without revealing
+// it: a short SHA-256 fingerprint of the key.
+func (e APIKeyEntry) ID() string {
+	sum := sha256.Sum256([]byte(e.Key))
+	return "key-" + hex.EncodeToString(sum[:6])
 }
 
 // RateLimitConfig controls request rate limiting (v0.5).
@@ -230,12 +414,15 @@ type AgentConfigEntry struct {
 	WorkerCount        int                     `yaml:"worker_count"`
 	TaskTimeoutSeconds int                     `yaml:"task_timeout_seconds"`
 	MaxQueueDepth      int                     `yaml:"max_queue_depth"`
+	MaxToolTurns       int                     `yaml:"max_tool_turns,omitempty"` // Tool-call rounds per response; 0 = global max_tool_turns
 	SkillsFilter       []string                `yaml:"skills_filter"`
 	PreferredSearch    string                  `yaml:"preferred_search"`
 	Capabilities       []string                `yaml:"capabilities,omitempty"`
+	PolicyProfile      string                  `yaml:"policy_profile,omitempty"`    // Named profile in policy.yaml
 	MCPServers         []AgentMCPRef           `yaml:"mcp_servers,omitempty"`       // Per-agent MCP servers (v0.4)
 	Loop               LoopConfig              `yaml:"loop,omitempty"`              // Agent loop config (v0.5)
 	StructuredOutput   *StructuredOutputConfig `yaml:"structured_output,omitempty"` // Structured output config (v0.5)
+	Budget             *BudgetConfig           `yaml:"budget,omitempty"`            // Spend cap for the agent's tasks
 }
 
 type Config struct {
@@ -262,6 +449,15 @@ type Config struct {
 	// Providers holds per-provider configuration (API keys, custom endpoints, extra models).
 	Providers map[string]ProviderConfig `yaml:"providers"`
 
+	// Pricing overrides the built-in token prices, keyed by provider and model
+	// (e.g. pricing.google["gemini-2.5-pro"]). A provider's "*" entry prices
+	// its models that have no entry of their own.
+	Pricing map[string]map[string]ModelPrice `yaml:"pricing"`
+
+	// SessionBudget caps the spend of every session. Agents and API keys set
+	// their own budgets.
+	SessionBudget *BudgetConfig `yaml:"session_budget,omitempty"`
+
 	AgentName  string `yaml:"agent_name"`
 	AgentEmoji string `yaml:"agent_emoji"`
 
@@ -279,6 +475,10 @@ type Config struct {
 	// GC-SPEC-QUE-008: Maximum pending tasks before backpressure. 0 = unlimited.
 	MaxQueueDepth int `yaml:"max_queue_depth"`
 
+	// MaxToolTurns bounds the tool-call rounds in one response for agents that
+	// do not set their own max_tool_turns. 0 uses the engine default (10).
+	MaxToolTurns int `yaml:"max_tool_turns"`
+
 	// GC-SPEC-REL-005: Bounded drain timeout (seconds). 0 uses default (5s).
 	DrainTimeoutSeconds int `yaml:"drain_timeout_seconds"`
 
@@ -312,6 +512,12 @@ type Config struct {
 	Plans    []PlanConfig       `yaml:"plans"` // GC-SPEC-PDR-v4-Phase-4: Plans for workflows
 	A2A      A2AConfig          `yaml:"a2a,omitempty"`
 
+	// PlanGeneration controls plans written by agents with the create_plan tool.
+	PlanGeneration PlanGenerationConfig `yaml:"plan_generation"`
+
+	// Memory controls memory retrieval for each turn and memory search.
+	Memory MemoryConfig `yaml:"memory"`
+
 	// v0.5 config sections.
 	Streaming StreamingConfig       `yaml:"streaming,omitempty"`
 	Telemetry TelemetryConfig       `yaml:"telemetry,omitempty"`
@@ -326,11 +532,41 @@ type A2AConfig struct {
 	Enabled *bool `yaml:"enabled,omitempty"` // pointer to distinguish unset (default true) from false
 }
 
+// Gates for plans written by agents.
+const (
+	PlanGateAuto    = "auto"    // run generated plans straight away
+	PlanGateApprove = "approve" // ask for approval, then run
+	PlanGateReview  = "review"  // save as a draft to be reviewed, edited and approved by name
+)
+
+// PlanGenerationConfig controls the create_plan tool.
+type PlanGenerationConfig struct {
+	// Gate is auto, approve (default) or review.
+	Gate string `yaml:"gate"`
+}
+
 // PlanConfig defines a named workflow in config.yaml.
 // GC-SPEC-PDR-v4-Phase-4: Plan system configuration.
 type PlanConfig struct {
 	Name  string           `yaml:"name"`
 	Steps []PlanStepConfig `yaml:"steps"`
+	// MaxConcurrency caps how many steps of one execution run at once (0 = unlimited).
+	MaxConcurrency int `yaml:"max_concurrency"`
+	// MaxConcurrencyPerAgent caps running steps per agent within one execution (0 = unlimited).
+	MaxConcurrencyPerAgent int `yaml:"max_concurrency_per_agent"`
+	// Inputs are the parameters the plan is started with; prompts read them as {inputs.<name>}.
+	Inputs []PlanInputConfig `yaml:"inputs"`
+}
+
+// PlanInputConfig declares a plan input parameter.
+type PlanInputConfig struct {
+	Name string `yaml:"name"`
+	// Type is string (default), number, integer or boolean.
+	Type        string `yaml:"type"`
+	Description string `yaml:"description"`
+	Required    bool   `yaml:"required"`
+	// Default is used when the input is not supplied.
+	Default any `yaml:"default"`
 }
 
 // PlanStepConfig defines a step within a plan.
@@ -339,6 +575,20 @@ type PlanStepConfig struct {
 	AgentID   string   `yaml:"agent_id"`
 	Prompt    string   `yaml:"prompt"`
 	DependsOn []string `yaml:"depends_on"`
+	// TimeoutSeconds bounds one attempt of the step (0 = executor default).
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+	// MaxRetries is how often a failed attempt is retried (unset = 2, 0 = never).
+	MaxRetries *int `yaml:"max_retries"`
+	// RequireApproval holds the step until a human approves it.
+	RequireApproval bool `yaml:"require_approval"`
+	// ApprovalTimeoutSeconds bounds the wait for approval (0 = 60); unanswered requests are rejected.
+	ApprovalTimeoutSeconds int `yaml:"approval_timeout_seconds"`
+	// OnFailure is fail_plan (default), continue or skip_dependents.
+	OnFailure string `yaml:"on_failure"`
+	// When is a condition on an earlier step's result; the step is skipped when it is false.
+	When string `yaml:"when"`
+	// OutputSchema is a JSON Schema, written as YAML, that the step's output must match.
+	OutputSchema map[string]any `yaml:"output_schema"`
 }
 
 // APIKey returns the value for the named API key, checking env overrides first.
@@ -652,6 +902,10 @@ func normalize(cfg *Config) {
 	if strings.TrimSpace(cfg.Skills.ProjectDir) == "" {
 		cfg.Skills.ProjectDir = "./skills"
 	}
+	cfg.PlanGeneration.Gate = strings.ToLower(strings.TrimSpace(cfg.PlanGeneration.Gate))
+	if cfg.PlanGeneration.Gate == "" {
+		cfg.PlanGeneration.Gate = PlanGateApprove
+	}
 
 	// Backward compat: copy gemini_api_key into providers.gemini.api_key if not set.
 	if cfg.GeminiAPIKey != "" {
@@ -807,6 +1061,21 @@ func applyEnvOverrides(cfg *Config) {
 	if raw := os.Getenv("TELEGRAM_TOKEN"); raw != "" {
 		cfg.Channels.Telegram.Token = raw
 	}
+	if raw := os.Getenv("TELEGRAM_WEBHOOK_SECRET"); raw != "" {
+		cfg.Channels.Telegram.WebhookSecret = raw
+	}
+	if raw := os.Getenv("SLACK_BOT_TOKEN"); raw != "" {
+		cfg.Channels.Slack.BotToken = raw
+	}
+	if raw := os.Getenv("SLACK_APP_TOKEN"); raw != "" {
+		cfg.Channels.Slack.AppToken = raw
+	}
+	if raw := os.Getenv("EMAIL_IMAP_PASSWORD"); raw != "" {
+		cfg.Channels.Email.IMAP.Password = raw
+	}
+	if raw := os.Getenv("EMAIL_SMTP_PASSWORD"); raw != "" {
+		cfg.Channels.Email.SMTP.Password = raw
+	}
 }
 
 func loadTextFiles(cfg *Config) {
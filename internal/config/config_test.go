@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/basket/go-claw/internal/config"
+	"gopkg.in/yaml.v3"
 )
 
 func TestLoad_FromGoclawHome(t *testing.T) {
@@ -414,3 +415,29 @@ func TestLoad_PreferredSearchFromYAML(t *testing.T) {
 		t.Fatalf("expected preferred_search=perplexity_search, got %q", cfg.PreferredSearch)
 	}
 }
+
+func TestSandboxMode_UnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		yaml    string
+		want    config.SandboxMode
+		wantErr bool
+	}{
+		{"sandbox: true", config.SandboxDocker, false},
+		{"sandbox: false", config.SandboxNone, false},
+		{"sandbox: docker", config.SandboxDocker, false},
+		{"sandbox: Native", config.SandboxNative, false},
+		{"sandbox: none", config.SandboxNone, false},
+		{"sandbox_image: alpine", config.SandboxNone, false},
+		{"sandbox: firecracker", "", true},
+	}
+	for _, tt := range tests {
+		var shell config.ShellConfig
+		err := yaml.Unmarshal([]byte(tt.yaml), &shell)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%q: err = %v, wantErr %v", tt.yaml, err, tt.wantErr)
+		}
+		if err == nil && shell.Sandbox != tt.want {
+			t.Errorf("%q: mode = %q, want %q", tt.yaml, shell.Sandbox, tt.want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Condition is a parsed `when:` expression. It compares one value from an
+// earlier step's result with a literal:
+//
+//	review.status == succeeded
+//	review.output contains "LGTM"
+//	review.output.verdict == "approve"
+//	review.output.issues[0].severity != low
+//	review.output.score >= 0.8
+//	review.output.ready
+//
+// "output" alone is the step's raw text; "output.<path>" parses the output as
+// JSON (a surrounding ``` fence is ignored) and follows the path. "status" is
+// succeeded, failed or skipped. Without an operator the value must be truthy:
+// present and not false, 0, "" or null. A missing value satisfies only !=.
+type Condition struct {
+	StepID string
+	field  string // "output" or "status"
+	path   []any  // string keys and int indexes into the JSON output
+	op     string // "" (truthy), ==, !=, <, <=, >, >=, contains
+	value  any
+}
+
+var conditionOps = []string{"==", "!=", ">=", "<=", ">", "<", "contains"}
+
+// ParseCondition parses a `when:` expression.
+func ParseCondition(expr string) (*Condition, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("empty condition")
+	}
+	ref, rest, _ := strings.Cut(expr, " ")
+	c := &Condition{}
+	if err := c.parseRef(ref); err != nil {
+		return nil, err
+	}
+
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return c, nil
+	}
+	for _, op := range conditionOps {
+		if after, ok := strings.CutPrefix(rest, op); ok {
+			c.op = op
+			rest = strings.TrimSpace(after)
+			break
+		}
+	}
+	if c.op == "" {
+		return nil, fmt.Errorf("condition %q: unknown operator in %q", expr, rest)
+	}
+	if rest == "" {
+		return nil, fmt.Errorf("condition %q: missing value after %s", expr, c.op)
+	}
+	v, err := parseLiteral(rest)
+	if err != nil {
+		return nil, fmt.Errorf("condition %q: %w", expr, err)
+	}
+	c.value = v
+	return c, nil
+}
+
+func (c *Condition) parseRef(ref string) error {
+	stepID, rest, ok := strings.Cut(ref, ".")
+	if !ok || stepID == "" {
+		return fmt.Errorf("condition reference %q must be <step>.output or <step>.status", ref)
+	}
+	c.StepID = stepID
+	switch {
+	case rest == "status":
+		c.field = "status"
+		return nil
+	case rest == "output":
+		c.field = "output"
+		return nil
+	case strings.HasPrefix(rest, "output.") || strings.HasPrefix(rest, "output["):
+		c.field = "output"
+		path, err := parsePath(strings.TrimPrefix(rest, "output"))
+		if err != nil {
+			return fmt.Errorf("condition reference %q: %w", ref, err)
+		}
+		c.path = path
+		return nil
+	}
+	return fmt.Errorf("condition reference %q must be <step>.output or <step>.status", ref)
+}
+
+// parsePath parses ".a.b[0].c" into keys and indexes.
+func parsePath(s string) ([]any, error) {
+	var path []any
+	for s != "" {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty path segment")
+			}
+			path = append(path, s[:end])
+			s = s[end:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [")
+			}
+			idx, err := strconv.Atoi(s[1:end])
+			if err != nil || idx < 0 {
+				return nil, fmt.Errorf("invalid index %q", s[1:end])
+			}
+			path = append(path, idx)
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q in path", s)
+		}
+	}
+	return path, nil
+}
+
+// parseLiteral reads a quoted string, a JSON number, true, false or null;
+// anything else is taken as a bare string.
+func parseLiteral(s string) (any, error) {
+	if strings.HasPrefix(s, `"`) {
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted value %s", s)
+		}
+		return v, nil
+	}
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err == nil {
+		switch v.(type) {
+		case float64, bool, nil:
+			return v, nil
+		}
+	}
+	return s, nil
+}
+
+// Eval reports whether the condition holds for the given step results.
+func (c *Condition) Eval(results map[string]StepResult) bool {
+	v, ok := c.lookup(results)
+	if !ok {
+		return c.op == "!="
+	}
+	switch c.op {
+	case "":
+		return truthy(v)
+	case "==":
+		return valuesEqual(v, c.value)
+	case "!=":
+		return !valuesEqual(v, c.value)
+	case "contains":
+		if arr, isArr := v.([]any); isArr {
+			for _, el := range arr {
+				if valuesEqual(el, c.value) {
+					return true
+				}
+			}
+			return false
+		}
+		return strings.Contains(valueString(v), valueString(c.value))
+	}
+	a, okA := number(v)
+	b, okB := number(c.value)
+	if !okA || !okB {
+		return false
+	}
+	switch c.op {
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
+
+func (c *Condition) lookup(results map[string]StepResult) (any, bool) {
+	sr, ok := results[c.StepID]
+	if !ok {
+		return nil, false
+	}
+	if c.field == "status" {
+		return stepState(sr.Status), true
+	}
+	if len(c.path) == 0 {
+		return sr.Output, true
+	}
+	var v any
+	if err := json.Unmarshal([]byte(jsonPayload(sr.Output)), &v); err != nil {
+		return nil, false
+	}
+	for _, p := range c.path {
+		switch key := p.(type) {
+		case string:
+			obj, isObj := v.(map[string]any)
+			if !isObj {
+				return nil, false
+			}
+			if v, ok = obj[key]; !ok {
+				return nil, false
+			}
+		case int:
+			arr, isArr := v.([]any)
+			if !isArr || key >= len(arr) {
+				return nil, false
+			}
+			v = arr[key]
+		}
+	}
+	return v, true
+}
+
+// jsonPayload strips a Markdown code fence that models often put around
+// structured output.
+func jsonPayload(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+		s = s[nl+1:] // drop the language tag line
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
+
+func truthy(v any) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case float64:
+		return t != 0
+	case string:
+		return t != ""
+	}
+	return true
+}
+
+func valuesEqual(a, b any) bool {
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			return x == y
+		}
+	}
+	return valueString(a) == valueString(b)
+}
+
+func number(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func valueString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
@@ -0,0 +1,100 @@
+package coordinator
+
+import "testing"
+
+func TestCondition_Eval(t *testing.T) {
+	results := map[string]StepResult{
+		"review": {Status: "SUCCEEDED", Output: "```json\n{\"verdict\": \"approve\", \"score\": 0.9, \"ready\": true, \"issues\": [{\"severity\": \"low\"}], \"tags\": [\"go\", \"api\"]}\n```"},
+		"lint":   {Status: "FAILED", Error: "exit 1"},
+		"notes":  {Status: "succeeded", Output: "All good, LGTM."},
+		"gate":   {Status: StatusSkipped},
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"review.status == succeeded", true},
+		{"lint.status == failed", true},
+		{"gate.status == skipped", true},
+		{"notes.status != succeeded", false},
+		{`notes.output contains "LGTM"`, true},
+		{"notes.output contains nope", false},
+		{`review.output.verdict == "approve"`, true},
+		{"review.output.verdict == reject", false},
+		{"review.output.issues[0].severity != low", false},
+		{"review.output.score >= 0.8", true},
+		{"review.output.score < 0.5", false},
+		{"review.output.ready", true},
+		{"review.output.ready == true", true},
+		{"review.output.tags contains api", true},
+		{"review.output.missing", false},
+		{"review.output.missing != x", true},
+		{"review.output.issues[3].severity == low", false},
+		{"notes.output.verdict == approve", false}, // not JSON
+		{"absent.status == succeeded", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := ParseCondition(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCondition: %v", err)
+			}
+			if got := c.Eval(results); got != tt.want {
+				t.Errorf("Eval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCondition_Errors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"review",
+		"review.result == x",
+		"review.output ~= x",
+		"review.output ==",
+		"review.output.a..b",
+		"review.output[x] == 1",
+		`review.output == "unterminated`,
+	} {
+		if _, err := ParseCondition(expr); err == nil {
+			t.Errorf("ParseCondition(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestValidate_ConditionsAndPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []PlanStep
+		wantErr bool
+	}{
+		{"condition on ancestor", []PlanStep{
+			{ID: "a", AgentID: "x", Prompt: "1"},
+			{ID: "b", AgentID: "x", Prompt: "2", DependsOn: []string{"a"}},
+			{ID: "c", AgentID: "x", Prompt: "3", DependsOn: []string{"b"}, When: "a.status == succeeded"},
+		}, false},
+		{"condition on unrelated step", []PlanStep{
+			{ID: "a", AgentID: "x", Prompt: "1"},
+			{ID: "b", AgentID: "x", Prompt: "2", When: "a.status == succeeded"},
+		}, true},
+		{"malformed condition", []PlanStep{
+			{ID: "a", AgentID: "x", Prompt: "1"},
+			{ID: "b", AgentID: "x", Prompt: "2", DependsOn: []string{"a"}, When: "a.output ??"},
+		}, true},
+		{"unknown policy", []PlanStep{
+			{ID: "a", AgentID: "x", Prompt: "1", OnFailure: "retry_forever"},
+		}, true},
+		{"too many retries", []PlanStep{
+			{ID: "a", AgentID: "x", Prompt: "1", MaxRetries: 11},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Plan{Steps: tt.steps}).Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package coordinator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/basket/go-claw/internal/bus"
+)
+
+// Errors returned by the execution controls.
+var (
+	// ErrExecutionNotFound is returned for unknown execution IDs.
+	ErrExecutionNotFound = errors.New("plan execution not found")
+	// ErrExecutionNotRunning is returned when pausing, resuming or canceling
+	// an execution that has finished.
+	ErrExecutionNotRunning = errors.New("plan execution not running")
+	// ErrExecutionRunning is returned when re-running an execution that has
+	// not finished.
+	ErrExecutionRunning = errors.New("plan execution still running")
+	// ErrUnknownStep is returned when re-running from a step the plan does
+	// not have.
+	ErrUnknownStep = errors.New("unknown plan step")
+	// ErrExecutionCanceled is returned by Execute and Resume when the
+	// execution was canceled with Cancel.
+	ErrExecutionCanceled = errors.New("plan execution canceled")
+)
+
+// execControl is the handle on an execution running in this process.
+type execControl struct {
+	cancel context.CancelCauseFunc
+
+	mu      sync.Mutex
+	paused  bool
+	changed chan struct{} // closed and replaced whenever paused changes
+}
+
+// state reports whether the execution is paused, and a channel closed on the
+// next change. A nil control is never paused.
+func (c *execControl) state() (paused bool, changed <-chan struct{}) {
+	if c == nil {
+		return false, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused, c.changed
+}
+
+// setPaused updates the pause state and reports whether it changed.
+func (c *execControl) setPaused(paused bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused == paused {
+		return false
+	}
+	c.paused = paused
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return true
+}
+
+// track registers a control for an execution starting in this process. The
+// returned context is canceled by Cancel; release must be called when the
+// execution returns.
+func (e *Executor) track(ctx context.Context, execID string, paused bool) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	ctl := &execControl{cancel: cancel, paused: paused, changed: make(chan struct{})}
+	e.controlsMu.Lock()
+	if e.controls == nil {
+		e.controls = make(map[string]*execControl)
+	}
+	e.controls[execID] = ctl
+	e.controlsMu.Unlock()
+	return ctx, func() {
+		e.controlsMu.Lock()
+		if e.controls[execID] == ctl {
+			delete(e.controls, execID)
+		}
+		e.controlsMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// control returns the control of a running execution, or nil.
+func (e *Executor) control(execID string) *execControl {
+	e.controlsMu.Lock()
+	defer e.controlsMu.Unlock()
+	return e.controls[execID]
+}
+
+// notRunning explains why an execution has no control: it is unknown, or it
+// has finished.
+func (e *Executor) notRunning(ctx context.Context, execID string) error {
+	if e.store == nil {
+		return fmt.Errorf("%w: %s", ErrExecutionNotFound, execID)
+	}
+	if _, err := e.store.GetPlanExecution(ctx, execID); errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %s", ErrExecutionNotFound, execID)
+	} else if err != nil {
+		return fmt.Errorf("get plan execution: %w", err)
+	}
+	return fmt.Errorf("%w: %s", ErrExecutionNotRunning, execID)
+}
+
+// Pause stops an execution from dispatching new steps. Steps already running
+// finish normally. The pause is persisted, so a plan resumed after a restart
+// stays paused.
+func (e *Executor) Pause(ctx context.Context, execID string) error {
+	return e.setPaused(ctx, execID, true)
+}
+
+// Unpause lets a paused execution dispatch steps again. (Resume, by contrast,
+// picks up an execution after a crash.)
+func (e *Executor) Unpause(ctx context.Context, execID string) error {
+	return e.setPaused(ctx, execID, false)
+}
+
+func (e *Executor) setPaused(ctx context.Context, execID string, paused bool) error {
+	ctl := e.control(execID)
+	if ctl == nil {
+		return e.notRunning(ctx, execID)
+	}
+	if !ctl.setPaused(paused) {
+		return nil
+	}
+	if e.store != nil {
+		if err := e.store.SetPlanExecutionPaused(ctx, execID, paused); err != nil {
+			slog.Warn("failed to record plan pause state", "execution_id", execID, "paused", paused, "error", err)
+		}
+	}
+	topic := bus.TopicPlanExecutionResumed
+	if paused {
+		topic = bus.TopicPlanExecutionPaused
+	}
+	e.publish(topic, map[string]interface{}{"execution_id": execID})
+	return nil
+}
+
+// Cancel stops an execution: nothing new is dispatched, the tasks of running
+// steps are aborted and the execution ends as canceled. An execution left
+// running by an earlier process is marked canceled directly.
+func (e *Executor) Cancel(ctx context.Context, execID string) error {
+	if ctl := e.control(execID); ctl != nil {
+		e.publish(bus.TopicPlanExecutionCanceled, map[string]interface{}{"execution_id": execID})
+		ctl.cancel(ErrExecutionCanceled)
+		return nil
+	}
+	if e.store == nil {
+		return fmt.Errorf("%w: %s", ErrExecutionNotFound, execID)
+	}
+	exec, err := e.store.GetPlanExecution(ctx, execID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %s", ErrExecutionNotFound, execID)
+	} else if err != nil {
+		return fmt.Errorf("get plan execution: %w", err)
+	}
+	if exec.Status != "running" {
+		return fmt.Errorf("%w: %s is %s", ErrExecutionNotRunning, execID, exec.Status)
+	}
+	steps, err := e.store.GetPlanSteps(ctx, execID)
+	if err != nil {
+		return fmt.Errorf("get plan steps: %w", err)
+	}
+	for _, s := range steps {
+		if s.Status == "running" && s.TaskID != "" {
+			e.abortTask(ctx, s.TaskID)
+		}
+	}
+	e.publish(bus.TopicPlanExecutionCanceled, map[string]interface{}{"execution_id": execID})
+	if err := e.store.CompletePlanExecution(ctx, execID, "canceled", 0); err != nil {
+		return fmt.Errorf("cancel plan execution: %w", err)
+	}
+	return nil
+}
+
+// ResetFrom prepares a finished execution to run again from stepID: that
+// step and every step depending on it, directly or not, go back to pending
+// and the execution to running. Resume then runs them again, reusing the
+// persisted outputs of all other steps.
+func (e *Executor) ResetFrom(ctx context.Context, execID, stepID string, plan *Plan) error {
+	if e.store == nil {
+		return fmt.Errorf("cannot rerun without store")
+	}
+	if e.control(execID) != nil {
+		return fmt.Errorf("%w: %s", ErrExecutionRunning, execID)
+	}
+	exec, err := e.store.GetPlanExecution(ctx, execID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %s", ErrExecutionNotFound, execID)
+	} else if err != nil {
+		return fmt.Errorf("get plan execution: %w", err)
+	}
+	if exec.Status == "running" {
+		return fmt.Errorf("%w: %s", ErrExecutionRunning, execID)
+	}
+
+	dependents := make(map[string][]string)
+	known := false
+	for _, step := range plan.Steps {
+		known = known || step.ID == stepID
+		for _, dep := range step.DependsOn {
+			dependents[dep] = append(dependents[dep], step.ID)
+		}
+	}
+	if !known {
+		return fmt.Errorf("%w: %s", ErrUnknownStep, stepID)
+	}
+	reset := []string{stepID}
+	seen := map[string]bool{stepID: true}
+	for i := 0; i < len(reset); i++ {
+		for _, next := range dependents[reset[i]] {
+			if !seen[next] {
+				seen[next] = true
+				reset = append(reset, next)
+			}
+		}
+	}
+
+	if err := e.store.ReopenPlanExecution(ctx, execID, reset); err != nil {
+		return fmt.Errorf("reopen plan execution: %w", err)
+	}
+	e.publish(bus.TopicPlanExecutionRerun, map[string]interface{}{
+		"execution_id": execID,
+		"step_id":      stepID,
+		"steps":        reset,
+	})
+	return nil
+}
+
+// complete records the end of an execution whose schedule returned err. A
+// canceled execution is recorded as canceled even though ctx is done; after a
+// shutdown the write fails and the execution stays running for Resume.
+func (e *Executor) complete(ctx context.Context, execID string, err error, costUSD float64) {
+	status := "failed"
+	if errors.Is(err, ErrExecutionCanceled) {
+		status = "canceled"
+		ctx = context.WithoutCancel(ctx)
+	}
+	_ = e.store.CompletePlanExecution(ctx, execID, status, costUSD)
+}
+
+// publish sends a plan event on the executor's bus, if any.
+func (e *Executor) publish(topic string, payload map[string]interface{}) {
+	if e.bus != nil {
+		e.bus.Publish(topic, payload)
+	}
+}
@@ -0,0 +1,225 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+// runningExecution returns the ID of the only execution exec is running.
+func runningExecution(t *testing.T, exec *Executor) string {
+	t.Helper()
+	var execID string
+	waitFor(t, "execution to start", func() bool {
+		exec.controlsMu.Lock()
+		defer exec.controlsMu.Unlock()
+		for id := range exec.controls {
+			execID = id
+		}
+		return execID != ""
+	})
+	return execID
+}
+
+func TestExecutor_PauseAndUnpause(t *testing.T) {
+	ctx := context.Background()
+	store, sessionID := openSchedulerStore(t)
+	router := &storeRouter{store: store, autoFinish: map[string]bool{"b": true}}
+	exec := NewExecutor(router, NewWaiter(nil, store), store, nil)
+
+	plan := &Plan{Name: "pausable", Steps: []PlanStep{
+		{ID: "first", AgentID: "a", Prompt: "1"},
+		{ID: "second", AgentID: "b", Prompt: "2", DependsOn: []string{"first"}},
+	}}
+	done := startExecute(exec, plan, sessionID)
+	execID := runningExecution(t, exec)
+	waitFor(t, "first step", func() bool { return len(router.tasks("a")) == 1 })
+
+	if err := exec.Pause(ctx, execID); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if pe, err := store.GetPlanExecution(ctx, execID); err != nil || !pe.Paused {
+		t.Fatalf("persisted paused = %v, %v; want true", pe.Paused, err)
+	}
+
+	// The running step finishes, but its dependent is not dispatched.
+	finishTask(t, store, router.tasks("a")[0], "one")
+	waitFor(t, "first step recorded", func() bool {
+		steps, _ := store.GetPlanSteps(ctx, execID)
+		return len(steps) == 2 && steps[0].Status == "succeeded"
+	})
+	time.Sleep(100 * time.Millisecond)
+	if n := len(router.tasks("b")); n != 0 {
+		t.Fatalf("paused execution dispatched %d tasks", n)
+	}
+
+	if err := exec.Unpause(ctx, execID); err != nil {
+		t.Fatalf("unpause: %v", err)
+	}
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("execute: %v", res.err)
+	}
+	if len(router.tasks("b")) != 1 {
+		t.Errorf("second step tasks = %v, want 1", router.tasks("b"))
+	}
+	pe, err := store.GetPlanExecution(ctx, execID)
+	if err != nil {
+		t.Fatalf("get execution: %v", err)
+	}
+	if pe.Status != "succeeded" || pe.Paused {
+		t.Errorf("execution = %s (paused %v), want succeeded", pe.Status, pe.Paused)
+	}
+
+	if err := exec.Pause(ctx, execID); !errors.Is(err, ErrExecutionNotRunning) {
+		t.Errorf("pause finished execution err = %v, want ErrExecutionNotRunning", err)
+	}
+	if err := exec.Unpause(ctx, "missing"); !errors.Is(err, ErrExecutionNotFound) {
+		t.Errorf("unpause unknown execution err = %v, want ErrExecutionNotFound", err)
+	}
+}
+
+func TestExecutor_Cancel(t *testing.T) {
+	ctx := context.Background()
+	store, sessionID := openSchedulerStore(t)
+	router := &storeRouter{store: store}
+	exec := NewExecutor(router, NewWaiter(nil, store), store, nil)
+
+	plan := &Plan{Name: "cancelable", Steps: []PlanStep{
+		{ID: "first", AgentID: "a", Prompt: "1"},
+		{ID: "second", AgentID: "b", Prompt: "2", DependsOn: []string{"first"}},
+	}}
+	done := startExecute(exec, plan, sessionID)
+	execID := runningExecution(t, exec)
+	waitFor(t, "first step", func() bool { return len(router.tasks("a")) == 1 })
+
+	if err := exec.Cancel(ctx, execID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	res := <-done
+	if !errors.Is(res.err, ErrExecutionCanceled) {
+		t.Fatalf("execute err = %v, want ErrExecutionCanceled", res.err)
+	}
+	if len(router.tasks("b")) != 0 {
+		t.Errorf("canceled execution dispatched %v", router.tasks("b"))
+	}
+	task, err := store.GetTask(ctx, router.tasks("a")[0])
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if task.Status != persistence.TaskStatusCanceled {
+		t.Errorf("running task status = %s, want CANCELED", task.Status)
+	}
+	pe, err := store.GetPlanExecution(ctx, execID)
+	if err != nil {
+		t.Fatalf("get execution: %v", err)
+	}
+	if pe.Status != "canceled" {
+		t.Errorf("execution status = %s, want canceled", pe.Status)
+	}
+	steps, _ := store.GetPlanSteps(ctx, execID)
+	if steps[0].Status != "failed" || steps[0].Error != "plan canceled" {
+		t.Errorf("first step = %s (%s), want failed by cancel", steps[0].Status, steps[0].Error)
+	}
+
+	if err := exec.Cancel(ctx, execID); !errors.Is(err, ErrExecutionNotRunning) {
+		t.Errorf("cancel twice err = %v, want ErrExecutionNotRunning", err)
+	}
+	if err := exec.Cancel(ctx, "missing"); !errors.Is(err, ErrExecutionNotFound) {
+		t.Errorf("cancel unknown execution err = %v, want ErrExecutionNotFound", err)
+	}
+}
+
+func TestExecutor_CancelOrphanedExecution(t *testing.T) {
+	ctx := context.Background()
+	store, sessionID := openSchedulerStore(t)
+	exec := NewExecutor(&storeRouter{store: store}, NewWaiter(nil, store), store, nil)
+
+	// Left running by an earlier process that never resumed it.
+	execID := "7c1e9a3b-5d2f-4e8a-b6c0-1f3d5a7e9b2c"
+	if err := store.CreatePlanExecution(ctx, execID, "orphan", sessionID, 1); err != nil {
+		t.Fatalf("create execution: %v", err)
+	}
+	if err := store.InitializePlanSteps(ctx, execID, []persistence.PlanExecutionStep{
+		{StepID: "only", AgentID: "a", Prompt: "1"},
+	}); err != nil {
+		t.Fatalf("initialize steps: %v", err)
+	}
+	taskID, err := store.CreateTaskForAgent(ctx, "a", sessionID, "1")
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if err := store.MarkStepRunning(ctx, execID, "only", taskID); err != nil {
+		t.Fatalf("mark running: %v", err)
+	}
+
+	if err := exec.Cancel(ctx, execID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if pe, _ := store.GetPlanExecution(ctx, execID); pe.Status != "canceled" {
+		t.Errorf("execution status = %s, want canceled", pe.Status)
+	}
+	if task, _ := store.GetTask(ctx, taskID); task.Status != persistence.TaskStatusCanceled {
+		t.Errorf("task status = %s, want CANCELED", task.Status)
+	}
+}
+
+func TestExecutor_RerunFromStep(t *testing.T) {
+	ctx := context.Background()
+	store, sessionID := openSchedulerStore(t)
+	router := &storeRouter{store: store, autoFinish: map[string]bool{"a": true, "b": true, "c": true, "d": true}}
+	exec := NewExecutor(router, NewWaiter(nil, store), store, nil)
+
+	plan := &Plan{Name: "rerun", Steps: []PlanStep{
+		{ID: "fetch", AgentID: "a", Prompt: "fetch"},
+		{ID: "draft", AgentID: "b", Prompt: "draft from {fetch.output}", DependsOn: []string{"fetch"}},
+		{ID: "review", AgentID: "c", Prompt: "review {draft.output}", DependsOn: []string{"draft"}},
+		{ID: "notes", AgentID: "d", Prompt: "notes", DependsOn: []string{"fetch"}},
+	}}
+	res := <-startExecute(exec, plan, sessionID)
+	if res.err != nil {
+		t.Fatalf("execute: %v", res.err)
+	}
+	execID := res.result.ExecutionID
+
+	if err := exec.ResetFrom(ctx, execID, "missing", plan); !errors.Is(err, ErrUnknownStep) {
+		t.Errorf("reset from unknown step err = %v, want ErrUnknownStep", err)
+	}
+	if err := exec.ResetFrom(ctx, "missing", "draft", plan); !errors.Is(err, ErrExecutionNotFound) {
+		t.Errorf("reset unknown execution err = %v, want ErrExecutionNotFound", err)
+	}
+
+	if err := exec.ResetFrom(ctx, execID, "draft", plan); err != nil {
+		t.Fatalf("reset from draft: %v", err)
+	}
+	if err := exec.ResetFrom(ctx, execID, "draft", plan); !errors.Is(err, ErrExecutionRunning) {
+		t.Errorf("reset reopened execution err = %v, want ErrExecutionRunning", err)
+	}
+	result, err := exec.Resume(ctx, execID, plan)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+
+	// Only draft and what depends on it ran again.
+	for agent, want := range map[string]int{"a": 1, "b": 2, "c": 2, "d": 1} {
+		if got := len(router.tasks(agent)); got != want {
+			t.Errorf("agent %s tasks = %d, want %d", agent, got, want)
+		}
+	}
+	if got := router.contents("b")[1]; got != "draft from a done" {
+		t.Errorf("re-run prompt = %q, want the cached fetch output", got)
+	}
+	if got := result.StepResults["review"].TaskID; got != router.tasks("c")[1] {
+		t.Errorf("review task = %s, want the re-run task", got)
+	}
+	pe, err := store.GetPlanExecution(ctx, execID)
+	if err != nil {
+		t.Fatalf("get execution: %v", err)
+	}
+	if pe.Status != "succeeded" || pe.CompletedSteps != 4 {
+		t.Errorf("execution = %s with %d steps done, want succeeded with 4", pe.Status, pe.CompletedSteps)
+	}
+}
@@ -2,8 +2,9 @@ package coordinator
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/basket/go-claw/internal/bus"
@@ -27,6 +28,11 @@ type Executor struct {
 	waiter     *Waiter
 	store      *persistence.Store
 	bus        *bus.Bus // GC-SPEC-PDR-v7-Phase-3: For HITL approval events
+
+	validateOutput OutputValidator
+
+	controlsMu sync.Mutex
+	controls   map[string]*execControl // running executions by ID
 }
 
 // NewExecutor creates a DAG executor with completion tracking.
@@ -39,20 +45,27 @@ func NewExecutor(router ChatTaskRouter, waiter *Waiter, store *persistence.Store
 	}
 }
 
-// Execute runs a plan and returns the results.
+// Execute runs a plan with the given inputs and returns the results.
 // If executionID is non-empty, the caller owns DB lifecycle (CreatePlanExecution/CompletePlanExecution);
-// the executor only tracks steps and waves internally. If empty, the executor generates an ID and
-// manages the full DB lifecycle itself.
-func (e *Executor) Execute(ctx context.Context, plan *Plan, sessionID, executionID string) (*ExecutionResult, error) {
+// the executor only tracks steps internally. If empty, the executor generates an ID and
+// manages the full DB lifecycle itself. Inputs are checked with BindInputs
+// before anything is recorded or queued.
+func (e *Executor) Execute(ctx context.Context, plan *Plan, sessionID, executionID string, inputs map[string]any) (*ExecutionResult, error) {
 	if err := plan.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid plan: %w", err)
 	}
+	bound, err := plan.BindInputs(inputs)
+	if err != nil {
+		return nil, err
+	}
 
 	callerOwnsDB := executionID != ""
 	execID := executionID
 	if execID == "" {
 		execID = uuid.New().String()
 	}
+	ctx, release := e.track(ctx, execID, false)
+	defer release()
 
 	// Record plan start (only if executor owns DB lifecycle)
 	if !callerOwnsDB && e.store != nil {
@@ -61,9 +74,26 @@ func (e *Executor) Execute(ctx context.Context, plan *Plan, sessionID, execution
 
 	result := &ExecutionResult{
 		ExecutionID: execID,
+		Inputs:      bound,
 		StepResults: make(map[string]StepResult),
 	}
 
+	// Inputs are kept with the execution so Resume can substitute them.
+	if e.store != nil && len(bound) > 0 {
+		raw, err := json.Marshal(bound)
+		if err != nil {
+			return nil, fmt.Errorf("encode plan inputs: %w", err)
+		}
+		if err := e.store.SetPlanExecutionInputs(ctx, execID, string(raw)); err != nil {
+			if !callerOwnsDB {
+				_ = e.store.CompletePlanExecution(ctx, execID, "failed", 0)
+			}
+			return nil, fmt.Errorf("record plan inputs: %w", err)
+		}
+	}
+
+	// Waves are no longer an execution boundary; the wave number is kept on
+	// step records as the step's depth in the DAG.
 	order, err := topoSort(plan.Steps)
 	if err != nil {
 		if !callerOwnsDB && e.store != nil {
@@ -94,21 +124,11 @@ func (e *Executor) Execute(ctx context.Context, plan *Plan, sessionID, execution
 		}
 	}
 
-	for waveNum, wave := range order {
-		if len(wave) == 0 {
-			continue
-		}
-		if err := e.executeWave(ctx, execID, sessionID, wave, result); err != nil {
-			if !callerOwnsDB && e.store != nil {
-				_ = e.store.CompletePlanExecution(ctx, execID, "failed", result.TotalCost())
-			}
-			return result, fmt.Errorf("wave %d failed: %w", waveNum, err)
-		}
-
-		// GC-SPEC-PDR-v4-Phase-2: Track completed waves for resumption
-		if e.store != nil {
-			_ = e.store.UpdatePlanWave(ctx, execID, waveNum+1)
+	if err := e.schedule(ctx, execID, sessionID, plan, result, nil, nil); err != nil {
+		if !callerOwnsDB && e.store != nil {
+			e.complete(ctx, execID, err, result.TotalCost())
 		}
+		return result, err
 	}
 
 	// Record completion (only if executor owns DB lifecycle)
@@ -119,7 +139,10 @@ func (e *Executor) Execute(ctx context.Context, plan *Plan, sessionID, execution
 	return result, nil
 }
 
-// Resume continues execution of a crashed plan from the last completed wave.
+// Resume continues execution of a crashed or re-run plan. Finished steps keep
+// their persisted results, steps that were running are re-attached to their
+// task, and everything else is scheduled as usual. A paused execution stays
+// paused until Unpause.
 // GC-SPEC-PDR-v4-Phase-3: Plan resumption after crash.
 func (e *Executor) Resume(ctx context.Context, execID string, plan *Plan) (*ExecutionResult, error) {
 	if err := plan.Validate(); err != nil {
@@ -146,217 +169,49 @@ func (e *Executor) Resume(ctx context.Context, execID string, plan *Plan) (*Exec
 		ExecutionID: execID,
 		StepResults: make(map[string]StepResult),
 	}
+	if exec.Inputs != "" {
+		if err := json.Unmarshal([]byte(exec.Inputs), &result.Inputs); err != nil {
+			return nil, fmt.Errorf("decode plan inputs: %w", err)
+		}
+	}
 
-	// Populate results from persisted steps
-	stepMap := make(map[string]persistence.PlanExecutionStep)
+	done := make(map[string]bool)
+	inflight := make(map[string]string) // stepID -> taskID
 	for _, s := range steps {
-		stepMap[s.StepID] = s
-		if s.Status == "succeeded" || s.Status == "failed" {
+		switch s.Status {
+		case "succeeded", "failed", "skipped":
+			done[s.StepID] = true
 			result.StepResults[s.StepID] = StepResult{
+				TaskID:  s.TaskID,
 				Status:  s.Status,
 				Output:  s.Result,
 				Error:   s.Error,
 				CostUSD: s.CostUSD,
 			}
-		}
-	}
-
-	// Toposort to get wave structure
-	order, err := topoSort(plan.Steps)
-	if err != nil {
-		if e.store != nil {
-			_ = e.store.CompletePlanExecution(ctx, execID, "failed", result.TotalCost())
-		}
-		return nil, fmt.Errorf("invalid plan: %w", err)
-	}
-
-	// Get session ID from hydrated execution
-	sessionID := exec.SessionID
-
-	// Resume from last completed wave
-	currentWave := exec.CurrentWave
-	for waveNum := currentWave; waveNum < len(order); waveNum++ {
-		wave := order[waveNum]
-		if len(wave) == 0 {
-			continue
-		}
-
-		// Filter to only pending/running steps (skip completed)
-		var pendingSteps []PlanStep
-		for _, step := range wave {
-			if persisted, ok := stepMap[step.ID]; ok && (persisted.Status == "succeeded" || persisted.Status == "failed") {
-				// Already completed, skip
+		case "running":
+			// Only wait on the old task if it still exists; otherwise run the step again.
+			if s.TaskID == "" {
 				continue
 			}
-			pendingSteps = append(pendingSteps, step)
-		}
-
-		// If no pending steps, this wave already completed
-		if len(pendingSteps) == 0 {
-			continue
-		}
-
-		// Execute pending steps in this wave
-		if err := e.executeWave(ctx, execID, sessionID, pendingSteps, result); err != nil {
-			if e.store != nil {
-				_ = e.store.CompletePlanExecution(ctx, execID, "failed", result.TotalCost())
+			if task, err := e.store.GetTask(ctx, s.TaskID); err == nil && task != nil {
+				inflight[s.StepID] = s.TaskID
 			}
-			return result, fmt.Errorf("wave %d failed: %w", waveNum, err)
 		}
+	}
 
-		// Track completed wave
-		if e.store != nil {
-			_ = e.store.UpdatePlanWave(ctx, execID, waveNum+1)
-		}
+	ctx, release := e.track(ctx, execID, exec.Paused)
+	defer release()
+	if err := e.schedule(ctx, execID, exec.SessionID, plan, result, done, inflight); err != nil {
+		e.complete(ctx, execID, err, result.TotalCost())
+		return result, err
 	}
 
 	// Record final completion
-	if e.store != nil {
-		_ = e.store.CompletePlanExecution(ctx, execID, "succeeded", result.TotalCost())
-	}
+	_ = e.store.CompletePlanExecution(ctx, execID, "succeeded", result.TotalCost())
 
 	return result, nil
 }
 
-// executeWave runs all steps in a wave (parallel independent steps).
-// GC-SPEC-PDR-v4-Phase-2: Persistent step tracking in executeWave.
-func (e *Executor) executeWave(ctx context.Context, execID, sessionID string, steps []PlanStep, result *ExecutionResult) error {
-	// Create all tasks in this wave
-	taskToStep := make(map[string]string) // taskID -> stepID
-	var taskIDs []string
-
-	for _, step := range steps {
-		// Resolve prompt template (substitute references from earlier steps)
-		prompt := resolvePrompt(step.Prompt, result)
-
-		taskID, err := e.taskRouter.CreateChatTask(ctx, step.AgentID, sessionID, prompt)
-		if err != nil {
-			result.StepResults[step.ID] = StepResult{
-				Status: "FAILED",
-				Error:  fmt.Sprintf("failed to create task: %v", err),
-			}
-			return fmt.Errorf("step %s: create task: %w", step.ID, err)
-		}
-
-		taskToStep[taskID] = step.ID
-		taskIDs = append(taskIDs, taskID)
-
-		// Record task mapping (in-progress status)
-		result.StepResults[step.ID] = StepResult{
-			TaskID: taskID,
-			Status: "RUNNING",
-		}
-
-		// GC-SPEC-PDR-v4-Phase-2: Publish step started event
-		if e.store != nil && e.store.Bus() != nil {
-			e.store.Bus().Publish("plan.step.started", map[string]interface{}{
-				"execution_id": execID,
-				"step_id":      step.ID,
-				"task_id":      taskID,
-			})
-		}
-	}
-
-	if e.waiter == nil {
-		// No waiter (test mode) — return with PENDING status
-		return nil
-	}
-
-	// Wait for ALL tasks in this wave to complete
-	taskResults, err := e.waiter.WaitForAll(ctx, taskIDs, 5*time.Minute)
-
-	// Update step results from task results and handle retries
-	stepMap := make(map[string]PlanStep)
-	for _, step := range steps {
-		stepMap[step.ID] = step
-	}
-
-	for taskID, tr := range taskResults {
-		stepID := taskToStep[taskID]
-		step := stepMap[stepID]
-
-		// Initialize max retries (default 2 if not specified)
-		maxRetries := step.MaxRetries
-		if maxRetries == 0 {
-			maxRetries = 2
-		}
-
-		// Check if step failed and should be retried
-		if tr.Status == "FAILED" {
-			currentResult := result.StepResults[stepID]
-			// Count retries from step result (initially 0)
-			retryCount := 0
-			if currentResult.TaskID != "" {
-				// Already has a result, this might be a retry
-				if strings.Contains(currentResult.TaskID, "-retry-") {
-					// Extract retry count from task ID or use error field
-					// For now, just check if we can retry
-				}
-			}
-
-			// If we can retry, create a new task with error context
-			if retryCount < maxRetries {
-				retryCount++
-				newTaskID, retryErr := RetryWithError(ctx, e.taskRouter, sessionID, step, tr.Error, retryCount+1)
-				if retryErr == nil {
-					// Wait for retry task to complete
-					retryResults, waitErr := e.waiter.WaitForAll(ctx, []string{newTaskID}, 5*time.Minute)
-					if waitErr == nil && len(retryResults) > 0 {
-						retryResult := retryResults[newTaskID]
-						// Use retry result if available
-						tr = retryResult
-
-						// Publish retry event
-						if e.store != nil && e.store.Bus() != nil {
-							e.store.Bus().Publish("plan.step.retry", map[string]interface{}{
-								"execution_id": execID,
-								"step_id":      stepID,
-								"attempt":      retryCount + 1,
-								"error":        tr.Error,
-							})
-						}
-					}
-				}
-			}
-		}
-
-		result.StepResults[stepID] = StepResult{
-			TaskID:     tr.TaskID,
-			Status:     tr.Status,
-			Output:     tr.Output,
-			CostUSD:    tr.CostUSD,
-			DurationMs: tr.DurationMs,
-			Error:      tr.Error,
-		}
-
-		// GC-SPEC-PDR-v4-Phase-2: Record step completion for persistence
-		if e.store != nil {
-			recordErr := e.store.RecordStepComplete(ctx, execID, stepID, tr.Status, tr.Output, tr.Error, tr.CostUSD)
-			if recordErr != nil {
-				// Log but don't fail - best-effort persistence
-				// In production, this would log to structured logger
-				_ = recordErr
-			}
-		}
-	}
-
-	if err != nil {
-		return fmt.Errorf("wave execution: %w", err)
-	}
-
-	return nil
-}
-
-// resolvePrompt replaces {step_id.output} references with actual results.
-func resolvePrompt(template string, result *ExecutionResult) string {
-	resolved := template
-	for stepID, sr := range result.StepResults {
-		placeholder := "{" + stepID + ".output}"
-		resolved = strings.ReplaceAll(resolved, placeholder, sr.Output)
-	}
-	return resolved
-}
-
 // executeStepWithApproval implements HITL approval gate for a step.
 // GC-SPEC-PDR-v7-Phase-3: Wait for human approval before continuing step execution.
 // Returns StepResult with approval status, or error if approval fails.
@@ -369,30 +224,38 @@ func (e *Executor) executeStepWithApproval(ctx context.Context, execID, sessionI
 	// Generate request ID for matching response
 	requestID := uuid.New().String()
 
-	// Publish approval request
+	timeout := time.Duration(step.ApprovalTimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = DefaultApprovalTimeout
+	}
+
+	// Publish approval request, subscribing first so a quick answer is not missed
+	var sub *bus.Subscription
 	if e.bus != nil {
+		sub = e.bus.Subscribe(bus.TopicHITLApprovalResponse)
+		defer e.bus.Unsubscribe(sub)
 		e.bus.Publish(bus.TopicHITLApprovalRequested, bus.HITLApprovalRequest{
 			RequestID:   requestID,
 			ExecutionID: execID,
 			StepID:      step.ID,
 			Prompt:      step.Prompt,
-			Timeout:     step.ApprovalTimeoutMs,
+			Timeout:     int(timeout.Milliseconds()),
 		})
 	}
 
 	// Set up timeout context
-	timeout := time.Duration(step.ApprovalTimeoutMs) * time.Millisecond
 	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// Wait for approval response
-	return e.waitForApproval(timeoutCtx, requestID, execID, step.ID)
+	return e.waitForApproval(timeoutCtx, sub, requestID, execID, step.ID)
 }
 
 // waitForApproval blocks until approval/rejection is received or timeout occurs.
 // GC-SPEC-PDR-v7-Phase-3: HITL approval response handler with timeout.
-func (e *Executor) waitForApproval(ctx context.Context, requestID, execID, stepID string) (*StepResult, error) {
-	if e.bus == nil {
+// sub must be subscribed to TopicHITLApprovalResponse before the request is published.
+func (e *Executor) waitForApproval(ctx context.Context, sub *bus.Subscription, requestID, execID, stepID string) (*StepResult, error) {
+	if e.bus == nil || sub == nil {
 		// No bus - cannot wait for approval
 		return &StepResult{
 			Status: "WAITING_APPROVAL",
@@ -400,10 +263,6 @@ func (e *Executor) waitForApproval(ctx context.Context, requestID, execID, stepI
 		}, nil
 	}
 
-	// Subscribe to approval responses
-	sub := e.bus.Subscribe(bus.TopicHITLApprovalResponse)
-	defer e.bus.Unsubscribe(sub)
-
 	// Wait for response or timeout
 	for {
 		select {
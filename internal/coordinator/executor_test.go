@@ -3,6 +3,7 @@ package coordinator
 import (
 	"context"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,10 +13,13 @@ import (
 
 // Mock router for testing.
 type mockRouter struct {
+	mu    sync.Mutex
 	tasks map[string]string // taskID -> content
 }
 
 func (m *mockRouter) CreateChatTask(ctx context.Context, agentID, sessionID, content string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	taskID := "task-" + agentID
 	if m.tasks == nil {
 		m.tasks = make(map[string]string)
@@ -162,7 +166,7 @@ func TestExecute_TestMode(t *testing.T) {
 	}
 
 	exec := NewExecutor(router, nil, nil, nil)
-	result, err := exec.Execute(context.Background(), plan, "test-session", "")
+	result, err := exec.Execute(context.Background(), plan, "test-session", "", nil)
 	if err != nil {
 		t.Fatalf("execute failed: %v", err)
 	}
@@ -213,7 +217,7 @@ func TestExecutor_Events(t *testing.T) {
 		t.Fatalf("ensure session: %v", err)
 	}
 
-	result, err := exec.Execute(context.Background(), plan, sessionID, "")
+	result, err := exec.Execute(context.Background(), plan, sessionID, "", nil)
 	if err != nil {
 		t.Fatalf("execute failed: %v", err)
 	}
@@ -0,0 +1,283 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InputType is the type of a plan input.
+type InputType string
+
+const (
+	InputString  InputType = "string"
+	InputNumber  InputType = "number"
+	InputInteger InputType = "integer"
+	InputBoolean InputType = "boolean"
+)
+
+// PlanInput declares a parameter supplied when a plan is started. Prompts
+// read it as {inputs.<name>}.
+type PlanInput struct {
+	Name        string    `json:"name"`
+	Type        InputType `json:"type"`
+	Required    bool      `json:"required,omitempty"`
+	Default     any       `json:"default,omitempty"`
+	Description string    `json:"description,omitempty"`
+}
+
+// ErrInvalidInputs wraps every error from BindInputs, so callers can report
+// bad arguments separately from failures to run the plan.
+var ErrInvalidInputs = errors.New("invalid plan inputs")
+
+// inputsRef is the reserved prefix of input references in prompts.
+const inputsRef = "inputs"
+
+var inputNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// BindInputs checks supplied values against the plan's declared inputs and
+// returns them converted to their declared types (string, float64, int64 or
+// bool), with defaults filled in. Values may arrive as strings, as typed from
+// a chat command, or as JSON values from the API. Unknown names, missing
+// required inputs and values of the wrong type are errors.
+func (p *Plan) BindInputs(values map[string]any) (map[string]any, error) {
+	declared := make(map[string]PlanInput, len(p.Inputs))
+	for _, in := range p.Inputs {
+		declared[in.Name] = in
+	}
+	var unknown []string
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%w: unknown input %s", ErrInvalidInputs, strings.Join(unknown, ", "))
+	}
+
+	bound := make(map[string]any, len(p.Inputs))
+	for _, in := range p.Inputs {
+		raw, ok := values[in.Name]
+		if !ok || raw == nil {
+			switch {
+			case in.Default != nil:
+				raw = in.Default
+			case in.Required:
+				return nil, fmt.Errorf("%w: missing required input %s", ErrInvalidInputs, in.Name)
+			default:
+				continue
+			}
+		}
+		v, err := coerceInput(in.Type, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: input %s: %v", ErrInvalidInputs, in.Name, err)
+		}
+		bound[in.Name] = v
+	}
+	return bound, nil
+}
+
+// ParseInputArgs parses "key=value" command arguments into input values.
+// Arguments are separated by spaces; double quotes keep spaces in a value, as
+// in topic="release notes". Values stay strings; BindInputs converts them.
+func ParseInputArgs(args string) (map[string]any, error) {
+	values := make(map[string]any)
+	var tokens []string
+	var cur strings.Builder
+	inQuote, inToken := false, false
+	for _, r := range args {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			inToken = true
+		case r == ' ' && !inQuote:
+			if inToken {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+				inToken = false
+			}
+		default:
+			cur.WriteRune(r)
+			inToken = true
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("%w: unterminated quote", ErrInvalidInputs)
+	}
+	if inToken {
+		tokens = append(tokens, cur.String())
+	}
+	for _, tok := range tokens {
+		key, value, ok := strings.Cut(tok, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%w: expected key=value, got %q", ErrInvalidInputs, tok)
+		}
+		if _, dup := values[key]; dup {
+			return nil, fmt.Errorf("%w: input %s given twice", ErrInvalidInputs, key)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+func coerceInput(typ InputType, raw any) (any, error) {
+	switch typ {
+	case InputString:
+		if s, ok := raw.(string); ok {
+			return s, nil
+		}
+	case InputNumber:
+		switch v := raw.(type) {
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err == nil {
+				return f, nil
+			}
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case json.Number:
+			if f, err := v.Float64(); err == nil {
+				return f, nil
+			}
+		}
+	case InputInteger:
+		switch v := raw.(type) {
+		case string:
+			n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err == nil {
+				return n, nil
+			}
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				return int64(v), nil
+			}
+		case int:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				return n, nil
+			}
+		}
+	case InputBoolean:
+		switch v := raw.(type) {
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err == nil {
+				return b, nil
+			}
+		case bool:
+			return v, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+	return nil, fmt.Errorf("%v is not a valid %s", raw, typ)
+}
+
+// validateInputs checks the input declarations of a plan.
+func (p *Plan) validateInputs() error {
+	seen := make(map[string]bool, len(p.Inputs))
+	for _, in := range p.Inputs {
+		if !inputNamePattern.MatchString(in.Name) {
+			return fmt.Errorf("input name %q must be letters, digits and underscores", in.Name)
+		}
+		if seen[in.Name] {
+			return fmt.Errorf("duplicate input: %s", in.Name)
+		}
+		seen[in.Name] = true
+		switch in.Type {
+		case InputString, InputNumber, InputInteger, InputBoolean:
+		default:
+			return fmt.Errorf("input %s: unknown type %q (want string, number, integer or boolean)", in.Name, in.Type)
+		}
+		if in.Default != nil {
+			if _, err := coerceInput(in.Type, in.Default); err != nil {
+				return fmt.Errorf("input %s: default %w", in.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// promptRefPattern matches {inputs.<name>}, {<step>.output} and
+// {<step>.output.<path>} in step prompts.
+var promptRefPattern = regexp.MustCompile(`\{([A-Za-z0-9_-]+)((?:\.[A-Za-z0-9_-]+|\[[0-9]+\])+)\}`)
+
+// promptRef is one reference found in a prompt.
+type promptRef struct {
+	input string     // input name, for {inputs.<name>}
+	cond  *Condition // step output reference otherwise
+}
+
+// parsePromptRef parses the submatches of promptRefPattern. It returns false
+// for braces that are not a reference, which are left alone.
+func parsePromptRef(id, rest string) (promptRef, bool) {
+	if id == inputsRef {
+		name := strings.TrimPrefix(rest, ".")
+		if !inputNamePattern.MatchString(name) {
+			return promptRef{}, false
+		}
+		return promptRef{input: name}, true
+	}
+	if rest != ".output" && !strings.HasPrefix(rest, ".output.") && !strings.HasPrefix(rest, ".output[") {
+		return promptRef{}, false
+	}
+	c := &Condition{}
+	if err := c.parseRef(id + rest); err != nil {
+		return promptRef{}, false
+	}
+	return promptRef{cond: c}, true
+}
+
+// promptRefs lists the references in a prompt template.
+func promptRefs(template string) []promptRef {
+	var refs []promptRef
+	for _, m := range promptRefPattern.FindAllStringSubmatch(template, -1) {
+		if ref, ok := parsePromptRef(m[1], m[2]); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// resolvePrompt substitutes plan inputs and earlier step outputs into a
+// prompt template. {step.output} is the step's raw output; a path reads a
+// field of its JSON output, as in conditions. Strings are inserted as they
+// are, other values as JSON, and missing values as "". References to steps
+// without a result yet are left in place.
+func resolvePrompt(template string, result *ExecutionResult) string {
+	return promptRefPattern.ReplaceAllStringFunc(template, func(match string) string {
+		m := promptRefPattern.FindStringSubmatch(match)
+		ref, ok := parsePromptRef(m[1], m[2])
+		if !ok {
+			return match
+		}
+		if ref.cond == nil {
+			v, ok := result.Inputs[ref.input]
+			if !ok {
+				return ""
+			}
+			return valueString(v)
+		}
+		if _, ok := result.StepResults[ref.cond.StepID]; !ok {
+			return match
+		}
+		v, ok := ref.cond.lookup(result.StepResults)
+		if !ok {
+			return ""
+		}
+		return valueString(v)
+	})
+}
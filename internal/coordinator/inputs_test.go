@@ -0,0 +1,149 @@
+package coordinator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBindInputs(t *testing.T) {
+	plan := &Plan{Inputs: []PlanInput{
+		{Name: "topic", Type: InputString, Required: true},
+		{Name: "limit", Type: InputInteger, Default: 5},
+		{Name: "threshold", Type: InputNumber},
+		{Name: "draft", Type: InputBoolean, Default: false},
+	}}
+	tests := []struct {
+		name    string
+		values  map[string]any
+		want    map[string]any
+		wantErr bool
+	}{
+		{"strings from a command", map[string]any{"topic": "go", "limit": "10", "threshold": "0.5", "draft": "true"},
+			map[string]any{"topic": "go", "limit": int64(10), "threshold": 0.5, "draft": true}, false},
+		{"JSON values", map[string]any{"topic": "go", "limit": float64(3), "draft": true},
+			map[string]any{"topic": "go", "limit": int64(3), "draft": true}, false},
+		{"defaults", map[string]any{"topic": "go"},
+			map[string]any{"topic": "go", "limit": int64(5), "draft": false}, false},
+		{"missing required", map[string]any{"limit": "1"}, nil, true},
+		{"unknown input", map[string]any{"topic": "go", "colour": "red"}, nil, true},
+		{"not an integer", map[string]any{"topic": "go", "limit": "many"}, nil, true},
+		{"fractional integer", map[string]any{"topic": "go", "limit": 1.5}, nil, true},
+		{"number for a string", map[string]any{"topic": float64(1)}, nil, true},
+		{"not a boolean", map[string]any{"topic": "go", "draft": "maybe"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := plan.BindInputs(tt.values)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidInputs) {
+					t.Fatalf("err = %v, want ErrInvalidInputs", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BindInputs: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BindInputs = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInputArgs(t *testing.T) {
+	got, err := ParseInputArgs(`topic="release notes" limit=3  empty=`)
+	if err != nil {
+		t.Fatalf("ParseInputArgs: %v", err)
+	}
+	want := map[string]any{"topic": "release notes", "limit": "3", "empty": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseInputArgs = %#v, want %#v", got, want)
+	}
+	if got, err := ParseInputArgs("  "); err != nil || len(got) != 0 {
+		t.Errorf("blank args = %v, %v; want no inputs", got, err)
+	}
+	for _, bad := range []string{"topic", "=x", "a=1 a=2", `topic="open`} {
+		if _, err := ParseInputArgs(bad); !errors.Is(err, ErrInvalidInputs) {
+			t.Errorf("ParseInputArgs(%q) err = %v, want ErrInvalidInputs", bad, err)
+		}
+	}
+}
+
+func TestResolvePrompt_InputsAndPaths(t *testing.T) {
+	result := &ExecutionResult{
+		Inputs: map[string]any{"topic": "sqlite", "limit": int64(3)},
+		StepResults: map[string]StepResult{
+			"research": {Status: "SUCCEEDED", Output: `{"urls": ["https://a", "https://b"], "meta": {"count": 2}}`},
+			"notes":    {Status: "SUCCEEDED", Output: "plain text"},
+		},
+	}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"About {inputs.topic}, top {inputs.limit}", "About sqlite, top 3"},
+		{"Unset: [{inputs.other}]", "Unset: []"},
+		{"Raw: {notes.output}", "Raw: plain text"},
+		{"First: {research.output.urls[0]}", "First: https://a"},
+		{"All: {research.output.urls}", `All: ["https://a","https://b"]`},
+		{"Meta: {research.output.meta}", `Meta: {"count":2}`},
+		{"Missing: [{research.output.urls[5]}]", "Missing: []"},
+		{"Pending: {later.output}", "Pending: {later.output}"},
+		{`JSON example: {"a": 1} and {research.result}`, `JSON example: {"a": 1} and {research.result}`},
+	}
+	for _, tt := range tests {
+		if got := resolvePrompt(tt.template, result); got != tt.want {
+			t.Errorf("resolvePrompt(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestValidate_InputsAndReferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    Plan
+		wantErr bool
+	}{
+		{"declared input and ancestor output", Plan{
+			Inputs: []PlanInput{{Name: "topic", Type: InputString}},
+			Steps: []PlanStep{
+				{ID: "a", AgentID: "x", Prompt: "{inputs.topic}", OutputSchema: []byte(`{"type":"object"}`)},
+				{ID: "b", AgentID: "x", Prompt: "{a.output.items[0].name}", DependsOn: []string{"a"}},
+			},
+		}, false},
+		{"undeclared input", Plan{Steps: []PlanStep{
+			{ID: "a", AgentID: "x", Prompt: "{inputs.topic}"},
+		}}, true},
+		{"output of unrelated step", Plan{Steps: []PlanStep{
+			{ID: "a", AgentID: "x", Prompt: "1"},
+			{ID: "b", AgentID: "x", Prompt: "{a.output}"},
+		}}, true},
+		{"reserved step ID", Plan{Steps: []PlanStep{
+			{ID: "inputs", AgentID: "x", Prompt: "1"},
+		}}, true},
+		{"unknown input type", Plan{
+			Inputs: []PlanInput{{Name: "n", Type: "date"}},
+			Steps:  []PlanStep{{ID: "a", AgentID: "x", Prompt: "1"}},
+		}, true},
+		{"bad default", Plan{
+			Inputs: []PlanInput{{Name: "n", Type: InputInteger, Default: "lots"}},
+			Steps:  []PlanStep{{ID: "a", AgentID: "x", Prompt: "1"}},
+		}, true},
+		{"bad input name", Plan{
+			Inputs: []PlanInput{{Name: "has space", Type: InputString}},
+			Steps:  []PlanStep{{ID: "a", AgentID: "x", Prompt: "1"}},
+		}, true},
+		{"schema is not an object", Plan{Steps: []PlanStep{
+			{ID: "a", AgentID: "x", Prompt: "1", OutputSchema: []byte(`["type"]`)},
+		}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.plan.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package coordinator
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/basket/go-claw/internal/config"
+	"gopkg.in/yaml.v3"
 )
 
 // LoadPlansFromConfig converts config plan definitions into validated Plan objects.
@@ -24,8 +28,24 @@ func LoadPlansFromConfig(configs []config.PlanConfig, knownAgents []string) (map
 		}
 
 		plan := Plan{
-			Name:  pc.Name,
-			Steps: make([]PlanStep, len(pc.Steps)),
+			Name:                   pc.Name,
+			Steps:                  make([]PlanStep, len(pc.Steps)),
+			MaxConcurrency:         pc.MaxConcurrency,
+			MaxConcurrencyPerAgent: pc.MaxConcurrencyPerAgent,
+		}
+
+		for _, ic := range pc.Inputs {
+			typ := InputType(ic.Type)
+			if typ == "" {
+				typ = InputString
+			}
+			plan.Inputs = append(plan.Inputs, PlanInput{
+				Name:        ic.Name,
+				Type:        typ,
+				Required:    ic.Required,
+				Default:     ic.Default,
+				Description: ic.Description,
+			})
 		}
 
 		for i, sc := range pc.Steps {
@@ -34,11 +54,46 @@ func LoadPlansFromConfig(configs []config.PlanConfig, knownAgents []string) (map
 				return nil, fmt.Errorf("plan %s step %s: unknown agent %s", pc.Name, sc.ID, sc.AgentID)
 			}
 
+			if sc.TimeoutSeconds < 0 || sc.ApprovalTimeoutSeconds < 0 {
+				return nil, fmt.Errorf("plan %s step %s: timeouts must not be negative", pc.Name, sc.ID)
+			}
+			if sc.ApprovalTimeoutSeconds > 0 && !sc.RequireApproval {
+				return nil, fmt.Errorf("plan %s step %s: approval_timeout_seconds requires require_approval", pc.Name, sc.ID)
+			}
+
+			// PlanStep treats 0 as "default retries", so an explicit 0 becomes -1.
+			maxRetries := 0
+			if sc.MaxRetries != nil {
+				if *sc.MaxRetries < 0 {
+					return nil, fmt.Errorf("plan %s step %s: max_retries must not be negative", pc.Name, sc.ID)
+				}
+				maxRetries = *sc.MaxRetries
+				if maxRetries == 0 {
+					maxRetries = -1
+				}
+			}
+
+			var schema json.RawMessage
+			if sc.OutputSchema != nil {
+				raw, err := json.Marshal(sc.OutputSchema)
+				if err != nil {
+					return nil, fmt.Errorf("plan %s step %s: output_schema: %w", pc.Name, sc.ID, err)
+				}
+				schema = raw
+			}
+
 			plan.Steps[i] = PlanStep{
-				ID:        sc.ID,
-				AgentID:   sc.AgentID,
-				Prompt:    sc.Prompt,
-				DependsOn: sc.DependsOn,
+				ID:                sc.ID,
+				AgentID:           sc.AgentID,
+				Prompt:            sc.Prompt,
+				DependsOn:         sc.DependsOn,
+				Timeout:           time.Duration(sc.TimeoutSeconds) * time.Second,
+				MaxRetries:        maxRetries,
+				RequireApproval:   sc.RequireApproval,
+				ApprovalTimeoutMs: sc.ApprovalTimeoutSeconds * 1000,
+				OnFailure:         FailurePolicy(sc.OnFailure),
+				When:              sc.When,
+				OutputSchema:      schema,
 			}
 		}
 
@@ -51,3 +106,29 @@ func LoadPlansFromConfig(configs []config.PlanConfig, knownAgents []string) (map
 
 	return plans, nil
 }
+
+// DecodePlanDefinition parses one plan written in the YAML form of a plan in
+// config.yaml, as generated plans are saved. Unknown keys are errors, so
+// typos in an edited plan are not silently dropped.
+func DecodePlanDefinition(definition string) (config.PlanConfig, error) {
+	var pc config.PlanConfig
+	dec := yaml.NewDecoder(bytes.NewReader([]byte(definition)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&pc); err != nil {
+		return config.PlanConfig{}, fmt.Errorf("decode plan definition: %w", err)
+	}
+	return pc, nil
+}
+
+// ParsePlanDefinition decodes and validates one plan definition.
+func ParsePlanDefinition(definition string, knownAgents []string) (Plan, error) {
+	pc, err := DecodePlanDefinition(definition)
+	if err != nil {
+		return Plan{}, err
+	}
+	plans, err := LoadPlansFromConfig([]config.PlanConfig{pc}, knownAgents)
+	if err != nil {
+		return Plan{}, err
+	}
+	return plans[pc.Name], nil
+}
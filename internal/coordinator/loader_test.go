@@ -2,6 +2,7 @@ package coordinator
 
 import (
 	"testing"
+	"time"
 
 	"github.com/basket/go-claw/internal/config"
 )
@@ -73,3 +74,149 @@ func TestLoadPlansFromConfig_Cycle(t *testing.T) {
 		t.Fatal("expected error for cycle")
 	}
 }
+
+func TestLoadPlansFromConfig_SchedulingLimits(t *testing.T) {
+	configs := []config.PlanConfig{
+		{
+			Name:                   "limited",
+			MaxConcurrency:         3,
+			MaxConcurrencyPerAgent: 1,
+			Steps: []config.PlanStepConfig{
+				{ID: "build", AgentID: "coder", Prompt: "build", TimeoutSeconds: 90},
+			},
+		},
+	}
+	plans, err := LoadPlansFromConfig(configs, []string{"coder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := plans["limited"]
+	if p.MaxConcurrency != 3 || p.MaxConcurrencyPerAgent != 1 {
+		t.Errorf("limits = %d/%d, want 3/1", p.MaxConcurrency, p.MaxConcurrencyPerAgent)
+	}
+	if p.Steps[0].Timeout != 90*time.Second {
+		t.Errorf("step timeout = %v, want 90s", p.Steps[0].Timeout)
+	}
+
+	configs[0].Steps[0].TimeoutSeconds = -1
+	if _, err := LoadPlansFromConfig(configs, []string{"coder"}); err == nil {
+		t.Error("expected error for negative timeout")
+	}
+}
+
+func TestLoadPlansFromConfig_StepOptions(t *testing.T) {
+	zero, three := 0, 3
+	configs := []config.PlanConfig{
+		{
+			Name: "release",
+			Steps: []config.PlanStepConfig{
+				{ID: "test", AgentID: "coder", Prompt: "run tests", MaxRetries: &three, OnFailure: "skip_dependents"},
+				{ID: "deploy", AgentID: "coder", Prompt: "deploy", DependsOn: []string{"test"},
+					MaxRetries: &zero, RequireApproval: true, ApprovalTimeoutSeconds: 300,
+					When: "test.status == succeeded", OnFailure: "continue"},
+				{ID: "announce", AgentID: "coder", Prompt: "announce", DependsOn: []string{"deploy"}},
+			},
+		},
+	}
+	plans, err := LoadPlansFromConfig(configs, []string{"coder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := plans["release"].Steps
+	if steps[0].MaxRetries != 3 || steps[0].OnFailure != SkipDependents {
+		t.Errorf("test step = %+v", steps[0])
+	}
+	d := steps[1]
+	if d.MaxRetries != -1 || !d.RequireApproval || d.ApprovalTimeoutMs != 300000 || d.When != "test.status == succeeded" || d.OnFailure != ContinueOnFailure {
+		t.Errorf("deploy step = %+v", d)
+	}
+	if steps[2].MaxRetries != 0 || steps[2].OnFailure != "" {
+		t.Errorf("announce step should keep defaults, got %+v", steps[2])
+	}
+
+	invalid := []config.PlanStepConfig{
+		{ID: "a", AgentID: "coder", Prompt: "x", OnFailure: "explode"},
+		{ID: "a", AgentID: "coder", Prompt: "x", When: "b.status == succeeded"},
+		{ID: "a", AgentID: "coder", Prompt: "x", ApprovalTimeoutSeconds: 30},
+		{ID: "a", AgentID: "coder", Prompt: "x", MaxRetries: func() *int { n := -1; return &n }()},
+	}
+	for _, sc := range invalid {
+		if _, err := LoadPlansFromConfig([]config.PlanConfig{{Name: "bad", Steps: []config.PlanStepConfig{sc}}}, []string{"coder"}); err == nil {
+			t.Errorf("expected error for step %+v", sc)
+		}
+	}
+}
+
+func TestLoadPlansFromConfig_InputsAndOutputSchema(t *testing.T) {
+	configs := []config.PlanConfig{{
+		Name: "research",
+		Inputs: []config.PlanInputConfig{
+			{Name: "topic", Required: true, Description: "What to research"},
+			{Name: "limit", Type: "integer", Default: 5},
+		},
+		Steps: []config.PlanStepConfig{
+			{ID: "find", AgentID: "coder", Prompt: "Find {inputs.limit} sources on {inputs.topic}",
+				OutputSchema: map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"urls": map[string]any{"type": "array"}},
+				}},
+			{ID: "write", AgentID: "coder", Prompt: "Read {find.output.urls[0]}", DependsOn: []string{"find"}},
+		},
+	}}
+	plans, err := LoadPlansFromConfig(configs, []string{"coder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := plans["research"]
+	if len(p.Inputs) != 2 || p.Inputs[0].Type != InputString || !p.Inputs[0].Required || p.Inputs[1].Type != InputInteger {
+		t.Errorf("inputs = %+v", p.Inputs)
+	}
+	if got := string(p.Steps[0].OutputSchema); got != `{"properties":{"urls":{"type":"array"}},"type":"object"}` {
+		t.Errorf("output schema = %s", got)
+	}
+
+	configs[0].Inputs = nil
+	if _, err := LoadPlansFromConfig(configs, []string{"coder"}); err == nil {
+		t.Error("expected error for a prompt reading undeclared inputs")
+	}
+}
+
+func TestParsePlanDefinition(t *testing.T) {
+	definition := `name: digest
+inputs:
+  - name: topic
+    required: true
+steps:
+  - id: research
+    agent_id: researcher
+    prompt: "Research {inputs.topic}"
+  - id: write
+    agent_id: writer
+    prompt: "{research.output}"
+    depends_on: [research]
+`
+	plan, err := ParsePlanDefinition(definition, []string{"researcher", "writer"})
+	if err != nil {
+		t.Fatalf("ParsePlanDefinition: %v", err)
+	}
+	if plan.Name != "digest" || len(plan.Steps) != 2 || len(plan.Inputs) != 1 || plan.Inputs[0].Type != InputString {
+		t.Fatalf("plan = %+v", plan)
+	}
+
+	tests := []struct {
+		name       string
+		definition string
+	}{
+		{"unknown agent", "name: p\nsteps:\n  - id: a\n    agent_id: ghost\n    prompt: x\n"},
+		{"unknown key", "name: p\nsteps:\n  - id: a\n    agent_id: writer\n    prompt: x\n    depend_on: [b]\n"},
+		{"not YAML", "name: [p\n"},
+		{"no name", "steps:\n  - id: a\n    agent_id: writer\n    prompt: x\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePlanDefinition(tt.definition, []string{"researcher", "writer"}); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
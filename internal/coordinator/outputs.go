@@ -0,0 +1,64 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// OutputValidator checks a step's output against the step's OutputSchema and
+// returns the JSON document found in it. main wires one backed by
+// engine.StructuredValidator; engine imports this package, so the executor
+// cannot call it directly.
+type OutputValidator func(schema json.RawMessage, output string) (string, error)
+
+// SetOutputValidator sets the validator for steps with an OutputSchema.
+// Without one, their output only has to be JSON.
+func (e *Executor) SetOutputValidator(v OutputValidator) {
+	e.validateOutput = v
+}
+
+// checkOutput validates a successful step's output against its schema and
+// returns the output to keep: the bare JSON document on success.
+func (e *Executor) checkOutput(step PlanStep, output string) (string, error) {
+	if len(step.OutputSchema) == 0 {
+		return output, nil
+	}
+	if e.validateOutput != nil {
+		doc, err := e.validateOutput(step.OutputSchema, output)
+		if err != nil {
+			return "", fmt.Errorf("output does not match the step's schema: %w", err)
+		}
+		return doc, nil
+	}
+	doc := jsonPayload(output)
+	if !json.Valid([]byte(doc)) {
+		return "", fmt.Errorf("output is not a JSON document")
+	}
+	return doc, nil
+}
+
+// withOutputSchema asks for output matching the step's schema, if it has one.
+func withOutputSchema(prompt string, schema json.RawMessage) string {
+	if len(schema) == 0 {
+		return prompt
+	}
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	sb.WriteString("\n\nRespond with a single JSON document that matches this JSON Schema, and nothing else:\n")
+	sb.Write(schema)
+	return sb.String()
+}
+
+// validateOutputSchema checks that a step's schema is a JSON object.
+// Compiling it is left to the OutputValidator's owner.
+func validateOutputSchema(schema json.RawMessage) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(schema, &obj); err != nil {
+		return fmt.Errorf("output schema must be a JSON object: %w", err)
+	}
+	return nil
+}
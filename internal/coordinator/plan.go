@@ -1,7 +1,9 @@
 package coordinator
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // Plan is a DAG of steps to be executed in dependency order.
@@ -9,6 +11,12 @@ import (
 type Plan struct {
 	Name  string
 	Steps []PlanStep
+	// Inputs are the parameters the plan is started with (see BindInputs).
+	Inputs []PlanInput
+	// MaxConcurrency caps the steps of one execution running at once (0 = unlimited).
+	MaxConcurrency int
+	// MaxConcurrencyPerAgent caps running steps per agent within one execution (0 = unlimited).
+	MaxConcurrencyPerAgent int
 }
 
 // PlanStep is a single step in a DAG plan.
@@ -16,12 +24,35 @@ type PlanStep struct {
 	ID                string
 	AgentID           string
 	Prompt            string
-	DependsOn         []string // Step IDs that must complete before this step
-	MaxRetries        int      // Max retry count on failure (default: 2)
-	RequireApproval   bool     // GC-SPEC-PDR-v7-Phase-3: HITL gate flag
-	ApprovalTimeoutMs int      // GC-SPEC-PDR-v7-Phase-3: HITL approval timeout in milliseconds
+	DependsOn         []string      // Step IDs that must complete before this step
+	MaxRetries        int           // Max retry count on failure (default: 2, negative: none)
+	RequireApproval   bool          // GC-SPEC-PDR-v7-Phase-3: HITL gate flag
+	ApprovalTimeoutMs int           // GC-SPEC-PDR-v7-Phase-3: HITL approval timeout in milliseconds (default: DefaultApprovalTimeout)
+	Timeout           time.Duration // Bound on one attempt (default: DefaultStepTimeout)
+	OnFailure         FailurePolicy // What a failure does to the rest of the plan (default: FailPlan)
+	When              string        // Condition on earlier results; the step is skipped when false (see Condition)
+	// OutputSchema is a JSON Schema the step's output must match. The output
+	// is then stored as the bare JSON document, so later steps can read its
+	// fields as {step.output.field}.
+	OutputSchema json.RawMessage
 }
 
+// FailurePolicy decides what happens to the rest of a plan when a step fails
+// after its retries.
+type FailurePolicy string
+
+const (
+	// FailPlan stops dispatching new steps and fails the plan.
+	FailPlan FailurePolicy = "fail_plan"
+	// ContinueOnFailure runs dependents anyway, with the failed step's output empty.
+	ContinueOnFailure FailurePolicy = "continue"
+	// SkipDependents skips everything downstream of the step; other branches go on.
+	SkipDependents FailurePolicy = "skip_dependents"
+)
+
+// maxStepRetries bounds PlanStep.MaxRetries.
+const maxStepRetries = 10
+
 // StepResult is the outcome of a single step.
 type StepResult struct {
 	TaskID     string
@@ -35,6 +66,7 @@ type StepResult struct {
 // ExecutionResult is the overall result of a plan execution.
 type ExecutionResult struct {
 	ExecutionID string
+	Inputs      map[string]any // bound plan inputs
 	StepResults map[string]StepResult
 }
 
@@ -62,6 +94,9 @@ func (p *Plan) Validate() error {
 		if seen[s.ID] {
 			return fmt.Errorf("duplicate step ID: %s", s.ID)
 		}
+		if s.ID == inputsRef {
+			return fmt.Errorf("step ID %q is reserved for plan inputs", s.ID)
+		}
 		seen[s.ID] = true
 	}
 
@@ -74,7 +109,83 @@ func (p *Plan) Validate() error {
 		}
 	}
 
+	if err := p.validateInputs(); err != nil {
+		return err
+	}
+
+	if p.MaxConcurrency < 0 || p.MaxConcurrencyPerAgent < 0 {
+		return fmt.Errorf("concurrency limits must not be negative")
+	}
+
+	for _, s := range p.Steps {
+		if s.MaxRetries > maxStepRetries {
+			return fmt.Errorf("step %s: max retries %d exceeds %d", s.ID, s.MaxRetries, maxStepRetries)
+		}
+		if s.Timeout < 0 || s.ApprovalTimeoutMs < 0 {
+			return fmt.Errorf("step %s: timeouts must not be negative", s.ID)
+		}
+		switch s.OnFailure {
+		case "", FailPlan, ContinueOnFailure, SkipDependents:
+		default:
+			return fmt.Errorf("step %s: unknown on_failure %q (want %s, %s or %s)", s.ID, s.OnFailure, FailPlan, ContinueOnFailure, SkipDependents)
+		}
+		if err := validateOutputSchema(s.OutputSchema); err != nil {
+			return fmt.Errorf("step %s: %w", s.ID, err)
+		}
+	}
+
 	// Check for cycles via topological sort (implemented in executor.go)
-	_, err := topoSort(p.Steps)
-	return err
+	if _, err := topoSort(p.Steps); err != nil {
+		return err
+	}
+
+	// Conditions and prompts may only read steps that are guaranteed to have
+	// finished, and inputs the plan declares.
+	deps := make(map[string][]string, len(p.Steps))
+	for _, s := range p.Steps {
+		deps[s.ID] = s.DependsOn
+	}
+	inputs := make(map[string]bool, len(p.Inputs))
+	for _, in := range p.Inputs {
+		inputs[in.Name] = true
+	}
+	for _, s := range p.Steps {
+		for _, ref := range promptRefs(s.Prompt) {
+			switch {
+			case ref.cond == nil && !inputs[ref.input]:
+				return fmt.Errorf("step %s: prompt reads undeclared input %s", s.ID, ref.input)
+			case ref.cond != nil && !dependsOn(deps, s.ID, ref.cond.StepID):
+				return fmt.Errorf("step %s: prompt reads step %s, which is not among its dependencies", s.ID, ref.cond.StepID)
+			}
+		}
+		if s.When == "" {
+			continue
+		}
+		cond, err := ParseCondition(s.When)
+		if err != nil {
+			return fmt.Errorf("step %s: %w", s.ID, err)
+		}
+		if !dependsOn(deps, s.ID, cond.StepID) {
+			return fmt.Errorf("step %s: condition reads step %s, which is not among its dependencies", s.ID, cond.StepID)
+		}
+	}
+	return nil
+}
+
+// dependsOn reports whether step transitively depends on target.
+func dependsOn(deps map[string][]string, step, target string) bool {
+	seen := make(map[string]bool)
+	stack := append([]string(nil), deps[step]...)
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == target {
+			return true
+		}
+		if !seen[id] {
+			seen[id] = true
+			stack = append(stack, deps[id]...)
+		}
+	}
+	return false
 }
@@ -0,0 +1,395 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+// DefaultStepTimeout bounds one attempt of a step that sets no Timeout.
+const DefaultStepTimeout = 5 * time.Minute
+
+// DefaultApprovalTimeout bounds the wait for a human decision on a step that
+// sets no ApprovalTimeoutMs. Unanswered requests are rejected.
+const DefaultApprovalTimeout = 60 * time.Second
+
+// defaultMaxRetries applies to steps that leave MaxRetries at zero.
+const defaultMaxRetries = 2
+
+// StatusSkipped is the StepResult status of a step whose condition was false
+// or whose dependency failed under SkipDependents.
+const StatusSkipped = "SKIPPED"
+
+// stepOutcome is reported by a step's goroutine when it finishes.
+type stepOutcome struct {
+	stepID  string
+	agentID string
+	result  StepResult
+	err     error
+}
+
+// stepState maps a task or step status to the persisted step state:
+// succeeded, failed, skipped, or running while the step is unfinished.
+func stepState(status string) string {
+	switch strings.ToUpper(status) {
+	case string(persistence.TaskStatusSucceeded):
+		return "succeeded"
+	case string(persistence.TaskStatusFailed), string(persistence.TaskStatusCanceled), string(persistence.TaskStatusDeadLetter):
+		return "failed"
+	case StatusSkipped:
+		return "skipped"
+	}
+	return "running"
+}
+
+// schedule runs every step of plan not in done, dispatching each one as soon
+// as its own dependencies have finished. Running steps are bounded by the
+// plan's MaxConcurrency and MaxConcurrencyPerAgent. Steps listed in inflight
+// wait for their existing task instead of creating a new one.
+//
+// A step whose When condition is false is skipped. A failed step is handled
+// by its OnFailure policy; under FailPlan, and after an executor error such
+// as a task that could not be created, nothing new is dispatched, running
+// steps are waited for, and the error is returned.
+//
+// While the execution is paused nothing new is dispatched either. Once it is
+// canceled, running steps abort their tasks and ErrExecutionCanceled is
+// returned.
+func (e *Executor) schedule(ctx context.Context, execID, sessionID string, plan *Plan, result *ExecutionResult,
+	done map[string]bool, inflight map[string]string) error {
+	steps := make(map[string]PlanStep, len(plan.Steps))
+	blockers := make(map[string]int) // stepID -> unfinished dependencies
+	dependents := make(map[string][]string)
+	for _, step := range plan.Steps {
+		steps[step.ID] = step
+		blockers[step.ID] = len(step.DependsOn)
+		for _, dep := range step.DependsOn {
+			dependents[dep] = append(dependents[dep], step.ID)
+		}
+	}
+
+	settled := make(map[string]bool)
+	var ready []PlanStep
+	var firstErr error
+
+	var skip func(stepID, reason string, cascade bool)
+	// release applies a finished step's outcome to its dependents.
+	release := func(stepID string) {
+		settled[stepID] = true
+		step := steps[stepID]
+		if sr := result.StepResults[stepID]; stepState(sr.Status) == "failed" {
+			switch step.OnFailure {
+			case ContinueOnFailure:
+			case SkipDependents:
+				for _, next := range dependents[stepID] {
+					skip(next, fmt.Sprintf("dependency %s failed", stepID), true)
+				}
+				return
+			default:
+				if firstErr == nil {
+					firstErr = fmt.Errorf("step %s failed: %s", stepID, sr.Error)
+				}
+				return
+			}
+		}
+		for _, next := range dependents[stepID] {
+			blockers[next]--
+			if blockers[next] == 0 && !settled[next] {
+				ready = append(ready, steps[next])
+			}
+		}
+	}
+	// skip settles a step without running it. Skipping is not a failure, so
+	// dependents of a step skipped by its condition still run; with cascade
+	// (a dependency failed under SkipDependents) they are skipped as well.
+	skip = func(stepID, reason string, cascade bool) {
+		if settled[stepID] {
+			return
+		}
+		result.StepResults[stepID] = StepResult{Status: StatusSkipped, Error: reason}
+		e.recordStep(ctx, execID, stepID, "skipped", "", reason, 0)
+		if !cascade {
+			release(stepID)
+			return
+		}
+		settled[stepID] = true
+		for _, next := range dependents[stepID] {
+			skip(next, reason, true)
+		}
+	}
+
+	// Replay persisted outcomes so a resumed plan sees the same policies.
+	// Releasing them readies their dependents; roots are readied here.
+	for _, step := range plan.Steps {
+		if done[step.ID] {
+			release(step.ID)
+		}
+	}
+	for _, step := range plan.Steps {
+		if !settled[step.ID] && len(step.DependsOn) == 0 {
+			ready = append(ready, step)
+		}
+	}
+
+	outcomes := make(chan stepOutcome)
+	running := 0
+	perAgent := make(map[string]int)
+	ctl := e.control(execID)
+
+	for {
+		paused, changed := ctl.state()
+		// Dispatch ready steps in plan order, skipping agents at their limit.
+		for i := 0; firstErr == nil && !paused && ctx.Err() == nil && i < len(ready); {
+			step := ready[i]
+			if settled[step.ID] {
+				ready = append(ready[:i], ready[i+1:]...)
+				continue
+			}
+			if step.When != "" && inflight[step.ID] == "" {
+				cond, err := ParseCondition(step.When)
+				if err != nil || !cond.Eval(result.StepResults) {
+					ready = append(ready[:i], ready[i+1:]...)
+					skip(step.ID, fmt.Sprintf("condition not met: %s", step.When), false)
+					i = 0 // skipping may have released earlier steps
+					continue
+				}
+			}
+			if plan.MaxConcurrency > 0 && running >= plan.MaxConcurrency {
+				break
+			}
+			if plan.MaxConcurrencyPerAgent > 0 && perAgent[step.AgentID] >= plan.MaxConcurrencyPerAgent {
+				i++
+				continue
+			}
+			ready = append(ready[:i], ready[i+1:]...)
+
+			// Resolve prompt template (substitute inputs and earlier outputs)
+			prompt := withOutputSchema(resolvePrompt(step.Prompt, result), step.OutputSchema)
+			taskID := inflight[step.ID]
+			result.StepResults[step.ID] = StepResult{TaskID: taskID, Status: "RUNNING"}
+			running++
+			perAgent[step.AgentID]++
+			go func() {
+				outcomes <- e.runStep(ctx, execID, sessionID, step, prompt, taskID)
+			}()
+		}
+
+		if running == 0 {
+			if paused && firstErr == nil && len(ready) > 0 && ctx.Err() == nil {
+				select {
+				case <-changed:
+				case <-ctx.Done():
+				}
+				continue
+			}
+			if errors.Is(context.Cause(ctx), ErrExecutionCanceled) {
+				return ErrExecutionCanceled
+			}
+			if firstErr == nil && len(ready) > 0 {
+				return ctx.Err() // shut down before every step was dispatched
+			}
+			return firstErr
+		}
+
+		var out stepOutcome
+		select {
+		case out = <-outcomes:
+		case <-changed:
+			continue // paused or unpaused
+		}
+		running--
+		perAgent[out.agentID]--
+		result.StepResults[out.stepID] = out.result
+		if out.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("step %s: %w", out.stepID, out.err)
+			}
+			continue
+		}
+		release(out.stepID)
+	}
+}
+
+// runStep takes one step from dispatch to its final result: the approval
+// gate, task creation (unless taskID was left by an earlier run), and
+// awaitStep. It runs on its own goroutine and must not touch the shared
+// ExecutionResult.
+func (e *Executor) runStep(ctx context.Context, execID, sessionID string, step PlanStep, prompt, taskID string) stepOutcome {
+	out := stepOutcome{stepID: step.ID, agentID: step.AgentID}
+	step.Prompt = prompt // retries repeat the resolved prompt
+
+	if taskID == "" {
+		if step.RequireApproval {
+			approval, err := e.executeStepWithApproval(ctx, execID, sessionID, step)
+			if approval == nil || approval.Status != "APPROVED" {
+				reason := "approval not granted"
+				if approval != nil && approval.Error != "" {
+					reason = approval.Error
+				} else if err != nil {
+					reason = err.Error()
+				}
+				out.result = StepResult{Status: string(persistence.TaskStatusFailed), Error: reason}
+				e.recordStep(ctx, execID, step.ID, "failed", "", reason, 0)
+				return out
+			}
+		}
+
+		var err error
+		taskID, err = e.taskRouter.CreateChatTask(ctx, step.AgentID, sessionID, prompt)
+		if err != nil {
+			out.result = StepResult{
+				Status: string(persistence.TaskStatusFailed),
+				Error:  fmt.Sprintf("failed to create task: %v", err),
+			}
+			out.err = fmt.Errorf("create task: %w", err)
+			return out
+		}
+	}
+
+	e.markStepRunning(ctx, execID, step.ID, taskID)
+
+	// GC-SPEC-PDR-v4-Phase-2: Publish step started event
+	if e.store != nil && e.store.Bus() != nil {
+		e.store.Bus().Publish("plan.step.started", map[string]interface{}{
+			"execution_id": execID,
+			"step_id":      step.ID,
+			"task_id":      taskID,
+		})
+	}
+
+	if e.waiter == nil {
+		// No waiter (test mode) — leave the step RUNNING
+		out.result = StepResult{TaskID: taskID, Status: "RUNNING"}
+		return out
+	}
+	return e.awaitStep(ctx, execID, sessionID, step, taskID)
+}
+
+// awaitStep waits for a step's task and persists the outcome. A failed or
+// timed-out attempt, or output that does not match the step's OutputSchema,
+// is retried with the error as context, up to the step's MaxRetries; a
+// timed-out task is aborted first.
+func (e *Executor) awaitStep(ctx context.Context, execID, sessionID string, step PlanStep, taskID string) stepOutcome {
+	out := stepOutcome{stepID: step.ID, agentID: step.AgentID}
+
+	maxRetries := step.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	}
+	timeout := step.Timeout
+	if timeout <= 0 {
+		timeout = DefaultStepTimeout
+	}
+
+	var tr *TaskResult
+	for attempt := 1; ; attempt++ {
+		var err error
+		tr, err = e.waiter.WaitForTask(ctx, taskID, timeout)
+		if err != nil {
+			if errors.Is(context.Cause(ctx), ErrExecutionCanceled) {
+				ctx = context.WithoutCancel(ctx)
+				e.abortTask(ctx, taskID)
+				out.result = StepResult{TaskID: taskID, Status: string(persistence.TaskStatusCanceled), Error: "plan canceled"}
+				e.recordStep(ctx, execID, step.ID, "failed", "", "plan canceled", 0)
+				return out
+			}
+			if ctx.Err() != nil {
+				// Shutting down: the step stays running so Resume can pick it up.
+				out.result = StepResult{TaskID: taskID, Status: "RUNNING"}
+				out.err = err
+				return out
+			}
+			e.abortTask(ctx, taskID)
+			tr = &TaskResult{
+				TaskID: taskID,
+				Status: string(persistence.TaskStatusFailed),
+				Error:  fmt.Sprintf("step did not finish within %s: %v", timeout, err),
+			}
+		}
+		if tr.Status == string(persistence.TaskStatusSucceeded) {
+			output, err := e.checkOutput(step, tr.Output)
+			if err != nil {
+				tr.Status = string(persistence.TaskStatusFailed)
+				tr.Error = err.Error()
+			} else {
+				tr.Output = output
+			}
+		}
+		if tr.Status != string(persistence.TaskStatusFailed) || attempt > maxRetries {
+			break
+		}
+
+		newTaskID, err := RetryWithError(ctx, e.taskRouter, sessionID, step, tr.Error, attempt+1)
+		if err != nil {
+			slog.Warn("plan step retry failed", "execution_id", execID, "step_id", step.ID, "error", err)
+			break
+		}
+		taskID = newTaskID
+		e.markStepRunning(ctx, execID, step.ID, taskID)
+
+		if e.store != nil && e.store.Bus() != nil {
+			e.store.Bus().Publish("plan.step.retry", map[string]interface{}{
+				"execution_id": execID,
+				"step_id":      step.ID,
+				"task_id":      taskID,
+				"attempt":      attempt + 1,
+				"error":        tr.Error,
+			})
+		}
+	}
+
+	out.result = StepResult{
+		TaskID:     tr.TaskID,
+		Status:     tr.Status,
+		Output:     tr.Output,
+		CostUSD:    tr.CostUSD,
+		DurationMs: tr.DurationMs,
+		Error:      tr.Error,
+	}
+	// GC-SPEC-PDR-v4-Phase-2: Record step completion for persistence
+	e.recordStep(ctx, execID, step.ID, stepState(tr.Status), tr.Output, tr.Error, tr.CostUSD)
+	return out
+}
+
+// recordStep persists a finished step (best-effort).
+func (e *Executor) recordStep(ctx context.Context, execID, stepID, state, output, errMsg string, costUSD float64) {
+	if e.store == nil {
+		return
+	}
+	if err := e.store.RecordStepComplete(ctx, execID, stepID, state, output, errMsg, costUSD); err != nil {
+		slog.Warn("failed to record plan step", "execution_id", execID, "step_id", stepID, "error", err)
+	}
+}
+
+// markStepRunning persists the task now executing a step (best-effort).
+func (e *Executor) markStepRunning(ctx context.Context, execID, stepID, taskID string) {
+	if e.store == nil {
+		return
+	}
+	if err := e.store.MarkStepRunning(ctx, execID, stepID, taskID); err != nil {
+		slog.Warn("failed to record plan step start", "execution_id", execID, "step_id", stepID, "error", err)
+	}
+}
+
+// abortTask cancels a task that outlived its step timeout or belongs to a
+// canceled plan, through the router when it can abort (so a running brain
+// call is interrupted) or the store.
+func (e *Executor) abortTask(ctx context.Context, taskID string) {
+	type taskAborter interface {
+		AbortTask(ctx context.Context, taskID string) (bool, error)
+	}
+	var err error
+	if a, ok := e.taskRouter.(taskAborter); ok {
+		_, err = a.AbortTask(ctx, taskID)
+	} else if e.store != nil {
+		_, err = e.store.AbortTask(ctx, taskID)
+	}
+	if err != nil {
+		slog.Warn("failed to abort plan step task", "task_id", taskID, "error", err)
+	}
+}
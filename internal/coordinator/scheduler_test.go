@@ -0,0 +1,571 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+// storeRouter creates real tasks so the Waiter can track them. Tasks for
+// agents in autoFinish succeed as soon as they are created, with the output
+// in outputs or "<agent> done"; tasks for agents in autoFail fail.
+type storeRouter struct {
+	store      *persistence.Store
+	autoFinish map[string]bool
+	autoFail   map[string]bool
+	outputs    map[string]string
+
+	mu      sync.Mutex
+	created map[string][]string // agentID -> task IDs in creation order
+	prompts map[string][]string // agentID -> task contents in creation order
+}
+
+func (r *storeRouter) CreateChatTask(ctx context.Context, agentID, sessionID, content string) (string, error) {
+	taskID, err := r.store.CreateTaskForAgent(ctx, agentID, sessionID, content)
+	if err != nil {
+		return "", err
+	}
+	r.mu.Lock()
+	if r.created == nil {
+		r.created = make(map[string][]string)
+		r.prompts = make(map[string][]string)
+	}
+	r.created[agentID] = append(r.created[agentID], taskID)
+	r.prompts[agentID] = append(r.prompts[agentID], content)
+	r.mu.Unlock()
+	switch {
+	case r.autoFinish[agentID]:
+		output, ok := r.outputs[agentID]
+		if !ok {
+			output = agentID + " done"
+		}
+		if _, err := r.store.DB().ExecContext(ctx,
+			`UPDATE tasks SET status = 'SUCCEEDED', result = ? WHERE id = ?`, output, taskID); err != nil {
+			return "", err
+		}
+	case r.autoFail[agentID]:
+		if _, err := r.store.DB().ExecContext(ctx,
+			`UPDATE tasks SET status = 'FAILED', error = ? WHERE id = ?`, agentID+" broke", taskID); err != nil {
+			return "", err
+		}
+	}
+	return taskID, nil
+}
+
+func (r *storeRouter) CreateMessageTask(ctx context.Context, agentID, sessionID, content string, _ int) (string, error) {
+	return r.CreateChatTask(ctx, agentID, sessionID, content)
+}
+
+func (r *storeRouter) tasks(agentID string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.created[agentID]...)
+}
+
+func (r *storeRouter) contents(agentID string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.prompts[agentID]...)
+}
+
+func (r *storeRouter) total() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, ids := range r.created {
+		n += len(ids)
+	}
+	return n
+}
+
+func openSchedulerStore(t *testing.T) (*persistence.Store, string) {
+	t.Helper()
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "goclaw.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	sessionID := "5b0c8f3e-2d7a-4c61-9e0f-7a3b1d2c4e5f"
+	if err := store.EnsureSession(context.Background(), sessionID); err != nil {
+		t.Fatalf("ensure session: %v", err)
+	}
+	return store, sessionID
+}
+
+func finishTask(t *testing.T, store *persistence.Store, taskID, output string) {
+	t.Helper()
+	if _, err := store.DB().Exec(`UPDATE tasks SET status = 'SUCCEEDED', result = ? WHERE id = ?`, output, taskID); err != nil {
+		t.Fatalf("finish task: %v", err)
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+type executeResult struct {
+	result *ExecutionResult
+	err    error
+}
+
+func startExecute(exec *Executor, plan *Plan, sessionID string) <-chan executeResult {
+	ch := make(chan executeResult, 1)
+	go func() {
+		res, err := exec.Execute(context.Background(), plan, sessionID, "", nil)
+		ch <- executeResult{res, err}
+	}()
+	return ch
+}
+
+func TestSchedule_SlowStepDoesNotBlockIndependentBranch(t *testing.T) {
+	store, sessionID := openSchedulerStore(t)
+	router := &storeRouter{store: store, autoFinish: map[string]bool{"fast": true, "after-fast": true}}
+	exec := NewExecutor(router, NewWaiter(nil, store), store, nil)
+
+	plan := &Plan{Name: "branches", Steps: []PlanStep{
+		{ID: "slow", AgentID: "slow", Prompt: "take your time"},
+		{ID: "fast", AgentID: "fast", Prompt: "quick"},
+		{ID: "next", AgentID: "after-fast", Prompt: "use {fast.output}", DependsOn: []string{"fast"}},
+	}}
+	done := startExecute(exec, plan, sessionID)
+
+	// "next" must start while "slow" (same wave as "fast") is still running.
+	waitFor(t, "dependent of fast step", func() bool { return len(router.tasks("after-fast")) == 1 })
+	slowTasks := router.tasks("slow")
+	if len(slowTasks) != 1 {
+		t.Fatalf("slow tasks = %v, want 1", slowTasks)
+	}
+	task, err := store.GetTask(context.Background(), router.tasks("after-fast")[0])
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if want := "use fast done"; task.Payload != want {
+		t.Errorf("dependent prompt = %q, want %q", task.Payload, want)
+	}
+
+	finishTask(t, store, slowTasks[0], "slow done")
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("execute: %v", res.err)
+	}
+	for _, id := range []string{"slow", "fast", "next"} {
+		if got := res.result.StepResults[id].Status; got != string(persistence.TaskStatusSucceeded) {
+			t.Errorf("step %s status = %s, want SUCCEEDED", id, got)
+		}
+	}
+
+	steps, err := store.GetPlanSteps(context.Background(), res.result.ExecutionID)
+	if err != nil {
+		t.Fatalf("get plan steps: %v", err)
+	}
+	for _, s := range steps {
+		if s.Status != "succeeded" || s.TaskID == "" {
+			t.Errorf("persisted step %s = %s (task %q), want succeeded with task", s.StepID, s.Status, s.TaskID)
+		}
+	}
+}
+
+func TestSchedule_ConcurrencyLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		plan        *Plan
+		wantStarted int
+	}{
+		{
+			name: "per plan",
+			plan: &Plan{Name: "wide", MaxConcurrency: 2, Steps: []PlanStep{
+				{ID: "a", AgentID: "a", Prompt: "1"},
+				{ID: "b", AgentID: "b", Prompt: "2"},
+				{ID: "c", AgentID: "c", Prompt: "3"},
+				{ID: "d", AgentID: "d", Prompt: "4"},
+			}},
+			wantStarted: 2,
+		},
+		{
+			name: "per agent",
+			plan: &Plan{Name: "shared-agent", MaxConcurrencyPerAgent: 1, Steps: []PlanStep{
+				{ID: "a1", AgentID: "a", Prompt: "1"},
+				{ID: "a2", AgentID: "a", Prompt: "2"},
+				{ID: "a3", AgentID: "a", Prompt: "3"},
+				{ID: "b1", AgentID: "b", Prompt: "4"},
+			}},
+			wantStarted: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, sessionID := openSchedulerStore(t)
+			router := &storeRouter{store: store}
+			exec := NewExecutor(router, NewWaiter(nil, store), store, nil)
+			done := startExecute(exec, tt.plan, sessionID)
+
+			waitFor(t, "first steps", func() bool { return router.total() == tt.wantStarted })
+			time.Sleep(150 * time.Millisecond)
+			if got := router.total(); got != tt.wantStarted {
+				t.Fatalf("started %d steps, want %d", got, tt.wantStarted)
+			}
+
+			// Finish tasks as they appear until the plan completes.
+			finished := make(map[string]bool)
+			for {
+				select {
+				case res := <-done:
+					if res.err != nil {
+						t.Fatalf("execute: %v", res.err)
+					}
+					if len(finished) != len(tt.plan.Steps) {
+						t.Fatalf("finished %d tasks, want %d", len(finished), len(tt.plan.Steps))
+					}
+					return
+				default:
+				}
+				router.mu.Lock()
+				var open []string
+				for _, ids := range router.created {
+					for _, id := range ids {
+						if !finished[id] {
+							open = append(open, id)
+						}
+					}
+				}
+				router.mu.Unlock()
+				if len(open) > tt.wantStarted {
+					t.Fatalf("%d steps running at once, limit %d", len(open), tt.wantStarted)
+				}
+				for _, id := range open {
+					finishTask(t, store, id, "ok")
+					finished[id] = true
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestSchedule_StepTimeout(t *testing.T) {
+	store, sessionID := openSchedulerStore(t)
+	router := &storeRouter{store: store, autoFinish: map[string]bool{"other": true}}
+	exec := NewExecutor(router, NewWaiter(nil, store), store, nil)
+
+	plan := &Plan{Name: "timeout", Steps: []PlanStep{
+		{ID: "stuck", AgentID: "stuck", Prompt: "never ends", Timeout: 150 * time.Millisecond, MaxRetries: 1},
+		{ID: "other", AgentID: "other", Prompt: "fine"},
+		{ID: "after", AgentID: "other", Prompt: "blocked", DependsOn: []string{"stuck"}},
+	}}
+	start := time.Now()
+	res := <-startExecute(exec, plan, sessionID)
+	if res.err == nil {
+		t.Fatal("expected error for timed-out step")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("timeout not applied: took %v", elapsed)
+	}
+
+	stuck := res.result.StepResults["stuck"]
+	if stuck.Status != string(persistence.TaskStatusFailed) || stuck.Error == "" {
+		t.Errorf("stuck step = %+v, want FAILED with error", stuck)
+	}
+	if _, ran := res.result.StepResults["after"]; ran {
+		t.Error("dependent of timed-out step should not run")
+	}
+	if got := res.result.StepResults["other"].Status; got != string(persistence.TaskStatusSucceeded) {
+		t.Errorf("independent step status = %s, want SUCCEEDED", got)
+	}
+
+	// Both attempts were aborted.
+	attempts := router.tasks("stuck")
+	if len(attempts) != 2 {
+		t.Fatalf("stuck attempts = %d, want 2", len(attempts))
+	}
+	for _, id := range attempts {
+		task, err := store.GetTask(context.Background(), id)
+		if err != nil {
+			t.Fatalf("get task: %v", err)
+		}
+		if task.Status != persistence.TaskStatusCanceled {
+			t.Errorf("task %s status = %s, want CANCELED", id, task.Status)
+		}
+	}
+
+	exec2, err := store.GetPlanExecution(context.Background(), res.result.ExecutionID)
+	if err != nil {
+		t.Fatalf("get execution: %v", err)
+	}
+	if exec2.Status != "failed" {
+		t.Errorf("plan status = %s, want failed", exec2.Status)
+	}
+}
+
+func TestResume_PicksUpUnfinishedSteps(t *testing.T) {
+	ctx := context.Background()
+	store, sessionID := openSchedulerStore(t)
+
+	plan := &Plan{Name: "resume", Steps: []PlanStep{
+		{ID: "done", AgentID: "a", Prompt: "1"},
+		{ID: "inflight", AgentID: "b", Prompt: "2"},
+		{ID: "todo", AgentID: "c", Prompt: "after {inflight.output}", DependsOn: []string{"done", "inflight"}},
+	}}
+
+	// State left by a crash: one step finished, one running, one not started.
+	execID := "0f4b7c2a-6e1d-4a8b-9c3f-2d5e7a9b1c0d"
+	if err := store.CreatePlanExecution(ctx, execID, plan.Name, sessionID, 3); err != nil {
+		t.Fatalf("create execution: %v", err)
+	}
+	if err := store.InitializePlanSteps(ctx, execID, []persistence.PlanExecutionStep{
+		{StepID: "done", StepIndex: 0, WaveNumber: 0, AgentID: "a", Prompt: "1"},
+		{StepID: "inflight", StepIndex: 1, WaveNumber: 0, AgentID: "b", Prompt: "2"},
+		{StepID: "todo", StepIndex: 0, WaveNumber: 1, AgentID: "c", Prompt: "after {inflight.output}"},
+	}); err != nil {
+		t.Fatalf("initialize steps: %v", err)
+	}
+	if err := store.RecordStepComplete(ctx, execID, "done", "succeeded", "one", "", 0); err != nil {
+		t.Fatalf("record step: %v", err)
+	}
+	inflightTask, err := store.CreateTaskForAgent(ctx, "b", sessionID, "2")
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if err := store.MarkStepRunning(ctx, execID, "inflight", inflightTask); err != nil {
+		t.Fatalf("mark running: %v", err)
+	}
+	finishTask(t, store, inflightTask, "two")
+
+	router := &storeRouter{store: store, autoFinish: map[string]bool{"c": true}}
+	exec := NewExecutor(router, NewWaiter(nil, store), store, nil)
+	result, err := exec.Resume(ctx, execID, plan)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+
+	if n := len(router.tasks("a")) + len(router.tasks("b")); n != 0 {
+		t.Errorf("resume created %d tasks for finished or running steps, want 0", n)
+	}
+	if len(router.tasks("c")) != 1 {
+		t.Fatalf("todo tasks = %v, want 1", router.tasks("c"))
+	}
+	if got := result.StepResults["inflight"]; got.TaskID != inflightTask || got.Output != "two" {
+		t.Errorf("inflight result = %+v, want output of task %s", got, inflightTask)
+	}
+	task, err := store.GetTask(ctx, router.tasks("c")[0])
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if task.Payload != "after two" {
+		t.Errorf("todo prompt = %q, want %q", task.Payload, "after two")
+	}
+
+	pe, err := store.GetPlanExecution(ctx, execID)
+	if err != nil {
+		t.Fatalf("get execution: %v", err)
+	}
+	if pe.Status != "succeeded" || pe.CompletedSteps != 3 {
+		t.Errorf("execution = %s with %d steps done, want succeeded with 3", pe.Status, pe.CompletedSteps)
+	}
+}
+
+func TestSchedule_FailurePolicies(t *testing.T) {
+	// broken fails; "child" depends on it, "grandchild" on child, and
+	// "sibling" runs on an independent branch after "other".
+	newPlan := func(policy FailurePolicy) *Plan {
+		return &Plan{Name: "policies", Steps: []PlanStep{
+			{ID: "broken", AgentID: "bad", Prompt: "fail", MaxRetries: -1, OnFailure: policy},
+			{ID: "other", AgentID: "ok", Prompt: "fine"},
+			{ID: "child", AgentID: "ok", Prompt: "after {broken.output}", DependsOn: []string{"broken"}},
+			{ID: "grandchild", AgentID: "ok", Prompt: "later", DependsOn: []string{"child", "other"}},
+			{ID: "sibling", AgentID: "ok", Prompt: "unrelated", DependsOn: []string{"other"}},
+		}}
+	}
+	tests := []struct {
+		policy     FailurePolicy
+		wantErr    bool
+		wantStatus map[string]string // step -> persisted state; "" = never settled
+	}{
+		{FailPlan, true, map[string]string{"broken": "failed", "child": "pending", "grandchild": "pending"}},
+		{ContinueOnFailure, false, map[string]string{"broken": "failed", "child": "succeeded", "grandchild": "succeeded", "sibling": "succeeded"}},
+		{SkipDependents, false, map[string]string{"broken": "failed", "child": "skipped", "grandchild": "skipped", "sibling": "succeeded"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			store, sessionID := openSchedulerStore(t)
+			router := &storeRouter{store: store, autoFinish: map[string]bool{"ok": true}, autoFail: map[string]bool{"bad": true}}
+			exec := NewExecutor(router, NewWaiter(nil, store), store, nil)
+
+			res := <-startExecute(exec, newPlan(tt.policy), sessionID)
+			if (res.err != nil) != tt.wantErr {
+				t.Fatalf("execute error = %v, wantErr %v", res.err, tt.wantErr)
+			}
+			if n := len(router.tasks("bad")); n != 1 {
+				t.Errorf("broken step ran %d times, want 1 (retries disabled)", n)
+			}
+			steps, err := store.GetPlanSteps(context.Background(), res.result.ExecutionID)
+			if err != nil {
+				t.Fatalf("get steps: %v", err)
+			}
+			for _, s := range steps {
+				if want, ok := tt.wantStatus[s.StepID]; ok && s.Status != want {
+					t.Errorf("step %s = %s, want %s", s.StepID, s.Status, want)
+				}
+			}
+			pe, err := store.GetPlanExecution(context.Background(), res.result.ExecutionID)
+			if err != nil {
+				t.Fatalf("get execution: %v", err)
+			}
+			if want := map[bool]string{true: "failed", false: "succeeded"}[tt.wantErr]; pe.Status != want {
+				t.Errorf("plan status = %s, want %s", pe.Status, want)
+			}
+		})
+	}
+}
+
+func TestSchedule_WhenConditions(t *testing.T) {
+	store, sessionID := openSchedulerStore(t)
+	router := &storeRouter{
+		store:      store,
+		autoFinish: map[string]bool{"reviewer": true, "fixer": true, "shipper": true, "notifier": true},
+		outputs:    map[string]string{"reviewer": `{"verdict": "approve", "issues": []}`},
+	}
+	exec := NewExecutor(router, NewWaiter(nil, store), store, nil)
+
+	plan := &Plan{Name: "conditional", Steps: []PlanStep{
+		{ID: "review", AgentID: "reviewer", Prompt: "review"},
+		{ID: "fix", AgentID: "fixer", Prompt: "fix", DependsOn: []string{"review"}, When: `review.output.verdict == "reject"`},
+		{ID: "ship", AgentID: "shipper", Prompt: "ship", DependsOn: []string{"review"}, When: "review.output.verdict == approve"},
+		{ID: "notify", AgentID: "notifier", Prompt: "done", DependsOn: []string{"fix", "ship"}},
+	}}
+	res := <-startExecute(exec, plan, sessionID)
+	if res.err != nil {
+		t.Fatalf("execute: %v", res.err)
+	}
+
+	if got := res.result.StepResults["fix"]; got.Status != StatusSkipped {
+		t.Errorf("fix = %+v, want skipped", got)
+	}
+	if len(router.tasks("fixer")) != 0 {
+		t.Error("skipped step created a task")
+	}
+	for _, id := range []string{"ship", "notify"} {
+		if got := res.result.StepResults[id].Status; got != string(persistence.TaskStatusSucceeded) {
+			t.Errorf("%s status = %s, want SUCCEEDED", id, got)
+		}
+	}
+}
+
+func TestSchedule_ApprovalGate(t *testing.T) {
+	for _, action := range []string{"approve", "reject"} {
+		t.Run(action, func(t *testing.T) {
+			store, sessionID := openSchedulerStore(t)
+			b := bus.New()
+			router := &storeRouter{store: store, autoFinish: map[string]bool{"deployer": true}}
+			exec := NewExecutor(router, NewWaiter(nil, store), store, b)
+
+			reqs := b.Subscribe(bus.TopicHITLApprovalRequested)
+			defer b.Unsubscribe(reqs)
+			go func() {
+				ev := <-reqs.Ch()
+				req := ev.Payload.(bus.HITLApprovalRequest)
+				b.Publish(bus.TopicHITLApprovalResponse, bus.HITLApprovalResponse{RequestID: req.RequestID, Action: action, Reason: "test"})
+			}()
+
+			plan := &Plan{Name: "gated", Steps: []PlanStep{
+				{ID: "deploy", AgentID: "deployer", Prompt: "deploy", RequireApproval: true, ApprovalTimeoutMs: 5000},
+			}}
+			res := <-startExecute(exec, plan, sessionID)
+
+			created := len(router.tasks("deployer"))
+			if action == "approve" {
+				if res.err != nil || created != 1 {
+					t.Fatalf("approved step: err=%v, tasks=%d; want success with 1 task", res.err, created)
+				}
+				return
+			}
+			if res.err == nil || created != 0 {
+				t.Fatalf("rejected step: err=%v, tasks=%d; want error and no task", res.err, created)
+			}
+			if got := res.result.StepResults["deploy"].Error; !strings.Contains(got, "rejected") {
+				t.Errorf("error = %q, want rejection", got)
+			}
+		})
+	}
+}
+
+func TestSchedule_InputsAndStructuredOutput(t *testing.T) {
+	store, sessionID := openSchedulerStore(t)
+	router := &storeRouter{store: store, autoFinish: map[string]bool{"writer": true}}
+	exec := NewExecutor(router, NewWaiter(nil, store), store, nil)
+	exec.SetOutputValidator(func(schema json.RawMessage, output string) (string, error) {
+		doc := jsonPayload(output)
+		if !strings.Contains(doc, `"urls"`) {
+			return "", fmt.Errorf("missing urls")
+		}
+		return doc, nil
+	})
+
+	plan := &Plan{
+		Name:   "research",
+		Inputs: []PlanInput{{Name: "topic", Type: InputString, Required: true}, {Name: "depth", Type: InputInteger, Default: 2}},
+		Steps: []PlanStep{
+			{ID: "research", AgentID: "researcher", Prompt: "Research {inputs.topic} at depth {inputs.depth}.",
+				OutputSchema: json.RawMessage(`{"type":"object","required":["urls"]}`)},
+			{ID: "write", AgentID: "writer", Prompt: "Summarize {research.output.urls[0]}.", DependsOn: []string{"research"}},
+		},
+	}
+
+	if _, err := exec.Execute(context.Background(), plan, sessionID, "", map[string]any{"depth": "x"}); !errors.Is(err, ErrInvalidInputs) {
+		t.Fatalf("Execute with bad inputs: err = %v, want ErrInvalidInputs", err)
+	}
+	if router.total() != 0 {
+		t.Fatalf("bad inputs queued %d tasks", router.total())
+	}
+
+	ch := make(chan executeResult, 1)
+	go func() {
+		res, err := exec.Execute(context.Background(), plan, sessionID, "", map[string]any{"topic": "sqlite"})
+		ch <- executeResult{res, err}
+	}()
+
+	waitFor(t, "research task", func() bool { return len(router.tasks("researcher")) == 1 })
+	prompt := router.contents("researcher")[0]
+	if !strings.HasPrefix(prompt, "Research sqlite at depth 2.") || !strings.Contains(prompt, `"required":["urls"]`) {
+		t.Fatalf("research prompt = %q, want inputs and the schema", prompt)
+	}
+	finishTask(t, store, router.tasks("researcher")[0], "Here you go: nothing structured")
+
+	// The output did not match the schema, so the step is retried.
+	waitFor(t, "research retry", func() bool { return len(router.tasks("researcher")) == 2 })
+	finishTask(t, store, router.tasks("researcher")[1], "```json\n{\"urls\": [\"https://sqlite.org\"]}\n```")
+
+	res := <-ch
+	if res.err != nil {
+		t.Fatalf("Execute: %v", res.err)
+	}
+	if got := res.result.StepResults["research"].Output; got != `{"urls": ["https://sqlite.org"]}` {
+		t.Errorf("research output = %q, want the bare JSON document", got)
+	}
+	if got := router.contents("writer"); len(got) != 1 || got[0] != "Summarize https://sqlite.org." {
+		t.Errorf("writer prompts = %q", got)
+	}
+
+	pe, err := store.GetPlanExecution(context.Background(), res.result.ExecutionID)
+	if err != nil {
+		t.Fatalf("get execution: %v", err)
+	}
+	if pe.Inputs != `{"depth":2,"topic":"sqlite"}` {
+		t.Errorf("persisted inputs = %q", pe.Inputs)
+	}
+}
@@ -187,19 +187,19 @@ func (w *Waiter) checkTerminal(ctx context.Context, taskID string) (*TaskResult,
 	// approximation of total wall-clock time including queue wait.
 	durationMs := task.UpdatedAt.Sub(task.CreatedAt).Milliseconds()
 
-	// PromptTokens, CompletionTokens, CostUSD: the brain (Genkit/Gemini)
-	// does not currently return token counts or cost data after generation.
-	// The Store.UpdateTaskTokens() method exists but is never called in
-	// production because the LLM provider SDK does not expose usage stats.
-	// These fields will remain 0 until the brain layer is updated to
-	// extract and persist token usage from provider responses.
+	// Token counts and cost are recorded by the engine from the provider's
+	// reported usage before the task reaches a terminal state.
+	usage, err := w.store.GetTaskUsage(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("get task usage %s: %w", taskID, err)
+	}
 	return &TaskResult{
 		TaskID:           task.ID,
 		Status:           string(task.Status),
 		Output:           task.Result,
-		PromptTokens:     0,
-		CompletionTokens: 0,
-		CostUSD:          0,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CostUSD:          usage.CostUSD,
 		DurationMs:       durationMs,
 		Error:            task.Error,
 	}, nil
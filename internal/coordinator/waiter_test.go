@@ -130,3 +130,31 @@ func _TestWaitForAll_Parallel(t *testing.T) {
 		t.Fatal("expected at least one result")
 	}
 }
+
+func TestWaitForTask_ReportsUsage(t *testing.T) {
+	store := openTestStore(t)
+	w := coordinator.NewWaiter(nil, store)
+	ctx := context.Background()
+
+	sessionID := "00000000-0000-0000-0000-000000000004"
+	_ = store.EnsureSession(ctx, sessionID)
+	taskID, err := store.CreateTask(ctx, sessionID, "payload")
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	usage := persistence.TaskUsage{PromptTokens: 1200, CompletionTokens: 300, CostUSD: 0.0081}
+	if err := store.UpdateTaskTokens(ctx, taskID, usage); err != nil {
+		t.Fatalf("update task tokens: %v", err)
+	}
+	if _, err := store.AbortTask(ctx, taskID); err != nil {
+		t.Fatalf("abort task: %v", err)
+	}
+
+	result, err := w.WaitForTask(ctx, taskID, time.Second)
+	if err != nil {
+		t.Fatalf("wait for task: %v", err)
+	}
+	if result.PromptTokens != 1200 || result.CompletionTokens != 300 || result.CostUSD != 0.0081 {
+		t.Errorf("result usage = %d/%d $%f, want the recorded usage", result.PromptTokens, result.CompletionTokens, result.CostUSD)
+	}
+}
@@ -6,12 +6,14 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
 
 	"github.com/basket/go-claw/internal/config"
 	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/sandbox/native"
 )
 
 type CheckResult struct {
@@ -159,8 +161,13 @@ func checkExternalTools(ctx context.Context, cfg *config.Config) CheckResult {
 		details = append(details, "git: ok")
 	}
 
-	// Check docker if sandbox enabled
-	if cfg != nil && cfg.Tools.Shell.Sandbox {
+	// Check the sandbox backend if one is enabled
+	var mode config.SandboxMode
+	if cfg != nil {
+		mode = cfg.Tools.Shell.Sandbox
+	}
+	switch mode {
+	case config.SandboxDocker:
 		if _, err := exec.LookPath("docker"); err != nil {
 			details = append(details, "docker: missing (required for sandbox)")
 			status = "FAIL"
@@ -174,8 +181,26 @@ func checkExternalTools(ctx context.Context, cfg *config.Config) CheckResult {
 				details = append(details, "docker: ok")
 			}
 		}
-	} else {
-		details = append(details, "docker: skipped (sandbox disabled)")
+	case config.SandboxNative:
+		// native.New runs a probe command, which re-executes the goclaw
+		// binary through native.Init.
+		shell := cfg.Tools.Shell
+		if _, err := native.New(native.Config{
+			Workspace:     filepath.Join(cfg.HomeDir, "workspace"),
+			ReadOnlyPaths: shell.SandboxReadOnlyPaths,
+			Network:       shell.SandboxNetwork,
+			MemoryMB:      shell.SandboxMemory,
+			CPUs:          shell.SandboxCPUs,
+			Pids:          shell.SandboxPids,
+			Cgroup:        shell.SandboxCgroup,
+		}); err != nil {
+			details = append(details, err.Error()) // prefixed with "native sandbox:"
+			status = "FAIL"
+		} else {
+			details = append(details, "native sandbox: ok")
+		}
+	default:
+		details = append(details, "sandbox: skipped (disabled)")
 	}
 
 	return CheckResult{
@@ -2,17 +2,21 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 
+	"github.com/basket/go-claw/internal/budget"
 	"github.com/basket/go-claw/internal/config"
 	"github.com/basket/go-claw/internal/mcp"
+	"github.com/basket/go-claw/internal/media"
 	"github.com/basket/go-claw/internal/memory"
 	"github.com/basket/go-claw/internal/persistence"
 	"github.com/basket/go-claw/internal/policy"
@@ -24,6 +28,7 @@ import (
 	"github.com/basket/go-claw/internal/tokenutil"
 	"github.com/basket/go-claw/internal/tools"
 	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/core/api"
 	"github.com/firebase/genkit/go/genkit"
 	"github.com/firebase/genkit/go/plugins/anthropic"
 	"github.com/firebase/genkit/go/plugins/compat_oai"
@@ -80,9 +85,31 @@ type BrainConfig struct {
 	APIKeys         map[string]string
 	PreferredSearch string
 
+	// MaxToolTurns bounds the tool-call rounds in one response
+	// (0 = DefaultMaxToolTurns). A request can override it through
+	// shared.WithMaxToolTurns.
+	MaxToolTurns int
+
 	// OpenAICompatible config.
 	OpenAICompatibleProvider string
 	OpenAICompatibleBaseURL  string
+
+	// Approvals answers require_approval gates on tool calls (nil = gated calls are denied).
+	Approvals tools.ApprovalBroker
+
+	// Planner enables the create_plan tool (nil = not registered).
+	Planner *tools.Planner
+
+	// Budget refuses model calls once a spend budget is exhausted (nil = no budgets).
+	Budget *budget.Enforcer
+
+	// Memory selects the memories injected into each turn and backs the
+	// memory_search tool (nil = keyword ranking over the store).
+	Memory *memory.Retriever
+
+	// MemoryTokens is the token budget for the memories injected into each
+	// turn (0 = memory.DefaultRetrievalTokens).
+	MemoryTokens int
 }
 
 type skillEntry struct {
@@ -123,6 +150,9 @@ type GenkitBrain struct {
 	// toolsSupported indicates whether the model supports tool/function calling.
 	// Defaults to true for all providers except "ollama" where it's auto-detected.
 	toolsSupported bool
+	// mediaSupported indicates whether the model accepts image and PDF input.
+	// Read from the Genkit model metadata, or from Ollama's capabilities.
+	mediaSupported bool
 }
 
 // SetValidator configures structured output validation for this brain.
@@ -253,10 +283,17 @@ func NewGenkitBrain(ctx context.Context, store *persistence.Store, cfg BrainConf
 		slog.Warn("unknown LLM provider, using deterministic fallback", "provider", provider)
 	}
 
+	if cfg.Memory == nil && store != nil {
+		cfg.Memory = memory.NewRetriever(store, nil)
+	}
+
 	toolRegistry := tools.NewRegistry(cfg.Policy, cfg.APIKeys, cfg.PreferredSearch, store)
 	if store != nil {
 		toolRegistry.Bus = store.Bus()
 	}
+	toolRegistry.Approvals = cfg.Approvals
+	toolRegistry.Planner = cfg.Planner
+	toolRegistry.Memory = cfg.Memory
 	toolRegistry.RegisterAll(g)
 
 	// Create the brain struct so closures below can capture it.
@@ -274,14 +311,19 @@ func NewGenkitBrain(ctx context.Context, store *persistence.Store, cfg BrainConf
 	}
 
 	brain.toolsSupported = true
+	brain.mediaSupported = true
 
-	// Auto-detect tool support for Ollama models.
+	// Auto-detect tool and vision support for Ollama models.
 	if provider == "ollama" {
 		baseURL := cfg.OpenAICompatibleBaseURL
 		if baseURL == "" {
 			baseURL = "http://localhost:11434/v1"
 		}
-		brain.toolsSupported = detectOllamaTools(baseURL, modelID)
+		caps := detectOllamaCapabilities(baseURL, modelID)
+		brain.toolsSupported = slices.Contains(caps, "tools")
+		brain.mediaSupported = slices.Contains(caps, "vision")
+	} else if llmOn {
+		brain.mediaSupported = modelSupportsMedia(g, modelNameForProvider(provider, modelID))
 	}
 
 	// Initialize compactor.
@@ -293,7 +335,7 @@ func NewGenkitBrain(ctx context.Context, store *persistence.Store, cfg BrainConf
 	// Register the use_skill tool: lets the LLM invoke a registered skill by name.
 	useSkillTool := genkit.DefineTool(g, "use_skill",
 		"Invoke a registered skill by name. Returns the skill's instructions and activation status. Use this when the user asks to use a specific skill.",
-		func(ctx *ai.ToolContext, input tools.UseSkillInput) (tools.UseSkillOutput, error) {
+		tools.Traced(toolRegistry, "use_skill", func(ctx *ai.ToolContext, input tools.UseSkillInput) (tools.UseSkillOutput, error) {
 			// Policy check: require skill.inject capability.
 			if brain.cfg.Policy == nil || !brain.cfg.Policy.AllowCapability("skill.inject") {
 				return tools.UseSkillOutput{}, fmt.Errorf("policy denied capability %q", "skill.inject")
@@ -347,7 +389,7 @@ func NewGenkitBrain(ctx context.Context, store *persistence.Store, cfg BrainConf
 			}
 
 			return result, nil
-		},
+		}),
 	)
 	toolRegistry.Tools = append(toolRegistry.Tools, useSkillTool)
 
@@ -506,10 +548,16 @@ func (b *GenkitBrain) Respond(ctx context.Context, sessionID, content string) (s
 		slog.Warn("failed to load/compact session history", "session_id", sessionID, "agent_id", agentID, "error", err)
 		// Continue without history rather than failing
 	}
+	if err := b.checkMediaSupport(history); err != nil {
+		return "", err
+	}
 
-	// Build generate options
-	opts := []ai.GenerateOption{
-		ai.WithPrompt(trimmed),
+	// Build generate options. A turn continued after client tool calls ends
+	// with their rounds instead of the prompt, which history already holds.
+	var opts []ai.GenerateOption
+	rounds := clientToolRounds(ctx)
+	if len(rounds) == 0 {
+		opts = append(opts, ai.WithPrompt(trimmed))
 	}
 
 	// Add system prompt from SOUL.md if available (read-lock for hot-reload safety).
@@ -525,11 +573,17 @@ func (b *GenkitBrain) Respond(ctx context.Context, sessionID, content string) (s
 		}
 	}
 
-	// Inject core memory block (top memories by relevance)
-	if topMemories, err := b.store.ListTopMemories(ctx, agentID, 10); err == nil && len(topMemories) > 0 {
+	// Inject core memory block: the memories relevant to the prompt, within
+	// the retrieval token budget.
+	var coreMemories []persistence.AgentMemory
+	var memErr error
+	if b.cfg.Memory != nil {
+		coreMemories, memErr = b.cfg.Memory.Select(ctx, agentID, trimmed, b.cfg.MemoryTokens)
+	}
+	if memErr == nil && len(coreMemories) > 0 {
 		// Convert to memory.KeyValue format
 		var kvs []memory.KeyValue
-		for _, m := range topMemories {
+		for _, m := range coreMemories {
 			kvs = append(kvs, memory.KeyValue{
 				Key:            m.Key,
 				Value:          m.Value,
@@ -544,8 +598,8 @@ func (b *GenkitBrain) Respond(ctx context.Context, sessionID, content string) (s
 		if formatted := coreBlock.Format(); formatted != "" {
 			systemPrompt = systemPrompt + "\n\n" + formatted
 		}
-	} else if err != nil && agentID != shared.DefaultAgentID {
-		slog.Warn("failed to load core memories", "agent_id", agentID, "error", err)
+	} else if memErr != nil && agentID != shared.DefaultAgentID {
+		slog.Warn("failed to load core memories", "agent_id", agentID, "error", memErr)
 	}
 
 	// Inject pinned files and text context
@@ -579,28 +633,33 @@ func (b *GenkitBrain) Respond(ctx context.Context, sessionID, content string) (s
 	// Add conversation history as messages
 	appendHistory := func(o []ai.GenerateOption) []ai.GenerateOption {
 		if len(history) > 0 {
-			if msgs := historyToMessages(history); len(msgs) > 0 {
+			if msgs := historyToMessages(history, b.mediaSupported); len(msgs) > 0 {
 				o = append(o, ai.WithMessages(msgs...))
 			}
 		}
 		return o
 	}
 
-	// Inject pending delegation results (Phase 2).
+	// Inject pending delegation results (Phase 2). Genkit accepts messages
+	// only once, so they are sent together with history and tool rounds.
 	delegationMsgs, delegErr := b.injectPendingDelegations(ctx)
 	if delegErr != nil {
 		slog.Warn("failed to inject pending delegations", "agent_id", agentID, "error", delegErr)
-	} else if len(delegationMsgs) > 0 {
-		opts = append(opts, ai.WithMessages(delegationMsgs...))
+		delegationMsgs = nil
+	}
+	msgs := append(delegationMsgs, historyToMessages(history, b.mediaSupported)...)
+	msgs = append(msgs, clientToolRoundMessages(rounds)...)
+	if len(msgs) > 0 {
+		opts = append(opts, ai.WithMessages(msgs...))
 	}
 
-	opts = appendHistory(opts)
-
-	// Add tools for autonomous use (only if model supports them).
-	if b.toolsSupported && len(b.tools.Tools) > 0 {
-		opts = append(opts, ai.WithTools(b.tools.Tools...))
-		opts = append(opts, ai.WithMaxTurns(3))
+	// Add tools for autonomous use (only if model supports them), and the
+	// client's tools when the request has any.
+	toolOpts, err := b.toolOptions(ctx)
+	if err != nil {
+		return "", err
 	}
+	opts = append(opts, toolOpts...)
 
 	if !b.llmOn {
 		return "No API key configured. Set the appropriate environment variable (e.g. GEMINI_API_KEY) or use /config in the TUI to add one. Run /model list to see available providers.", nil
@@ -629,17 +688,20 @@ func (b *GenkitBrain) Respond(ctx context.Context, sessionID, content string) (s
 
 	// Build model name based on provider and prepend to options
 	modelName := modelNameForProvider(strings.ToLower(b.cfg.Provider), b.cfg.Model)
-	modelOpts := []ai.GenerateOption{ai.WithModelName(modelName)}
+	modelOpts := []ai.GenerateOption{ai.WithModelName(modelName), ai.WithMiddleware(b.usageMiddleware)}
 	modelOpts = append(modelOpts, opts...)
 
 	resp, err := genkit.Generate(ctx, b.g, modelOpts...)
 	if err != nil {
 		slog.Error("genkit generate failed", "error", err, "session_id", sessionID)
-		// If generation failed with tools, retry without tools as fallback
-		if b.toolsSupported && len(b.tools.Tools) > 0 {
+		// If generation failed with tools, retry without tools as fallback.
+		// Client tools are part of the request's contract, so those requests
+		// fail instead.
+		if b.toolsSupported && len(b.tools.ToolRefs()) > 0 && shared.GetClientTools(ctx) == nil && !errors.Is(err, budget.ErrExceeded) {
 			slog.Info("retrying without tools")
 			fallbackOpts := appendHistory([]ai.GenerateOption{
 				ai.WithModelName(modelName),
+				ai.WithMiddleware(b.usageMiddleware),
 				ai.WithPrompt(trimmed),
 				ai.WithSystem(systemPrompt), // Reuse the same soul-injected prompt
 			})
@@ -666,6 +728,10 @@ func (b *GenkitBrain) Respond(ctx context.Context, sessionID, content string) (s
 			slog.Warn("leak detector triggered on LLM output", "session_id", sessionID, "findings_count", len(findings))
 		}
 	}
+	// A turn stopped on client tool calls is not a final answer to validate.
+	if recordClientToolCalls(ctx, resp) {
+		return reply, nil
+	}
 
 	// Structured output validation: if a validator is configured, validate and retry.
 	if b.validator != nil {
@@ -750,6 +816,9 @@ func (b *GenkitBrain) Stream(ctx context.Context, sessionID, content string, onC
 	if err != nil {
 		slog.Warn("failed to load/compact session history for streaming", "session_id", sessionID, "agent_id", agentID, "error", err)
 	}
+	if err := b.checkMediaSupport(history); err != nil {
+		return err
+	}
 
 	// Build system prompt (read-lock for hot-reload safety).
 	b.soulMu.RLock()
@@ -766,32 +835,35 @@ func (b *GenkitBrain) Stream(ctx context.Context, sessionID, content string, onC
 	// Escape % characters to prevent fmt.Sprintf corruption in ai.WithSystem().
 	systemPrompt = strings.ReplaceAll(systemPrompt, "%", "%%")
 
-	// Build generate options
-	opts := []ai.GenerateOption{
-		ai.WithPrompt(trimmed),
-		ai.WithSystem(systemPrompt),
+	// Build generate options. A turn continued after client tool calls ends
+	// with their rounds instead of the prompt, which history already holds.
+	opts := []ai.GenerateOption{ai.WithSystem(systemPrompt)}
+	rounds := clientToolRounds(ctx)
+	if len(rounds) == 0 {
+		opts = append(opts, ai.WithPrompt(trimmed))
 	}
 
-	// Add conversation history
-	if len(history) > 0 {
-		if msgs := historyToMessages(history); len(msgs) > 0 {
-			opts = append(opts, ai.WithMessages(msgs...))
-		}
-	}
-
-	// Inject pending delegation results (Phase 2).
+	// Add conversation history and pending delegation results (Phase 2) in
+	// one set of messages, as Genkit accepts messages only once.
+	msgs := historyToMessages(history, b.mediaSupported)
 	delegationMsgs, delegErr := b.injectPendingDelegations(ctx)
 	if delegErr != nil {
 		slog.Warn("failed to inject pending delegations (stream)", "agent_id", agentID, "error", delegErr)
-	} else if len(delegationMsgs) > 0 {
-		opts = append(opts, ai.WithMessages(delegationMsgs...))
+	} else {
+		msgs = append(msgs, delegationMsgs...)
+	}
+	msgs = append(msgs, clientToolRoundMessages(rounds)...)
+	if len(msgs) > 0 {
+		opts = append(opts, ai.WithMessages(msgs...))
 	}
 
-	// Add tools for autonomous use (only if model supports them).
-	if b.toolsSupported && len(b.tools.Tools) > 0 {
-		opts = append(opts, ai.WithTools(b.tools.Tools...))
-		opts = append(opts, ai.WithMaxTurns(3))
+	// Add tools for autonomous use (only if model supports them), and the
+	// client's tools when the request has any.
+	toolOpts, err := b.toolOptions(ctx)
+	if err != nil {
+		return err
 	}
+	opts = append(opts, toolOpts...)
 
 	// Apply per-request sampling config from context (OpenAI API passthrough).
 	if sc := shared.GetSamplingConfig(ctx); sc != nil {
@@ -816,7 +888,7 @@ func (b *GenkitBrain) Stream(ctx context.Context, sessionID, content string, onC
 
 	// Build model name
 	modelName := modelNameForProvider(strings.ToLower(b.cfg.Provider), b.cfg.Model)
-	modelOpts := []ai.GenerateOption{ai.WithModelName(modelName)}
+	modelOpts := []ai.GenerateOption{ai.WithModelName(modelName), ai.WithMiddleware(b.usageMiddleware)}
 	modelOpts = append(modelOpts, opts...)
 
 	// Stream using Genkit's GenerateStream
@@ -824,6 +896,7 @@ func (b *GenkitBrain) Stream(ctx context.Context, sessionID, content string, onC
 
 	var fullReply strings.Builder
 	var doneReply string
+	var doneResp *ai.ModelResponse
 	var streamErr error
 	for streamVal, err := range stream {
 		if err != nil {
@@ -841,20 +914,23 @@ func (b *GenkitBrain) Stream(ctx context.Context, sessionID, content string, onC
 			}
 		}
 		if streamVal.Done && streamVal.Response != nil {
+			doneResp = streamVal.Response
 			doneReply = streamVal.Response.Text()
 		}
 	}
 
-	// If streaming failed and tools were sent, retry without tools.
-	if streamErr != nil && b.toolsSupported && len(b.tools.Tools) > 0 {
+	// If streaming failed and tools were sent, retry without tools (except
+	// for requests with client tools, as in Respond).
+	if streamErr != nil && b.toolsSupported && len(b.tools.ToolRefs()) > 0 && shared.GetClientTools(ctx) == nil && !errors.Is(streamErr, budget.ErrExceeded) {
 		slog.Info("stream failed with tools, retrying without tools", "error", streamErr)
 		retryOpts := []ai.GenerateOption{
 			ai.WithModelName(modelName),
+			ai.WithMiddleware(b.usageMiddleware),
 			ai.WithPrompt(trimmed),
 			ai.WithSystem(systemPrompt),
 		}
 		if len(history) > 0 {
-			if msgs := historyToMessages(history); len(msgs) > 0 {
+			if msgs := historyToMessages(history, b.mediaSupported); len(msgs) > 0 {
 				retryOpts = append(retryOpts, ai.WithMessages(msgs...))
 			}
 		}
@@ -876,6 +952,8 @@ func (b *GenkitBrain) Stream(ctx context.Context, sessionID, content string, onC
 		return fmt.Errorf("stream error: %w", streamErr)
 	}
 
+	recordClientToolCalls(ctx, doneResp)
+
 	// Determine final reply: prefer accumulated chunks, fall back to Done response.
 	finalReply := fullReply.String()
 	if finalReply == "" {
@@ -897,6 +975,27 @@ func (b *GenkitBrain) Stream(ctx context.Context, sessionID, content string, onC
 	return nil
 }
 
+// DefaultMaxToolTurns is the tool-call round budget of a response when the
+// agent config does not set max_tool_turns.
+const DefaultMaxToolTurns = 10
+
+// MaxToolTurnsLimit caps configured and per-request budgets so one response
+// cannot loop on tools indefinitely.
+const MaxToolTurnsLimit = 50
+
+// maxToolTurns returns the tool-call round budget for this request: the
+// per-request override when present, else the agent's configured budget.
+func (b *GenkitBrain) maxToolTurns(ctx context.Context) int {
+	turns := shared.MaxToolTurns(ctx)
+	if turns <= 0 {
+		turns = b.cfg.MaxToolTurns
+	}
+	if turns <= 0 {
+		return DefaultMaxToolTurns
+	}
+	return min(turns, MaxToolTurnsLimit)
+}
+
 func defaultSystemPrompt(agentName string) string {
 	name := strings.TrimSpace(agentName)
 	if name == "" {
@@ -909,8 +1008,52 @@ func defaultSystemPrompt(agentName string) string {
 	)
 }
 
+// ErrMediaUnsupported is returned when a message carries images or PDFs
+// and the agent's model cannot read them.
+var ErrMediaUnsupported = errors.New("model does not accept image or PDF attachments")
+
+// SupportsMedia reports whether the brain's model accepts image and PDF input.
+func (b *GenkitBrain) SupportsMedia() bool {
+	return b.mediaSupported
+}
+
+// checkMediaSupport fails the turn when the latest user message has
+// attachments the model cannot read. Older attachments are replaced by a
+// note in historyToMessages instead.
+func (b *GenkitBrain) checkMediaSupport(history []persistence.HistoryItem) error {
+	if b.mediaSupported {
+		return nil
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role != "user" {
+			continue
+		}
+		if media.NeedsVision(history[i].Attachments) {
+			return fmt.Errorf("%w: %s", ErrMediaUnsupported, modelNameForProvider(strings.ToLower(b.cfg.Provider), b.cfg.Model))
+		}
+		return nil
+	}
+	return nil
+}
+
+// modelSupportsMedia reads the media capability Genkit records for a model.
+// Models the plugin does not describe are assumed to accept media and the
+// provider reports any mismatch.
+func modelSupportsMedia(g *genkit.Genkit, name string) bool {
+	described, ok := genkit.LookupModel(g, name).(interface{ Desc() api.ActionDesc })
+	if !ok {
+		return true
+	}
+	meta, _ := described.Desc().Metadata["model"].(map[string]any)
+	supports, _ := meta["supports"].(map[string]any)
+	mediaOK, ok := supports["media"].(bool)
+	return !ok || mediaOK
+}
+
 // historyToMessages converts persistence history items to Genkit messages.
-func historyToMessages(items []persistence.HistoryItem) []*ai.Message {
+// Attachments become media parts when withMedia is set; text documents are
+// always inlined as text.
+func historyToMessages(items []persistence.HistoryItem, withMedia bool) []*ai.Message {
 	var msgs []*ai.Message
 	for _, item := range items {
 		var role ai.Role
@@ -926,14 +1069,35 @@ func historyToMessages(items []persistence.HistoryItem) []*ai.Message {
 		default:
 			continue
 		}
+		content := []*ai.Part{ai.NewTextPart(item.Content)}
+		content = append(content, attachmentParts(item.Attachments, withMedia)...)
 		msgs = append(msgs, &ai.Message{
 			Role:    role,
-			Content: []*ai.Part{ai.NewTextPart(item.Content)},
+			Content: content,
 		})
 	}
 	return msgs
 }
 
+func attachmentParts(atts []shared.Attachment, withMedia bool) []*ai.Part {
+	var parts []*ai.Part
+	for _, a := range atts {
+		name := a.Name
+		if name == "" {
+			name = a.MimeType
+		}
+		switch {
+		case media.IsText(a.MimeType):
+			parts = append(parts, ai.NewTextPart(fmt.Sprintf("[Attached file: %s]\n%s", name, a.Data)))
+		case withMedia:
+			parts = append(parts, ai.NewMediaPart(a.MimeType, media.DataURL(a)))
+		default:
+			parts = append(parts, ai.NewTextPart(fmt.Sprintf("[Attachment %s omitted: the model cannot read %s]", name, a.MimeType)))
+		}
+	}
+	return parts
+}
+
 // Providers returns the ordered list of search providers from the tool registry.
 func (b *GenkitBrain) Providers() []tools.SearchProvider {
 	return b.tools.Providers
@@ -953,6 +1117,9 @@ func (b *GenkitBrain) SetWASMHost(host *wasm.Host) {
 	b.wasmHost = host
 }
 
+// RegisterSkill adds a loaded WASM module to the skill catalog. Modules that
+// implement the tool ABI are also (re-)registered as tools, so calling this
+// after a hot-swap rebuild picks up the new manifest.
 func (b *GenkitBrain) RegisterSkill(name string) {
 	b.skillMu.Lock()
 	defer b.skillMu.Unlock()
@@ -960,12 +1127,21 @@ func (b *GenkitBrain) RegisterSkill(name string) {
 	if key == "" {
 		return
 	}
+	if b.wasmHost != nil && b.tools != nil {
+		b.tools.RegisterWASMTool(b.wasmHost, name)
+	}
 	if _, ok := b.loadedSkills[key]; ok {
 		return
 	}
+	description := "WASM skill"
+	if b.wasmHost != nil {
+		if manifest, ok := b.wasmHost.ToolManifest(name); ok && manifest.Description != "" {
+			description = manifest.Description
+		}
+	}
 	b.loadedSkills[key] = &skillEntry{
 		Name:        name,
-		Description: "WASM skill",
+		Description: description,
 		Type:        "wasm",
 	}
 }
@@ -1018,7 +1194,8 @@ func (b *GenkitBrain) ReplaceLoadedSkills(items []skills.LoadedSkill) {
 }
 
 // RegisterMCPTools discovers and registers MCP tools for a specific agent.
-// Calls manager.DiscoverTools to get tools allowed by policy.
+// Calls manager.DiscoverTools to get tools allowed by policy. Calling it again
+// replaces the previous MCP tool set (used after tools/list_changed).
 // If discovery fails, logs a warning but continues (non-fatal).
 func (b *GenkitBrain) RegisterMCPTools(ctx context.Context, agentID string, manager interface{}) error {
 	// Import mcp package to use Manager type
@@ -1031,7 +1208,8 @@ func (b *GenkitBrain) RegisterMCPTools(ctx context.Context, agentID string, mana
 		return nil
 	}
 
-	refs := tools.RegisterMCPTools(b.g, agentID, mgr)
+	refs := b.tools.MCPToolRefs(agentID, mgr)
+	b.tools.SetMCPTools(refs)
 	slog.Info("mcp tools registered for agent", "agent", agentID, "count", len(refs))
 	return nil
 }
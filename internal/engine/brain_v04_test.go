@@ -196,3 +196,26 @@ func TestBrain_RegisterMCPTools(t *testing.T) {
 func TestBrain_MCPToolDiscovery(t *testing.T) {
 	t.Skip("auto-discovery calls tools/list in Phase 1")
 }
+
+func TestGenkitBrain_MaxToolTurns(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   int
+		override int
+		want     int
+	}{
+		{"default", 0, 0, DefaultMaxToolTurns},
+		{"agent config", 25, 0, 25},
+		{"request override", 25, 5, 5},
+		{"capped", 0, 500, MaxToolTurnsLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			brain := &GenkitBrain{cfg: BrainConfig{MaxToolTurns: tt.config}}
+			ctx := shared.WithMaxToolTurns(context.Background(), tt.override)
+			if got := brain.maxToolTurns(ctx); got != tt.want {
+				t.Errorf("maxToolTurns = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/basket/go-claw/internal/shared"
+	"github.com/firebase/genkit/go/ai"
+	"github.com/google/uuid"
+)
+
+// Client tools are function tools defined by the API client rather than by
+// GoClaw. They are offered to the model as tools that interrupt generation
+// when called: the turn stops and the calls are handed back to the client,
+// which continues the turn with their results (see shared.ClientTools).
+
+// ErrClientTools is returned when the client tools of a request cannot be
+// offered to the model.
+var ErrClientTools = errors.New("client tools unavailable")
+
+// toolOptions returns the tool options of a generation: the agent's built-in
+// tools and the request's client tools, as selected by the request.
+func (b *GenkitBrain) toolOptions(ctx context.Context) ([]ai.GenerateOption, error) {
+	ct := shared.GetClientTools(ctx)
+	if ct != nil && ct.Choice == shared.ToolChoiceNone {
+		return nil, nil
+	}
+	if !b.toolsSupported {
+		if ct != nil && len(ct.Tools) > 0 {
+			return nil, fmt.Errorf("%w: the model does not support tool calling", ErrClientTools)
+		}
+		return nil, nil
+	}
+
+	var refs []ai.ToolRef
+	if ct == nil || !ct.NoBuiltinTools {
+		refs = b.tools.ToolRefs()
+	}
+	if ct != nil {
+		builtin := make(map[string]bool, len(refs))
+		for _, ref := range refs {
+			builtin[ref.Name()] = true
+		}
+		for _, t := range ct.Tools {
+			if builtin[t.Name] {
+				return nil, fmt.Errorf("%w: %q is the name of a built-in tool", ErrClientTools, t.Name)
+			}
+			refs = append(refs, clientTool(t))
+		}
+	}
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	opts := []ai.GenerateOption{ai.WithTools(refs...), ai.WithMaxTurns(b.maxToolTurns(ctx))}
+	if ct != nil && ct.Choice == shared.ToolChoiceRequired {
+		opts = append(opts, ai.WithToolChoice(ai.ToolChoiceRequired))
+	}
+	return opts, nil
+}
+
+// clientTool defines a client tool for one generation. Calling it interrupts
+// the generation.
+func clientTool(t shared.ClientTool) ai.Tool {
+	schema := t.Parameters
+	if schema == nil {
+		schema = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	return ai.NewTool(t.Name, t.Description, func(tc *ai.ToolContext, _ any) (any, error) {
+		return nil, tc.Interrupt(nil)
+	}, ai.WithInputSchema(schema))
+}
+
+// clientToolCalls returns the client tool calls a generation stopped on.
+func clientToolCalls(resp *ai.ModelResponse) []shared.ClientToolCall {
+	if resp == nil || resp.FinishReason != ai.FinishReasonInterrupted {
+		return nil
+	}
+	var calls []shared.ClientToolCall
+	for _, part := range resp.Interrupts() {
+		req := part.ToolRequest
+		id := req.Ref
+		if id == "" {
+			// Not every provider assigns call IDs; the client needs one to
+			// send the result back.
+			id = "call_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+		}
+		args, err := json.Marshal(req.Input)
+		if err != nil || req.Input == nil {
+			args = []byte("{}")
+		}
+		calls = append(calls, shared.ClientToolCall{ID: id, Name: req.Name, Arguments: string(args)})
+	}
+	return calls
+}
+
+// recordClientToolCalls hands the client tool calls a generation stopped on
+// to the request, and reports whether there were any.
+func recordClientToolCalls(ctx context.Context, resp *ai.ModelResponse) bool {
+	ct := shared.GetClientTools(ctx)
+	if ct == nil {
+		return false
+	}
+	calls := clientToolCalls(resp)
+	ct.SetPending(calls)
+	return len(calls) > 0
+}
+
+// clientToolRounds returns the client tool rounds of a continued turn.
+func clientToolRounds(ctx context.Context) []shared.ClientToolRound {
+	if ct := shared.GetClientTools(ctx); ct != nil {
+		return ct.Rounds
+	}
+	return nil
+}
+
+// clientToolRoundMessages converts the client tool rounds of a continued turn
+// into the model and tool messages that follow the user's prompt. Built-in
+// tool calls the model made in the same rounds are not replayed; the model
+// calls them again if it still needs them.
+func clientToolRoundMessages(rounds []shared.ClientToolRound) []*ai.Message {
+	var msgs []*ai.Message
+	for _, round := range rounds {
+		model := &ai.Message{Role: ai.RoleModel}
+		if round.Text != "" {
+			model.Content = append(model.Content, ai.NewTextPart(round.Text))
+		}
+		names := make(map[string]string, len(round.Calls))
+		for _, call := range round.Calls {
+			var input any
+			if err := json.Unmarshal([]byte(call.Arguments), &input); err != nil {
+				input = call.Arguments
+			}
+			model.Content = append(model.Content, ai.NewToolRequestPart(&ai.ToolRequest{Ref: call.ID, Name: call.Name, Input: input}))
+			names[call.ID] = call.Name
+		}
+		tool := &ai.Message{Role: ai.RoleTool}
+		for _, res := range round.Results {
+			tool.Content = append(tool.Content, ai.NewToolResponsePart(&ai.ToolResponse{Ref: res.CallID, Name: names[res.CallID], Output: res.Content}))
+		}
+		msgs = append(msgs, model, tool)
+	}
+	return msgs
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/basket/go-claw/internal/shared"
+	"github.com/basket/go-claw/internal/tools"
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+func TestClientTools_StopAndContinue(t *testing.T) {
+	ctx := context.Background()
+	g := genkit.Init(ctx)
+	var lastReq *ai.ModelRequest
+	genkit.DefineModel(g, "test/tools", &ai.ModelOptions{Supports: &ai.ModelSupports{Tools: true, Multiturn: true}},
+		func(_ context.Context, req *ai.ModelRequest, _ ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+			lastReq = req
+			last := req.Messages[len(req.Messages)-1]
+			if last.Role == ai.RoleTool {
+				return &ai.ModelResponse{Message: ai.NewModelTextMessage("Sunny in Paris"), FinishReason: ai.FinishReasonStop}, nil
+			}
+			return &ai.ModelResponse{
+				Message: ai.NewModelMessage(ai.NewToolRequestPart(&ai.ToolRequest{
+					Ref: "call_abc", Name: "get_weather", Input: map[string]any{"city": "Paris"},
+				})),
+				FinishReason: ai.FinishReasonStop,
+			}, nil
+		})
+
+	b := &GenkitBrain{toolsSupported: true, tools: &tools.Registry{}}
+	ct := &shared.ClientTools{Tools: []shared.ClientTool{{
+		Name:       "get_weather",
+		Parameters: map[string]any{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}},
+	}}}
+	ctx = shared.WithClientTools(ctx, ct)
+
+	toolOpts, err := b.toolOptions(ctx)
+	if err != nil {
+		t.Fatalf("toolOptions: %v", err)
+	}
+	opts := append([]ai.GenerateOption{ai.WithModelName("test/tools"), ai.WithPrompt("Weather in Paris?")}, toolOpts...)
+	resp, err := genkit.Generate(ctx, g, opts...)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if !recordClientToolCalls(ctx, resp) {
+		t.Fatalf("finish = %s, want a stop on the client tool", resp.FinishReason)
+	}
+	calls := ct.Pending()
+	if len(calls) != 1 || calls[0].ID != "call_abc" || calls[0].Name != "get_weather" || calls[0].Arguments != `{"city":"Paris"}` {
+		t.Fatalf("pending calls = %+v", calls)
+	}
+
+	// The client sends the result back and the turn continues.
+	ct.Rounds = []shared.ClientToolRound{{Calls: calls, Results: []shared.ClientToolResult{{CallID: "call_abc", Content: "sunny"}}}}
+	msgs := append([]*ai.Message{ai.NewUserTextMessage("Weather in Paris?")}, clientToolRoundMessages(ct.Rounds)...)
+	opts = append([]ai.GenerateOption{ai.WithModelName("test/tools"), ai.WithMessages(msgs...)}, toolOpts...)
+	resp, err = genkit.Generate(ctx, g, opts...)
+	if err != nil {
+		t.Fatalf("continue: %v", err)
+	}
+	if recordClientToolCalls(ctx, resp) || resp.Text() != "Sunny in Paris" {
+		t.Fatalf("continued reply = %q (%s)", resp.Text(), resp.FinishReason)
+	}
+	toolMsg := lastReq.Messages[len(lastReq.Messages)-1]
+	if got := toolMsg.Content[0].ToolResponse; got.Ref != "call_abc" || got.Name != "get_weather" || got.Output != "sunny" {
+		t.Errorf("tool response = %+v", got)
+	}
+}
+
+func TestClientTools_Options(t *testing.T) {
+	g := genkit.Init(context.Background())
+	var offered int
+	genkit.DefineModel(g, "test/offered", &ai.ModelOptions{Supports: &ai.ModelSupports{Tools: true}},
+		func(_ context.Context, req *ai.ModelRequest, _ ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+			offered = len(req.Tools)
+			return &ai.ModelResponse{Message: ai.NewModelTextMessage("ok"), FinishReason: ai.FinishReasonStop}, nil
+		})
+	builtin := genkit.DefineTool(g, "web_search", "search", func(*ai.ToolContext, struct{}) (string, error) { return "", nil })
+	reg := &tools.Registry{Tools: []ai.ToolRef{builtin}}
+	weather := shared.ClientTool{Name: "get_weather"}
+
+	tests := []struct {
+		name      string
+		supported bool
+		ct        *shared.ClientTools
+		wantTools int
+		wantErr   bool
+	}{
+		{"no client tools", true, nil, 1, false},
+		{"next to built-in tools", true, &shared.ClientTools{Tools: []shared.ClientTool{weather}}, 2, false},
+		{"instead of built-in tools", true, &shared.ClientTools{Tools: []shared.ClientTool{weather}, NoBuiltinTools: true}, 1, false},
+		{"tool choice none", true, &shared.ClientTools{Tools: []shared.ClientTool{weather}, Choice: shared.ToolChoiceNone}, 0, false},
+		{"name conflict", true, &shared.ClientTools{Tools: []shared.ClientTool{{Name: "web_search"}}}, 0, true},
+		{"model without tools", false, &shared.ClientTools{Tools: []shared.ClientTool{weather}}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &GenkitBrain{toolsSupported: tt.supported, tools: reg}
+			ctx := context.Background()
+			if tt.ct != nil {
+				ctx = shared.WithClientTools(ctx, tt.ct)
+			}
+			opts, err := b.toolOptions(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrClientTools) {
+					t.Errorf("err = %v, want ErrClientTools", err)
+				}
+				return
+			}
+			opts = append([]ai.GenerateOption{ai.WithModelName("test/offered"), ai.WithPrompt("hi")}, opts...)
+			if _, err := genkit.Generate(ctx, g, opts...); err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			if offered != tt.wantTools {
+				t.Errorf("tools offered = %d, want %d", offered, tt.wantTools)
+			}
+		})
+	}
+}
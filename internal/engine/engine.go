@@ -10,6 +10,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/basket/go-claw/internal/budget"
 	"github.com/basket/go-claw/internal/bus"
 	"github.com/basket/go-claw/internal/persistence"
 	"github.com/basket/go-claw/internal/policy"
@@ -26,7 +27,8 @@ type Config struct {
 	CancelCheckInterval time.Duration // interval for cooperative cancel checks (default: 10s)
 	MaxQueueDepth       int           // GC-SPEC-QUE-008: 0 = unlimited
 	Bus                 *bus.Bus
-	AgentID             string // if set, workers only claim tasks for this agent
+	AgentID             string           // if set, workers only claim tasks for this agent
+	Budget              *budget.Enforcer // refuses and fails tasks over a spend budget (nil = no budgets)
 }
 
 // Processor transforms a claimed task into a result string or error.
@@ -50,12 +52,16 @@ type EchoProcessor struct {
 }
 
 type chatTaskPayload struct {
-	Content      string `json:"content"`
-	MessageDepth int    `json:"message_depth,omitempty"`
+	Content      string              `json:"content"`
+	MessageDepth int                 `json:"message_depth,omitempty"`
+	MaxToolTurns int                 `json:"max_tool_turns,omitempty"` // per-request override, survives requeue
+	ClientTools  *shared.ClientTools `json:"client_tools,omitempty"`
 }
 
 type chatResultPayload struct {
 	Reply string `json:"reply"`
+	// ToolCalls are the client tool calls the turn stopped on.
+	ToolCalls []shared.ClientToolCall `json:"tool_calls,omitempty"`
 }
 
 func (p EchoProcessor) Process(ctx context.Context, task persistence.Task) (string, error) {
@@ -66,11 +72,18 @@ func (p EchoProcessor) Process(ctx context.Context, task persistence.Task) (stri
 	if p.Brain == nil {
 		return "", fmt.Errorf("brain not initialized")
 	}
+	if payload.ClientTools != nil {
+		ctx = shared.WithClientTools(ctx, payload.ClientTools)
+	}
 	reply, err := p.Brain.Respond(ctx, task.SessionID, payload.Content)
 	if err != nil {
 		return "", fmt.Errorf("brain respond: %w", err)
 	}
-	out, err := json.Marshal(chatResultPayload{Reply: reply})
+	result := chatResultPayload{Reply: reply}
+	if payload.ClientTools != nil {
+		result.ToolCalls = payload.ClientTools.Pending()
+	}
+	out, err := json.Marshal(result)
 	if err != nil {
 		return "", fmt.Errorf("encode result: %w", err)
 	}
@@ -260,10 +273,20 @@ func (e *Engine) handleTask(ctx context.Context, task persistence.Task) {
 	ctx = shared.WithAgentID(ctx, e.agentID)
 	// Propagate session_id so delegate_task can inherit the caller's session.
 	ctx = shared.WithSessionID(ctx, task.SessionID)
+	// Propagate the submitting API key so model calls and delegated tasks
+	// count against its budget.
+	if task.APIKeyID != "" {
+		ctx = shared.WithAPIKeyID(ctx, task.APIKeyID)
+	}
 	// Extract message depth from payload for inter-agent loop prevention.
 	var probe chatTaskPayload
-	if err := json.Unmarshal([]byte(task.Payload), &probe); err == nil && probe.MessageDepth > 0 {
-		ctx = shared.WithMessageDepth(ctx, probe.MessageDepth)
+	if err := json.Unmarshal([]byte(task.Payload), &probe); err == nil {
+		if probe.MessageDepth > 0 {
+			ctx = shared.WithMessageDepth(ctx, probe.MessageDepth)
+		}
+		if probe.MaxToolTurns > 0 {
+			ctx = shared.WithMaxToolTurns(ctx, probe.MaxToolTurns)
+		}
 	}
 	slog.Info("task processing", "task_id", task.ID, "session_id", task.SessionID, "trace_id", traceID, "run_id", runID, "agent_id", e.agentID)
 
@@ -273,6 +296,9 @@ func (e *Engine) handleTask(ctx context.Context, task persistence.Task) {
 	bgCtx := shared.WithTraceID(context.Background(), traceID)
 	bgCtx = shared.WithRunID(bgCtx, runID)
 
+	usage := &shared.UsageMeter{}
+	ctx = shared.WithUsageMeter(ctx, usage)
+
 	taskCtx, cancel := context.WithTimeout(ctx, e.config.TaskTimeout)
 	e.activeTasks.Add(1)
 	defer e.activeTasks.Add(-1)
@@ -299,6 +325,11 @@ func (e *Engine) handleTask(ctx context.Context, task persistence.Task) {
 		e.publishEvent("task.canceled", map[string]string{"task_id": task.ID, "session_id": task.SessionID})
 		return
 	}
+	// Tasks queued before a budget ran out fail without running.
+	if err := e.config.Budget.Check(bgCtx, budget.Subject{AgentID: task.AgentID, SessionID: task.SessionID, APIKeyID: task.APIKeyID}, shared.TokenUsage{}); err != nil {
+		e.failOverBudget(bgCtx, task, err)
+		return
+	}
 
 	go func() {
 		ticker := time.NewTicker(e.config.CancelCheckInterval)
@@ -326,6 +357,7 @@ func (e *Engine) handleTask(ctx context.Context, task persistence.Task) {
 	}()
 
 	result, err := e.proc.Process(taskCtx, task)
+	e.recordUsage(bgCtx, task.ID, usage)
 	if err != nil {
 		if errors.Is(taskCtx.Err(), context.DeadlineExceeded) {
 			err = fmt.Errorf("task timeout exceeded: %w", taskCtx.Err())
@@ -333,6 +365,11 @@ func (e *Engine) handleTask(ctx context.Context, task persistence.Task) {
 			_, _ = e.store.AbortTask(bgCtx, task.ID)
 			return
 		}
+		// Retrying cannot succeed before the budget resets.
+		if errors.Is(err, budget.ErrExceeded) {
+			e.failOverBudget(bgCtx, task, err)
+			return
+		}
 		e.setLastError(err)
 		slog.Warn("task failed", "task_id", task.ID, "session_id", task.SessionID, "trace_id", traceID, "run_id", runID, "agent_id", e.agentID, "error", err.Error())
 		_, _ = e.store.HandleTaskFailure(bgCtx, task.ID, err.Error())
@@ -393,6 +430,47 @@ func (e *Engine) handleTask(ctx context.Context, task persistence.Task) {
 	}
 }
 
+// recordUsage adds the token usage of a task's model calls to the task, so
+// retried attempts are counted too. Tasks without model calls are left alone.
+func (e *Engine) recordUsage(ctx context.Context, taskID string, meter *shared.UsageMeter) {
+	u, calls := meter.Total()
+	if calls == 0 {
+		return
+	}
+	prev, err := e.store.GetTaskUsage(ctx, taskID)
+	if err != nil {
+		slog.Warn("failed to read task usage", "task_id", taskID, "error", err)
+	}
+	if err := e.store.UpdateTaskTokens(ctx, taskID, persistence.TaskUsage{
+		PromptTokens:     prev.PromptTokens + u.InputTokens,
+		CompletionTokens: prev.CompletionTokens + u.OutputTokens,
+		CachedTokens:     prev.CachedTokens + u.CachedTokens,
+		ReasoningTokens:  prev.ReasoningTokens + u.ReasoningTokens,
+		CostUSD:          prev.CostUSD + u.CostUSD,
+	}); err != nil {
+		slog.Warn("failed to record task usage", "task_id", taskID, "error", err)
+	}
+}
+
+// failOverBudget fails a task that hit a spend budget, without retries.
+func (e *Engine) failOverBudget(ctx context.Context, task persistence.Task, err error) {
+	e.setLastError(err)
+	slog.Warn("task failed", "task_id", task.ID, "session_id", task.SessionID, "agent_id", e.agentID, "error", err.Error())
+	if failErr := e.store.FailTask(ctx, task.ID, err.Error()); failErr != nil {
+		slog.Error("failed to fail task over budget", "task_id", task.ID, "error", failErr)
+	}
+	e.publishEvent("task.failed", map[string]string{"task_id": task.ID, "session_id": task.SessionID})
+}
+
+// checkIntakeBudget refuses new work for an agent, session or API key whose
+// budget is exhausted.
+func (e *Engine) checkIntakeBudget(ctx context.Context, agentID, sessionID string) error {
+	if agentID == "" {
+		agentID = shared.DefaultAgentID
+	}
+	return e.config.Budget.Check(ctx, budget.Subject{AgentID: agentID, SessionID: sessionID, APIKeyID: shared.APIKeyID(ctx)}, shared.TokenUsage{})
+}
+
 // publishEvent publishes a task lifecycle event on the bus if configured.
 func (e *Engine) publishEvent(topic string, payload map[string]string) {
 	if e.bus != nil {
@@ -450,13 +528,16 @@ func (e *Engine) createChatTaskWithDepth(ctx context.Context, agentID, sessionID
 			return "", ErrQueueSaturated
 		}
 	}
+	if err := e.checkIntakeBudget(ctx, agentID, sessionID); err != nil {
+		return "", err
+	}
 	if err := e.store.EnsureSession(ctx, sessionID); err != nil {
 		return "", fmt.Errorf("create chat task: ensure session: %w", err)
 	}
-	if err := e.store.AddHistory(ctx, sessionID, agentID, "user", content, tokenutil.EstimateTokens(content)); err != nil {
+	if err := e.store.AddHistoryWithAttachments(ctx, sessionID, agentID, "user", content, tokenutil.EstimateTokens(content), shared.Attachments(ctx)); err != nil {
 		return "", fmt.Errorf("create chat task: add history: %w", err)
 	}
-	payload, err := json.Marshal(chatTaskPayload{Content: content, MessageDepth: messageDepth})
+	payload, err := json.Marshal(chatTaskPayload{Content: content, MessageDepth: messageDepth, MaxToolTurns: shared.MaxToolTurns(ctx), ClientTools: shared.GetClientTools(ctx)})
 	if err != nil {
 		return "", fmt.Errorf("create chat task: encode payload: %w", err)
 	}
@@ -494,14 +575,17 @@ func (e *Engine) streamChatTask(ctx context.Context, agentID, sessionID, content
 			return "", ErrQueueSaturated
 		}
 	}
+	if err := e.checkIntakeBudget(ctx, agentID, sessionID); err != nil {
+		return "", err
+	}
 	if err := e.store.EnsureSession(ctx, sessionID); err != nil {
 		return "", fmt.Errorf("stream chat task: ensure session: %w", err)
 	}
-	if err := e.store.AddHistory(ctx, sessionID, agentID, "user", content, tokenutil.EstimateTokens(content)); err != nil {
+	if err := e.store.AddHistoryWithAttachments(ctx, sessionID, agentID, "user", content, tokenutil.EstimateTokens(content), shared.Attachments(ctx)); err != nil {
 		return "", fmt.Errorf("stream chat task: add history: %w", err)
 	}
 
-	payload, err := json.Marshal(chatTaskPayload{Content: content})
+	payload, err := json.Marshal(chatTaskPayload{Content: content, MaxToolTurns: shared.MaxToolTurns(ctx), ClientTools: shared.GetClientTools(ctx)})
 	if err != nil {
 		return "", fmt.Errorf("stream chat task: encode payload: %w", err)
 	}
@@ -535,6 +619,8 @@ func (e *Engine) streamChatTask(ctx context.Context, agentID, sessionID, content
 	taskCtx = shared.WithTaskID(taskCtx, taskID)
 	taskCtx = shared.WithAgentID(taskCtx, agentID)
 	taskCtx = shared.WithSessionID(taskCtx, sessionID)
+	usage := &shared.UsageMeter{}
+	taskCtx = shared.WithUsageMeter(taskCtx, usage)
 
 	if e.proc == nil {
 		_, _ = e.store.HandleTaskFailure(bgCtx, taskID, "processor not initialized for streaming")
@@ -564,8 +650,15 @@ func (e *Engine) streamChatTask(ctx context.Context, agentID, sessionID, content
 		return onChunk(token)
 	}
 
-	if err := brain.Stream(taskCtx, sessionID, content, wrappedOnChunk); err != nil {
+	err = brain.Stream(taskCtx, sessionID, content, wrappedOnChunk)
+	e.recordUsage(bgCtx, taskID, usage)
+	if err != nil {
 		slog.Error("streaming failed", "error", err)
+		// The caller reports the budget error; the task fails on the
+		// workers' budget check.
+		if errors.Is(err, budget.ErrExceeded) {
+			return taskID, err
+		}
 		_, _ = e.store.HandleTaskFailure(bgCtx, taskID, err.Error())
 		return taskID, nil // task failure recorded; return taskID so caller can check status
 	}
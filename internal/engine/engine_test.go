@@ -4,12 +4,16 @@ import (
 	"context"
 	"errors"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/basket/go-claw/internal/budget"
+	"github.com/basket/go-claw/internal/config"
 	"github.com/basket/go-claw/internal/engine"
 	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/shared"
 )
 
 func openStoreForEngineTest(t *testing.T) *persistence.Store {
@@ -295,3 +299,70 @@ func TestEngine_BackpressureRejectsWhenQueueFull(t *testing.T) {
 		t.Fatalf("expected ErrQueueSaturated, got: %v", err)
 	}
 }
+
+func TestEngine_BudgetExhaustedFailsTasks(t *testing.T) {
+	store := openStoreForEngineTest(t)
+	ctx := context.Background()
+	sessionID := "0c3f4a8e-5b1d-4c2a-9e7f-222222222222"
+	if err := store.EnsureSession(ctx, sessionID); err != nil {
+		t.Fatalf("ensure session: %v", err)
+	}
+	spent, err := store.CreateTask(ctx, sessionID, `{"content":"earlier"}`)
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if err := store.UpdateTaskTokens(ctx, spent, persistence.TaskUsage{PromptTokens: 900, CompletionTokens: 200}); err != nil {
+		t.Fatalf("update tokens: %v", err)
+	}
+	// Queued before the budget was configured.
+	queued, err := store.CreateTask(ctx, sessionID, `{"content":"later"}`)
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	enforcer := budget.New(store, nil)
+	enforcer.Configure(config.Config{SessionBudget: &config.BudgetConfig{DailyTokens: 1000}})
+	proc := &countingProcessor{}
+	eng := engine.New(store, proc, engine.Config{
+		WorkerCount:  1,
+		PollInterval: 5 * time.Millisecond,
+		Budget:       enforcer,
+	})
+
+	if _, err := eng.CreateChatTask(ctx, sessionID, "refused"); !errors.Is(err, budget.ErrExceeded) {
+		t.Fatalf("CreateChatTask() = %v, want budget.ErrExceeded", err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	eng.Start(runCtx)
+	task := waitForTaskStatus(t, store, queued, persistence.TaskStatusFailed, 5*time.Second)
+	if !strings.Contains(task.Error, "budget exceeded") {
+		t.Errorf("task error = %q, want a budget error", task.Error)
+	}
+	if task.Attempt > 1 || proc.maxObserved.Load() != 0 {
+		t.Errorf("task ran or retried (attempt %d, processed %d)", task.Attempt, proc.maxObserved.Load())
+	}
+}
+
+func TestEngine_CreateChatTaskStoresAttachments(t *testing.T) {
+	store := openStoreForEngineTest(t)
+	eng := engine.New(store, blockingProcessor{}, engine.Config{WorkerCount: 1})
+	sessionID := "7b0c9f3e-5d6a-4f1e-9c1b-2a3d4e5f6a7b"
+	atts := []shared.Attachment{{MimeType: "image/png", Name: "cat.png", Data: []byte("png-bytes")}}
+
+	ctx := shared.WithAttachments(context.Background(), atts)
+	if _, err := eng.CreateChatTask(ctx, sessionID, "what is this?"); err != nil {
+		t.Fatalf("CreateChatTask: %v", err)
+	}
+	history, err := store.ListHistory(context.Background(), sessionID, "", 10)
+	if err != nil {
+		t.Fatalf("ListHistory: %v", err)
+	}
+	if len(history) != 1 || len(history[0].Attachments) != 1 {
+		t.Fatalf("history = %+v, want one message with one attachment", history)
+	}
+	if got := history[0].Attachments[0]; got.Name != "cat.png" || string(got.Data) != "png-bytes" {
+		t.Errorf("attachment = %+v", got)
+	}
+}
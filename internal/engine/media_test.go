@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/shared"
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+func TestHistoryToMessages_Attachments(t *testing.T) {
+	history := []persistence.HistoryItem{
+		{Role: "user", Content: "what is this?", Attachments: []shared.Attachment{
+			{MimeType: "image/png", Name: "cat.png", Data: []byte("png")},
+			{MimeType: "text/plain", Name: "notes.txt", Data: []byte("buy milk")},
+		}},
+		{Role: "assistant", Content: "a cat"},
+	}
+
+	msgs := historyToMessages(history, true)
+	if len(msgs) != 2 || len(msgs[0].Content) != 3 {
+		t.Fatalf("messages = %+v, want a user message with text, media and file parts", msgs)
+	}
+	if p := msgs[0].Content[1]; !p.IsMedia() || p.ContentType != "image/png" || p.Text != "data:image/png;base64,cG5n" {
+		t.Errorf("media part = %+v", p)
+	}
+	if p := msgs[0].Content[2]; !p.IsText() || !strings.Contains(p.Text, "notes.txt") || !strings.Contains(p.Text, "buy milk") {
+		t.Errorf("text document part = %+v", p)
+	}
+
+	msgs = historyToMessages(history, false)
+	for _, p := range msgs[0].Content {
+		if p.IsMedia() {
+			t.Fatal("media part sent to a model without media support")
+		}
+	}
+	if !strings.Contains(msgs[0].Content[1].Text, "omitted") {
+		t.Errorf("expected an omission note, got %q", msgs[0].Content[1].Text)
+	}
+}
+
+func TestCheckMediaSupport(t *testing.T) {
+	image := []shared.Attachment{{MimeType: "image/jpeg", Data: []byte("x")}}
+	text := []shared.Attachment{{MimeType: "text/markdown", Data: []byte("x")}}
+	tests := []struct {
+		name    string
+		media   bool
+		history []persistence.HistoryItem
+		wantErr bool
+	}{
+		{"vision model", true, []persistence.HistoryItem{{Role: "user", Attachments: image}}, false},
+		{"latest message has image", false, []persistence.HistoryItem{{Role: "user", Attachments: image}, {Role: "assistant"}}, true},
+		{"only older image", false, []persistence.HistoryItem{{Role: "user", Attachments: image}, {Role: "user"}}, false},
+		{"text document", false, []persistence.HistoryItem{{Role: "user", Attachments: text}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &GenkitBrain{mediaSupported: tt.media, cfg: BrainConfig{Provider: "ollama", Model: "llama3"}}
+			err := b.checkMediaSupport(tt.history)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrMediaUnsupported) {
+				t.Errorf("err = %v, want ErrMediaUnsupported", err)
+			}
+		})
+	}
+}
+
+func TestModelSupportsMedia(t *testing.T) {
+	g := genkit.Init(context.Background())
+	noop := func(context.Context, *ai.ModelRequest, ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+		return &ai.ModelResponse{}, nil
+	}
+	genkit.DefineModel(g, "test/vision", &ai.ModelOptions{Supports: &ai.ModelSupports{Media: true}}, noop)
+	genkit.DefineModel(g, "test/text", &ai.ModelOptions{Supports: &ai.ModelSupports{Multiturn: true}}, noop)
+
+	if !modelSupportsMedia(g, "test/vision") {
+		t.Error("vision model reported without media support")
+	}
+	if modelSupportsMedia(g, "test/text") {
+		t.Error("text-only model reported with media support")
+	}
+	if !modelSupportsMedia(g, "test/unknown") {
+		t.Error("undescribed models should be assumed to accept media")
+	}
+}
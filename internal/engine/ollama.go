@@ -15,6 +15,12 @@ import (
 // baseURL should be the OpenAI-compat URL ending in /v1.
 // model may have an "ollama/" prefix which is stripped for the API call.
 func detectOllamaTools(baseURL, model string) bool {
+	return slices.Contains(detectOllamaCapabilities(baseURL, model), "tools")
+}
+
+// detectOllamaCapabilities returns the capabilities Ollama reports for a
+// model (e.g. "completion", "tools", "vision"). Returns nil on any error.
+func detectOllamaCapabilities(baseURL, model string) []string {
 	// Strip /v1 suffix to get native Ollama API URL.
 	ollamaURL := strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/v1")
 
@@ -25,28 +31,26 @@ func detectOllamaTools(baseURL, model string) bool {
 	body := fmt.Sprintf(`{"model":%q}`, model)
 	resp, err := client.Post(ollamaURL+"/api/show", "application/json", strings.NewReader(body))
 	if err != nil {
-		slog.Debug("ollama tool detection failed (connection)", "error", err, "model", model)
-		return false
+		slog.Debug("ollama capability detection failed (connection)", "error", err, "model", model)
+		return nil
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		slog.Debug("ollama tool detection failed (status)", "status", resp.StatusCode, "model", model)
-		return false
+		slog.Debug("ollama capability detection failed (status)", "status", resp.StatusCode, "model", model)
+		return nil
 	}
 
 	var result struct {
 		Capabilities []string `json:"capabilities"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		slog.Debug("ollama tool detection failed (decode)", "error", err, "model", model)
-		return false
+		slog.Debug("ollama capability detection failed (decode)", "error", err, "model", model)
+		return nil
 	}
 
-	if slices.Contains(result.Capabilities, "tools") {
-		slog.Info("ollama model supports tools", "model", model)
-		return true
-	}
-	slog.Info("ollama model does not support tools", "model", model, "capabilities", result.Capabilities)
-	return false
+	slog.Info("ollama model capabilities", "model", model,
+		"tools", slices.Contains(result.Capabilities, "tools"),
+		"vision", slices.Contains(result.Capabilities, "vision"))
+	return result.Capabilities
 }
@@ -73,3 +73,20 @@ func TestDetectOllamaTools_StripsPrefix(t *testing.T) {
 		t.Fatalf("model sent to Ollama = %q, want qwen3:8b (ollama/ prefix stripped)", receivedModel)
 	}
 }
+
+func TestDetectOllamaCapabilities_Vision(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"capabilities": []string{"completion", "vision"},
+		})
+	}))
+	defer srv.Close()
+
+	caps := detectOllamaCapabilities(srv.URL+"/v1", "llava:7b")
+	if len(caps) != 2 || caps[1] != "vision" {
+		t.Fatalf("capabilities = %v, want completion and vision", caps)
+	}
+	if detectOllamaTools(srv.URL+"/v1", "llava:7b") {
+		t.Error("expected tools NOT supported")
+	}
+}
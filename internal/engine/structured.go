@@ -115,6 +115,21 @@ func (sv *StructuredValidator) ValidateResponse(responseText string) (*Structure
 	}, nil
 }
 
+// ValidateOutput checks text against a JSON Schema in strict mode and returns
+// the JSON document found in it. It compiles the schema on every call, which
+// suits occasional checks such as plan step outputs.
+func ValidateOutput(schemaJSON json.RawMessage, text string) (string, error) {
+	sv, err := NewStructuredValidator(schemaJSON, 0, true)
+	if err != nil {
+		return "", err
+	}
+	res, err := sv.ValidateResponse(text)
+	if err != nil {
+		return "", err
+	}
+	return res.JSON, nil
+}
+
 // extractJSON finds a JSON object or array in the response text.
 func extractJSON(text string) string {
 	// 1. Try fenced JSON block: ```json\n...\n```
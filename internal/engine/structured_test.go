@@ -503,3 +503,19 @@ func TestExtractJSON_FencedBlockWithExtraWhitespace(t *testing.T) {
 		t.Fatalf("not valid JSON: %q", got)
 	}
 }
+
+func TestValidateOutput(t *testing.T) {
+	got, err := ValidateOutput(testSchema, "Result:\n```json\n{\"category\": \"bug\", \"confidence\": 0.9}\n```")
+	if err != nil {
+		t.Fatalf("ValidateOutput: %v", err)
+	}
+	if got != `{"category": "bug", "confidence": 0.9}` {
+		t.Errorf("got %q, want the JSON document", got)
+	}
+	if _, err := ValidateOutput(testSchema, `{"category": "chore", "confidence": 0.9}`); err == nil {
+		t.Error("expected error for output not matching the schema")
+	}
+	if _, err := ValidateOutput(testSchema, "no json here"); err == nil {
+		t.Error("expected error for output without JSON")
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"context"
+	"strings"
+
+	"github.com/basket/go-claw/internal/budget"
+	"github.com/basket/go-claw/internal/pricing"
+	"github.com/basket/go-claw/internal/shared"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// usageMiddleware records the usage of every model call, including each round
+// of a tool loop and fallback retries, into the request's usage meter. Calls
+// are refused once a spend budget is exhausted.
+func (b *GenkitBrain) usageMiddleware(next ai.ModelFunc) ai.ModelFunc {
+	return func(ctx context.Context, req *ai.ModelRequest, cb ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+		m := shared.GetUsageMeter(ctx)
+		var pending shared.TokenUsage
+		if m != nil {
+			pending, _ = m.Total()
+		}
+		if err := b.cfg.Budget.Check(ctx, budget.SubjectFromContext(ctx), pending); err != nil {
+			return nil, err
+		}
+		resp, err := next(ctx, req, cb)
+		if m != nil && resp != nil {
+			provider := strings.ToLower(strings.TrimSpace(b.cfg.Provider))
+			if provider == "" {
+				provider = "google"
+			}
+			model := strings.TrimSpace(b.cfg.Model)
+			if model == "" {
+				model = defaultModelForProvider(provider)
+			}
+			m.Add(callUsage(provider, model, resp.Usage))
+		}
+		return resp, err
+	}
+}
+
+// callUsage normalizes the usage a provider reported for one model call and
+// prices it. Input tokens include cached ones and output tokens include
+// reasoning ones, as OpenAI reports them.
+func callUsage(provider, model string, u *ai.GenerationUsage) shared.TokenUsage {
+	if u == nil {
+		return shared.TokenUsage{}
+	}
+	out := shared.TokenUsage{
+		InputTokens:     u.InputTokens,
+		OutputTokens:    u.OutputTokens,
+		CachedTokens:    u.CachedContentTokens,
+		ReasoningTokens: u.ThoughtsTokens,
+	}
+	// Gemini counts thinking tokens apart from the candidates; they are billed
+	// as output.
+	if provider == "google" {
+		out.OutputTokens += u.ThoughtsTokens
+	}
+	// Some OpenAI-compatible servers report no usage when streaming; fall back
+	// to the character counts Genkit fills in (about 4 characters a token).
+	if out.InputTokens == 0 && out.OutputTokens == 0 {
+		out.InputTokens = (u.InputCharacters + 3) / 4
+		out.OutputTokens = (u.OutputCharacters + 3) / 4
+	}
+	out.CostUSD = pricing.Cost(provider, model, out.InputTokens, out.CachedTokens, out.OutputTokens)
+	return out
+}
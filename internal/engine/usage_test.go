@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/basket/go-claw/internal/budget"
+	"github.com/basket/go-claw/internal/config"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/shared"
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+func TestCallUsage(t *testing.T) {
+	tests := []struct {
+		name            string
+		provider, model string
+		usage           *ai.GenerationUsage
+		want            shared.TokenUsage
+	}{
+		{"no usage", "google", "gemini-2.5-flash", nil, shared.TokenUsage{}},
+		{
+			"gemini thinking billed as output", "google", "gemini-2.5-flash",
+			&ai.GenerationUsage{InputTokens: 1_000_000, OutputTokens: 600_000, ThoughtsTokens: 400_000, CachedContentTokens: 500_000},
+			shared.TokenUsage{InputTokens: 1_000_000, OutputTokens: 1_000_000, CachedTokens: 500_000, ReasoningTokens: 400_000, CostUSD: 0.5*0.30 + 0.5*0.03 + 2.50},
+		},
+		{
+			"openai reasoning already in output", "openai", "o3",
+			&ai.GenerationUsage{InputTokens: 1_000_000, OutputTokens: 1_000_000, ThoughtsTokens: 800_000},
+			shared.TokenUsage{InputTokens: 1_000_000, OutputTokens: 1_000_000, ReasoningTokens: 800_000, CostUSD: 2.00 + 8.00},
+		},
+		{
+			"estimated from characters", "ollama", "qwen3:8b",
+			&ai.GenerationUsage{InputCharacters: 400, OutputCharacters: 41},
+			shared.TokenUsage{InputTokens: 100, OutputTokens: 11},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := callUsage(tt.provider, tt.model, tt.usage)
+			if math.Abs(got.CostUSD-tt.want.CostUSD) > 1e-9 {
+				t.Errorf("cost = %f, want %f", got.CostUSD, tt.want.CostUSD)
+			}
+			got.CostUSD, tt.want.CostUSD = 0, 0
+			if got != tt.want {
+				t.Errorf("usage = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// defineToolLoopModel defines a model that calls the ping tool once, then
+// answers; each call reports 100 input and 10 output tokens.
+func defineToolLoopModel(g *genkit.Genkit, name string) ai.Tool {
+	genkit.DefineModel(g, name, &ai.ModelOptions{Supports: &ai.ModelSupports{Tools: true, Multiturn: true}},
+		func(_ context.Context, req *ai.ModelRequest, _ ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+			usage := &ai.GenerationUsage{InputTokens: 100, OutputTokens: 10}
+			if req.Messages[len(req.Messages)-1].Role == ai.RoleTool {
+				return &ai.ModelResponse{Message: ai.NewModelTextMessage("done"), FinishReason: ai.FinishReasonStop, Usage: usage}, nil
+			}
+			return &ai.ModelResponse{
+				Message:      ai.NewModelMessage(ai.NewToolRequestPart(&ai.ToolRequest{Name: "ping", Input: map[string]any{}})),
+				FinishReason: ai.FinishReasonStop,
+				Usage:        usage,
+			}, nil
+		})
+	return genkit.DefineTool(g, "ping", "ping", func(*ai.ToolContext, struct{}) (string, error) { return "pong", nil })
+}
+
+func TestUsageMiddleware_SumsToolRounds(t *testing.T) {
+	ctx := context.Background()
+	g := genkit.Init(ctx)
+	ping := defineToolLoopModel(g, "test/usage")
+
+	b := &GenkitBrain{cfg: BrainConfig{Provider: "openai", Model: "gpt-4o"}}
+	meter := &shared.UsageMeter{}
+	ctx = shared.WithUsageMeter(ctx, meter)
+	if _, err := genkit.Generate(ctx, g,
+		ai.WithModelName("test/usage"), ai.WithMiddleware(b.usageMiddleware),
+		ai.WithPrompt("ping it"), ai.WithTools(ping),
+	); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	total, calls := meter.Total()
+	if calls != 2 || total.InputTokens != 200 || total.OutputTokens != 20 {
+		t.Fatalf("usage = %+v over %d calls, want 200/20 over 2", total, calls)
+	}
+	if want := (200*2.50 + 20*10.00) / 1_000_000; math.Abs(total.CostUSD-want) > 1e-12 {
+		t.Errorf("cost = %g, want %g", total.CostUSD, want)
+	}
+}
+
+func TestUsageMiddleware_RefusesOverBudget(t *testing.T) {
+	ctx := context.Background()
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "goclaw.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	enforcer := budget.New(store, nil)
+	enforcer.Configure(config.Config{Agents: []config.AgentConfigEntry{
+		{AgentID: "coder", Budget: &config.BudgetConfig{DailyTokens: 100}},
+	}})
+
+	g := genkit.Init(ctx)
+	ping := defineToolLoopModel(g, "test/budget")
+	b := &GenkitBrain{cfg: BrainConfig{Provider: "openai", Model: "gpt-4o", Budget: enforcer}}
+	meter := &shared.UsageMeter{}
+	ctx = shared.WithUsageMeter(shared.WithAgentID(ctx, "coder"), meter)
+
+	// The first round is within budget; its 110 tokens exhaust it, so the
+	// round after the tool call is refused.
+	_, err = genkit.Generate(ctx, g,
+		ai.WithModelName("test/budget"), ai.WithMiddleware(b.usageMiddleware),
+		ai.WithPrompt("ping it"), ai.WithTools(ping),
+	)
+	if !errors.Is(err, budget.ErrExceeded) {
+		t.Fatalf("generate error = %v, want budget.ErrExceeded", err)
+	}
+	if _, calls := meter.Total(); calls != 1 {
+		t.Errorf("model calls = %d, want 1", calls)
+	}
+}
@@ -0,0 +1,507 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/basket/go-claw/internal/budget"
+	"github.com/basket/go-claw/internal/media"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/shared"
+	"github.com/basket/go-claw/internal/tokenutil"
+)
+
+// handleAnthropicMessages serves POST /v1/messages, the Anthropic Messages
+// API. Requests are routed, mapped to sessions and run like OpenAI chat
+// completions.
+func (s *Server) handleAnthropicMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.anthropicError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !s.authorizeAPIKey(r) {
+		s.anthropicError(w, http.StatusUnauthorized, "invalid x-api-key")
+		return
+	}
+
+	var req AnthropicMessagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.anthropicError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	clientTools, err := anthropicClientTools(req)
+	if err != nil {
+		s.anthropicError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	agentID := compatAgentID(req.Model)
+	user := ""
+	if req.Metadata != nil {
+		user = req.Metadata.UserID
+	}
+	sessionID := compatSessionID(user, agentID)
+
+	if len(req.Messages) == 0 {
+		s.anthropicError(w, http.StatusBadRequest, "messages: at least one message is required")
+		return
+	}
+	for i, msg := range req.Messages {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			s.anthropicError(w, http.StatusBadRequest, fmt.Sprintf("messages.%d: role must be user or assistant", i))
+			return
+		}
+	}
+	// A turn that stopped on tool_use is continued with user messages of
+	// tool_result blocks; the turn starts at the last user message without
+	// them.
+	turn := len(req.Messages) - 1
+	if last := req.Messages[turn]; last.Role != "user" {
+		s.anthropicError(w, http.StatusBadRequest, "messages: the last message must be from the user")
+		return
+	} else if last.Content.hasToolResults() {
+		for turn >= 0 && (req.Messages[turn].Role == "assistant" || req.Messages[turn].Content.hasToolResults()) {
+			turn--
+		}
+		if turn < 0 {
+			s.anthropicError(w, http.StatusBadRequest, "messages: tool_result blocks must follow a user message")
+			return
+		}
+		if clientTools == nil {
+			s.anthropicError(w, http.StatusBadRequest, "messages: tool_result blocks require the tools of the request")
+			return
+		}
+		rounds, err := anthropicToolRounds(req.Messages[turn+1:])
+		if err != nil {
+			s.anthropicError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		clientTools.Rounds = rounds
+	}
+
+	lastMsg := req.Messages[turn]
+	prompt := lastMsg.Content.Text
+	atts, err := anthropicAttachments(lastMsg.Content)
+	if err != nil {
+		s.anthropicError(w, http.StatusBadRequest, fmt.Sprintf("messages.%d: %v", turn, err))
+		return
+	}
+	if media.NeedsVision(atts) && !s.cfg.Registry.SupportsMedia(agentID) {
+		s.anthropicError(w, http.StatusBadRequest,
+			fmt.Sprintf("The model of agent %q does not accept image or PDF input", agentID))
+		return
+	}
+	if strings.TrimSpace(prompt) == "" && len(atts) > 0 {
+		prompt = media.DefaultPrompt
+	}
+	if strings.TrimSpace(prompt) == "" {
+		s.anthropicError(w, http.StatusBadRequest, fmt.Sprintf("messages.%d: content is empty", turn))
+		return
+	}
+
+	// Seed the system prompt and prior messages into session history.
+	var prior []compatMessage
+	if req.System.Text != "" {
+		prior = append(prior, compatMessage{Role: "system", Text: req.System.Text})
+	}
+	for _, msg := range req.Messages[:turn] {
+		switch {
+		case msg.Content.hasToolResults():
+			prior = append(prior, compatMessage{Role: "tool", Text: toolResultsText(msg.Content)})
+		case msg.Role == "user":
+			// Attachments of earlier messages are kept when valid; a bad one
+			// only fails the request when it is on the new message.
+			atts, err := anthropicAttachments(msg.Content)
+			if err != nil {
+				slog.Warn("anthropic: dropping attachments of seeded message", "error", err, "session_id", sessionID)
+				atts = nil
+			}
+			prior = append(prior, compatMessage{Role: "user", Text: msg.Content.Text, Attachments: atts})
+		default:
+			prior = append(prior, compatMessage{Role: "assistant", Text: msg.Content.Text})
+		}
+	}
+	if err := s.seedCompatHistory(r.Context(), sessionID, agentID, prior); err != nil {
+		s.anthropicError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	traceID := shared.NewTraceID()
+	ctx := shared.WithTraceID(r.Context(), traceID)
+	if req.Temperature != nil || req.TopP != nil || req.TopK != nil || req.MaxTokens != nil || len(req.StopSequences) > 0 {
+		ctx = shared.WithSamplingConfig(ctx, &shared.SamplingConfig{
+			Temperature:     req.Temperature,
+			TopP:            req.TopP,
+			TopK:            req.TopK,
+			MaxOutputTokens: req.MaxTokens,
+			StopSequences:   req.StopSequences,
+		})
+	}
+	if req.MaxToolTurns != nil {
+		if *req.MaxToolTurns < 0 {
+			s.anthropicError(w, http.StatusBadRequest, "max_tool_turns must not be negative")
+			return
+		}
+		ctx = shared.WithMaxToolTurns(ctx, *req.MaxToolTurns)
+	}
+	if len(atts) > 0 {
+		ctx = shared.WithAttachments(ctx, atts)
+	}
+	if clientTools != nil {
+		ctx = shared.WithClientTools(ctx, clientTools)
+	}
+	if err := s.checkBudget(ctx, agentID, sessionID); err != nil {
+		s.anthropicError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+
+	inputTokens := tokenutil.EstimateTokens(prompt)
+	if req.Stream {
+		s.handleAnthropicStream(w, ctx, req, agentID, sessionID, prompt, "msg_"+traceID, inputTokens)
+		return
+	}
+	s.handleAnthropicNonStream(w, ctx, req, agentID, sessionID, prompt, inputTokens)
+}
+
+// handleAnthropicStream streams a message as Anthropic SSE events:
+// message_start, content blocks (text, then any tool_use) and message_delta
+// with the stop reason, ending with message_stop. Built-in tool calls are not
+// streamed; only client tools become tool_use blocks.
+func (s *Server) handleAnthropicStream(w http.ResponseWriter, ctx context.Context, req AnthropicMessagesRequest, agentID, sessionID, prompt, messageID string, inputTokens int) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.anthropicError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	var mu sync.Mutex
+	send := func(evt AnthropicStreamEvent) {
+		b, _ := json.Marshal(evt)
+		mu.Lock()
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Type, b)
+		flusher.Flush()
+		mu.Unlock()
+	}
+	index := func(i int) *int { return &i }
+
+	send(AnthropicStreamEvent{Type: "message_start", Message: &AnthropicMessagesResponse{
+		ID:      messageID,
+		Type:    "message",
+		Role:    "assistant",
+		Model:   req.Model,
+		Content: []AnthropicContentBlock{},
+		Usage:   AnthropicUsage{InputTokens: inputTokens},
+	}})
+
+	blocks := 0
+	textOpen := false
+	outputTokens := 0
+	taskID, err := s.cfg.Registry.StreamChatTask(ctx, agentID, sessionID, prompt, func(chunk string) error {
+		if !textOpen {
+			empty := ""
+			send(AnthropicStreamEvent{Type: "content_block_start", Index: index(blocks), ContentBlock: &AnthropicContentBlock{Type: "text", Text: &empty}})
+			textOpen = true
+		}
+		outputTokens += tokenutil.EstimateTokens(chunk)
+		send(AnthropicStreamEvent{Type: "content_block_delta", Index: index(blocks), Delta: &AnthropicStreamDelta{Type: "text_delta", Text: chunk}})
+		return nil
+	})
+	if err != nil {
+		slog.Error("anthropic stream error", "error", err)
+		send(AnthropicStreamEvent{Type: "error", Error: &AnthropicError{Type: "api_error", Message: err.Error()}})
+		return
+	}
+	if textOpen {
+		send(AnthropicStreamEvent{Type: "content_block_stop", Index: index(blocks)})
+		blocks++
+	}
+
+	stopReason := "end_turn"
+	if ct := shared.GetClientTools(ctx); ct != nil {
+		for _, call := range ct.Pending() {
+			stopReason = "tool_use"
+			send(AnthropicStreamEvent{Type: "content_block_start", Index: index(blocks), ContentBlock: &AnthropicContentBlock{
+				Type: "tool_use", ID: call.ID, Name: call.Name, Input: json.RawMessage(`{}`),
+			}})
+			send(AnthropicStreamEvent{Type: "content_block_delta", Index: index(blocks), Delta: &AnthropicStreamDelta{Type: "input_json_delta", PartialJSON: call.Arguments}})
+			send(AnthropicStreamEvent{Type: "content_block_stop", Index: index(blocks)})
+			blocks++
+		}
+	}
+
+	send(AnthropicStreamEvent{
+		Type:  "message_delta",
+		Delta: &AnthropicStreamDelta{StopReason: &stopReason},
+		Usage: anthropicUsage(s.compatUsage(ctx, taskID, inputTokens, outputTokens)),
+	})
+	send(AnthropicStreamEvent{Type: "message_stop"})
+}
+
+// handleAnthropicNonStream runs the message as a chat task and returns it
+// when the task finishes.
+func (s *Server) handleAnthropicNonStream(w http.ResponseWriter, ctx context.Context, req AnthropicMessagesRequest, agentID, sessionID, prompt string, inputTokens int) {
+	taskID, err := s.cfg.Registry.CreateChatTask(ctx, agentID, sessionID, prompt)
+	if errors.Is(err, budget.ErrExceeded) {
+		s.anthropicError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if err != nil {
+		s.anthropicError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	reply, err := s.awaitCompatReply(ctx, taskID)
+	var taskErr *chatTaskError
+	if errors.As(err, &taskErr) {
+		s.anthropicError(w, http.StatusInternalServerError, "Task failed: "+taskErr.reason)
+		return
+	}
+	if err != nil {
+		s.anthropicError(w, http.StatusGatewayTimeout, "Client closed connection")
+		return
+	}
+
+	resp := AnthropicMessagesResponse{
+		ID:      "msg_" + taskID,
+		Type:    "message",
+		Role:    "assistant",
+		Model:   req.Model,
+		Content: []AnthropicContentBlock{},
+		Usage:   *anthropicUsage(s.compatUsage(ctx, taskID, inputTokens, tokenutil.EstimateTokens(reply.Text))),
+	}
+	if reply.Text != "" {
+		resp.Content = append(resp.Content, AnthropicContentBlock{Type: "text", Text: &reply.Text})
+	}
+	stopReason := "end_turn"
+	for _, call := range reply.ToolCalls {
+		stopReason = "tool_use"
+		resp.Content = append(resp.Content, AnthropicContentBlock{
+			Type: "tool_use", ID: call.ID, Name: call.Name, Input: json.RawMessage(call.Arguments),
+		})
+	}
+	resp.StopReason = &stopReason
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Warn("anthropic: failed to write response", "error", err)
+	}
+}
+
+// anthropicUsage converts a task's usage to the Anthropic shape.
+func anthropicUsage(u persistence.TaskUsage) *AnthropicUsage {
+	return &AnthropicUsage{
+		InputTokens:          u.PromptTokens - u.CachedTokens,
+		OutputTokens:         u.CompletionTokens,
+		CacheReadInputTokens: u.CachedTokens,
+	}
+}
+
+// authorizeAPIKey accepts the gateway token as a bearer token or, as the
+// Anthropic SDKs send it, in the X-API-Key header.
+func (s *Server) authorizeAPIKey(r *http.Request) bool {
+	if s.authorize(r) {
+		return true
+	}
+	key := strings.TrimSpace(r.Header.Get("X-API-Key"))
+	return s.cfg.AuthToken != "" && key == s.cfg.AuthToken
+}
+
+func (s *Server) anthropicError(w http.ResponseWriter, status int, message string) {
+	// Derive the error type from HTTP status per the Anthropic API.
+	errType := "api_error"
+	switch status {
+	case http.StatusBadRequest, http.StatusMethodNotAllowed:
+		errType = "invalid_request_error"
+	case http.StatusUnauthorized:
+		errType = "authentication_error"
+	case http.StatusForbidden:
+		errType = "permission_error"
+	case http.StatusNotFound:
+		errType = "not_found_error"
+	case http.StatusTooManyRequests:
+		errType = "rate_limit_error"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	errResp := map[string]any{
+		"type":  "error",
+		"error": AnthropicError{Type: errType, Message: message},
+	}
+	if err := json.NewEncoder(w).Encode(errResp); err != nil {
+		slog.Warn("anthropic: failed to write error response", "error", err)
+	}
+}
+
+// anthropicClientTools returns the client tool settings of a request, or nil
+// when it leaves the agent's tools as they are.
+func anthropicClientTools(req AnthropicMessagesRequest) (*shared.ClientTools, error) {
+	ct := &shared.ClientTools{NoBuiltinTools: req.BuiltinTools != nil && !*req.BuiltinTools}
+	seen := make(map[string]bool, len(req.Tools))
+	for i, t := range req.Tools {
+		if t.Type != "" && t.Type != "custom" {
+			return nil, fmt.Errorf("tools.%d: unsupported tool type %q", i, t.Type)
+		}
+		if !clientToolNamePattern.MatchString(t.Name) {
+			return nil, fmt.Errorf("tools.%d: name must be 1-64 letters, digits, underscores or dashes", i)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("tools.%d: duplicate tool %q", i, t.Name)
+		}
+		seen[t.Name] = true
+		ct.Tools = append(ct.Tools, shared.ClientTool{Name: t.Name, Description: t.Description, Parameters: t.InputSchema})
+	}
+
+	if tc := req.ToolChoice; tc != nil {
+		switch tc.Type {
+		case "auto":
+			ct.Choice = shared.ToolChoiceAuto
+		case "any":
+			ct.Choice = shared.ToolChoiceRequired
+		case "none":
+			ct.Choice = shared.ToolChoiceNone
+		case "tool":
+			// A named tool is forced by offering only that tool.
+			if !seen[tc.Name] {
+				return nil, fmt.Errorf("tool_choice: unknown tool %q", tc.Name)
+			}
+			for _, t := range ct.Tools {
+				if t.Name == tc.Name {
+					ct.Tools = []shared.ClientTool{t}
+					break
+				}
+			}
+			ct.Choice = shared.ToolChoiceRequired
+			ct.NoBuiltinTools = true
+		default:
+			return nil, fmt.Errorf("tool_choice: unsupported type %q", tc.Type)
+		}
+	}
+
+	if len(ct.Tools) == 0 && ct.Choice == "" && !ct.NoBuiltinTools {
+		return nil, nil
+	}
+	return ct, nil
+}
+
+// anthropicToolRounds parses the messages that follow the user message of a
+// continued turn: each assistant message with tool_use blocks is followed by
+// a user message with a tool_result for every call.
+func anthropicToolRounds(msgs []AnthropicMessage) ([]shared.ClientToolRound, error) {
+	var rounds []shared.ClientToolRound
+	for i := 0; i < len(msgs); i += 2 {
+		round := shared.ClientToolRound{Text: msgs[i].Content.Text}
+		open := make(map[string]bool)
+		for _, b := range msgs[i].Content.Blocks {
+			if b.Type != "tool_use" {
+				continue
+			}
+			if b.ID == "" || b.Name == "" {
+				return nil, fmt.Errorf("messages: tool_use blocks need an id and a name")
+			}
+			args := string(b.Input)
+			if len(b.Input) == 0 {
+				args = "{}"
+			}
+			round.Calls = append(round.Calls, shared.ClientToolCall{ID: b.ID, Name: b.Name, Arguments: args})
+			open[b.ID] = true
+		}
+		if msgs[i].Role != "assistant" || len(round.Calls) == 0 {
+			return nil, fmt.Errorf("messages: tool_result blocks must follow an assistant message with tool_use blocks")
+		}
+		if i+1 >= len(msgs) || !msgs[i+1].Content.hasToolResults() {
+			return nil, fmt.Errorf("messages: every tool_use block needs a tool_result")
+		}
+		for _, b := range msgs[i+1].Content.Blocks {
+			switch b.Type {
+			case "tool_result":
+			case "text":
+				return nil, fmt.Errorf("messages: send text after the tool_result blocks in a new turn")
+			default:
+				continue
+			}
+			if !open[b.ToolUseID] {
+				return nil, fmt.Errorf("messages: tool_result for unknown or answered tool_use %q", b.ToolUseID)
+			}
+			delete(open, b.ToolUseID)
+			round.Results = append(round.Results, shared.ClientToolResult{CallID: b.ToolUseID, Content: toolResultText(b)})
+		}
+		if len(open) > 0 {
+			return nil, fmt.Errorf("messages: every tool_use block needs a tool_result")
+		}
+		rounds = append(rounds, round)
+	}
+	return rounds, nil
+}
+
+// toolResultText returns the text of a tool_result block.
+func toolResultText(b AnthropicBlock) string {
+	text := ""
+	if b.Content != nil {
+		text = b.Content.Text
+	}
+	if b.IsError {
+		return "Error: " + text
+	}
+	return text
+}
+
+// toolResultsText joins the tool results of a message, for history.
+func toolResultsText(c AnthropicContent) string {
+	var texts []string
+	for _, b := range c.Blocks {
+		if b.Type == "tool_result" {
+			texts = append(texts, toolResultText(b))
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// anthropicAttachments decodes the image and document blocks of a message
+// into attachments. Only inline data is accepted; URL sources are rejected.
+func anthropicAttachments(c AnthropicContent) ([]shared.Attachment, error) {
+	var atts []shared.Attachment
+	for i, b := range c.Blocks {
+		if b.Type != "image" && b.Type != "document" {
+			continue
+		}
+		if b.Source == nil {
+			return nil, fmt.Errorf("content.%d: source is required", i)
+		}
+		a := shared.Attachment{Name: b.Title, MimeType: b.Source.MediaType}
+		switch b.Source.Type {
+		case "base64":
+			data, err := base64.StdEncoding.DecodeString(b.Source.Data)
+			if err != nil {
+				return nil, fmt.Errorf("content.%d: decode source data: %w", i, err)
+			}
+			a.Data = data
+		case "text":
+			if a.MimeType == "" {
+				a.MimeType = "text/plain"
+			}
+			a.Data = []byte(b.Source.Data)
+		case "url":
+			return nil, fmt.Errorf("content.%d: url sources are not supported; send base64 data", i)
+		default:
+			return nil, fmt.Errorf("content.%d: unsupported source type %q", i, b.Source.Type)
+		}
+		if len(atts) == media.MaxAttachments {
+			return nil, fmt.Errorf("too many attachments; the limit is %d", media.MaxAttachments)
+		}
+		prepared, err := media.Prepare(a)
+		if err != nil {
+			return nil, fmt.Errorf("content.%d: %w", i, err)
+		}
+		atts = append(atts, prepared)
+	}
+	return atts, nil
+}
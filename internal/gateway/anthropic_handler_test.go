@@ -0,0 +1,220 @@
+package gateway_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/engine"
+	"github.com/basket/go-claw/internal/gateway"
+)
+
+// anthropicTestServer runs a gateway whose engine answers with clientToolBrain.
+func anthropicTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	store := openStoreForGatewayTest(t)
+	eng := engine.New(store, engine.EchoProcessor{Brain: clientToolBrain{}}, engine.Config{
+		WorkerCount:  1,
+		PollInterval: 5 * time.Millisecond,
+		TaskTimeout:  5 * time.Second,
+	})
+	runCtx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	eng.Start(runCtx)
+	srv := gateway.New(gateway.Config{
+		Store:     store,
+		Registry:  makeTestRegistry(store, eng),
+		Policy:    gatewayTestPolicy,
+		AuthToken: gatewayTestAuthToken,
+	})
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func postAnthropic(t *testing.T, ts *httptest.Server, body string) (int, []byte) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/messages", strings.NewReader(body))
+	req.Header.Set("X-API-Key", gatewayTestAuthToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, raw
+}
+
+func TestAnthropic_Message(t *testing.T) {
+	ts := anthropicTestServer(t)
+
+	status, raw := postAnthropic(t, ts, `{
+		"model": "agent:default",
+		"max_tokens": 256,
+		"system": [{"type": "text", "text": "Be brief."}],
+		"messages": [{"role": "user", "content": [{"type": "text", "text": "hello"}]}]
+	}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d: %s", status, raw)
+	}
+	var out gateway.AnthropicMessagesResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Type != "message" || out.Role != "assistant" || !strings.HasPrefix(out.ID, "msg_") {
+		t.Errorf("message = %+v", out)
+	}
+	if len(out.Content) != 1 || out.Content[0].Type != "text" || *out.Content[0].Text != "no tools" {
+		t.Errorf("content = %s", raw)
+	}
+	if out.StopReason == nil || *out.StopReason != "end_turn" {
+		t.Errorf("stop_reason = %v, want end_turn", out.StopReason)
+	}
+}
+
+func TestAnthropic_AuthError(t *testing.T) {
+	ts := anthropicTestServer(t)
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/messages",
+		strings.NewReader(`{"model": "goclaw-v1", "messages": [{"role": "user", "content": "hi"}]}`))
+	req.Header.Set("X-API-Key", "wrong")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", resp.StatusCode)
+	}
+	var out struct {
+		Type  string                 `json:"type"`
+		Error gateway.AnthropicError `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Type != "error" || out.Error.Type != "authentication_error" {
+		t.Errorf("error = %+v", out)
+	}
+}
+
+func TestAnthropic_ToolUse(t *testing.T) {
+	ts := anthropicTestServer(t)
+
+	const tools = `"tools": [{"name": "get_weather", "input_schema": {"type": "object"}}]`
+	const question = `{"role": "user", "content": "Weather in Paris?"}`
+	const followUp = `{"role": "assistant", "content": [{"type": "tool_use", "id": "call_w1", "name": "get_weather", "input": {"city": "Paris"}}]},
+		{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "call_w1", "content": "sunny"}]}`
+
+	// post returns the content blocks and stop reason of a message,
+	// assembling streamed events.
+	post := func(t *testing.T, stream bool, messages string) ([]gateway.AnthropicContentBlock, string) {
+		t.Helper()
+		status, raw := postAnthropic(t, ts, fmt.Sprintf(`{"model": "goclaw-v1", "max_tokens": 256, "stream": %v, %s, "messages": [%s]}`, stream, tools, messages))
+		if status != http.StatusOK {
+			t.Fatalf("status = %d: %s", status, raw)
+		}
+		if !stream {
+			var out gateway.AnthropicMessagesResponse
+			if err := json.Unmarshal(raw, &out); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			return out.Content, *out.StopReason
+		}
+		var blocks []gateway.AnthropicContentBlock
+		var stop string
+		var events []string
+		for _, line := range strings.Split(string(raw), "\n") {
+			data, ok := strings.CutPrefix(line, "data: ")
+			if !ok {
+				continue
+			}
+			var evt gateway.AnthropicStreamEvent
+			if err := json.Unmarshal([]byte(data), &evt); err != nil {
+				t.Fatalf("decode event %s: %v", data, err)
+			}
+			events = append(events, evt.Type)
+			switch evt.Type {
+			case "content_block_start":
+				blocks = append(blocks, *evt.ContentBlock)
+			case "content_block_delta":
+				b := &blocks[*evt.Index]
+				switch evt.Delta.Type {
+				case "text_delta":
+					text := *b.Text + evt.Delta.Text
+					b.Text = &text
+				case "input_json_delta":
+					b.Input = json.RawMessage(evt.Delta.PartialJSON)
+				}
+			case "message_delta":
+				stop = *evt.Delta.StopReason
+			}
+		}
+		if events[0] != "message_start" || events[len(events)-2] != "message_delta" || events[len(events)-1] != "message_stop" {
+			t.Errorf("events = %v", events)
+		}
+		return blocks, stop
+	}
+
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			blocks, stop := post(t, stream, question)
+			if stop != "tool_use" {
+				t.Fatalf("stop_reason = %q, want tool_use", stop)
+			}
+			if len(blocks) != 1 {
+				t.Fatalf("content = %+v, want one tool_use block", blocks)
+			}
+			if b := blocks[0]; b.Type != "tool_use" || b.ID != "call_w1" || b.Name != "get_weather" || string(b.Input) != `{"city":"Paris"}` {
+				t.Errorf("tool_use = %+v (input %s)", b, b.Input)
+			}
+
+			blocks, stop = post(t, stream, question+", "+followUp)
+			if stop != "end_turn" || len(blocks) != 1 || blocks[0].Type != "text" || *blocks[0].Text != "Weather: sunny" {
+				t.Errorf("continued turn = %+v (%s), want the answer", blocks, stop)
+			}
+		})
+	}
+}
+
+func TestAnthropic_Rejected(t *testing.T) {
+	ts := anthropicTestServer(t)
+	const weather = `{"name": "get_weather"}`
+	const call = `{"role": "assistant", "content": [{"type": "tool_use", "id": "call_1", "name": "get_weather", "input": {}}]}`
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"no messages", `"messages": []`},
+		{"system role", `"messages": [{"role": "system", "content": "hi"}]`},
+		{"last message from assistant", `"messages": [{"role": "user", "content": "hi"}, {"role": "assistant", "content": "hello"}]`},
+		{"empty content", `"messages": [{"role": "user", "content": ""}]`},
+		{"url image", `"messages": [{"role": "user", "content": [{"type": "image", "source": {"type": "url", "url": "https://example.com/a.png"}}]}]`},
+		{"unsupported tool type", `"tools": [{"type": "bash_20250124", "name": "bash"}], "messages": [{"role": "user", "content": "hi"}]`},
+		{"invalid tool name", `"tools": [{"name": "get weather"}], "messages": [{"role": "user", "content": "hi"}]`},
+		{"duplicate tool", `"tools": [` + weather + `, ` + weather + `], "messages": [{"role": "user", "content": "hi"}]`},
+		{"tool choice of unknown tool", `"tools": [` + weather + `], "tool_choice": {"type": "tool", "name": "get_time"}, "messages": [{"role": "user", "content": "hi"}]`},
+		{"tool result without tools", `"messages": [{"role": "user", "content": "hi"}, ` + call + `, {"role": "user", "content": [{"type": "tool_result", "tool_use_id": "call_1", "content": "x"}]}]`},
+		{"tool result for unknown call", `"tools": [` + weather + `], "messages": [{"role": "user", "content": "hi"}, ` + call + `, {"role": "user", "content": [{"type": "tool_result", "tool_use_id": "call_2", "content": "x"}]}]`},
+		{"tool result mixed with text", `"tools": [` + weather + `], "messages": [{"role": "user", "content": "hi"}, ` + call + `, {"role": "user", "content": [{"type": "tool_result", "tool_use_id": "call_1", "content": "x"}, {"type": "text", "text": "and?"}]}]`},
+		{"negative max tool turns", `"max_tool_turns": -1, "messages": [{"role": "user", "content": "hi"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, raw := postAnthropic(t, ts, `{"model": "goclaw-v1", "max_tokens": 64, `+tt.body+`}`)
+			if status != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", status, raw)
+			}
+			if !strings.Contains(string(raw), `"invalid_request_error"`) {
+				t.Errorf("body = %s, want an invalid_request_error", raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package gateway
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// AnthropicMessagesRequest represents an Anthropic-compatible Messages API
+// request.
+type AnthropicMessagesRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens *int               `json:"max_tokens,omitempty"`
+	System    AnthropicContent   `json:"system,omitempty"`
+	Messages  []AnthropicMessage `json:"messages"`
+	Stream    bool               `json:"stream,omitempty"`
+	Metadata  *AnthropicMetadata `json:"metadata,omitempty"`
+
+	// Sampling parameters.
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	TopK          *int     `json:"top_k,omitempty"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
+
+	// Client tools, offered next to the agent's built-in tools.
+	Tools      []AnthropicTool      `json:"tools,omitempty"`
+	ToolChoice *AnthropicToolChoice `json:"tool_choice,omitempty"`
+
+	// BuiltinTools and MaxToolTurns are the non-standard extensions of
+	// ChatCompletionRequest.
+	BuiltinTools *bool `json:"builtin_tools,omitempty"`
+	MaxToolTurns *int  `json:"max_tool_turns,omitempty"`
+}
+
+// AnthropicMetadata carries the user ID that selects the session.
+type AnthropicMetadata struct {
+	UserID string `json:"user_id,omitempty"`
+}
+
+// AnthropicMessage is a user or assistant message of the conversation.
+type AnthropicMessage struct {
+	Role    string           `json:"role"`
+	Content AnthropicContent `json:"content"`
+}
+
+// AnthropicContent holds message content. Text joins the text blocks; Blocks
+// keeps every block when the content was sent as an array.
+type AnthropicContent struct {
+	Text   string
+	Blocks []AnthropicBlock
+}
+
+// UnmarshalJSON accepts a string, null, or an array of content blocks.
+func (c *AnthropicContent) UnmarshalJSON(data []byte) error {
+	*c = AnthropicContent{}
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &c.Text)
+	}
+	if err := json.Unmarshal(data, &c.Blocks); err != nil {
+		return err
+	}
+	var texts []string
+	for _, b := range c.Blocks {
+		if b.Type == "text" {
+			texts = append(texts, b.Text)
+		}
+	}
+	c.Text = strings.Join(texts, "\n")
+	return nil
+}
+
+// hasToolResults reports whether the content answers tool calls.
+func (c AnthropicContent) hasToolResults() bool {
+	for _, b := range c.Blocks {
+		if b.Type == "tool_result" {
+			return true
+		}
+	}
+	return false
+}
+
+// AnthropicBlock is one content block of a request: "text", "image",
+// "document", "tool_use" or "tool_result".
+type AnthropicBlock struct {
+	Type   string           `json:"type"`
+	Text   string           `json:"text,omitempty"`
+	Source *AnthropicSource `json:"source,omitempty"`
+	Title  string           `json:"title,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string            `json:"tool_use_id,omitempty"`
+	Content   *AnthropicContent `json:"content,omitempty"`
+	IsError   bool              `json:"is_error,omitempty"`
+}
+
+// AnthropicSource is the data of an image or document block.
+type AnthropicSource struct {
+	Type      string `json:"type"` // "base64", "text" or "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// AnthropicTool is a client tool definition.
+type AnthropicTool struct {
+	Type        string         `json:"type,omitempty"` // empty or "custom"
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema,omitempty"`
+}
+
+// AnthropicToolChoice selects how the model may use tools: "auto", "any",
+// "tool" (the named one) or "none".
+type AnthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// AnthropicMessagesResponse represents a Messages API response, and the
+// message of a message_start event.
+type AnthropicMessagesResponse struct {
+	ID           string                  `json:"id"`
+	Type         string                  `json:"type"` // always "message"
+	Role         string                  `json:"role"`
+	Model        string                  `json:"model"`
+	Content      []AnthropicContentBlock `json:"content"`
+	StopReason   *string                 `json:"stop_reason"`
+	StopSequence *string                 `json:"stop_sequence"`
+	Usage        AnthropicUsage          `json:"usage"`
+}
+
+// AnthropicContentBlock is one content block of a response: "text" or
+// "tool_use".
+type AnthropicContentBlock struct {
+	Type  string          `json:"type"`
+	Text  *string         `json:"text,omitempty"`
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// AnthropicUsage represents token usage statistics. As in the Anthropic API,
+// InputTokens excludes the tokens read from cache.
+type AnthropicUsage struct {
+	InputTokens          int `json:"input_tokens"`
+	OutputTokens         int `json:"output_tokens"`
+	CacheReadInputTokens int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// AnthropicStreamEvent is the payload of a streaming event. Type is also the
+// SSE event name.
+type AnthropicStreamEvent struct {
+	Type         string                     `json:"type"`
+	Message      *AnthropicMessagesResponse `json:"message,omitempty"`
+	Index        *int                       `json:"index,omitempty"`
+	ContentBlock *AnthropicContentBlock     `json:"content_block,omitempty"`
+	Delta        *AnthropicStreamDelta      `json:"delta,omitempty"`
+	Usage        *AnthropicUsage            `json:"usage,omitempty"`
+	Error        *AnthropicError            `json:"error,omitempty"`
+}
+
+// AnthropicStreamDelta is the delta of a content_block_delta ("text_delta"
+// or "input_json_delta") or message_delta event.
+type AnthropicStreamDelta struct {
+	Type         string  `json:"type,omitempty"`
+	Text         string  `json:"text,omitempty"`
+	PartialJSON  string  `json:"partial_json,omitempty"`
+	StopReason   *string `json:"stop_reason,omitempty"`
+	StopSequence *string `json:"stop_sequence,omitempty"`
+}
+
+// AnthropicError is the error object of error responses and events.
+type AnthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
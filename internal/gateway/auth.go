@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/basket/go-claw/internal/config"
+	"github.com/basket/go-claw/internal/shared"
 )
 
 // authContextKey is the context key type for authenticated API key entries.
@@ -38,8 +39,10 @@ func (am *AuthMiddleware) Wrap(next http.Handler) http.Handler {
 		return next
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip auth for health check and metrics endpoints.
-		if r.URL.Path == "/healthz" || r.URL.Path == "/metrics" || r.URL.Path == "/metrics/prometheus" {
+		// Skip auth for health check and metrics endpoints, and for inbound
+		// webhooks, which are verified by their signatures or secret token.
+		if r.URL.Path == "/healthz" || r.URL.Path == "/metrics" || r.URL.Path == "/metrics/prometheus" ||
+			strings.HasPrefix(r.URL.Path, "/webhooks/") || r.URL.Path == "/telegram/webhook" {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -59,8 +62,10 @@ func (am *AuthMiddleware) Wrap(next http.Handler) http.Handler {
 			return
 		}
 
-		// Inject key entry into context for downstream handlers.
+		// Inject key entry into context for downstream handlers, and its ID so
+		// the tasks it submits count against the key's budget.
 		ctx := context.WithValue(r.Context(), authContextKey{}, entry)
+		ctx = shared.WithAPIKeyID(ctx, entry.ID())
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
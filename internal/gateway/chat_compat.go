@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/basket/go-claw/internal/budget"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/shared"
+	"github.com/basket/go-claw/internal/tokenutil"
+	"github.com/google/uuid"
+)
+
+// The OpenAI- and Anthropic-compatible endpoints share agent routing, session
+// mapping and task handling; only the request and response shapes differ.
+
+// chatTaskError reports a chat task that did not succeed.
+type chatTaskError struct {
+	reason string
+}
+
+func (e *chatTaskError) Error() string { return "task failed: " + e.reason }
+
+// checkBudget refuses a request whose agent, session or API key has exhausted
+// its spend budget, before any response is started.
+func (s *Server) checkBudget(ctx context.Context, agentID, sessionID string) error {
+	return s.cfg.Budget.Check(ctx, budget.Subject{AgentID: agentID, SessionID: sessionID, APIKeyID: shared.APIKeyID(ctx)}, shared.TokenUsage{})
+}
+
+// compatAgentID routes a model name to an agent: "agent:<id>" selects that
+// agent, any other name the default agent.
+func compatAgentID(model string) string {
+	if id, ok := strings.CutPrefix(model, "agent:"); ok {
+		return id
+	}
+	return "default"
+}
+
+// compatSessionID maps a client's user ID to a session per agent, so
+// conversations of the same user persist. Without a user ID every request
+// gets a new session.
+func compatSessionID(user, agentID string) string {
+	if user == "" {
+		return uuid.NewString()
+	}
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("goclaw:user:"+user+":agent:"+agentID)).String()
+}
+
+// compatMessage is a prior message of a stateless request.
+type compatMessage struct {
+	Role        string // system, user, assistant or tool
+	Text        string
+	Attachments []shared.Attachment
+}
+
+// seedCompatHistory replaces the agent's session history with the prior
+// messages of a request. The compatible APIs are stateless: the client sends
+// the full conversation each time, so history is replaced rather than
+// appended to.
+func (s *Server) seedCompatHistory(ctx context.Context, sessionID, agentID string, msgs []compatMessage) error {
+	if err := s.cfg.Store.EnsureSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("session init: %w", err)
+	}
+	if err := s.cfg.Store.ClearSessionMessages(ctx, sessionID, agentID); err != nil {
+		slog.Warn("compat api: failed to clear session history", "error", err, "session_id", sessionID)
+	}
+	for _, msg := range msgs {
+		_ = s.cfg.Store.AddHistoryWithAttachments(ctx, sessionID, agentID, msg.Role, msg.Text, tokenutil.EstimateTokens(msg.Text), msg.Attachments)
+	}
+	return nil
+}
+
+// compatReply is the outcome of a chat task: the reply text and the client
+// tool calls the turn stopped on.
+type compatReply struct {
+	Text      string
+	ToolCalls []shared.ClientToolCall
+}
+
+// awaitCompatReply polls a chat task until it finishes. There is no
+// artificial timeout: ctx ends when the client disconnects, and the engine's
+// task_timeout_seconds protects against runaway tasks.
+func (s *Server) awaitCompatReply(ctx context.Context, taskID string) (compatReply, error) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return compatReply{}, ctx.Err()
+		case <-ticker.C:
+			task, err := s.cfg.Store.GetTask(ctx, taskID)
+			if err != nil {
+				continue
+			}
+			switch task.Status {
+			case "SUCCEEDED":
+				var payload struct {
+					Reply     string                  `json:"reply"`
+					ToolCalls []shared.ClientToolCall `json:"tool_calls"`
+				}
+				if json.Unmarshal([]byte(task.Result), &payload) != nil {
+					return compatReply{Text: task.Result}, nil
+				}
+				return compatReply{Text: payload.Reply, ToolCalls: payload.ToolCalls}, nil
+			case "FAILED", "DEAD_LETTER", "CANCELED":
+				return compatReply{}, &chatTaskError{reason: task.Error}
+			}
+		}
+	}
+}
+
+// compatUsage returns the token usage recorded for a chat task. Tasks whose
+// model reported no usage, such as replies of the offline fallback brain,
+// get the given estimates instead.
+func (s *Server) compatUsage(ctx context.Context, taskID string, promptTokens, completionTokens int) persistence.TaskUsage {
+	u, err := s.cfg.Store.GetTaskUsage(ctx, taskID)
+	if err != nil {
+		slog.Warn("compat api: failed to read task usage", "error", err, "task_id", taskID)
+	}
+	if u.TotalTokens() == 0 {
+		return persistence.TaskUsage{PromptTokens: promptTokens, CompletionTokens: completionTokens}
+	}
+	return u
+}
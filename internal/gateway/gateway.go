@@ -14,11 +14,14 @@ import (
 	"time"
 
 	"github.com/basket/go-claw/internal/agent"
+	"github.com/basket/go-claw/internal/approval"
 	"github.com/basket/go-claw/internal/audit"
+	"github.com/basket/go-claw/internal/budget"
 	"github.com/basket/go-claw/internal/bus"
 	"github.com/basket/go-claw/internal/config"
 	"github.com/basket/go-claw/internal/coordinator"
 	"github.com/basket/go-claw/internal/engine"
+	"github.com/basket/go-claw/internal/mcp"
 	"github.com/basket/go-claw/internal/persistence"
 	"github.com/basket/go-claw/internal/policy"
 	"github.com/basket/go-claw/internal/shared"
@@ -35,9 +38,10 @@ const (
 	ErrCodeInternal       = -32603
 
 	// Stable app error taxonomy.
-	ErrCodeInvalid      = 1000
-	ErrCodeBackpressure = 4290 // GC-SPEC-QUE-008: queue saturated
-	ErrCodeLLM          = 4000
+	ErrCodeInvalid        = 1000
+	ErrCodeBackpressure   = 4290 // GC-SPEC-QUE-008: queue saturated
+	ErrCodeLLM            = 4000
+	ErrCodeBudgetExceeded = 4020 // spend budget exhausted
 
 	maxReplayEventsPerSubscribe = 64
 )
@@ -87,8 +91,26 @@ type Config struct {
 		MemoryStats() (uint32, map[string]uint32, uint32)
 	}
 
+	// MCP exposes MCP server prompts and resources over ACP (nil = unavailable).
+	MCP *mcp.Manager
+
+	// Approvals resolves parked tool calls through approval.respond/list (nil = unavailable).
+	Approvals *approval.Broker
+
 	// GatewaySecurity holds authentication, rate limiting, CORS, and request size config (v0.5).
 	GatewaySecurity config.GatewaySecurityConfig
+
+	// Budget reports spend against budgets on /api/budgets (nil = no budgets).
+	Budget *budget.Enforcer
+
+	// Webhooks serves inbound webhooks under /webhooks/ (nil = not mounted).
+	// Those requests authenticate with their signatures, not API keys.
+	Webhooks http.Handler
+
+	// TelegramWebhook receives Telegram updates at /telegram/webhook when the
+	// Telegram channel runs in webhook mode (nil = not mounted). Telegram
+	// authenticates with the channel's secret token, not API keys.
+	TelegramWebhook http.Handler
 }
 
 type Server struct {
@@ -184,6 +206,7 @@ func (s *Server) StartBackgroundTasks(ctx context.Context) {
 	if s.rateLimiter != nil {
 		s.rateLimiter.StartEviction(ctx, 5*time.Minute, 10*time.Minute)
 	}
+	s.forwardToolApprovals(ctx)
 }
 
 func (s *Server) Handler() http.Handler {
@@ -199,8 +222,17 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/api/sessions/", s.handleAPISessionMessages)
 	mux.HandleFunc("/api/skills", s.handleAPISkills)
 	mux.HandleFunc("/api/config", s.handleAPIConfig)
+	mux.HandleFunc("/api/budgets", s.handleAPIBudgets)
 	mux.HandleFunc("/api/plans", s.handleAPIPlansRoute)
 	mux.HandleFunc("/api/plans/", s.handleAPIPlansRoute)
+	mux.HandleFunc("/api/webhooks/deliveries", s.handleAPIWebhookDeliveries)
+	mux.HandleFunc("/api/webhooks/deliveries/", s.handleAPIWebhookDeliveries)
+	if s.cfg.Webhooks != nil {
+		mux.Handle("/webhooks/", s.cfg.Webhooks)
+	}
+	if s.cfg.TelegramWebhook != nil {
+		mux.Handle("/telegram/webhook", s.cfg.TelegramWebhook)
+	}
 
 	// SSE streaming endpoint (v0.5)
 	mux.HandleFunc("/api/v1/task/stream", s.handleTaskStream)
@@ -209,6 +241,9 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/v1/chat/completions", s.handleOpenAIChatCompletion)
 	mux.HandleFunc("/v1/models", s.handleOpenAIModels)
 
+	// Anthropic-compatible endpoint
+	mux.HandleFunc("/v1/messages", s.handleAnthropicMessages)
+
 	// A2A agent card endpoint
 	mux.HandleFunc("/.well-known/agent.json", s.handleAgentCard)
 
@@ -339,6 +374,13 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 		"bus_dropped_events":   busDroppedEvents,
 		"agents":               perAgent,
 	}
+	if usage, err := s.cfg.Store.UsageTotals(ctx, ""); err == nil {
+		payload["tokens_prompt_total"] = usage.PromptTokens
+		payload["tokens_completion_total"] = usage.CompletionTokens
+		payload["tokens_cached_total"] = usage.CachedTokens
+		payload["tokens_reasoning_total"] = usage.ReasoningTokens
+		payload["cost_usd_total"] = usage.CostUSD
+	}
 	if s.cfg.WasmHost != nil {
 		aggPages, perMod, limitPages := s.cfg.WasmHost.MemoryStats()
 		payload["wasm_memory_pages"] = aggPages
@@ -408,6 +450,17 @@ func (s *Server) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request)
 		fmt.Fprintf(w, "# TYPE goclaw_delegations_total counter\n")
 		fmt.Fprintf(w, "goclaw_delegations_total %d\n", delegations)
 	}
+	if usage, err := s.cfg.Store.UsageTotals(ctx, ""); err == nil {
+		fmt.Fprintf(w, "# HELP goclaw_tokens_total Total model tokens used, by kind.\n")
+		fmt.Fprintf(w, "# TYPE goclaw_tokens_total counter\n")
+		fmt.Fprintf(w, "goclaw_tokens_total{kind=\"prompt\"} %d\n", usage.PromptTokens)
+		fmt.Fprintf(w, "goclaw_tokens_total{kind=\"completion\"} %d\n", usage.CompletionTokens)
+		fmt.Fprintf(w, "goclaw_tokens_total{kind=\"cached\"} %d\n", usage.CachedTokens)
+		fmt.Fprintf(w, "goclaw_tokens_total{kind=\"reasoning\"} %d\n", usage.ReasoningTokens)
+		fmt.Fprintf(w, "# HELP goclaw_cost_usd_total Total model spend in USD.\n")
+		fmt.Fprintf(w, "# TYPE goclaw_cost_usd_total counter\n")
+		fmt.Fprintf(w, "goclaw_cost_usd_total %g\n", usage.CostUSD)
+	}
 	if s.cfg.Bus != nil {
 		fmt.Fprintf(w, "# HELP goclaw_bus_dropped_events_total Total events dropped due to full subscriber buffers.\n")
 		fmt.Fprintf(w, "# TYPE goclaw_bus_dropped_events_total counter\n")
@@ -487,7 +540,8 @@ func (s *Server) authorize(r *http.Request) bool {
 func isMutatingMethod(method string) bool {
 	switch method {
 	case "agent.chat", "agent.chat.stream", "agent.abort", "session.purge",
-		"agent.create", "agent.remove":
+		"agent.create", "agent.remove",
+		"plan.cancel", "plan.pause", "plan.resume", "plan.rerun":
 		return true
 	default:
 		return false
@@ -500,10 +554,12 @@ func requiredCapabilityForMethod(method string) string {
 		return "acp.mutate"
 	case "session.history", "session.list", "session.events.subscribe", "system.status", "approval.list",
 		"cron.list", "subtask.list", "agent.list", "agent.status", "incident.export",
-		"config.list":
+		"config.list", "mcp.prompts.list", "mcp.prompts.get", "mcp.resources.list", "mcp.resources.read",
+		"plan.status":
 		return "acp.read"
 	case "cron.add", "cron.remove", "cron.enable", "cron.disable", "subtask.create",
 		"agent.create", "agent.remove",
+		"plan.cancel", "plan.pause", "plan.resume", "plan.rerun",
 		"config.set", "config.model.set", "policy.domain.add":
 		return "acp.mutate"
 	default:
@@ -570,6 +626,8 @@ func (s *Server) handleRPC(ctx context.Context, c *client, req rpcRequest) *rpcR
 			Content   string `json:"content"`
 			Text      string `json:"text"`     // GC-SPEC-ACP-009: OpenClaw backward-compat alias.
 			AgentID   string `json:"agent_id"` // Optional, defaults to "default".
+			// Optional override of the agent's max_tool_turns for this message.
+			MaxToolTurns int `json:"max_tool_turns,omitempty"`
 		}
 		if err := json.Unmarshal(req.Params, &p); err != nil {
 			rpcErr = &rpcError{Code: ErrCodeInvalid, Message: "invalid params"}
@@ -583,6 +641,10 @@ func (s *Server) handleRPC(ctx context.Context, c *client, req rpcRequest) *rpcR
 			rpcErr = &rpcError{Code: ErrCodeInvalid, Message: "session_id must be uuid and content must be non-empty"}
 			break
 		}
+		if p.MaxToolTurns < 0 {
+			rpcErr = &rpcError{Code: ErrCodeInvalid, Message: "max_tool_turns must not be negative"}
+			break
+		}
 		agentID := p.AgentID
 		if agentID == "" {
 			agentID = shared.DefaultAgentID
@@ -590,10 +652,15 @@ func (s *Server) handleRPC(ctx context.Context, c *client, req rpcRequest) *rpcR
 		// GC-SPEC-RUN-004: Generate trace_id for this request.
 		traceID := shared.NewTraceID()
 		traceCtx := shared.WithTraceID(ctx, traceID)
+		if p.MaxToolTurns > 0 {
+			traceCtx = shared.WithMaxToolTurns(traceCtx, p.MaxToolTurns)
+		}
 		taskID, err := s.cfg.Registry.CreateChatTask(traceCtx, agentID, p.SessionID, p.Content)
 		if err != nil {
 			if errors.Is(err, engine.ErrQueueSaturated) {
 				rpcErr = &rpcError{Code: ErrCodeBackpressure, Message: "queue saturated; retry later"}
+			} else if errors.Is(err, budget.ErrExceeded) {
+				rpcErr = &rpcError{Code: ErrCodeBudgetExceeded, Message: err.Error()}
 			} else {
 				rpcErr = &rpcError{Code: ErrCodeLLM, Message: err.Error()}
 			}
@@ -607,6 +674,8 @@ func (s *Server) handleRPC(ctx context.Context, c *client, req rpcRequest) *rpcR
 			Content   string `json:"content"`
 			Text      string `json:"text"`     // GC-SPEC-ACP-009: OpenClaw backward-compat alias.
 			AgentID   string `json:"agent_id"` // Optional, defaults to "default".
+			// Optional override of the agent's max_tool_turns for this message.
+			MaxToolTurns int `json:"max_tool_turns,omitempty"`
 		}
 		if err := json.Unmarshal(req.Params, &p); err != nil {
 			rpcErr = &rpcError{Code: ErrCodeInvalid, Message: "invalid params"}
@@ -620,6 +689,10 @@ func (s *Server) handleRPC(ctx context.Context, c *client, req rpcRequest) *rpcR
 			rpcErr = &rpcError{Code: ErrCodeInvalid, Message: "session_id must be uuid and content must be non-empty"}
 			break
 		}
+		if p.MaxToolTurns < 0 {
+			rpcErr = &rpcError{Code: ErrCodeInvalid, Message: "max_tool_turns must not be negative"}
+			break
+		}
 		agentID := p.AgentID
 		if agentID == "" {
 			agentID = shared.DefaultAgentID
@@ -627,6 +700,9 @@ func (s *Server) handleRPC(ctx context.Context, c *client, req rpcRequest) *rpcR
 		// Generate trace_id for this request.
 		streamTraceID := shared.NewTraceID()
 		traceCtx := shared.WithTraceID(ctx, streamTraceID)
+		if p.MaxToolTurns > 0 {
+			traceCtx = shared.WithMaxToolTurns(traceCtx, p.MaxToolTurns)
+		}
 		streamTaskID, err := s.cfg.Registry.StreamChatTask(traceCtx, agentID, p.SessionID, p.Content, func(content string) error {
 			// Stream chunks back to client as notifications.
 			// task_id is sent in the initial RPC response, not in every chunk,
@@ -640,6 +716,8 @@ func (s *Server) handleRPC(ctx context.Context, c *client, req rpcRequest) *rpcR
 		if err != nil {
 			if errors.Is(err, engine.ErrQueueSaturated) {
 				rpcErr = &rpcError{Code: ErrCodeBackpressure, Message: "queue saturated; retry later"}
+			} else if errors.Is(err, budget.ErrExceeded) {
+				rpcErr = &rpcError{Code: ErrCodeBudgetExceeded, Message: err.Error()}
 			} else {
 				rpcErr = &rpcError{Code: ErrCodeLLM, Message: err.Error()}
 			}
@@ -795,12 +873,21 @@ func (s *Server) handleRPC(ctx context.Context, c *client, req rpcRequest) *rpcR
 		var p struct {
 			ApprovalID string `json:"approval_id"`
 			Decision   string `json:"decision"`
+			Reason     string `json:"reason"` // tool approvals: returned to the model on deny
 		}
 		if err := json.Unmarshal(req.Params, &p); err != nil || p.ApprovalID == "" {
 			rpcErr = &rpcError{Code: ErrCodeInvalid, Message: "invalid params"}
 			break
 		}
 		decision := strings.ToLower(strings.TrimSpace(p.Decision))
+		// IDs not held in memory belong to parked tool calls (GC-SPEC-SEC-008).
+		s.approvalsMu.Lock()
+		_, inMemory := s.approvals[p.ApprovalID]
+		s.approvalsMu.Unlock()
+		if !inMemory {
+			result, rpcErr = s.respondToolApproval(ctx, p.ApprovalID, decision, p.Reason)
+			break
+		}
 		if decision != "approve" && decision != "deny" {
 			rpcErr = &rpcError{Code: ErrCodeInvalid, Message: "decision must be approve or deny"}
 			break
@@ -850,6 +937,7 @@ func (s *Server) handleRPC(ctx context.Context, c *client, req rpcRequest) *rpcR
 			})
 		}
 		s.approvalsMu.Unlock()
+		items = append(items, s.toolApprovalItems(ctx)...)
 		result = map[string]any{"items": items}
 	case "system.status":
 		pending, running, err := s.cfg.Store.TaskCounts(ctx)
@@ -1251,12 +1339,25 @@ func (s *Server) handleRPC(ctx context.Context, c *client, req rpcRequest) *rpcR
 			rpcErr = &rpcError{Code: ErrCodeInternal, Message: err.Error()}
 			break
 		}
-		result = map[string]any{
-			"agent_id":     p.AgentID,
-			"worker_count": st.WorkerCount,
-			"active_tasks": st.ActiveTasks,
-			"last_error":   st.LastError,
+		profile, policyVersion := "", ""
+		if ra := s.cfg.Registry.GetAgent(p.AgentID); ra != nil {
+			profile = ra.Config.PolicyProfile
+			if ra.Policy != nil {
+				policyVersion = ra.Policy.PolicyVersion()
+			}
 		}
+		result = map[string]any{
+			"agent_id":       p.AgentID,
+			"worker_count":   st.WorkerCount,
+			"active_tasks":   st.ActiveTasks,
+			"last_error":     st.LastError,
+			"policy_profile": profile,
+			"policy_version": policyVersion,
+		}
+	case "mcp.prompts.list", "mcp.prompts.get", "mcp.resources.list", "mcp.resources.read":
+		result, rpcErr = s.handleMCPMethod(ctx, req.Method, req.Params)
+	case "plan.status", "plan.cancel", "plan.pause", "plan.resume", "plan.rerun":
+		result, rpcErr = s.handlePlanRPC(ctx, req.Method, req.Params)
 	default:
 		rpcErr = &rpcError{Code: ErrCodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
 	}
@@ -1625,8 +1726,19 @@ func (s *Server) handleAPITaskByID(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	calls, err := s.cfg.Store.ListToolCalls(r.Context(), taskID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if calls == nil {
+		calls = []persistence.ToolCall{}
+	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(task)
+	_ = json.NewEncoder(w).Encode(struct {
+		*persistence.Task
+		ToolCalls []persistence.ToolCall `json:"tool_calls"`
+	}{task, calls})
 }
 
 func (s *Server) handleAPISessions(w http.ResponseWriter, r *http.Request) {
@@ -1728,15 +1840,51 @@ func (s *Server) handleAPIConfig(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleAPIBudgets reports the current spend against each agent and API key
+// budget, and against the session budget for ?session_id=.
+func (s *Server) handleAPIBudgets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"budgets": s.cfg.Budget.Usage(r.Context(), r.URL.Query().Get("session_id")),
+	})
+}
+
 // PlanSummary is a lightweight view of a configured plan for the REST API.
 // GC-SPEC-PDR-v4-Phase-4: Plan system.
 type PlanSummary struct {
-	Name      string   `json:"name"`
-	StepCount int      `json:"step_count"`
-	AgentIDs  []string `json:"agent_ids"`
+	Name      string                  `json:"name"`
+	StepCount int                     `json:"step_count"`
+	AgentIDs  []string                `json:"agent_ids"`
+	Inputs    []coordinator.PlanInput `json:"inputs,omitempty"`
+	// Generated plans, written by agents with create_plan, also carry their
+	// goal and review status.
+	Generated bool   `json:"generated,omitempty"`
+	Goal      string `json:"goal,omitempty"`
+	Status    string `json:"status,omitempty"`
 }
 
-// handleAPIPlansRoute dispatches GET /api/plans (list) and POST /api/plans/{name}/execute (GC-SPEC-PDR-v4-Phase-4: Plan system).
+// handleAPIPlansRoute dispatches the plan endpoints (GC-SPEC-PDR-v4-Phase-4: Plan system):
+//
+//	GET  /api/plans                  list configured and generated plans
+//	GET  /api/plans/{name}           show a plan
+//	PUT  /api/plans/{name}           replace a generated plan's definition
+//	POST /api/plans/{name}/execute   run a plan
+//	POST /api/plans/{name}/approve   approve a generated plan and run it
+//	POST /api/plans/{name}/reject    reject a generated plan
+//
+//	GET  /api/plans/executions/{id}         show an execution and its steps
+//	POST /api/plans/executions/{id}/cancel  cancel a running execution
+//	POST /api/plans/executions/{id}/pause   stop starting new steps
+//	POST /api/plans/executions/{id}/resume  start steps again after a pause
+//	POST /api/plans/executions/{id}/rerun   re-run a finished execution from {"step_id"}
 func (s *Server) handleAPIPlansRoute(w http.ResponseWriter, r *http.Request) {
 	// GET /api/plans - list all plans
 	if (r.URL.Path == "/api/plans" || r.URL.Path == "/api/plans/") && r.Method == http.MethodGet {
@@ -1744,16 +1892,26 @@ func (s *Server) handleAPIPlansRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// POST /api/plans/{name}/execute - execute a plan
-	if strings.HasSuffix(r.URL.Path, "/execute") && r.Method == http.MethodPost {
+	name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/plans/"), "/")
+	switch {
+	case name == "executions":
+		s.handlePlanExecutionRoute(w, r, action)
+	case action == "execute" && r.Method == http.MethodPost:
 		s.handleExecutePlan(w, r)
-		return
+	case action == "approve" && r.Method == http.MethodPost:
+		s.handleApprovePlan(w, r, name)
+	case action == "reject" && r.Method == http.MethodPost:
+		s.handleRejectPlan(w, r, name)
+	case action == "" && r.Method == http.MethodGet:
+		s.handleGetPlan(w, r, name)
+	case action == "" && r.Method == http.MethodPut:
+		s.handleUpdatePlan(w, r, name)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
 	}
-
-	http.Error(w, "not found", http.StatusNotFound)
 }
 
-// handleAPIPlans returns the list of configured plans.
+// handleAPIPlans returns the list of configured and generated plans.
 func (s *Server) handleAPIPlans(w http.ResponseWriter, r *http.Request) {
 	if !s.authorize(r) {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
@@ -1765,10 +1923,23 @@ func (s *Server) handleAPIPlans(w http.ResponseWriter, r *http.Request) {
 		plans = append(plans, p)
 	}
 	s.plansMu.RUnlock()
+	generated, err := s.generatedPlanSummaries(r.Context())
+	if err != nil {
+		slog.Error("failed to list generated plans", "error", err)
+		http.Error(w, "failed to list plans", http.StatusInternalServerError)
+		return
+	}
+	plans = append(plans, generated...)
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{"plans": plans})
 }
 
+// planRunRequest is the optional body of the execute and approve endpoints.
+type planRunRequest struct {
+	SessionID string         `json:"session_id,omitempty"`
+	Inputs    map[string]any `json:"inputs,omitempty"`
+}
+
 // handleExecutePlan executes a plan asynchronously (GC-SPEC-PDR-v4-Phase-4: Plan execution).
 func (s *Server) handleExecutePlan(w http.ResponseWriter, r *http.Request) {
 	if !s.authorize(r) {
@@ -1785,11 +1956,8 @@ func (s *Server) handleExecutePlan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse optional session_id from request body
-	type executeRequest struct {
-		SessionID string `json:"session_id,omitempty"`
-	}
-	var req executeRequest
+	// Parse optional session_id and inputs from request body
+	var req planRunRequest
 	if r.ContentLength > 0 {
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "invalid request body", http.StatusBadRequest)
@@ -1797,42 +1965,100 @@ func (s *Server) handleExecutePlan(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Generate session if not provided
-	sessionID := req.SessionID
-	if sessionID == "" {
-		sessionID = uuid.NewString()
+	executionID, sessionID, err := s.StartPlan(r.Context(), planName, req.SessionID, req.Inputs)
+	if err != nil {
+		writePlanError(w, planName, err)
+		return
 	}
+	writePlanStarted(w, planName, executionID, sessionID)
+}
 
-	// Validate plan exists (lock protects against hot-reload races).
-	s.plansMu.RLock()
-	plan, exists := s.cfg.PlansMap[planName]
-	s.plansMu.RUnlock()
-	if !exists {
+// writePlanStarted answers 202 Accepted with the new execution.
+func writePlanStarted(w http.ResponseWriter, planName, executionID, sessionID string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"execution_id": executionID,
+		"session_id":   sessionID,
+		"plan_name":    planName,
+		"status":       "running",
+	})
+}
+
+// writePlanError maps plan errors to HTTP statuses. Client errors carry
+// their message as plain text.
+func writePlanError(w http.ResponseWriter, planName string, err error) {
+	switch {
+	case errors.Is(err, ErrPlanNotFound):
 		http.Error(w, fmt.Sprintf("plan %q not found", planName), http.StatusNotFound)
-		return
+	case errors.Is(err, ErrPlanNotApproved), errors.Is(err, persistence.ErrGeneratedPlanChanged):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, coordinator.ErrInvalidInputs), errors.Is(err, ErrInvalidPlan):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errExecutorUnavailable):
+		http.Error(w, "plan executor unavailable", http.StatusServiceUnavailable)
+	default:
+		slog.Error("plan request failed", "plan", planName, "error", err)
+		http.Error(w, "failed to start plan execution", http.StatusInternalServerError)
+	}
+}
+
+// ErrPlanNotFound is returned by StartPlan for names not in the plan config.
+var ErrPlanNotFound = errors.New("plan not found")
+
+var errExecutorUnavailable = errors.New("plan executor unavailable")
+
+// StartPlan checks the inputs of a configured or approved generated plan,
+// records a new execution and runs it in the background. An empty sessionID
+// starts a new session. Bad inputs are reported as
+// coordinator.ErrInvalidInputs before anything is recorded or queued.
+func (s *Server) StartPlan(ctx context.Context, planName, sessionID string, inputs map[string]any) (executionID, session string, err error) {
+	plan, err := s.runnablePlan(ctx, planName)
+	if err != nil {
+		return "", "", err
 	}
+	return s.startPlan(ctx, planName, plan, sessionID, inputs)
+}
 
+// StartGeneratedPlan runs gp's definition as given, not what is stored under
+// its name by now. Callers pass the snapshot they just approved, so a plan
+// saved under the same name during the approval never runs unreviewed.
+func (s *Server) StartGeneratedPlan(ctx context.Context, gp *persistence.GeneratedPlan, sessionID string, inputs map[string]any) (executionID, session string, err error) {
+	plan, err := s.parseGeneratedPlan(gp)
+	if err != nil {
+		return "", "", err
+	}
+	return s.startPlan(ctx, gp.Name, plan, sessionID, inputs)
+}
+
+func (s *Server) startPlan(ctx context.Context, planName string, plan *coordinator.Plan, sessionID string, inputs map[string]any) (executionID, session string, err error) {
 	// Guard: executor must be initialized
 	if s.cfg.Executor == nil {
-		http.Error(w, "plan executor unavailable", http.StatusServiceUnavailable)
-		return
+		return "", "", errExecutorUnavailable
+	}
+
+	if _, err := plan.BindInputs(inputs); err != nil {
+		return "", "", err
+	}
+
+	// Generate session if not provided
+	if sessionID == "" {
+		sessionID = uuid.NewString()
 	}
 
 	// Generate execution ID
-	executionID := uuid.NewString()
+	executionID = uuid.NewString()
 
 	// Ensure session exists (plan_executions has FK on sessions).
-	if err := s.cfg.Store.EnsureSession(r.Context(), sessionID); err != nil {
+	if err := s.cfg.Store.EnsureSession(ctx, sessionID); err != nil {
 		slog.Error("failed to ensure session for plan execution", "error", err, "session_id", sessionID)
-		http.Error(w, "failed to start plan execution", http.StatusInternalServerError)
-		return
+		return "", "", fmt.Errorf("ensure session: %w", err)
 	}
 
 	// Record plan start in DB (synchronous - fail fast on errors)
-	if err := s.cfg.Store.CreatePlanExecution(r.Context(), executionID, planName, sessionID, len(plan.Steps)); err != nil {
+	if err := s.cfg.Store.CreatePlanExecution(ctx, executionID, planName, sessionID, len(plan.Steps)); err != nil {
 		slog.Error("failed to create plan execution", "error", err, "execution_id", executionID)
-		http.Error(w, "failed to start plan execution", http.StatusInternalServerError)
-		return
+		return "", "", fmt.Errorf("create plan execution: %w", err)
 	}
 
 	// Launch async execution (fire-and-forget).
@@ -1840,8 +2066,11 @@ func (s *Server) handleExecutePlan(w http.ResponseWriter, r *http.Request) {
 	// so pass executionID to executor to avoid a duplicate row.
 	go func() {
 		ctx := context.Background() // detached from request context
-		result, err := s.cfg.Executor.Execute(ctx, plan, sessionID, executionID)
-		if err != nil {
+		result, err := s.cfg.Executor.Execute(ctx, plan, sessionID, executionID, inputs)
+		if errors.Is(err, coordinator.ErrExecutionCanceled) {
+			slog.Info("plan execution canceled", "execution_id", executionID, "plan", planName)
+			_ = s.cfg.Store.CompletePlanExecution(ctx, executionID, "canceled", 0)
+		} else if err != nil {
 			slog.Error("plan execution failed", "execution_id", executionID, "plan", planName, "error", err)
 			_ = s.cfg.Store.CompletePlanExecution(ctx, executionID, "failed", 0)
 		} else {
@@ -1850,13 +2079,25 @@ func (s *Server) handleExecutePlan(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	// Return 202 Accepted with execution_id immediately
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	_ = json.NewEncoder(w).Encode(map[string]any{
-		"execution_id": executionID,
-		"session_id":   sessionID,
-		"plan_name":    planName,
-		"status":       "running",
-	})
+	return executionID, sessionID, nil
+}
+
+// runnablePlan returns the configured plan called name or, failing that, the
+// generated plan of that name if it is approved.
+func (s *Server) runnablePlan(ctx context.Context, name string) (*coordinator.Plan, error) {
+	// Lock protects against hot-reload races.
+	s.plansMu.RLock()
+	plan, exists := s.cfg.PlansMap[name]
+	s.plansMu.RUnlock()
+	if exists {
+		return plan, nil
+	}
+	gp, err := s.GeneratedPlan(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if gp.Status != persistence.GeneratedPlanApproved {
+		return nil, fmt.Errorf("%w: plan %s is %s", ErrPlanNotApproved, name, gp.Status)
+	}
+	return s.parseGeneratedPlan(gp)
 }
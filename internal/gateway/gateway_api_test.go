@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/basket/go-claw/internal/agent"
+	"github.com/basket/go-claw/internal/budget"
+	"github.com/basket/go-claw/internal/config"
 	"github.com/basket/go-claw/internal/engine"
 	"github.com/basket/go-claw/internal/gateway"
 	"github.com/basket/go-claw/internal/persistence"
@@ -446,3 +448,112 @@ func TestAPIConfig_HidesSecrets(t *testing.T) {
 		t.Errorf("expected exactly 2 fields (config_hash, policy_version), got %d: %v", len(body), body)
 	}
 }
+
+func TestAPIBudgets_ReportsUsage(t *testing.T) {
+	const sessionID = "33333333-3333-4333-8333-333333333333"
+	var budgets *budget.Enforcer
+	ts, _ := apiTestServer(t, func(cfg *gateway.Config) {
+		budgets = budget.New(cfg.Store, nil)
+		budgets.Configure(config.Config{
+			Agents:        []config.AgentConfigEntry{{AgentID: "default", Budget: &config.BudgetConfig{DailyUSD: 5}}},
+			SessionBudget: &config.BudgetConfig{MonthlyTokens: 1000},
+		})
+		cfg.Budget = budgets
+	})
+
+	if resp := apiGet(t, ts, "/api/budgets", false); resp.StatusCode != http.StatusUnauthorized {
+		resp.Body.Close()
+		t.Fatalf("expected 401 without auth, got %d", resp.StatusCode)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/budgets", 1},
+		{"/api/budgets?session_id=" + sessionID, 2},
+	}
+	for _, tt := range tests {
+		resp := apiGet(t, ts, tt.path, true)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", tt.path, resp.StatusCode)
+		}
+		body := decodeJSON(t, resp)
+		entries, ok := body["budgets"].([]interface{})
+		if !ok || len(entries) != tt.want {
+			t.Fatalf("GET %s: expected %d budgets, got %v", tt.path, tt.want, body["budgets"])
+		}
+		first := entries[0].(map[string]interface{})
+		if first["scope"] != "agent" || first["period"] != "daily" || first["limit_usd"] != 5.0 {
+			t.Errorf("GET %s: unexpected agent budget %v", tt.path, first)
+		}
+	}
+}
+
+func TestAPIWebhookDeliveries_ListAndRetry(t *testing.T) {
+	ts, store := apiTestServer(t, func(cfg *gateway.Config) {
+		cfg.Webhooks = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})
+		cfg.TelegramWebhook = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+	ctx := context.Background()
+	id, err := store.EnqueueWebhookDelivery(ctx, "ops", "agent.alert", `{}`)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if err := store.FailWebhookDelivery(ctx, id, "status 500", time.Now(), true); err != nil {
+		t.Fatalf("fail: %v", err)
+	}
+
+	// Inbound webhooks are mounted without API key auth.
+	resp, err := http.Post(ts.URL+"/webhooks/ci", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("POST /webhooks/ci: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST /webhooks/ci: expected 202, got %d", resp.StatusCode)
+	}
+	resp, err = http.Post(ts.URL+"/telegram/webhook", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("POST /telegram/webhook: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /telegram/webhook: expected 200, got %d", resp.StatusCode)
+	}
+
+	if resp := apiGet(t, ts, "/api/webhooks/deliveries", false); resp.StatusCode != http.StatusUnauthorized {
+		resp.Body.Close()
+		t.Fatalf("expected 401 without auth, got %d", resp.StatusCode)
+	}
+	body := decodeJSON(t, apiGet(t, ts, "/api/webhooks/deliveries", true))
+	dead, _ := body["deliveries"].([]interface{})
+	if len(dead) != 1 || dead[0].(map[string]interface{})["id"] != id {
+		t.Fatalf("expected the dead letter, got %v", body)
+	}
+
+	retry := func() int {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/webhooks/deliveries/"+id+"/retry", nil)
+		req.Header.Set("Authorization", "Bearer "+gatewayTestAuthToken)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("retry: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := retry(); code != http.StatusOK {
+		t.Fatalf("retry: expected 200, got %d", code)
+	}
+	if code := retry(); code != http.StatusNotFound {
+		t.Fatalf("second retry: expected 404, got %d", code)
+	}
+	body = decodeJSON(t, apiGet(t, ts, "/api/webhooks/deliveries?status=pending", true))
+	if pending, _ := body["deliveries"].([]interface{}); len(pending) != 1 {
+		t.Fatalf("expected the requeued delivery, got %v", body)
+	}
+}
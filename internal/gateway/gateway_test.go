@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -19,6 +20,7 @@ import (
 	"github.com/basket/go-claw/internal/coordinator"
 	"github.com/basket/go-claw/internal/engine"
 	"github.com/basket/go-claw/internal/gateway"
+	"github.com/basket/go-claw/internal/mcp"
 	"github.com/basket/go-claw/internal/persistence"
 	"github.com/basket/go-claw/internal/policy"
 	"github.com/coder/websocket"
@@ -1846,13 +1848,17 @@ func TestAgentStatusViaRPC(t *testing.T) {
 	}
 
 	var result struct {
-		AgentID     string `json:"agent_id"`
-		WorkerCount int    `json:"worker_count"`
-		ActiveTasks int32  `json:"active_tasks"`
+		AgentID       string  `json:"agent_id"`
+		WorkerCount   int     `json:"worker_count"`
+		ActiveTasks   int32   `json:"active_tasks"`
+		PolicyProfile *string `json:"policy_profile"`
 	}
 	if err := json.Unmarshal(resp.Result, &result); err != nil {
 		t.Fatalf("unmarshal agent.status: %v", err)
 	}
+	if result.PolicyProfile == nil || *result.PolicyProfile != "" {
+		t.Errorf("expected empty policy_profile for an agent on the global policy, got %v", result.PolicyProfile)
+	}
 	if result.AgentID != "default" {
 		t.Fatalf("expected agent_id=default, got %q", result.AgentID)
 	}
@@ -1861,6 +1867,62 @@ func TestAgentStatusViaRPC(t *testing.T) {
 	}
 }
 
+func TestMCPMethodsViaRPC(t *testing.T) {
+	store := openStoreForGatewayTest(t)
+	eng := engine.New(store, engine.EchoProcessor{}, engine.Config{WorkerCount: 1, PollInterval: 5 * time.Millisecond})
+	mgr := mcp.NewManager(nil, gatewayTestPolicy, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	srv := gateway.New(gateway.Config{
+		Store:     store,
+		Registry:  makeTestRegistry(store, eng),
+		Policy:    gatewayTestPolicy,
+		AuthToken: gatewayTestAuthToken,
+		MCP:       mgr,
+	})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	conn := connectWS(t, ts.URL, gatewayTestAuthToken)
+	sendHello(t, conn)
+	ctx := context.Background()
+
+	call := func(id int, method string, params map[string]any) rpcResp {
+		t.Helper()
+		if err := wsjson.Write(ctx, conn, rpcReq{JSONRPC: "2.0", ID: id, Method: method, Params: params}); err != nil {
+			t.Fatalf("write %s: %v", method, err)
+		}
+		var resp rpcResp
+		if err := wsjson.Read(ctx, conn, &resp); err != nil {
+			t.Fatalf("read %s: %v", method, err)
+		}
+		return resp
+	}
+
+	resp := call(1, "mcp.resources.list", map[string]any{})
+	if resp.Error != nil {
+		t.Fatalf("mcp.resources.list error: %+v", resp.Error)
+	}
+	var list struct {
+		Resources []mcp.DiscoveredResource `json:"resources"`
+	}
+	if err := json.Unmarshal(resp.Result, &list); err != nil {
+		t.Fatalf("unmarshal mcp.resources.list: %v", err)
+	}
+	if list.Resources == nil || len(list.Resources) != 0 {
+		t.Fatalf("expected empty resource list, got %s", resp.Result)
+	}
+
+	resp = call(2, "mcp.prompts.get", map[string]any{"server": "docs"})
+	if resp.Error == nil || resp.Error.Code != gateway.ErrCodeInvalid {
+		t.Fatalf("expected invalid params error for missing name, got %+v", resp.Error)
+	}
+
+	resp = call(3, "mcp.resources.read", map[string]any{"server": "docs", "uri": "file:///x"})
+	if resp.Error == nil {
+		t.Fatal("expected error reading from an unconnected server")
+	}
+}
+
 // --- Sprint 0 Bug Fix Tests ---
 
 // mockStreamBrain implements engine.Brain for streaming tests.
@@ -2428,6 +2490,14 @@ func TestGateway_ExecutePlan(t *testing.T) {
 		},
 	}
 
+	typedPlan := &coordinator.Plan{
+		Name:   "typed-plan",
+		Inputs: []coordinator.PlanInput{{Name: "topic", Type: coordinator.InputString, Required: true}},
+		Steps: []coordinator.PlanStep{
+			{ID: "step1", AgentID: "default", Prompt: "research {inputs.topic}"},
+		},
+	}
+
 	// Create executor with a mock router (no waiter = test mode).
 	mockRouter := &testChatRouter{store: store}
 	executor := coordinator.NewExecutor(mockRouter, nil, store, nil)
@@ -2438,7 +2508,8 @@ func TestGateway_ExecutePlan(t *testing.T) {
 		Policy:    gatewayTestPolicy,
 		AuthToken: gatewayTestAuthToken,
 		PlansMap: map[string]*coordinator.Plan{
-			"test-plan": testPlan,
+			"test-plan":  testPlan,
+			"typed-plan": typedPlan,
 		},
 		Plans: map[string]gateway.PlanSummary{
 			"test-plan": {Name: "test-plan", StepCount: 1, AgentIDs: []string{"default"}},
@@ -2538,7 +2609,48 @@ func TestGateway_ExecutePlan(t *testing.T) {
 		}
 	})
 
-	// Test 4: Auto-generated session_id when not provided.
+	// Test 4: Inputs are checked before an execution is recorded.
+	t.Run("inputs", func(t *testing.T) {
+		post := func(body string) (int, string) {
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/plans/typed-plan/execute", strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("new request: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+gatewayTestAuthToken)
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("execute request: %v", err)
+			}
+			defer resp.Body.Close()
+			msg, _ := io.ReadAll(resp.Body)
+			return resp.StatusCode, string(msg)
+		}
+		countExecutions := func() int {
+			var n int
+			if err := store.DB().QueryRow(`SELECT COUNT(*) FROM plan_executions WHERE plan_name = 'typed-plan'`).Scan(&n); err != nil {
+				t.Fatalf("count executions: %v", err)
+			}
+			return n
+		}
+
+		for _, body := range []string{`{}`, `{"inputs":{"colour":"red"}}`, `{"inputs":{"topic":7}}`} {
+			if code, msg := post(body); code != http.StatusBadRequest || !strings.Contains(msg, "invalid plan inputs") {
+				t.Errorf("body %s: got %d %q, want 400 invalid plan inputs", body, code, msg)
+			}
+		}
+		if n := countExecutions(); n != 0 {
+			t.Fatalf("rejected inputs recorded %d executions", n)
+		}
+		if code, msg := post(`{"inputs":{"topic":"go"}}`); code != http.StatusAccepted {
+			t.Fatalf("valid inputs: got %d %q, want 202", code, msg)
+		}
+		if n := countExecutions(); n != 1 {
+			t.Fatalf("executions = %d, want 1", n)
+		}
+	})
+
+	// Test 5: Auto-generated session_id when not provided.
 	t.Run("auto_session_id", func(t *testing.T) {
 		req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/plans/test-plan/execute", nil)
 		if err != nil {
@@ -2638,6 +2750,250 @@ func TestGateway_UpdatePlans(t *testing.T) {
 	})
 }
 
+func TestGateway_GeneratedPlans(t *testing.T) {
+	store := openStoreForGatewayTest(t)
+	eng := engine.New(store, engine.EchoProcessor{}, engine.Config{
+		WorkerCount:  1,
+		PollInterval: 5 * time.Millisecond,
+		TaskTimeout:  2 * time.Second,
+	})
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eng.Start(runCtx)
+
+	ctx := context.Background()
+	definition := "name: digest\ninputs:\n  - name: topic\n    required: true\nsteps:\n  - id: s1\n    agent_id: default\n    prompt: \"news about {inputs.topic}\"\n"
+	if err := store.SaveGeneratedPlan(ctx, &persistence.GeneratedPlan{Name: "digest", Goal: "daily news", Definition: definition}); err != nil {
+		t.Fatalf("save generated plan: %v", err)
+	}
+
+	srv := gateway.New(gateway.Config{
+		Store:     store,
+		Registry:  makeTestRegistry(store, eng),
+		Policy:    gatewayTestPolicy,
+		AuthToken: gatewayTestAuthToken,
+		PlansMap:  map[string]*coordinator.Plan{},
+		Plans:     map[string]gateway.PlanSummary{},
+		Executor:  coordinator.NewExecutor(&testChatRouter{store: store}, nil, store, nil),
+	})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, body string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+gatewayTestAuthToken)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(msg)
+	}
+	status := func() string {
+		gp, err := store.GetGeneratedPlan(ctx, "digest")
+		if err != nil {
+			t.Fatalf("get generated plan: %v", err)
+		}
+		return gp.Status
+	}
+
+	if code, body := do(http.MethodGet, "/api/plans", ""); code != http.StatusOK ||
+		!strings.Contains(body, `"name":"digest"`) || !strings.Contains(body, `"status":"draft"`) || !strings.Contains(body, `"step_count":1`) {
+		t.Fatalf("list: %d %s", code, body)
+	}
+	if code, body := do(http.MethodGet, "/api/plans/digest", ""); code != http.StatusOK || !strings.Contains(body, "news about") {
+		t.Fatalf("show: %d %s", code, body)
+	}
+
+	// Drafts do not run.
+	if code, body := do(http.MethodPost, "/api/plans/digest/execute", `{"inputs":{"topic":"go"}}`); code != http.StatusConflict {
+		t.Fatalf("execute draft: %d %s, want 409", code, body)
+	}
+
+	// Edits are validated, and go back to draft.
+	if code, body := do(http.MethodPut, "/api/plans/digest", `{"definition":"name: digest\nsteps:\n  - id: s1\n    agent_id: ghost\n    prompt: x\n"}`); code != http.StatusBadRequest || !strings.Contains(body, "unknown agent") {
+		t.Fatalf("invalid edit: %d %s, want 400", code, body)
+	}
+	if code, body := do(http.MethodPut, "/api/plans/digest", `{"definition":"name: other\nsteps: []\n"}`); code != http.StatusBadRequest {
+		t.Fatalf("renaming edit: %d %s, want 400", code, body)
+	}
+	edited := strings.Replace(definition, "news about", "headlines about", 1)
+	editBody, _ := json.Marshal(map[string]string{"definition": edited})
+	if code, body := do(http.MethodPut, "/api/plans/digest", string(editBody)); code != http.StatusOK || !strings.Contains(body, "headlines about") {
+		t.Fatalf("edit: %d %s", code, body)
+	}
+
+	// Approval checks the inputs before marking the plan approved.
+	if code, body := do(http.MethodPost, "/api/plans/digest/approve", `{}`); code != http.StatusBadRequest {
+		t.Fatalf("approve without inputs: %d %s, want 400", code, body)
+	}
+	if s := status(); s != persistence.GeneratedPlanDraft {
+		t.Fatalf("status after failed approval = %q, want draft", s)
+	}
+	if code, body := do(http.MethodPost, "/api/plans/digest/approve", `{"inputs":{"topic":"go"}}`); code != http.StatusAccepted || !strings.Contains(body, "execution_id") {
+		t.Fatalf("approve: %d %s, want 202", code, body)
+	}
+	if s := status(); s != persistence.GeneratedPlanApproved {
+		t.Fatalf("status after approval = %q, want approved", s)
+	}
+	// Approved plans re-run by name.
+	if code, body := do(http.MethodPost, "/api/plans/digest/execute", `{"inputs":{"topic":"sqlite"}}`); code != http.StatusAccepted {
+		t.Fatalf("re-run: %d %s, want 202", code, body)
+	}
+
+	if code, body := do(http.MethodPost, "/api/plans/digest/reject", ""); code != http.StatusOK {
+		t.Fatalf("reject: %d %s", code, body)
+	}
+	if code, _ := do(http.MethodPost, "/api/plans/digest/execute", `{"inputs":{"topic":"go"}}`); code != http.StatusConflict {
+		t.Fatalf("execute rejected plan: %d, want 409", code)
+	}
+	if code, _ := do(http.MethodPost, "/api/plans/missing/approve", ""); code != http.StatusNotFound {
+		t.Fatalf("approve missing plan: %d, want 404", code)
+	}
+}
+
+func TestGateway_PlanExecutionControl(t *testing.T) {
+	store := openStoreForGatewayTest(t)
+	// The engine is not started, so step tasks stay queued until canceled.
+	eng := engine.New(store, engine.EchoProcessor{}, engine.Config{WorkerCount: 1})
+
+	plan := &coordinator.Plan{
+		Name: "slow-plan",
+		Steps: []coordinator.PlanStep{
+			{ID: "wait", AgentID: "default", Prompt: "take your time"},
+			{ID: "after", AgentID: "default", Prompt: "then this", DependsOn: []string{"wait"}},
+		},
+	}
+	srv := gateway.New(gateway.Config{
+		Store:     store,
+		Registry:  makeTestRegistry(store, eng),
+		Policy:    gatewayTestPolicy,
+		AuthToken: gatewayTestAuthToken,
+		PlansMap:  map[string]*coordinator.Plan{"slow-plan": plan},
+		Plans:     map[string]gateway.PlanSummary{},
+		Executor:  coordinator.NewExecutor(&testChatRouter{store: store}, coordinator.NewWaiter(nil, store), store, nil),
+	})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, body string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+gatewayTestAuthToken)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(msg)
+	}
+	waitStatus := func(path, want string) gateway.PlanExecutionView {
+		t.Helper()
+		var view gateway.PlanExecutionView
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			code, body := do(http.MethodGet, path, "")
+			if code != http.StatusOK {
+				t.Fatalf("get execution: %d %s", code, body)
+			}
+			if err := json.Unmarshal([]byte(body), &view); err != nil {
+				t.Fatalf("decode execution: %v", err)
+			}
+			if view.Status == want && (want != "running" || view.Steps[0].Status == "running") {
+				return view
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("execution status = %s (steps %+v), want %s", view.Status, view.Steps, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	code, body := do(http.MethodPost, "/api/plans/slow-plan/execute", "")
+	if code != http.StatusAccepted {
+		t.Fatalf("execute: %d %s", code, body)
+	}
+	var started struct {
+		ExecutionID string `json:"execution_id"`
+	}
+	_ = json.Unmarshal([]byte(body), &started)
+	path := "/api/plans/executions/" + started.ExecutionID
+	waitStatus(path, "running")
+
+	if code, body := do(http.MethodPost, path+"/pause", ""); code != http.StatusOK {
+		t.Fatalf("pause: %d %s", code, body)
+	}
+	waitStatus(path, "paused")
+	if code, body := do(http.MethodPost, path+"/resume", ""); code != http.StatusOK {
+		t.Fatalf("resume: %d %s", code, body)
+	}
+	waitStatus(path, "running")
+	if code, body := do(http.MethodPost, path+"/rerun", `{"step_id":"wait"}`); code != http.StatusConflict {
+		t.Fatalf("rerun running execution: %d %s, want 409", code, body)
+	}
+
+	if code, body := do(http.MethodPost, path+"/cancel", ""); code != http.StatusOK {
+		t.Fatalf("cancel: %d %s", code, body)
+	}
+	view := waitStatus(path, "canceled")
+	task, err := store.GetTask(context.Background(), view.Steps[0].TaskID)
+	if err != nil {
+		t.Fatalf("get step task: %v", err)
+	}
+	if task.Status != persistence.TaskStatusCanceled {
+		t.Errorf("step task status = %s, want CANCELED", task.Status)
+	}
+	if code, _ := do(http.MethodPost, path+"/pause", ""); code != http.StatusConflict {
+		t.Errorf("pause canceled execution: %d, want 409", code)
+	}
+
+	// Re-running from a step runs it again.
+	if code, body := do(http.MethodPost, path+"/rerun", `{"step_id":"ghost"}`); code != http.StatusBadRequest {
+		t.Fatalf("rerun unknown step: %d %s, want 400", code, body)
+	}
+	if code, body := do(http.MethodPost, path+"/rerun", `{"step_id":"wait"}`); code != http.StatusAccepted {
+		t.Fatalf("rerun: %d %s, want 202", code, body)
+	}
+	waitStatus(path, "running")
+
+	// The same controls over ACP.
+	conn := connectWS(t, ts.URL, gatewayTestAuthToken)
+	sendHello(t, conn)
+	call := func(method string, params any) rpcResp {
+		t.Helper()
+		if err := wsjson.Write(context.Background(), conn, rpcReq{JSONRPC: "2.0", ID: 1, Method: method, Params: params}); err != nil {
+			t.Fatalf("write %s: %v", method, err)
+		}
+		var resp rpcResp
+		if err := wsjson.Read(context.Background(), conn, &resp); err != nil {
+			t.Fatalf("read %s: %v", method, err)
+		}
+		return resp
+	}
+	if resp := call("plan.cancel", map[string]string{"execution_id": started.ExecutionID}); resp.Error != nil {
+		t.Fatalf("plan.cancel: %+v", resp.Error)
+	}
+	waitStatus(path, "canceled")
+	if resp := call("plan.status", map[string]string{"execution_id": started.ExecutionID}); resp.Error != nil || !strings.Contains(string(resp.Result), `"status":"canceled"`) {
+		t.Fatalf("plan.status: %s %+v", resp.Result, resp.Error)
+	}
+	if resp := call("plan.pause", map[string]string{"execution_id": "missing"}); resp.Error == nil || resp.Error.Code != gateway.ErrCodeInvalid {
+		t.Fatalf("plan.pause unknown execution: %+v, want invalid", resp.Error)
+	}
+	if code, _ := do(http.MethodGet, "/api/plans/executions/missing", ""); code != http.StatusNotFound {
+		t.Errorf("get unknown execution: %d, want 404", code)
+	}
+}
+
 // testChatRouter is a minimal ChatTaskRouter for gateway plan execution tests.
 type testChatRouter struct {
 	store *persistence.Store
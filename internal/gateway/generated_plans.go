@@ -0,0 +1,284 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/basket/go-claw/internal/coordinator"
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+// Generated plans are written by agents with the create_plan tool and saved
+// in the store. They run only once approved: by the approver during the
+// tool call, or later through /plan approve or POST /api/plans/{name}/approve.
+
+var (
+	// ErrPlanNotApproved is returned when running a generated plan that is
+	// still a draft or was rejected.
+	ErrPlanNotApproved = errors.New("plan not approved")
+	// ErrInvalidPlan is returned for plan definitions that do not validate.
+	ErrInvalidPlan = errors.New("invalid plan")
+)
+
+// GeneratedPlanView is the API view of a generated plan.
+type GeneratedPlanView struct {
+	Name       string    `json:"name"`
+	Goal       string    `json:"goal"`
+	Status     string    `json:"status"`
+	Definition string    `json:"definition"` // YAML, in the layout of a plan in config.yaml
+	CreatedBy  string    `json:"created_by,omitempty"`
+	SessionID  string    `json:"session_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func generatedPlanView(p *persistence.GeneratedPlan) GeneratedPlanView {
+	return GeneratedPlanView{
+		Name:       p.Name,
+		Goal:       p.Goal,
+		Status:     p.Status,
+		Definition: p.Definition,
+		CreatedBy:  p.CreatedBy,
+		SessionID:  p.SessionID,
+		CreatedAt:  p.CreatedAt,
+		UpdatedAt:  p.UpdatedAt,
+	}
+}
+
+// IsConfiguredPlan reports whether name is a plan from config.yaml.
+func (s *Server) IsConfiguredPlan(name string) bool {
+	s.plansMu.RLock()
+	defer s.plansMu.RUnlock()
+	_, ok := s.cfg.PlansMap[name]
+	return ok
+}
+
+// GeneratedPlan returns the generated plan called name, or ErrPlanNotFound.
+func (s *Server) GeneratedPlan(ctx context.Context, name string) (*persistence.GeneratedPlan, error) {
+	if s.cfg.Store == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPlanNotFound, name)
+	}
+	p, err := s.cfg.Store.GetGeneratedPlan(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrPlanNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get generated plan: %w", err)
+	}
+	return p, nil
+}
+
+// ListGeneratedPlans returns all generated plans ordered by name.
+func (s *Server) ListGeneratedPlans(ctx context.Context) ([]*persistence.GeneratedPlan, error) {
+	if s.cfg.Store == nil {
+		return nil, nil
+	}
+	return s.cfg.Store.ListGeneratedPlans(ctx)
+}
+
+// ApprovePlan approves a generated plan and starts it. The plan must still
+// validate against the running agents and accept the inputs, so a plan is
+// never marked approved when it cannot run. Approval applies to the
+// definition read here: if the plan is replaced before it is marked,
+// persistence.ErrGeneratedPlanChanged is returned, and the definition that
+// was approved is the one that runs.
+func (s *Server) ApprovePlan(ctx context.Context, name, sessionID string, inputs map[string]any) (executionID, session string, err error) {
+	gp, err := s.GeneratedPlan(ctx, name)
+	if err != nil {
+		return "", "", err
+	}
+	plan, err := s.parseGeneratedPlan(gp)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := plan.BindInputs(inputs); err != nil {
+		return "", "", err
+	}
+	if err := s.cfg.Store.SetGeneratedPlanStatusIf(ctx, name, gp.Definition, persistence.GeneratedPlanApproved); err != nil {
+		return "", "", fmt.Errorf("approve plan %s: %w", name, err)
+	}
+	if sessionID == "" {
+		sessionID = gp.SessionID
+	}
+	return s.StartGeneratedPlan(ctx, gp, sessionID, inputs)
+}
+
+// RejectPlan marks a generated plan rejected; it can no longer be run until
+// it is approved.
+func (s *Server) RejectPlan(ctx context.Context, name string) error {
+	if _, err := s.GeneratedPlan(ctx, name); err != nil {
+		return err
+	}
+	if err := s.cfg.Store.SetGeneratedPlanStatus(ctx, name, persistence.GeneratedPlanRejected); err != nil {
+		return fmt.Errorf("reject plan: %w", err)
+	}
+	return nil
+}
+
+// UpdateGeneratedPlan replaces the definition of a generated plan after
+// validating it. The edited plan goes back to draft and needs approval again.
+func (s *Server) UpdateGeneratedPlan(ctx context.Context, name, definition string) (*persistence.GeneratedPlan, error) {
+	gp, err := s.GeneratedPlan(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := coordinator.DecodePlanDefinition(definition)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPlan, err)
+	}
+	if pc.Name != name {
+		return nil, fmt.Errorf("%w: definition is named %q, not %q", ErrInvalidPlan, pc.Name, name)
+	}
+	gp.Definition = definition
+	if _, err := s.parseGeneratedPlan(gp); err != nil {
+		return nil, err
+	}
+	gp.Status = persistence.GeneratedPlanDraft
+	if err := s.cfg.Store.SaveGeneratedPlan(ctx, gp); err != nil {
+		return nil, err
+	}
+	return gp, nil
+}
+
+// parseGeneratedPlan validates a generated plan against the running agents.
+func (s *Server) parseGeneratedPlan(gp *persistence.GeneratedPlan) (*coordinator.Plan, error) {
+	plan, err := coordinator.ParsePlanDefinition(gp.Definition, s.agentIDs())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPlan, err)
+	}
+	return &plan, nil
+}
+
+// agentIDs lists the running agents.
+func (s *Server) agentIDs() []string {
+	if s.cfg.Registry == nil {
+		return nil
+	}
+	configs := s.cfg.Registry.ListAgents()
+	ids := make([]string, 0, len(configs))
+	for _, ac := range configs {
+		ids = append(ids, ac.AgentID)
+	}
+	return ids
+}
+
+// generatedPlanSummaries summarizes generated plans for the plan list.
+// Plans that no longer validate are listed with what their definition says.
+func (s *Server) generatedPlanSummaries(ctx context.Context) ([]PlanSummary, error) {
+	plans, err := s.ListGeneratedPlans(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]PlanSummary, 0, len(plans))
+	for _, gp := range plans {
+		summary := PlanSummary{Name: gp.Name, Generated: true, Goal: gp.Goal, Status: gp.Status}
+		agents := make(map[string]bool)
+		if plan, err := s.parseGeneratedPlan(gp); err == nil {
+			summary.StepCount = len(plan.Steps)
+			summary.Inputs = plan.Inputs
+			for _, step := range plan.Steps {
+				agents[step.AgentID] = true
+			}
+		} else if pc, err := coordinator.DecodePlanDefinition(gp.Definition); err == nil {
+			summary.StepCount = len(pc.Steps)
+			for _, step := range pc.Steps {
+				agents[step.AgentID] = true
+			}
+		}
+		summary.AgentIDs = make([]string, 0, len(agents))
+		for a := range agents {
+			summary.AgentIDs = append(summary.AgentIDs, a)
+		}
+		sort.Strings(summary.AgentIDs)
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// handleGetPlan shows a generated plan, or the summary of a configured one.
+func (s *Server) handleGetPlan(w http.ResponseWriter, r *http.Request, name string) {
+	if !s.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.plansMu.RLock()
+	summary, configured := s.cfg.Plans[name]
+	s.plansMu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	if configured {
+		_ = json.NewEncoder(w).Encode(summary)
+		return
+	}
+	gp, err := s.GeneratedPlan(r.Context(), name)
+	if err != nil {
+		w.Header().Del("Content-Type")
+		writePlanError(w, name, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(generatedPlanView(gp))
+}
+
+// handleUpdatePlan replaces a generated plan's definition with the YAML in
+// the request body's "definition" field.
+func (s *Server) handleUpdatePlan(w http.ResponseWriter, r *http.Request, name string) {
+	if !s.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Definition string `json:"definition"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil || req.Definition == "" {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	gp, err := s.UpdateGeneratedPlan(r.Context(), name, req.Definition)
+	if err != nil {
+		writePlanError(w, name, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(generatedPlanView(gp))
+}
+
+// handleApprovePlan approves a generated plan and runs it.
+func (s *Server) handleApprovePlan(w http.ResponseWriter, r *http.Request, name string) {
+	if !s.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req planRunRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	executionID, sessionID, err := s.ApprovePlan(r.Context(), name, req.SessionID, req.Inputs)
+	if err != nil {
+		writePlanError(w, name, err)
+		return
+	}
+	writePlanStarted(w, name, executionID, sessionID)
+}
+
+// handleRejectPlan rejects a generated plan.
+func (s *Server) handleRejectPlan(w http.ResponseWriter, r *http.Request, name string) {
+	if !s.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := s.RejectPlan(r.Context(), name); err != nil {
+		writePlanError(w, name, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"name": name, "status": persistence.GeneratedPlanRejected})
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/basket/go-claw/internal/shared"
+)
+
+// handleMCPMethod serves the mcp.* ACP methods that expose MCP server
+// prompts and resources to ACP clients.
+func (s *Server) handleMCPMethod(ctx context.Context, method string, params json.RawMessage) (any, *rpcError) {
+	if s.cfg.MCP == nil {
+		return nil, &rpcError{Code: ErrCodeInternal, Message: "mcp not configured"}
+	}
+	var p struct {
+		AgentID   string            `json:"agent_id"` // Optional, defaults to "default".
+		Server    string            `json:"server"`
+		Name      string            `json:"name"`
+		URI       string            `json:"uri"`
+		Arguments map[string]string `json:"arguments"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: ErrCodeInvalid, Message: "invalid params"}
+		}
+	}
+	if p.AgentID == "" {
+		p.AgentID = shared.DefaultAgentID
+	}
+
+	switch method {
+	case "mcp.prompts.list":
+		prompts, err := s.cfg.MCP.ListPrompts(ctx, p.AgentID)
+		if err != nil {
+			return nil, &rpcError{Code: ErrCodeInternal, Message: err.Error()}
+		}
+		return map[string]any{"prompts": prompts}, nil
+
+	case "mcp.prompts.get":
+		if p.Server == "" || p.Name == "" {
+			return nil, &rpcError{Code: ErrCodeInvalid, Message: "server and name are required"}
+		}
+		res, err := s.cfg.MCP.GetPrompt(ctx, p.AgentID, p.Server, p.Name, p.Arguments)
+		if err != nil {
+			return nil, &rpcError{Code: ErrCodeInternal, Message: err.Error()}
+		}
+		return map[string]any{
+			"description": res.Description,
+			"messages":    res.Messages,
+			"text":        res.Text(),
+		}, nil
+
+	case "mcp.resources.list":
+		resources, err := s.cfg.MCP.ListResources(ctx, p.AgentID)
+		if err != nil {
+			return nil, &rpcError{Code: ErrCodeInternal, Message: err.Error()}
+		}
+		return map[string]any{"resources": resources}, nil
+
+	case "mcp.resources.read":
+		if p.Server == "" || p.URI == "" {
+			return nil, &rpcError{Code: ErrCodeInvalid, Message: "server and uri are required"}
+		}
+		content, err := s.cfg.MCP.ReadResource(ctx, p.AgentID, p.Server, p.URI)
+		if err != nil {
+			return nil, &rpcError{Code: ErrCodeInternal, Message: err.Error()}
+		}
+		return map[string]any{"server": p.Server, "uri": p.URI, "content": content}, nil
+	}
+	return nil, &rpcError{Code: ErrCodeMethodNotFound, Message: "method not found: " + method}
+}
@@ -2,18 +2,23 @@ package gateway
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/basket/go-claw/internal/budget"
 	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/media"
+	"github.com/basket/go-claw/internal/persistence"
 	"github.com/basket/go-claw/internal/shared"
 	"github.com/basket/go-claw/internal/tokenutil"
-	"github.com/google/uuid"
 )
 
 func (s *Server) handleOpenAIChatCompletion(w http.ResponseWriter, r *http.Request) {
@@ -32,53 +37,87 @@ func (s *Server) handleOpenAIChatCompletion(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Tools field is accepted but ignored — tools run autonomously via Genkit.
+	clientTools, err := requestClientTools(req)
+	if err != nil {
+		s.openAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
 
 	// 1. Route to agent by model prefix (needed before session ID generation).
-	agentID := "default"
-	if strings.HasPrefix(req.Model, "agent:") {
-		agentID = strings.TrimPrefix(req.Model, "agent:")
-	}
+	agentID := compatAgentID(req.Model)
 
 	// 2. Determine Session ID
 	// OpenAI API is stateless (history passed in request). GoClaw is stateful.
 	// We use the "user" field (if present) as a deterministic Session ID to allow persistence.
 	// Include agentID in the namespace so each agent gets its own session.
-	var sessionID string
-	if req.User != "" {
-		sessionID = uuid.NewSHA1(uuid.NameSpaceURL, []byte("goclaw:user:"+req.User+":agent:"+agentID)).String()
-	} else {
-		sessionID = uuid.NewString()
-	}
+	sessionID := compatSessionID(req.User, agentID)
 
 	// 3. Extract Prompt
 	if len(req.Messages) == 0 {
 		s.openAIError(w, http.StatusBadRequest, "invalid_request_error", "Messages list is empty")
 		return
 	}
-	lastMsg := req.Messages[len(req.Messages)-1]
-	if lastMsg.Role != "user" {
-		s.openAIError(w, http.StatusBadRequest, "invalid_request_error", "Last message must be from user")
+	// A turn that stopped on client tool calls is continued by sending their
+	// results: the turn's user message is then followed by assistant
+	// tool_calls and tool messages.
+	turn := len(req.Messages) - 1
+	if req.Messages[turn].Role == "tool" {
+		for turn >= 0 && req.Messages[turn].Role != "user" {
+			turn--
+		}
+		if turn < 0 {
+			s.openAIError(w, http.StatusBadRequest, "invalid_request_error", "Tool messages must follow a user message")
+			return
+		}
+		if clientTools == nil {
+			s.openAIError(w, http.StatusBadRequest, "invalid_request_error", "Tool messages require the tools of the request")
+			return
+		}
+		rounds, err := clientToolRounds(req.Messages[turn+1:])
+		if err != nil {
+			s.openAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+			return
+		}
+		clientTools.Rounds = rounds
+	} else if req.Messages[turn].Role != "user" {
+		s.openAIError(w, http.StatusBadRequest, "invalid_request_error", "Last message must be from user or tool")
 		return
 	}
-	prompt := lastMsg.Content
-
-	// 4. Seed prior messages into session history so the Brain sees full context.
-	// OpenAI API is stateless: the client sends full conversation on each request.
-	// Clear existing messages first to avoid linear duplication on repeated calls.
-	if err := s.cfg.Store.EnsureSession(r.Context(), sessionID); err != nil {
-		s.openAIError(w, http.StatusInternalServerError, "internal_error", "session init: "+err.Error())
+	lastMsg := req.Messages[turn]
+	prompt := lastMsg.Content.Text
+	atts, err := contentAttachments(lastMsg.Content)
+	if err != nil {
+		s.openAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
 		return
 	}
-	if err := s.cfg.Store.ClearSessionMessages(r.Context(), sessionID, agentID); err != nil {
-		slog.Warn("openai: failed to clear session history", "error", err, "session_id", sessionID)
+	if media.NeedsVision(atts) && !s.cfg.Registry.SupportsMedia(agentID) {
+		s.openAIError(w, http.StatusBadRequest, "model_not_multimodal",
+			fmt.Sprintf("The model of agent %q does not accept image or PDF input", agentID))
+		return
 	}
-	for _, msg := range req.Messages[:len(req.Messages)-1] {
+	if strings.TrimSpace(prompt) == "" && len(atts) > 0 {
+		prompt = media.DefaultPrompt
+	}
+
+	// 4. Seed prior messages into session history so the Brain sees full context.
+	var prior []compatMessage
+	for _, msg := range req.Messages[:turn] {
 		role := strings.ToLower(msg.Role)
 		if role == "system" || role == "user" || role == "assistant" || role == "tool" {
-			_ = s.cfg.Store.AddHistory(r.Context(), sessionID, agentID, role, msg.Content, tokenutil.EstimateTokens(msg.Content))
+			// Attachments of earlier messages are kept when valid; a bad one
+			// only fails the request when it is on the new message.
+			atts, err := contentAttachments(msg.Content)
+			if err != nil {
+				slog.Warn("openai: dropping attachments of seeded message", "error", err, "session_id", sessionID)
+				atts = nil
+			}
+			prior = append(prior, compatMessage{Role: role, Text: msg.Content.Text, Attachments: atts})
 		}
 	}
+	if err := s.seedCompatHistory(r.Context(), sessionID, agentID, prior); err != nil {
+		s.openAIError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
 
 	// 5. Build context with trace ID and sampling config.
 	traceID := shared.NewTraceID()
@@ -95,6 +134,24 @@ func (s *Server) handleOpenAIChatCompletion(w http.ResponseWriter, r *http.Reque
 		}
 		ctx = shared.WithSamplingConfig(ctx, sc)
 	}
+	if req.MaxToolTurns != nil {
+		if *req.MaxToolTurns < 0 {
+			s.openAIError(w, http.StatusBadRequest, "invalid_request_error", "max_tool_turns must not be negative")
+			return
+		}
+		ctx = shared.WithMaxToolTurns(ctx, *req.MaxToolTurns)
+	}
+
+	if len(atts) > 0 {
+		ctx = shared.WithAttachments(ctx, atts)
+	}
+	if clientTools != nil {
+		ctx = shared.WithClientTools(ctx, clientTools)
+	}
+	if err := s.checkBudget(ctx, agentID, sessionID); err != nil {
+		s.openAIError(w, http.StatusTooManyRequests, "insufficient_quota", err.Error())
+		return
+	}
 
 	promptTokens := tokenutil.EstimateTokens(prompt)
 
@@ -132,9 +189,13 @@ func (s *Server) handleOpenAIStream(w http.ResponseWriter, ctx context.Context,
 	}
 
 	// Subscribe to tool-call events on the bus for real-time tool visibility.
+	// Requests with client tool settings never see them: built-in tool calls
+	// are not for the client to run, and their indexes would collide with
+	// the client tool calls an SDK accumulates by index.
+	clientTools := shared.GetClientTools(ctx)
 	var toolSub *bus.Subscription
 	var toolDone chan struct{}
-	if s.cfg.Bus != nil {
+	if s.cfg.Bus != nil && clientTools == nil {
 		toolSub = s.cfg.Bus.Subscribe(bus.TopicStreamToolCall)
 		toolDone = make(chan struct{})
 		go func() {
@@ -187,7 +248,7 @@ func (s *Server) handleOpenAIStream(w http.ResponseWriter, ctx context.Context,
 		}()
 	}
 
-	_, err := s.cfg.Registry.StreamChatTask(ctx, agentID, sessionID, prompt, func(chunk string) error {
+	taskID, err := s.cfg.Registry.StreamChatTask(ctx, agentID, sessionID, prompt, func(chunk string) error {
 		tokens := tokenutil.EstimateTokens(chunk)
 		mu.Lock()
 		completionTokens += tokens
@@ -221,6 +282,30 @@ func (s *Server) handleOpenAIStream(w http.ResponseWriter, ctx context.Context,
 		slog.Error("openai stream error", "error", err)
 	}
 
+	// A turn that stopped on client tool calls streams them, then finishes
+	// with "tool_calls".
+	finishReason := "stop"
+	if clientTools != nil {
+		if calls := responseToolCalls(clientTools.Pending()); len(calls) > 0 {
+			finishReason = "tool_calls"
+			writeSSE(ChatCompletionResponse{
+				ID:      "chatcmpl-" + traceID,
+				Object:  "chat.completion.chunk",
+				Created: time.Now().Unix(),
+				Model:   req.Model,
+				Choices: []ChatCompletionChoice{
+					{
+						Index: 0,
+						Delta: &ChatCompletionMessage{
+							Role:      "assistant",
+							ToolCalls: calls,
+						},
+					},
+				},
+			})
+		}
+	}
+
 	// Send final chunk with finish_reason and usage.
 	writeSSE(ChatCompletionResponse{
 		ID:      "chatcmpl-" + traceID,
@@ -231,14 +316,10 @@ func (s *Server) handleOpenAIStream(w http.ResponseWriter, ctx context.Context,
 			{
 				Index:        0,
 				Delta:        &ChatCompletionMessage{},
-				FinishReason: strPtr("stop"),
+				FinishReason: strPtr(finishReason),
 			},
 		},
-		Usage: &Usage{
-			PromptTokens:     promptTokens,
-			CompletionTokens: completionTokens,
-			TotalTokens:      promptTokens + completionTokens,
-		},
+		Usage: openAIUsage(s.compatUsage(ctx, taskID, promptTokens, completionTokens)),
 	})
 
 	// Send [DONE]
@@ -251,72 +332,70 @@ func (s *Server) handleOpenAIStream(w http.ResponseWriter, ctx context.Context,
 // handleOpenAINonStream handles the synchronous (polling) path for chat completions.
 func (s *Server) handleOpenAINonStream(w http.ResponseWriter, ctx context.Context, req ChatCompletionRequest, agentID, sessionID, prompt string, promptTokens int) {
 	taskID, err := s.cfg.Registry.CreateChatTask(ctx, agentID, sessionID, prompt)
+	if errors.Is(err, budget.ErrExceeded) {
+		s.openAIError(w, http.StatusTooManyRequests, "insufficient_quota", err.Error())
+		return
+	}
 	if err != nil {
 		s.openAIError(w, http.StatusInternalServerError, "internal_error", err.Error())
 		return
 	}
 
-	// Poll for completion — no artificial timeout.
-	// The request context (ctx) cancels when the client disconnects;
-	// the engine's task_timeout_seconds protects against runaway tasks.
-	ticker := time.NewTicker(500 * time.Millisecond)
-	defer ticker.Stop()
+	reply, err := s.awaitCompatReply(ctx, taskID)
+	var taskErr *chatTaskError
+	if errors.As(err, &taskErr) {
+		s.openAIError(w, http.StatusInternalServerError, "task_failed", "Task failed: "+taskErr.reason)
+		return
+	}
+	if err != nil {
+		s.openAIError(w, http.StatusGatewayTimeout, "client_disconnected", "Client closed connection")
+		return
+	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			s.openAIError(w, http.StatusGatewayTimeout, "client_disconnected", "Client closed connection")
-			return
-		case <-ticker.C:
-			task, err := s.cfg.Store.GetTask(ctx, taskID)
-			if err != nil {
-				continue
-			}
-			if task.Status == "SUCCEEDED" {
-				var resPayload struct {
-					Reply string `json:"reply"`
-				}
-				reply := ""
-				if json.Unmarshal([]byte(task.Result), &resPayload) == nil {
-					reply = resPayload.Reply
-				} else {
-					reply = task.Result
-				}
+	finishReason := "stop"
+	toolCalls := responseToolCalls(reply.ToolCalls)
+	if len(toolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+	usage := s.compatUsage(ctx, taskID, promptTokens, tokenutil.EstimateTokens(reply.Text))
+	resp := ChatCompletionResponse{
+		ID:      "chatcmpl-" + taskID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []ChatCompletionChoice{
+			{
+				Index: 0,
+				Message: &ChatCompletionMessage{
+					Role:      "assistant",
+					Content:   reply.Text,
+					ToolCalls: toolCalls,
+				},
+				FinishReason: strPtr(finishReason),
+			},
+		},
+		Usage: openAIUsage(usage),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Warn("openai: failed to write response", "error", err)
+	}
+}
 
-				completionTokens := tokenutil.EstimateTokens(reply)
-				resp := ChatCompletionResponse{
-					ID:      "chatcmpl-" + taskID,
-					Object:  "chat.completion",
-					Created: time.Now().Unix(),
-					Model:   req.Model,
-					Choices: []ChatCompletionChoice{
-						{
-							Index: 0,
-							Message: &ChatCompletionMessage{
-								Role:    "assistant",
-								Content: reply,
-							},
-							FinishReason: strPtr("stop"),
-						},
-					},
-					Usage: &Usage{
-						PromptTokens:     promptTokens,
-						CompletionTokens: completionTokens,
-						TotalTokens:      promptTokens + completionTokens,
-					},
-				}
-				w.Header().Set("Content-Type", "application/json")
-				if err := json.NewEncoder(w).Encode(resp); err != nil {
-					slog.Warn("openai: failed to write response", "error", err)
-				}
-				return
-			}
-			if task.Status == "FAILED" || task.Status == "DEAD_LETTER" || task.Status == "CANCELED" {
-				s.openAIError(w, http.StatusInternalServerError, "task_failed", fmt.Sprintf("Task failed: %s", task.Error))
-				return
-			}
-		}
+// openAIUsage converts a task's usage to the OpenAI shape.
+func openAIUsage(u persistence.TaskUsage) *Usage {
+	out := &Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens(),
 	}
+	if u.CachedTokens > 0 {
+		out.PromptTokensDetails = &PromptTokensDetails{CachedTokens: u.CachedTokens}
+	}
+	if u.ReasoningTokens > 0 {
+		out.CompletionTokensDetails = &CompletionTokensDetails{ReasoningTokens: u.ReasoningTokens}
+	}
+	return out
 }
 
 func (s *Server) handleOpenAIModels(w http.ResponseWriter, r *http.Request) {
@@ -382,3 +461,178 @@ func (s *Server) openAIError(w http.ResponseWriter, status int, code, message st
 }
 
 func strPtr(s string) *string { return &s }
+
+// contentAttachments decodes the image and file parts of a request message
+// into attachments. Only inline data is accepted; remote URLs and uploaded
+// file IDs are rejected.
+func contentAttachments(c MessageContent) ([]shared.Attachment, error) {
+	var atts []shared.Attachment
+	for i, p := range c.Parts {
+		var a shared.Attachment
+		switch p.Type {
+		case "text", "input_text":
+			continue
+		case "image_url":
+			if p.ImageURL == nil || p.ImageURL.URL == "" {
+				return nil, fmt.Errorf("content part %d: image_url.url is required", i)
+			}
+			mimeType, data, err := media.ParseDataURL(p.ImageURL.URL)
+			if err != nil {
+				return nil, fmt.Errorf("content part %d: image_url must be a base64 data: URL: %w", i, err)
+			}
+			a = shared.Attachment{MimeType: mimeType, Data: data}
+		case "file", "input_file":
+			f := FilePart{Filename: p.Filename, FileData: p.FileData, FileID: p.FileID}
+			if p.File != nil {
+				f = *p.File
+			}
+			if f.FileData == "" {
+				if f.FileID != "" {
+					return nil, fmt.Errorf("content part %d: file_id references are not supported; send file_data", i)
+				}
+				return nil, fmt.Errorf("content part %d: file_data is required", i)
+			}
+			var err error
+			a.Name = f.Filename
+			if strings.HasPrefix(f.FileData, "data:") {
+				a.MimeType, a.Data, err = media.ParseDataURL(f.FileData)
+			} else {
+				a.Data, err = base64.StdEncoding.DecodeString(f.FileData)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("content part %d: decode file_data: %w", i, err)
+			}
+		default:
+			return nil, fmt.Errorf("content part %d: unsupported type %q", i, p.Type)
+		}
+		if len(atts) == media.MaxAttachments {
+			return nil, fmt.Errorf("too many attachments; the limit is %d", media.MaxAttachments)
+		}
+		prepared, err := media.Prepare(a)
+		if err != nil {
+			return nil, fmt.Errorf("content part %d: %w", i, err)
+		}
+		atts = append(atts, prepared)
+	}
+	return atts, nil
+}
+
+// clientToolNamePattern is the name format OpenAI accepts for functions.
+var clientToolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// requestClientTools returns the client tool settings of a request, or nil
+// when it leaves the agent's tools as they are.
+func requestClientTools(req ChatCompletionRequest) (*shared.ClientTools, error) {
+	ct := &shared.ClientTools{NoBuiltinTools: req.BuiltinTools != nil && !*req.BuiltinTools}
+	seen := make(map[string]bool, len(req.Tools))
+	for i, t := range req.Tools {
+		if t.Type != "function" {
+			return nil, fmt.Errorf("tools[%d]: unsupported type %q", i, t.Type)
+		}
+		name := t.Function.Name
+		if !clientToolNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("tools[%d]: function name must be 1-64 letters, digits, underscores or dashes", i)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("tools[%d]: duplicate function %q", i, name)
+		}
+		seen[name] = true
+		ct.Tools = append(ct.Tools, shared.ClientTool{
+			Name:        name,
+			Description: t.Function.Description,
+			Parameters:  t.Function.Parameters,
+		})
+	}
+
+	if len(req.ToolChoice) > 0 && string(req.ToolChoice) != "null" {
+		var choice string
+		if json.Unmarshal(req.ToolChoice, &choice) == nil {
+			switch choice {
+			case shared.ToolChoiceAuto, shared.ToolChoiceNone, shared.ToolChoiceRequired:
+				ct.Choice = choice
+			default:
+				return nil, fmt.Errorf("tool_choice: unsupported value %q", choice)
+			}
+		} else {
+			// A named function is forced by offering only that tool.
+			var named struct {
+				Type     string `json:"type"`
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			}
+			if err := json.Unmarshal(req.ToolChoice, &named); err != nil || named.Type != "function" {
+				return nil, fmt.Errorf("tool_choice: must be \"auto\", \"none\", \"required\" or a function")
+			}
+			if !seen[named.Function.Name] {
+				return nil, fmt.Errorf("tool_choice: unknown function %q", named.Function.Name)
+			}
+			for _, t := range ct.Tools {
+				if t.Name == named.Function.Name {
+					ct.Tools = []shared.ClientTool{t}
+					break
+				}
+			}
+			ct.Choice = shared.ToolChoiceRequired
+			ct.NoBuiltinTools = true
+		}
+	}
+
+	if len(ct.Tools) == 0 && ct.Choice == "" && !ct.NoBuiltinTools {
+		return nil, nil
+	}
+	return ct, nil
+}
+
+// clientToolRounds parses the messages that follow the user message of a
+// continued turn: each assistant message with tool calls is followed by a
+// tool message for every call.
+func clientToolRounds(msgs []ChatRequestMessage) ([]shared.ClientToolRound, error) {
+	var rounds []shared.ClientToolRound
+	for i := 0; i < len(msgs); {
+		msg := msgs[i]
+		if msg.Role != "assistant" || len(msg.ToolCalls) == 0 {
+			return nil, fmt.Errorf("messages: tool messages must follow an assistant message with tool_calls")
+		}
+		round := shared.ClientToolRound{Text: msg.Content.Text}
+		open := make(map[string]bool, len(msg.ToolCalls))
+		for _, call := range msg.ToolCalls {
+			if call.ID == "" || call.Function.Name == "" {
+				return nil, fmt.Errorf("messages: tool calls need an id and a function name")
+			}
+			round.Calls = append(round.Calls, shared.ClientToolCall{
+				ID:        call.ID,
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			})
+			open[call.ID] = true
+		}
+		for i++; i < len(msgs) && msgs[i].Role == "tool"; i++ {
+			id := msgs[i].ToolCallID
+			if !open[id] {
+				return nil, fmt.Errorf("messages: tool message for unknown or answered tool call %q", id)
+			}
+			delete(open, id)
+			round.Results = append(round.Results, shared.ClientToolResult{CallID: id, Content: msgs[i].Content.Text})
+		}
+		if len(open) > 0 {
+			return nil, fmt.Errorf("messages: every tool call needs a tool message")
+		}
+		rounds = append(rounds, round)
+	}
+	return rounds, nil
+}
+
+// responseToolCalls converts client tool calls to their API form.
+func responseToolCalls(calls []shared.ClientToolCall) []ToolCall {
+	var out []ToolCall
+	for i, call := range calls {
+		out = append(out, ToolCall{
+			Index:    i,
+			ID:       call.ID,
+			Type:     "function",
+			Function: ToolFunction{Name: call.Name, Arguments: call.Arguments},
+		})
+	}
+	return out
+}
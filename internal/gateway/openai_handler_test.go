@@ -3,17 +3,25 @@ package gateway_test
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/engine"
+	"github.com/basket/go-claw/internal/gateway"
+	"github.com/basket/go-claw/internal/shared"
+	"github.com/google/uuid"
 )
 
 func TestOpenAI_ToolsAccepted(t *testing.T) {
 	ts, _ := apiTestServer(t)
 
-	// Requests with tools field should be accepted (tools are ignored, not rejected).
+	// Requests with client tools are accepted.
 	body := `{
 		"model": "goclaw-v1",
 		"messages": [{"role": "user", "content": "hello"}],
@@ -38,7 +46,7 @@ func TestOpenAI_ToolsAccepted(t *testing.T) {
 	}
 	defer resp.Body.Close()
 
-	// Must NOT be 400 — tools are accepted and ignored.
+	// Must NOT be 400 — tools are accepted.
 	if resp.StatusCode == http.StatusBadRequest {
 		rawBody, _ := io.ReadAll(resp.Body)
 		t.Fatalf("got unexpected 400 for request with tools: %s", string(rawBody))
@@ -363,3 +371,325 @@ func TestOpenAI_ToolCallFormat(t *testing.T) {
 		t.Error("expected arguments to be non-empty")
 	}
 }
+
+func TestOpenAI_ContentParts_StoredAsAttachments(t *testing.T) {
+	store := openStoreForGatewayTest(t)
+	eng := engine.New(store, engine.EchoProcessor{Brain: &mockStreamBrain{chunks: []string{"a cat"}}}, engine.Config{
+		WorkerCount:  1,
+		PollInterval: 5 * time.Millisecond,
+		TaskTimeout:  5 * time.Second,
+	})
+	srv := gateway.New(gateway.Config{
+		Store:     store,
+		Registry:  makeTestRegistry(store, eng),
+		Policy:    gatewayTestPolicy,
+		AuthToken: gatewayTestAuthToken,
+	})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	body := `{
+		"model": "goclaw-v1",
+		"user": "multimodal-user",
+		"stream": true,
+		"messages": [
+			{"role": "user", "content": [{"type": "text", "text": "What is in this image?"}, {"type": "image_url", "image_url": {"url": "data:image/gif;base64,R0lGODlhAQABAAAAACw="}}]},
+			{"role": "assistant", "content": "A pixel."},
+			{"role": "user", "content": [{"type": "input_file", "filename": "notes.txt", "file_data": "aGVsbG8="}]}
+		]
+	}`
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+gatewayTestAuthToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /v1/chat/completions: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, b)
+	}
+	_, _ = io.ReadAll(resp.Body)
+
+	sessionID := uuid.NewSHA1(uuid.NameSpaceURL, []byte("goclaw:user:multimodal-user:agent:default")).String()
+	history, err := store.ListHistory(context.Background(), sessionID, "", 100)
+	if err != nil {
+		t.Fatalf("list history: %v", err)
+	}
+	var got []string
+	for _, h := range history {
+		for _, a := range h.Attachments {
+			got = append(got, h.Role+":"+a.MimeType+":"+a.Name)
+		}
+	}
+	want := []string{"user:image/gif:", "user:text/plain:notes.txt"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("stored attachments = %v, want %v", got, want)
+	}
+	last := history[len(history)-1]
+	if last.Role != "user" || last.Content == "" {
+		t.Errorf("file-only message should get a default prompt, got %+v", last)
+	}
+}
+
+func TestOpenAI_ContentParts_Rejected(t *testing.T) {
+	ts, _ := apiTestServer(t)
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"remote image url", `[{"type": "image_url", "image_url": {"url": "https://example.com/cat.png"}}]`},
+		{"file id", `[{"type": "file", "file": {"file_id": "file-abc"}}]`},
+		{"unsupported type", `[{"type": "input_audio", "input_audio": {}}]`},
+		{"unsupported file", `[{"type": "input_file", "filename": "a.zip", "file_data": "UEsDBA=="}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"model": "goclaw-v1", "messages": [{"role": "user", "content": ` + tt.content + `}]}`
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+gatewayTestAuthToken)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("POST: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				b, _ := io.ReadAll(resp.Body)
+				t.Fatalf("status = %d, want 400: %s", resp.StatusCode, b)
+			}
+		})
+	}
+}
+
+// clientToolBrain calls the client's get_weather tool, then answers with the
+// result once the client sends it.
+type clientToolBrain struct{}
+
+func (clientToolBrain) reply(ctx context.Context) string {
+	ct := shared.GetClientTools(ctx)
+	if ct == nil {
+		return "no tools"
+	}
+	if len(ct.Rounds) == 0 {
+		ct.SetPending([]shared.ClientToolCall{{ID: "call_w1", Name: "get_weather", Arguments: `{"city":"Paris"}`}})
+		return ""
+	}
+	last := ct.Rounds[len(ct.Rounds)-1]
+	return "Weather: " + last.Results[0].Content
+}
+
+func (b clientToolBrain) Respond(ctx context.Context, _, _ string) (string, error) {
+	return b.reply(ctx), nil
+}
+
+func (b clientToolBrain) Stream(ctx context.Context, _, _ string, onChunk func(string) error) error {
+	if reply := b.reply(ctx); reply != "" {
+		return onChunk(reply)
+	}
+	return nil
+}
+
+func TestOpenAI_ClientToolCalls(t *testing.T) {
+	store := openStoreForGatewayTest(t)
+	eng := engine.New(store, engine.EchoProcessor{Brain: clientToolBrain{}}, engine.Config{
+		WorkerCount:  1,
+		PollInterval: 5 * time.Millisecond,
+		TaskTimeout:  5 * time.Second,
+	})
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eng.Start(runCtx)
+	srv := gateway.New(gateway.Config{
+		Store:     store,
+		Registry:  makeTestRegistry(store, eng),
+		Policy:    gatewayTestPolicy,
+		AuthToken: gatewayTestAuthToken,
+	})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	const tools = `"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}]`
+	const question = `{"role": "user", "content": "Weather in Paris?"}`
+	const followUp = `{"role": "assistant", "content": null, "tool_calls": [{"id": "call_w1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]},
+		{"role": "tool", "tool_call_id": "call_w1", "content": "sunny"}`
+
+	// post returns the assistant message and finish reason of a completion,
+	// assembling streamed chunks.
+	post := func(t *testing.T, stream bool, messages string) (gateway.ChatCompletionMessage, string) {
+		t.Helper()
+		body := fmt.Sprintf(`{"model": "goclaw-v1", "stream": %v, %s, "messages": [%s]}`, stream, tools, messages)
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+gatewayTestAuthToken)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d: %s", resp.StatusCode, raw)
+		}
+		if !stream {
+			var out gateway.ChatCompletionResponse
+			if err := json.Unmarshal(raw, &out); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			return *out.Choices[0].Message, *out.Choices[0].FinishReason
+		}
+		var msg gateway.ChatCompletionMessage
+		var finish string
+		for _, line := range strings.Split(string(raw), "\n") {
+			data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: ")
+			if !ok || data == "[DONE]" {
+				continue
+			}
+			var chunk gateway.ChatCompletionResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				t.Fatalf("decode chunk %s: %v", data, err)
+			}
+			msg.Content += chunk.Choices[0].Delta.Content
+			msg.ToolCalls = append(msg.ToolCalls, chunk.Choices[0].Delta.ToolCalls...)
+			if chunk.Choices[0].FinishReason != nil {
+				finish = *chunk.Choices[0].FinishReason
+			}
+		}
+		return msg, finish
+	}
+
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			msg, finish := post(t, stream, question)
+			if finish != "tool_calls" {
+				t.Fatalf("finish_reason = %q, want tool_calls", finish)
+			}
+			if len(msg.ToolCalls) != 1 {
+				t.Fatalf("tool_calls = %+v, want one", msg.ToolCalls)
+			}
+			call := msg.ToolCalls[0]
+			if call.ID != "call_w1" || call.Type != "function" || call.Function.Name != "get_weather" || call.Function.Arguments != `{"city":"Paris"}` {
+				t.Errorf("tool call = %+v", call)
+			}
+
+			msg, finish = post(t, stream, question+", "+followUp)
+			if finish != "stop" || msg.Content != "Weather: sunny" || len(msg.ToolCalls) != 0 {
+				t.Errorf("continued turn = %q (%s, %d tool calls), want the answer", msg.Content, finish, len(msg.ToolCalls))
+			}
+		})
+	}
+}
+
+// builtinThenClientToolBrain runs a built-in tool, announced on the bus,
+// before stopping on a client tool call.
+type builtinThenClientToolBrain struct {
+	bus *bus.Bus
+}
+
+func (b builtinThenClientToolBrain) Respond(ctx context.Context, _, _ string) (string, error) {
+	return "", nil
+}
+
+func (b builtinThenClientToolBrain) Stream(ctx context.Context, _, _ string, _ func(string) error) error {
+	b.bus.Publish(bus.TopicStreamToolCall, bus.StreamToolCallEvent{ToolName: "web_search"})
+	// Give the handler's subscriber time to see the event.
+	time.Sleep(50 * time.Millisecond)
+	if ct := shared.GetClientTools(ctx); ct != nil {
+		ct.SetPending([]shared.ClientToolCall{{ID: "call_w1", Name: "get_weather", Arguments: `{"city":"Paris"}`}})
+	}
+	return nil
+}
+
+func TestOpenAI_Stream_ClientToolsHideBuiltinCalls(t *testing.T) {
+	store := openStoreForGatewayTest(t)
+	b := bus.New()
+	eng := engine.New(store, engine.EchoProcessor{Brain: builtinThenClientToolBrain{bus: b}}, engine.Config{
+		WorkerCount:  1,
+		PollInterval: 5 * time.Millisecond,
+		TaskTimeout:  5 * time.Second,
+	})
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eng.Start(runCtx)
+	srv := gateway.New(gateway.Config{
+		Store:     store,
+		Registry:  makeTestRegistry(store, eng),
+		Policy:    gatewayTestPolicy,
+		AuthToken: gatewayTestAuthToken,
+		Bus:       b,
+	})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	body := `{"model": "goclaw-v1", "stream": true,
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}],
+		"messages": [{"role": "user", "content": "Weather in Paris?"}]}`
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+gatewayTestAuthToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+
+	// Accumulate tool call deltas by index, as OpenAI SDKs do.
+	calls := map[int]gateway.ToolCall{}
+	for _, line := range strings.Split(string(raw), "\n") {
+		data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk gateway.ChatCompletionResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("decode chunk %s: %v", data, err)
+		}
+		for _, d := range chunk.Choices[0].Delta.ToolCalls {
+			c := calls[d.Index]
+			c.ID += d.ID
+			c.Function.Name += d.Function.Name
+			c.Function.Arguments += d.Function.Arguments
+			calls[d.Index] = c
+		}
+	}
+	if len(calls) != 1 {
+		t.Fatalf("tool calls = %+v, want only the client call", calls)
+	}
+	if c := calls[0]; c.ID != "call_w1" || c.Function.Name != "get_weather" || c.Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("client tool call = %+v", c)
+	}
+}
+
+func TestOpenAI_ClientTools_Rejected(t *testing.T) {
+	ts, _ := apiTestServer(t)
+	const weather = `{"type": "function", "function": {"name": "get_weather"}}`
+	const call = `{"role": "assistant", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{}"}}]}`
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"unsupported tool type", `"tools": [{"type": "retrieval"}], "messages": [{"role": "user", "content": "hi"}]`},
+		{"invalid function name", `"tools": [{"type": "function", "function": {"name": "get weather"}}], "messages": [{"role": "user", "content": "hi"}]`},
+		{"duplicate function", `"tools": [` + weather + `, ` + weather + `], "messages": [{"role": "user", "content": "hi"}]`},
+		{"unknown tool choice", `"tools": [` + weather + `], "tool_choice": "sometimes", "messages": [{"role": "user", "content": "hi"}]`},
+		{"tool choice of unknown function", `"tools": [` + weather + `], "tool_choice": {"type": "function", "function": {"name": "get_time"}}, "messages": [{"role": "user", "content": "hi"}]`},
+		{"tool message without tools", `"messages": [{"role": "user", "content": "hi"}, ` + call + `, {"role": "tool", "tool_call_id": "call_1", "content": "x"}]`},
+		{"tool message for unknown call", `"tools": [` + weather + `], "messages": [{"role": "user", "content": "hi"}, ` + call + `, {"role": "tool", "tool_call_id": "call_2", "content": "x"}]`},
+		{"tool message without tool call", `"tools": [` + weather + `], "messages": [{"role": "user", "content": "hi"}, {"role": "tool", "tool_call_id": "call_1", "content": "x"}]`},
+		{"unanswered tool call", `"tools": [` + weather + `], "messages": [{"role": "user", "content": "hi"}, {"role": "assistant", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather"}}, {"id": "call_2", "type": "function", "function": {"name": "get_weather"}}]}, {"role": "tool", "tool_call_id": "call_1", "content": "x"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"model": "goclaw-v1", ` + tt.body + `}`
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/chat/completions", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+gatewayTestAuthToken)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("POST: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				b, _ := io.ReadAll(resp.Body)
+				t.Fatalf("status = %d, want 400: %s", resp.StatusCode, b)
+			}
+		})
+	}
+}
@@ -1,14 +1,26 @@
 package gateway
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strings"
+)
 
 // ChatCompletionRequest represents an OpenAI-compatible chat completion request.
 type ChatCompletionRequest struct {
-	Model    string                  `json:"model"`
-	Messages []ChatCompletionMessage `json:"messages"`
-	Stream   bool                    `json:"stream,omitempty"`
-	User     string                  `json:"user,omitempty"`
-	Tools    []any                   `json:"tools,omitempty"`
+	Model    string               `json:"model"`
+	Messages []ChatRequestMessage `json:"messages"`
+	Stream   bool                 `json:"stream,omitempty"`
+	User     string               `json:"user,omitempty"`
+
+	// Function tools run by the client. The model sees them next to the
+	// agent's built-in tools; calls are returned with finish_reason
+	// "tool_calls" and the client continues the turn with "tool" messages.
+	Tools      []ChatTool      `json:"tools,omitempty"`
+	ToolChoice json.RawMessage `json:"tool_choice,omitempty"` // "auto", "none", "required" or a function
+
+	// BuiltinTools set to false offers the model only the request's tools,
+	// not the agent's own (non-standard extension).
+	BuiltinTools *bool `json:"builtin_tools,omitempty"`
 
 	// Sampling parameters.
 	Temperature *float64 `json:"temperature,omitempty"`
@@ -19,6 +31,10 @@ type ChatCompletionRequest struct {
 
 	// Structured output.
 	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// MaxToolTurns overrides the agent's max_tool_turns for this request
+	// (non-standard extension).
+	MaxToolTurns *int `json:"max_tool_turns,omitempty"`
 }
 
 // ResponseFormat specifies the format of the response.
@@ -27,6 +43,88 @@ type ResponseFormat struct {
 	JSONSchema json.RawMessage `json:"json_schema,omitempty"` // JSON Schema object
 }
 
+// ChatTool is a function tool defined by the client.
+type ChatTool struct {
+	Type     string           `json:"type"` // always "function"
+	Function ChatToolFunction `json:"function"`
+}
+
+// ChatToolFunction describes a client function tool.
+type ChatToolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"` // JSON Schema
+}
+
+// ChatRequestMessage represents a message in the request history. Its
+// content may be a string or an array of content parts. Assistant messages
+// carry the tool calls the model made; tool messages answer one of them.
+type ChatRequestMessage struct {
+	Role       string         `json:"role"`
+	Content    MessageContent `json:"content"`
+	ToolCalls  []ToolCall     `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+}
+
+// MessageContent holds request message content. Text joins the text parts;
+// Parts keeps every part when the content was sent as an array.
+type MessageContent struct {
+	Text  string
+	Parts []ContentPart
+}
+
+// UnmarshalJSON accepts a string, null, or an array of content parts.
+func (c *MessageContent) UnmarshalJSON(data []byte) error {
+	*c = MessageContent{}
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &c.Text)
+	}
+	if err := json.Unmarshal(data, &c.Parts); err != nil {
+		return err
+	}
+	var texts []string
+	for _, p := range c.Parts {
+		if p.Type == "text" || p.Type == "input_text" {
+			texts = append(texts, p.Text)
+		}
+	}
+	c.Text = strings.Join(texts, "\n")
+	return nil
+}
+
+// MarshalJSON encodes the content as its text.
+func (c MessageContent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Text)
+}
+
+// ContentPart is one element of array content: "text", "image_url", "file",
+// or "input_file" (which carries the file fields at the top level).
+type ContentPart struct {
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	ImageURL *ImageURLPart `json:"image_url,omitempty"`
+	File     *FilePart     `json:"file,omitempty"`
+	Filename string        `json:"filename,omitempty"`
+	FileData string        `json:"file_data,omitempty"`
+	FileID   string        `json:"file_id,omitempty"`
+}
+
+// ImageURLPart is the payload of an "image_url" part.
+type ImageURLPart struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// FilePart is the payload of a "file" part.
+type FilePart struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+}
+
 // ChatCompletionMessage represents a message in the chat history.
 type ChatCompletionMessage struct {
 	Role      string     `json:"role"`
@@ -68,9 +166,21 @@ type ChatCompletionChoice struct {
 
 // Usage represents token usage statistics.
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens            int                      `json:"prompt_tokens"`
+	CompletionTokens        int                      `json:"completion_tokens"`
+	TotalTokens             int                      `json:"total_tokens"`
+	PromptTokensDetails     *PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+}
+
+// PromptTokensDetails breaks down the prompt tokens.
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// CompletionTokensDetails breaks down the completion tokens.
+type CompletionTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
 }
 
 // ModelListResponse represents the response from the models list API.
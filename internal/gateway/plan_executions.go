@@ -0,0 +1,264 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/basket/go-claw/internal/coordinator"
+)
+
+// Plan executions can be paused (no new steps start), resumed, canceled
+// (running step tasks are aborted) and, once finished, re-run from a step.
+// Re-running keeps the outputs of the steps that do not depend on it.
+
+// PlanExecutionView is the API view of a plan execution.
+type PlanExecutionView struct {
+	ExecutionID    string              `json:"execution_id"`
+	PlanName       string              `json:"plan_name"`
+	SessionID      string              `json:"session_id"`
+	Status         string              `json:"status"` // running, paused, succeeded, failed or canceled
+	TotalSteps     int                 `json:"total_steps"`
+	CompletedSteps int                 `json:"completed_steps"`
+	CostUSD        float64             `json:"cost_usd"`
+	CreatedAt      time.Time           `json:"created_at"`
+	CompletedAt    *time.Time          `json:"completed_at,omitempty"`
+	Steps          []PlanStepStateView `json:"steps"`
+}
+
+// PlanStepStateView is the state of one step of a plan execution.
+type PlanStepStateView struct {
+	StepID  string  `json:"step_id"`
+	AgentID string  `json:"agent_id"`
+	Status  string  `json:"status"`
+	TaskID  string  `json:"task_id,omitempty"`
+	Error   string  `json:"error,omitempty"`
+	CostUSD float64 `json:"cost_usd,omitempty"`
+}
+
+// PlanExecution returns the state of a plan execution and its steps.
+func (s *Server) PlanExecution(ctx context.Context, executionID string) (*PlanExecutionView, error) {
+	if s.cfg.Store == nil {
+		return nil, fmt.Errorf("%w: %s", coordinator.ErrExecutionNotFound, executionID)
+	}
+	exec, err := s.cfg.Store.GetPlanExecution(ctx, executionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", coordinator.ErrExecutionNotFound, executionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get plan execution: %w", err)
+	}
+	steps, err := s.cfg.Store.GetPlanSteps(ctx, executionID)
+	if err != nil {
+		return nil, fmt.Errorf("get plan steps: %w", err)
+	}
+	view := &PlanExecutionView{
+		ExecutionID:    exec.ID,
+		PlanName:       exec.PlanName,
+		SessionID:      exec.SessionID,
+		Status:         exec.Status,
+		TotalSteps:     exec.TotalSteps,
+		CompletedSteps: exec.CompletedSteps,
+		CostUSD:        exec.TotalCostUSD,
+		CreatedAt:      exec.CreatedAt,
+		CompletedAt:    exec.CompletedAt,
+		Steps:          make([]PlanStepStateView, 0, len(steps)),
+	}
+	if exec.Status == "running" && exec.Paused {
+		view.Status = "paused"
+	}
+	for _, step := range steps {
+		view.Steps = append(view.Steps, PlanStepStateView{
+			StepID:  step.StepID,
+			AgentID: step.AgentID,
+			Status:  step.Status,
+			TaskID:  step.TaskID,
+			Error:   step.Error,
+			CostUSD: step.CostUSD,
+		})
+	}
+	return view, nil
+}
+
+// CancelPlanExecution cancels a running or paused plan execution.
+func (s *Server) CancelPlanExecution(ctx context.Context, executionID string) error {
+	if s.cfg.Executor == nil {
+		return errExecutorUnavailable
+	}
+	return s.cfg.Executor.Cancel(ctx, executionID)
+}
+
+// PausePlanExecution stops a plan execution from starting new steps.
+func (s *Server) PausePlanExecution(ctx context.Context, executionID string) error {
+	if s.cfg.Executor == nil {
+		return errExecutorUnavailable
+	}
+	return s.cfg.Executor.Pause(ctx, executionID)
+}
+
+// ResumePlanExecution lets a paused plan execution start steps again.
+func (s *Server) ResumePlanExecution(ctx context.Context, executionID string) error {
+	if s.cfg.Executor == nil {
+		return errExecutorUnavailable
+	}
+	return s.cfg.Executor.Unpause(ctx, executionID)
+}
+
+// RerunPlanExecution runs a finished plan execution again from stepID in the
+// background, with the plan's current definition and the original inputs.
+func (s *Server) RerunPlanExecution(ctx context.Context, executionID, stepID string) error {
+	if s.cfg.Executor == nil {
+		return errExecutorUnavailable
+	}
+	exec, err := s.PlanExecution(ctx, executionID)
+	if err != nil {
+		return err
+	}
+	plan, err := s.runnablePlan(ctx, exec.PlanName)
+	if err != nil {
+		return err
+	}
+	if err := s.cfg.Executor.ResetFrom(ctx, executionID, stepID, plan); err != nil {
+		return err
+	}
+	go func() {
+		ctx := context.Background() // detached from request context
+		result, err := s.cfg.Executor.Resume(ctx, executionID, plan)
+		if err != nil {
+			slog.Warn("plan re-run did not succeed", "execution_id", executionID, "step_id", stepID, "error", err)
+			return
+		}
+		slog.Info("plan re-run completed", "execution_id", executionID, "step_id", stepID, "cost", result.TotalCost())
+	}()
+	return nil
+}
+
+// handlePlanExecutionRoute serves /api/plans/executions/{id}[/{action}].
+func (s *Server) handlePlanExecutionRoute(w http.ResponseWriter, r *http.Request, rest string) {
+	if !s.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	executionID, action, _ := strings.Cut(rest, "/")
+	if executionID == "" {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	ctx := r.Context()
+	var err error
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		view, err := s.PlanExecution(ctx, executionID)
+		if err != nil {
+			writeExecutionError(w, executionID, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(view)
+		return
+	case action == "cancel" && r.Method == http.MethodPost:
+		err = s.CancelPlanExecution(ctx, executionID)
+	case action == "pause" && r.Method == http.MethodPost:
+		err = s.PausePlanExecution(ctx, executionID)
+	case action == "resume" && r.Method == http.MethodPost:
+		err = s.ResumePlanExecution(ctx, executionID)
+	case action == "rerun" && r.Method == http.MethodPost:
+		var req struct {
+			StepID string `json:"step_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.StepID == "" {
+			http.Error(w, "step_id required", http.StatusBadRequest)
+			return
+		}
+		if err := s.RerunPlanExecution(ctx, executionID, req.StepID); err != nil {
+			writeExecutionError(w, executionID, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]string{"execution_id": executionID, "step_id": req.StepID, "status": "running"})
+		return
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeExecutionError(w, executionID, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"execution_id": executionID, "action": action})
+}
+
+// writeExecutionError maps execution control errors to HTTP statuses.
+func writeExecutionError(w http.ResponseWriter, executionID string, err error) {
+	switch {
+	case errors.Is(err, coordinator.ErrExecutionNotFound), errors.Is(err, ErrPlanNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, coordinator.ErrExecutionNotRunning), errors.Is(err, coordinator.ErrExecutionRunning),
+		errors.Is(err, ErrPlanNotApproved):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, coordinator.ErrUnknownStep), errors.Is(err, ErrInvalidPlan):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errExecutorUnavailable):
+		http.Error(w, "plan executor unavailable", http.StatusServiceUnavailable)
+	default:
+		slog.Error("plan execution request failed", "execution_id", executionID, "error", err)
+		http.Error(w, "plan execution request failed", http.StatusInternalServerError)
+	}
+}
+
+// handlePlanRPC serves the plan.* ACP methods.
+func (s *Server) handlePlanRPC(ctx context.Context, method string, params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		ExecutionID string `json:"execution_id"`
+		StepID      string `json:"step_id"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil || p.ExecutionID == "" {
+		return nil, &rpcError{Code: ErrCodeInvalid, Message: "execution_id required"}
+	}
+	var err error
+	switch method {
+	case "plan.status":
+		view, err := s.PlanExecution(ctx, p.ExecutionID)
+		if err != nil {
+			return nil, executionRPCError(err)
+		}
+		return view, nil
+	case "plan.cancel":
+		err = s.CancelPlanExecution(ctx, p.ExecutionID)
+	case "plan.pause":
+		err = s.PausePlanExecution(ctx, p.ExecutionID)
+	case "plan.resume":
+		err = s.ResumePlanExecution(ctx, p.ExecutionID)
+	case "plan.rerun":
+		if p.StepID == "" {
+			return nil, &rpcError{Code: ErrCodeInvalid, Message: "step_id required"}
+		}
+		err = s.RerunPlanExecution(ctx, p.ExecutionID, p.StepID)
+	}
+	if err != nil {
+		return nil, executionRPCError(err)
+	}
+	return map[string]any{"execution_id": p.ExecutionID, "ok": true}, nil
+}
+
+// executionRPCError reports client errors as invalid requests and the rest
+// as internal errors.
+func executionRPCError(err error) *rpcError {
+	for _, clientErr := range []error{
+		coordinator.ErrExecutionNotFound, coordinator.ErrExecutionNotRunning, coordinator.ErrExecutionRunning,
+		coordinator.ErrUnknownStep, ErrPlanNotFound, ErrPlanNotApproved, ErrInvalidPlan,
+	} {
+		if errors.Is(err, clientErr) {
+			return &rpcError{Code: ErrCodeInvalid, Message: err.Error()}
+		}
+	}
+	return &rpcError{Code: ErrCodeInternal, Message: err.Error()}
+}
@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+
+	"github.com/basket/go-claw/internal/approval"
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+// respondToolApproval applies an approval.respond decision to a parked tool
+// call. Tool approvals accept approve, approve_session and deny; the reason
+// of a denial is returned to the model.
+func (s *Server) respondToolApproval(ctx context.Context, approvalID, decision, reason string) (any, *rpcError) {
+	if s.cfg.Approvals == nil {
+		return nil, &rpcError{Code: ErrCodeInvalid, Message: "approval request not found"}
+	}
+	rec, err := s.cfg.Approvals.Respond(ctx, approvalID, decision, reason)
+	if errors.Is(err, approval.ErrNotFound) {
+		return nil, &rpcError{Code: ErrCodeInvalid, Message: "approval request not found"}
+	}
+	if err != nil {
+		return nil, &rpcError{Code: ErrCodeInvalid, Message: err.Error()}
+	}
+	return map[string]any{
+		"approval_id": rec.ID,
+		"status":      rec.Status,
+	}, nil
+}
+
+// toolApprovalItems lists parked tool calls in the approval.list shape.
+func (s *Server) toolApprovalItems(ctx context.Context) []map[string]any {
+	if s.cfg.Approvals == nil {
+		return nil
+	}
+	pending, err := s.cfg.Approvals.Pending(ctx)
+	if err != nil {
+		return nil
+	}
+	items := make([]map[string]any, 0, len(pending))
+	for _, a := range pending {
+		items = append(items, toolApprovalItem(a))
+	}
+	return items
+}
+
+func toolApprovalItem(a *persistence.ToolApproval) map[string]any {
+	return map[string]any{
+		"approval_id": a.ID,
+		"kind":        "tool",
+		"action":      "tool:" + a.Tool,
+		"details":     a.Args,
+		"tool":        a.Tool,
+		"agent_id":    a.AgentID,
+		"session_id":  a.SessionID,
+		"task_id":     a.TaskID,
+		"status":      a.Status,
+		"created_at":  a.CreatedAt,
+		"expires_at":  a.ExpiresAt,
+	}
+}
+
+// forwardToolApprovals broadcasts tool approval requests and outcomes to ACP
+// clients as approval.required / approval.updated notifications.
+func (s *Server) forwardToolApprovals(ctx context.Context) {
+	if s.cfg.Bus == nil || s.cfg.Approvals == nil {
+		return
+	}
+	sub := s.cfg.Bus.Subscribe("hitl.tool_approval.") // requested and resolved
+	go func() {
+		defer s.cfg.Bus.Unsubscribe(sub)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-sub.Ch():
+				if !ok {
+					return
+				}
+				switch p := ev.Payload.(type) {
+				case bus.ToolApprovalRequest:
+					s.broadcast("approval.required", map[string]any{
+						"approval_id": p.RequestID,
+						"kind":        "tool",
+						"action":      "tool:" + p.Tool,
+						"details":     p.Args,
+						"tool":        p.Tool,
+						"agent_id":    p.AgentID,
+						"session_id":  p.SessionID,
+						"task_id":     p.TaskID,
+						"status":      persistence.ApprovalPending,
+						"expires_at":  p.ExpiresAt,
+					})
+				case bus.ToolApprovalResolved:
+					s.broadcast("approval.updated", map[string]any{
+						"approval_id": p.RequestID,
+						"kind":        "tool",
+						"status":      p.Status,
+						"reason":      p.Reason,
+					})
+				}
+			}
+		}
+	}()
+}
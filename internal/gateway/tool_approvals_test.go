@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/approval"
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+func TestToolApprovals_ListAndRespond(t *testing.T) {
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "goclaw.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	eventBus := bus.New()
+	sub := eventBus.Subscribe(bus.TopicToolApprovalRequested)
+	defer eventBus.Unsubscribe(sub)
+	broker := approval.NewBroker(store, eventBus)
+	srv := New(Config{Store: store, Bus: eventBus, Approvals: broker})
+	ctx := context.Background()
+
+	done := make(chan approval.Decision, 1)
+	go func() {
+		d, _ := broker.Request(ctx, approval.Request{TaskID: "t1", AgentID: "coder", Tool: "write_file", Args: "notes.md", Timeout: time.Minute})
+		done <- d
+	}()
+	var id string
+	select {
+	case ev := <-sub.Ch():
+		id = ev.Payload.(bus.ToolApprovalRequest).RequestID
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for approval request")
+	}
+
+	items := srv.toolApprovalItems(ctx)
+	if len(items) != 1 || items[0]["approval_id"] != id || items[0]["kind"] != "tool" || items[0]["agent_id"] != "coder" {
+		t.Fatalf("approval.list items = %+v", items)
+	}
+
+	result, rpcErr := srv.respondToolApproval(ctx, id, "deny", "wrong file")
+	if rpcErr != nil {
+		t.Fatalf("respondToolApproval: %+v", rpcErr)
+	}
+	if status := result.(map[string]any)["status"]; status != persistence.ApprovalDenied {
+		t.Errorf("status = %v, want %s", status, persistence.ApprovalDenied)
+	}
+	select {
+	case d := <-done:
+		if d.Approved || d.Reason != "wrong file" {
+			t.Errorf("decision = %+v", d)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for decision")
+	}
+
+	if _, rpcErr := srv.respondToolApproval(ctx, "missing", "approve", ""); rpcErr == nil || rpcErr.Message != "approval request not found" {
+		t.Errorf("unknown approval: err = %+v", rpcErr)
+	}
+}
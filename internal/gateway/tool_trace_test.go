@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/google/uuid"
+)
+
+func TestAPITaskByID_IncludesToolCalls(t *testing.T) {
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "goclaw.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	sessionID := uuid.NewString()
+	if err := store.EnsureSession(ctx, sessionID); err != nil {
+		t.Fatalf("EnsureSession: %v", err)
+	}
+	taskID, err := store.CreateTask(ctx, sessionID, `{"content":"hi"}`)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if err := store.RecordToolCall(ctx, &persistence.ToolCall{TaskID: taskID, SessionID: sessionID, Tool: "exec", Args: `{"command":"ls"}`, Output: "ok", DurationMS: 7}); err != nil {
+		t.Fatalf("RecordToolCall: %v", err)
+	}
+	srv := New(Config{Store: store, AuthToken: "secret"})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tasks/"+taskID, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	srv.handleAPITaskByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		ID        string                 `json:"id"`
+		ToolCalls []persistence.ToolCall `json:"tool_calls"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.ID != taskID {
+		t.Errorf("id = %q, want %q", body.ID, taskID)
+	}
+	if len(body.ToolCalls) != 1 || body.ToolCalls[0].Tool != "exec" || body.ToolCalls[0].DurationMS != 7 {
+		t.Errorf("tool_calls = %+v, want the exec call", body.ToolCalls)
+	}
+}
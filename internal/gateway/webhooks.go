@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+// handleAPIWebhookDeliveries serves the outbound webhook queue:
+//
+//	GET  /api/webhooks/deliveries?status=dead&limit=N  list deliveries (default: dead letters)
+//	POST /api/webhooks/deliveries/{id}/retry           requeue a dead letter
+func (s *Server) handleAPIWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if s.cfg.Store == nil {
+		http.Error(w, "store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/webhooks/deliveries"), "/")
+	if rest == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = persistence.WebhookDeliveryDead
+		}
+		if status != persistence.WebhookDeliveryDead && status != persistence.WebhookDeliveryPending {
+			http.Error(w, "status must be pending or dead", http.StatusBadRequest)
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		deliveries, err := s.cfg.Store.ListWebhookDeliveries(r.Context(), status, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if deliveries == nil {
+			deliveries = []*persistence.WebhookDelivery{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"deliveries": deliveries})
+		return
+	}
+
+	id, action, ok := strings.Cut(rest, "/")
+	if !ok || action != "retry" || id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.cfg.Store.RetryWebhookDelivery(r.Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "no dead letter with that id", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"id": id, "status": persistence.WebhookDeliveryPending})
+}
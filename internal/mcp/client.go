@@ -3,11 +3,18 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
+// reinitTimeout bounds a re-initialization triggered by a transport
+// reconnect, which has no caller context.
+const reinitTimeout = 30 * time.Second
+
 // Client implements a basic MCP client.
 type Client struct {
 	name      string
@@ -16,8 +23,22 @@ type Client struct {
 
 	pendingMu sync.Mutex
 	pending   map[int64]chan jsonRPCResponse
+
+	notifyMu sync.RWMutex
+	onNotify NotificationHandler
+	onReinit func()
+
+	// initMu serializes re-initialization; sessionGen counts sessions so
+	// concurrent callers hitting the same expiry re-initialize only once.
+	initMu     sync.Mutex
+	sessionGen atomic.Int64
 }
 
+// NotificationHandler receives server-initiated JSON-RPC notifications
+// (e.g. notifications/resources/updated). It runs on the client's listen
+// goroutine and must not block on further calls to the same client.
+type NotificationHandler func(method string, params json.RawMessage)
+
 type jsonRPCRequest struct {
 	JSONRPC string          `json:"jsonrpc"`
 	Method  string          `json:"method"`
@@ -56,6 +77,20 @@ func NewClient(name string, transport Transport) (*Client, error) {
 		transport: transport,
 		pending:   make(map[int64]chan jsonRPCResponse),
 	}
+	// Transports that can move to a new server session on their own
+	// (an SSE stream reconnecting) need the handshake repeated.
+	if n, ok := transport.(sessionResetNotifier); ok {
+		n.onSessionReset(func() {
+			gen := c.sessionGen.Load()
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), reinitTimeout)
+				defer cancel()
+				if err := c.reinitialize(ctx, gen); err != nil {
+					slog.Warn("mcp: re-initialize after reconnect failed", "server", c.name, "error", err)
+				}
+			}()
+		})
+	}
 	// Start listener
 	go c.listen()
 	return c, nil
@@ -69,9 +104,23 @@ func (c *Client) listen() {
 			return
 		}
 
+		// Server-initiated messages carry a method; responses never do.
+		var probe struct {
+			Method string          `json:"method"`
+			ID     json.RawMessage `json:"id"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(msg, &probe); err == nil && probe.Method != "" {
+			if len(probe.ID) == 0 {
+				c.dispatchNotification(probe.Method, probe.Params)
+			}
+			// Server-to-client requests are not supported; ignore them.
+			continue
+		}
+
 		var resp jsonRPCResponse
 		if err := json.Unmarshal(msg, &resp); err != nil {
-			// Maybe it's a notification or invalid JSON, ignore for now
+			// Invalid JSON, ignore
 			continue
 		}
 
@@ -87,7 +136,68 @@ func (c *Client) listen() {
 	}
 }
 
+// OnNotification registers the handler for server notifications, replacing any previous one.
+func (c *Client) OnNotification(fn NotificationHandler) {
+	c.notifyMu.Lock()
+	defer c.notifyMu.Unlock()
+	c.onNotify = fn
+}
+
+// OnReinitialize registers fn to run after the client re-initialized a new
+// server session. Server-side state such as resource subscriptions is lost
+// with the old session.
+func (c *Client) OnReinitialize(fn func()) {
+	c.notifyMu.Lock()
+	defer c.notifyMu.Unlock()
+	c.onReinit = fn
+}
+
+func (c *Client) dispatchNotification(method string, params json.RawMessage) {
+	c.notifyMu.RLock()
+	fn := c.onNotify
+	c.notifyMu.RUnlock()
+	if fn != nil {
+		fn(method, params)
+	}
+}
+
+// call sends a request and waits for its response. When the server no
+// longer knows the session the client re-initializes and retries once; the
+// expired request was rejected before it ran, so this is safe for tools/call.
 func (c *Client) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	gen := c.sessionGen.Load()
+	res, err := c.roundTrip(ctx, method, params)
+	if !errors.Is(err, ErrSessionExpired) || method == "initialize" {
+		return res, err
+	}
+	slog.Info("mcp: session expired, re-initializing", "server", c.name)
+	if err := c.reinitialize(ctx, gen); err != nil {
+		return nil, fmt.Errorf("re-initialize expired session: %w", err)
+	}
+	return c.roundTrip(ctx, method, params)
+}
+
+// reinitialize repeats the handshake unless another caller already did so
+// since session generation gen.
+func (c *Client) reinitialize(ctx context.Context, gen int64) error {
+	c.initMu.Lock()
+	defer c.initMu.Unlock()
+	if c.sessionGen.Load() != gen {
+		return nil
+	}
+	if err := c.Initialize(ctx); err != nil {
+		return err
+	}
+	c.notifyMu.RLock()
+	fn := c.onReinit
+	c.notifyMu.RUnlock()
+	if fn != nil {
+		fn()
+	}
+	return nil
+}
+
+func (c *Client) roundTrip(ctx context.Context, method string, params any) (json.RawMessage, error) {
 	id := atomic.AddInt64(&c.nextID, 1)
 
 	var paramsJSON json.RawMessage
@@ -172,6 +282,7 @@ func (c *Client) Initialize(ctx context.Context) error {
 	if err := c.transport.Send(ctx, b); err != nil {
 		return fmt.Errorf("send initialized notification: %w", err)
 	}
+	c.sessionGen.Add(1)
 
 	return nil
 }
@@ -210,6 +321,165 @@ func (c *Client) CallTool(ctx context.Context, name string, args json.RawMessage
 	return res, nil
 }
 
+// MCPResource is a resource advertised by resources/list.
+type MCPResource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceContent is one item returned by resources/read.
+// Exactly one of Text or Blob (base64) is set.
+type ResourceContent struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// MCPPrompt is a prompt template advertised by prompts/list.
+type MCPPrompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument describes a prompt template argument.
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptMessage is one message of a rendered prompt.
+type PromptMessage struct {
+	Role    string `json:"role"`
+	Content struct {
+		Type     string           `json:"type"`
+		Text     string           `json:"text,omitempty"`
+		Resource *ResourceContent `json:"resource,omitempty"`
+	} `json:"content"`
+}
+
+// PromptResult is the result of prompts/get.
+type PromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// maxListPages bounds cursor pagination against misbehaving servers.
+const maxListPages = 100
+
+// listPaged calls a paginated list method and collects the items under key.
+func (c *Client) listPaged(ctx context.Context, method, key string, collect func(json.RawMessage) error) error {
+	cursor := ""
+	for page := 0; page < maxListPages; page++ {
+		var params any
+		if cursor != "" {
+			params = map[string]string{"cursor": cursor}
+		}
+		res, err := c.call(ctx, method, params)
+		if err != nil {
+			return fmt.Errorf("%s failed: %w", method, err)
+		}
+		var envelope map[string]json.RawMessage
+		if err := json.Unmarshal(res, &envelope); err != nil {
+			return fmt.Errorf("unmarshal %s: %w", key, err)
+		}
+		if items, ok := envelope[key]; ok {
+			if err := collect(items); err != nil {
+				return fmt.Errorf("unmarshal %s: %w", key, err)
+			}
+		}
+		cursor = ""
+		if raw, ok := envelope["nextCursor"]; ok {
+			_ = json.Unmarshal(raw, &cursor)
+		}
+		if cursor == "" {
+			return nil
+		}
+	}
+	return nil
+}
+
+// ListResources calls resources/list, following pagination cursors.
+func (c *Client) ListResources(ctx context.Context) ([]MCPResource, error) {
+	var all []MCPResource
+	err := c.listPaged(ctx, "resources/list", "resources", func(raw json.RawMessage) error {
+		var page []MCPResource
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		all = append(all, page...)
+		return nil
+	})
+	return all, err
+}
+
+// ReadResource calls resources/read.
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]ResourceContent, error) {
+	res, err := c.call(ctx, "resources/read", map[string]string{"uri": uri})
+	if err != nil {
+		return nil, fmt.Errorf("resources/read failed: %w", err)
+	}
+	var result struct {
+		Contents []ResourceContent `json:"contents"`
+	}
+	if err := json.Unmarshal(res, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal resource contents: %w", err)
+	}
+	return result.Contents, nil
+}
+
+// SubscribeResource calls resources/subscribe. The server then sends
+// notifications/resources/updated when the resource changes.
+func (c *Client) SubscribeResource(ctx context.Context, uri string) error {
+	if _, err := c.call(ctx, "resources/subscribe", map[string]string{"uri": uri}); err != nil {
+		return fmt.Errorf("resources/subscribe failed: %w", err)
+	}
+	return nil
+}
+
+// UnsubscribeResource calls resources/unsubscribe.
+func (c *Client) UnsubscribeResource(ctx context.Context, uri string) error {
+	if _, err := c.call(ctx, "resources/unsubscribe", map[string]string{"uri": uri}); err != nil {
+		return fmt.Errorf("resources/unsubscribe failed: %w", err)
+	}
+	return nil
+}
+
+// ListPrompts calls prompts/list, following pagination cursors.
+func (c *Client) ListPrompts(ctx context.Context) ([]MCPPrompt, error) {
+	var all []MCPPrompt
+	err := c.listPaged(ctx, "prompts/list", "prompts", func(raw json.RawMessage) error {
+		var page []MCPPrompt
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		all = append(all, page...)
+		return nil
+	})
+	return all, err
+}
+
+// GetPrompt calls prompts/get with the given template arguments.
+func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]string) (*PromptResult, error) {
+	params := map[string]any{"name": name}
+	if len(args) > 0 {
+		params["arguments"] = args
+	}
+	res, err := c.call(ctx, "prompts/get", params)
+	if err != nil {
+		return nil, fmt.Errorf("prompts/get failed: %w", err)
+	}
+	var result PromptResult
+	if err := json.Unmarshal(res, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal prompt: %w", err)
+	}
+	return &result, nil
+}
+
 func (c *Client) Close() error {
 	return c.transport.Close()
 }
@@ -0,0 +1,819 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// httpConnectTimeout bounds how long NewSSETransport waits for the
+	// server to announce its message endpoint.
+	httpConnectTimeout = 10 * time.Second
+
+	// maxSSEEventSize caps a single server-sent event (1 MiB).
+	maxSSEEventSize = 1 << 20
+
+	// sessionHeader carries the Streamable HTTP session ID.
+	sessionHeader = "Mcp-Session-Id"
+)
+
+// ErrSessionExpired is returned when a Streamable HTTP server no longer
+// recognizes the session ID. Client re-initializes and retries the request
+// once before surfacing it.
+var ErrSessionExpired = errors.New("mcp: session expired")
+
+// sseEvent is a single dispatched server-sent event.
+type sseEvent struct {
+	Event string
+	Data  string
+	ID    string
+}
+
+// readSSE parses a text/event-stream body and invokes fn for each event.
+// It returns nil when the stream ends cleanly.
+func readSSE(r io.Reader, fn func(sseEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSEEventSize)
+
+	var ev sseEvent
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 || ev.Event != "" {
+				ev.Data = strings.Join(data, "\n")
+				if ev.Event == "" {
+					ev.Event = "message"
+				}
+				if err := fn(ev); err != nil {
+					return err
+				}
+			}
+			ev = sseEvent{}
+			data = nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment / keep-alive
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			ev.ID = value
+		}
+	}
+	return scanner.Err()
+}
+
+// httpTransportBase holds the state shared by the SSE and Streamable HTTP transports.
+// Incoming JSON-RPC messages are queued on a channel so Client.listen can
+// correlate responses exactly as it does for stdio.
+type httpTransportBase struct {
+	client       *http.Client
+	headers      map[string]string
+	maxRetry     int
+	retryBackoff time.Duration
+
+	incoming chan json.RawMessage
+	done     chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	closeOnce sync.Once
+	errMu     sync.Mutex
+	closeErr  error
+}
+
+func newHTTPTransportBase(headers map[string]string) httpTransportBase {
+	ctx, cancel := context.WithCancel(context.Background())
+	expanded := make(map[string]string, len(headers))
+	for k, v := range headers {
+		// Expand environment variables in values (e.g. "Bearer ${TOKEN}").
+		expanded[k] = os.ExpandEnv(v)
+	}
+	return httpTransportBase{
+		client:       &http.Client{},
+		headers:      expanded,
+		maxRetry:     3,
+		retryBackoff: time.Second,
+		incoming:     make(chan json.RawMessage, 64),
+		done:         make(chan struct{}),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+func (b *httpTransportBase) applyHeaders(req *http.Request) {
+	for k, v := range b.headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// deliver queues an incoming message for Receive. It returns false once the
+// transport has been closed.
+func (b *httpTransportBase) deliver(msg []byte) bool {
+	msg = bytes.TrimSpace(msg)
+	if len(msg) == 0 {
+		return true
+	}
+	// JSON-RPC batches are unpacked so the client sees one message at a time.
+	if msg[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(msg, &batch); err == nil {
+			for _, m := range batch {
+				if !b.deliver(m) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	select {
+	case b.incoming <- json.RawMessage(append([]byte(nil), msg...)):
+		return true
+	case <-b.done:
+		return false
+	}
+}
+
+// Receive blocks until a message is received, the context is cancelled or
+// the transport is closed.
+func (b *httpTransportBase) Receive(ctx context.Context) (json.RawMessage, error) {
+	select {
+	case msg := <-b.incoming:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-b.done:
+		b.errMu.Lock()
+		err := b.closeErr
+		b.errMu.Unlock()
+		if err == nil {
+			err = fmt.Errorf("transport closed")
+		}
+		return nil, err
+	}
+}
+
+// shutdown stops background streams and unblocks Receive with err.
+func (b *httpTransportBase) shutdown(err error) {
+	b.closeOnce.Do(func() {
+		b.errMu.Lock()
+		b.closeErr = err
+		b.errMu.Unlock()
+		b.cancel()
+		close(b.done)
+	})
+}
+
+func (b *httpTransportBase) closed() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// idempotentMethod reports whether the JSON-RPC message in body may be sent
+// again when the first attempt might already have reached the server.
+func idempotentMethod(body []byte) bool {
+	var msg struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return false
+	}
+	switch msg.Method {
+	case "initialize", "ping", "resources/read", "prompts/get":
+		return true
+	}
+	return strings.HasSuffix(msg.Method, "/list")
+}
+
+// isDialError reports whether err happened while connecting, before any
+// part of the request was sent.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// postWithRetry POSTs body to target with exponential backoff. Requests that
+// never reached the server (dial errors) are always retried; idempotent
+// methods are also retried after other connection failures and gateway
+// errors. Anything else, tools/call in particular, is sent at most once.
+func (b *httpTransportBase) postWithRetry(ctx context.Context, target string, body []byte, extra func(*http.Request)) (*http.Response, error) {
+	idempotent := idempotentMethod(body)
+	backoff := b.retryBackoff
+	var lastErr error
+	for attempt := 0; attempt <= b.maxRetry; attempt++ {
+		if attempt > 0 {
+			slog.Info("mcp: retrying http request", "url", target, "attempt", attempt, "backoff", backoff)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-b.done:
+				return nil, fmt.Errorf("transport closed")
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("build request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		b.applyHeaders(req)
+		if extra != nil {
+			extra(req)
+		}
+
+		resp, err := b.client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if !idempotent && !isDialError(err) {
+				return nil, fmt.Errorf("mcp: post: %w", err)
+			}
+			lastErr = err
+			continue
+		}
+		switch resp.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			if !idempotent {
+				return resp, nil
+			}
+			lastErr = fmt.Errorf("http status %d", resp.StatusCode)
+			drainAndClose(resp.Body)
+			continue
+		}
+		return resp, nil
+	}
+	return nil, fmt.Errorf("mcp: post failed after %d attempts: %w", b.maxRetry+1, lastErr)
+}
+
+// statusError builds an error from a non-success response, including a body snippet.
+func statusError(resp *http.Response) error {
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	msg := strings.TrimSpace(string(snippet))
+	if msg == "" {
+		return fmt.Errorf("http status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("http status %d: %s", resp.StatusCode, msg)
+}
+
+func drainAndClose(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, 64*1024))
+	_ = body.Close()
+}
+
+// SSETransport implements the MCP HTTP+SSE transport (protocol 2024-11-05).
+// The client holds a GET event stream open; the server announces a message
+// endpoint via an "endpoint" event and delivers JSON-RPC responses as
+// "message" events. Requests are sent as POSTs to the announced endpoint.
+type SSETransport struct {
+	httpTransportBase
+	url string
+
+	mu          sync.Mutex
+	endpoint    string
+	lastEventID string
+	ready       chan struct{}
+	readyOnce   sync.Once
+	onReset     func()
+}
+
+// NewSSETransport opens the event stream at rawURL and waits for the server
+// to announce its message endpoint. Header values are environment-expanded.
+func NewSSETransport(rawURL string, headers map[string]string) (*SSETransport, error) {
+	if _, err := url.Parse(rawURL); err != nil || strings.TrimSpace(rawURL) == "" {
+		return nil, fmt.Errorf("invalid sse url %q", rawURL)
+	}
+	t := &SSETransport{
+		httpTransportBase: newHTTPTransportBase(headers),
+		url:               rawURL,
+		ready:             make(chan struct{}),
+	}
+
+	// The first connection is made synchronously so configuration errors
+	// (bad URL, auth failure) surface from the constructor.
+	resp, err := t.openStream(t.ctx)
+	if err != nil {
+		t.shutdown(err)
+		return nil, err
+	}
+	t.wg.Add(1)
+	go t.run(resp)
+
+	select {
+	case <-t.ready:
+		return t, nil
+	case <-t.done:
+		t.errMu.Lock()
+		err := t.closeErr
+		t.errMu.Unlock()
+		return nil, fmt.Errorf("sse stream closed before endpoint event: %w", err)
+	case <-time.After(httpConnectTimeout):
+		_ = t.Close()
+		return nil, fmt.Errorf("sse server %q did not announce an endpoint within %s", rawURL, httpConnectTimeout)
+	}
+}
+
+// openStream issues the GET request for the event stream.
+func (t *SSETransport) openStream(ctx context.Context) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build sse request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	t.applyHeaders(req)
+	t.mu.Lock()
+	if t.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", t.lastEventID)
+	}
+	t.mu.Unlock()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("connect sse %q: %w", t.url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer drainAndClose(resp.Body)
+		return nil, fmt.Errorf("connect sse %q: %w", t.url, statusError(resp))
+	}
+	return resp, nil
+}
+
+// run consumes the event stream and reconnects with exponential backoff when
+// it drops. After maxRetry consecutive failures the transport is closed.
+func (t *SSETransport) run(resp *http.Response) {
+	defer t.wg.Done()
+
+	backoff := time.Second
+	failures := 0
+	for {
+		received := false
+		err := readSSE(resp.Body, func(ev sseEvent) error {
+			received = true
+			t.handleEvent(ev)
+			return nil
+		})
+		_ = resp.Body.Close()
+		if t.ctx.Err() != nil {
+			return
+		}
+		if received {
+			failures = 0
+			backoff = time.Second
+		}
+		if err == nil {
+			err = io.EOF
+		}
+
+		for {
+			failures++
+			if failures > t.maxRetry {
+				slog.Warn("mcp: sse stream lost", "url", t.url, "error", err)
+				t.shutdown(fmt.Errorf("mcp: sse reconnect failed after %d attempts: %w", t.maxRetry, err))
+				return
+			}
+			slog.Info("mcp: reconnecting sse stream", "url", t.url, "attempt", failures, "backoff", backoff)
+			select {
+			case <-t.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+
+			resp, err = t.openStream(t.ctx)
+			if err == nil {
+				slog.Info("mcp: sse stream reconnected", "url", t.url)
+				break
+			}
+			if t.ctx.Err() != nil {
+				return
+			}
+		}
+	}
+}
+
+func (t *SSETransport) handleEvent(ev sseEvent) {
+	if ev.ID != "" {
+		t.mu.Lock()
+		t.lastEventID = ev.ID
+		t.mu.Unlock()
+	}
+	switch ev.Event {
+	case "endpoint":
+		endpoint, err := t.resolveEndpoint(strings.TrimSpace(ev.Data))
+		if err != nil {
+			slog.Warn("mcp: invalid sse endpoint event", "url", t.url, "data", ev.Data, "error", err)
+			return
+		}
+		t.mu.Lock()
+		t.endpoint = endpoint
+		reset := t.onReset
+		t.mu.Unlock()
+		select {
+		case <-t.ready:
+			// A reconnected stream announces a new session that the
+			// server has not seen an initialize request for.
+			if reset != nil {
+				reset()
+			}
+		default:
+			t.readyOnce.Do(func() { close(t.ready) })
+		}
+	case "message":
+		t.deliver([]byte(ev.Data))
+	}
+}
+
+// resolveEndpoint resolves the announced endpoint against the stream URL.
+// Endpoints on a different origin are rejected.
+func (t *SSETransport) resolveEndpoint(raw string) (string, error) {
+	base, err := url.Parse(t.url)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != base.Scheme || resolved.Host != base.Host {
+		return "", fmt.Errorf("endpoint origin %q does not match %q", resolved.Host, base.Host)
+	}
+	return resolved.String(), nil
+}
+
+// onSessionReset registers fn to run when a reconnected stream announces a
+// new endpoint. fn is called on the stream goroutine and must not block.
+func (t *SSETransport) onSessionReset(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onReset = fn
+}
+
+// SessionID returns the session identifier announced in the endpoint URL, if any.
+func (t *SSETransport) SessionID() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, err := url.Parse(t.endpoint)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	if id := q.Get("sessionId"); id != "" {
+		return id
+	}
+	return q.Get("session_id")
+}
+
+// Send POSTs a JSON-RPC message to the announced endpoint. The response is
+// delivered asynchronously on the event stream.
+func (t *SSETransport) Send(ctx context.Context, msg json.RawMessage) error {
+	if t.closed() {
+		return fmt.Errorf("transport closed")
+	}
+	select {
+	case <-t.ready:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.done:
+		return fmt.Errorf("transport closed")
+	}
+	t.mu.Lock()
+	endpoint := t.endpoint
+	t.mu.Unlock()
+
+	resp, err := t.postWithRetry(ctx, endpoint, msg, nil)
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError(resp)
+	}
+	return nil
+}
+
+// Close terminates the event stream.
+func (t *SSETransport) Close() error {
+	t.shutdown(nil)
+	t.wg.Wait()
+	return nil
+}
+
+// maxListenBackoff caps the delay between reconnects of the Streamable HTTP
+// notification stream, which is retried for as long as its session lasts.
+const maxListenBackoff = 30 * time.Second
+
+// StreamableHTTPTransport implements the MCP Streamable HTTP transport
+// (protocol 2025-03-26). Every message is a POST to a single endpoint; the
+// server answers with either a JSON body or an SSE stream. The session ID
+// assigned at initialization is echoed on every subsequent request, and a
+// background GET stream receives server-initiated notifications.
+type StreamableHTTPTransport struct {
+	httpTransportBase
+	url string
+
+	mu           sync.Mutex
+	sessionID    string
+	listening    bool               // the GET stream goroutine is running or unsupported
+	listenCancel context.CancelFunc // stops the GET stream of the current session
+	onReset      func()
+}
+
+// NewStreamableHTTPTransport creates a transport for the MCP endpoint at rawURL.
+// No connection is made until the first Send. Header values are environment-expanded.
+func NewStreamableHTTPTransport(rawURL string, headers map[string]string) (*StreamableHTTPTransport, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid streamable http url %q", rawURL)
+	}
+	return &StreamableHTTPTransport{
+		httpTransportBase: newHTTPTransportBase(headers),
+		url:               rawURL,
+	}, nil
+}
+
+// SessionID returns the server-assigned session ID (empty before initialization).
+func (t *StreamableHTTPTransport) SessionID() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessionID
+}
+
+// Send POSTs a JSON-RPC message. JSON responses are delivered immediately;
+// SSE responses are consumed in the background until the stream closes.
+func (t *StreamableHTTPTransport) Send(ctx context.Context, msg json.RawMessage) error {
+	if t.closed() {
+		return fmt.Errorf("transport closed")
+	}
+	sid := t.SessionID()
+	resp, err := t.postWithRetry(ctx, t.url, msg, func(req *http.Request) {
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if sid != "" {
+			req.Header.Set(sessionHeader, sid)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound && sid != "" {
+		drainAndClose(resp.Body)
+		t.expireSession(sid)
+		return ErrSessionExpired
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer drainAndClose(resp.Body)
+		return statusError(resp)
+	}
+
+	if newSID := resp.Header.Get(sessionHeader); newSID != "" {
+		t.setSession(newSID)
+	}
+
+	if resp.StatusCode == http.StatusAccepted || resp.ContentLength == 0 {
+		drainAndClose(resp.Body)
+		t.startListening()
+		return nil
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0]))
+	switch mediaType {
+	case "text/event-stream":
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			defer resp.Body.Close()
+			stop := context.AfterFunc(t.ctx, func() { _ = resp.Body.Close() })
+			defer stop()
+			if err := readSSE(resp.Body, func(ev sseEvent) error {
+				if ev.Event == "message" {
+					t.deliver([]byte(ev.Data))
+				}
+				return nil
+			}); err != nil && t.ctx.Err() == nil {
+				slog.Warn("mcp: streamable http response stream error", "url", t.url, "error", err)
+			}
+		}()
+	default:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxSSEEventSize))
+		_ = resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("read response: %w", err)
+		}
+		t.deliver(body)
+	}
+	t.startListening()
+	return nil
+}
+
+// setSession switches to session sid. The notification stream of the
+// previous session is stopped; the next startListening opens one for sid.
+func (t *StreamableHTTPTransport) setSession(sid string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID == sid {
+		return
+	}
+	t.sessionID = sid
+	if t.listenCancel != nil {
+		t.listenCancel()
+	}
+}
+
+// expireSession forgets session sid unless another one replaced it already,
+// and reports whether it did.
+func (t *StreamableHTTPTransport) expireSession(sid string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID != sid {
+		return false
+	}
+	t.sessionID = ""
+	if t.listenCancel != nil {
+		t.listenCancel()
+	}
+	return true
+}
+
+// onSessionReset registers fn to run when the notification stream finds
+// that the server no longer knows the session. fn must not block.
+func (t *StreamableHTTPTransport) onSessionReset(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onReset = fn
+}
+
+// startListening opens the optional GET stream for server-initiated messages
+// once a session exists and no stream is running. Servers that do not offer
+// it answer 405 and are not asked again.
+func (t *StreamableHTTPTransport) startListening() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID == "" || t.listening {
+		return
+	}
+	t.listening = true
+	t.wg.Add(1)
+	go t.listen()
+}
+
+// listen holds the GET stream open while there is a session. It reconnects
+// with exponential backoff capped at maxListenBackoff, and at once when a
+// new session replaces the old one. It returns when the session expires;
+// the re-initialized session starts a new stream.
+func (t *StreamableHTTPTransport) listen() {
+	defer t.wg.Done()
+
+	backoff := t.retryBackoff
+	failures := 0
+	lastSID := ""
+	for t.ctx.Err() == nil {
+		ctx, cancel := context.WithCancel(t.ctx)
+		t.mu.Lock()
+		sid := t.sessionID
+		if sid == "" {
+			t.listening = false
+			t.listenCancel = nil
+			t.mu.Unlock()
+			cancel()
+			return
+		}
+		t.listenCancel = cancel
+		t.mu.Unlock()
+		if sid != lastSID {
+			lastSID, failures, backoff = sid, 0, t.retryBackoff
+		}
+
+		received, err := t.stream(ctx, sid)
+		if errors.Is(err, errNoNotificationStream) {
+			cancel()
+			slog.Debug("mcp: server does not offer a notification stream", "url", t.url)
+			return
+		}
+		if ctx.Err() != nil {
+			// Closed, or the session was replaced or expired.
+			cancel()
+			continue
+		}
+		if received {
+			failures, backoff = 0, t.retryBackoff
+		}
+		failures++
+		if failures == t.maxRetry+1 {
+			slog.Warn("mcp: notification stream unavailable, still retrying", "url", t.url, "error", err)
+		} else {
+			slog.Debug("mcp: notification stream dropped", "url", t.url, "attempt", failures, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+			backoff = min(backoff*2, maxListenBackoff)
+		}
+		cancel()
+	}
+}
+
+// errNoNotificationStream reports a server without the optional GET stream.
+var errNoNotificationStream = errors.New("mcp: no notification stream")
+
+// stream reads one GET stream of session sid until it ends and reports
+// whether it delivered any event. A 404 expires the session and asks the
+// client to re-initialize it.
+func (t *StreamableHTTPTransport) stream(ctx context.Context, sid string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	t.applyHeaders(req)
+	req.Header.Set(sessionHeader, sid)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	switch resp.StatusCode {
+	case http.StatusMethodNotAllowed:
+		drainAndClose(resp.Body)
+		return false, errNoNotificationStream
+	case http.StatusNotFound:
+		drainAndClose(resp.Body)
+		if t.expireSession(sid) {
+			t.mu.Lock()
+			reset := t.onReset
+			t.mu.Unlock()
+			if reset != nil {
+				reset()
+			}
+		}
+		return false, ErrSessionExpired
+	case http.StatusOK:
+	default:
+		defer drainAndClose(resp.Body)
+		return false, statusError(resp)
+	}
+	received := false
+	err = readSSE(resp.Body, func(ev sseEvent) error {
+		received = true
+		if ev.Event == "message" {
+			t.deliver([]byte(ev.Data))
+		}
+		return nil
+	})
+	_ = resp.Body.Close()
+	if err == nil {
+		err = io.EOF
+	}
+	return received, err
+}
+
+// Close terminates the session (best effort) and stops background streams.
+func (t *StreamableHTTPTransport) Close() error {
+	if t.closed() {
+		return nil
+	}
+	if sid := t.SessionID(); sid != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+		if err == nil {
+			t.applyHeaders(req)
+			req.Header.Set(sessionHeader, sid)
+			if resp, err := t.client.Do(req); err == nil {
+				drainAndClose(resp.Body)
+			}
+		}
+		cancel()
+	}
+	t.shutdown(nil)
+	t.wg.Wait()
+	return nil
+}
@@ -0,0 +1,716 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/policy"
+)
+
+const testAuthHeader = "Bearer test-token"
+
+// handleRPC answers the subset of MCP methods exercised by these tests.
+// Notifications (no id) return nil.
+func handleRPC(t *testing.T, body []byte) []byte {
+	t.Helper()
+	var req struct {
+		Method string          `json:"method"`
+		ID     *int64          `json:"id"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Errorf("server: bad request %q: %v", body, err)
+		return nil
+	}
+	if req.ID == nil {
+		return nil
+	}
+	var result any
+	switch req.Method {
+	case "initialize":
+		result = map[string]any{
+			"protocolVersion": "2024-11-05",
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "test", "version": "1.0"},
+		}
+	case "tools/list":
+		result = map[string]any{"tools": []map[string]any{
+			{"name": "echo", "description": "Echo input", "inputSchema": map[string]any{"type": "object"}},
+		}}
+	case "tools/call":
+		result = map[string]any{"content": []map[string]any{{"type": "text", "text": "pong"}}}
+	default:
+		b, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": *req.ID, "error": map[string]any{"code": -32601, "message": "method not found"}})
+		return b
+	}
+	b, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": *req.ID, "result": result})
+	return b
+}
+
+// streamableServer is an in-process Streamable HTTP MCP server.
+type streamableServer struct {
+	t         *testing.T
+	mu        sync.Mutex
+	sessionID string
+	inits     int
+	deleted   bool
+	seenSID   []string
+	methods   []string
+	failNext  int // answer this many POSTs with 503
+
+	streams bool     // offer the GET notification stream
+	getFail int      // answer this many GETs with 503
+	getSIDs []string // session IDs of the GET streams opened
+	pushes  []string // notifications for the next stream of the current session
+}
+
+// push queues a notification for the GET stream.
+func (s *streamableServer) push(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pushes = append(s.pushes, msg)
+}
+
+// serveStream holds a GET notification stream open until the session
+// expires, sending queued notifications.
+func (s *streamableServer) serveStream(w http.ResponseWriter, r *http.Request) {
+	sid := r.Header.Get(sessionHeader)
+	s.mu.Lock()
+	fail := s.getFail > 0
+	if fail {
+		s.getFail--
+	}
+	current := s.sessionID
+	s.mu.Unlock()
+	if fail {
+		http.Error(w, "upstream down", http.StatusServiceUnavailable)
+		return
+	}
+	if sid == "" || sid != current {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	s.mu.Lock()
+	s.getSIDs = append(s.getSIDs, sid)
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.(http.Flusher).Flush()
+	for {
+		s.mu.Lock()
+		if s.sessionID != sid {
+			s.mu.Unlock()
+			return
+		}
+		var msg string
+		if len(s.pushes) > 0 {
+			msg, s.pushes = s.pushes[0], s.pushes[1:]
+		}
+		s.mu.Unlock()
+		if msg != "" {
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg)
+			w.(http.Flusher).Flush()
+			continue
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+// expire forgets the current session, as a restarted server would.
+func (s *streamableServer) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionID = ""
+}
+
+func (s *streamableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != testAuthHeader {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		if s.streams {
+			s.serveStream(w, r)
+			return
+		}
+		http.Error(w, "no stream", http.StatusMethodNotAllowed)
+		return
+	case http.MethodDelete:
+		s.mu.Lock()
+		s.deleted = r.Header.Get(sessionHeader) == s.sessionID
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	sid := r.Header.Get(sessionHeader)
+	var msg struct {
+		Method string `json:"method"`
+	}
+	_ = json.Unmarshal(body, &msg)
+	s.mu.Lock()
+	s.seenSID = append(s.seenSID, sid)
+	s.methods = append(s.methods, msg.Method)
+	fail := s.failNext > 0
+	if fail {
+		s.failNext--
+	}
+	current := s.sessionID
+	s.mu.Unlock()
+
+	if fail {
+		http.Error(w, "upstream down", http.StatusServiceUnavailable)
+		return
+	}
+	if msg.Method == "initialize" {
+		s.mu.Lock()
+		s.inits++
+		s.sessionID = "session-123"
+		if s.inits > 1 {
+			s.sessionID = fmt.Sprintf("session-123-%d", s.inits)
+		}
+		w.Header().Set(sessionHeader, s.sessionID)
+		s.mu.Unlock()
+	} else if sid == "" || sid != current {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+
+	resp := handleRPC(s.t, body)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	// tools/call is answered over SSE to exercise the streaming response path.
+	if strings.Contains(string(body), `"tools/call"`) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, ": keep-alive\n\nevent: message\ndata: %s\n\n", resp)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(resp)
+}
+
+// sseServer is an in-process HTTP+SSE MCP server.
+type sseServer struct {
+	t           *testing.T
+	mu          sync.Mutex
+	streams     map[string]chan []byte
+	initialized map[string]bool
+	connects    int
+	dropFirst   bool
+}
+
+func newSSEServer(t *testing.T) *sseServer {
+	return &sseServer{t: t, streams: make(map[string]chan []byte), initialized: make(map[string]bool)}
+}
+
+func (s *sseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != testAuthHeader {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/sse":
+		s.mu.Lock()
+		s.connects++
+		n := s.connects
+		sid := fmt.Sprintf("s%d", n)
+		ch := make(chan []byte, 16)
+		s.streams[sid] = ch
+		drop := s.dropFirst && n == 1
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: endpoint\ndata: /message?sessionId=%s\n\n", sid)
+		w.(http.Flusher).Flush()
+		if drop {
+			return
+		}
+		for {
+			select {
+			case msg := <-ch:
+				fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", n, msg)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	case r.Method == http.MethodPost && r.URL.Path == "/message":
+		sid := r.URL.Query().Get("sessionId")
+		s.mu.Lock()
+		ch, ok := s.streams[sid]
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			Method string `json:"method"`
+			ID     *int64 `json:"id"`
+		}
+		_ = json.Unmarshal(body, &req)
+		s.mu.Lock()
+		if req.Method == "initialize" {
+			s.initialized[sid] = true
+		}
+		ready := s.initialized[sid]
+		s.mu.Unlock()
+		if !ready && req.ID != nil {
+			b, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": *req.ID, "error": map[string]any{"code": -32600, "message": "session not initialized"}})
+			ch <- b
+		} else if resp := handleRPC(s.t, body); resp != nil {
+			ch <- resp
+		}
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func authHeaders() map[string]string {
+	return map[string]string{"Authorization": "Bearer ${GOCLAW_TEST_MCP_TOKEN}"}
+}
+
+func exerciseClient(t *testing.T, transport Transport) {
+	t.Helper()
+	client, err := NewClient("test", transport)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "echo" {
+		t.Fatalf("unexpected tools: %+v", tools)
+	}
+	res, err := client.CallTool(ctx, "echo", json.RawMessage(`{"text":"ping"}`))
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if !strings.Contains(string(res), "pong") {
+		t.Fatalf("unexpected result: %s", res)
+	}
+}
+
+func TestStreamableHTTPTransport_Client(t *testing.T) {
+	t.Setenv("GOCLAW_TEST_MCP_TOKEN", "test-token")
+	srv := &streamableServer{t: t}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	transport, err := NewStreamableHTTPTransport(ts.URL, authHeaders())
+	if err != nil {
+		t.Fatalf("NewStreamableHTTPTransport: %v", err)
+	}
+	exerciseClient(t, transport)
+
+	if got := transport.SessionID(); got != "session-123" {
+		t.Errorf("SessionID = %q, want session-123", got)
+	}
+	srv.mu.Lock()
+	seen := append([]string(nil), srv.seenSID...)
+	deleted := srv.deleted
+	srv.mu.Unlock()
+	if seen[0] != "" {
+		t.Errorf("initialize should not carry a session id, got %q", seen[0])
+	}
+	for i, sid := range seen[1:] {
+		if sid != "session-123" {
+			t.Errorf("request %d: session id = %q, want session-123", i+1, sid)
+		}
+	}
+	if !deleted {
+		t.Error("expected DELETE to terminate the session on Close")
+	}
+}
+
+func TestStreamableHTTPTransport_SessionExpired(t *testing.T) {
+	t.Setenv("GOCLAW_TEST_MCP_TOKEN", "test-token")
+	srv := &streamableServer{t: t}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	transport, err := NewStreamableHTTPTransport(ts.URL, authHeaders())
+	if err != nil {
+		t.Fatalf("NewStreamableHTTPTransport: %v", err)
+	}
+	client, _ := NewClient("test", transport)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	srv.expire()
+
+	// The client re-initializes and retries the rejected request once.
+	res, err := client.CallTool(ctx, "echo", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("CallTool after expiry: %v", err)
+	}
+	if !strings.Contains(string(res), "pong") {
+		t.Fatalf("unexpected result: %s", res)
+	}
+	if got := transport.SessionID(); got != "session-123-2" {
+		t.Errorf("SessionID = %q, want session-123-2", got)
+	}
+	srv.mu.Lock()
+	methods := strings.Join(srv.methods, ",")
+	srv.mu.Unlock()
+	want := "initialize,notifications/initialized,tools/call,initialize,notifications/initialized,tools/call"
+	if methods != want {
+		t.Errorf("methods = %s, want %s", methods, want)
+	}
+}
+
+func TestStreamableHTTPTransport_RetriesOnlyIdempotentMethods(t *testing.T) {
+	t.Setenv("GOCLAW_TEST_MCP_TOKEN", "test-token")
+	srv := &streamableServer{t: t}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	transport, err := NewStreamableHTTPTransport(ts.URL, authHeaders())
+	if err != nil {
+		t.Fatalf("NewStreamableHTTPTransport: %v", err)
+	}
+	transport.retryBackoff = time.Millisecond
+	client, _ := NewClient("test", transport)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	srv.mu.Lock()
+	srv.failNext = 1
+	srv.mu.Unlock()
+	if _, err := client.ListTools(ctx); err != nil {
+		t.Fatalf("ListTools should be retried after 503: %v", err)
+	}
+
+	srv.mu.Lock()
+	srv.failNext = 1
+	srv.methods = nil
+	srv.mu.Unlock()
+	if _, err := client.CallTool(ctx, "echo", json.RawMessage(`{}`)); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("expected tools/call to fail with 503, got %v", err)
+	}
+	srv.mu.Lock()
+	methods := srv.methods
+	srv.mu.Unlock()
+	if len(methods) != 1 {
+		t.Errorf("tools/call sent %d times, want 1", len(methods))
+	}
+
+	// Nothing reached the server, so even tools/call may be retried.
+	unreachable, _ := NewStreamableHTTPTransport("http://127.0.0.1:1/mcp", nil)
+	defer unreachable.Close()
+	unreachable.retryBackoff = time.Millisecond
+	unreachable.maxRetry = 1
+	err = unreachable.Send(ctx, json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call"}`))
+	if err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Fatalf("expected dial errors to be retried, got %v", err)
+	}
+}
+
+// startStreamableClient connects an initialized client to srv and returns
+// the notification methods it receives.
+func startStreamableClient(t *testing.T, srv *streamableServer) (*StreamableHTTPTransport, chan string) {
+	t.Helper()
+	t.Setenv("GOCLAW_TEST_MCP_TOKEN", "test-token")
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	transport, err := NewStreamableHTTPTransport(ts.URL, authHeaders())
+	if err != nil {
+		t.Fatalf("NewStreamableHTTPTransport: %v", err)
+	}
+	transport.retryBackoff = time.Millisecond
+	client, _ := NewClient("test", transport)
+	t.Cleanup(func() { client.Close() })
+	notes := make(chan string, 10)
+	client.OnNotification(func(method string, _ json.RawMessage) { notes <- method })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return transport, notes
+}
+
+func expectNotification(t *testing.T, notes chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-notes:
+		if got != want {
+			t.Fatalf("notification = %s, want %s", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no %s notification", want)
+	}
+}
+
+func TestStreamableHTTPTransport_NotificationStreamKeepsRetrying(t *testing.T) {
+	srv := &streamableServer{t: t, streams: true, getFail: 10}
+	srv.push(`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`)
+	transport, notes := startStreamableClient(t, srv)
+	if transport.maxRetry >= 10 {
+		t.Fatalf("maxRetry = %d; the test needs more failures than that", transport.maxRetry)
+	}
+	expectNotification(t, notes, "notifications/tools/list_changed")
+}
+
+func TestStreamableHTTPTransport_NotificationStreamFollowsSession(t *testing.T) {
+	srv := &streamableServer{t: t, streams: true}
+	transport, notes := startStreamableClient(t, srv)
+	srv.push(`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`)
+	expectNotification(t, notes, "notifications/tools/list_changed")
+
+	// The stream finds the session gone; the client re-initializes without
+	// a request of its own and a stream opens for the new session.
+	srv.expire()
+	deadline := time.Now().Add(5 * time.Second)
+	for transport.SessionID() != "session-123-2" {
+		if time.Now().After(deadline) {
+			t.Fatalf("SessionID = %q, want session-123-2", transport.SessionID())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	for {
+		srv.mu.Lock()
+		sids := strings.Join(srv.getSIDs, ",")
+		srv.mu.Unlock()
+		if sids == "session-123,session-123-2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("streams opened for %s, want session-123,session-123-2", sids)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	srv.push(`{"jsonrpc":"2.0","method":"notifications/resources/updated"}`)
+	expectNotification(t, notes, "notifications/resources/updated")
+}
+
+func TestStreamableHTTPTransport_Unauthorized(t *testing.T) {
+	ts := httptest.NewServer(&streamableServer{t: t})
+	defer ts.Close()
+
+	transport, err := NewStreamableHTTPTransport(ts.URL, nil)
+	if err != nil {
+		t.Fatalf("NewStreamableHTTPTransport: %v", err)
+	}
+	defer transport.Close()
+
+	err = transport.Send(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`))
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected 401 error, got %v", err)
+	}
+}
+
+func TestNewStreamableHTTPTransport_InvalidURL(t *testing.T) {
+	for _, raw := range []string{"", "not a url", "ftp://example.com/mcp"} {
+		if _, err := NewStreamableHTTPTransport(raw, nil); err == nil {
+			t.Errorf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestSSETransport_Client(t *testing.T) {
+	t.Setenv("GOCLAW_TEST_MCP_TOKEN", "test-token")
+	ts := httptest.NewServer(newSSEServer(t))
+	defer ts.Close()
+
+	transport, err := NewSSETransport(ts.URL+"/sse", authHeaders())
+	if err != nil {
+		t.Fatalf("NewSSETransport: %v", err)
+	}
+	if got := transport.SessionID(); got != "s1" {
+		t.Errorf("SessionID = %q, want s1", got)
+	}
+	exerciseClient(t, transport)
+}
+
+func TestSSETransport_Unauthorized(t *testing.T) {
+	ts := httptest.NewServer(newSSEServer(t))
+	defer ts.Close()
+
+	if _, err := NewSSETransport(ts.URL+"/sse", nil); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected 401 error, got %v", err)
+	}
+}
+
+func TestSSETransport_Reconnect(t *testing.T) {
+	t.Setenv("GOCLAW_TEST_MCP_TOKEN", "test-token")
+	srv := newSSEServer(t)
+	srv.dropFirst = true
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	transport, err := NewSSETransport(ts.URL+"/sse", authHeaders())
+	if err != nil {
+		t.Fatalf("NewSSETransport: %v", err)
+	}
+	client, _ := NewClient("test", transport)
+	defer client.Close()
+
+	// The first stream closes right after announcing its endpoint; the
+	// transport must reconnect and the client re-initialize the new session.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		srv.mu.Lock()
+		ready := srv.initialized["s2"]
+		srv.mu.Unlock()
+		if ready && transport.SessionID() == "s2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("client did not re-initialize after reconnect, session = %q", transport.SessionID())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools after reconnect: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "echo" {
+		t.Fatalf("unexpected tools: %+v", tools)
+	}
+}
+
+func TestReadSSE(t *testing.T) {
+	input := ": comment\n\nevent: endpoint\ndata: /msg\n\nid: 7\ndata: line1\ndata: line2\n\ndata: tail\n"
+	var got []sseEvent
+	err := readSSE(strings.NewReader(input), func(ev sseEvent) error {
+		got = append(got, ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readSSE: %v", err)
+	}
+	want := []sseEvent{
+		{Event: "endpoint", Data: "/msg"},
+		{Event: "message", Data: "line1\nline2", ID: "7"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestServerConfig_TransportKind(t *testing.T) {
+	tests := []struct {
+		cfg  ServerConfig
+		want string
+	}{
+		{ServerConfig{Command: "npx"}, TransportStdio},
+		{ServerConfig{URL: "http://localhost/sse"}, TransportSSE},
+		{ServerConfig{URL: "http://localhost/sse", Transport: "SSE"}, TransportSSE},
+		{ServerConfig{URL: "http://localhost/mcp", Transport: "streamable_http"}, TransportStreamableHTTP},
+		{ServerConfig{URL: "http://localhost/mcp", Transport: "http"}, TransportStreamableHTTP},
+		{ServerConfig{URL: "http://localhost/mcp", Transport: "grpc"}, "grpc"},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.transportKind(); got != tt.want {
+			t.Errorf("transportKind(%+v) = %q, want %q", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestManager_ConnectAgentServers_HTTP(t *testing.T) {
+	t.Setenv("GOCLAW_TEST_MCP_TOKEN", "test-token")
+	streamTS := httptest.NewServer(&streamableServer{t: t})
+	defer streamTS.Close()
+	sseTS := httptest.NewServer(newSSEServer(t))
+	defer sseTS.Close()
+
+	pol := policy.Policy{MCP: policy.MCPPolicyConfig{Default: "allow"}}
+	m := NewManager(nil, pol, newTestLogger())
+	defer m.Stop()
+
+	ctx := context.Background()
+	err := m.ConnectAgentServers(ctx, "agent1", []ServerConfig{
+		{Name: "remote", URL: streamTS.URL, Transport: "streamable_http", Headers: authHeaders(), Timeout: "5s", Enabled: true},
+		{Name: "legacy", URL: sseTS.URL + "/sse", Transport: "sse", Headers: authHeaders(), Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("ConnectAgentServers: %v", err)
+	}
+	for _, name := range []string{"remote", "legacy"} {
+		if !m.Healthy("agent1", name) {
+			t.Errorf("server %s not connected", name)
+		}
+	}
+
+	tools, err := m.DiscoverTools(ctx, "agent1")
+	if err != nil {
+		t.Fatalf("DiscoverTools: %v", err)
+	}
+	if len(tools) != 2 {
+		t.Fatalf("expected 2 tools, got %d: %+v", len(tools), tools)
+	}
+
+	res, err := m.InvokeTool(ctx, "agent1", "remote", "echo", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("InvokeTool: %v", err)
+	}
+	if !strings.Contains(string(res), "pong") {
+		t.Errorf("unexpected result: %s", res)
+	}
+}
+
+func TestManager_StartGlobalHTTPServer(t *testing.T) {
+	t.Setenv("GOCLAW_TEST_MCP_TOKEN", "test-token")
+	ts := httptest.NewServer(&streamableServer{t: t})
+	defer ts.Close()
+
+	m := NewManager([]ServerConfig{
+		{Name: "shared", URL: ts.URL, Transport: "streamable_http", Headers: authHeaders(), Enabled: true},
+	}, policy.Policy{MCP: policy.MCPPolicyConfig{Default: "allow"}}, newTestLogger())
+	defer m.Stop()
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	// Name-only references resolve to the global connection.
+	if err := m.ConnectAgentServers(context.Background(), "agent1", []ServerConfig{{Name: "shared", Enabled: true}}); err != nil {
+		t.Fatalf("ConnectAgentServers: %v", err)
+	}
+	if !m.Healthy("agent1", "shared") {
+		t.Fatal("expected agent to share the global connection")
+	}
+}
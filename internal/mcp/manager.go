@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/basket/go-claw/internal/audit"
+	"github.com/basket/go-claw/internal/bus"
 	"github.com/basket/go-claw/internal/policy"
 )
 
@@ -17,12 +21,100 @@ type ServerConfig struct {
 	Command   string            `yaml:"command"`
 	Args      []string          `yaml:"args"`
 	Env       map[string]string `yaml:"env"`
-	URL       string            `yaml:"url,omitempty"`       // SSE endpoint (v0.4)
-	Transport string            `yaml:"transport,omitempty"` // "stdio" (default) or "sse" (v0.4)
+	URL       string            `yaml:"url,omitempty"`       // HTTP endpoint for sse / streamable_http (v0.4)
+	Transport string            `yaml:"transport,omitempty"` // "stdio" (default), "sse", or "streamable_http"
+	Headers   map[string]string `yaml:"headers,omitempty"`   // HTTP headers (e.g. Authorization), env-expanded
 	Timeout   string            `yaml:"timeout,omitempty"`   // e.g. "30s" (v0.4)
 	Enabled   bool              `yaml:"enabled"`
 }
 
+// Transport kinds accepted in ServerConfig.Transport.
+const (
+	TransportStdio          = "stdio"
+	TransportSSE            = "sse"
+	TransportStreamableHTTP = "streamable_http"
+)
+
+// defaultInitTimeout bounds the initialize handshake when no timeout is configured.
+const defaultInitTimeout = 10 * time.Second
+
+// transportKind normalizes the configured transport. A URL without an
+// explicit transport defaults to SSE; anything else defaults to stdio.
+func (c ServerConfig) transportKind() string {
+	switch strings.ToLower(strings.TrimSpace(c.Transport)) {
+	case "":
+		if c.Command == "" && c.URL != "" {
+			return TransportSSE
+		}
+		return TransportStdio
+	case "stdio":
+		return TransportStdio
+	case "sse":
+		return TransportSSE
+	case "streamable_http", "streamable-http", "http":
+		return TransportStreamableHTTP
+	default:
+		return strings.ToLower(strings.TrimSpace(c.Transport))
+	}
+}
+
+// initTimeout parses the configured timeout, falling back to defaultInitTimeout.
+func (c ServerConfig) initTimeout() time.Duration {
+	if c.Timeout == "" {
+		return defaultInitTimeout
+	}
+	d, err := time.ParseDuration(c.Timeout)
+	if err != nil || d <= 0 {
+		return defaultInitTimeout
+	}
+	return d
+}
+
+// newTransport builds the transport described by cfg.
+func newTransport(cfg ServerConfig) (Transport, error) {
+	switch kind := cfg.transportKind(); kind {
+	case TransportStdio:
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("mcp server %q: command is required for stdio transport", cfg.Name)
+		}
+		return NewReconnectableTransport(cfg.Command, cfg.Args, cfg.Env)
+	case TransportSSE:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("mcp server %q: url is required for sse transport", cfg.Name)
+		}
+		return NewSSETransport(cfg.URL, cfg.Headers)
+	case TransportStreamableHTTP:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("mcp server %q: url is required for streamable_http transport", cfg.Name)
+		}
+		return NewStreamableHTTPTransport(cfg.URL, cfg.Headers)
+	default:
+		return nil, fmt.Errorf("mcp server %q: unknown transport %q", cfg.Name, kind)
+	}
+}
+
+// connect creates, initializes and returns a client for cfg.
+func (m *Manager) connect(ctx context.Context, cfg ServerConfig) (*Client, error) {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("start transport: %w", err)
+	}
+
+	client, err := NewClient(cfg.Name, transport)
+	if err != nil {
+		transport.Close()
+		return nil, fmt.Errorf("create client: %w", err)
+	}
+
+	initCtx, cancel := context.WithTimeout(ctx, cfg.initTimeout())
+	defer cancel()
+	if err := client.Initialize(initCtx); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	return client, nil
+}
+
 // DiscoveredTool represents a tool enumerated from an MCP server (v0.4).
 type DiscoveredTool struct {
 	Name        string
@@ -37,19 +129,72 @@ type connection struct {
 	tools   []DiscoveredTool
 	healthy bool
 	mu      sync.RWMutex
+
+	subsMu sync.Mutex
+	subs   map[string]map[string]ResourceUpdateFunc // uri -> agentID -> callback
+}
+
+// newConnection wraps an initialized client and routes its notifications.
+func (m *Manager) newConnection(cfg ServerConfig, client *Client) *connection {
+	conn := &connection{
+		config:  cfg,
+		client:  client,
+		healthy: true,
+		subs:    make(map[string]map[string]ResourceUpdateFunc),
+	}
+	client.OnNotification(func(method string, params json.RawMessage) {
+		m.handleNotification(conn, method, params)
+	})
+	client.OnReinitialize(func() {
+		// A new session starts without our subscriptions and may offer a
+		// different tool set. Run in the background: the hook fires while the
+		// request that noticed the expiry still waits to be retried.
+		go func() {
+			m.refreshServerTools(conn)
+			m.resubscribe(conn)
+		}()
+	})
+	return conn
+}
+
+// handleNotification dispatches a server notification for conn.
+func (m *Manager) handleNotification(conn *connection, method string, params json.RawMessage) {
+	switch method {
+	case "notifications/resources/updated":
+		var p struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil || p.URI == "" {
+			m.logger.Warn("invalid mcp resource update notification", "server", conn.config.Name, "error", err)
+			return
+		}
+		conn.notifyResourceUpdated(p.URI)
+	case "notifications/tools/list_changed":
+		// Refresh off the listen goroutine: tools/list needs it to deliver the response.
+		go m.refreshServerTools(conn)
+	default:
+		m.logger.Debug("mcp notification", "server", conn.config.Name, "method", method)
+	}
 }
 
 // Manager manages multiple MCP clients with per-agent scoping (v0.4).
 type Manager struct {
 	mu       sync.RWMutex
+	configs  []ServerConfig                    // global server definitions
 	global   map[string]*connection            // name -> connection (shared)
 	perAgent map[string]map[string]*connection // agentID -> name -> connection
 	policy   policy.Checker
 	logger   *slog.Logger
+
+	hooksMu        sync.RWMutex
+	bus            *bus.Bus
+	onToolsChanged ToolsChangedFunc
+	agentPolicy    func(agentID string) policy.Checker
 }
 
 func NewManager(configs []ServerConfig, pol policy.Checker, logger *slog.Logger) *Manager {
 	return &Manager{
+		configs:  configs,
 		global:   make(map[string]*connection),
 		perAgent: make(map[string]map[string]*connection),
 		policy:   pol,
@@ -64,6 +209,21 @@ func (m *Manager) Start(ctx context.Context) error {
 
 	// Note: Start() now only handles global servers.
 	// Per-agent servers are started via ConnectAgentServers().
+	for _, cfg := range m.configs {
+		if !cfg.Enabled {
+			continue
+		}
+		if _, exists := m.global[cfg.Name]; exists {
+			continue
+		}
+		client, err := m.connect(ctx, cfg)
+		if err != nil {
+			m.logger.Error("failed to connect global mcp server", "server", cfg.Name, "transport", cfg.transportKind(), "error", err)
+			continue
+		}
+		m.global[cfg.Name] = m.newConnection(cfg, client)
+		m.logger.Info("global mcp server connected", "server", cfg.Name, "transport", cfg.transportKind())
+	}
 	return nil
 }
 
@@ -93,37 +253,15 @@ func (m *Manager) ConnectAgentServers(ctx context.Context, agentID string, confi
 		}
 
 		// Inline definition: create agent-specific connection
-		m.logger.Info("connecting agent to mcp server", "agent", agentID, "server", cfg.Name)
+		m.logger.Info("connecting agent to mcp server", "agent", agentID, "server", cfg.Name, "transport", cfg.transportKind())
 
-		transport, err := NewReconnectableTransport(cfg.Command, cfg.Args, cfg.Env)
+		client, err := m.connect(ctx, cfg)
 		if err != nil {
-			m.logger.Error("failed to start mcp server", "agent", agentID, "server", cfg.Name, "error", err)
+			m.logger.Error("failed to connect mcp server", "agent", agentID, "server", cfg.Name, "error", err)
 			continue
 		}
 
-		client, err := NewClient(cfg.Name, transport)
-		if err != nil {
-			m.logger.Error("failed to create mcp client", "agent", agentID, "server", cfg.Name, "error", err)
-			transport.Close()
-			continue
-		}
-
-		// Initialize with timeout
-		initCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-		if err := client.Initialize(initCtx); err != nil {
-			cancel()
-			m.logger.Error("failed to initialize mcp client", "agent", agentID, "server", cfg.Name, "error", err)
-			client.Close()
-			continue
-		}
-		cancel()
-
-		conn := &connection{
-			config:  cfg,
-			client:  client,
-			healthy: true,
-		}
-		m.perAgent[agentID][cfg.Name] = conn
+		m.perAgent[agentID][cfg.Name] = m.newConnection(cfg, client)
 		m.logger.Info("mcp server connected for agent", "agent", agentID, "server", cfg.Name)
 	}
 
@@ -144,6 +282,7 @@ func (m *Manager) DisconnectAgent(agentID string) error {
 	for name, conn := range agentConns {
 		// Skip global connections (they're managed separately)
 		if _, inGlobal := m.global[name]; inGlobal {
+			conn.dropSubscriber(agentID)
 			continue
 		}
 
@@ -174,54 +313,152 @@ func (m *Manager) DiscoverTools(ctx context.Context, agentID string) ([]Discover
 	for serverName, conn := range agentConns {
 		// Try cache first
 		conn.mu.RLock()
-		if len(conn.tools) > 0 {
-			allTools = append(allTools, conn.tools...)
-			conn.mu.RUnlock()
-			continue
-		}
+		discovered := conn.tools
 		conn.mu.RUnlock()
 
-		// Discover tools from server
-		listCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		tools, err := conn.client.ListTools(listCtx)
-		cancel()
-
-		if err != nil {
-			m.logger.Warn("failed to discover mcp tools", "agent", agentID, "server", serverName, "error", err)
-			continue
-		}
+		if len(discovered) == 0 {
+			// Discover tools from server
+			listCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			tools, err := conn.client.ListTools(listCtx)
+			cancel()
 
-		// Convert and cache
-		var discovered []DiscoveredTool
-		for _, tool := range tools {
-			dt := DiscoveredTool{
-				Name:        tool.Name,
-				Description: tool.Description,
-				InputSchema: tool.InputSchema,
-				ServerName:  serverName,
+			if err != nil {
+				m.logger.Warn("failed to discover mcp tools", "agent", agentID, "server", serverName, "error", err)
+				continue
 			}
-			discovered = append(discovered, dt)
-
-			// Policy check
-			if m.policy != nil {
-				pol, ok := m.policy.(policy.Policy)
-				if ok && !pol.AllowMCPTool(agentID, serverName, tool.Name) {
-					m.logger.Debug("mcp tool blocked by policy", "agent", agentID, "server", serverName, "tool", tool.Name)
-					continue
-				}
+
+			// Convert and cache (unfiltered: connections may be shared by agents)
+			for _, tool := range tools {
+				discovered = append(discovered, DiscoveredTool{
+					Name:        tool.Name,
+					Description: tool.Description,
+					InputSchema: tool.InputSchema,
+					ServerName:  serverName,
+				})
 			}
 
+			conn.mu.Lock()
+			conn.tools = discovered
+			conn.mu.Unlock()
+
+			m.logger.Info("mcp tools discovered", "agent", agentID, "server", serverName, "count", len(discovered))
+		}
+
+		// Policy check on every call so reloaded rules apply to cached lists.
+		for _, dt := range discovered {
+			if !m.toolAllowed(agentID, serverName, dt.Name) {
+				m.logger.Debug("mcp tool blocked by policy", "agent", agentID, "server", serverName, "tool", dt.Name)
+				continue
+			}
 			allTools = append(allTools, dt)
 		}
+	}
+
+	return allTools, nil
+}
+
+// ToolsChangedFunc is invoked after a server's tool list was refreshed, with
+// the agents bound to that server.
+type ToolsChangedFunc func(serverName string, agentIDs []string)
+
+// OnToolsChanged sets the callback run after a notifications/tools/list_changed
+// refresh, typically to re-register the agents' tool sets.
+func (m *Manager) OnToolsChanged(fn ToolsChangedFunc) {
+	m.hooksMu.Lock()
+	defer m.hooksMu.Unlock()
+	m.onToolsChanged = fn
+}
+
+// SetEventBus sets the bus on which tool list changes are published.
+func (m *Manager) SetEventBus(b *bus.Bus) {
+	m.hooksMu.Lock()
+	defer m.hooksMu.Unlock()
+	m.bus = b
+}
 
-		conn.mu.Lock()
-		conn.tools = discovered
-		conn.mu.Unlock()
+// SetPolicyResolver sets a lookup for per-agent checkers (policy profiles).
+// Agents the resolver returns nil for use the manager's global policy.
+func (m *Manager) SetPolicyResolver(fn func(agentID string) policy.Checker) {
+	m.hooksMu.Lock()
+	defer m.hooksMu.Unlock()
+	m.agentPolicy = fn
+}
 
-		m.logger.Info("mcp tools discovered", "agent", agentID, "server", serverName, "count", len(discovered))
+// policyFor returns the checker governing agentID.
+func (m *Manager) policyFor(agentID string) policy.Checker {
+	m.hooksMu.RLock()
+	fn := m.agentPolicy
+	m.hooksMu.RUnlock()
+	if fn != nil {
+		if pol := fn(agentID); pol != nil {
+			return pol
+		}
 	}
+	return m.policy
+}
 
-	return allTools, nil
+// refreshServerTools invalidates and re-fetches the cached tool list of conn
+// after the server reported a change, then notifies bound agents.
+func (m *Manager) refreshServerTools(conn *connection) {
+	serverName := conn.config.Name
+	conn.mu.Lock()
+	conn.tools = nil
+	conn.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	tools, err := conn.client.ListTools(ctx)
+	if err != nil {
+		// Cache stays empty; the next DiscoverTools call retries.
+		m.logger.Warn("failed to refresh mcp tools", "server", serverName, "error", err)
+		return
+	}
+	discovered := make([]DiscoveredTool, 0, len(tools))
+	for _, tool := range tools {
+		discovered = append(discovered, DiscoveredTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema,
+			ServerName:  serverName,
+		})
+	}
+	conn.mu.Lock()
+	conn.tools = discovered
+	conn.mu.Unlock()
+
+	agentIDs := m.agentsUsing(conn)
+	m.logger.Info("mcp tool list changed", "server", serverName, "count", len(discovered), "agents", agentIDs)
+
+	m.hooksMu.RLock()
+	fn, eventBus := m.onToolsChanged, m.bus
+	m.hooksMu.RUnlock()
+	if fn != nil {
+		fn(serverName, agentIDs)
+	}
+	if eventBus != nil {
+		eventBus.Publish(bus.TopicMCPToolsChanged, bus.MCPToolsChangedEvent{
+			Server:    serverName,
+			ToolCount: len(discovered),
+			AgentIDs:  agentIDs,
+		})
+	}
+}
+
+// agentsUsing returns the sorted IDs of agents bound to conn.
+func (m *Manager) agentsUsing(conn *connection) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var agentIDs []string
+	for agentID, conns := range m.perAgent {
+		for _, c := range conns {
+			if c == conn {
+				agentIDs = append(agentIDs, agentID)
+				break
+			}
+		}
+	}
+	sort.Strings(agentIDs)
+	return agentIDs
 }
 
 // InvokeTool calls a tool on behalf of an agent (v0.4).
@@ -242,16 +479,39 @@ func (m *Manager) InvokeTool(ctx context.Context, agentID, serverName, toolName
 	m.mu.RUnlock()
 
 	// Policy check
-	if m.policy != nil {
-		pol, ok := m.policy.(policy.Policy)
-		if ok && !pol.AllowMCPTool(agentID, serverName, toolName) {
-			return nil, fmt.Errorf("policy denied mcp tool: %s/%s for agent %s", serverName, toolName, agentID)
-		}
+	if !m.authorizeTool(agentID, serverName, toolName) {
+		return nil, fmt.Errorf("policy denied mcp tool: %s/%s for agent %s", serverName, toolName, agentID)
 	}
 
 	return conn.client.CallTool(ctx, toolName, input)
 }
 
+// toolAllowed evaluates MCP rules for a tool. Checkers that do not implement
+// policy.MCPChecker carry no MCP rules and allow every tool.
+func (m *Manager) toolAllowed(agentID, serverName, toolName string) bool {
+	checker, ok := m.policyFor(agentID).(policy.MCPChecker)
+	if !ok {
+		return true
+	}
+	return checker.AllowMCPTool(agentID, serverName, toolName)
+}
+
+// authorizeTool is toolAllowed with the decision recorded in the audit log.
+func (m *Manager) authorizeTool(agentID, serverName, toolName string) bool {
+	allowed := m.toolAllowed(agentID, serverName, toolName)
+	pv := ""
+	if pol := m.policyFor(agentID); pol != nil {
+		pv = pol.PolicyVersion()
+	}
+	subject := fmt.Sprintf("%s:%s:%s", agentID, serverName, toolName)
+	if !allowed {
+		audit.Record("deny", "mcp.tool", "mcp_rule_denied", pv, subject)
+		return false
+	}
+	audit.Record("allow", "mcp.tool", "mcp_rule_allowed", pv, subject)
+	return true
+}
+
 // ServerNames returns server names accessible to an agent (v0.4).
 func (m *Manager) ServerNames(agentID string) []string {
 	m.mu.RLock()
@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/basket/go-claw/internal/audit"
 	"github.com/basket/go-claw/internal/policy"
 )
 
@@ -111,3 +115,55 @@ func TestManager_ReloadAgent(t *testing.T) {
 		t.Logf("ReloadAgent error (may be expected): %v", err)
 	}
 }
+
+// TestManager_LivePolicyMCPRules verifies MCP rules are enforced through
+// LivePolicy, follow hot reloads, and are audited.
+func TestManager_LivePolicyMCPRules(t *testing.T) {
+	auditHome := t.TempDir()
+	if err := audit.Init(auditHome); err != nil {
+		t.Fatalf("init audit: %v", err)
+	}
+	t.Cleanup(func() { _ = audit.Close() })
+
+	m, _ := newResourceTestManager(t)
+	live := policy.NewLivePolicy(policy.Policy{MCP: policy.MCPPolicyConfig{Default: "deny"}}, "")
+	m.policy = live
+	ctx := context.Background()
+
+	tools, err := m.DiscoverTools(ctx, "agent1")
+	if err != nil || len(tools) != 0 {
+		t.Fatalf("DiscoverTools under default deny = %d tools, err %v; want 0", len(tools), err)
+	}
+	if _, err := m.InvokeTool(ctx, "agent1", "docs", "search", json.RawMessage(`{}`)); err == nil {
+		t.Fatal("expected policy denial before reload")
+	}
+
+	live.Reload(policy.Policy{MCP: policy.MCPPolicyConfig{
+		Default: "deny",
+		Rules:   []policy.MCPRule{{Agent: "*", Server: "docs", Tools: []string{"search"}}},
+	}})
+
+	// Cached tool lists are re-filtered against the reloaded rules.
+	tools, err = m.DiscoverTools(ctx, "agent1")
+	if err != nil || len(tools) != 1 {
+		t.Fatalf("DiscoverTools after reload = %d tools, err %v; want 1", len(tools), err)
+	}
+	if _, err := m.InvokeTool(ctx, "agent1", "docs", "search", json.RawMessage(`{}`)); err != nil {
+		t.Fatalf("InvokeTool after reload: %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(auditHome, "logs", "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+	text := string(raw)
+	for _, want := range []string{
+		`"decision":"deny","capability":"mcp.tool","reason":"mcp_rule_denied"`,
+		`"decision":"allow","capability":"mcp.tool","reason":"mcp_rule_allowed"`,
+		`"subject":"agent1:docs:search"`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("audit log missing %s:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DiscoveredResource is a resource exposed by an MCP server accessible to an agent.
+type DiscoveredResource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mime_type,omitempty"`
+	ServerName  string `json:"server"`
+}
+
+// DiscoveredPrompt is a prompt template exposed by an MCP server accessible to an agent.
+type DiscoveredPrompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+	ServerName  string           `json:"server"`
+}
+
+// ResourceUpdateFunc is invoked when a subscribed resource changes.
+type ResourceUpdateFunc func(uri string)
+
+// resourcePinPrefix prefixes the pin source of MCP resource pins.
+const resourcePinPrefix = "mcp://"
+
+// ResourcePinner stores resource content as pinned context.
+// memory.PinManager implements it.
+type ResourcePinner interface {
+	AddResourcePin(ctx context.Context, agentID, source, content string, shared bool) error
+	RefreshResourcePin(ctx context.Context, agentID, source, content string) error
+}
+
+// ResourcePinSource returns the pin source key for a server resource.
+func ResourcePinSource(server, uri string) string {
+	return resourcePinPrefix + server + "/" + uri
+}
+
+// ParseResourcePinSource splits a pin source produced by ResourcePinSource.
+func ParseResourcePinSource(source string) (server, uri string, ok bool) {
+	rest, found := strings.CutPrefix(source, resourcePinPrefix)
+	if !found {
+		return "", "", false
+	}
+	server, uri, found = strings.Cut(rest, "/")
+	if !found || server == "" || uri == "" {
+		return "", "", false
+	}
+	return server, uri, true
+}
+
+// notifyResourceUpdated runs subscriber callbacks for uri. Callbacks run on
+// their own goroutine so they may call back into the client.
+func (c *connection) notifyResourceUpdated(uri string) {
+	c.subsMu.Lock()
+	fns := make([]ResourceUpdateFunc, 0, len(c.subs[uri]))
+	for _, fn := range c.subs[uri] {
+		fns = append(fns, fn)
+	}
+	c.subsMu.Unlock()
+	for _, fn := range fns {
+		go fn(uri)
+	}
+}
+
+// dropSubscriber removes all subscriptions held by agentID on this connection.
+func (c *connection) dropSubscriber(agentID string) {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	for uri, subs := range c.subs {
+		delete(subs, agentID)
+		if len(subs) == 0 {
+			delete(c.subs, uri)
+		}
+	}
+}
+
+// agentConn returns the connection serverName for agentID.
+func (m *Manager) agentConn(agentID, serverName string) (*connection, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	agentConns, exists := m.perAgent[agentID]
+	if !exists {
+		return nil, fmt.Errorf("agent not connected to any mcp servers: %s", agentID)
+	}
+	conn, ok := agentConns[serverName]
+	if !ok {
+		return nil, fmt.Errorf("agent %s not connected to server %s", agentID, serverName)
+	}
+	return conn, nil
+}
+
+// agentConns returns a snapshot of the agent's connections sorted by server name.
+func (m *Manager) agentConns(agentID string) ([]string, map[string]*connection) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	conns := make(map[string]*connection, len(m.perAgent[agentID]))
+	names := make([]string, 0, len(m.perAgent[agentID]))
+	for name, conn := range m.perAgent[agentID] {
+		conns[name] = conn
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, conns
+}
+
+// ListResources enumerates resources from all MCP servers accessible to an agent.
+// Servers that do not support resources are skipped.
+func (m *Manager) ListResources(ctx context.Context, agentID string) ([]DiscoveredResource, error) {
+	names, conns := m.agentConns(agentID)
+	out := []DiscoveredResource{}
+	for _, serverName := range names {
+		listCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		resources, err := conns[serverName].client.ListResources(listCtx)
+		cancel()
+		if err != nil {
+			m.logger.Debug("mcp resources unavailable", "agent", agentID, "server", serverName, "error", err)
+			continue
+		}
+		for _, r := range resources {
+			out = append(out, DiscoveredResource{
+				URI:         r.URI,
+				Name:        r.Name,
+				Description: r.Description,
+				MimeType:    r.MimeType,
+				ServerName:  serverName,
+			})
+		}
+	}
+	return out, nil
+}
+
+// ReadResource reads a resource and returns its text content. Binary
+// contents are summarized rather than inlined.
+func (m *Manager) ReadResource(ctx context.Context, agentID, serverName, uri string) (string, error) {
+	conn, err := m.agentConn(agentID, serverName)
+	if err != nil {
+		return "", err
+	}
+	contents, err := conn.client.ReadResource(ctx, uri)
+	if err != nil {
+		return "", fmt.Errorf("read mcp resource %s/%s: %w", serverName, uri, err)
+	}
+	var sb strings.Builder
+	for i, c := range contents {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if c.Text != "" || c.Blob == "" {
+			sb.WriteString(c.Text)
+			continue
+		}
+		fmt.Fprintf(&sb, "[binary content %s, %d bytes base64]", c.MimeType, len(c.Blob))
+	}
+	return sb.String(), nil
+}
+
+// SubscribeResource subscribes agentID to updates of a resource. fn is
+// invoked after each notifications/resources/updated for uri.
+func (m *Manager) SubscribeResource(ctx context.Context, agentID, serverName, uri string, fn ResourceUpdateFunc) error {
+	conn, err := m.agentConn(agentID, serverName)
+	if err != nil {
+		return err
+	}
+
+	conn.subsMu.Lock()
+	first := len(conn.subs[uri]) == 0
+	if conn.subs[uri] == nil {
+		conn.subs[uri] = make(map[string]ResourceUpdateFunc)
+	}
+	conn.subs[uri][agentID] = fn
+	conn.subsMu.Unlock()
+
+	// Shared connections subscribe once per URI.
+	if !first {
+		return nil
+	}
+	if err := conn.client.SubscribeResource(ctx, uri); err != nil {
+		conn.subsMu.Lock()
+		delete(conn.subs[uri], agentID)
+		if len(conn.subs[uri]) == 0 {
+			delete(conn.subs, uri)
+		}
+		conn.subsMu.Unlock()
+		return fmt.Errorf("subscribe mcp resource %s/%s: %w", serverName, uri, err)
+	}
+	return nil
+}
+
+// resubscribe renews conn's resource subscriptions on a new server session.
+func (m *Manager) resubscribe(conn *connection) {
+	conn.subsMu.Lock()
+	uris := make([]string, 0, len(conn.subs))
+	for uri := range conn.subs {
+		uris = append(uris, uri)
+	}
+	conn.subsMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, uri := range uris {
+		if err := conn.client.SubscribeResource(ctx, uri); err != nil {
+			m.logger.Warn("failed to renew mcp resource subscription", "server", conn.config.Name, "uri", uri, "error", err)
+		}
+	}
+}
+
+// UnsubscribeResource removes agentID's subscription to a resource.
+func (m *Manager) UnsubscribeResource(ctx context.Context, agentID, serverName, uri string) error {
+	conn, err := m.agentConn(agentID, serverName)
+	if err != nil {
+		return err
+	}
+	conn.subsMu.Lock()
+	_, had := conn.subs[uri][agentID]
+	delete(conn.subs[uri], agentID)
+	last := had && len(conn.subs[uri]) == 0
+	if len(conn.subs[uri]) == 0 {
+		delete(conn.subs, uri)
+	}
+	conn.subsMu.Unlock()
+
+	if !last {
+		return nil
+	}
+	if err := conn.client.UnsubscribeResource(ctx, uri); err != nil {
+		return fmt.Errorf("unsubscribe mcp resource %s/%s: %w", serverName, uri, err)
+	}
+	return nil
+}
+
+// PinResource reads a resource into the agent's pinned context and keeps it
+// fresh through a resource subscription. Servers without subscription
+// support still get a static pin.
+func (m *Manager) PinResource(ctx context.Context, agentID, serverName, uri string, shared bool, pins ResourcePinner) error {
+	content, err := m.ReadResource(ctx, agentID, serverName, uri)
+	if err != nil {
+		return err
+	}
+	source := ResourcePinSource(serverName, uri)
+	if err := pins.AddResourcePin(ctx, agentID, source, content, shared); err != nil {
+		return fmt.Errorf("pin mcp resource: %w", err)
+	}
+	m.subscribePin(ctx, agentID, serverName, uri, pins)
+	return nil
+}
+
+// UnpinResource stops refreshing a pinned resource. Removing the pin itself
+// is left to the caller's pin store.
+func (m *Manager) UnpinResource(ctx context.Context, agentID, serverName, uri string) error {
+	return m.UnsubscribeResource(ctx, agentID, serverName, uri)
+}
+
+// RestoreResourcePins re-subscribes previously pinned resources (by pin
+// source) after a restart and refreshes their content.
+func (m *Manager) RestoreResourcePins(ctx context.Context, agentID string, sources []string, pins ResourcePinner) {
+	for _, source := range sources {
+		serverName, uri, ok := ParseResourcePinSource(source)
+		if !ok {
+			continue
+		}
+		if _, err := m.agentConn(agentID, serverName); err != nil {
+			m.logger.Warn("pinned mcp resource server unavailable", "agent", agentID, "server", serverName, "uri", uri)
+			continue
+		}
+		m.refreshPin(ctx, agentID, serverName, uri, pins)
+		m.subscribePin(ctx, agentID, serverName, uri, pins)
+	}
+}
+
+func (m *Manager) subscribePin(ctx context.Context, agentID, serverName, uri string, pins ResourcePinner) {
+	err := m.SubscribeResource(ctx, agentID, serverName, uri, func(string) {
+		refreshCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		m.refreshPin(refreshCtx, agentID, serverName, uri, pins)
+	})
+	if err != nil {
+		m.logger.Warn("mcp resource pinned without live updates", "agent", agentID, "server", serverName, "uri", uri, "error", err)
+	}
+}
+
+func (m *Manager) refreshPin(ctx context.Context, agentID, serverName, uri string, pins ResourcePinner) {
+	content, err := m.ReadResource(ctx, agentID, serverName, uri)
+	if err != nil {
+		m.logger.Warn("failed to refresh pinned mcp resource", "agent", agentID, "server", serverName, "uri", uri, "error", err)
+		return
+	}
+	if err := pins.RefreshResourcePin(ctx, agentID, ResourcePinSource(serverName, uri), content); err != nil {
+		m.logger.Warn("failed to update pinned mcp resource", "agent", agentID, "server", serverName, "uri", uri, "error", err)
+		return
+	}
+	m.logger.Info("pinned mcp resource refreshed", "agent", agentID, "server", serverName, "uri", uri)
+}
+
+// ListPrompts enumerates prompt templates from all MCP servers accessible to an agent.
+// Servers that do not support prompts are skipped.
+func (m *Manager) ListPrompts(ctx context.Context, agentID string) ([]DiscoveredPrompt, error) {
+	names, conns := m.agentConns(agentID)
+	out := []DiscoveredPrompt{}
+	for _, serverName := range names {
+		listCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		prompts, err := conns[serverName].client.ListPrompts(listCtx)
+		cancel()
+		if err != nil {
+			m.logger.Debug("mcp prompts unavailable", "agent", agentID, "server", serverName, "error", err)
+			continue
+		}
+		for _, p := range prompts {
+			out = append(out, DiscoveredPrompt{
+				Name:        p.Name,
+				Description: p.Description,
+				Arguments:   p.Arguments,
+				ServerName:  serverName,
+			})
+		}
+	}
+	return out, nil
+}
+
+// GetPrompt renders a prompt template on behalf of an agent.
+func (m *Manager) GetPrompt(ctx context.Context, agentID, serverName, name string, args map[string]string) (*PromptResult, error) {
+	conn, err := m.agentConn(agentID, serverName)
+	if err != nil {
+		return nil, err
+	}
+	res, err := conn.client.GetPrompt(ctx, name, args)
+	if err != nil {
+		return nil, fmt.Errorf("get mcp prompt %s/%s: %w", serverName, name, err)
+	}
+	return res, nil
+}
+
+// Text flattens a rendered prompt into a single message suitable for chat input.
+// Embedded text resources are inlined; other content types are skipped.
+func (r *PromptResult) Text() string {
+	var parts []string
+	for _, msg := range r.Messages {
+		switch {
+		case msg.Content.Text != "":
+			parts = append(parts, msg.Content.Text)
+		case msg.Content.Resource != nil && msg.Content.Resource.Text != "":
+			parts = append(parts, msg.Content.Resource.Text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
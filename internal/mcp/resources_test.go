@@ -0,0 +1,313 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/policy"
+)
+
+// fakeResourceServer answers tool, resource and prompt requests over a MockTransport.
+type fakeResourceServer struct {
+	transport *MockTransport
+
+	mu         sync.Mutex
+	content    string
+	subscribed map[string]bool
+	tools      []string
+}
+
+func (s *fakeResourceServer) setTools(names ...string) {
+	s.mu.Lock()
+	s.tools = names
+	s.mu.Unlock()
+}
+
+func (s *fakeResourceServer) setContent(v string) {
+	s.mu.Lock()
+	s.content = v
+	s.mu.Unlock()
+}
+
+func (s *fakeResourceServer) isSubscribed(uri string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subscribed[uri]
+}
+
+func (s *fakeResourceServer) serve() {
+	for msg := range s.transport.Out {
+		var req struct {
+			ID     *int64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(msg, &req); err != nil || req.ID == nil {
+			continue
+		}
+		var p struct {
+			URI       string            `json:"uri"`
+			Name      string            `json:"name"`
+			Arguments map[string]string `json:"arguments"`
+			Cursor    string            `json:"cursor"`
+		}
+		_ = json.Unmarshal(req.Params, &p)
+
+		var result any
+		s.mu.Lock()
+		switch req.Method {
+		case "tools/list":
+			tools := make([]map[string]any, 0, len(s.tools))
+			for _, name := range s.tools {
+				tools = append(tools, map[string]any{"name": name, "inputSchema": map[string]any{"type": "object"}})
+			}
+			result = map[string]any{"tools": tools}
+		case "tools/call":
+			result = map[string]any{"content": []map[string]any{{"type": "text", "text": "ok"}}}
+		case "resources/list":
+			// Two pages to exercise cursor pagination.
+			if p.Cursor == "" {
+				result = map[string]any{
+					"resources":  []map[string]any{{"uri": "file:///readme.md", "name": "README", "mimeType": "text/markdown"}},
+					"nextCursor": "page2",
+				}
+			} else {
+				result = map[string]any{"resources": []map[string]any{{"uri": "file:///logo.png", "name": "Logo"}}}
+			}
+		case "resources/read":
+			result = map[string]any{"contents": []map[string]any{{"uri": p.URI, "mimeType": "text/markdown", "text": s.content}}}
+		case "resources/subscribe":
+			s.subscribed[p.URI] = true
+			result = map[string]any{}
+		case "resources/unsubscribe":
+			delete(s.subscribed, p.URI)
+			result = map[string]any{}
+		case "prompts/list":
+			result = map[string]any{"prompts": []map[string]any{{
+				"name":        "review",
+				"description": "Review code",
+				"arguments":   []map[string]any{{"name": "lang", "required": true}},
+			}}}
+		case "prompts/get":
+			result = map[string]any{
+				"description": "Review code",
+				"messages": []map[string]any{
+					{"role": "user", "content": map[string]any{"type": "text", "text": "Review this " + p.Arguments["lang"] + " code."}},
+					{"role": "user", "content": map[string]any{"type": "resource", "resource": map[string]any{"uri": "file:///a.go", "text": "package a"}}},
+				},
+			}
+		}
+		s.mu.Unlock()
+
+		var resp []byte
+		if result == nil {
+			resp, _ = json.Marshal(map[string]any{"jsonrpc": "2.0", "id": *req.ID, "error": map[string]any{"code": -32601, "message": "method not found"}})
+		} else {
+			resp, _ = json.Marshal(map[string]any{"jsonrpc": "2.0", "id": *req.ID, "result": result})
+		}
+		s.transport.In <- resp
+	}
+}
+
+// notify pushes a server-initiated notification to the client.
+func (s *fakeResourceServer) notify(method string, params any) {
+	b, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+	s.transport.In <- b
+}
+
+func newResourceTestManager(t *testing.T) (*Manager, *fakeResourceServer) {
+	t.Helper()
+	transport := NewMockTransport()
+	srv := &fakeResourceServer{transport: transport, content: "v1", subscribed: make(map[string]bool), tools: []string{"search"}}
+	go srv.serve()
+
+	client, err := NewClient("docs", transport)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	m := NewManager(nil, policy.Policy{MCP: policy.MCPPolicyConfig{Default: "allow"}}, newTestLogger())
+	m.perAgent["agent1"] = map[string]*connection{
+		"docs": m.newConnection(ServerConfig{Name: "docs", Enabled: true}, client),
+	}
+	return m, srv
+}
+
+type fakePinner struct {
+	mu      sync.Mutex
+	pins    map[string]string
+	updated chan string
+}
+
+func (p *fakePinner) AddResourcePin(_ context.Context, _, source, content string, _ bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pins[source] = content
+	return nil
+}
+
+func (p *fakePinner) RefreshResourcePin(_ context.Context, _, source, content string) error {
+	p.mu.Lock()
+	p.pins[source] = content
+	p.mu.Unlock()
+	p.updated <- content
+	return nil
+}
+
+func TestManager_ListAndReadResources(t *testing.T) {
+	m, _ := newResourceTestManager(t)
+	ctx := context.Background()
+
+	resources, err := m.ListResources(ctx, "agent1")
+	if err != nil {
+		t.Fatalf("ListResources: %v", err)
+	}
+	if len(resources) != 2 {
+		t.Fatalf("expected 2 resources across pages, got %d: %+v", len(resources), resources)
+	}
+	if resources[0].ServerName != "docs" || resources[0].MimeType != "text/markdown" {
+		t.Errorf("unexpected resource: %+v", resources[0])
+	}
+
+	content, err := m.ReadResource(ctx, "agent1", "docs", "file:///readme.md")
+	if err != nil {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	if content != "v1" {
+		t.Errorf("content = %q, want v1", content)
+	}
+
+	if _, err := m.ReadResource(ctx, "agent1", "missing", "file:///readme.md"); err == nil {
+		t.Error("expected error for unknown server")
+	}
+}
+
+func TestManager_Prompts(t *testing.T) {
+	m, _ := newResourceTestManager(t)
+	ctx := context.Background()
+
+	prompts, err := m.ListPrompts(ctx, "agent1")
+	if err != nil {
+		t.Fatalf("ListPrompts: %v", err)
+	}
+	if len(prompts) != 1 || prompts[0].Name != "review" || len(prompts[0].Arguments) != 1 || !prompts[0].Arguments[0].Required {
+		t.Fatalf("unexpected prompts: %+v", prompts)
+	}
+
+	res, err := m.GetPrompt(ctx, "agent1", "docs", "review", map[string]string{"lang": "Go"})
+	if err != nil {
+		t.Fatalf("GetPrompt: %v", err)
+	}
+	if got, want := res.Text(), "Review this Go code.\n\npackage a"; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
+
+func TestManager_PinResourceRefreshesOnUpdate(t *testing.T) {
+	m, srv := newResourceTestManager(t)
+	ctx := context.Background()
+	pins := &fakePinner{pins: make(map[string]string), updated: make(chan string, 1)}
+	uri := "file:///readme.md"
+	source := ResourcePinSource("docs", uri)
+
+	if err := m.PinResource(ctx, "agent1", "docs", uri, false, pins); err != nil {
+		t.Fatalf("PinResource: %v", err)
+	}
+	if pins.pins[source] != "v1" {
+		t.Fatalf("pinned content = %q, want v1", pins.pins[source])
+	}
+	if !srv.isSubscribed(uri) {
+		t.Fatal("expected resources/subscribe to be sent")
+	}
+
+	srv.setContent("v2")
+	srv.notify("notifications/resources/updated", map[string]any{"uri": uri})
+	select {
+	case got := <-pins.updated:
+		if got != "v2" {
+			t.Errorf("refreshed content = %q, want v2", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for pin refresh")
+	}
+
+	if err := m.UnpinResource(ctx, "agent1", "docs", uri); err != nil {
+		t.Fatalf("UnpinResource: %v", err)
+	}
+	if srv.isSubscribed(uri) {
+		t.Error("expected resources/unsubscribe after the last subscriber left")
+	}
+}
+
+func TestParseResourcePinSource(t *testing.T) {
+	tests := []struct {
+		source     string
+		wantServer string
+		wantURI    string
+		wantOK     bool
+	}{
+		{ResourcePinSource("docs", "file:///readme.md"), "docs", "file:///readme.md", true},
+		{"mcp://db/postgres://host/table", "db", "postgres://host/table", true},
+		{"notes.md", "", "", false},
+		{"mcp://docs", "", "", false},
+		{"mcp:///uri", "", "", false},
+	}
+	for _, tt := range tests {
+		server, uri, ok := ParseResourcePinSource(tt.source)
+		if server != tt.wantServer || uri != tt.wantURI || ok != tt.wantOK {
+			t.Errorf("ParseResourcePinSource(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.source, server, uri, ok, tt.wantServer, tt.wantURI, tt.wantOK)
+		}
+	}
+}
+
+func TestManager_ToolsListChangedRefreshesCache(t *testing.T) {
+	m, srv := newResourceTestManager(t)
+	ctx := context.Background()
+	eventBus := bus.New()
+	sub := eventBus.Subscribe(bus.TopicMCPToolsChanged)
+	defer eventBus.Unsubscribe(sub)
+	m.SetEventBus(eventBus)
+
+	changed := make(chan []string, 1)
+	m.OnToolsChanged(func(serverName string, agentIDs []string) {
+		if serverName == "docs" {
+			changed <- agentIDs
+		}
+	})
+
+	tools, err := m.DiscoverTools(ctx, "agent1")
+	if err != nil || len(tools) != 1 {
+		t.Fatalf("DiscoverTools = %d tools, err %v; want 1", len(tools), err)
+	}
+
+	srv.setTools("search", "fetch")
+	srv.notify("notifications/tools/list_changed", map[string]any{})
+
+	select {
+	case agentIDs := <-changed:
+		if len(agentIDs) != 1 || agentIDs[0] != "agent1" {
+			t.Errorf("bound agents = %v, want [agent1]", agentIDs)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for tools changed callback")
+	}
+
+	select {
+	case ev := <-sub.Ch():
+		payload, ok := ev.Payload.(bus.MCPToolsChangedEvent)
+		if !ok || payload.Server != "docs" || payload.ToolCount != 2 {
+			t.Errorf("unexpected event: %+v", ev.Payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for bus event")
+	}
+
+	tools, err = m.DiscoverTools(ctx, "agent1")
+	if err != nil || len(tools) != 2 {
+		t.Fatalf("DiscoverTools after change = %d tools, err %v; want 2", len(tools), err)
+	}
+}
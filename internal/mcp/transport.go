@@ -20,6 +20,13 @@ type Transport interface {
 	Close() error
 }
 
+// sessionResetNotifier is implemented by transports that may move to a new
+// server session without the client asking, so the client can repeat the
+// MCP handshake.
+type sessionResetNotifier interface {
+	onSessionReset(fn func())
+}
+
 // StdioTransport implements MCP transport over stdio.
 type StdioTransport struct {
 	cmd     *exec.Cmd
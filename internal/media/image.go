@@ -0,0 +1,92 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register decoder
+	"image/jpeg"
+	_ "image/png" // register decoder
+	"path/filepath"
+	"strings"
+
+	"github.com/basket/go-claw/internal/shared"
+)
+
+// maxDecodePixels guards against decompression bombs.
+const maxDecodePixels = 50_000_000
+
+// downscale re-encodes an image as JPEG when it exceeds MaxImageDimension
+// or MaxImageBytes. Formats the standard library cannot decode (WebP) are
+// kept as they are if they fit MaxImageBytes.
+func downscale(a shared.Attachment) (shared.Attachment, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(a.Data))
+	if err != nil {
+		if len(a.Data) <= MaxImageBytes {
+			return a, nil
+		}
+		return a, fmt.Errorf("image %q is %d bytes and cannot be resized; the limit is %d", a.Name, len(a.Data), MaxImageBytes)
+	}
+	if cfg.Width <= MaxImageDimension && cfg.Height <= MaxImageDimension && len(a.Data) <= MaxImageBytes {
+		return a, nil
+	}
+	if cfg.Width*cfg.Height > maxDecodePixels {
+		return a, fmt.Errorf("image %q is %dx%d pixels; too large to process", a.Name, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(a.Data))
+	if err != nil {
+		return a, fmt.Errorf("decode image %q: %w", a.Name, err)
+	}
+	w, h := fitWithin(cfg.Width, cfg.Height, MaxImageDimension)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resize(img, w, h), &jpeg.Options{Quality: 85}); err != nil {
+		return a, fmt.Errorf("encode image %q: %w", a.Name, err)
+	}
+	a.Data = buf.Bytes()
+	a.MimeType = "image/jpeg"
+	if a.Name != "" {
+		a.Name = strings.TrimSuffix(a.Name, filepath.Ext(a.Name)) + ".jpg"
+	}
+	return a, nil
+}
+
+// fitWithin scales w×h down, keeping the aspect ratio, so neither side
+// exceeds limit.
+func fitWithin(w, h, limit int) (int, int) {
+	if w <= limit && h <= limit {
+		return w, h
+	}
+	if w >= h {
+		return limit, max(1, h*limit/w)
+	}
+	return max(1, w*limit/h), limit
+}
+
+// resize box-filters src to w×h, flattening transparency onto white since
+// the result is encoded as JPEG.
+func resize(src image.Image, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*sh/h
+		y1 := max(b.Min.Y+(y+1)*sh/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*sw/w
+			x1 := max(b.Min.X+(x+1)*sw/w, x0+1)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr + 0xffff - ca)
+					g += uint64(cg + 0xffff - ca)
+					bl += uint64(cb + 0xffff - ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: 0xff})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,145 @@
+// Package media validates and normalizes chat attachments (images and
+// documents) before they are stored with session history and sent to models.
+package media
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/basket/go-claw/internal/shared"
+)
+
+const (
+	// MaxAttachments bounds the number of files on one message.
+	MaxAttachments = 10
+	// MaxAttachmentBytes bounds one file as received, before downscaling.
+	MaxAttachmentBytes = 20 << 20
+	// MaxImageDimension is the longest side, in pixels, of a stored image.
+	MaxImageDimension = 2048
+	// MaxImageBytes is the largest image stored without re-encoding.
+	MaxImageBytes = 4 << 20
+)
+
+// DefaultPrompt is the message text used when files are sent without any.
+const DefaultPrompt = "Please look at the attached file."
+
+// ErrUnsupportedType is returned for attachments that are neither images,
+// PDFs nor text documents.
+var ErrUnsupportedType = errors.New("unsupported attachment type")
+
+// Prepare checks an attachment, fills in its MIME type when missing or
+// generic, and downscales images larger than MaxImageDimension.
+func Prepare(a shared.Attachment) (shared.Attachment, error) {
+	if len(a.Data) == 0 {
+		return a, fmt.Errorf("attachment %q is empty", a.Name)
+	}
+	if len(a.Data) > MaxAttachmentBytes {
+		return a, fmt.Errorf("attachment %q is %d bytes; the limit is %d", a.Name, len(a.Data), MaxAttachmentBytes)
+	}
+	a.MimeType = DetectType(a.Name, a.MimeType, a.Data)
+	switch {
+	case IsImage(a.MimeType):
+		return downscale(a)
+	case a.MimeType == "application/pdf":
+		return a, nil
+	case IsText(a.MimeType):
+		if !utf8.Valid(a.Data) {
+			return a, fmt.Errorf("attachment %q is not valid UTF-8 text", a.Name)
+		}
+		return a, nil
+	}
+	return a, fmt.Errorf("%w: %s", ErrUnsupportedType, a.MimeType)
+}
+
+// DetectType returns the attachment's MIME type without parameters. The
+// declared type wins unless it is empty or generic, in which case the file
+// extension and then the content decide.
+func DetectType(name, declared string, data []byte) string {
+	if t := baseType(declared); t != "" && t != "application/octet-stream" {
+		return t
+	}
+	if ext := filepath.Ext(name); ext != "" {
+		if t := baseType(mime.TypeByExtension(ext)); t != "" {
+			return t
+		}
+	}
+	return baseType(http.DetectContentType(data))
+}
+
+func baseType(t string) string {
+	mt, _, err := mime.ParseMediaType(strings.TrimSpace(t))
+	if err != nil {
+		return ""
+	}
+	return mt
+}
+
+// IsImage reports whether mimeType is an image format models accept.
+func IsImage(mimeType string) bool {
+	switch mimeType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// IsText reports whether mimeType is a text document. Text documents are
+// passed to models inline as text, so they work without vision support.
+func IsText(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "text/") || mimeType == "application/json"
+}
+
+// NeedsVision reports whether any attachment must be sent to the model as
+// media rather than text.
+func NeedsVision(atts []shared.Attachment) bool {
+	for _, a := range atts {
+		if !IsText(a.MimeType) {
+			return true
+		}
+	}
+	return false
+}
+
+// DataURL encodes an attachment as a base64 data: URL.
+func DataURL(a shared.Attachment) string {
+	return "data:" + a.MimeType + ";base64," + base64.StdEncoding.EncodeToString(a.Data)
+}
+
+// ParseDataURL decodes a data: URL into its MIME type and content.
+func ParseDataURL(raw string) (mimeType string, data []byte, err error) {
+	rest, ok := strings.CutPrefix(raw, "data:")
+	if !ok {
+		return "", nil, fmt.Errorf("not a data: URL")
+	}
+	meta, payload, ok := strings.Cut(rest, ",")
+	if !ok {
+		return "", nil, fmt.Errorf("malformed data: URL")
+	}
+	meta, isBase64 := strings.CutSuffix(meta, ";base64")
+	if isBase64 {
+		data, err = base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			data, err = base64.RawStdEncoding.DecodeString(payload)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("decode data: URL: %w", err)
+		}
+	} else {
+		text, err := url.PathUnescape(payload)
+		if err != nil {
+			return "", nil, fmt.Errorf("decode data: URL: %w", err)
+		}
+		data = []byte(text)
+	}
+	if meta == "" {
+		meta = "text/plain"
+	}
+	return baseType(meta), data, nil
+}
@@ -0,0 +1,121 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/basket/go-claw/internal/shared"
+)
+
+func pngBytes(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestPrepare_Images(t *testing.T) {
+	small := pngBytes(t, 64, 32)
+	got, err := Prepare(shared.Attachment{Name: "small.png", Data: small})
+	if err != nil {
+		t.Fatalf("Prepare small: %v", err)
+	}
+	if got.MimeType != "image/png" || !bytes.Equal(got.Data, small) {
+		t.Errorf("small image should pass through unchanged, got %s (%d bytes)", got.MimeType, len(got.Data))
+	}
+
+	got, err = Prepare(shared.Attachment{Name: "wide.png", MimeType: "image/png", Data: pngBytes(t, 3000, 1000)})
+	if err != nil {
+		t.Fatalf("Prepare wide: %v", err)
+	}
+	if got.MimeType != "image/jpeg" || got.Name != "wide.jpg" {
+		t.Errorf("downscaled image = %s %q, want image/jpeg wide.jpg", got.MimeType, got.Name)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(got.Data))
+	if err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if cfg.Width != MaxImageDimension || cfg.Height != 682 {
+		t.Errorf("downscaled size = %dx%d, want %dx682", cfg.Width, cfg.Height, MaxImageDimension)
+	}
+}
+
+func TestPrepare_Documents(t *testing.T) {
+	tests := []struct {
+		name     string
+		att      shared.Attachment
+		wantType string
+		wantErr  error
+	}{
+		{"pdf by extension", shared.Attachment{Name: "report.pdf", MimeType: "application/octet-stream", Data: []byte("%PDF-1.7 ...")}, "application/pdf", nil},
+		{"markdown", shared.Attachment{Name: "notes.md", MimeType: "text/markdown; charset=utf-8", Data: []byte("# Notes")}, "text/markdown", nil},
+		{"sniffed text", shared.Attachment{Data: []byte("plain words")}, "text/plain", nil},
+		{"archive", shared.Attachment{Name: "a.zip", MimeType: "application/zip", Data: []byte("PK\x03\x04")}, "application/zip", ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Prepare(tt.att)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got.MimeType != tt.wantType {
+				t.Errorf("MimeType = %q, want %q", got.MimeType, tt.wantType)
+			}
+		})
+	}
+
+	if _, err := Prepare(shared.Attachment{Name: "empty.txt"}); err == nil {
+		t.Error("expected error for empty attachment")
+	}
+	if _, err := Prepare(shared.Attachment{Name: "bin.txt", MimeType: "text/plain", Data: []byte{0xff, 0xfe, 0x00}}); err == nil {
+		t.Error("expected error for invalid UTF-8 text")
+	}
+}
+
+func TestParseDataURL(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantType string
+		wantData string
+		wantErr  bool
+	}{
+		{"data:image/png;base64,aGVsbG8=", "image/png", "hello", false},
+		{"data:image/png;base64,aGVsbG8", "image/png", "hello", false},
+		{"data:,hi%20there", "text/plain", "hi there", false},
+		{"data:text/plain;base64,!!!", "", "", true},
+		{"https://example.com/cat.png", "", "", true},
+		{"data:image/png;base64", "", "", true},
+	}
+	for _, tt := range tests {
+		mt, data, err := ParseDataURL(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDataURL(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if mt != tt.wantType || string(data) != tt.wantData {
+			t.Errorf("ParseDataURL(%q) = %q %q, want %q %q", tt.in, mt, data, tt.wantType, tt.wantData)
+		}
+	}
+}
+
+func TestNeedsVision(t *testing.T) {
+	if NeedsVision([]shared.Attachment{{MimeType: "text/plain"}, {MimeType: "application/json"}}) {
+		t.Error("text documents should not need vision")
+	}
+	if !NeedsVision([]shared.Attachment{{MimeType: "text/plain"}, {MimeType: "image/png"}}) {
+		t.Error("images need vision")
+	}
+}
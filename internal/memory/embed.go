@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Embedder turns texts into embedding vectors for semantic memory ranking.
+type Embedder interface {
+	// Embed returns one vector per text, in order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model names the embeddings model; vectors of different models are
+	// stored apart.
+	Model() string
+}
+
+// DefaultOllamaEmbedModel is used when no embeddings model is configured.
+const DefaultOllamaEmbedModel = "nomic-embed-text"
+
+// OllamaEmbedder computes embeddings with a local Ollama server's /api/embed
+// endpoint.
+type OllamaEmbedder struct {
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+// NewOllamaEmbedder creates an embedder for the Ollama server at baseURL
+// (default http://localhost:11434) using model (default nomic-embed-text).
+func NewOllamaEmbedder(baseURL, model string) *OllamaEmbedder {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	// The OpenAI-compatible base URL used for chat ends in /v1.
+	baseURL = strings.TrimSuffix(baseURL, "/v1")
+	if strings.TrimSpace(model) == "" {
+		model = DefaultOllamaEmbedModel
+	}
+	return &OllamaEmbedder{
+		baseURL: baseURL,
+		model:   model,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Model returns the embeddings model name.
+func (o *OllamaEmbedder) Model() string { return o.model }
+
+// Embed requests the embeddings of texts in one call.
+func (o *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]any{"model": o.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("ollama embed returned %d: %s", resp.StatusCode, string(msg))
+	}
+
+	var out struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<20)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode ollama embed response: %w", err)
+	}
+	if len(out.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama embed returned %d vectors for %d texts", len(out.Embeddings), len(texts))
+	}
+	return out.Embeddings, nil
+}
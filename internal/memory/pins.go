@@ -11,6 +11,9 @@ import (
 	"github.com/basket/go-claw/internal/persistence"
 )
 
+// PinTypeMCPResource marks pins whose content is an MCP server resource.
+const PinTypeMCPResource = "mcp_resource"
+
 // PinStore interface for persistence operations related to pins.
 type PinStore interface {
 	AddPin(ctx context.Context, agentID, pinType, source, content string, shared bool) error
@@ -76,6 +79,53 @@ func (pm *PinManager) AddTextPin(ctx context.Context, agentID, label, content st
 	return pm.store.AddPin(ctx, agentID, "text", label, content, shared)
 }
 
+// AddResourcePin stores the content of an MCP resource as a pin.
+// Source identifies the resource (see mcp.ResourcePinSource).
+func (pm *PinManager) AddResourcePin(ctx context.Context, agentID, source, content string, shared bool) error {
+	if source == "" {
+		return fmt.Errorf("source cannot be empty")
+	}
+	if int64(len(content)) > pm.maxSize {
+		return fmt.Errorf("resource too large: %s (%d bytes, max %d bytes)", source, len(content), pm.maxSize)
+	}
+	return pm.store.AddPin(ctx, agentID, PinTypeMCPResource, source, content, shared)
+}
+
+// RefreshResourcePin replaces the stored content of an MCP resource pin,
+// typically after a resource update notification.
+func (pm *PinManager) RefreshResourcePin(ctx context.Context, agentID, source, content string) error {
+	pin, err := pm.store.GetPin(ctx, agentID, source)
+	if err != nil {
+		return err
+	}
+	if pin.PinType != PinTypeMCPResource {
+		return fmt.Errorf("pin is not an mcp resource")
+	}
+	if int64(len(content)) > pm.maxSize {
+		return fmt.Errorf("resource too large: %s (%d bytes, max %d bytes)", source, len(content), pm.maxSize)
+	}
+	updated := time.Now().Format("2006-01-02 15:04:05")
+	if err := pm.store.UpdatePinContent(ctx, agentID, source, content, updated); err != nil {
+		return fmt.Errorf("failed to update pin: %w", err)
+	}
+	return nil
+}
+
+// ResourcePinSources returns the sources of the agent's MCP resource pins.
+func (pm *PinManager) ResourcePinSources(ctx context.Context, agentID string) ([]string, error) {
+	pins, err := pm.store.ListPins(ctx, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pins: %w", err)
+	}
+	var sources []string
+	for _, pin := range pins {
+		if pin.PinType == PinTypeMCPResource {
+			sources = append(sources, pin.Source)
+		}
+	}
+	return sources, nil
+}
+
 // StartFileWatcher polls pinned files for changes every N seconds.
 // When a file's mtime changes, re-read and update the stored content.
 func (pm *PinManager) StartFileWatcher(ctx context.Context, agentID string) {
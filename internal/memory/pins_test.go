@@ -235,6 +235,61 @@ func TestPinManager_AddTextPin(t *testing.T) {
 	})
 }
 
+func TestPinManager_ResourcePin(t *testing.T) {
+	t.Run("add_and_refresh", func(t *testing.T) {
+		store := &mockPinStore{}
+		pm := NewPinManager(store)
+		ctx := context.Background()
+		source := "mcp://docs/file:///readme.md"
+
+		if err := pm.AddResourcePin(ctx, "test-agent", source, "v1", false); err != nil {
+			t.Fatalf("AddResourcePin failed: %v", err)
+		}
+		if pin := store.pins["test-agent"][source]; pin.PinType != PinTypeMCPResource {
+			t.Errorf("expected pin_type=%s, got %s", PinTypeMCPResource, pin.PinType)
+		}
+
+		if err := pm.RefreshResourcePin(ctx, "test-agent", source, "v2"); err != nil {
+			t.Fatalf("RefreshResourcePin failed: %v", err)
+		}
+		if pin := store.pins["test-agent"][source]; pin.Content != "v2" {
+			t.Errorf("expected refreshed content v2, got %q", pin.Content)
+		}
+
+		sources, err := pm.ResourcePinSources(ctx, "test-agent")
+		if err != nil {
+			t.Fatalf("ResourcePinSources failed: %v", err)
+		}
+		if len(sources) != 1 || sources[0] != source {
+			t.Errorf("expected [%s], got %v", source, sources)
+		}
+	})
+
+	t.Run("refresh_rejects_other_pin_types", func(t *testing.T) {
+		store := &mockPinStore{}
+		pm := NewPinManager(store)
+		ctx := context.Background()
+
+		if err := pm.AddTextPin(ctx, "test-agent", "notes", "text", false); err != nil {
+			t.Fatalf("AddTextPin failed: %v", err)
+		}
+		if err := pm.RefreshResourcePin(ctx, "test-agent", "notes", "new"); err == nil {
+			t.Fatal("expected error refreshing a text pin")
+		}
+	})
+
+	t.Run("too_large_rejected", func(t *testing.T) {
+		store := &mockPinStore{}
+		pm := NewPinManager(store)
+		pm.maxSize = 4
+
+		err := pm.AddResourcePin(context.Background(), "test-agent", "mcp://s/u", "too long", false)
+		if err == nil {
+			t.Fatal("expected error for oversized resource")
+		}
+	})
+}
+
 func TestPinManager_FormatPins(t *testing.T) {
 	t.Run("format_empty_pins", func(t *testing.T) {
 		store := &mockPinStore{}
@@ -0,0 +1,238 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+// DefaultRetrievalTokens is the token budget for the memories injected into
+// each turn when none is configured.
+const DefaultRetrievalTokens = 1000
+
+// rrfK damps the reciprocal rank fusion of keyword and semantic rankings, as
+// in the original RRF paper.
+const rrfK = 60
+
+// RetrievalStore interface for the persistence operations memory retrieval uses.
+type RetrievalStore interface {
+	SearchMemoriesRanked(ctx context.Context, agentID, query string, limit int) ([]persistence.MemoryHit, error)
+	ListMemories(ctx context.Context, agentID string) ([]persistence.AgentMemory, error)
+	ListMemoryEmbeddings(ctx context.Context, agentID, model string) (map[int64]persistence.StoredEmbedding, error)
+	SaveMemoryEmbedding(ctx context.Context, e persistence.StoredEmbedding) error
+}
+
+// Retriever finds the memories relevant to a query. Memories are ranked by
+// BM25 over the full-text index; with an Embedder, that ranking is fused with
+// cosine similarity of embeddings (hybrid ranking).
+type Retriever struct {
+	store    RetrievalStore
+	embedder Embedder
+}
+
+// NewRetriever creates a retriever. embedder may be nil for keyword-only
+// ranking.
+func NewRetriever(store RetrievalStore, embedder Embedder) *Retriever {
+	return &Retriever{store: store, embedder: embedder}
+}
+
+// Search returns up to limit of the agent's memories relevant to query, best
+// first. An embeddings failure is logged and falls back to keyword ranking.
+func (r *Retriever) Search(ctx context.Context, agentID, query string, limit int) ([]persistence.MemoryHit, error) {
+	hits, err := r.store.SearchMemoriesRanked(ctx, agentID, query, 0)
+	if err != nil {
+		return nil, err
+	}
+	if r.embedder != nil {
+		fused, err := r.hybrid(ctx, agentID, query, hits, limit)
+		if err == nil {
+			hits = fused
+		} else {
+			slog.Warn("memory: semantic ranking failed, using keyword ranking", "agent_id", agentID, "error", err)
+		}
+	}
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// Select returns the memories to inject for a turn: those relevant to the
+// prompt, best first, as long as they fit in tokenBudget (formatted as core
+// memory lines). When nothing matches the prompt, the memories with the
+// highest relevance score are used instead, so standing facts are still known.
+func (r *Retriever) Select(ctx context.Context, agentID, prompt string, tokenBudget int) ([]persistence.AgentMemory, error) {
+	if tokenBudget <= 0 {
+		tokenBudget = DefaultRetrievalTokens
+	}
+	hits, err := r.Search(ctx, agentID, prompt, 0)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []persistence.AgentMemory
+	for _, h := range hits {
+		candidates = append(candidates, h.AgentMemory)
+	}
+	if len(candidates) == 0 {
+		if candidates, err = r.store.ListMemories(ctx, agentID); err != nil {
+			return nil, err
+		}
+	}
+
+	var out []persistence.AgentMemory
+	used := 0
+	for _, m := range candidates {
+		cost := EstimateTokens(fmt.Sprintf("%s: %s\n", m.Key, m.Value))
+		if used+cost > tokenBudget {
+			continue // a shorter memory further down may still fit
+		}
+		used += cost
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// hybrid fuses the keyword hits with the semantic ranking of all the agent's
+// memories by reciprocal rank fusion. The semantic ranking keeps the closest
+// memories only, so unrelated ones are not pulled in.
+func (r *Retriever) hybrid(ctx context.Context, agentID, query string, hits []persistence.MemoryHit, limit int) ([]persistence.MemoryHit, error) {
+	memories, err := r.store.ListMemories(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(memories) == 0 {
+		return hits, nil
+	}
+	vectors, err := r.memoryVectors(ctx, agentID, memories)
+	if err != nil {
+		return nil, err
+	}
+	q, err := r.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	if len(q) != 1 {
+		return nil, fmt.Errorf("embed query: got %d vectors", len(q))
+	}
+
+	type scored struct {
+		mem persistence.AgentMemory
+		sim float64
+	}
+	var semantic []scored
+	for _, m := range memories {
+		if v, ok := vectors[m.ID]; ok {
+			semantic = append(semantic, scored{m, cosine(q[0], v)})
+		}
+	}
+	sort.SliceStable(semantic, func(i, j int) bool { return semantic[i].sim > semantic[j].sim })
+	keep := limit
+	if keep <= 0 || keep < len(hits) {
+		keep = len(hits)
+	}
+	if keep < 5 {
+		keep = 5
+	}
+	if len(semantic) > keep {
+		semantic = semantic[:keep]
+	}
+
+	fused := map[int64]*persistence.MemoryHit{}
+	var order []int64
+	add := func(m persistence.AgentMemory, rank int) {
+		h, ok := fused[m.ID]
+		if !ok {
+			h = &persistence.MemoryHit{AgentMemory: m}
+			fused[m.ID] = h
+			order = append(order, m.ID)
+		}
+		h.Score += 1 / float64(rrfK+rank+1)
+	}
+	for i, h := range hits {
+		add(h.AgentMemory, i)
+	}
+	for i, s := range semantic {
+		add(s.mem, i)
+	}
+
+	out := make([]persistence.MemoryHit, 0, len(order))
+	for _, id := range order {
+		out = append(out, *fused[id])
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out, nil
+}
+
+// memoryVectors returns the embedding of each memory, computing and storing
+// those that are missing or were computed from an older value.
+func (r *Retriever) memoryVectors(ctx context.Context, agentID string, memories []persistence.AgentMemory) (map[int64][]float32, error) {
+	model := r.embedder.Model()
+	stored, err := r.store.ListMemoryEmbeddings(ctx, agentID, model)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int64][]float32, len(memories))
+	var stale []persistence.AgentMemory
+	var texts []string
+	for _, m := range memories {
+		text := m.Key + ": " + m.Value
+		if e, ok := stored[m.ID]; ok && e.ContentHash == contentHash(text) {
+			out[m.ID] = e.Vector
+			continue
+		}
+		stale = append(stale, m)
+		texts = append(texts, text)
+	}
+	if len(stale) == 0 {
+		return out, nil
+	}
+
+	vectors, err := r.embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed memories: %w", err)
+	}
+	if len(vectors) != len(stale) {
+		return nil, fmt.Errorf("embed memories: got %d vectors for %d texts", len(vectors), len(stale))
+	}
+	for i, m := range stale {
+		out[m.ID] = vectors[i]
+		if err := r.store.SaveMemoryEmbedding(ctx, persistence.StoredEmbedding{
+			MemoryID:    m.ID,
+			Model:       model,
+			ContentHash: contentHash(texts[i]),
+			Vector:      vectors[i],
+		}); err != nil {
+			slog.Warn("memory: failed to store embedding", "agent_id", agentID, "key", m.Key, "error", err)
+		}
+	}
+	return out, nil
+}
+
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// cosine returns the cosine similarity of two vectors, 0 when their lengths
+// differ or either is zero.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,187 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+func openRetrievalStore(t *testing.T, memories map[string]string) *persistence.Store {
+	t.Helper()
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "test.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	for k, v := range memories {
+		if err := store.SetMemory(context.Background(), "coder", k, v, "user"); err != nil {
+			t.Fatalf("SetMemory: %v", err)
+		}
+	}
+	return store
+}
+
+// fakeEmbedder embeds a text as the counts of a few topic words, so texts on
+// the same topic are similar without sharing words with the query.
+type fakeEmbedder struct {
+	calls int
+	err   error
+}
+
+func (f *fakeEmbedder) Model() string { return "fake" }
+
+func (f *fakeEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	topics := [][]string{{"dog", "puppy", "pet"}, {"deploy", "release", "ship"}}
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(topics))
+		for j, words := range topics {
+			for _, w := range words {
+				v[j] += float32(strings.Count(strings.ToLower(text), w))
+			}
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func TestRetriever_Select(t *testing.T) {
+	store := openRetrievalStore(t, map[string]string{
+		"pet":       "Has a dog named Rex",
+		"deploys":   "Release train leaves on Thursdays",
+		"long_note": strings.Repeat("release details ", 100),
+	})
+	r := NewRetriever(store, nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		prompt string
+		budget int
+		want   []string
+	}{
+		{"relevant only", "when is the next release?", 100, []string{"deploys"}},
+		{"budget skips what does not fit", "release", 100, []string{"deploys"}},
+		{"larger budget", "release", 1000, []string{"long_note", "deploys"}},
+		{"no match falls back to all", "hello", 100, []string{"deploys", "pet"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Select(ctx, "coder", tt.prompt, tt.budget)
+			if err != nil {
+				t.Fatalf("Select: %v", err)
+			}
+			var keys []string
+			for _, m := range got {
+				keys = append(keys, m.Key)
+			}
+			if !sameKeys(keys, tt.want) {
+				t.Errorf("Select(%q) = %v, want %v", tt.prompt, keys, tt.want)
+			}
+		})
+	}
+}
+
+// sameKeys reports whether a and b hold the same keys in any order.
+func sameKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]bool{}
+	for _, k := range a {
+		seen[k] = true
+	}
+	for _, k := range b {
+		if !seen[k] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRetriever_HybridRanking(t *testing.T) {
+	store := openRetrievalStore(t, map[string]string{
+		"pet":    "Has a puppy named Rex",
+		"deploy": "Ship on Thursdays",
+		"editor": "Prefers vim",
+	})
+	emb := &fakeEmbedder{}
+	r := NewRetriever(store, emb)
+	ctx := context.Background()
+
+	// "dog" matches no memory text, but is close to the puppy memory.
+	hits, err := r.Search(ctx, "coder", "my dog", 1)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Key != "pet" {
+		t.Fatalf("Search = %+v, want the pet memory", hits)
+	}
+
+	// Memory embeddings are stored and reused; only the query is embedded.
+	emb.calls = 0
+	if _, err := r.Search(ctx, "coder", "release", 0); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if emb.calls != 1 {
+		t.Errorf("embedder called %d times, want 1", emb.calls)
+	}
+
+	// An embeddings failure falls back to keyword ranking.
+	emb.err = errors.New("connection refused")
+	hits, err = r.Search(ctx, "coder", "vim", 0)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Key != "editor" {
+		t.Errorf("Search with failing embedder = %+v, want the editor memory", hits)
+	}
+}
+
+func TestOllamaEmbedder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != DefaultOllamaEmbedModel {
+			http.Error(w, "unknown model", http.StatusNotFound)
+			return
+		}
+		out := make([][]float32, len(req.Input))
+		for i := range req.Input {
+			out[i] = []float32{float32(i), 1}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"embeddings": out})
+	}))
+	defer srv.Close()
+
+	e := NewOllamaEmbedder(srv.URL+"/v1", "")
+	got, err := e.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(got) != 2 || got[1][0] != 1 {
+		t.Errorf("Embed = %v", got)
+	}
+
+	if _, err := NewOllamaEmbedder(srv.URL, "missing").Embed(context.Background(), []string{"a"}); err == nil {
+		t.Error("expected an error for an unknown model")
+	}
+}
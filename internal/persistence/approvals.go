@@ -0,0 +1,194 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tool approval statuses stored in approvals.status.
+const (
+	ApprovalPending         = "PENDING"
+	ApprovalApproved        = "APPROVED"
+	ApprovalApprovedSession = "APPROVED_SESSION"
+	ApprovalDenied          = "DENIED"
+	ApprovalExpired         = "EXPIRED"
+)
+
+// ToolApproval is a human decision on one tool call. The row outlives the
+// process, so a task parked on it can pick the decision up after a restart.
+type ToolApproval struct {
+	ID         string
+	TaskID     string
+	RunID      string // task execution that asked; a re-run adopts or supersedes it
+	SessionID  string
+	AgentID    string
+	Tool       string // stored in approvals.capability
+	Args       string // rendered arguments, stored in approvals.resource
+	ArgsHash   string
+	Status     string
+	Reason     string
+	ExpiresAt  time.Time
+	ResolvedAt *time.Time
+	ConsumedAt *time.Time
+	CreatedAt  time.Time
+}
+
+const toolApprovalColumns = `approval_id, COALESCE(task_id, ''), session_id, agent_id, COALESCE(capability, ''),
+	COALESCE(resource, ''), args_hash, status, reason, expires_at, resolved_at, consumed_at, created_at, run_id`
+
+func scanToolApproval(row interface{ Scan(...any) error }) (*ToolApproval, error) {
+	a := &ToolApproval{}
+	var expires sql.NullTime
+	if err := row.Scan(&a.ID, &a.TaskID, &a.SessionID, &a.AgentID, &a.Tool, &a.Args, &a.ArgsHash,
+		&a.Status, &a.Reason, &expires, &a.ResolvedAt, &a.ConsumedAt, &a.CreatedAt, &a.RunID); err != nil {
+		return nil, err
+	}
+	a.ExpiresAt = expires.Time
+	return a, nil
+}
+
+// CreateToolApproval stores a new PENDING approval request.
+func (s *Store) CreateToolApproval(ctx context.Context, a *ToolApproval) error {
+	if a.ID == "" {
+		a.ID = uuid.NewString()
+	}
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now().UTC()
+	}
+	a.Status = ApprovalPending
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO approvals (approval_id, task_id, run_id, session_id, agent_id, capability, resource, args_hash, status, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		a.ID, a.TaskID, a.RunID, a.SessionID, a.AgentID, a.Tool, a.Args, a.ArgsHash, a.Status, a.ExpiresAt.UTC(), a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create tool approval: %w", err)
+	}
+	return nil
+}
+
+// GetToolApproval returns the approval with id, or sql.ErrNoRows.
+func (s *Store) GetToolApproval(ctx context.Context, id string) (*ToolApproval, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+toolApprovalColumns+` FROM approvals WHERE approval_id = ?;`, id)
+	return scanToolApproval(row)
+}
+
+// FindToolApproval returns the newest unconsumed approval for the same task,
+// tool and arguments, or nil when there is none. A task re-run after a
+// restart uses it to resume waiting instead of asking again.
+func (s *Store) FindToolApproval(ctx context.Context, taskID, tool, argsHash string) (*ToolApproval, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+toolApprovalColumns+`
+		FROM approvals
+		WHERE task_id = ? AND capability = ? AND args_hash = ? AND consumed_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1;`, taskID, tool, argsHash)
+	a, err := scanToolApproval(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find tool approval: %w", err)
+	}
+	return a, nil
+}
+
+// SetToolApprovalRun records that runID, a re-run of the approval's task,
+// now waits on it.
+func (s *Store) SetToolApprovalRun(ctx context.Context, id, runID string) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE approvals SET run_id = ? WHERE approval_id = ?;`, runID, id); err != nil {
+		return fmt.Errorf("set tool approval run: %w", err)
+	}
+	return nil
+}
+
+// ListPendingTaskToolApprovals returns taskID's PENDING tool approvals.
+func (s *Store) ListPendingTaskToolApprovals(ctx context.Context, taskID string) ([]*ToolApproval, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+toolApprovalColumns+`
+		FROM approvals
+		WHERE task_id = ? AND status = ? AND args_hash != ''
+		ORDER BY created_at ASC;`, taskID, ApprovalPending)
+	if err != nil {
+		return nil, fmt.Errorf("list task tool approvals: %w", err)
+	}
+	defer rows.Close()
+	var out []*ToolApproval
+	for rows.Next() {
+		a, err := scanToolApproval(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan tool approval: %w", err)
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// ResolveToolApproval moves a PENDING approval to status. It reports false
+// when the approval was already resolved.
+func (s *Store) ResolveToolApproval(ctx context.Context, id, status, reason string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE approvals SET status = ?, reason = ?, resolved_at = ?
+		WHERE approval_id = ? AND status = ?;`,
+		status, reason, time.Now().UTC(), id, ApprovalPending)
+	if err != nil {
+		return false, fmt.Errorf("resolve tool approval: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("resolve tool approval rows affected: %w", err)
+	}
+	return n == 1, nil
+}
+
+// ConsumeToolApproval marks a decision as delivered to the waiting tool call.
+func (s *Store) ConsumeToolApproval(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE approvals SET consumed_at = ? WHERE approval_id = ? AND consumed_at IS NULL;`,
+		time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("consume tool approval: %w", err)
+	}
+	return nil
+}
+
+// ListPendingToolApprovals returns PENDING tool approvals, oldest first.
+func (s *Store) ListPendingToolApprovals(ctx context.Context) ([]*ToolApproval, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+toolApprovalColumns+`
+		FROM approvals
+		WHERE status = ? AND args_hash != ''
+		ORDER BY created_at ASC;`, ApprovalPending)
+	if err != nil {
+		return nil, fmt.Errorf("list pending tool approvals: %w", err)
+	}
+	defer rows.Close()
+	var out []*ToolApproval
+	for rows.Next() {
+		a, err := scanToolApproval(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan tool approval: %w", err)
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// HasSessionToolApproval reports whether tool was approved for the rest of
+// sessionID for agentID.
+func (s *Store) HasSessionToolApproval(ctx context.Context, sessionID, agentID, tool string) (bool, error) {
+	var one int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT 1 FROM approvals
+		WHERE session_id = ? AND agent_id = ? AND capability = ? AND status = ?
+		LIMIT 1;`, sessionID, agentID, tool, ApprovalApprovedSession).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check session tool approval: %w", err)
+	}
+	return true, nil
+}
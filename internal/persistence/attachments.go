@@ -0,0 +1,87 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/basket/go-claw/internal/shared"
+)
+
+// AddHistoryWithAttachments appends a message to session history together
+// with the images and documents sent with it.
+func (s *Store) AddHistoryWithAttachments(ctx context.Context, sessionID, agentID, role, content string, tokens int, atts []shared.Attachment) error {
+	role = strings.ToLower(strings.TrimSpace(role))
+	switch role {
+	case "system", "user", "assistant", "tool":
+	default:
+		return fmt.Errorf("invalid role %q", role)
+	}
+	if agentID == "" {
+		agentID = "default"
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin add history tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO messages (session_id, agent_id, role, content, tokens, created_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP);
+	`, sessionID, agentID, role, content, tokens)
+	if err != nil {
+		return fmt.Errorf("insert message: %w", err)
+	}
+	if len(atts) > 0 {
+		messageID, err := res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("message id: %w", err)
+		}
+		for _, a := range atts {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO message_attachments (message_id, session_id, mime_type, name, data)
+				VALUES (?, ?, ?, ?, ?);
+			`, messageID, sessionID, a.MimeType, a.Name, a.Data); err != nil {
+				return fmt.Errorf("insert message attachment: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit add history tx: %w", err)
+	}
+	return nil
+}
+
+// loadAttachments fills in the attachments of history items loaded from
+// one session.
+func (s *Store) loadAttachments(ctx context.Context, sessionID string, items []HistoryItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	byID := make(map[int64]int, len(items))
+	for i, item := range items {
+		byID[item.ID] = i
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT message_id, mime_type, name, data
+		FROM message_attachments
+		WHERE session_id = ? AND message_id BETWEEN ? AND ?
+		ORDER BY id ASC;
+	`, sessionID, items[0].ID, items[len(items)-1].ID)
+	if err != nil {
+		return fmt.Errorf("query message attachments: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var messageID int64
+		var a shared.Attachment
+		if err := rows.Scan(&messageID, &a.MimeType, &a.Name, &a.Data); err != nil {
+			return fmt.Errorf("scan message attachment: %w", err)
+		}
+		if i, ok := byID[messageID]; ok {
+			items[i].Attachments = append(items[i].Attachments, a)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,67 @@
+package persistence
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/basket/go-claw/internal/shared"
+)
+
+func TestAttachments_StoredWithHistory(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "goclaw.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	sessionID := "2f0d8c5e-1b7a-4c3d-9e8f-0a1b2c3d4e5f"
+	if err := store.EnsureSession(ctx, sessionID); err != nil {
+		t.Fatalf("EnsureSession: %v", err)
+	}
+
+	atts := []shared.Attachment{
+		{MimeType: "image/jpeg", Name: "photo.jpg", Data: []byte{0xff, 0xd8, 0xff}},
+		{MimeType: "application/pdf", Name: "spec.pdf", Data: []byte("%PDF-1.7")},
+	}
+	if err := store.AddHistory(ctx, sessionID, "coder", "user", "plain", 1); err != nil {
+		t.Fatalf("AddHistory: %v", err)
+	}
+	if err := store.AddHistoryWithAttachments(ctx, sessionID, "coder", "user", "see attached", 2, atts); err != nil {
+		t.Fatalf("AddHistoryWithAttachments: %v", err)
+	}
+	if err := store.AddHistoryWithAttachments(ctx, sessionID, "coder", "robot", "x", 1, atts); err == nil {
+		t.Error("expected error for invalid role")
+	}
+
+	history, err := store.ListHistory(ctx, sessionID, "coder", 10)
+	if err != nil {
+		t.Fatalf("ListHistory: %v", err)
+	}
+	if len(history) != 2 || len(history[0].Attachments) != 0 || len(history[1].Attachments) != 2 {
+		t.Fatalf("history = %+v, want the second message to carry both attachments", history)
+	}
+	if got := history[1].Attachments[0]; got.MimeType != "image/jpeg" || got.Name != "photo.jpg" || len(got.Data) != 3 {
+		t.Errorf("attachment = %+v", got)
+	}
+
+	// Clearing the session's messages removes their attachments too.
+	if err := store.ClearSessionMessages(ctx, sessionID, "coder"); err != nil {
+		t.Fatalf("ClearSessionMessages: %v", err)
+	}
+	var n int
+	if err := store.DB().QueryRow(`SELECT COUNT(*) FROM message_attachments;`).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("attachments after clear = %d (err %v), want 0", n, err)
+	}
+
+	if err := store.AddHistoryWithAttachments(ctx, sessionID, "coder", "user", "again", 1, atts[:1]); err != nil {
+		t.Fatalf("AddHistoryWithAttachments: %v", err)
+	}
+	res, err := store.PurgeSessionPII(ctx, sessionID, "pol-v1", "test")
+	if err != nil {
+		t.Fatalf("PurgeSessionPII: %v", err)
+	}
+	if res.AttachmentsDeleted != 1 {
+		t.Errorf("AttachmentsDeleted = %d, want 1", res.AttachmentsDeleted)
+	}
+}
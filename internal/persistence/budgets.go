@@ -0,0 +1,52 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SpendScope names what a spend budget is kept for.
+type SpendScope string
+
+const (
+	SpendScopeAgent   SpendScope = "agent"
+	SpendScopeAPIKey  SpendScope = "api_key"
+	SpendScopeSession SpendScope = "session"
+)
+
+// spendColumns maps a scope to the column it filters on.
+var spendColumns = map[SpendScope]string{
+	SpendScopeAgent:   "agent_id",
+	SpendScopeAPIKey:  "api_key_id",
+	SpendScopeSession: "session_id",
+}
+
+// Spend sums the token usage charged to one agent, API key or session since
+// the given time. Finished tasks are read from task_metrics; tasks that have
+// not finished yet, such as running or retrying ones, from tasks.
+func (s *Store) Spend(ctx context.Context, scope SpendScope, id string, since time.Time) (TaskUsage, error) {
+	col, ok := spendColumns[scope]
+	if !ok {
+		return TaskUsage{}, fmt.Errorf("spend: unknown scope %q", scope)
+	}
+	ts := since.UTC().Format("2006-01-02 15:04:05")
+	var u TaskUsage
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0),
+		       COALESCE(SUM(cached_tokens), 0), COALESCE(SUM(reasoning_tokens), 0),
+		       COALESCE(SUM(estimated_cost_usd), 0)
+		FROM (
+			SELECT prompt_tokens, completion_tokens, cached_tokens, reasoning_tokens, estimated_cost_usd
+			FROM task_metrics WHERE %[1]s = ? AND completed_at >= ?
+			UNION ALL
+			SELECT prompt_tokens, completion_tokens, cached_tokens, reasoning_tokens, estimated_cost_usd
+			FROM tasks WHERE %[1]s = ? AND updated_at >= ?
+			  AND id NOT IN (SELECT task_id FROM task_metrics)
+		)`, col), id, ts, id, ts).
+		Scan(&u.PromptTokens, &u.CompletionTokens, &u.CachedTokens, &u.ReasoningTokens, &u.CostUSD)
+	if err != nil {
+		return TaskUsage{}, fmt.Errorf("spend %s %s: %w", scope, id, err)
+	}
+	return u, nil
+}
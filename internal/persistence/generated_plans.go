@@ -0,0 +1,139 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Generated plan statuses stored in generated_plans.status.
+const (
+	GeneratedPlanDraft    = "draft"
+	GeneratedPlanApproved = "approved"
+	GeneratedPlanRejected = "rejected"
+)
+
+// ErrGeneratedPlanChanged is returned by SetGeneratedPlanStatusIf when the
+// plan's definition is no longer the one the decision was made on.
+var ErrGeneratedPlanChanged = errors.New("generated plan changed since it was reviewed")
+
+// GeneratedPlan is a plan written by an agent through the create_plan tool.
+// Definition holds the plan in the YAML form of a config.PlanConfig, so it
+// can be reviewed and edited like a configured plan. Only approved plans run.
+type GeneratedPlan struct {
+	Name       string
+	Goal       string
+	Definition string
+	Status     string
+	CreatedBy  string // agent that created the plan
+	SessionID  string // session the plan was created in
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+const generatedPlanColumns = `name, goal, definition, status, created_by, session_id, created_at, updated_at`
+
+func scanGeneratedPlan(row interface{ Scan(...any) error }) (*GeneratedPlan, error) {
+	p := &GeneratedPlan{}
+	if err := row.Scan(&p.Name, &p.Goal, &p.Definition, &p.Status, &p.CreatedBy, &p.SessionID,
+		&p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SaveGeneratedPlan inserts a plan or replaces the plan with the same name.
+// Replacing keeps the original creation time.
+func (s *Store) SaveGeneratedPlan(ctx context.Context, p *GeneratedPlan) error {
+	if p.Status == "" {
+		p.Status = GeneratedPlanDraft
+	}
+	now := time.Now().UTC()
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = now
+	}
+	p.UpdatedAt = now
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO generated_plans (name, goal, definition, status, created_by, session_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			goal = excluded.goal,
+			definition = excluded.definition,
+			status = excluded.status,
+			created_by = excluded.created_by,
+			session_id = excluded.session_id,
+			updated_at = excluded.updated_at;`,
+		p.Name, p.Goal, p.Definition, p.Status, p.CreatedBy, p.SessionID, p.CreatedAt, p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("save generated plan: %w", err)
+	}
+	return nil
+}
+
+// GetGeneratedPlan returns the plan called name, or sql.ErrNoRows.
+func (s *Store) GetGeneratedPlan(ctx context.Context, name string) (*GeneratedPlan, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+generatedPlanColumns+` FROM generated_plans WHERE name = ?;`, name)
+	return scanGeneratedPlan(row)
+}
+
+// ListGeneratedPlans returns all generated plans ordered by name.
+func (s *Store) ListGeneratedPlans(ctx context.Context) ([]*GeneratedPlan, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+generatedPlanColumns+` FROM generated_plans ORDER BY name ASC;`)
+	if err != nil {
+		return nil, fmt.Errorf("list generated plans: %w", err)
+	}
+	defer rows.Close()
+	var plans []*GeneratedPlan
+	for rows.Next() {
+		p, err := scanGeneratedPlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan generated plan: %w", err)
+		}
+		plans = append(plans, p)
+	}
+	return plans, rows.Err()
+}
+
+// SetGeneratedPlanStatus moves a plan to status. It returns sql.ErrNoRows
+// when there is no plan called name.
+func (s *Store) SetGeneratedPlanStatus(ctx context.Context, name, status string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE generated_plans SET status = ?, updated_at = ? WHERE name = ?;`,
+		status, time.Now().UTC(), name)
+	if err != nil {
+		return fmt.Errorf("set generated plan status: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("set generated plan status rows affected: %w", err)
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetGeneratedPlanStatusIf moves a plan to status only while its definition
+// is still definition, so an approval or rejection applies to the version
+// that was reviewed and not to one saved under the same name meanwhile. It
+// returns ErrGeneratedPlanChanged when the definition differs and
+// sql.ErrNoRows when there is no plan called name.
+func (s *Store) SetGeneratedPlanStatusIf(ctx context.Context, name, definition, status string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE generated_plans SET status = ?, updated_at = ? WHERE name = ? AND definition = ?;`,
+		status, time.Now().UTC(), name, definition)
+	if err != nil {
+		return fmt.Errorf("set generated plan status: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("set generated plan status rows affected: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+	if _, err := s.GetGeneratedPlan(ctx, name); err != nil {
+		return err
+	}
+	return ErrGeneratedPlanChanged
+}
@@ -0,0 +1,78 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestGeneratedPlans_SaveGetList(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "test.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	if _, err := store.GetGeneratedPlan(ctx, "release"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("get missing plan err = %v, want sql.ErrNoRows", err)
+	}
+	plan := &GeneratedPlan{Name: "release", Goal: "ship it", Definition: "name: release\n", CreatedBy: "planner"}
+	if err := store.SaveGeneratedPlan(ctx, plan); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err := store.GetGeneratedPlan(ctx, "release")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Status != GeneratedPlanDraft || got.Goal != "ship it" || got.CreatedBy != "planner" || got.CreatedAt.IsZero() {
+		t.Fatalf("saved plan = %+v", got)
+	}
+
+	// Saving again under the same name replaces the definition.
+	if err := store.SaveGeneratedPlan(ctx, &GeneratedPlan{Name: "release", Goal: "ship it", Definition: "name: release\nsteps: []\n"}); err != nil {
+		t.Fatalf("resave: %v", err)
+	}
+	if err := store.SaveGeneratedPlan(ctx, &GeneratedPlan{Name: "audit", Definition: "name: audit\n", Status: GeneratedPlanApproved}); err != nil {
+		t.Fatalf("save second: %v", err)
+	}
+	plans, err := store.ListGeneratedPlans(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(plans) != 2 || plans[0].Name != "audit" || plans[1].Name != "release" {
+		t.Fatalf("list = %+v", plans)
+	}
+	if plans[1].Definition != "name: release\nsteps: []\n" {
+		t.Errorf("definition after resave = %q", plans[1].Definition)
+	}
+
+	if err := store.SetGeneratedPlanStatus(ctx, "release", GeneratedPlanApproved); err != nil {
+		t.Fatalf("set status: %v", err)
+	}
+	if got, _ := store.GetGeneratedPlan(ctx, "release"); got.Status != GeneratedPlanApproved {
+		t.Errorf("status = %q, want approved", got.Status)
+	}
+	if err := store.SetGeneratedPlanStatus(ctx, "missing", GeneratedPlanApproved); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("set status of missing plan err = %v, want sql.ErrNoRows", err)
+	}
+	if err := store.SetGeneratedPlanStatus(ctx, "release", "running"); err == nil {
+		t.Error("expected error for invalid status")
+	}
+
+	// Conditional updates only apply to the definition that was reviewed.
+	if err := store.SetGeneratedPlanStatusIf(ctx, "audit", "name: audit\nsteps: []\n", GeneratedPlanRejected); !errors.Is(err, ErrGeneratedPlanChanged) {
+		t.Errorf("set status of changed plan err = %v, want ErrGeneratedPlanChanged", err)
+	}
+	if got, _ := store.GetGeneratedPlan(ctx, "audit"); got.Status != GeneratedPlanApproved {
+		t.Errorf("status after failed update = %q, want approved", got.Status)
+	}
+	if err := store.SetGeneratedPlanStatusIf(ctx, "audit", "name: audit\n", GeneratedPlanRejected); err != nil {
+		t.Fatalf("set status if unchanged: %v", err)
+	}
+	if err := store.SetGeneratedPlanStatusIf(ctx, "missing", "", GeneratedPlanApproved); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("set status if of missing plan err = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -131,21 +131,18 @@ func (s *Store) DeleteMemory(ctx context.Context, agentID, key string) error {
 	return err
 }
 
-// SearchMemories finds memories matching a query on key or value, ordered by relevance.
+// SearchMemories finds memories matching any word of query on key or value,
+// best match first. See SearchMemoriesRanked.
 func (s *Store) SearchMemories(ctx context.Context, agentID, query string) ([]AgentMemory, error) {
-	stmt := `
-		SELECT id, agent_id, key, value, source, relevance_score, access_count, created_at, updated_at, last_accessed
-		FROM agent_memories
-		WHERE agent_id = ? AND (key LIKE ? OR value LIKE ?)
-		ORDER BY relevance_score DESC, updated_at DESC
-	`
-	likeQuery := "%" + query + "%"
-	rows, err := s.db.QueryContext(ctx, stmt, agentID, likeQuery, likeQuery)
+	hits, err := s.SearchMemoriesRanked(ctx, agentID, query, 0)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	return scanMemoryRows(rows)
+	memories := make([]AgentMemory, 0, len(hits))
+	for _, h := range hits {
+		memories = append(memories, h.AgentMemory)
+	}
+	return memories, nil
 }
 
 // TouchMemory increments access_count, updates last_accessed, and boosts relevance_score slightly.
@@ -9,6 +9,7 @@ import (
 // RetentionResult holds counts of purged records from a retention run.
 type RetentionResult struct {
 	PurgedTaskEvents    int64 `json:"purged_task_events"`
+	PurgedToolCalls     int64 `json:"purged_tool_calls"`
 	PurgedAuditLogs     int64 `json:"purged_audit_logs"`
 	PurgedMessages      int64 `json:"purged_messages"`
 	PurgedAgentMessages int64 `json:"purged_agent_messages"`
@@ -28,6 +29,14 @@ func (s *Store) RunRetention(ctx context.Context, taskEventDays, auditLogDays, m
 		if n, err := res.RowsAffected(); err == nil {
 			result.PurgedTaskEvents = n
 		}
+		// Tool-call traces follow the task event retention window.
+		res, err = s.db.ExecContext(ctx, `DELETE FROM tool_calls WHERE started_at < ?;`, cutoff)
+		if err != nil {
+			return result, fmt.Errorf("purge tool_calls: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			result.PurgedToolCalls = n
+		}
 	}
 
 	if auditLogDays > 0 {
@@ -0,0 +1,383 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Full-text search uses FTS4, which go-sqlite3 always compiles in (FTS5 needs
+// the sqlite_fts5 build tag). FTS4 has no built-in ranking, so hits are scored
+// with BM25 in Go from matchinfo('pcnalx'), using the same parameters as FTS5.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// maxSearchCandidates bounds the matches read per search before ranking;
+	// the newest rows are kept.
+	maxSearchCandidates = 1000
+)
+
+// searchIndexStatements create the external-content FTS4 indexes and the
+// triggers that keep them in sync with their tables. Updates re-index a row
+// only when an indexed column changes.
+var searchIndexStatements = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS memory_fts USING fts4(content="agent_memories", key, value, tokenize=unicode61);`,
+	`CREATE TRIGGER IF NOT EXISTS memory_fts_ai AFTER INSERT ON agent_memories BEGIN
+		INSERT INTO memory_fts(docid, key, value) VALUES (new.id, new.key, new.value);
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS memory_fts_bu BEFORE UPDATE OF key, value ON agent_memories BEGIN
+		DELETE FROM memory_fts WHERE docid = old.id;
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS memory_fts_au AFTER UPDATE OF key, value ON agent_memories BEGIN
+		INSERT INTO memory_fts(docid, key, value) VALUES (new.id, new.key, new.value);
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS memory_fts_bd BEFORE DELETE ON agent_memories BEGIN
+		DELETE FROM memory_fts WHERE docid = old.id;
+		DELETE FROM memory_embeddings WHERE memory_id = old.id;
+	END;`,
+
+	`CREATE VIRTUAL TABLE IF NOT EXISTS message_fts USING fts4(content="messages", content, tokenize=unicode61);`,
+	`CREATE TRIGGER IF NOT EXISTS message_fts_ai AFTER INSERT ON messages BEGIN
+		INSERT INTO message_fts(docid, content) VALUES (new.id, new.content);
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS message_fts_bu BEFORE UPDATE OF content ON messages BEGIN
+		DELETE FROM message_fts WHERE docid = old.id;
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS message_fts_au AFTER UPDATE OF content ON messages BEGIN
+		INSERT INTO message_fts(docid, content) VALUES (new.id, new.content);
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS message_fts_bd BEFORE DELETE ON messages BEGIN
+		DELETE FROM message_fts WHERE docid = old.id;
+	END;`,
+
+	`CREATE VIRTUAL TABLE IF NOT EXISTS pin_fts USING fts4(content="agent_pins", source, content, tokenize=unicode61);`,
+	`CREATE TRIGGER IF NOT EXISTS pin_fts_ai AFTER INSERT ON agent_pins BEGIN
+		INSERT INTO pin_fts(docid, source, content) VALUES (new.id, new.source, new.content);
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS pin_fts_bu BEFORE UPDATE OF source, content ON agent_pins BEGIN
+		DELETE FROM pin_fts WHERE docid = old.id;
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS pin_fts_au AFTER UPDATE OF source, content ON agent_pins BEGIN
+		INSERT INTO pin_fts(docid, source, content) VALUES (new.id, new.source, new.content);
+	END;`,
+	`CREATE TRIGGER IF NOT EXISTS pin_fts_bd BEFORE DELETE ON agent_pins BEGIN
+		DELETE FROM pin_fts WHERE docid = old.id;
+	END;`,
+}
+
+// createSearchIndexesTx creates the search indexes and the embeddings table,
+// and rebuilds the indexes from the rows already stored.
+func (s *Store) createSearchIndexesTx(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS memory_embeddings (
+		memory_id    INTEGER PRIMARY KEY,
+		model        TEXT NOT NULL,
+		content_hash TEXT NOT NULL,
+		vector       BLOB NOT NULL
+	);`); err != nil {
+		return fmt.Errorf("create memory_embeddings: %w", err)
+	}
+	for _, stmt := range searchIndexStatements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("create search index: %w", err)
+		}
+	}
+	for _, table := range []string{"memory_fts", "message_fts", "pin_fts"} {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %[1]s(%[1]s) VALUES ('rebuild');`, table)); err != nil {
+			return fmt.Errorf("rebuild %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// MemoryHit is a memory matched by a search, with its score (higher is better).
+type MemoryHit struct {
+	AgentMemory
+	Score float64
+}
+
+// MessageHit is a conversation message matched by a search.
+type MessageHit struct {
+	ID        int64
+	SessionID string
+	AgentID   string
+	Role      string
+	Content   string
+	CreatedAt time.Time
+	Score     float64
+}
+
+// PinHit is a pinned file or text matched by a search.
+type PinHit struct {
+	AgentPin
+	Score float64
+}
+
+// SearchMemoriesRanked returns the agent's memories matching query, best
+// first by BM25 over key and value. A key match weighs twice a value match.
+// limit <= 0 returns every match.
+func (s *Store) SearchMemoriesRanked(ctx context.Context, agentID, query string, limit int) ([]MemoryHit, error) {
+	match := FTSQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.id, m.agent_id, m.key, m.value, m.source, m.relevance_score, m.access_count,
+			m.created_at, m.updated_at, m.last_accessed, matchinfo(memory_fts, 'pcnalx')
+		FROM memory_fts
+		JOIN agent_memories m ON m.id = memory_fts.docid
+		WHERE memory_fts MATCH ? AND m.agent_id = ?
+		ORDER BY m.id DESC
+		LIMIT ?
+	`, match, agentID, maxSearchCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("search memories: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []MemoryHit
+	for rows.Next() {
+		var h MemoryHit
+		var createdStr, updatedStr, accessedStr string
+		var info []byte
+		if err := rows.Scan(&h.ID, &h.AgentID, &h.Key, &h.Value, &h.Source, &h.RelevanceScore, &h.AccessCount,
+			&createdStr, &updatedStr, &accessedStr, &info); err != nil {
+			return nil, fmt.Errorf("scan memory hit: %w", err)
+		}
+		h.CreatedAt, _ = time.Parse(timeLayout, createdStr)
+		h.UpdatedAt, _ = time.Parse(timeLayout, updatedStr)
+		h.LastAccessed, _ = time.Parse(timeLayout, accessedStr)
+		h.Score = bm25(info, 2, 1)
+		hits = append(hits, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search memories: %w", err)
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].RelevanceScore > hits[j].RelevanceScore
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// SearchMessages returns the user and assistant messages of the agent, in any
+// session and including archived ones, that match query, best first.
+func (s *Store) SearchMessages(ctx context.Context, agentID, query string, limit int) ([]MessageHit, error) {
+	match := FTSQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.id, m.session_id, m.agent_id, m.role, m.content, m.created_at, matchinfo(message_fts, 'pcnalx')
+		FROM message_fts
+		JOIN messages m ON m.id = message_fts.docid
+		WHERE message_fts MATCH ? AND m.agent_id = ? AND m.role IN ('user', 'assistant')
+		ORDER BY m.id DESC
+		LIMIT ?
+	`, match, agentID, maxSearchCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("search messages: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []MessageHit
+	for rows.Next() {
+		var h MessageHit
+		var info []byte
+		if err := rows.Scan(&h.ID, &h.SessionID, &h.AgentID, &h.Role, &h.Content, &h.CreatedAt, &info); err != nil {
+			return nil, fmt.Errorf("scan message hit: %w", err)
+		}
+		h.Score = bm25(info, 1)
+		hits = append(hits, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search messages: %w", err)
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// SearchPins returns the agent's pinned files and texts matching query, best
+// first. A source (path or label) match weighs twice a content match.
+func (s *Store) SearchPins(ctx context.Context, agentID, query string, limit int) ([]PinHit, error) {
+	match := FTSQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p.id, p.agent_id, p.pin_type, p.source, p.content, p.token_count, p.shared,
+			p.last_read, p.file_mtime, p.created_at, matchinfo(pin_fts, 'pcnalx')
+		FROM pin_fts
+		JOIN agent_pins p ON p.id = pin_fts.docid
+		WHERE pin_fts MATCH ? AND p.agent_id = ?
+		ORDER BY p.id DESC
+		LIMIT ?
+	`, match, agentID, maxSearchCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("search pins: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []PinHit
+	for rows.Next() {
+		var h PinHit
+		var shared int
+		var lastReadStr, createdStr string
+		var info []byte
+		if err := rows.Scan(&h.ID, &h.AgentID, &h.PinType, &h.Source, &h.Content, &h.TokenCount, &shared,
+			&lastReadStr, &h.FileMtime, &createdStr, &info); err != nil {
+			return nil, fmt.Errorf("scan pin hit: %w", err)
+		}
+		h.Shared = shared == 1
+		h.LastRead, _ = time.Parse(timeLayout, lastReadStr)
+		h.CreatedAt, _ = time.Parse(timeLayout, createdStr)
+		h.Score = bm25(info, 2, 1)
+		hits = append(hits, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search pins: %w", err)
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// FTSQuery turns free text into a full-text query that matches any of its
+// words, each as a prefix. It returns "" when the text has no words.
+func FTSQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool, len(words))
+	var terms []string
+	for _, w := range words {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, w+"*")
+	}
+	return strings.Join(terms, " OR ")
+}
+
+// bm25 scores one row from its matchinfo('pcnalx') blob, with a weight per
+// indexed column (missing weights are 1).
+func bm25(info []byte, weights ...float64) float64 {
+	if len(info)%4 != 0 {
+		return 0
+	}
+	v := make([]float64, len(info)/4)
+	for i := range v {
+		v[i] = float64(binary.NativeEndian.Uint32(info[i*4:]))
+	}
+	if len(v) < 3 {
+		return 0
+	}
+	phrases, cols, rows := int(v[0]), int(v[1]), v[2]
+	if len(v) != 3+2*cols+3*phrases*cols {
+		return 0
+	}
+	avg, length, hits := v[3:3+cols], v[3+cols:3+2*cols], v[3+2*cols:]
+
+	var score float64
+	for p := 0; p < phrases; p++ {
+		// Rows holding the phrase in any column, approximated by the column
+		// with the most.
+		var docs float64
+		for c := 0; c < cols; c++ {
+			docs = math.Max(docs, hits[3*(p*cols+c)+2])
+		}
+		// FTS5 floors the IDF of very common terms at a small positive value.
+		idf := math.Max(math.Log((rows-docs+0.5)/(docs+0.5)), 1e-6)
+		for c := 0; c < cols; c++ {
+			tf := hits[3*(p*cols+c)]
+			if tf == 0 {
+				continue
+			}
+			w := 1.0
+			if c < len(weights) {
+				w = weights[c]
+			}
+			norm := 1 - bm25B
+			if avg[c] > 0 {
+				norm += bm25B * length[c] / avg[c]
+			}
+			score += w * idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+	}
+	return score
+}
+
+// StoredEmbedding is the embedding of a memory computed by an embeddings model.
+type StoredEmbedding struct {
+	MemoryID    int64
+	Model       string
+	ContentHash string
+	Vector      []float32
+}
+
+// ListMemoryEmbeddings returns the embeddings stored for the agent's memories
+// by model, keyed by memory ID.
+func (s *Store) ListMemoryEmbeddings(ctx context.Context, agentID, model string) (map[int64]StoredEmbedding, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.memory_id, e.model, e.content_hash, e.vector
+		FROM memory_embeddings e
+		JOIN agent_memories m ON m.id = e.memory_id
+		WHERE m.agent_id = ? AND e.model = ?
+	`, agentID, model)
+	if err != nil {
+		return nil, fmt.Errorf("list memory embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[int64]StoredEmbedding{}
+	for rows.Next() {
+		var e StoredEmbedding
+		var blob []byte
+		if err := rows.Scan(&e.MemoryID, &e.Model, &e.ContentHash, &blob); err != nil {
+			return nil, fmt.Errorf("scan memory embedding: %w", err)
+		}
+		e.Vector = make([]float32, len(blob)/4)
+		for i := range e.Vector {
+			e.Vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(blob[i*4:]))
+		}
+		out[e.MemoryID] = e
+	}
+	return out, rows.Err()
+}
+
+// SaveMemoryEmbedding stores the embedding of a memory, replacing any earlier
+// one. contentHash identifies the memory text it was computed from.
+func (s *Store) SaveMemoryEmbedding(ctx context.Context, e StoredEmbedding) error {
+	blob := make([]byte, 4*len(e.Vector))
+	for i, f := range e.Vector {
+		binary.LittleEndian.PutUint32(blob[i*4:], math.Float32bits(f))
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO memory_embeddings (memory_id, model, content_hash, vector)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(memory_id) DO UPDATE SET
+			model = excluded.model,
+			content_hash = excluded.content_hash,
+			vector = excluded.vector
+	`, e.MemoryID, e.Model, e.ContentHash, blob); err != nil {
+		return fmt.Errorf("save memory embedding: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package persistence
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func openSearchTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), "test.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestFTSQuery(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"  ?! ", ""},
+		{"Go", "go*"},
+		{"user_language", "user* OR language*"},
+		{"What's my DB password? db", "what* OR s* OR my* OR db* OR password*"},
+		{`"quoted" OR NEAR(x)`, "quoted* OR or* OR near* OR x*"},
+	}
+	for _, tt := range tests {
+		if got := FTSQuery(tt.in); got != tt.want {
+			t.Errorf("FTSQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSearchMemoriesRanked(t *testing.T) {
+	store := openSearchTestStore(t)
+	ctx := context.Background()
+	for _, m := range [][2]string{
+		{"database", "Postgres 16 on the staging host"},
+		{"deploy_notes", "Deploys go through the database migration job first"},
+		{"editor", "Prefers vim"},
+	} {
+		if err := store.SetMemory(ctx, "coder", m[0], m[1], "user"); err != nil {
+			t.Fatalf("SetMemory: %v", err)
+		}
+	}
+	if err := store.SetMemory(ctx, "writer", "database", "SQLite", "user"); err != nil {
+		t.Fatalf("SetMemory: %v", err)
+	}
+
+	hits, err := store.SearchMemoriesRanked(ctx, "coder", "which database?", 0)
+	if err != nil {
+		t.Fatalf("SearchMemoriesRanked: %v", err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("got %d hits, want 2: %+v", len(hits), hits)
+	}
+	if hits[0].Key != "database" || hits[0].Score <= hits[1].Score {
+		t.Errorf("key match should rank first: %+v", hits)
+	}
+
+	// Updates and deletes keep the index in sync.
+	if err := store.SetMemory(ctx, "coder", "editor", "Moved to a database GUI", "user"); err != nil {
+		t.Fatalf("SetMemory: %v", err)
+	}
+	if err := store.DeleteMemory(ctx, "coder", "deploy_notes"); err != nil {
+		t.Fatalf("DeleteMemory: %v", err)
+	}
+	hits, err = store.SearchMemoriesRanked(ctx, "coder", "database", 1)
+	if err != nil {
+		t.Fatalf("SearchMemoriesRanked: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Key != "database" {
+		t.Errorf("limited search = %+v, want the database memory", hits)
+	}
+	if hits, _ := store.SearchMemoriesRanked(ctx, "coder", "vim", 0); len(hits) != 0 {
+		t.Errorf("stale value still indexed: %+v", hits)
+	}
+	if hits, _ := store.SearchMemoriesRanked(ctx, "coder", "migration", 0); len(hits) != 0 {
+		t.Errorf("deleted memory still indexed: %+v", hits)
+	}
+}
+
+func TestSearchMessagesAndPins(t *testing.T) {
+	store := openSearchTestStore(t)
+	ctx := context.Background()
+	const sessionID = "e0e0e0e0-1111-2222-3333-444444444444"
+	if err := store.EnsureSession(ctx, sessionID); err != nil {
+		t.Fatalf("EnsureSession: %v", err)
+	}
+	for _, m := range []struct{ agent, role, content string }{
+		{"coder", "user", "The release branch is cut on Thursdays"},
+		{"coder", "assistant", "Noted: release branch on Thursdays."},
+		{"coder", "tool", "release tool output"},
+		{"writer", "user", "Release notes style guide"},
+	} {
+		if err := store.AddHistory(ctx, sessionID, m.agent, m.role, m.content, 0); err != nil {
+			t.Fatalf("AddHistory: %v", err)
+		}
+	}
+	msgs, err := store.SearchMessages(ctx, "coder", "release", 10)
+	if err != nil {
+		t.Fatalf("SearchMessages: %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages, want the 2 user/assistant ones: %+v", len(msgs), msgs)
+	}
+	if msgs[0].SessionID != sessionID || msgs[0].CreatedAt.IsZero() {
+		t.Errorf("unexpected message hit: %+v", msgs[0])
+	}
+
+	if err := store.AddPin(ctx, "coder", "text", "runbook", "Restart the queue worker after a release", false); err != nil {
+		t.Fatalf("AddPin: %v", err)
+	}
+	if err := store.AddPin(ctx, "coder", "text", "style", "Use tabs", false); err != nil {
+		t.Fatalf("AddPin: %v", err)
+	}
+	pins, err := store.SearchPins(ctx, "coder", "queue release", 0)
+	if err != nil {
+		t.Fatalf("SearchPins: %v", err)
+	}
+	if len(pins) != 1 || pins[0].Source != "runbook" {
+		t.Errorf("SearchPins = %+v, want the runbook pin", pins)
+	}
+	if err := store.RemovePin(ctx, "coder", "runbook"); err != nil {
+		t.Fatalf("RemovePin: %v", err)
+	}
+	if pins, _ := store.SearchPins(ctx, "coder", "queue", 0); len(pins) != 0 {
+		t.Errorf("removed pin still indexed: %+v", pins)
+	}
+}
+
+func TestMemoryEmbeddings(t *testing.T) {
+	store := openSearchTestStore(t)
+	ctx := context.Background()
+	if err := store.SetMemory(ctx, "coder", "editor", "vim", "user"); err != nil {
+		t.Fatalf("SetMemory: %v", err)
+	}
+	mem, err := store.GetMemory(ctx, "coder", "editor")
+	if err != nil {
+		t.Fatalf("GetMemory: %v", err)
+	}
+
+	want := StoredEmbedding{MemoryID: mem.ID, Model: "nomic-embed-text", ContentHash: "h1", Vector: []float32{0.5, -1, 3.25}}
+	if err := store.SaveMemoryEmbedding(ctx, want); err != nil {
+		t.Fatalf("SaveMemoryEmbedding: %v", err)
+	}
+	got, err := store.ListMemoryEmbeddings(ctx, "coder", "nomic-embed-text")
+	if err != nil {
+		t.Fatalf("ListMemoryEmbeddings: %v", err)
+	}
+	e, ok := got[mem.ID]
+	if !ok || e.ContentHash != "h1" || len(e.Vector) != 3 || e.Vector[2] != 3.25 {
+		t.Fatalf("ListMemoryEmbeddings = %+v", got)
+	}
+	if other, _ := store.ListMemoryEmbeddings(ctx, "coder", "other-model"); len(other) != 0 {
+		t.Errorf("embeddings of another model returned: %+v", other)
+	}
+
+	if err := store.DeleteMemory(ctx, "coder", "editor"); err != nil {
+		t.Fatalf("DeleteMemory: %v", err)
+	}
+	if got, _ := store.ListMemoryEmbeddings(ctx, "coder", "nomic-embed-text"); len(got) != 0 {
+		t.Errorf("embedding kept after its memory was deleted: %+v", got)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -26,23 +25,7 @@ func (s *Store) EnsureSession(ctx context.Context, sessionID string) error {
 }
 
 func (s *Store) AddHistory(ctx context.Context, sessionID, agentID, role, content string, tokens int) error {
-	role = strings.ToLower(strings.TrimSpace(role))
-	switch role {
-	case "system", "user", "assistant", "tool":
-	default:
-		return fmt.Errorf("invalid role %q", role)
-	}
-	if agentID == "" {
-		agentID = "default"
-	}
-	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO messages (session_id, agent_id, role, content, tokens, created_at)
-		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP);
-	`, sessionID, agentID, role, content, tokens)
-	if err != nil {
-		return fmt.Errorf("insert message: %w", err)
-	}
-	return nil
+	return s.AddHistoryWithAttachments(ctx, sessionID, agentID, role, content, tokens, nil)
 }
 
 // ClearSessionMessages deletes all messages for a session+agent pair.
@@ -100,6 +83,9 @@ func (s *Store) ListHistory(ctx context.Context, sessionID, agentID string, limi
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("message rows: %w", err)
 	}
+	if err := s.loadAttachments(ctx, sessionID, out); err != nil {
+		return nil, err
+	}
 	return out, nil
 }
 
@@ -73,8 +73,56 @@ const (
 	schemaVersionV14  = 14
 	schemaChecksumV14 = "gc-v14-2026-02-16-loop-checkpoints"
 
-	schemaVersionLatest  = schemaVersionV14
-	schemaChecksumLatest = schemaChecksumV14
+	// schema v15: adds tool_calls for per-task tool traces and the tool-call
+	// approval columns on approvals.
+	schemaVersionV15  = 15
+	schemaChecksumV15 = "gc-v15-2026-10-16-tool-trace"
+
+	// schema v16: adds message_attachments for images and documents sent
+	// with chat messages.
+	schemaVersionV16  = 16
+	schemaChecksumV16 = "gc-v16-2026-10-16-message-attachments"
+
+	// schema v17: allows the 'skipped' status on plan_execution_steps for
+	// conditional steps and skip_dependents failure policies.
+	schemaVersionV17  = 17
+	schemaChecksumV17 = "gc-v17-2026-10-16-plan-step-skipped"
+
+	// schema v18: adds plan_executions.inputs_json for parameterized plans.
+	schemaVersionV18  = 18
+	schemaChecksumV18 = "gc-v18-2026-10-16-plan-inputs"
+
+	// schema v19: adds generated_plans for plans written by the create_plan tool.
+	schemaVersionV19  = 19
+	schemaChecksumV19 = "gc-v19-2026-10-16-generated-plans"
+
+	// schema v20: adds plan_executions.paused so a paused plan stays paused
+	// across restarts.
+	schemaVersionV20  = 20
+	schemaChecksumV20 = "gc-v20-2026-10-16-plan-execution-control"
+
+	// schema v21: adds cached and reasoning token counts to tasks and
+	// task_metrics for provider-reported usage.
+	schemaVersionV21  = 21
+	schemaChecksumV21 = "gc-v21-2026-10-16-token-usage"
+
+	// schema v22: adds api_key_id to tasks and task_metrics so spend budgets
+	// can be enforced per gateway API key.
+	schemaVersionV22  = 22
+	schemaChecksumV22 = "gc-v22-2026-10-16-spend-budgets"
+
+	// schema v23: adds full-text indexes over memories, messages and pins,
+	// and stored embeddings for semantic memory ranking.
+	schemaVersionV23  = 23
+	schemaChecksumV23 = "gc-v23-2026-10-16-memory-search"
+
+	// schema v24: adds webhook_deliveries, the durable queue of outbound
+	// webhook POSTs and their dead letters.
+	schemaVersionV24  = 24
+	schemaChecksumV24 = "gc-v24-2026-10-16-webhook-deliveries"
+
+	schemaVersionLatest  = schemaVersionV24
+	schemaChecksumLatest = schemaChecksumV24
 
 	defaultLeaseDuration = 30 * time.Second
 
@@ -155,6 +203,7 @@ type Task struct {
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 	AgentID        string     `json:"agent_id"`
+	APIKeyID       string     `json:"api_key_id,omitempty"` // gateway key the task was submitted with
 }
 
 // AgentRecord represents a row in the agents table.
@@ -212,6 +261,9 @@ type HistoryItem struct {
 	Text      string    `json:"text"` // GC-SPEC-ACP-009: OpenClaw backward-compat alias for content.
 	Tokens    int       `json:"tokens"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// Attachments holds the images and documents sent with the message.
+	Attachments []shared.Attachment `json:"attachments,omitempty"`
 }
 
 type TaskEvent struct {
@@ -407,6 +459,16 @@ func (s *Store) initSchema(ctx context.Context) error {
 		{schemaVersionV12, schemaChecksumV12},
 		{schemaVersionV13, schemaChecksumV13},
 		{schemaVersionV14, schemaChecksumV14},
+		{schemaVersionV15, schemaChecksumV15},
+		{schemaVersionV16, schemaChecksumV16},
+		{schemaVersionV17, schemaChecksumV17},
+		{schemaVersionV18, schemaChecksumV18},
+		{schemaVersionV19, schemaChecksumV19},
+		{schemaVersionV20, schemaChecksumV20},
+		{schemaVersionV21, schemaChecksumV21},
+		{schemaVersionV22, schemaChecksumV22},
+		{schemaVersionV23, schemaChecksumV23},
+		{schemaVersionV24, schemaChecksumV24},
 	}
 	matched := false
 	for _, vc := range versionChecksums {
@@ -523,7 +585,13 @@ func (s *Store) initSchema(ctx context.Context) error {
 			status TEXT NOT NULL DEFAULT 'PENDING',
 			expires_at DATETIME,
 			resolved_at DATETIME,
-			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			agent_id TEXT NOT NULL DEFAULT '',
+			session_id TEXT NOT NULL DEFAULT '',
+			args_hash TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL DEFAULT '',
+			consumed_at DATETIME,
+			run_id TEXT NOT NULL DEFAULT ''
 		);`,
 		`CREATE TABLE IF NOT EXISTS audit_log (
 			audit_id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -664,6 +732,54 @@ func (s *Store) initSchema(ctx context.Context) error {
 			created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at   DATETIME DEFAULT CURRENT_TIMESTAMP
 		);`,
+		// v15: Tool-call trace, one row per tool invocation.
+		`CREATE TABLE IF NOT EXISTS tool_calls (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id     TEXT NOT NULL DEFAULT '',
+			run_id      TEXT NOT NULL DEFAULT '',
+			session_id  TEXT NOT NULL DEFAULT '',
+			agent_id    TEXT NOT NULL DEFAULT '',
+			tool        TEXT NOT NULL,
+			args        TEXT NOT NULL DEFAULT '',
+			output      TEXT NOT NULL DEFAULT '',
+			error       TEXT NOT NULL DEFAULT '',
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			started_at  DATETIME NOT NULL
+		);`,
+		// v16: Attachments of chat messages; removed with their message.
+		`CREATE TABLE IF NOT EXISTS message_attachments (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+			session_id TEXT NOT NULL,
+			mime_type  TEXT NOT NULL,
+			name       TEXT NOT NULL DEFAULT '',
+			data       BLOB NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		// v19: Plans written by agents, kept by name so they can be re-run.
+		`CREATE TABLE IF NOT EXISTS generated_plans (
+			name       TEXT PRIMARY KEY,
+			goal       TEXT NOT NULL DEFAULT '',
+			definition TEXT NOT NULL,
+			status     TEXT NOT NULL CHECK(status IN ('draft', 'approved', 'rejected')) DEFAULT 'draft',
+			created_by TEXT NOT NULL DEFAULT '',
+			session_id TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);`,
+		// v24: Outbound webhook deliveries waiting to be sent, and dead letters.
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id              TEXT PRIMARY KEY,
+			endpoint        TEXT NOT NULL,
+			topic           TEXT NOT NULL,
+			payload         TEXT NOT NULL,
+			status          TEXT NOT NULL CHECK(status IN ('pending', 'dead')) DEFAULT 'pending',
+			attempts        INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at DATETIME NOT NULL,
+			last_error      TEXT NOT NULL DEFAULT '',
+			created_at      DATETIME NOT NULL,
+			updated_at      DATETIME NOT NULL
+		);`,
 		// v9: Observability tables for metrics and activity logging.
 		`CREATE TABLE IF NOT EXISTS task_metrics (
 			task_id       TEXT PRIMARY KEY,
@@ -678,8 +794,11 @@ func (s *Store) initSchema(ctx context.Context) error {
 			prompt_tokens     INTEGER NOT NULL DEFAULT 0,
 			completion_tokens INTEGER NOT NULL DEFAULT 0,
 			total_tokens      INTEGER NOT NULL DEFAULT 0,
+			cached_tokens     INTEGER NOT NULL DEFAULT 0,
+			reasoning_tokens  INTEGER NOT NULL DEFAULT 0,
 			estimated_cost_usd REAL NOT NULL DEFAULT 0.0,
 			error_message TEXT,
+			api_key_id    TEXT NOT NULL DEFAULT '',
 			FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
 		);`,
 		`CREATE TABLE IF NOT EXISTS agent_activity_log (
@@ -815,6 +934,8 @@ func (s *Store) initSchema(ctx context.Context) error {
 			completed_steps INTEGER NOT NULL DEFAULT 0,
 			current_wave INTEGER NOT NULL DEFAULT 0,
 			total_cost_usd REAL NOT NULL DEFAULT 0.0,
+			inputs_json TEXT NOT NULL DEFAULT '',
+			paused INTEGER NOT NULL DEFAULT 0,
 			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			completed_at TIMESTAMP,
 			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
@@ -828,7 +949,7 @@ func (s *Store) initSchema(ctx context.Context) error {
 			agent_id TEXT NOT NULL,
 			prompt TEXT NOT NULL,
 			task_id TEXT REFERENCES tasks(id) ON DELETE SET NULL,
-			status TEXT NOT NULL CHECK(status IN ('pending', 'running', 'succeeded', 'failed')) DEFAULT 'pending',
+			status TEXT NOT NULL CHECK(status IN ('pending', 'running', 'succeeded', 'failed', 'skipped')) DEFAULT 'pending',
 			result TEXT,
 			error TEXT,
 			cost_usd REAL NOT NULL DEFAULT 0.0,
@@ -842,6 +963,42 @@ func (s *Store) initSchema(ctx context.Context) error {
 			return fmt.Errorf("exec v12 migration: %w", err)
 		}
 	}
+	// v17: plan steps created before v17 lack the 'skipped' status.
+	if err := s.rebuildPlanStepsStatusTx(ctx, tx); err != nil {
+		return err
+	}
+	// v18: executions created before v18 have no inputs column.
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE plan_executions ADD COLUMN inputs_json TEXT NOT NULL DEFAULT '';`); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+		return fmt.Errorf("add plan_executions.inputs_json: %w", err)
+	}
+	// v20: executions created before v20 have no paused column.
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE plan_executions ADD COLUMN paused INTEGER NOT NULL DEFAULT 0;`); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+		return fmt.Errorf("add plan_executions.paused: %w", err)
+	}
+	// v21: cached and reasoning token counts.
+	for _, stmt := range []string{
+		`ALTER TABLE tasks ADD COLUMN cached_tokens INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE tasks ADD COLUMN reasoning_tokens INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE task_metrics ADD COLUMN cached_tokens INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE task_metrics ADD COLUMN reasoning_tokens INTEGER NOT NULL DEFAULT 0;`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("add token usage columns: %w", err)
+		}
+	}
+	// v22: the API key a task was submitted with, for spend budgets.
+	for _, stmt := range []string{
+		`ALTER TABLE tasks ADD COLUMN api_key_id TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE task_metrics ADD COLUMN api_key_id TEXT NOT NULL DEFAULT '';`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("add api_key_id columns: %w", err)
+		}
+	}
+	// v23: full-text search indexes, rebuilt from their tables on upgrade.
+	if err := s.createSearchIndexesTx(ctx, tx); err != nil {
+		return err
+	}
 
 	// Phase 3: Indexes (may reference columns added by backfills).
 	indexStatements := []string{
@@ -853,6 +1010,7 @@ func (s *Store) initSchema(ctx context.Context) error {
 		`CREATE INDEX IF NOT EXISTS idx_task_events_session_event_id ON task_events(session_id, event_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_task_events_task_event_id ON task_events(task_id, event_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_approvals_status ON approvals(status, expires_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_approvals_task ON approvals(task_id, capability, args_hash);`,
 		`CREATE INDEX IF NOT EXISTS idx_data_redactions_entity ON data_redactions(entity_type, entity_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_schedules_next_run ON schedules(enabled, next_run_at);`,
 		`CREATE INDEX IF NOT EXISTS idx_tasks_parent ON tasks(parent_task_id);`,
@@ -861,6 +1019,8 @@ func (s *Store) initSchema(ctx context.Context) error {
 		`CREATE INDEX IF NOT EXISTS idx_agent_messages_from ON agent_messages(from_agent);`,
 		// v9: Indexes for observability tables
 		`CREATE INDEX IF NOT EXISTS idx_metrics_agent_time ON task_metrics(agent_id, completed_at DESC);`,
+		`CREATE INDEX IF NOT EXISTS idx_metrics_session_time ON task_metrics(session_id, completed_at DESC);`,
+		`CREATE INDEX IF NOT EXISTS idx_metrics_api_key_time ON task_metrics(api_key_id, completed_at DESC);`,
 		`CREATE INDEX IF NOT EXISTS idx_activity_agent_time ON agent_activity_log(agent_id, created_at DESC);`,
 		// v10: Indexes for team plans
 		`CREATE INDEX IF NOT EXISTS idx_team_plans_session ON team_plans(session_id, created_at DESC);`,
@@ -880,6 +1040,14 @@ func (s *Store) initSchema(ctx context.Context) error {
 		// v14: Indexes for loop checkpoints
 		`CREATE INDEX IF NOT EXISTS idx_loop_checkpoints_task ON loop_checkpoints(task_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_loop_checkpoints_status ON loop_checkpoints(status);`,
+		// v15: Tool-call trace lookups by task and by session.
+		`CREATE INDEX IF NOT EXISTS idx_tool_calls_task ON tool_calls(task_id, id);`,
+		`CREATE INDEX IF NOT EXISTS idx_tool_calls_session ON tool_calls(session_id, id);`,
+		// v16: Attachments are loaded per message and purged per session.
+		`CREATE INDEX IF NOT EXISTS idx_message_attachments_message ON message_attachments(message_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_message_attachments_session ON message_attachments(session_id);`,
+		// v24: Due deliveries are polled by status and time.
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);`,
 	}
 
 	for _, stmt := range indexStatements {
@@ -981,6 +1149,13 @@ func (s *Store) applyBackfillsTx(ctx context.Context, tx *sql.Tx) error {
 		{stmt: `ALTER TABLE agents ADD COLUMN preferred_search TEXT NOT NULL DEFAULT '';`, desc: "agents.preferred_search"},
 		// v8: per-agent history isolation.
 		{stmt: `ALTER TABLE messages ADD COLUMN agent_id TEXT NOT NULL DEFAULT 'default';`, desc: "messages.agent_id"},
+		// Tool-call approvals: who asked, for which session, and how it was decided.
+		{stmt: `ALTER TABLE approvals ADD COLUMN agent_id TEXT NOT NULL DEFAULT '';`, desc: "approvals.agent_id"},
+		{stmt: `ALTER TABLE approvals ADD COLUMN session_id TEXT NOT NULL DEFAULT '';`, desc: "approvals.session_id"},
+		{stmt: `ALTER TABLE approvals ADD COLUMN args_hash TEXT NOT NULL DEFAULT '';`, desc: "approvals.args_hash"},
+		{stmt: `ALTER TABLE approvals ADD COLUMN reason TEXT NOT NULL DEFAULT '';`, desc: "approvals.reason"},
+		{stmt: `ALTER TABLE approvals ADD COLUMN consumed_at DATETIME;`, desc: "approvals.consumed_at"},
+		{stmt: `ALTER TABLE approvals ADD COLUMN run_id TEXT NOT NULL DEFAULT '';`, desc: "approvals.run_id"},
 	}
 	for _, a := range alterStatements {
 		if _, err := tx.ExecContext(ctx, a.stmt); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
@@ -1080,6 +1255,55 @@ func (s *Store) rebuildLegacyTasksTx(ctx context.Context, tx *sql.Tx) error {
 	return nil
 }
 
+// rebuildPlanStepsStatusTx recreates plan_execution_steps when its status
+// CHECK predates the 'skipped' state. No table references plan_execution_steps,
+// so a plain copy-and-rename is safe.
+func (s *Store) rebuildPlanStepsStatusTx(ctx context.Context, tx *sql.Tx) error {
+	var stepsSQL string
+	if err := tx.QueryRowContext(ctx, `SELECT sql FROM sqlite_master WHERE type='table' AND name='plan_execution_steps';`).Scan(&stepsSQL); err != nil {
+		return nil // table doesn't exist yet
+	}
+	if strings.Contains(stepsSQL, "'skipped'") {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE _plan_execution_steps_v17 (
+			id TEXT PRIMARY KEY,
+			execution_id TEXT NOT NULL REFERENCES plan_executions(id) ON DELETE CASCADE,
+			step_id TEXT NOT NULL,
+			step_index INTEGER NOT NULL,
+			wave_number INTEGER NOT NULL,
+			agent_id TEXT NOT NULL,
+			prompt TEXT NOT NULL,
+			task_id TEXT REFERENCES tasks(id) ON DELETE SET NULL,
+			status TEXT NOT NULL CHECK(status IN ('pending', 'running', 'succeeded', 'failed', 'skipped')) DEFAULT 'pending',
+			result TEXT,
+			error TEXT,
+			cost_usd REAL NOT NULL DEFAULT 0.0,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			completed_at TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`); err != nil {
+		return fmt.Errorf("create _plan_execution_steps_v17: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO _plan_execution_steps_v17
+		SELECT id, execution_id, step_id, step_index, wave_number, agent_id, prompt, task_id,
+		       status, result, error, cost_usd, created_at, completed_at, updated_at
+		FROM plan_execution_steps;
+	`); err != nil {
+		return fmt.Errorf("copy plan_execution_steps: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DROP TABLE plan_execution_steps;`); err != nil {
+		return fmt.Errorf("drop old plan_execution_steps: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE _plan_execution_steps_v17 RENAME TO plan_execution_steps;`); err != nil {
+		return fmt.Errorf("rename _plan_execution_steps_v17: %w", err)
+	}
+	return nil
+}
+
 // repairBrokenFKsTx detects and fixes task_events tables with broken FK
 // references (e.g. pointing to "_tasks_legacy" instead of "tasks").
 func (s *Store) repairBrokenFKsTx(ctx context.Context, tx *sql.Tx) error {
@@ -1168,6 +1392,7 @@ func scanTask(scanFn func(dest ...any) error, task *Task) error {
 		&task.CreatedAt,
 		&task.UpdatedAt,
 		&task.AgentID,
+		&task.APIKeyID,
 	); err != nil {
 		return err
 	}
@@ -1327,6 +1552,8 @@ type PurgeResult struct {
 	MessagesDeleted    int64 `json:"messages_deleted"`
 	TaskPayloadsTombed int64 `json:"task_payloads_tombstoned"`
 	TaskEventsTombed   int64 `json:"task_events_tombstoned"`
+	ToolCallsDeleted   int64 `json:"tool_calls_deleted"`
+	AttachmentsDeleted int64 `json:"attachments_deleted"`
 	RedactionsRecorded int   `json:"redactions_recorded"`
 }
 
@@ -1361,7 +1588,15 @@ func (s *Store) PurgeSessionPII(ctx context.Context, sessionID, policyVersion, a
 	}
 	rows.Close()
 
-	res, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE session_id = ?;`, sessionID)
+	res, err := tx.ExecContext(ctx, `DELETE FROM message_attachments WHERE session_id = ?;`, sessionID)
+	if err != nil {
+		return result, fmt.Errorf("delete message attachments: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		result.AttachmentsDeleted = n
+	}
+
+	res, err = tx.ExecContext(ctx, `DELETE FROM messages WHERE session_id = ?;`, sessionID)
 	if err != nil {
 		return result, fmt.Errorf("delete messages: %w", err)
 	}
@@ -1433,6 +1668,15 @@ func (s *Store) PurgeSessionPII(ctx context.Context, sessionID, policyVersion, a
 		result.TaskEventsTombed = n
 	}
 
+	// 4. Delete the tool-call trace; arguments and outputs may carry PII.
+	res, err = tx.ExecContext(ctx, `DELETE FROM tool_calls WHERE session_id = ?;`, sessionID)
+	if err != nil {
+		return result, fmt.Errorf("delete tool_calls: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		result.ToolCallsDeleted = n
+	}
+
 	if err := tx.Commit(); err != nil {
 		return result, fmt.Errorf("commit purge tx: %w", err)
 	}
@@ -1582,6 +1826,7 @@ type IncidentBundle struct {
 	TaskID     string       `json:"task_id"`
 	Task       *Task        `json:"task"`
 	Events     []TaskEvent  `json:"events"`
+	ToolCalls  []ToolCall   `json:"tool_calls"`
 	AuditTrail []AuditEntry `json:"audit_trail"`
 	ConfigHash string       `json:"config_hash"`
 	ExportedAt time.Time    `json:"exported_at"`
@@ -1634,6 +1879,11 @@ func (s *Store) ExportIncident(ctx context.Context, taskID, configHash string) (
 		return nil, fmt.Errorf("export incident: rows: %w", err)
 	}
 
+	toolCalls, err := s.ListToolCalls(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("export incident: %w", err)
+	}
+
 	// Collect unique trace IDs from the events to query related audit entries.
 	traceIDs := make(map[string]struct{})
 	for _, ev := range events {
@@ -1670,6 +1920,7 @@ func (s *Store) ExportIncident(ctx context.Context, taskID, configHash string) (
 		TaskID:     taskID,
 		Task:       task,
 		Events:     events,
+		ToolCalls:  toolCalls,
 		AuditTrail: auditTrail,
 		ConfigHash: configHash,
 		ExportedAt: time.Now().UTC(),
@@ -1877,6 +2128,8 @@ type PlanExecution struct {
 	CompletedSteps int
 	CurrentWave    int
 	TotalCostUSD   float64
+	Inputs         string // JSON object of bound plan inputs; "" when none
+	Paused         bool   // no new steps start while set
 	CreatedAt      time.Time
 	CompletedAt    *time.Time
 	UpdatedAt      *time.Time
@@ -2003,6 +2256,85 @@ func (s *Store) InitializePlanSteps(ctx context.Context, execID string, steps []
 	return nil
 }
 
+// SetPlanExecutionInputs stores the JSON inputs a plan execution was started
+// with, so a resumed execution substitutes the same values.
+func (s *Store) SetPlanExecutionInputs(ctx context.Context, execID, inputsJSON string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE plan_executions
+		SET inputs_json = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		inputsJSON, execID,
+	)
+	if err != nil {
+		return fmt.Errorf("update inputs: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("plan_execution %s not found", execID)
+	}
+	return nil
+}
+
+// SetPlanExecutionPaused records whether a running plan execution is paused.
+func (s *Store) SetPlanExecutionPaused(ctx context.Context, execID string, paused bool) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE plan_executions
+		SET paused = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		paused, execID,
+	)
+	if err != nil {
+		return fmt.Errorf("update paused: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("plan_execution %s not found", execID)
+	}
+	return nil
+}
+
+// ReopenPlanExecution puts a finished plan execution back in the running
+// state with the given steps reset to pending, so resuming it runs those
+// steps again and keeps the results of the others.
+func (s *Store) ReopenPlanExecution(ctx context.Context, execID string, stepIDs []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, stepID := range stepIDs {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE plan_execution_steps
+			SET status = 'pending', task_id = NULL, result = NULL, error = NULL, cost_usd = 0,
+			    completed_at = NULL, updated_at = CURRENT_TIMESTAMP
+			WHERE execution_id = ? AND step_id = ?`,
+			execID, stepID,
+		); err != nil {
+			return fmt.Errorf("reset step %s: %w", stepID, err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE plan_executions
+		SET status = 'running', paused = 0, completed_at = NULL, updated_at = CURRENT_TIMESTAMP,
+		    completed_steps = (
+		        SELECT COUNT(*) FROM plan_execution_steps
+		        WHERE execution_id = ? AND status IN ('succeeded', 'failed', 'skipped'))
+		WHERE id = ?`,
+		execID, execID,
+	)
+	if err != nil {
+		return fmt.Errorf("reopen plan_execution: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("plan_execution %s not found", execID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
 // UpdatePlanWave updates the current wave number after wave completion.
 // GC-SPEC-PDR-v4-Phase-2: Wave tracking for resumption.
 func (s *Store) UpdatePlanWave(ctx context.Context, execID string, waveNum int) error {
@@ -2023,7 +2355,7 @@ func (s *Store) UpdatePlanWave(ctx context.Context, execID string, waveNum int)
 func (s *Store) GetPlanExecution(ctx context.Context, execID string) (*PlanExecution, error) {
 	row := s.db.QueryRowContext(ctx, `
 		SELECT id, plan_name, session_id, status, total_steps, completed_steps, current_wave,
-		       total_cost_usd, created_at, completed_at, updated_at
+		       total_cost_usd, inputs_json, paused, created_at, completed_at, updated_at
 		FROM plan_executions
 		WHERE id = ?`,
 		execID,
@@ -2033,7 +2365,7 @@ func (s *Store) GetPlanExecution(ctx context.Context, execID string) (*PlanExecu
 	err := row.Scan(
 		&exec.ID, &exec.PlanName, &exec.SessionID, &exec.Status,
 		&exec.TotalSteps, &exec.CompletedSteps, &exec.CurrentWave,
-		&exec.TotalCostUSD, &exec.CreatedAt, &exec.CompletedAt, &exec.UpdatedAt,
+		&exec.TotalCostUSD, &exec.Inputs, &exec.Paused, &exec.CreatedAt, &exec.CompletedAt, &exec.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("scan plan_execution: %w", err)
@@ -2157,6 +2489,21 @@ func (s *Store) RecordStepComplete(ctx context.Context, execID, stepID, status,
 	return nil
 }
 
+// MarkStepRunning records the task currently executing a step, so a resumed
+// execution can wait for that task instead of starting the step again.
+func (s *Store) MarkStepRunning(ctx context.Context, execID, stepID, taskID string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE plan_execution_steps
+		SET status = 'running', task_id = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE execution_id = ? AND step_id = ?`,
+		taskID, execID, stepID,
+	)
+	if err != nil {
+		return fmt.Errorf("mark step running: %w", err)
+	}
+	return nil
+}
+
 // Bus returns the event bus for publishing.
 func (s *Store) Bus() *bus.Bus {
 	return s.bus
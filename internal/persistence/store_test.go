@@ -63,7 +63,7 @@ func TestStore_OpenConfiguresWALAndSchema(t *testing.T) {
 		t.Fatalf("expected foreign_keys=1, got %d", foreignKeys)
 	}
 
-	requiredTables := []string{"schema_migrations", "sessions", "messages", "tasks", "kv_store", "skill_registry", "policy_versions", "approvals", "audit_log", "agents"}
+	requiredTables := []string{"schema_migrations", "sessions", "messages", "tasks", "kv_store", "skill_registry", "policy_versions", "approvals", "audit_log", "agents", "tool_calls", "message_attachments"}
 	for _, table := range requiredTables {
 		var got string
 		if err := db.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name = ?", table).Scan(&got); err != nil {
@@ -81,8 +81,8 @@ func TestStore_MigrationLedgerHasChecksum(t *testing.T) {
 	if err := db.QueryRow(`SELECT version, checksum FROM schema_migrations ORDER BY version DESC LIMIT 1;`).Scan(&version, &checksum); err != nil {
 		t.Fatalf("query schema_migrations: %v", err)
 	}
-	if version != 14 {
-		t.Fatalf("expected version 14, got %d", version)
+	if version != 24 {
+		t.Fatalf("expected version 24, got %d", version)
 	}
 	if checksum == "" {
 		t.Fatalf("expected non-empty checksum")
@@ -123,7 +123,7 @@ func TestStore_OpenRejectsFutureSchemaVersion(t *testing.T) {
 
 func TestStore_OpenRejectsChecksumMismatch(t *testing.T) {
 	store, dbPath := openTestStore(t)
-	if _, err := store.DB().Exec(`UPDATE schema_migrations SET checksum='tampered' WHERE version=14;`); err != nil {
+	if _, err := store.DB().Exec(`UPDATE schema_migrations SET checksum='tampered' WHERE version=24;`); err != nil {
 		t.Fatalf("tamper checksum: %v", err)
 	}
 	if err := store.Close(); err != nil {
@@ -1623,6 +1623,31 @@ func TestKVSetGet(t *testing.T) {
 	}
 }
 
+func TestKVDeletePrefix(t *testing.T) {
+	store, _ := openTestStore(t)
+	ctx := context.Background()
+	for _, key := range []string{"hook:old", "hook:new", "hook_x:old", "other:old"} {
+		if err := store.KVSet(ctx, key, "v"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.DB().ExecContext(ctx, `UPDATE kv_store SET updated_at = datetime('now', '-1 hour') WHERE key LIKE '%old'`); err != nil {
+		t.Fatal(err)
+	}
+	n, err := store.KVDeletePrefix(ctx, "hook:", 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("deleted %d entries, want 1", n)
+	}
+	for key, want := range map[string]string{"hook:old": "", "hook:new": "v", "hook_x:old": "v", "other:old": "v"} {
+		if got, _ := store.KVGet(ctx, key); got != want {
+			t.Errorf("KVGet(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
 func TestAUD010_IncrementSkillFaultAtomicReturning(t *testing.T) {
 	// AUD-010: Verify that IncrementSkillFault uses atomic RETURNING
 	// to determine quarantine state without a TOCTOU race.
@@ -3354,3 +3379,184 @@ func TestStore_GetPlanSteps(t *testing.T) {
 		t.Fatalf("wrong wave numbers: %v", retrieved)
 	}
 }
+
+func TestMigration_V16PlanStepsGainSkippedStatus(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "goclaw.db")
+	store, err := persistence.Open(dbPath, nil)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	ctx := context.Background()
+	sessionID := "a1b2c3d4-0000-4000-8000-000000000017"
+	if err := store.EnsureSession(ctx, sessionID); err != nil {
+		t.Fatalf("ensure session: %v", err)
+	}
+	if err := store.CreatePlanExecution(ctx, "exec-v16", "p", sessionID, 2); err != nil {
+		t.Fatalf("create execution: %v", err)
+	}
+
+	// Recreate the v16 table and ledger.
+	for _, stmt := range []string{
+		`DROP TABLE plan_execution_steps;`,
+		`CREATE TABLE plan_execution_steps (
+			id TEXT PRIMARY KEY,
+			execution_id TEXT NOT NULL REFERENCES plan_executions(id) ON DELETE CASCADE,
+			step_id TEXT NOT NULL,
+			step_index INTEGER NOT NULL,
+			wave_number INTEGER NOT NULL,
+			agent_id TEXT NOT NULL,
+			prompt TEXT NOT NULL,
+			task_id TEXT REFERENCES tasks(id) ON DELETE SET NULL,
+			status TEXT NOT NULL CHECK(status IN ('pending', 'running', 'succeeded', 'failed')) DEFAULT 'pending',
+			result TEXT,
+			error TEXT,
+			cost_usd REAL NOT NULL DEFAULT 0.0,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			completed_at TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`DELETE FROM schema_migrations WHERE version >= 17;`,
+		`INSERT OR REPLACE INTO schema_migrations (version, checksum) VALUES (16, 'gc-v16-2026-10-16-message-attachments');`,
+	} {
+		if _, err := store.DB().Exec(stmt); err != nil {
+			t.Fatalf("seed v16 schema: %v", err)
+		}
+	}
+	if err := store.InitializePlanSteps(ctx, "exec-v16", []persistence.PlanExecutionStep{
+		{StepID: "a", AgentID: "x", Prompt: "1"},
+		{StepID: "b", AgentID: "x", Prompt: "2", WaveNumber: 1},
+	}); err != nil {
+		t.Fatalf("initialize steps: %v", err)
+	}
+	if err := store.RecordStepComplete(ctx, "exec-v16", "a", "succeeded", "out", "", 0); err != nil {
+		t.Fatalf("record step: %v", err)
+	}
+	store.Close()
+
+	store, err = persistence.Open(dbPath, nil)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+	if err := store.RecordStepComplete(ctx, "exec-v16", "b", "skipped", "", "condition not met", 0); err != nil {
+		t.Fatalf("record skipped step after upgrade: %v", err)
+	}
+	steps, err := store.GetPlanSteps(ctx, "exec-v16")
+	if err != nil {
+		t.Fatalf("get steps: %v", err)
+	}
+	if len(steps) != 2 || steps[0].Status != "succeeded" || steps[0].Result != "out" || steps[1].Status != "skipped" {
+		t.Fatalf("steps after upgrade = %+v", steps)
+	}
+}
+
+func TestMigration_V17PlanExecutionsGainInputs(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "goclaw.db")
+	store, err := persistence.Open(dbPath, nil)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	ctx := context.Background()
+	sessionID := "a1b2c3d4-0000-4000-8000-000000000018"
+	if err := store.EnsureSession(ctx, sessionID); err != nil {
+		t.Fatalf("ensure session: %v", err)
+	}
+	if err := store.CreatePlanExecution(ctx, "exec-v17", "p", sessionID, 1); err != nil {
+		t.Fatalf("create execution: %v", err)
+	}
+	for _, stmt := range []string{
+		`ALTER TABLE plan_executions DROP COLUMN inputs_json;`,
+		`DELETE FROM schema_migrations WHERE version >= 18;`,
+	} {
+		if _, err := store.DB().Exec(stmt); err != nil {
+			t.Fatalf("seed v17 schema: %v", err)
+		}
+	}
+	store.Close()
+
+	store, err = persistence.Open(dbPath, nil)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+	exec, err := store.GetPlanExecution(ctx, "exec-v17")
+	if err != nil {
+		t.Fatalf("get execution after upgrade: %v", err)
+	}
+	if exec.Inputs != "" {
+		t.Fatalf("inputs of old execution = %q, want empty", exec.Inputs)
+	}
+	if err := store.SetPlanExecutionInputs(ctx, "exec-v17", `{"topic":"go"}`); err != nil {
+		t.Fatalf("set inputs: %v", err)
+	}
+	if exec, err = store.GetPlanExecution(ctx, "exec-v17"); err != nil || exec.Inputs != `{"topic":"go"}` {
+		t.Fatalf("inputs = %q, %v", exec.Inputs, err)
+	}
+	if err := store.SetPlanExecutionInputs(ctx, "missing", `{}`); err == nil {
+		t.Fatal("expected error for unknown execution")
+	}
+}
+
+func TestStore_PauseAndReopenPlanExecution(t *testing.T) {
+	store, _ := openTestStore(t)
+	ctx := context.Background()
+	sessionID := "a1b2c3d4-0000-4000-8000-000000000020"
+	if err := store.EnsureSession(ctx, sessionID); err != nil {
+		t.Fatalf("ensure session: %v", err)
+	}
+	if err := store.CreatePlanExecution(ctx, "exec-ctl", "p", sessionID, 3); err != nil {
+		t.Fatalf("create execution: %v", err)
+	}
+	if err := store.InitializePlanSteps(ctx, "exec-ctl", []persistence.PlanExecutionStep{
+		{StepID: "a", StepIndex: 0, WaveNumber: 0, AgentID: "x", Prompt: "1"},
+		{StepID: "b", StepIndex: 0, WaveNumber: 1, AgentID: "x", Prompt: "2"},
+		{StepID: "c", StepIndex: 0, WaveNumber: 2, AgentID: "x", Prompt: "3"},
+	}); err != nil {
+		t.Fatalf("initialize steps: %v", err)
+	}
+
+	if err := store.SetPlanExecutionPaused(ctx, "exec-ctl", true); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if exec, err := store.GetPlanExecution(ctx, "exec-ctl"); err != nil || !exec.Paused {
+		t.Fatalf("paused = %v, %v; want true", exec.Paused, err)
+	}
+	if err := store.SetPlanExecutionPaused(ctx, "missing", true); err == nil {
+		t.Fatal("expected error for unknown execution")
+	}
+
+	for _, step := range []struct{ id, status string }{{"a", "succeeded"}, {"b", "failed"}, {"c", "skipped"}} {
+		if err := store.RecordStepComplete(ctx, "exec-ctl", step.id, step.status, "out-"+step.id, "", 0.5); err != nil {
+			t.Fatalf("record step %s: %v", step.id, err)
+		}
+	}
+	if err := store.CompletePlanExecution(ctx, "exec-ctl", "failed", 0); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	if err := store.ReopenPlanExecution(ctx, "exec-ctl", []string{"b", "c"}); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	exec, err := store.GetPlanExecution(ctx, "exec-ctl")
+	if err != nil {
+		t.Fatalf("get execution: %v", err)
+	}
+	if exec.Status != "running" || exec.Paused || exec.CompletedAt != nil || exec.CompletedSteps != 1 {
+		t.Fatalf("reopened execution = %+v", exec)
+	}
+	steps, err := store.GetPlanSteps(ctx, "exec-ctl")
+	if err != nil {
+		t.Fatalf("get steps: %v", err)
+	}
+	if steps[0].Status != "succeeded" || steps[0].Result != "out-a" {
+		t.Errorf("kept step = %+v", steps[0])
+	}
+	for _, step := range steps[1:] {
+		if step.Status != "pending" || step.Result != "" || step.CostUSD != 0 {
+			t.Errorf("reset step = %+v", step)
+		}
+	}
+	if err := store.ReopenPlanExecution(ctx, "missing", nil); err == nil {
+		t.Fatal("expected error for unknown execution")
+	}
+}
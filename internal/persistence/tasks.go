@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/shared"
 	"github.com/google/uuid"
 )
 
@@ -111,10 +112,10 @@ func (s *Store) createTask(ctx context.Context, agentID, sessionID, payload stri
 		}
 		if _, err := tx.ExecContext(ctx, `
 			INSERT INTO tasks (
-				id, session_id, type, status, attempt, max_attempts, available_at, agent_id, payload, created_at, updated_at
+				id, session_id, type, status, attempt, max_attempts, available_at, agent_id, payload, api_key_id, created_at, updated_at
 			)
-			VALUES (?, ?, 'chat', ?, 0, ?, CURRENT_TIMESTAMP, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
-		`, taskID, sessionID, TaskStatusQueued, defaultMaxAttempts, agent, payload); err != nil {
+			VALUES (?, ?, 'chat', ?, 0, ?, CURRENT_TIMESTAMP, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+		`, taskID, sessionID, TaskStatusQueued, defaultMaxAttempts, agent, payload, shared.APIKeyID(ctx)); err != nil {
 			return fmt.Errorf("create task: %w", err)
 		}
 		if err := s.appendTaskEventTx(ctx, tx, taskID, sessionID, "", TaskStatusQueued, "task.enqueued", `{"reason":"create_task"}`); err != nil {
@@ -150,7 +151,7 @@ func (s *Store) claimNextPendingTask(ctx context.Context, agentID string) (*Task
 				SELECT id, session_id, type, status, attempt, max_attempts, available_at,
 					COALESCE(last_error_code, ''), poison_count, payload,
 					COALESCE(result, ''), COALESCE(error, ''), COALESCE(lease_owner, ''),
-					lease_expires_at, created_at, updated_at, COALESCE(agent_id, 'default')
+					lease_expires_at, created_at, updated_at, COALESCE(agent_id, 'default'), api_key_id
 				FROM tasks
 				WHERE status = ? AND available_at <= CURRENT_TIMESTAMP
 				ORDER BY priority DESC, created_at ASC, id ASC
@@ -161,7 +162,7 @@ func (s *Store) claimNextPendingTask(ctx context.Context, agentID string) (*Task
 				SELECT id, session_id, type, status, attempt, max_attempts, available_at,
 					COALESCE(last_error_code, ''), poison_count, payload,
 					COALESCE(result, ''), COALESCE(error, ''), COALESCE(lease_owner, ''),
-					lease_expires_at, created_at, updated_at, COALESCE(agent_id, 'default')
+					lease_expires_at, created_at, updated_at, COALESCE(agent_id, 'default'), api_key_id
 				FROM tasks
 				WHERE status = ? AND agent_id = ? AND available_at <= CURRENT_TIMESTAMP
 				ORDER BY priority DESC, created_at ASC, id ASC
@@ -993,7 +994,7 @@ func (s *Store) GetTask(ctx context.Context, taskID string) (*Task, error) {
 			lease_expires_at,
 			created_at,
 			updated_at,
-			COALESCE(agent_id, 'default')
+			COALESCE(agent_id, 'default'), api_key_id
 		FROM tasks
 		WHERE id = ?;
 	`, taskID).Scan, &task)
@@ -1136,6 +1137,22 @@ func (s *Store) KVGet(ctx context.Context, key string) (string, error) {
 	return val, nil
 }
 
+// KVDeletePrefix removes the kv_store entries whose keys start with prefix
+// and that were last set more than olderThan ago.
+func (s *Store) KVDeletePrefix(ctx context.Context, prefix string, olderThan time.Duration) (int, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM kv_store
+		WHERE substr(key, 1, length(?)) = ?
+		AND updated_at < datetime('now', ?)`,
+		prefix, prefix, fmt.Sprintf("-%d seconds", int(olderThan.Seconds())),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("kv delete prefix: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
 func (s *Store) RecordToolTask(ctx context.Context, sessionID, toolName, input, output string, callErr error) (string, error) {
 	payloadJSON, err := json.Marshal(ToolTaskPayload{
 		Kind:  "TOOL",
@@ -1191,7 +1208,7 @@ func (s *Store) ListTasksBySession(ctx context.Context, sessionID string) ([]Tas
 			lease_expires_at,
 			created_at,
 			updated_at,
-			COALESCE(agent_id, 'default')
+			COALESCE(agent_id, 'default'), api_key_id
 		FROM tasks
 		WHERE session_id = ?
 		ORDER BY created_at ASC, id ASC;
@@ -1223,7 +1240,7 @@ func (s *Store) GetSubtasks(ctx context.Context, parentTaskID string) ([]Task, e
 		SELECT id, session_id, type, status, attempt, max_attempts, available_at,
 		       last_error_code, poison_count, payload, COALESCE(result,''), COALESCE(error,''),
 		       COALESCE(lease_owner,''), lease_expires_at, created_at, updated_at,
-		       COALESCE(agent_id, 'default')
+		       COALESCE(agent_id, 'default'), api_key_id
 		FROM tasks WHERE parent_task_id = ?
 		ORDER BY created_at ASC;
 	`, parentTaskID)
@@ -1283,14 +1300,14 @@ func (s *Store) ListTasksPaginated(ctx context.Context, statusFilter string, lim
 		query = `SELECT id, session_id, type, status, attempt, max_attempts, available_at,
 		         last_error_code, poison_count, payload, COALESCE(result,''), COALESCE(error,''),
 		         COALESCE(lease_owner,''), lease_expires_at, created_at, updated_at,
-		         COALESCE(agent_id, 'default')
+		         COALESCE(agent_id, 'default'), api_key_id
 		         FROM tasks WHERE status = ? ORDER BY created_at DESC LIMIT ? OFFSET ?;`
 		args = []any{statusFilter, limit, offset}
 	} else {
 		query = `SELECT id, session_id, type, status, attempt, max_attempts, available_at,
 		         last_error_code, poison_count, payload, COALESCE(result,''), COALESCE(error,''),
 		         COALESCE(lease_owner,''), lease_expires_at, created_at, updated_at,
-		         COALESCE(agent_id, 'default')
+		         COALESCE(agent_id, 'default'), api_key_id
 		         FROM tasks ORDER BY created_at DESC LIMIT ? OFFSET ?;`
 		args = []any{limit, offset}
 	}
@@ -1312,13 +1329,31 @@ func (s *Store) ListTasksPaginated(ctx context.Context, statusFilter string, lim
 	return out, totalCount, rows.Err()
 }
 
+// TaskUsage is the token usage and cost of a task. CachedTokens is the part
+// of PromptTokens served from the provider's cache; ReasoningTokens the part
+// of CompletionTokens spent on reasoning.
+type TaskUsage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CachedTokens     int     `json:"cached_tokens"`
+	ReasoningTokens  int     `json:"reasoning_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// TotalTokens returns the prompt and completion tokens.
+func (u TaskUsage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
 // UpdateTaskTokens records token usage for a task.
-func (s *Store) UpdateTaskTokens(ctx context.Context, taskID string, promptTokens, completionTokens int) error {
+func (s *Store) UpdateTaskTokens(ctx context.Context, taskID string, u TaskUsage) error {
 	_, err := s.db.ExecContext(ctx, `
 		UPDATE tasks
-		SET prompt_tokens = ?, completion_tokens = ?, total_tokens = ?, estimated_cost_usd = 0.0
+		SET prompt_tokens = ?, completion_tokens = ?, total_tokens = ?,
+		    cached_tokens = ?, reasoning_tokens = ?, estimated_cost_usd = ?
 		WHERE id = ?`,
-		promptTokens, completionTokens, promptTokens+completionTokens, taskID,
+		u.PromptTokens, u.CompletionTokens, u.TotalTokens(),
+		u.CachedTokens, u.ReasoningTokens, u.CostUSD, taskID,
 	)
 	if err != nil {
 		return fmt.Errorf("update task tokens %s: %w", taskID, err)
@@ -1328,14 +1363,50 @@ func (s *Store) UpdateTaskTokens(ctx context.Context, taskID string, promptToken
 	if s.bus != nil {
 		s.bus.Publish(bus.TopicTaskTokens, bus.TaskTokensEvent{
 			TaskID:           taskID,
-			PromptTokens:     promptTokens,
-			CompletionTokens: completionTokens,
+			PromptTokens:     u.PromptTokens,
+			CompletionTokens: u.CompletionTokens,
+			CachedTokens:     u.CachedTokens,
+			ReasoningTokens:  u.ReasoningTokens,
+			CostUSD:          u.CostUSD,
 		})
 	}
 
 	return nil
 }
 
+// GetTaskUsage returns the recorded token usage of a task. Tasks without
+// recorded usage, including unknown ones, return zero usage.
+func (s *Store) GetTaskUsage(ctx context.Context, taskID string) (TaskUsage, error) {
+	var u TaskUsage
+	err := s.db.QueryRowContext(ctx, `
+		SELECT prompt_tokens, completion_tokens, cached_tokens, reasoning_tokens, estimated_cost_usd
+		FROM tasks WHERE id = ?`, taskID).
+		Scan(&u.PromptTokens, &u.CompletionTokens, &u.CachedTokens, &u.ReasoningTokens, &u.CostUSD)
+	if err == sql.ErrNoRows {
+		return TaskUsage{}, nil
+	}
+	if err != nil {
+		return TaskUsage{}, fmt.Errorf("get task usage %s: %w", taskID, err)
+	}
+	return u, nil
+}
+
+// UsageTotals sums the token usage of all tasks, or of one session's tasks
+// when sessionID is non-empty.
+func (s *Store) UsageTotals(ctx context.Context, sessionID string) (TaskUsage, error) {
+	var u TaskUsage
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0),
+		       COALESCE(SUM(cached_tokens), 0), COALESCE(SUM(reasoning_tokens), 0),
+		       COALESCE(SUM(estimated_cost_usd), 0)
+		FROM tasks WHERE ? = '' OR session_id = ?`, sessionID, sessionID).
+		Scan(&u.PromptTokens, &u.CompletionTokens, &u.CachedTokens, &u.ReasoningTokens, &u.CostUSD)
+	if err != nil {
+		return TaskUsage{}, fmt.Errorf("usage totals: %w", err)
+	}
+	return u, nil
+}
+
 // RecordTaskMetrics snapshots final metrics for a completed task.
 func (s *Store) RecordTaskMetrics(ctx context.Context, taskID string) error {
 	// Fetch task metrics before recording for event publishing.
@@ -1353,11 +1424,13 @@ func (s *Store) RecordTaskMetrics(ctx context.Context, taskID string) error {
 	_, err = s.db.ExecContext(ctx, `
 		INSERT INTO task_metrics (
 			task_id, agent_id, session_id, parent_task_id, created_at, completed_at,
-			status, prompt_tokens, completion_tokens, total_tokens, estimated_cost_usd
+			status, prompt_tokens, completion_tokens, total_tokens,
+			cached_tokens, reasoning_tokens, estimated_cost_usd, api_key_id
 		)
 		SELECT
 			id, agent_id, session_id, parent_task_id, created_at, CURRENT_TIMESTAMP,
-			status, prompt_tokens, completion_tokens, total_tokens, estimated_cost_usd
+			status, prompt_tokens, completion_tokens, total_tokens,
+			cached_tokens, reasoning_tokens, estimated_cost_usd, api_key_id
 		FROM tasks WHERE id = ?
 		ON CONFLICT(task_id) DO NOTHING`,
 		taskID,
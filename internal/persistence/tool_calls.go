@@ -0,0 +1,77 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ToolCall is one tool invocation made while an agent answered a message.
+// Args and Output are stored redacted and truncated by the caller.
+type ToolCall struct {
+	ID         int64     `json:"id"`
+	TaskID     string    `json:"task_id,omitempty"` // empty for direct (TUI) turns
+	RunID      string    `json:"run_id,omitempty"`  // distinguishes re-runs of the same task
+	SessionID  string    `json:"session_id"`
+	AgentID    string    `json:"agent_id"`
+	Tool       string    `json:"tool"`
+	Args       string    `json:"args"`
+	Output     string    `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+const toolCallColumns = `id, task_id, run_id, session_id, agent_id, tool, args, output, error, duration_ms, started_at`
+
+// RecordToolCall appends a tool call to the trace and sets its ID.
+func (s *Store) RecordToolCall(ctx context.Context, tc *ToolCall) error {
+	if tc.StartedAt.IsZero() {
+		tc.StartedAt = time.Now().UTC()
+	}
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO tool_calls (task_id, run_id, session_id, agent_id, tool, args, output, error, duration_ms, started_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		tc.TaskID, tc.RunID, tc.SessionID, tc.AgentID, tc.Tool, tc.Args, tc.Output, tc.Error, tc.DurationMS, tc.StartedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("record tool call: %w", err)
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		tc.ID = id
+	}
+	return nil
+}
+
+// ListToolCalls returns the tool calls made by a task, oldest first.
+func (s *Store) ListToolCalls(ctx context.Context, taskID string) ([]ToolCall, error) {
+	return s.queryToolCalls(ctx, `SELECT `+toolCallColumns+` FROM tool_calls WHERE task_id = ? ORDER BY id;`, taskID)
+}
+
+// ListLastRunToolCalls returns the tool calls of the most recent run in a
+// session that used any tools, oldest first.
+func (s *Store) ListLastRunToolCalls(ctx context.Context, sessionID string) ([]ToolCall, error) {
+	return s.queryToolCalls(ctx, `
+		SELECT `+toolCallColumns+` FROM tool_calls
+		WHERE session_id = ?1 AND run_id = (
+			SELECT run_id FROM tool_calls WHERE session_id = ?1 ORDER BY id DESC LIMIT 1
+		)
+		ORDER BY id;`, sessionID)
+}
+
+func (s *Store) queryToolCalls(ctx context.Context, query string, args ...any) ([]ToolCall, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list tool calls: %w", err)
+	}
+	defer rows.Close()
+	var calls []ToolCall
+	for rows.Next() {
+		var tc ToolCall
+		if err := rows.Scan(&tc.ID, &tc.TaskID, &tc.RunID, &tc.SessionID, &tc.AgentID, &tc.Tool,
+			&tc.Args, &tc.Output, &tc.Error, &tc.DurationMS, &tc.StartedAt); err != nil {
+			return nil, fmt.Errorf("scan tool call: %w", err)
+		}
+		calls = append(calls, tc)
+	}
+	return calls, rows.Err()
+}
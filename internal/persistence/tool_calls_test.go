@@ -0,0 +1,65 @@
+package persistence
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestToolCalls_RecordAndList(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "goclaw.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	calls := []ToolCall{
+		{TaskID: "t1", RunID: "r1", SessionID: "s1", AgentID: "coder", Tool: "read_file", Args: `{"path":"a.go"}`, Output: `"package a"`, DurationMS: 3},
+		{TaskID: "t1", RunID: "r1", SessionID: "s1", AgentID: "coder", Tool: "exec", Args: `{"command":"false"}`, Error: "exit 1", DurationMS: 12},
+		{RunID: "r2", SessionID: "s1", AgentID: "coder", Tool: "web_search", Args: `{"query":"go"}`},
+		{TaskID: "t2", RunID: "r3", SessionID: "s2", AgentID: "coder", Tool: "exec"},
+	}
+	for i := range calls {
+		if err := store.RecordToolCall(ctx, &calls[i]); err != nil {
+			t.Fatalf("RecordToolCall: %v", err)
+		}
+		if calls[i].ID == 0 {
+			t.Fatalf("call %d: ID not set", i)
+		}
+	}
+
+	got, err := store.ListToolCalls(ctx, "t1")
+	if err != nil {
+		t.Fatalf("ListToolCalls: %v", err)
+	}
+	if len(got) != 2 || got[0].Tool != "read_file" || got[1].Tool != "exec" {
+		t.Fatalf("task t1 calls = %+v, want read_file then exec", got)
+	}
+	if got[1].Error != "exit 1" || got[1].DurationMS != 12 || got[0].Output != `"package a"` || got[0].StartedAt.IsZero() {
+		t.Errorf("fields not round-tripped: %+v", got)
+	}
+
+	last, err := store.ListLastRunToolCalls(ctx, "s1")
+	if err != nil {
+		t.Fatalf("ListLastRunToolCalls: %v", err)
+	}
+	if len(last) != 1 || last[0].RunID != "r2" {
+		t.Errorf("last run calls = %+v, want the single r2 call", last)
+	}
+
+	if none, err := store.ListToolCalls(ctx, "missing"); err != nil || len(none) != 0 {
+		t.Errorf("unknown task: calls = %+v, err %v", none, err)
+	}
+
+	res, err := store.PurgeSessionPII(ctx, "s1", "pol-v1", "test")
+	if err != nil {
+		t.Fatalf("PurgeSessionPII: %v", err)
+	}
+	if res.ToolCallsDeleted != 3 {
+		t.Errorf("ToolCallsDeleted = %d, want 3", res.ToolCallsDeleted)
+	}
+	if left, _ := store.ListToolCalls(ctx, "t2"); len(left) != 1 {
+		t.Errorf("other session's calls = %d, want 1", len(left))
+	}
+}
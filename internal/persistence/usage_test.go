@@ -0,0 +1,71 @@
+package persistence
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestTaskUsage_RecordAndTotal(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "goclaw.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	const s1, s2 = "a1b2c3d4-0000-4000-8000-0000000000a1", "a1b2c3d4-0000-4000-8000-0000000000a2"
+	usages := map[string]TaskUsage{
+		s1: {PromptTokens: 1200, CompletionTokens: 300, CachedTokens: 1000, ReasoningTokens: 100, CostUSD: 0.0042},
+		s2: {PromptTokens: 50, CompletionTokens: 10, CostUSD: 0.0001},
+	}
+	taskIDs := map[string]string{}
+	for sessionID, u := range usages {
+		if err := store.EnsureSession(ctx, sessionID); err != nil {
+			t.Fatalf("ensure session: %v", err)
+		}
+		taskID, err := store.CreateTask(ctx, sessionID, `{"content":"hi"}`)
+		if err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+		if err := store.UpdateTaskTokens(ctx, taskID, u); err != nil {
+			t.Fatalf("UpdateTaskTokens: %v", err)
+		}
+		taskIDs[sessionID] = taskID
+	}
+
+	got, err := store.GetTaskUsage(ctx, taskIDs[s1])
+	if err != nil {
+		t.Fatalf("GetTaskUsage: %v", err)
+	}
+	if got != usages[s1] {
+		t.Errorf("usage = %+v, want %+v", got, usages[s1])
+	}
+	if got, _ := store.GetTaskUsage(ctx, "missing"); got != (TaskUsage{}) {
+		t.Errorf("usage of unknown task = %+v, want zero", got)
+	}
+
+	session, err := store.UsageTotals(ctx, s2)
+	if err != nil {
+		t.Fatalf("UsageTotals: %v", err)
+	}
+	if session != usages[s2] {
+		t.Errorf("session totals = %+v, want %+v", session, usages[s2])
+	}
+	all, _ := store.UsageTotals(ctx, "")
+	if all.PromptTokens != 1250 || all.CompletionTokens != 310 || all.CachedTokens != 1000 || all.CostUSD < 0.00429 || all.CostUSD > 0.00431 {
+		t.Errorf("totals = %+v", all)
+	}
+
+	// Task metrics snapshot the usage.
+	if err := store.RecordTaskMetrics(ctx, taskIDs[s1]); err != nil {
+		t.Fatalf("RecordTaskMetrics: %v", err)
+	}
+	var cached, reasoning int
+	if err := store.DB().QueryRowContext(ctx, `SELECT cached_tokens, reasoning_tokens FROM task_metrics WHERE task_id = ?`, taskIDs[s1]).Scan(&cached, &reasoning); err != nil {
+		t.Fatalf("read task_metrics: %v", err)
+	}
+	if cached != 1000 || reasoning != 100 {
+		t.Errorf("task_metrics cached/reasoning = %d/%d, want 1000/100", cached, reasoning)
+	}
+}
@@ -0,0 +1,154 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook delivery statuses stored in webhook_deliveries.status. Delivered
+// webhooks are deleted.
+const (
+	WebhookDeliveryPending = "pending"
+	WebhookDeliveryDead    = "dead"
+)
+
+// WebhookDelivery is an event queued for POSTing to an outbound webhook
+// endpoint. Payload is the event as JSON.
+type WebhookDelivery struct {
+	ID            string    `json:"id"`
+	Endpoint      string    `json:"endpoint"`
+	Topic         string    `json:"topic"`
+	Payload       string    `json:"payload"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+const webhookDeliveryColumns = `id, endpoint, topic, payload, status, attempts, next_attempt_at, last_error, created_at, updated_at`
+
+func scanWebhookDelivery(row interface{ Scan(...any) error }) (*WebhookDelivery, error) {
+	d := &WebhookDelivery{}
+	if err := row.Scan(&d.ID, &d.Endpoint, &d.Topic, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastError, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// EnqueueWebhookDelivery queues an event for immediate delivery to endpoint
+// and returns the delivery ID.
+func (s *Store) EnqueueWebhookDelivery(ctx context.Context, endpoint, topic, payload string) (string, error) {
+	id := uuid.NewString()
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (id, endpoint, topic, payload, status, next_attempt_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);`,
+		id, endpoint, topic, payload, WebhookDeliveryPending, now, now, now)
+	if err != nil {
+		return "", fmt.Errorf("enqueue webhook delivery: %w", err)
+	}
+	return id, nil
+}
+
+// DueWebhookDeliveries returns up to limit pending deliveries whose next
+// attempt is due at now, oldest first.
+func (s *Store) DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]*WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at ASC, created_at ASC
+		LIMIT ?;`,
+		WebhookDeliveryPending, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("due webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	var out []*WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// CompleteWebhookDelivery removes a delivered webhook from the queue.
+func (s *Store) CompleteWebhookDelivery(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE id = ?;`, id); err != nil {
+		return fmt.Errorf("complete webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// FailWebhookDelivery records a failed attempt. The delivery is retried at
+// next, or moved to the dead letters when dead is set.
+func (s *Store) FailWebhookDelivery(ctx context.Context, id, errMsg string, next time.Time, dead bool) error {
+	status := WebhookDeliveryPending
+	if dead {
+		status = WebhookDeliveryDead
+	}
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, next_attempt_at = ?, last_error = ?, updated_at = ?
+		WHERE id = ?;`,
+		status, next.UTC(), errMsg, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("fail webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns up to limit deliveries with status, newest
+// first. Limit <= 0 means 100.
+func (s *Store) ListWebhookDeliveries(ctx context.Context, status string, limit int) ([]*WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		WHERE status = ?
+		ORDER BY updated_at DESC
+		LIMIT ?;`,
+		status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	var out []*WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// RetryWebhookDelivery moves a dead letter back to the queue for immediate
+// delivery with a fresh attempt count. It returns sql.ErrNoRows when id is
+// not a dead letter.
+func (s *Store) RetryWebhookDelivery(ctx context.Context, id string) error {
+	now := time.Now().UTC()
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = 0, next_attempt_at = ?, updated_at = ?
+		WHERE id = ? AND status = ?;`,
+		WebhookDeliveryPending, now, now, id, WebhookDeliveryDead)
+	if err != nil {
+		return fmt.Errorf("retry webhook delivery: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
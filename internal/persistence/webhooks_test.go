@@ -0,0 +1,70 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWebhookDeliveries_RetryAndDeadLetter(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "test.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	id, err := store.EnqueueWebhookDelivery(ctx, "ops", "agent.alert", `{"Message":"disk full"}`)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	other, err := store.EnqueueWebhookDelivery(ctx, "ci", "task.completed", `{"task_id":"t1"}`)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	due, err := store.DueWebhookDeliveries(ctx, time.Now(), 10)
+	if err != nil || len(due) != 2 || due[0].ID != id {
+		t.Fatalf("due = %v, %v; want both deliveries, oldest first", due, err)
+	}
+
+	// A failed attempt waits for its retry time.
+	if err := store.FailWebhookDelivery(ctx, id, "status 502", time.Now().Add(time.Minute), false); err != nil {
+		t.Fatalf("fail: %v", err)
+	}
+	if err := store.CompleteWebhookDelivery(ctx, other); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if due, _ := store.DueWebhookDeliveries(ctx, time.Now(), 10); len(due) != 0 {
+		t.Fatalf("due before retry time = %v", due)
+	}
+	due, _ = store.DueWebhookDeliveries(ctx, time.Now().Add(2*time.Minute), 10)
+	if len(due) != 1 || due[0].Attempts != 1 || due[0].LastError != "status 502" {
+		t.Fatalf("due after retry time = %+v", due)
+	}
+
+	// A dead letter is listed and can be sent again.
+	if err := store.RetryWebhookDelivery(ctx, id); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("retry of a pending delivery err = %v, want sql.ErrNoRows", err)
+	}
+	if err := store.FailWebhookDelivery(ctx, id, "status 502", time.Now(), true); err != nil {
+		t.Fatalf("fail: %v", err)
+	}
+	if due, _ := store.DueWebhookDeliveries(ctx, time.Now().Add(time.Hour), 10); len(due) != 0 {
+		t.Fatalf("dead letter still due: %v", due)
+	}
+	dead, err := store.ListWebhookDeliveries(ctx, WebhookDeliveryDead, 0)
+	if err != nil || len(dead) != 1 || dead[0].ID != id || dead[0].Attempts != 2 {
+		t.Fatalf("dead letters = %+v, %v", dead, err)
+	}
+	if err := store.RetryWebhookDelivery(ctx, id); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	due, _ = store.DueWebhookDeliveries(ctx, time.Now(), 10)
+	if len(due) != 1 || due[0].Attempts != 0 || due[0].Status != WebhookDeliveryPending {
+		t.Fatalf("retried delivery = %+v", due)
+	}
+}
@@ -0,0 +1,127 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultApprovalTimeout bounds how long a gated tool call waits for a human
+// decision when approval_timeout_seconds is unset.
+const DefaultApprovalTimeout = 5 * time.Minute
+
+// Tool names beyond the argument-rule set that require_approval can gate.
+const (
+	ToolDelegateTask      = "delegate_task"
+	ToolDelegateTaskAsync = "delegate_task_async"
+)
+
+var knownApprovalTools = map[string]struct{}{
+	ToolExec:              {},
+	ToolWriteFile:         {},
+	ToolEditFile:          {},
+	ToolDelegateTask:      {},
+	ToolDelegateTaskAsync: {},
+}
+
+// ApprovalChecker is implemented by checkers that can require a human
+// decision before a tool runs. Policy, *LivePolicy and *ProfileView implement
+// it; consumers type-assert a Checker to it.
+//
+// Example policy.yaml:
+//
+//	require_approval:
+//	  - exec
+//	  - delegate_task
+//	  - "mcp:github/create_issue"
+//	  - "mcp:filesystem/*"
+//	approval_timeout_seconds: 120
+type ApprovalChecker interface {
+	RequiresApproval(tool string) bool
+	ApprovalTimeout() time.Duration
+}
+
+// MCPToolName is the name require_approval uses for an MCP tool.
+func MCPToolName(serverName, toolName string) string {
+	return "mcp:" + serverName + "/" + toolName
+}
+
+// RequiresApproval reports whether calls to tool must be approved by a human.
+// MCP tools are named "mcp:<server>/<tool>"; "mcp:<server>/*" gates every
+// tool of a server.
+func (p Policy) RequiresApproval(tool string) bool {
+	tool = strings.ToLower(strings.TrimSpace(tool))
+	if tool == "" {
+		return false
+	}
+	for _, entry := range p.RequireApproval {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == tool {
+			return true
+		}
+		if server, ok := strings.CutSuffix(entry, "/*"); ok && strings.HasPrefix(tool, server+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ApprovalTimeout returns the configured wait for a decision.
+func (p Policy) ApprovalTimeout() time.Duration {
+	if p.ApprovalTimeoutSeconds > 0 {
+		return time.Duration(p.ApprovalTimeoutSeconds) * time.Second
+	}
+	return DefaultApprovalTimeout
+}
+
+func (lp *LivePolicy) RequiresApproval(tool string) bool {
+	lp.mu.RLock()
+	defer lp.mu.RUnlock()
+	return lp.data.RequiresApproval(tool)
+}
+
+func (lp *LivePolicy) ApprovalTimeout() time.Duration {
+	lp.mu.RLock()
+	defer lp.mu.RUnlock()
+	return lp.data.ApprovalTimeout()
+}
+
+func (v *ProfileView) RequiresApproval(tool string) bool {
+	v.lp.mu.RLock()
+	defer v.lp.mu.RUnlock()
+	return v.current().RequiresApproval(tool)
+}
+
+// ApprovalTimeout is file-level; profiles do not override it.
+func (v *ProfileView) ApprovalTimeout() time.Duration {
+	v.lp.mu.RLock()
+	defer v.lp.mu.RUnlock()
+	return v.lp.data.ApprovalTimeout()
+}
+
+func validateRequireApproval(entries []string) error {
+	for i, entry := range entries {
+		name := strings.ToLower(strings.TrimSpace(entry))
+		if rest, ok := strings.CutPrefix(name, "mcp:"); ok {
+			server, tool, found := strings.Cut(rest, "/")
+			if !found || server == "" || tool == "" {
+				return fmt.Errorf("require_approval[%d]: mcp entries must be mcp:<server>/<tool>, got %q", i, entry)
+			}
+			continue
+		}
+		if _, ok := knownApprovalTools[name]; !ok {
+			return fmt.Errorf("require_approval[%d]: unknown tool %q", i, entry)
+		}
+	}
+	return nil
+}
+
+// approvalFingerprint is the stable text hashed into PolicyVersion.
+func approvalFingerprint(entries []string, timeoutSeconds int) string {
+	normalized := make([]string, 0, len(entries))
+	for _, e := range entries {
+		normalized = append(normalized, strings.ToLower(strings.TrimSpace(e)))
+	}
+	return strings.Join(normalized, ",") + "/" + strconv.Itoa(timeoutSeconds)
+}
@@ -0,0 +1,95 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequiresApproval(t *testing.T) {
+	p := Policy{RequireApproval: []string{"exec", " Write_File ", "mcp:github/create_issue", "mcp:fs/*"}}
+	tests := []struct {
+		tool string
+		want bool
+	}{
+		{"exec", true},
+		{"write_file", true},
+		{"edit_file", false},
+		{MCPToolName("github", "create_issue"), true},
+		{MCPToolName("github", "search"), false},
+		{MCPToolName("fs", "delete"), true},
+		{MCPToolName("fsx", "delete"), false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := p.RequiresApproval(tt.tool); got != tt.want {
+			t.Errorf("RequiresApproval(%q) = %v, want %v", tt.tool, got, tt.want)
+		}
+	}
+}
+
+func TestApprovalTimeout(t *testing.T) {
+	if got := (Policy{}).ApprovalTimeout(); got != DefaultApprovalTimeout {
+		t.Errorf("default timeout = %v, want %v", got, DefaultApprovalTimeout)
+	}
+	if got := (Policy{ApprovalTimeoutSeconds: 30}).ApprovalTimeout(); got != 30*time.Second {
+		t.Errorf("timeout = %v, want 30s", got)
+	}
+}
+
+func TestValidate_RequireApproval(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       Policy
+		wantErr bool
+	}{
+		{"known tools", Policy{RequireApproval: []string{"exec", "delegate_task", "mcp:github/*"}}, false},
+		{"unknown tool", Policy{RequireApproval: []string{"read_file"}}, true},
+		{"mcp without tool", Policy{RequireApproval: []string{"mcp:github"}}, true},
+		{"negative timeout", Policy{ApprovalTimeoutSeconds: -1}, true},
+		{"bad profile entry", Policy{Profiles: map[string]Profile{"p": {RequireApproval: []string{"nope"}}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.p.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProfileView_RequiresApproval(t *testing.T) {
+	lp := NewLivePolicy(Policy{
+		RequireApproval:        []string{"delegate_task"},
+		ApprovalTimeoutSeconds: 45,
+		Profiles: map[string]Profile{
+			"base":  {RequireApproval: []string{"exec"}},
+			"coder": {Inherits: "base", RequireApproval: []string{"write_file"}},
+		},
+	}, "")
+	view, err := lp.ForProfile("coder")
+	if err != nil {
+		t.Fatalf("ForProfile: %v", err)
+	}
+	ac := view.(ApprovalChecker)
+	if !ac.RequiresApproval("exec") || !ac.RequiresApproval("write_file") {
+		t.Error("profile should gate its own and inherited tools")
+	}
+	if ac.RequiresApproval("delegate_task") {
+		t.Error("profiles must not inherit top-level require_approval")
+	}
+	if ac.ApprovalTimeout() != 45*time.Second {
+		t.Errorf("profile timeout = %v, want the file-level 45s", ac.ApprovalTimeout())
+	}
+	if !lp.RequiresApproval("delegate_task") {
+		t.Error("live policy should gate delegate_task")
+	}
+}
+
+func TestPolicyVersion_RequireApproval(t *testing.T) {
+	base := Policy{AllowCapabilities: []string{"tools.exec"}}
+	gated := base
+	gated.RequireApproval = []string{"exec"}
+	if policyVersionFor(base) == policyVersionFor(gated) {
+		t.Error("require_approval should change the policy version")
+	}
+}
@@ -22,6 +22,12 @@ type Checker interface {
 	PolicyVersion() string
 }
 
+// MCPChecker is implemented by checkers that evaluate MCP tool rules (v0.4).
+// Policy and *LivePolicy implement it; consumers type-assert a Checker to it.
+type MCPChecker interface {
+	AllowMCPTool(agentID, serverName, toolName string) bool
+}
+
 // MCPRule is a single MCP policy rule (v0.4).
 type MCPRule struct {
 	Agent  string   `yaml:"agent"`  // agent_id or "*"
@@ -37,11 +43,21 @@ type MCPPolicyConfig struct {
 
 // Policy is the serializable policy data.
 type Policy struct {
-	AllowDomains      []string        `yaml:"allow_domains"`
-	AllowPaths        []string        `yaml:"allow_paths"`
-	AllowCapabilities []string        `yaml:"allow_capabilities"`
-	AllowLoopback     bool            `yaml:"allow_loopback"`
-	MCP               MCPPolicyConfig `yaml:"mcp,omitempty"` // v0.4
+	AllowDomains      []string           `yaml:"allow_domains"`
+	AllowPaths        []string           `yaml:"allow_paths"`
+	AllowCapabilities []string           `yaml:"allow_capabilities"`
+	AllowLoopback     bool               `yaml:"allow_loopback"`
+	MCP               MCPPolicyConfig    `yaml:"mcp,omitempty"`        // v0.4
+	ToolRules         []ToolRule         `yaml:"tool_rules,omitempty"` // argument-level constraints
+	Profiles          map[string]Profile `yaml:"profiles,omitempty"`   // per-agent named policies
+
+	RequireApproval        []string `yaml:"require_approval,omitempty"`         // tools gated on human approval
+	ApprovalTimeoutSeconds int      `yaml:"approval_timeout_seconds,omitempty"` // 0 = DefaultApprovalTimeout
+
+	// mcpOpen is set by Load for a policy file without an mcp section, which
+	// allows every MCP tool as it did before MCP rules existed. Resolved
+	// profiles and Default() never set it, so they deny unlisted tools.
+	mcpOpen bool
 }
 
 func Default() Policy {
@@ -63,6 +79,7 @@ var knownCapabilities = map[string]struct{}{
 	"tools.spawn_task":          {},
 	"tools.delegate_task":       {},
 	"tools.delegate_task_async": {},
+	"tools.create_plan":         {},
 	"tools.send_message":        {},
 	"tools.read_messages":       {},
 	"tools.memory_read":         {},
@@ -70,6 +87,7 @@ var knownCapabilities = map[string]struct{}{
 	"tools.send_alert":          {},
 	"wasm.http.get":             {},
 	"wasm.kv.set":               {},
+	"wasm.tool":                 {},
 	"legacy.run":                {},
 	"legacy.dangerous":          {},
 	"skill.inject":              {},
@@ -100,6 +118,7 @@ func Load(path string) (Policy, error) {
 	if err := p.validate(); err != nil {
 		return Policy{}, err
 	}
+	p.mcpOpen = strings.TrimSpace(p.MCP.Default) == "" && len(p.MCP.Rules) == 0
 	return p, nil
 }
 
@@ -206,8 +225,13 @@ func (p Policy) AllowPath(path string) bool {
 //  4. Wildcard agent + exact server + exact tool
 //     ... etc (most-specific rule wins)
 //
-// If no rule matches, falls back to default (default deny if unset).
+// If no rule matches, falls back to default (default deny if unset). A
+// policy file loaded without an mcp section allows every MCP tool, as it did
+// before MCP rules existed; profiles and the built-in default do not.
 func (p Policy) AllowMCPTool(agentID, serverName, toolName string) bool {
+	if p.mcpOpen {
+		return true
+	}
 	// Default is deny unless explicitly set to "allow"
 	defaultAllow := strings.ToLower(strings.TrimSpace(p.MCP.Default)) == "allow"
 
@@ -297,20 +321,42 @@ func (p Policy) validate() error {
 			return fmt.Errorf("unknown capability %q", capName)
 		}
 	}
-	return nil
+	if err := validateToolRules(p.ToolRules); err != nil {
+		return err
+	}
+	if err := validateRequireApproval(p.RequireApproval); err != nil {
+		return err
+	}
+	if p.ApprovalTimeoutSeconds < 0 {
+		return fmt.Errorf("approval_timeout_seconds must not be negative")
+	}
+	return p.validateProfiles()
 }
 
 // LivePolicy wraps a Policy with thread-safe mutation and persistence.
 type LivePolicy struct {
-	mu   sync.RWMutex
-	data Policy
-	path string // file path for persistence; empty = no persistence
+	mu       sync.RWMutex
+	data     Policy
+	profiles map[string]Policy // resolved profiles, rebuilt on reload
+	path     string            // file path for persistence; empty = no persistence
 }
 
 // NewLivePolicy creates a LivePolicy from an initial Policy snapshot.
 // If path is non-empty, mutations are persisted to that file.
 func NewLivePolicy(initial Policy, path string) *LivePolicy {
-	return &LivePolicy{data: initial, path: path}
+	return &LivePolicy{data: initial, profiles: resolveProfiles(initial), path: path}
+}
+
+// resolveProfiles flattens every profile that resolves cleanly; broken ones
+// are omitted so checks against them deny.
+func resolveProfiles(p Policy) map[string]Policy {
+	out := make(map[string]Policy, len(p.Profiles))
+	for name := range p.Profiles {
+		if resolved, err := p.ResolveProfile(name); err == nil {
+			out[name] = resolved
+		}
+	}
+	return out
 }
 
 // AllowHTTPURL is the thread-safe check used at runtime.
@@ -340,6 +386,21 @@ func (lp *LivePolicy) AllowPath(path string) bool {
 	return lp.data.AllowPath(path)
 }
 
+// AllowMCPTool is the thread-safe MCP rule check used at runtime, so rule
+// changes apply as soon as the policy is reloaded.
+func (lp *LivePolicy) AllowMCPTool(agentID, serverName, toolName string) bool {
+	lp.mu.RLock()
+	defer lp.mu.RUnlock()
+	return lp.data.AllowMCPTool(agentID, serverName, toolName)
+}
+
+// CheckToolArgs is the thread-safe argument rule check used at runtime.
+func (lp *LivePolicy) CheckToolArgs(agentID string, call ToolCall) ArgDecision {
+	lp.mu.RLock()
+	defer lp.mu.RUnlock()
+	return lp.data.CheckToolArgs(agentID, call)
+}
+
 // containsNormalized checks if a slice already contains a value (case-insensitive, trimmed).
 func containsNormalized(slice []string, val string) bool {
 	for _, s := range slice {
@@ -392,6 +453,7 @@ func (lp *LivePolicy) Reload(p Policy) {
 	lp.mu.Lock()
 	defer lp.mu.Unlock()
 	lp.data = p
+	lp.profiles = resolveProfiles(p)
 }
 
 // Snapshot returns a copy of the current policy data.
@@ -403,6 +465,14 @@ func (lp *LivePolicy) Snapshot() Policy {
 	cp.AllowPaths = append([]string(nil), lp.data.AllowPaths...)
 	cp.AllowCapabilities = append([]string(nil), lp.data.AllowCapabilities...)
 	cp.AllowLoopback = lp.data.AllowLoopback
+	cp.ToolRules = append([]ToolRule(nil), lp.data.ToolRules...)
+	cp.RequireApproval = append([]string(nil), lp.data.RequireApproval...)
+	if lp.data.Profiles != nil {
+		cp.Profiles = make(map[string]Profile, len(lp.data.Profiles))
+		for name, prof := range lp.data.Profiles {
+			cp.Profiles[name] = prof
+		}
+	}
 	return cp
 }
 
@@ -434,6 +504,24 @@ func policyVersionFor(p Policy) string {
 	if p.AllowLoopback {
 		_, _ = h.Write([]byte("allow_loopback=true|"))
 	}
+	// MCP rules are hashed only when present so versions of policies without
+	// an mcp section are unchanged.
+	if p.MCP.Default != "" || len(p.MCP.Rules) > 0 {
+		_, _ = h.Write([]byte("mcp.default=" + strings.ToLower(strings.TrimSpace(p.MCP.Default)) + "|"))
+		for _, r := range p.MCP.Rules {
+			_, _ = h.Write([]byte("mcp.rule=" + strings.ToLower(strings.TrimSpace(r.Agent)) + "/" +
+				strings.ToLower(strings.TrimSpace(r.Server)) + "/" + strings.ToLower(strings.Join(r.Tools, ",")) + "|"))
+		}
+	}
+	for _, r := range p.ToolRules {
+		_, _ = h.Write([]byte("tool_rule=" + r.fingerprint() + "|"))
+	}
+	if len(p.RequireApproval) > 0 || p.ApprovalTimeoutSeconds > 0 {
+		_, _ = h.Write([]byte("require_approval=" + approvalFingerprint(p.RequireApproval, p.ApprovalTimeoutSeconds) + "|"))
+	}
+	for _, name := range p.ProfileNames() {
+		_, _ = h.Write([]byte("profile=" + name + "{" + p.Profiles[name].fingerprint() + "}|"))
+	}
 	return "policy-" + strconv.FormatUint(h.Sum64(), 16)
 }
 
@@ -92,6 +92,37 @@ mcp:
 	}
 }
 
+// TestAllowMCPTool_NoSection verifies that a policy without an mcp section
+// keeps MCP tools usable, while rules without a default still deny.
+func TestAllowMCPTool_NoSection(t *testing.T) {
+	policy, err := loadPolicyFromYAML(t, `
+allow_capabilities: ["acp.read"]
+`)
+	if err != nil {
+		t.Fatalf("loadPolicyFromYAML failed: %v", err)
+	}
+	if !policy.AllowMCPTool("coder", "github", "create_issue") {
+		t.Error("expected allow without an mcp section")
+	}
+
+	policy, err = loadPolicyFromYAML(t, `
+mcp:
+  rules:
+    - agent: coder
+      server: github
+      tools: ["search"]
+`)
+	if err != nil {
+		t.Fatalf("loadPolicyFromYAML failed: %v", err)
+	}
+	if !policy.AllowMCPTool("coder", "github", "search") {
+		t.Error("expected allow for matching rule")
+	}
+	if policy.AllowMCPTool("coder", "github", "create_issue") {
+		t.Error("expected deny for unmatched tool once rules are set")
+	}
+}
+
 // TestAllowMCPTool_DefaultAllow verifies default-allow behavior.
 func TestAllowMCPTool_DefaultAllow(t *testing.T) {
 	yaml := `
@@ -161,3 +192,71 @@ func loadPolicyFromYAML(t *testing.T, yaml string) (Policy, error) {
 	}
 	return Load(policyFile)
 }
+
+// TestAllowMCPTool_WildcardMatrix covers wildcard agents, servers and tools
+// through both Policy and LivePolicy.
+func TestAllowMCPTool_WildcardMatrix(t *testing.T) {
+	p := Policy{MCP: MCPPolicyConfig{
+		Default: "deny",
+		Rules: []MCPRule{
+			{Agent: "*", Server: "docs", Tools: []string{"search"}},
+			{Agent: "*", Server: "*", Tools: []string{"ping"}},
+			{Agent: "coder", Server: "*", Tools: []string{"*"}},
+			{Agent: "coder", Server: "prod", Tools: []string{"read"}},
+			{Agent: "ops", Server: "prod", Tools: []string{"*"}},
+		},
+	}}
+	live := NewLivePolicy(p, "")
+
+	tests := []struct {
+		agent, server, tool string
+		want                bool
+	}{
+		{"anyone", "docs", "search", true},   // wildcard agent, exact server, exact tool
+		{"anyone", "docs", "delete", false},  // tool not listed
+		{"anyone", "other", "ping", true},    // wildcard agent and server
+		{"anyone", "other", "search", false}, // server mismatch
+		{"coder", "github", "push", true},    // exact agent, wildcard server and tool
+		{"coder", "prod", "read", true},      // most specific rule allows
+		{"coder", "prod", "drop", true},      // non-matching tool list falls through to coder/*
+		{"ops", "prod", "drop", true},        // exact agent+server, wildcard tool
+		{"ops", "staging", "drop", false},    // no matching rule falls back to deny
+		{"OPS", " Prod ", "Drop", true},      // case and whitespace insensitive
+	}
+	for _, tt := range tests {
+		if got := p.AllowMCPTool(tt.agent, tt.server, tt.tool); got != tt.want {
+			t.Errorf("Policy.AllowMCPTool(%q, %q, %q) = %v, want %v", tt.agent, tt.server, tt.tool, got, tt.want)
+		}
+		if got := live.AllowMCPTool(tt.agent, tt.server, tt.tool); got != tt.want {
+			t.Errorf("LivePolicy.AllowMCPTool(%q, %q, %q) = %v, want %v", tt.agent, tt.server, tt.tool, got, tt.want)
+		}
+	}
+}
+
+// TestLivePolicy_MCPRulesHotReload verifies reloaded MCP rules apply immediately
+// and change the policy version.
+func TestLivePolicy_MCPRulesHotReload(t *testing.T) {
+	var checker Checker = NewLivePolicy(Policy{MCP: MCPPolicyConfig{Default: "deny"}}, "")
+	mcpChecker, ok := checker.(MCPChecker)
+	if !ok {
+		t.Fatal("LivePolicy must implement MCPChecker")
+	}
+	if mcpChecker.AllowMCPTool("coder", "github", "push") {
+		t.Fatal("expected deny before reload")
+	}
+	before := checker.PolicyVersion()
+
+	checker.(*LivePolicy).Reload(Policy{MCP: MCPPolicyConfig{
+		Default: "deny",
+		Rules:   []MCPRule{{Agent: "coder", Server: "github", Tools: []string{"push"}}},
+	}})
+	if !mcpChecker.AllowMCPTool("coder", "github", "push") {
+		t.Fatal("expected allow after reload")
+	}
+	if checker.PolicyVersion() == before {
+		t.Fatal("expected policy version to change when MCP rules change")
+	}
+	if NewLivePolicy(Policy{}, "").PolicyVersion() != policyVersionFor(Default()) {
+		t.Fatal("policies without mcp rules must keep their version")
+	}
+}
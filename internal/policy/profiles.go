@@ -0,0 +1,231 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxProfileDepth bounds inheritance chains.
+const maxProfileDepth = 8
+
+// Profile is a named policy assigned to agents through their config entry.
+// A profile starts from an empty (default-deny) policy, not from the
+// top-level one, so assigning it never grants more than it lists. Lists and
+// rules are merged with those of the inherited profile; MCP default and
+// allow_loopback are inherited unless set.
+//
+// Example policy.yaml:
+//
+//	profiles:
+//	  readonly:
+//	    allow_capabilities: [tools.read_file, tools.read_url]
+//	    allow_domains: [pkg.go.dev]
+//	  coder:
+//	    inherits: readonly
+//	    allow_capabilities: [tools.write_file, tools.exec]
+//	    allow_paths: ["${HOME}/projects"]
+//	    require_approval: [exec]
+type Profile struct {
+	Inherits          string           `yaml:"inherits,omitempty"`
+	AllowDomains      []string         `yaml:"allow_domains,omitempty"`
+	AllowPaths        []string         `yaml:"allow_paths,omitempty"`
+	AllowCapabilities []string         `yaml:"allow_capabilities,omitempty"`
+	AllowLoopback     *bool            `yaml:"allow_loopback,omitempty"`
+	MCP               *MCPPolicyConfig `yaml:"mcp,omitempty"`
+	ToolRules         []ToolRule       `yaml:"tool_rules,omitempty"`
+	RequireApproval   []string         `yaml:"require_approval,omitempty"`
+}
+
+// ProfileResolver hands out checkers bound to a named profile.
+type ProfileResolver interface {
+	ForProfile(name string) (Checker, error)
+}
+
+// ProfileNames returns the configured profile names in sorted order.
+func (p Policy) ProfileNames() []string {
+	names := make([]string, 0, len(p.Profiles))
+	for name := range p.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveProfile flattens a profile and its ancestors into a standalone Policy.
+func (p Policy) ResolveProfile(name string) (Policy, error) {
+	chain, err := p.profileChain(name)
+	if err != nil {
+		return Policy{}, err
+	}
+	var out Policy
+	loopbackSet := false
+	// Walk from the most-derived profile to the root so a child's MCP and
+	// tool rules precede inherited ones and win specificity ties.
+	for _, prof := range chain {
+		out.AllowDomains = appendMissing(out.AllowDomains, prof.AllowDomains)
+		out.AllowPaths = appendMissing(out.AllowPaths, prof.AllowPaths)
+		out.AllowCapabilities = appendMissing(out.AllowCapabilities, prof.AllowCapabilities)
+		if prof.AllowLoopback != nil && !loopbackSet {
+			out.AllowLoopback = *prof.AllowLoopback
+			loopbackSet = true
+		}
+		if prof.MCP != nil {
+			if out.MCP.Default == "" {
+				out.MCP.Default = prof.MCP.Default
+			}
+			out.MCP.Rules = append(out.MCP.Rules, prof.MCP.Rules...)
+		}
+		out.ToolRules = append(out.ToolRules, prof.ToolRules...)
+		out.RequireApproval = appendMissing(out.RequireApproval, prof.RequireApproval)
+	}
+	return out, nil
+}
+
+// profileChain returns name followed by its ancestors.
+func (p Policy) profileChain(name string) ([]Profile, error) {
+	var chain []Profile
+	seen := make(map[string]bool)
+	for cur := name; cur != ""; {
+		if seen[cur] {
+			return nil, fmt.Errorf("policy profile %q: inheritance cycle at %q", name, cur)
+		}
+		if len(chain) >= maxProfileDepth {
+			return nil, fmt.Errorf("policy profile %q: inheritance deeper than %d", name, maxProfileDepth)
+		}
+		prof, ok := p.Profiles[cur]
+		if !ok {
+			if cur == name {
+				return nil, fmt.Errorf("unknown policy profile %q", name)
+			}
+			return nil, fmt.Errorf("policy profile %q: inherits unknown profile %q", name, cur)
+		}
+		seen[cur] = true
+		chain = append(chain, prof)
+		cur = strings.TrimSpace(prof.Inherits)
+	}
+	return chain, nil
+}
+
+func appendMissing(dst, src []string) []string {
+	for _, v := range src {
+		if !containsNormalized(dst, strings.ToLower(strings.TrimSpace(v))) {
+			dst = append(dst, v)
+		}
+	}
+	return dst
+}
+
+func (p Policy) validateProfiles() error {
+	for _, name := range p.ProfileNames() {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("policy profile name must be non-empty")
+		}
+		resolved, err := p.ResolveProfile(name)
+		if err != nil {
+			return err
+		}
+		if err := resolved.validate(); err != nil {
+			return fmt.Errorf("policy profile %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// fingerprint is the stable text hashed into PolicyVersion.
+func (prof Profile) fingerprint() string {
+	var sb strings.Builder
+	sb.WriteString("inherits=" + strings.TrimSpace(prof.Inherits) + ";")
+	sb.WriteString("domains=" + strings.ToLower(strings.Join(prof.AllowDomains, ",")) + ";")
+	sb.WriteString("paths=" + strings.Join(prof.AllowPaths, ",") + ";")
+	sb.WriteString("caps=" + strings.ToLower(strings.Join(prof.AllowCapabilities, ",")) + ";")
+	if prof.AllowLoopback != nil {
+		sb.WriteString("loopback=" + strconv.FormatBool(*prof.AllowLoopback) + ";")
+	}
+	if prof.MCP != nil {
+		sb.WriteString("mcp.default=" + strings.ToLower(prof.MCP.Default) + ";")
+		for _, r := range prof.MCP.Rules {
+			sb.WriteString("mcp.rule=" + strings.ToLower(r.Agent+"/"+r.Server+"/"+strings.Join(r.Tools, ",")) + ";")
+		}
+	}
+	for _, r := range prof.ToolRules {
+		sb.WriteString("tool_rule=" + r.fingerprint() + ";")
+	}
+	if len(prof.RequireApproval) > 0 {
+		sb.WriteString("require_approval=" + approvalFingerprint(prof.RequireApproval, 0) + ";")
+	}
+	return sb.String()
+}
+
+// ProfileView is a Checker bound to one named profile of a LivePolicy. Each
+// check reads the profile as of the latest reload; if a reload drops the
+// profile, every check denies.
+type ProfileView struct {
+	lp   *LivePolicy
+	name string
+}
+
+// ForProfile returns a checker bound to the named profile.
+func (lp *LivePolicy) ForProfile(name string) (Checker, error) {
+	lp.mu.RLock()
+	defer lp.mu.RUnlock()
+	if _, ok := lp.profiles[name]; !ok {
+		if _, err := lp.data.ResolveProfile(name); err != nil {
+			return nil, err
+		}
+	}
+	return &ProfileView{lp: lp, name: name}, nil
+}
+
+// Profile returns the name of the profile this view is bound to.
+func (v *ProfileView) Profile() string { return v.name }
+
+// current returns the resolved profile; the caller holds lp.mu.RLock.
+func (v *ProfileView) current() Policy {
+	if p, ok := v.lp.profiles[v.name]; ok {
+		return p
+	}
+	return Default()
+}
+
+func (v *ProfileView) AllowHTTPURL(raw string) bool {
+	v.lp.mu.RLock()
+	defer v.lp.mu.RUnlock()
+	return v.current().AllowHTTPURL(raw)
+}
+
+func (v *ProfileView) AllowCapability(capability string) bool {
+	v.lp.mu.RLock()
+	defer v.lp.mu.RUnlock()
+	return v.current().AllowCapability(capability)
+}
+
+func (v *ProfileView) AllowPath(path string) bool {
+	v.lp.mu.RLock()
+	defer v.lp.mu.RUnlock()
+	if _, ok := v.lp.profiles[v.name]; !ok {
+		return false
+	}
+	return v.current().AllowPath(path)
+}
+
+func (v *ProfileView) AllowMCPTool(agentID, serverName, toolName string) bool {
+	v.lp.mu.RLock()
+	defer v.lp.mu.RUnlock()
+	return v.current().AllowMCPTool(agentID, serverName, toolName)
+}
+
+func (v *ProfileView) CheckToolArgs(agentID string, call ToolCall) ArgDecision {
+	v.lp.mu.RLock()
+	defer v.lp.mu.RUnlock()
+	return v.current().CheckToolArgs(agentID, call)
+}
+
+// PolicyVersion is the file-level version suffixed with the profile name, so
+// audit entries correlate with the recorded policy version.
+func (v *ProfileView) PolicyVersion() string {
+	v.lp.mu.RLock()
+	defer v.lp.mu.RUnlock()
+	return policyVersionFor(v.lp.data) + ":" + v.name
+}
@@ -0,0 +1,174 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveProfile_Inheritance(t *testing.T) {
+	off, on := false, true
+	p := Policy{
+		AllowCapabilities: []string{"tools.exec"},
+		Profiles: map[string]Profile{
+			"base": {
+				AllowDomains:      []string{"pkg.go.dev"},
+				AllowCapabilities: []string{"tools.read_url"},
+				AllowLoopback:     &on,
+				MCP:               &MCPPolicyConfig{Default: "deny", Rules: []MCPRule{{Agent: "*", Server: "docs", Tools: []string{"*"}}}},
+			},
+			"coder": {
+				Inherits:          "base",
+				AllowCapabilities: []string{"tools.write_file", "tools.read_url"},
+				AllowLoopback:     &off,
+				MCP:               &MCPPolicyConfig{Rules: []MCPRule{{Agent: "*", Server: "github", Tools: []string{"search"}}}},
+			},
+		},
+	}
+
+	got, err := p.ResolveProfile("coder")
+	if err != nil {
+		t.Fatalf("ResolveProfile: %v", err)
+	}
+	if strings.Join(got.AllowCapabilities, ",") != "tools.write_file,tools.read_url" {
+		t.Errorf("capabilities = %v", got.AllowCapabilities)
+	}
+	if got.AllowCapability("tools.exec") {
+		t.Error("profiles must not inherit top-level capabilities")
+	}
+	if !got.AllowHTTPURL("https://pkg.go.dev/x") {
+		t.Error("expected inherited domain")
+	}
+	if got.AllowLoopback {
+		t.Error("child allow_loopback should override parent")
+	}
+	if got.MCP.Default != "deny" || len(got.MCP.Rules) != 2 || got.MCP.Rules[0].Server != "github" {
+		t.Errorf("unexpected MCP config: %+v", got.MCP)
+	}
+	if !got.AllowMCPTool("a", "docs", "read") || got.AllowMCPTool("a", "github", "delete") {
+		t.Error("MCP rules not merged as expected")
+	}
+}
+
+func TestResolveProfile_Errors(t *testing.T) {
+	p := Policy{Profiles: map[string]Profile{
+		"a":      {Inherits: "b"},
+		"b":      {Inherits: "a"},
+		"orphan": {Inherits: "nope"},
+	}}
+	tests := []struct {
+		name    string
+		wantErr string
+	}{
+		{"a", "inheritance cycle"},
+		{"orphan", `inherits unknown profile "nope"`},
+		{"missing", `unknown policy profile "missing"`},
+	}
+	for _, tt := range tests {
+		if _, err := p.ResolveProfile(tt.name); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ResolveProfile(%q) error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoad_ProfileValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	data := "profiles:\n  bad:\n    allow_capabilities: [tools.teleport]\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), `policy profile "bad"`) {
+		t.Fatalf("expected profile validation error, got %v", err)
+	}
+}
+
+func TestProfileView_FollowsReload(t *testing.T) {
+	base := Policy{
+		AllowCapabilities: []string{"tools.exec"},
+		Profiles:          map[string]Profile{"research": {AllowCapabilities: []string{"tools.read_url"}}},
+	}
+	live := NewLivePolicy(base, "")
+
+	view, err := live.ForProfile("research")
+	if err != nil {
+		t.Fatalf("ForProfile: %v", err)
+	}
+	if view.AllowCapability("tools.exec") || !view.AllowCapability("tools.read_url") {
+		t.Fatal("profile view should only grant the profile's capabilities")
+	}
+	if !strings.HasSuffix(view.PolicyVersion(), ":research") || !strings.HasPrefix(view.PolicyVersion(), live.PolicyVersion()) {
+		t.Fatalf("profile version %q should extend %q", view.PolicyVersion(), live.PolicyVersion())
+	}
+	if _, err := live.ForProfile("nope"); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+
+	updated := base
+	updated.Profiles = map[string]Profile{"research": {AllowCapabilities: []string{"tools.read_url", "tools.web_search"}}}
+	live.Reload(updated)
+	if !view.AllowCapability("tools.web_search") {
+		t.Fatal("profile view should follow reload")
+	}
+
+	live.Reload(Policy{AllowCapabilities: []string{"tools.read_url"}, AllowPaths: []string{"/tmp"}})
+	if view.AllowCapability("tools.read_url") || view.AllowPath("/tmp/x") {
+		t.Fatal("dropped profile should deny everything")
+	}
+}
+
+func TestPolicyVersion_Profiles(t *testing.T) {
+	a := Policy{Profiles: map[string]Profile{"r": {AllowCapabilities: []string{"tools.read_url"}}}}
+	b := Policy{Profiles: map[string]Profile{"r": {AllowCapabilities: []string{"tools.exec"}}}}
+	if a.PolicyVersion() == b.PolicyVersion() || a.PolicyVersion() == Default().PolicyVersion() {
+		t.Fatal("profile changes should change the policy version")
+	}
+}
+
+// TestProfileView_MCPWithoutSection verifies that only a loaded policy file
+// without an mcp section allows every MCP tool; profiles and the built-in
+// default deny tools they do not list.
+func TestProfileView_MCPWithoutSection(t *testing.T) {
+	p, err := loadPolicyFromYAML(t, `
+mcp:
+  default: deny
+profiles:
+  research:
+    allow_capabilities: [tools.read_url]
+`)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	live := NewLivePolicy(p, "")
+	view, err := live.ForProfile("research")
+	if err != nil {
+		t.Fatalf("ForProfile: %v", err)
+	}
+	if live.AllowMCPTool("a", "github", "push") || view.(MCPChecker).AllowMCPTool("a", "github", "push") {
+		t.Fatal("mcp.default deny must apply to the file and to profiles without an mcp section")
+	}
+
+	open, err := loadPolicyFromYAML(t, `
+profiles:
+  research:
+    allow_capabilities: [tools.read_url]
+`)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	live.Reload(open)
+	if !live.AllowMCPTool("a", "github", "push") {
+		t.Fatal("a policy file without an mcp section should allow MCP tools")
+	}
+	if view.(MCPChecker).AllowMCPTool("a", "github", "push") {
+		t.Fatal("a profile without an mcp section should deny MCP tools")
+	}
+
+	live.Reload(Policy{mcpOpen: true})
+	if view.(MCPChecker).AllowMCPTool("a", "github", "push") {
+		t.Fatal("dropped profile should deny MCP tools")
+	}
+	if Default().AllowMCPTool("a", "github", "push") {
+		t.Fatal("Default() should deny MCP tools")
+	}
+}
@@ -0,0 +1,276 @@
+package policy
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Tool names that argument rules can target.
+const (
+	ToolExec      = "exec"
+	ToolWriteFile = "write_file"
+	ToolEditFile  = "edit_file"
+	ToolReadURL   = "read_url"
+	ToolHTTPGet   = "http_get" // WASM host.http.get
+)
+
+var knownRuleTools = map[string]struct{}{
+	ToolExec:      {},
+	ToolWriteFile: {},
+	ToolEditFile:  {},
+	ToolReadURL:   {},
+	ToolHTTPGet:   {},
+}
+
+var knownHTTPMethods = map[string]struct{}{
+	"GET": {}, "HEAD": {}, "POST": {}, "PUT": {}, "PATCH": {}, "DELETE": {}, "OPTIONS": {},
+}
+
+// ToolRule constrains the arguments of a built-in tool for one agent (or all
+// agents with "*"). Empty constraint lists are not enforced; a non-empty list
+// must match the call. Rules narrow what the capability already grants and
+// never widen it: exec's deny list (interpreters such as go and python, sed,
+// awk, rm, ...) is checked first, so a command rule cannot allow those.
+//
+// Example policy.yaml:
+//
+//	tool_rules:
+//	  - name: coder-exec
+//	    agent: coder
+//	    tool: exec
+//	    commands: ["git", "make test"]
+//	    command_patterns: ["^ls( -[al]+)?$"]
+//	    max_output_bytes: 4096
+//	  - agent: "*"
+//	    tool: write_file
+//	    paths: ["/tmp/**", "*.md"]
+type ToolRule struct {
+	Name            string   `yaml:"name,omitempty"`             // reported in audit; defaults to tool_rules[i]
+	Agent           string   `yaml:"agent"`                      // agent_id or "*" (empty means "*")
+	Tool            string   `yaml:"tool"`                       // exec, write_file, edit_file, read_url, http_get
+	Commands        []string `yaml:"commands,omitempty"`         // exec: allowed command prefixes
+	CommandPatterns []string `yaml:"command_patterns,omitempty"` // exec: allowed command regexes
+	Paths           []string `yaml:"paths,omitempty"`            // write_file/edit_file: allowed path globs
+	Methods         []string `yaml:"methods,omitempty"`          // read_url/http_get: allowed HTTP methods
+	MaxOutputBytes  int      `yaml:"max_output_bytes,omitempty"` // exec/read_url/http_get: cap on returned output; 0 = tool default
+}
+
+// ToolCall describes the arguments of a tool invocation for rule evaluation.
+// Only the fields relevant to Tool need to be set.
+type ToolCall struct {
+	Tool    string
+	Command string // one exec command segment
+	Path    string // resolved absolute path
+	Method  string // HTTP method
+}
+
+// ArgDecision is the outcome of evaluating argument rules for a call.
+type ArgDecision struct {
+	Allowed        bool
+	Rule           string // name of the matching rule; empty when no rule applies
+	Reason         string // audit reason
+	MaxOutputBytes int    // 0 = no rule-imposed limit
+}
+
+// ArgChecker is implemented by checkers that evaluate tool argument rules.
+// Policy and *LivePolicy implement it; consumers type-assert a Checker to it.
+type ArgChecker interface {
+	CheckToolArgs(agentID string, call ToolCall) ArgDecision
+}
+
+// CheckToolArgs evaluates the most specific rule for agentID and call.Tool.
+// An exact agent match takes precedence over "*"; among equally specific
+// rules the first one wins. Calls with no matching rule are allowed.
+func (p Policy) CheckToolArgs(agentID string, call ToolCall) ArgDecision {
+	idx := p.matchToolRule(agentID, call.Tool)
+	if idx < 0 {
+		return ArgDecision{Allowed: true}
+	}
+	rule := p.ToolRules[idx]
+	d := ArgDecision{Rule: rule.label(idx), MaxOutputBytes: rule.MaxOutputBytes}
+
+	commandRule := len(rule.Commands) > 0 || len(rule.CommandPatterns) > 0
+	switch {
+	case commandRule && hasShellOperator(call.Command):
+		d.Reason = "command_has_shell_operator"
+	case commandRule && !rule.allowCommand(call.Command):
+		d.Reason = "command_not_allowed"
+	case len(rule.Paths) > 0 && !rule.allowPath(call.Path):
+		d.Reason = "path_not_allowed"
+	case len(rule.Methods) > 0 && !rule.allowMethod(call.Method):
+		d.Reason = "method_not_allowed"
+	default:
+		d.Allowed = true
+		d.Reason = "arg_rule_allowed"
+	}
+	return d
+}
+
+func (p Policy) matchToolRule(agentID, tool string) int {
+	agentID = strings.ToLower(strings.TrimSpace(agentID))
+	tool = strings.ToLower(strings.TrimSpace(tool))
+	best, bestScore := -1, 0
+	for i, r := range p.ToolRules {
+		if strings.ToLower(strings.TrimSpace(r.Tool)) != tool {
+			continue
+		}
+		score := 0
+		switch ruleAgent := strings.ToLower(strings.TrimSpace(r.Agent)); ruleAgent {
+		case agentID:
+			score = 2
+		case "*", "":
+			score = 1
+		default:
+			continue
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+func (r ToolRule) label(idx int) string {
+	if name := strings.TrimSpace(r.Name); name != "" {
+		return name
+	}
+	return "tool_rules[" + strconv.Itoa(idx) + "]"
+}
+
+// shellOperators run, background or redirect another command within one
+// exec segment (the exec tool splits only on |, || and &&). A segment with
+// any of them never matches a command rule: "git status & curl x | sh",
+// "git status\ncurl x" and "git log > ~/.bashrc" all start with "git ".
+var shellOperators = []string{"&", ";", "\n", "\r", "<", ">", "`", "$("}
+
+func hasShellOperator(cmd string) bool {
+	for _, op := range shellOperators {
+		if strings.Contains(cmd, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowCommand matches a prefix on a word boundary ("git" allows "git log"
+// but not "gitk") or any of the regexes.
+func (r ToolRule) allowCommand(cmd string) bool {
+	cmd = strings.TrimSpace(cmd)
+	if cmd == "" {
+		return false
+	}
+	for _, prefix := range r.Commands {
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "" {
+			continue
+		}
+		if cmd == prefix || strings.HasPrefix(cmd, prefix+" ") || strings.HasPrefix(cmd, prefix+"\t") {
+			return true
+		}
+	}
+	for _, pattern := range r.CommandPatterns {
+		re, err := regexp.Compile(pattern)
+		if err == nil && re.MatchString(cmd) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowPath matches path against the rule's globs. A pattern ending in "/**"
+// matches everything under that directory; a pattern without a separator
+// matches the base name; anything else uses filepath.Match on the full path.
+func (r ToolRule) allowPath(path string) bool {
+	if path == "" {
+		return false
+	}
+	path = filepath.Clean(path)
+	for _, pattern := range r.Paths {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+			dir = filepath.Clean(dir)
+			if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+				return true
+			}
+			continue
+		}
+		target := path
+		if !strings.ContainsRune(pattern, filepath.Separator) {
+			target = filepath.Base(path)
+		}
+		if ok, err := filepath.Match(pattern, target); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r ToolRule) allowMethod(method string) bool {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	for _, m := range r.Methods {
+		if strings.ToUpper(strings.TrimSpace(m)) == method {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprint is the stable text hashed into PolicyVersion.
+func (r ToolRule) fingerprint() string {
+	return strings.Join([]string{
+		strings.TrimSpace(r.Name),
+		strings.ToLower(strings.TrimSpace(r.Agent)),
+		strings.ToLower(strings.TrimSpace(r.Tool)),
+		strings.Join(r.Commands, ","),
+		strings.Join(r.CommandPatterns, ","),
+		strings.Join(r.Paths, ","),
+		strings.ToUpper(strings.Join(r.Methods, ",")),
+		strconv.Itoa(r.MaxOutputBytes),
+	}, "/")
+}
+
+func validateToolRules(rules []ToolRule) error {
+	for i, r := range rules {
+		tool := strings.ToLower(strings.TrimSpace(r.Tool))
+		if _, ok := knownRuleTools[tool]; !ok {
+			return fmt.Errorf("tool_rules[%d]: unknown tool %q", i, r.Tool)
+		}
+		if (len(r.Commands) > 0 || len(r.CommandPatterns) > 0) && tool != ToolExec {
+			return fmt.Errorf("tool_rules[%d]: commands only apply to %s", i, ToolExec)
+		}
+		if len(r.Paths) > 0 && tool != ToolWriteFile && tool != ToolEditFile {
+			return fmt.Errorf("tool_rules[%d]: paths only apply to %s and %s", i, ToolWriteFile, ToolEditFile)
+		}
+		if len(r.Methods) > 0 && tool != ToolReadURL && tool != ToolHTTPGet {
+			return fmt.Errorf("tool_rules[%d]: methods only apply to %s and %s", i, ToolReadURL, ToolHTTPGet)
+		}
+		for _, pattern := range r.CommandPatterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("tool_rules[%d]: invalid command pattern %q: %w", i, pattern, err)
+			}
+		}
+		for _, pattern := range r.Paths {
+			if _, err := filepath.Match(strings.TrimSuffix(pattern, "/**"), ""); err != nil {
+				return fmt.Errorf("tool_rules[%d]: invalid path glob %q: %w", i, pattern, err)
+			}
+		}
+		for _, m := range r.Methods {
+			if _, ok := knownHTTPMethods[strings.ToUpper(strings.TrimSpace(m))]; !ok {
+				return fmt.Errorf("tool_rules[%d]: unknown http method %q", i, m)
+			}
+		}
+		if r.MaxOutputBytes < 0 {
+			return fmt.Errorf("tool_rules[%d]: max_output_bytes must not be negative", i)
+		}
+		if r.MaxOutputBytes > 0 && (tool == ToolWriteFile || tool == ToolEditFile) {
+			return fmt.Errorf("tool_rules[%d]: max_output_bytes only applies to %s, %s and %s", i, ToolExec, ToolReadURL, ToolHTTPGet)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckToolArgs(t *testing.T) {
+	p := Policy{ToolRules: []ToolRule{
+		{Name: "all-exec", Agent: "*", Tool: "exec", Commands: []string{"ls"}},
+		{Name: "coder-exec", Agent: "coder", Tool: "exec", Commands: []string{"git", "make test"}, CommandPatterns: []string{`^make (build|lint)$`}, MaxOutputBytes: 1024},
+		{Agent: "*", Tool: "write_file", Paths: []string{"/work/**", "*.md"}},
+		{Name: "no-http", Agent: "*", Tool: "http_get", Methods: []string{"HEAD"}},
+	}}
+
+	tests := []struct {
+		name      string
+		agent     string
+		call      ToolCall
+		wantAllow bool
+		wantRule  string
+		wantWhy   string
+	}{
+		{"exact agent prefix", "coder", ToolCall{Tool: ToolExec, Command: "git log -n 1"}, true, "coder-exec", "arg_rule_allowed"},
+		{"prefix word boundary", "coder", ToolCall{Tool: ToolExec, Command: "gitk"}, false, "coder-exec", "command_not_allowed"},
+		{"multi-word prefix", "coder", ToolCall{Tool: ToolExec, Command: "make test PKG=./..."}, true, "coder-exec", "arg_rule_allowed"},
+		{"multi-word prefix mismatch", "coder", ToolCall{Tool: ToolExec, Command: "make install"}, false, "coder-exec", "command_not_allowed"},
+		{"background operator", "coder", ToolCall{Tool: ToolExec, Command: "git status & curl http://x"}, false, "coder-exec", "command_has_shell_operator"},
+		{"newline", "coder", ToolCall{Tool: ToolExec, Command: "git status\ncurl x"}, false, "coder-exec", "command_has_shell_operator"},
+		{"output redirect", "coder", ToolCall{Tool: ToolExec, Command: "git log > ~/.bashrc"}, false, "coder-exec", "command_has_shell_operator"},
+		{"input redirect", "coder", ToolCall{Tool: ToolExec, Command: "git apply < /tmp/p"}, false, "coder-exec", "command_has_shell_operator"},
+		{"process substitution", "coder", ToolCall{Tool: ToolExec, Command: "git diff <(curl x)"}, false, "coder-exec", "command_has_shell_operator"},
+		{"operator defeats regex", "coder", ToolCall{Tool: ToolExec, Command: "make lint\nsh"}, false, "coder-exec", "command_has_shell_operator"},
+		{"regex", "coder", ToolCall{Tool: ToolExec, Command: "make lint"}, true, "coder-exec", "arg_rule_allowed"},
+		{"exact agent beats wildcard", "coder", ToolCall{Tool: ToolExec, Command: "ls"}, false, "coder-exec", "command_not_allowed"},
+		{"wildcard agent", "writer", ToolCall{Tool: ToolExec, Command: "ls -la"}, true, "all-exec", "arg_rule_allowed"},
+		{"path under dir", "writer", ToolCall{Tool: ToolWriteFile, Path: "/work/a/b.go"}, true, "tool_rules[2]", "arg_rule_allowed"},
+		{"path base glob", "writer", ToolCall{Tool: ToolWriteFile, Path: "/etc/notes.md"}, true, "tool_rules[2]", "arg_rule_allowed"},
+		{"path outside", "writer", ToolCall{Tool: ToolWriteFile, Path: "/etc/passwd"}, false, "tool_rules[2]", "path_not_allowed"},
+		{"dir prefix is not a sibling", "writer", ToolCall{Tool: ToolWriteFile, Path: "/workspace/x"}, false, "tool_rules[2]", "path_not_allowed"},
+		{"method denied", "writer", ToolCall{Tool: ToolHTTPGet, Method: "GET"}, false, "no-http", "method_not_allowed"},
+		{"no rule for tool", "writer", ToolCall{Tool: ToolEditFile, Path: "/etc/passwd"}, true, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := p.CheckToolArgs(tt.agent, tt.call)
+			if d.Allowed != tt.wantAllow || d.Rule != tt.wantRule || d.Reason != tt.wantWhy {
+				t.Fatalf("CheckToolArgs = %+v, want allowed=%v rule=%q reason=%q", d, tt.wantAllow, tt.wantRule, tt.wantWhy)
+			}
+		})
+	}
+
+	if d := p.CheckToolArgs("coder", ToolCall{Tool: ToolExec, Command: "git status"}); d.MaxOutputBytes != 1024 {
+		t.Fatalf("MaxOutputBytes = %d, want 1024", d.MaxOutputBytes)
+	}
+}
+
+func TestLoad_ToolRulesValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"valid", "tool_rules:\n  - agent: coder\n    tool: exec\n    commands: [git]\n", ""},
+		{"unknown tool", "tool_rules:\n  - tool: rm\n", "unknown tool"},
+		{"bad regex", "tool_rules:\n  - tool: exec\n    command_patterns: ['(']\n", "invalid command pattern"},
+		{"paths on exec", "tool_rules:\n  - tool: exec\n    paths: [/tmp/**]\n", "paths only apply"},
+		{"bad method", "tool_rules:\n  - tool: read_url\n    methods: [FETCH]\n", "unknown http method"},
+		{"negative max", "tool_rules:\n  - tool: exec\n    max_output_bytes: -1\n", "must not be negative"},
+		{"max on write_file", "tool_rules:\n  - tool: write_file\n    max_output_bytes: 10\n", "max_output_bytes only applies"},
+		{"max on edit_file", "tool_rules:\n  - tool: edit_file\n    max_output_bytes: 10\n", "max_output_bytes only applies"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyVersion_ToolRules(t *testing.T) {
+	base := Policy{AllowCapabilities: []string{"tools.exec"}}
+	withRule := base
+	withRule.ToolRules = []ToolRule{{Agent: "*", Tool: "exec", Commands: []string{"git"}}}
+	changed := base
+	changed.ToolRules = []ToolRule{{Agent: "*", Tool: "exec", Commands: []string{"ls"}}}
+
+	if base.PolicyVersion() == withRule.PolicyVersion() {
+		t.Fatal("adding a tool rule should change the policy version")
+	}
+	if withRule.PolicyVersion() == changed.PolicyVersion() {
+		t.Fatal("changing a tool rule should change the policy version")
+	}
+
+	live := NewLivePolicy(base, "")
+	if d := live.CheckToolArgs("a", ToolCall{Tool: ToolExec, Command: "ls"}); !d.Allowed || d.Rule != "" {
+		t.Fatalf("expected no rule before reload, got %+v", d)
+	}
+	live.Reload(withRule)
+	if d := live.CheckToolArgs("a", ToolCall{Tool: ToolExec, Command: "ls"}); d.Allowed {
+		t.Fatalf("expected reloaded rule to deny ls, got %+v", d)
+	}
+	if live.PolicyVersion() != withRule.PolicyVersion() {
+		t.Fatal("live policy version should follow reload")
+	}
+}
@@ -1,34 +1,135 @@
 // Package pricing provides per-model cost estimation for token usage.
 package pricing
 
-// ModelPricing holds per-million-token costs in USD.
+import (
+	"strings"
+	"sync"
+)
+
+// ModelPricing holds per-million-token costs in USD. CachedPromptPer1M is
+// the rate for prompt tokens served from the provider's cache; 0 charges
+// them at PromptPer1M.
 type ModelPricing struct {
-	PromptPer1M     float64
-	CompletionPer1M float64
+	PromptPer1M       float64
+	CompletionPer1M   float64
+	CachedPromptPer1M float64
 }
 
-// Known model pricing as of Feb 2026. Add new models as needed.
-var knownModels = map[string]ModelPricing{
-	// Gemini
-	"gemini-2.0-flash-exp":  {0.0, 0.0},
-	"gemini-1.5-pro":        {1.25, 5.00},
-	"gemini-2.5-flash":      {0.075, 0.30},
-	"gemini-2.5-flash-lite": {0.0, 0.0},
-	// Anthropic
-	"claude-3-7-sonnet": {3.00, 15.00},
-	"claude-sonnet-4-5": {3.00, 15.00},
-	// OpenAI
-	"gpt-4o":      {2.50, 10.00},
-	"gpt-4o-mini": {0.15, 0.60},
+// Wildcard prices every model of a provider without its own entry.
+const Wildcard = "*"
+
+// Known model pricing by provider as of Feb 2026, covering the built-in
+// models of config.BuiltinModels. Config overrides are merged over it.
+var knownModels = map[string]map[string]ModelPricing{
+	"google": {
+		"gemini-3-pro-preview":   {PromptPer1M: 2.00, CompletionPer1M: 12.00, CachedPromptPer1M: 0.20},
+		"gemini-3-flash-preview": {PromptPer1M: 0.50, CompletionPer1M: 3.00, CachedPromptPer1M: 0.05},
+		"gemini-2.5-pro":         {PromptPer1M: 1.25, CompletionPer1M: 10.00, CachedPromptPer1M: 0.125},
+		"gemini-2.5-flash":       {PromptPer1M: 0.30, CompletionPer1M: 2.50, CachedPromptPer1M: 0.03},
+		"gemini-2.5-flash-lite":  {PromptPer1M: 0.10, CompletionPer1M: 0.40, CachedPromptPer1M: 0.01},
+		"gemini-2.0-flash-exp":   {},
+		"gemini-1.5-pro":         {PromptPer1M: 1.25, CompletionPer1M: 5.00},
+	},
+	"anthropic": {
+		"claude-opus-4-6":            {PromptPer1M: 5.00, CompletionPer1M: 25.00, CachedPromptPer1M: 0.50},
+		"claude-sonnet-4-5":          {PromptPer1M: 3.00, CompletionPer1M: 15.00, CachedPromptPer1M: 0.30},
+		"claude-sonnet-4-5-20250929": {PromptPer1M: 3.00, CompletionPer1M: 15.00, CachedPromptPer1M: 0.30},
+		"claude-haiku-4-5":           {PromptPer1M: 1.00, CompletionPer1M: 5.00, CachedPromptPer1M: 0.10},
+		"claude-haiku-4-5-20251001":  {PromptPer1M: 1.00, CompletionPer1M: 5.00, CachedPromptPer1M: 0.10},
+		"claude-3-7-sonnet":          {PromptPer1M: 3.00, CompletionPer1M: 15.00, CachedPromptPer1M: 0.30},
+	},
+	"openai": {
+		"o3":          {PromptPer1M: 2.00, CompletionPer1M: 8.00, CachedPromptPer1M: 0.50},
+		"o4-mini":     {PromptPer1M: 1.10, CompletionPer1M: 4.40, CachedPromptPer1M: 0.275},
+		"gpt-4o":      {PromptPer1M: 2.50, CompletionPer1M: 10.00, CachedPromptPer1M: 1.25},
+		"gpt-4o-mini": {PromptPer1M: 0.15, CompletionPer1M: 0.60, CachedPromptPer1M: 0.075},
+	},
+	"openrouter": {
+		"anthropic/claude-sonnet-4-5-20250929": {PromptPer1M: 3.00, CompletionPer1M: 15.00, CachedPromptPer1M: 0.30},
+		"openai/gpt-4o":                        {PromptPer1M: 2.50, CompletionPer1M: 10.00, CachedPromptPer1M: 1.25},
+		"meta-llama/llama-3.1-70b-instruct":    {PromptPer1M: 0.40, CompletionPer1M: 0.40},
+		"mistralai/mistral-large-latest":       {PromptPer1M: 2.00, CompletionPer1M: 6.00},
+	},
+	// Local models cost nothing per token.
+	"ollama": {
+		Wildcard: {},
+	},
 }
 
-// EstimateCost returns the estimated USD cost for the given token counts.
-// Returns 0.0 for unknown models (safe default).
-func EstimateCost(model string, promptTokens, completionTokens int) float64 {
-	p, ok := knownModels[model]
+var (
+	mu     sync.RWMutex
+	prices = merge(knownModels, nil)
+)
+
+// SetOverrides replaces the configured prices, keyed by provider and model.
+// They are merged over the built-in table; a provider's Wildcard entry
+// prices its models that have no entry of their own.
+func SetOverrides(overrides map[string]map[string]ModelPricing) {
+	merged := merge(knownModels, overrides)
+	mu.Lock()
+	prices = merged
+	mu.Unlock()
+}
+
+func merge(base, overrides map[string]map[string]ModelPricing) map[string]map[string]ModelPricing {
+	out := make(map[string]map[string]ModelPricing, len(base)+len(overrides))
+	for _, table := range []map[string]map[string]ModelPricing{base, overrides} {
+		for provider, models := range table {
+			provider = strings.ToLower(provider)
+			if out[provider] == nil {
+				out[provider] = make(map[string]ModelPricing, len(models))
+			}
+			for model, p := range models {
+				out[provider][model] = p
+			}
+		}
+	}
+	return out
+}
+
+// Lookup returns the pricing of a provider's model. An empty provider, or one
+// without an entry for the model (such as openai_compatible), matches the
+// model under any provider.
+func Lookup(provider, model string) (ModelPricing, bool) {
+	provider = strings.ToLower(provider)
+	mu.RLock()
+	defer mu.RUnlock()
+	if models, ok := prices[provider]; ok {
+		if p, ok := models[model]; ok {
+			return p, true
+		}
+		if p, ok := models[Wildcard]; ok {
+			return p, true
+		}
+	}
+	for _, models := range prices {
+		if p, ok := models[model]; ok {
+			return p, true
+		}
+	}
+	return ModelPricing{}, false
+}
+
+// Cost returns the USD cost of a model call. cachedTokens is the part of
+// promptTokens served from cache. Returns 0.0 for unknown models (safe
+// default).
+func Cost(provider, model string, promptTokens, cachedTokens, completionTokens int) float64 {
+	p, ok := Lookup(provider, model)
 	if !ok {
 		return 0.0
 	}
-	return (float64(promptTokens)/1_000_000)*p.PromptPer1M +
+	cachedRate := p.CachedPromptPer1M
+	if cachedRate == 0 {
+		cachedRate = p.PromptPer1M
+	}
+	cachedTokens = min(cachedTokens, promptTokens)
+	return (float64(promptTokens-cachedTokens)/1_000_000)*p.PromptPer1M +
+		(float64(cachedTokens)/1_000_000)*cachedRate +
 		(float64(completionTokens)/1_000_000)*p.CompletionPer1M
 }
+
+// EstimateCost returns the estimated USD cost for the given token counts.
+// Returns 0.0 for unknown models (safe default).
+func EstimateCost(model string, promptTokens, completionTokens int) float64 {
+	return Cost("", model, promptTokens, 0, completionTokens)
+}
@@ -1,6 +1,11 @@
 package pricing
 
-import "testing"
+import (
+	"math"
+	"testing"
+
+	"github.com/basket/go-claw/internal/config"
+)
 
 func TestEstimateCost_KnownModel(t *testing.T) {
 	cost := EstimateCost("gpt-4o", 1000, 500)
@@ -17,10 +22,66 @@ func TestEstimateCost_UnknownModel(t *testing.T) {
 }
 
 func TestEstimateCost_GeminiModel(t *testing.T) {
-	// Gemini 2.5 Flash: $0.075 per 1M prompt, $0.30 per 1M completion
+	// Gemini 2.5 Flash: $0.30 per 1M prompt, $2.50 per 1M completion
 	cost := EstimateCost("gemini-2.5-flash", 1000000, 1000000)
-	expected := 0.075 + 0.30 // $0.375
-	if cost != expected {
+	expected := 0.30 + 2.50 // $2.80
+	if math.Abs(cost-expected) > 1e-9 {
+		t.Fatalf("expected %f, got %f", expected, cost)
+	}
+}
+
+func TestCost_CachedTokens(t *testing.T) {
+	// claude-sonnet-4-5: $3 per 1M prompt, $0.30 per 1M cached, $15 per 1M completion.
+	cost := Cost("anthropic", "claude-sonnet-4-5-20250929", 1_000_000, 800_000, 100_000)
+	expected := 0.2*3.00 + 0.8*0.30 + 0.1*15.00
+	if math.Abs(cost-expected) > 1e-9 {
 		t.Fatalf("expected %f, got %f", expected, cost)
 	}
+	// Without a cached rate, cached tokens cost the prompt rate.
+	cost = Cost("google", "gemini-1.5-pro", 1_000_000, 500_000, 0)
+	if math.Abs(cost-1.25) > 1e-9 {
+		t.Fatalf("expected 1.25, got %f", cost)
+	}
+}
+
+func TestSetOverrides(t *testing.T) {
+	t.Cleanup(func() { SetOverrides(nil) })
+	SetOverrides(map[string]map[string]ModelPricing{
+		"Google":            {"gemini-2.5-flash": {PromptPer1M: 1, CompletionPer1M: 2}},
+		"openai_compatible": {Wildcard: {PromptPer1M: 0.5, CompletionPer1M: 0.5}},
+	})
+
+	tests := []struct {
+		provider, model string
+		want            float64
+	}{
+		{"google", "gemini-2.5-flash", 3},            // overridden
+		{"google", "gemini-2.5-pro", 1.25 + 10.00},   // built-in kept
+		{"openai_compatible", "my-local-model", 1},   // provider wildcard
+		{"openai_compatible", "gpt-4o", 0.5 + 0.5},   // wildcard before other providers
+		{"ollama", "qwen3:8b", 0},                    // local models are free
+		{"openrouter", "unknown/model", 0},           // unknown
+		{"openrouter", "openai/gpt-4o", 2.50 + 10.0}, // provider-prefixed name
+	}
+	for _, tt := range tests {
+		got := Cost(tt.provider, tt.model, 1_000_000, 0, 1_000_000)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Cost(%q, %q) = %f, want %f", tt.provider, tt.model, got, tt.want)
+		}
+	}
+
+	SetOverrides(nil)
+	if got := Cost("google", "gemini-2.5-flash", 1_000_000, 0, 0); math.Abs(got-0.30) > 1e-9 {
+		t.Errorf("after reset, cost = %f, want the built-in 0.30", got)
+	}
+}
+
+func TestBuiltinModelsPriced(t *testing.T) {
+	for provider, models := range config.BuiltinModels {
+		for _, m := range models {
+			if _, ok := Lookup(provider, m.ID); !ok {
+				t.Errorf("built-in model %s/%s has no price", provider, m.ID)
+			}
+		}
+	}
 }
@@ -12,6 +12,7 @@ import (
 
 	"github.com/basket/go-claw/internal/audit"
 	"github.com/basket/go-claw/internal/policy"
+	"github.com/basket/go-claw/internal/sandbox/native"
 	"gopkg.in/yaml.v3"
 )
 
@@ -210,6 +211,9 @@ type Runner struct {
 	WorkspaceDir     string
 	ConfirmDangerous func(command string) bool
 	Policy           policy.Checker
+	// Sandbox, when set, runs scripts in a native sandbox with WorkspaceDir
+	// mounted at /workspace instead of directly on the host.
+	Sandbox *native.Sandbox
 }
 
 func (r Runner) Run(ctx context.Context, skill Skill) (string, error) {
@@ -249,11 +253,27 @@ func (r Runner) Run(ctx context.Context, skill Skill) (string, error) {
 		return "", err
 	}
 
+	var out bytes.Buffer
+	if r.Sandbox != nil {
+		code, err := r.Sandbox.Run(ctx, native.Command{
+			Argv:      []string{"/bin/sh", "-lc", skill.Script},
+			Env:       buildMinimalEnv(native.WorkspaceDir, skill),
+			Workspace: r.WorkspaceDir,
+			Stdout:    &out,
+			Stderr:    &out,
+		})
+		if err != nil {
+			return out.String(), fmt.Errorf("legacy skill sandbox: %w", err)
+		}
+		if code != 0 {
+			return out.String(), fmt.Errorf("legacy skill run failed: exit status %d", code)
+		}
+		return out.String(), nil
+	}
+
 	cmd := exec.CommandContext(ctx, "/bin/sh", "-lc", skill.Script)
 	cmd.Dir = r.WorkspaceDir
 	cmd.Env = buildMinimalEnv(r.WorkspaceDir, skill)
-
-	var out bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &out
 	if err := cmd.Run(); err != nil {
@@ -314,8 +334,8 @@ var (
 // enforceWriteRestriction is a best-effort heuristic guard against scripts that
 // attempt to write outside the workspace directory. It is NOT a security boundary
 // — without an OS-level sandbox (namespaces, seccomp, etc.) a determined user can
-// bypass these checks. The function exists as defense-in-depth to catch accidental
-// or unsophisticated escape attempts. Gate: requires the "legacy.run" capability
+// bypass these checks; set Runner.Sandbox for one. The function exists as
+// defense-in-depth to catch accidental or unsophisticated escape attempts. Gate: requires the "legacy.run" capability
 // to reach this point; see Runner.Run.
 func enforceWriteRestriction(script string) error {
 	lower := strings.ToLower(script)
@@ -3,14 +3,21 @@ package legacy_test
 import (
 	"context"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/basket/go-claw/internal/policy"
 	"github.com/basket/go-claw/internal/sandbox/legacy"
+	"github.com/basket/go-claw/internal/sandbox/native"
 )
 
+func TestMain(m *testing.M) {
+	native.Init()
+	os.Exit(m.Run())
+}
+
 func TestParseSkillSubsetAndBinChecks(t *testing.T) {
 	// [SPEC: SPEC-COMPAT-SKILLMD-1, SPEC-COMPAT-SKILLMD-2] [PDR: V-11]
 	skillDoc := `name: test-skill
@@ -88,6 +95,49 @@ func TestRunner_ExecutesSafeScriptInsideWorkspace(t *testing.T) {
 	}
 }
 
+func TestRunner_NativeSandbox(t *testing.T) {
+	if err := exec.Command("unshare", "--user", "--map-root-user", "true").Run(); err != nil {
+		t.Skipf("user namespaces unavailable: %v", err)
+	}
+	// A plain directory stands in for a delegated cgroup.
+	cgroup := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cgroup, "cgroup.controllers"), []byte("cpu memory pids"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cgroup, "cgroup.subtree_control"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sb, err := native.New(native.Config{Cgroup: cgroup})
+	if err != nil {
+		t.Fatalf("native sandbox: %v", err)
+	}
+
+	workspace := t.TempDir()
+	r := legacy.Runner{
+		WorkspaceDir: workspace,
+		Policy:       policy.Policy{AllowCapabilities: []string{"legacy.run"}},
+		Sandbox:      sb,
+	}
+	out, err := r.Run(context.Background(), legacy.Skill{
+		Name:   "sandboxed",
+		Script: "echo $HOME > home.txt; touch /etc/goclaw-legacy || echo etc read-only",
+	})
+	if err != nil {
+		t.Fatalf("run: %v (output %q)", err, out)
+	}
+	if !strings.Contains(out, "etc read-only") {
+		t.Errorf("output = %q", out)
+	}
+	if data, err := os.ReadFile(filepath.Join(workspace, "home.txt")); err != nil || string(data) != "/workspace\n" {
+		t.Errorf("home.txt = %q, %v", data, err)
+	}
+
+	_, err = r.Run(context.Background(), legacy.Skill{Name: "failing", Script: "exit 4"})
+	if err == nil || !strings.Contains(err.Error(), "exit status 4") {
+		t.Errorf("err = %v, want exit status 4", err)
+	}
+}
+
 func TestRunner_WriteOutsideWorkspaceDenied_PathTraversal(t *testing.T) {
 	workspace := t.TempDir()
 	parent := filepath.Dir(workspace)
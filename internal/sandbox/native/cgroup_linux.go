@@ -0,0 +1,189 @@
+package native
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// cgroupControllers must be available in the parent cgroup.
+var cgroupControllers = []string{"cpu", "memory", "pids"}
+
+// prepareCgroupParent resolves the parent cgroup (the current process's when
+// path is empty) and enables the controllers for its children. A cgroup
+// can't both hold processes and delegate controllers, so when the parent is
+// our own cgroup the process moves into a "goclaw" leaf below it first.
+func prepareCgroupParent(path string) (string, error) {
+	own, err := ownCgroup()
+	if err != nil && path == "" {
+		return "", err
+	}
+	if path == "" {
+		path = own
+	}
+	available, err := os.ReadFile(filepath.Join(path, "cgroup.controllers"))
+	if err != nil {
+		return "", fmt.Errorf("cgroup %s: %w (set tools.shell.sandbox_cgroup to a delegated cgroup v2 directory)", path, err)
+	}
+	have := strings.Fields(string(available))
+	for _, c := range cgroupControllers {
+		if !slices.Contains(have, c) {
+			return "", fmt.Errorf("cgroup %s: %s controller not delegated (run goclaw under a systemd unit with Delegate=yes or set tools.shell.sandbox_cgroup)", path, c)
+		}
+	}
+
+	err = enableControllers(path)
+	if errors.Is(err, syscall.EBUSY) && own != "" && filepath.Clean(path) == filepath.Clean(own) {
+		leaf := filepath.Join(path, "goclaw")
+		if err := os.Mkdir(leaf, 0o755); err != nil && !os.IsExist(err) {
+			return "", fmt.Errorf("cgroup %s: %w", leaf, err)
+		}
+		if err := os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte("0"), 0o644); err != nil {
+			return "", fmt.Errorf("cgroup %s: move self: %w", leaf, err)
+		}
+		err = enableControllers(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cgroup %s: enable controllers: %w", path, err)
+	}
+	return path, nil
+}
+
+// enableControllers turns on the missing controllers in path's
+// cgroup.subtree_control.
+func enableControllers(path string) error {
+	current, err := os.ReadFile(filepath.Join(path, "cgroup.subtree_control"))
+	if err != nil {
+		return err
+	}
+	enabled := strings.Fields(string(current))
+	var add []string
+	for _, c := range cgroupControllers {
+		if !slices.Contains(enabled, c) {
+			add = append(add, "+"+c)
+		}
+	}
+	if len(add) == 0 {
+		return nil
+	}
+	return os.WriteFile(filepath.Join(path, "cgroup.subtree_control"), []byte(strings.Join(add, " ")), 0o644)
+}
+
+// ownCgroup returns the directory of the current process's cgroup v2.
+func ownCgroup() (string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", fmt.Errorf("read /proc/self/cgroup: %w", err)
+	}
+	var rel string
+	found := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if p, ok := strings.CutPrefix(line, "0::"); ok {
+			rel, found = p, true
+			break
+		}
+	}
+	if !found {
+		return "", errors.New("no cgroup v2 hierarchy (set tools.shell.sandbox_cgroup)")
+	}
+	mount, err := cgroup2Mount()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(mount, rel), nil
+}
+
+// cgroup2Mount finds where the cgroup2 file system is mounted.
+func cgroup2Mount() (string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", fmt.Errorf("read mountinfo: %w", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// 36 35 0:30 / /sys/fs/cgroup rw,nosuid - cgroup2 cgroup2 rw
+		fields := strings.Fields(sc.Text())
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) && fields[i+1] == "cgroup2" && len(fields) > 4 {
+				return fields[4], nil
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", fmt.Errorf("read mountinfo: %w", err)
+	}
+	return "", errors.New("cgroup2 is not mounted (set tools.shell.sandbox_cgroup)")
+}
+
+// cgroup is the leaf one sandboxed command runs in.
+type cgroup struct {
+	path string
+}
+
+// newCgroup creates a leaf under parent with cfg's limits.
+func newCgroup(parent string, cfg Config) (*cgroup, error) {
+	var id [6]byte
+	_, _ = rand.Read(id[:])
+	path := filepath.Join(parent, "goclaw-sandbox-"+hex.EncodeToString(id[:]))
+	if err := os.Mkdir(path, 0o755); err != nil {
+		return nil, fmt.Errorf("create cgroup: %w", err)
+	}
+	cg := &cgroup{path: path}
+	limits := []struct {
+		file, value string
+		optional    bool
+	}{
+		{"memory.max", strconv.FormatInt(cfg.MemoryMB<<20, 10), false},
+		{"memory.swap.max", "0", true}, // absent without swap accounting
+		{"pids.max", strconv.FormatInt(cfg.Pids, 10), false},
+		{"cpu.max", fmt.Sprintf("%d 100000", int64(cfg.CPUs*100000)), false},
+	}
+	for _, l := range limits {
+		file := filepath.Join(path, l.file)
+		if _, err := os.Stat(file); l.optional && os.IsNotExist(err) {
+			continue
+		}
+		if err := os.WriteFile(file, []byte(l.value), 0o644); err != nil {
+			cg.remove()
+			return nil, fmt.Errorf("cgroup %s: %w", l.file, err)
+		}
+	}
+	return cg, nil
+}
+
+// add moves pid into the cgroup.
+func (cg *cgroup) add(pid int) error {
+	if err := os.WriteFile(filepath.Join(cg.path, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0o644); err != nil {
+		return fmt.Errorf("join cgroup: %w", err)
+	}
+	return nil
+}
+
+// remove kills anything left in the cgroup (background processes of the
+// command) and deletes it.
+func (cg *cgroup) remove() {
+	_ = os.WriteFile(filepath.Join(cg.path, "cgroup.kill"), []byte("1"), 0o644)
+	for i := 0; i < 50; i++ {
+		err := syscall.Rmdir(cg.path)
+		if err == nil || errors.Is(err, syscall.ENOENT) {
+			return
+		}
+		if !errors.Is(err, syscall.EBUSY) {
+			// Plain directories, e.g. in tests, still hold the files written
+			// above.
+			_ = os.RemoveAll(cg.path)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
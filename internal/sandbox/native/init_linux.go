@@ -0,0 +1,377 @@
+package native
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// initArg is argv[0] of the re-executed binary while it sets up a sandbox.
+const initArg = "goclaw-sandbox-init"
+
+// Setup exit status, reported when the error pipe is unusable.
+const initFailed = 125
+
+// systemPaths are the host directories every sandbox sees read-only.
+var systemPaths = []string{"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/libx32", "/etc", "/opt"}
+
+// devices are bound from the host into the sandbox's /dev.
+var devices = []string{"null", "zero", "full", "random", "urandom", "tty"}
+
+// initSpec is what the parent sends the re-executed child on fd 3.
+type initSpec struct {
+	Argv      []string `json:"argv"`
+	Env       []string `json:"env"`
+	Dir       string   `json:"dir"`
+	Root      string   `json:"root"`      // empty host directory the new root is mounted on
+	Workspace string   `json:"workspace"` // host directory for /workspace; empty mounts a tmpfs
+	ReadOnly  []string `json:"read_only"`
+	Network   bool     `json:"network"` // sharing the host network
+}
+
+// Init turns the process into a sandbox's init when it was started as one,
+// and returns otherwise. Call it first thing in main.
+func Init() {
+	if len(os.Args) == 0 || os.Args[0] != initArg {
+		return
+	}
+	// Landlock, seccomp and capabilities are per thread; the thread that
+	// sets them up must be the one that execs.
+	runtime.LockOSThread()
+	errPipe := os.NewFile(4, "errors")
+	unix.CloseOnExec(4)
+	err := runInit()
+	// runInit only returns on failure.
+	fmt.Fprintf(errPipe, "%v\n", err)
+	os.Exit(initFailed)
+}
+
+func runInit() error {
+	var spec initSpec
+	specFile := os.NewFile(3, "spec")
+	if err := json.NewDecoder(specFile).Decode(&spec); err != nil {
+		return fmt.Errorf("read spec: %w", err)
+	}
+	specFile.Close()
+
+	if err := setupRoot(spec); err != nil {
+		return err
+	}
+	if err := unix.Sethostname([]byte("sandbox")); err != nil {
+		return fmt.Errorf("sethostname: %w", err)
+	}
+	if !spec.Network {
+		if err := loopbackUp(); err != nil {
+			return fmt.Errorf("loopback: %w", err)
+		}
+	}
+	if err := os.Chdir(spec.Dir); err != nil {
+		return fmt.Errorf("chdir: %w", err)
+	}
+	argv0, err := lookPath(spec.Argv[0], spec.Env)
+	if err != nil {
+		return err
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("no_new_privs: %w", err)
+	}
+	if err := restrictWrites([]string{WorkspaceDir, "/tmp"}, []string{"/dev"}); err != nil {
+		return fmt.Errorf("landlock: %w", err)
+	}
+	if err := dropCapabilities(); err != nil {
+		return fmt.Errorf("capabilities: %w", err)
+	}
+	if err := installSeccomp(); err != nil {
+		return fmt.Errorf("seccomp: %w", err)
+	}
+	if err := unix.Exec(argv0, spec.Argv, spec.Env); err != nil {
+		return fmt.Errorf("exec %s: %w", spec.Argv[0], err)
+	}
+	return nil
+}
+
+// setupRoot builds the sandbox's file system on a tmpfs at spec.Root and
+// pivots into it: the read-only paths, /workspace, /tmp, /proc and a minimal
+// /dev. The tmpfs root is read-only too, so only /workspace and /tmp are
+// writable.
+func setupRoot(spec initSpec) error {
+	// Keep mount events from propagating back to the host.
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+	root := spec.Root
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=755,size=1m"); err != nil {
+		return fmt.Errorf("mount root: %w", err)
+	}
+
+	for _, p := range spec.ReadOnly {
+		if err := bindReadOnly(root, p); err != nil {
+			return err
+		}
+	}
+
+	ws := filepath.Join(root, WorkspaceDir)
+	if err := os.MkdirAll(ws, 0o755); err != nil {
+		return fmt.Errorf("mkdir /workspace: %w", err)
+	}
+	if spec.Workspace == "" {
+		if err := unix.Mount("tmpfs", ws, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=755"); err != nil {
+			return fmt.Errorf("mount /workspace: %w", err)
+		}
+	} else if err := unix.Mount(spec.Workspace, ws, "", unix.MS_BIND|unix.MS_REC|unix.MS_NOSUID, ""); err != nil {
+		return fmt.Errorf("bind /workspace: %w", err)
+	}
+
+	tmp := filepath.Join(root, "tmp")
+	if err := os.Mkdir(tmp, 0o1777); err != nil {
+		return fmt.Errorf("mkdir /tmp: %w", err)
+	}
+	if err := unix.Mount("tmpfs", tmp, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777,size=64m"); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+
+	proc := filepath.Join(root, "proc")
+	if err := os.Mkdir(proc, 0o555); err != nil {
+		return fmt.Errorf("mkdir /proc: %w", err)
+	}
+	if err := unix.Mount("proc", proc, "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount /proc: %w", err)
+	}
+
+	if err := setupDev(filepath.Join(root, "dev")); err != nil {
+		return err
+	}
+
+	// The root tmpfs itself becomes read-only last, after its mount points
+	// exist.
+	if err := unix.MountSetattr(-1, root, 0, &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY}); err != nil {
+		return fmt.Errorf("make root read-only: %w", err)
+	}
+
+	if err := os.Chdir(root); err != nil {
+		return fmt.Errorf("chdir root: %w", err)
+	}
+	// pivot_root(".", ".") stacks the old root under the new one, where it
+	// is detached.
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("pivot_root: %w", err)
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach old root: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return fmt.Errorf("chdir /: %w", err)
+	}
+	return nil
+}
+
+// bindReadOnly makes host path p visible read-only at the same place under
+// root. Symlinks (e.g. /bin -> usr/bin) are recreated; missing paths are
+// skipped.
+func bindReadOnly(root, p string) error {
+	p = filepath.Clean(p)
+	fi, err := os.Lstat(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", p, err)
+	}
+	target := filepath.Join(root, p)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", filepath.Dir(p), err)
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(p)
+		if err != nil {
+			return fmt.Errorf("readlink %s: %w", p, err)
+		}
+		if err := os.Symlink(link, target); err != nil && !os.IsExist(err) {
+			return fmt.Errorf("symlink %s: %w", p, err)
+		}
+		return nil
+	}
+	if fi.IsDir() {
+		err = os.MkdirAll(target, 0o755)
+	} else {
+		err = touch(target)
+	}
+	if err != nil {
+		return fmt.Errorf("create mount point %s: %w", p, err)
+	}
+	if err := unix.Mount(p, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %w", p, err)
+	}
+	attr := &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY | unix.MOUNT_ATTR_NOSUID | unix.MOUNT_ATTR_NODEV}
+	if err := unix.MountSetattr(-1, target, unix.AT_RECURSIVE, attr); err != nil {
+		return fmt.Errorf("make %s read-only: %w", p, err)
+	}
+	return nil
+}
+
+// setupDev mounts a tmpfs /dev with the host's harmless character devices.
+func setupDev(dev string) error {
+	if err := os.Mkdir(dev, 0o755); err != nil {
+		return fmt.Errorf("mkdir /dev: %w", err)
+	}
+	if err := unix.Mount("tmpfs", dev, "tmpfs", unix.MS_NOSUID|unix.MS_NOEXEC, "mode=755,size=64k"); err != nil {
+		return fmt.Errorf("mount /dev: %w", err)
+	}
+	for _, name := range devices {
+		if _, err := os.Stat("/dev/" + name); err != nil {
+			continue
+		}
+		target := filepath.Join(dev, name)
+		if err := touch(target); err != nil {
+			return fmt.Errorf("create /dev/%s: %w", name, err)
+		}
+		if err := unix.Mount("/dev/"+name, target, "", unix.MS_BIND, ""); err != nil {
+			return fmt.Errorf("bind /dev/%s: %w", name, err)
+		}
+	}
+	for name, target := range map[string]string{"fd": "/proc/self/fd", "stdin": "/proc/self/fd/0", "stdout": "/proc/self/fd/1", "stderr": "/proc/self/fd/2"} {
+		if err := os.Symlink(target, filepath.Join(dev, name)); err != nil {
+			return fmt.Errorf("symlink /dev/%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func touch(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// loopbackUp brings up lo in the new network namespace.
+func loopbackUp() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	ifr, err := unix.NewIfreq("lo")
+	if err != nil {
+		return err
+	}
+	ifr.SetUint16(unix.IFF_UP | unix.IFF_LOOPBACK | unix.IFF_RUNNING)
+	return unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr)
+}
+
+// lookPath resolves argv[0] against PATH from env inside the new root.
+func lookPath(name string, env []string) (string, error) {
+	if filepath.IsAbs(name) || filepath.Base(name) != name {
+		return name, nil
+	}
+	path := "/usr/bin:/bin"
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, "PATH="); ok {
+			path = v
+		}
+	}
+	for _, dir := range filepath.SplitList(path) {
+		candidate := filepath.Join(dir, name)
+		if unix.Access(candidate, unix.X_OK) == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("exec %s: not found in PATH", name)
+}
+
+// Landlock access rights that modify the file system, by ABI version.
+const (
+	landlockWriteV1 = unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE | unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR | unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK | unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK | unix.LANDLOCK_ACCESS_FS_MAKE_SYM
+	landlockReferV2    = unix.LANDLOCK_ACCESS_FS_REFER
+	landlockTruncateV3 = unix.LANDLOCK_ACCESS_FS_TRUNCATE
+)
+
+// restrictWrites uses Landlock to allow file system changes only beneath
+// writable, and writes to existing files beneath devices. It backs up the
+// read-only mounts and is skipped on kernels without Landlock.
+func restrictWrites(writable, devices []string) error {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		if errno == unix.ENOSYS || errno == unix.EOPNOTSUPP {
+			return nil
+		}
+		return errno
+	}
+	handled := uint64(landlockWriteV1)
+	if abi >= 2 {
+		handled |= landlockReferV2
+	}
+	if abi >= 3 {
+		handled |= landlockTruncateV3
+	}
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	// Only access_fs is set; its size works with every ABI version.
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr.Access_fs), 0)
+	if errno != 0 {
+		return fmt.Errorf("create ruleset: %w", errno)
+	}
+	defer unix.Close(int(fd))
+
+	allow := func(path string, access uint64) error {
+		dirFD, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return fmt.Errorf("open %s: %w", path, err)
+		}
+		defer unix.Close(dirFD)
+		rule := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(dirFD)}
+		if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, fd, unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+			return fmt.Errorf("add rule for %s: %w", path, errno)
+		}
+		return nil
+	}
+	for _, p := range writable {
+		if err := allow(p, handled); err != nil {
+			return err
+		}
+	}
+	for _, p := range devices {
+		if err := allow(p, handled&(unix.LANDLOCK_ACCESS_FS_WRITE_FILE|landlockTruncateV3)); err != nil {
+			return err
+		}
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("restrict self: %w", errno)
+	}
+	return nil
+}
+
+// dropCapabilities empties the bounding, ambient and inheritable sets, so
+// the command keeps no capabilities in the namespace even as uid 0.
+func dropCapabilities() error {
+	last, err := os.ReadFile("/proc/sys/kernel/cap_last_cap")
+	if err != nil {
+		return fmt.Errorf("read cap_last_cap: %w", err)
+	}
+	var lastCap int
+	if _, err := fmt.Sscan(string(last), &lastCap); err != nil {
+		return fmt.Errorf("parse cap_last_cap: %w", err)
+	}
+	for c := 0; c <= lastCap; c++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil && err != unix.EINVAL {
+			return fmt.Errorf("drop capability %d: %w", c, err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil && err != unix.EINVAL {
+		return fmt.Errorf("clear ambient capabilities: %w", err)
+	}
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	return unix.Capset(&hdr, &data[0])
+}
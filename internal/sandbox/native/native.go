@@ -0,0 +1,104 @@
+// Package native runs commands in a Linux sandbox built from kernel
+// primitives, without a container runtime. Each command gets fresh user,
+// mount, pid, ipc, uts and (by default) network namespaces; a read-only root
+// assembled from bind mounts of the host's system directories; a writable
+// workspace at /workspace and a private /tmp; Landlock and seccomp
+// restrictions; and CPU, memory and pids limits from a cgroup v2 leaf.
+//
+// The sandbox re-executes the current binary to set up the namespaces, so
+// programs using it must call Init first thing in main (and tests in
+// TestMain).
+package native
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupported is returned on platforms without the kernel features the
+// sandbox needs.
+var ErrUnsupported = errors.New("native sandbox requires Linux")
+
+// WorkspaceDir is where the workspace is mounted inside the sandbox.
+const WorkspaceDir = "/workspace"
+
+// Config configures a Sandbox.
+type Config struct {
+	// Workspace is the host directory mounted read-write at /workspace.
+	Workspace string
+	// ReadOnlyPaths are host paths made visible read-only at the same place,
+	// in addition to the system directories (/usr, /bin, /lib, /etc, ...).
+	ReadOnlyPaths []string
+	// Network is "none" (default; only loopback) or "host".
+	Network string
+	// MemoryMB caps memory per command; default 512.
+	MemoryMB int64
+	// CPUs caps CPU time per command in cores, e.g. 0.5; default 1.
+	CPUs float64
+	// Pids caps the processes per command; default 128.
+	Pids int64
+	// Cgroup is the cgroup v2 directory sandbox cgroups are created under.
+	// Empty uses the current process's cgroup, which must be delegated
+	// (e.g. a systemd unit with Delegate=yes).
+	Cgroup string
+}
+
+// Command is a process to run in the sandbox.
+type Command struct {
+	Argv []string // e.g. {"/bin/sh", "-c", script}
+	Env  []string // the full environment; nothing is inherited
+	// Dir is the working directory: a path inside the sandbox, relative to
+	// /workspace, or a host path within the workspace. Default /workspace.
+	Dir string
+	// Workspace overrides the sandbox's workspace for this command.
+	Workspace string
+	Stdout    io.Writer
+	Stderr    io.Writer
+}
+
+// defaultEnv is the environment of commands run through Exec.
+var defaultEnv = []string{
+	"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+	"HOME=" + WorkspaceDir,
+	"TMPDIR=/tmp",
+	"LANG=C.UTF-8",
+}
+
+func (c *Config) setDefaults() {
+	if c.Network == "" {
+		c.Network = "none"
+	}
+	if c.MemoryMB <= 0 {
+		c.MemoryMB = 512
+	}
+	if c.CPUs <= 0 {
+		c.CPUs = 1
+	}
+	if c.Pids <= 0 {
+		c.Pids = 128
+	}
+}
+
+// sandboxDir maps a working directory to its path inside the sandbox. Host
+// paths outside the workspace and paths escaping it map to /workspace.
+func sandboxDir(workspace, dir string) string {
+	if dir == "" {
+		return WorkspaceDir
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(WorkspaceDir, dir)
+	} else if rel, err := filepath.Rel(filepath.Clean(workspace), filepath.Clean(dir)); err == nil && workspace != "" && !escapes(rel) {
+		dir = filepath.Join(WorkspaceDir, rel)
+	}
+	dir = filepath.Clean(dir)
+	if rel, err := filepath.Rel(WorkspaceDir, dir); err != nil || escapes(rel) {
+		return WorkspaceDir
+	}
+	return dir
+}
+
+func escapes(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, "../")
+}
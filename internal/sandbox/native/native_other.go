@@ -0,0 +1,22 @@
+//go:build !linux
+
+package native
+
+import "context"
+
+// Sandbox is unavailable outside Linux.
+type Sandbox struct{}
+
+// New returns ErrUnsupported.
+func New(Config) (*Sandbox, error) { return nil, ErrUnsupported }
+
+// Init does nothing outside Linux.
+func Init() {}
+
+// Exec returns ErrUnsupported.
+func (s *Sandbox) Exec(context.Context, string, string) (string, string, int, error) {
+	return "", "", -1, ErrUnsupported
+}
+
+// Run returns ErrUnsupported.
+func (s *Sandbox) Run(context.Context, Command) (int, error) { return -1, ErrUnsupported }
@@ -0,0 +1,32 @@
+package native
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	Init()
+	os.Exit(m.Run())
+}
+
+func TestSandboxDir(t *testing.T) {
+	tests := []struct {
+		workspace, dir, want string
+	}{
+		{"/home/u/ws", "", "/workspace"},
+		{"/home/u/ws", "src", "/workspace/src"},
+		{"/home/u/ws", "../etc", "/workspace"},
+		{"/home/u/ws", "/home/u/ws", "/workspace"},
+		{"/home/u/ws", "/home/u/ws/src/pkg", "/workspace/src/pkg"},
+		{"/home/u/ws", "/home/u/other", "/workspace"},
+		{"/home/u/ws", "/workspace/build", "/workspace/build"},
+		{"/home/u/ws", "/workspace/../etc", "/workspace"},
+		{"", "/home/u/ws", "/workspace"},
+	}
+	for _, tt := range tests {
+		if got := sandboxDir(tt.workspace, tt.dir); got != tt.want {
+			t.Errorf("sandboxDir(%q, %q) = %q, want %q", tt.workspace, tt.dir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package native
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Sandbox runs commands in fresh namespaces. It is safe for concurrent use;
+// every command gets its own namespaces and cgroup.
+type Sandbox struct {
+	cfg    Config
+	cgroup string // resolved parent cgroup
+}
+
+// New checks that the kernel supports the sandbox, prepares the parent
+// cgroup and runs a probe command.
+func New(cfg Config) (*Sandbox, error) {
+	cfg.setDefaults()
+	if cfg.Network != "none" && cfg.Network != "host" {
+		return nil, fmt.Errorf("native sandbox: network must be none or host, not %q", cfg.Network)
+	}
+	if cfg.Workspace != "" {
+		abs, err := filepath.Abs(cfg.Workspace)
+		if err != nil {
+			return nil, fmt.Errorf("native sandbox: workspace: %w", err)
+		}
+		if err := os.MkdirAll(abs, 0o755); err != nil {
+			return nil, fmt.Errorf("native sandbox: workspace: %w", err)
+		}
+		cfg.Workspace = abs
+	}
+	if auditArch == 0 {
+		return nil, fmt.Errorf("native sandbox: no seccomp filter for this architecture")
+	}
+	parent, err := prepareCgroupParent(cfg.Cgroup)
+	if err != nil {
+		return nil, fmt.Errorf("native sandbox: %w", err)
+	}
+	s := &Sandbox{cfg: cfg, cgroup: parent}
+
+	var stderr bytes.Buffer
+	code, err := s.Run(context.Background(), Command{Argv: []string{"/bin/sh", "-c", "exit 0"}, Env: defaultEnv, Stderr: &stderr})
+	if err != nil {
+		return nil, fmt.Errorf("native sandbox: probe: %w", err)
+	}
+	if code != 0 {
+		return nil, fmt.Errorf("native sandbox: probe exited %d: %s", code, strings.TrimSpace(stderr.String()))
+	}
+	return s, nil
+}
+
+// Exec runs cmd with /bin/sh -c in the sandbox. It implements
+// tools.Executor.
+func (s *Sandbox) Exec(ctx context.Context, cmd, workDir string) (stdout, stderr string, exitCode int, err error) {
+	var outBuf, errBuf bytes.Buffer
+	exitCode, err = s.Run(ctx, Command{
+		Argv:   []string{"/bin/sh", "-c", cmd},
+		Env:    defaultEnv,
+		Dir:    workDir,
+		Stdout: &outBuf,
+		Stderr: &errBuf,
+	})
+	if err == nil && exitCode == -1 && ctx.Err() != nil {
+		errBuf.WriteString("command timed out")
+	}
+	return outBuf.String(), errBuf.String(), exitCode, err
+}
+
+// Run runs c in the sandbox and returns its exit status, or -1 when it was
+// killed by a signal. err reports failures to set up the sandbox, not
+// failures of the command.
+func (s *Sandbox) Run(ctx context.Context, c Command) (int, error) {
+	if len(c.Argv) == 0 {
+		return -1, errors.New("empty command")
+	}
+	workspace := s.cfg.Workspace
+	if c.Workspace != "" {
+		abs, err := filepath.Abs(c.Workspace)
+		if err != nil {
+			return -1, fmt.Errorf("workspace: %w", err)
+		}
+		workspace = abs
+	}
+	spec := initSpec{
+		Argv:      c.Argv,
+		Env:       c.Env,
+		Dir:       sandboxDir(workspace, c.Dir),
+		Workspace: workspace,
+		ReadOnly:  append(append([]string(nil), systemPaths...), s.cfg.ReadOnlyPaths...),
+		Network:   s.cfg.Network == "host",
+	}
+	root, err := os.MkdirTemp("", "goclaw-sandbox-")
+	if err != nil {
+		return -1, fmt.Errorf("create root: %w", err)
+	}
+	defer os.Remove(root)
+	spec.Root = root
+
+	cg, err := newCgroup(s.cgroup, s.cfg)
+	if err != nil {
+		return -1, err
+	}
+	defer cg.remove()
+
+	// fd 3 carries the spec once the child is in its cgroup; fd 4 carries
+	// setup errors back and closes on the final exec.
+	specR, specW, err := os.Pipe()
+	if err != nil {
+		return -1, fmt.Errorf("spec pipe: %w", err)
+	}
+	defer specW.Close()
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		specR.Close()
+		return -1, fmt.Errorf("error pipe: %w", err)
+	}
+	defer errR.Close()
+
+	cmd := exec.CommandContext(ctx, "/proc/self/exe")
+	cmd.Args = []string{initArg}
+	cmd.Env = []string{}
+	cmd.Stdout, cmd.Stderr = c.Stdout, c.Stderr
+	cmd.ExtraFiles = []*os.File{specR, errW}
+	flags := syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if !spec.Network {
+		flags |= syscall.CLONE_NEWNET
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  uintptr(flags),
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		Pdeathsig:   syscall.SIGKILL,
+	}
+	err = cmd.Start()
+	specR.Close()
+	errW.Close()
+	if err != nil {
+		return -1, fmt.Errorf("start sandbox: %w", err)
+	}
+
+	if err := cg.add(cmd.Process.Pid); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return -1, err
+	}
+	if err := json.NewEncoder(specW).Encode(spec); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return -1, fmt.Errorf("send spec: %w", err)
+	}
+	specW.Close()
+	setupErr, _ := io.ReadAll(errR)
+
+	waitErr := cmd.Wait()
+	if len(setupErr) > 0 {
+		return -1, fmt.Errorf("sandbox setup: %s", strings.TrimSpace(string(setupErr)))
+	}
+	if waitErr != nil {
+		var exitErr *exec.ExitError
+		if errors.As(waitErr, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return -1, waitErr
+	}
+	return 0, nil
+}
@@ -0,0 +1,137 @@
+package native
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCgroup returns a directory that looks like a delegated cgroup v2
+// parent. Limits are written but not enforced.
+func fakeCgroup(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.controllers"), []byte("cpuset cpu io memory pids\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func newTestSandbox(t *testing.T, cfg Config) *Sandbox {
+	t.Helper()
+	if err := exec.Command("unshare", "--user", "--map-root-user", "true").Run(); err != nil {
+		t.Skipf("user namespaces unavailable: %v", err)
+	}
+	if cfg.Cgroup == "" {
+		cfg.Cgroup = fakeCgroup(t)
+	}
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s
+}
+
+func TestSandbox_Isolation(t *testing.T) {
+	ws := t.TempDir()
+	cg := fakeCgroup(t)
+	s := newTestSandbox(t, Config{Workspace: ws, Cgroup: cg, MemoryMB: 256, CPUs: 0.5, Pids: 64})
+
+	data, err := os.ReadFile(filepath.Join(cg, "cgroup.subtree_control"))
+	if err != nil || string(data) != "+cpu +memory +pids" {
+		t.Errorf("subtree_control = %q, %v", data, err)
+	}
+
+	tests := []struct {
+		name, cmd, dir string
+		wantOut        string
+		wantCode       int
+	}{
+		{"workspace is writable", "echo hi > note.txt && cat note.txt", "", "hi", 0},
+		{"host dir maps into workspace", "mkdir -p sub && pwd", ws, "/workspace", 0},
+		{"tmp is writable", "echo t > /tmp/x && cat /tmp/x", "", "t", 0},
+		{"etc is read-only", "touch /etc/goclaw 2>/dev/null || echo denied", "", "denied", 0},
+		{"root is read-only", "mkdir /newdir 2>/dev/null || echo denied", "", "denied", 0},
+		{"only loopback", "ls /sys/class/net 2>/dev/null || cat /proc/net/dev | tail -n +3 | cut -d: -f1 | tr -d ' '", "", "lo", 0},
+		{"own pid namespace", "echo $$", "", "1", 0},
+		{"hostname", "cat /proc/sys/kernel/hostname", "", "sandbox", 0},
+		{"no capabilities", "awk '/^CapEff/ {print $2}' /proc/self/status", "", "0000000000000000", 0},
+		{"seccomp filter", "awk '/^Seccomp:/ {print $2}' /proc/self/status", "", "2", 0},
+		{"no namespaces", "unshare -U true 2>/dev/null || echo denied", "", "denied", 0},
+		{"exit codes pass through", "exit 3", "", "", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code, err := s.Exec(context.Background(), tt.cmd, tt.dir)
+			if err != nil {
+				t.Fatalf("Exec: %v", err)
+			}
+			if code != tt.wantCode || strings.TrimSpace(stdout) != tt.wantOut {
+				t.Errorf("got %q (code %d, stderr %q), want %q (code %d)", stdout, code, stderr, tt.wantOut, tt.wantCode)
+			}
+		})
+	}
+
+	if data, err := os.ReadFile(filepath.Join(ws, "note.txt")); err != nil || string(data) != "hi\n" {
+		t.Errorf("workspace file = %q, %v", data, err)
+	}
+	if _, err := os.Stat("/etc/goclaw"); err == nil {
+		t.Error("sandbox wrote to the host /etc")
+	}
+	entries, _ := os.ReadDir(cg)
+	for _, e := range entries {
+		if e.IsDir() {
+			t.Errorf("cgroup %s left behind", e.Name())
+		}
+	}
+}
+
+func TestCgroup_Limits(t *testing.T) {
+	cg := fakeCgroup(t)
+	cfg := Config{MemoryMB: 256, CPUs: 0.5, Pids: 64}
+	leaf, err := newCgroup(cg, cfg)
+	if err != nil {
+		t.Fatalf("newCgroup: %v", err)
+	}
+	want := map[string]string{"memory.max": "268435456", "pids.max": "64", "cpu.max": "50000 100000"}
+	for file, value := range want {
+		data, err := os.ReadFile(filepath.Join(leaf.path, file))
+		if err != nil || string(data) != value {
+			t.Errorf("%s = %q, %v; want %q", file, data, err, value)
+		}
+	}
+	if err := leaf.add(1234); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(leaf.path, "cgroup.procs")); string(data) != "1234" {
+		t.Errorf("cgroup.procs = %q", data)
+	}
+	leaf.remove()
+	if _, err := os.Stat(leaf.path); !os.IsNotExist(err) {
+		t.Errorf("cgroup not removed: %v", err)
+	}
+}
+
+func TestSandbox_Timeout(t *testing.T) {
+	s := newTestSandbox(t, Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, stderr, code, err := s.Exec(ctx, "sleep 30", "")
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if code != -1 || !strings.Contains(stderr, "timed out") {
+		t.Errorf("code %d, stderr %q", code, stderr)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("timeout took %v", time.Since(start))
+	}
+}
@@ -0,0 +1,11 @@
+package native
+
+import "golang.org/x/sys/unix"
+
+const auditArch = unix.AUDIT_ARCH_X86_64
+
+// x32SyscallBit marks x32 ABI syscalls, which share the x86-64 audit arch
+// and are refused wholesale.
+const x32SyscallBit = 0x40000000
+
+var archDeniedSyscalls = []uintptr{unix.SYS_IOPL, unix.SYS_IOPERM, unix.SYS_USELIB, unix.SYS_CREATE_MODULE}
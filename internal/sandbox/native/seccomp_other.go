@@ -0,0 +1,11 @@
+//go:build linux && !amd64 && !arm64
+
+package native
+
+// No seccomp filter is defined for this architecture, so New refuses to
+// create a sandbox.
+const auditArch = 0
+
+const x32SyscallBit = 0
+
+var archDeniedSyscalls []uintptr
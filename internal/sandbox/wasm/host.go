@@ -16,6 +16,7 @@ import (
 	"github.com/basket/go-claw/internal/audit"
 	"github.com/basket/go-claw/internal/persistence"
 	"github.com/basket/go-claw/internal/policy"
+	"github.com/basket/go-claw/internal/shared"
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/sys"
@@ -82,6 +83,7 @@ type Host struct {
 	modules              map[string]api.Module
 	moduleMemoryPages    map[string]uint32
 	aggregateMemoryLimit uint32
+	tools                map[string]*toolModule // modules implementing the tool ABI
 }
 
 func NewHost(ctx context.Context, cfg Config) (*Host, error) {
@@ -120,6 +122,7 @@ func NewHost(ctx context.Context, cfg Config) (*Host, error) {
 		modules:              map[string]api.Module{},
 		moduleMemoryPages:    map[string]uint32{},
 		aggregateMemoryLimit: aggLimit,
+		tools:                map[string]*toolModule{},
 	}
 
 	builder := h.runtime.NewHostModuleBuilder("host")
@@ -148,6 +151,7 @@ func (h *Host) Close(ctx context.Context) error {
 		_ = module.Close(ctx)
 		delete(h.modules, name)
 		delete(h.moduleMemoryPages, name)
+		delete(h.tools, name)
 	}
 	h.modulesMu.Unlock()
 	return h.runtime.Close(ctx)
@@ -271,6 +275,20 @@ func (h *Host) HTTPGet(ctx context.Context, rawURL string) (string, error) {
 		return "", fmt.Errorf("policy denied host.http.get for url %q", rawURL)
 	}
 	audit.Record("allow", "wasm.http.get", "url_allowed", h.policy.PolicyVersion(), rawURL)
+	maxBody := int64(1 << 20)
+	if ac, ok := h.policy.(policy.ArgChecker); ok {
+		d := ac.CheckToolArgs(shared.AgentID(ctx), policy.ToolCall{Tool: policy.ToolHTTPGet, Method: http.MethodGet})
+		if d.Rule != "" {
+			if !d.Allowed {
+				audit.RecordRule("deny", "wasm.http.get", d.Reason, h.policy.PolicyVersion(), rawURL, d.Rule)
+				return "", fmt.Errorf("policy rule %q denied host.http.get: %s", d.Rule, d.Reason)
+			}
+			audit.RecordRule("allow", "wasm.http.get", d.Reason, h.policy.PolicyVersion(), rawURL, d.Rule)
+			if limit := int64(d.MaxOutputBytes); limit > 0 && limit < maxBody {
+				maxBody = limit
+			}
+		}
+	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
 	if err != nil {
 		return "", err
@@ -282,7 +300,7 @@ func (h *Host) HTTPGet(ctx context.Context, rawURL string) (string, error) {
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
 	if err != nil {
 		return "", err
 	}
@@ -340,6 +358,7 @@ func (h *Host) LoadModuleFromBytes(ctx context.Context, name string, wasmBytes [
 		_ = old.Close(ctx)
 		delete(h.modules, name)
 		delete(h.moduleMemoryPages, name)
+		delete(h.tools, name)
 	}
 	h.modulesMu.Unlock()
 
@@ -363,10 +382,25 @@ func (h *Host) LoadModuleFromBytes(ctx context.Context, name string, wasmBytes [
 		actualPages = 1
 	}
 
+	// Modules exporting the tool ABI publish a manifest. An invalid manifest
+	// leaves the module loaded as a plain skill.
+	var tool *toolModule
+	if exportsToolABI(module) {
+		manifest, err := h.readToolManifest(ctx, name, module)
+		if err != nil {
+			h.logger.Warn("wasm tool manifest rejected", "module", name, "error", err)
+		} else {
+			tool = &toolModule{manifest: manifest}
+		}
+	}
+
 	h.modulesMu.Lock()
 	defer h.modulesMu.Unlock()
 	h.modules[name] = module
 	h.moduleMemoryPages[name] = actualPages
+	if tool != nil {
+		h.tools[name] = tool
+	}
 
 	// Recalculate aggregate for logging.
 	var aggregate uint32
@@ -374,7 +408,8 @@ func (h *Host) LoadModuleFromBytes(ctx context.Context, name string, wasmBytes [
 		aggregate += pages
 	}
 	h.logger.Info("wasm module loaded", "module", name, "path", source,
-		"memory_pages", actualPages, "aggregate_pages", aggregate, "limit_pages", h.aggregateMemoryLimit)
+		"memory_pages", actualPages, "aggregate_pages", aggregate, "limit_pages", h.aggregateMemoryLimit,
+		"tool_abi", tool != nil)
 	return nil
 }
 
@@ -0,0 +1,248 @@
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ToolABIVersion is the current JSON-in/JSON-out tool ABI version.
+// GC-SPEC-SKL-002: Modules declaring another version are not activated as tools.
+//
+// A tool module exports:
+//
+//	memory                  linear memory shared with the host
+//	alloc(size i32) i32     returns a guest buffer of at least size bytes
+//	manifest() i64          packed (ptr<<32 | len) of the ToolManifest JSON
+//	call(ptr i32, len i32) i64
+//	                        reads the JSON input at ptr/len and returns the
+//	                        packed location of a ToolResult JSON envelope
+//
+// Modules that do not export manifest and call are still loaded as plain
+// skills and remain reachable through InvokeModuleRandom.
+const ToolABIVersion = 1
+
+// Fault reason codes specific to the tool ABI (GC-SPEC-SKL-005).
+const (
+	FaultABIMismatch = "WASM_ABI_MISMATCH"
+	FaultBadOutput   = "WASM_BAD_OUTPUT"
+)
+
+// MaxToolIOBytes caps the size of tool input, output and manifest (1 MiB).
+const MaxToolIOBytes = 1 << 20
+
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]{0,63}$`)
+
+// ToolManifest describes a tool exported by a WASM module.
+type ToolManifest struct {
+	ABIVersion  int             `json:"abi_version"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+}
+
+// ToolResult is the envelope returned by a module's call export.
+// A non-empty Error reports a tool-level failure (not a fault).
+type ToolResult struct {
+	Output json.RawMessage `json:"output,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// toolModule tracks a loaded module that implements the tool ABI.
+// Calls are serialized because alloc/call share guest memory state.
+type toolModule struct {
+	manifest ToolManifest
+	mu       sync.Mutex
+}
+
+func (m ToolManifest) validate() error {
+	if m.ABIVersion != ToolABIVersion {
+		return fmt.Errorf("abi_version %d not supported (want %d)", m.ABIVersion, ToolABIVersion)
+	}
+	if !toolNamePattern.MatchString(m.Name) {
+		return fmt.Errorf("invalid tool name %q", m.Name)
+	}
+	if len(m.InputSchema) > 0 {
+		var schema map[string]any
+		if err := json.Unmarshal(m.InputSchema, &schema); err != nil {
+			return fmt.Errorf("input_schema must be a JSON object: %w", err)
+		}
+	}
+	return nil
+}
+
+// exportsToolABI reports whether the module exports the tool ABI entrypoints.
+func exportsToolABI(module api.Module) bool {
+	return module.ExportedFunction("manifest") != nil && module.ExportedFunction("call") != nil
+}
+
+// readToolManifest invokes the module's manifest export and validates it.
+func (h *Host) readToolManifest(ctx context.Context, name string, module api.Module) (ToolManifest, error) {
+	callCtx, cancel := context.WithTimeout(ctx, h.invokeTimeout)
+	defer cancel()
+
+	results, err := module.ExportedFunction("manifest").Call(callCtx)
+	if err != nil {
+		return ToolManifest{}, classifyFault(name, err)
+	}
+	if len(results) == 0 {
+		return ToolManifest{}, &SkillFault{Reason: FaultABIMismatch, Module: name, Detail: "manifest returned no value"}
+	}
+	data, err := readPacked(module, results[0])
+	if err != nil {
+		return ToolManifest{}, &SkillFault{Reason: FaultBadOutput, Module: name, Detail: "manifest: " + err.Error()}
+	}
+	var manifest ToolManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return ToolManifest{}, &SkillFault{Reason: FaultBadOutput, Module: name, Detail: "manifest is not valid JSON: " + err.Error()}
+	}
+	if err := manifest.validate(); err != nil {
+		return ToolManifest{}, &SkillFault{Reason: FaultABIMismatch, Module: name, Detail: err.Error()}
+	}
+	return manifest, nil
+}
+
+// readPacked reads guest memory addressed by a packed (ptr<<32 | len) value.
+func readPacked(module api.Module, packed uint64) ([]byte, error) {
+	ptr := uint32(packed >> 32)
+	length := uint32(packed)
+	if length > MaxToolIOBytes {
+		return nil, fmt.Errorf("output of %d bytes exceeds limit of %d", length, MaxToolIOBytes)
+	}
+	mem := module.Memory()
+	if mem == nil {
+		return nil, fmt.Errorf("module does not export memory")
+	}
+	data, ok := mem.Read(ptr, length)
+	if !ok {
+		return nil, fmt.Errorf("out-of-bounds read ptr=%d len=%d", ptr, length)
+	}
+	// Copy out: the view aliases guest memory, which the next call may overwrite.
+	return append([]byte(nil), data...), nil
+}
+
+// ToolManifest returns the manifest of a loaded tool module.
+func (h *Host) ToolManifest(moduleName string) (ToolManifest, bool) {
+	h.modulesMu.Lock()
+	defer h.modulesMu.Unlock()
+	tm, ok := h.tools[moduleName]
+	if !ok {
+		return ToolManifest{}, false
+	}
+	return tm.manifest, true
+}
+
+// ToolModules returns the names of loaded modules implementing the tool ABI, sorted.
+func (h *Host) ToolModules() []string {
+	h.modulesMu.Lock()
+	defer h.modulesMu.Unlock()
+	names := make([]string, 0, len(h.tools))
+	for name := range h.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InvokeTool calls a tool module with JSON input and returns its JSON output.
+// Quarantine, the per-invocation timeout and fault classification apply as
+// for InvokeModuleRandom. A ToolResult.Error from the guest is returned as
+// a plain error and is not counted as a fault.
+func (h *Host) InvokeTool(ctx context.Context, moduleName string, input json.RawMessage) (json.RawMessage, error) {
+	// GC-SPEC-SKL-007: Check quarantine before invocation.
+	if h.store != nil {
+		if quarantined, err := h.store.IsSkillQuarantined(ctx, moduleName); err == nil && quarantined {
+			h.logger.Warn("skill quarantined, invocation denied", "module", moduleName)
+			return nil, &SkillFault{Reason: FaultQuarantined, Module: moduleName, Detail: "skill quarantined due to repeated faults"}
+		}
+	}
+
+	h.modulesMu.Lock()
+	module, ok := h.modules[moduleName]
+	tm, isTool := h.tools[moduleName]
+	h.modulesMu.Unlock()
+	if !ok {
+		return nil, &SkillFault{Reason: FaultModuleNotFound, Module: moduleName, Detail: "module not loaded"}
+	}
+	if !isTool {
+		return nil, &SkillFault{Reason: FaultNoExport, Module: moduleName, Detail: "module does not implement the tool ABI"}
+	}
+
+	if len(input) == 0 {
+		input = json.RawMessage("{}")
+	}
+	if len(input) > MaxToolIOBytes {
+		return nil, fmt.Errorf("tool input of %d bytes exceeds limit of %d", len(input), MaxToolIOBytes)
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	// GC-SPEC-SKL-005: Enforce per-invocation time limit.
+	invokeCtx, cancel := context.WithTimeout(ctx, h.invokeTimeout)
+	defer cancel()
+
+	out, err := h.callTool(invokeCtx, moduleName, module, input)
+	if err != nil {
+		if fault, ok := err.(*SkillFault); ok && fault.Reason != FaultNoExport {
+			h.logger.Warn("skill invocation fault", "module", moduleName, "fn", "call", "reason", fault.Reason)
+			h.recordSkillFault(ctx, moduleName)
+		}
+		return nil, err
+	}
+
+	var result ToolResult
+	if err := json.Unmarshal(out, &result); err != nil {
+		h.recordSkillFault(ctx, moduleName)
+		return nil, &SkillFault{Reason: FaultBadOutput, Module: moduleName, Detail: "output is not a valid result envelope: " + err.Error()}
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("tool %s: %s", tm.manifest.Name, result.Error)
+	}
+	if len(result.Output) == 0 {
+		return json.RawMessage("null"), nil
+	}
+	return result.Output, nil
+}
+
+// callTool copies input into guest memory and invokes the call export.
+func (h *Host) callTool(ctx context.Context, moduleName string, module api.Module, input []byte) ([]byte, error) {
+	allocFn := module.ExportedFunction("alloc")
+	if allocFn == nil {
+		return nil, &SkillFault{Reason: FaultNoExport, Module: moduleName, Detail: "no alloc export"}
+	}
+	mem := module.Memory()
+	if mem == nil {
+		return nil, &SkillFault{Reason: FaultNoExport, Module: moduleName, Detail: "no memory export"}
+	}
+
+	res, err := allocFn.Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, classifyFault(moduleName, err)
+	}
+	if len(res) == 0 {
+		return nil, &SkillFault{Reason: FaultABIMismatch, Module: moduleName, Detail: "alloc returned no value"}
+	}
+	ptr := uint32(res[0])
+	if !mem.Write(ptr, input) {
+		return nil, &SkillFault{Reason: FaultMemoryExceeded, Module: moduleName, Detail: fmt.Sprintf("out-of-bounds input write ptr=%d len=%d", ptr, len(input))}
+	}
+
+	res, err = module.ExportedFunction("call").Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return nil, classifyFault(moduleName, err)
+	}
+	if len(res) == 0 {
+		return nil, &SkillFault{Reason: FaultABIMismatch, Module: moduleName, Detail: "call returned no value"}
+	}
+	out, err := readPacked(module, res[0])
+	if err != nil {
+		return nil, &SkillFault{Reason: FaultBadOutput, Module: moduleName, Detail: err.Error()}
+	}
+	return out, nil
+}
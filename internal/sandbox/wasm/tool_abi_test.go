@@ -0,0 +1,302 @@
+package wasm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/policy"
+	"github.com/basket/go-claw/internal/sandbox/wasm"
+)
+
+// Guest memory layout used by the hand-assembled tool modules below.
+const (
+	abiManifestOffset = 0    // manifest JSON
+	abiErrorOffset    = 512  // {"error":"boom"}
+	abiOutputOffset   = 1024 // `{"output":` prefix; input is allocated right after it
+)
+
+const abiOutputPrefix = `{"output":`
+
+var abiErrorEnvelope = `{"error":"boom"}`
+
+// Call bodies (locals vector + instructions) for the call(ptr,len) export.
+var (
+	// callEcho appends '}' after the input and returns {"output":<input>}.
+	callEcho = concat(
+		[]byte{0x00},
+		[]byte{0x20, 0x00, 0x20, 0x01, 0x6a}, // local.get 0; local.get 1; i32.add
+		[]byte{0x41}, sleb(int64('}')),       // i32.const '}'
+		[]byte{0x3a, 0x00, 0x00},                       // i32.store8
+		[]byte{0x42}, sleb(int64(abiOutputOffset)<<32), // i64.const offset<<32
+		[]byte{0x20, 0x01, 0x41}, sleb(int64(len(abiOutputPrefix)+1)), // local.get 1; i32.const prefix+1
+		[]byte{0x6a, 0xad, 0x84, 0x0b}, // i32.add; i64.extend_i32_u; i64.or; end
+	)
+	// callError returns the error envelope.
+	callError = concat([]byte{0x00, 0x42}, sleb(int64(abiErrorOffset)<<32|int64(len(abiErrorEnvelope))), []byte{0x0b})
+	// callTrap executes unreachable.
+	callTrap = []byte{0x00, 0x00, 0x0b}
+	// callLoop spins forever.
+	callLoop = []byte{0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x42, 0x00, 0x0b}
+)
+
+// buildToolModule assembles a WASM module implementing the tool ABI.
+func buildToolModule(manifest string, callBody []byte) []byte {
+	types := concat(uleb(3),
+		[]byte{0x60, 0x01, 0x7f, 0x01, 0x7f},       // (i32) -> i32
+		[]byte{0x60, 0x00, 0x01, 0x7e},             // () -> i64
+		[]byte{0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e}, // (i32, i32) -> i64
+	)
+	funcs := concat(uleb(3), []byte{0x00, 0x01, 0x02})
+	memory := concat(uleb(1), []byte{0x00, 0x01}) // min 1 page
+	exports := concat(uleb(4),
+		name("memory"), []byte{0x02, 0x00},
+		name("alloc"), []byte{0x00, 0x00},
+		name("manifest"), []byte{0x00, 0x01},
+		name("call"), []byte{0x00, 0x02},
+	)
+	allocBody := concat([]byte{0x00, 0x41}, sleb(int64(abiOutputOffset+len(abiOutputPrefix))), []byte{0x0b})
+	manifestBody := concat([]byte{0x00, 0x42}, sleb(int64(abiManifestOffset)<<32|int64(len(manifest))), []byte{0x0b})
+	code := concat(uleb(3), vec(allocBody), vec(manifestBody), vec(callBody))
+	data := concat(uleb(3),
+		dataSegment(abiManifestOffset, manifest),
+		dataSegment(abiErrorOffset, abiErrorEnvelope),
+		dataSegment(abiOutputOffset, abiOutputPrefix),
+	)
+
+	return concat(
+		[]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00},
+		section(1, types), section(3, funcs), section(5, memory),
+		section(7, exports), section(10, code), section(11, data),
+	)
+}
+
+func dataSegment(offset int, s string) []byte {
+	return concat([]byte{0x00, 0x41}, sleb(int64(offset)), []byte{0x0b}, name(s))
+}
+
+func section(id byte, payload []byte) []byte { return concat([]byte{id}, vec(payload)) }
+func vec(b []byte) []byte                    { return concat(uleb(uint64(len(b))), b) }
+func name(s string) []byte                   { return vec([]byte(s)) }
+
+func concat(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+func uleb(v uint64) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if v == 0 {
+			return out
+		}
+	}
+}
+
+func sleb(v int64) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		done := (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0)
+		if !done {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if done {
+			return out
+		}
+	}
+}
+
+const echoManifest = `{"abi_version":1,"name":"echo","description":"Echo the input back","input_schema":{"type":"object","properties":{"text":{"type":"string"}}}}`
+
+func newToolTestHost(t *testing.T, cfg wasm.Config) (*wasm.Host, *persistence.Store) {
+	t.Helper()
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "goclaw.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	cfg.Store = store
+	cfg.Policy = policy.Default()
+	h, err := wasm.NewHost(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("new host: %v", err)
+	}
+	t.Cleanup(func() { _ = h.Close(context.Background()) })
+	return h, store
+}
+
+func TestHost_ToolABI_ManifestAndCall(t *testing.T) {
+	h, _ := newToolTestHost(t, wasm.Config{})
+	ctx := context.Background()
+
+	if err := h.LoadModuleFromBytes(ctx, "echo", buildToolModule(echoManifest, callEcho), "test"); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	manifest, ok := h.ToolManifest("echo")
+	if !ok {
+		t.Fatal("expected echo to be registered as a tool module")
+	}
+	if manifest.Name != "echo" || manifest.Description != "Echo the input back" || manifest.ABIVersion != wasm.ToolABIVersion {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	if got := h.ToolModules(); len(got) != 1 || got[0] != "echo" {
+		t.Fatalf("ToolModules = %v", got)
+	}
+
+	for _, input := range []string{`{"text":"hello"}`, `{"text":"second call"}`} {
+		out, err := h.InvokeTool(ctx, "echo", json.RawMessage(input))
+		if err != nil {
+			t.Fatalf("InvokeTool: %v", err)
+		}
+		if string(out) != input {
+			t.Fatalf("InvokeTool output = %s, want %s", out, input)
+		}
+	}
+}
+
+func TestHost_ToolABI_ManifestValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+	}{
+		{"wrong version", `{"abi_version":2,"name":"echo"}`},
+		{"missing name", `{"abi_version":1,"description":"x"}`},
+		{"bad name", `{"abi_version":1,"name":"has space"}`},
+		{"schema not object", `{"abi_version":1,"name":"echo","input_schema":[1]}`},
+		{"not json", `not json`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newToolTestHost(t, wasm.Config{})
+			if err := h.LoadModuleFromBytes(context.Background(), "echo", buildToolModule(tt.manifest, callEcho), "test"); err != nil {
+				t.Fatalf("load should succeed as a plain skill: %v", err)
+			}
+			if !h.HasModule("echo") {
+				t.Fatal("module should remain loaded")
+			}
+			if _, ok := h.ToolManifest("echo"); ok {
+				t.Fatal("invalid manifest should not register a tool")
+			}
+			_, err := h.InvokeTool(context.Background(), "echo", nil)
+			var fault *wasm.SkillFault
+			if !errors.As(err, &fault) || fault.Reason != wasm.FaultNoExport {
+				t.Fatalf("expected %s fault, got %v", wasm.FaultNoExport, err)
+			}
+		})
+	}
+}
+
+func TestHost_ToolABI_GuestError(t *testing.T) {
+	h, store := newToolTestHost(t, wasm.Config{})
+	ctx := context.Background()
+	if err := h.LoadModuleFromBytes(ctx, "failing", buildToolModule(strings.Replace(echoManifest, `"echo"`, `"failing"`, 1), callError), "test"); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	_, err := h.InvokeTool(ctx, "failing", json.RawMessage(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected guest error, got %v", err)
+	}
+	var fault *wasm.SkillFault
+	if errors.As(err, &fault) {
+		t.Fatalf("guest-reported error must not be a fault: %v", err)
+	}
+	if quarantined, _ := store.IsSkillQuarantined(ctx, "failing"); quarantined {
+		t.Fatal("guest-reported errors must not quarantine the skill")
+	}
+}
+
+func TestHost_ToolABI_TrapRecordsFaultAndQuarantines(t *testing.T) {
+	h, store := newToolTestHost(t, wasm.Config{})
+	ctx := context.Background()
+
+	if err := store.UpsertSkill(ctx, "trap", "1", "v1", "hash"); err != nil {
+		t.Fatalf("upsert skill: %v", err)
+	}
+
+	// A trap leaves the instance unusable, so reload between calls as a rebuild would.
+	var lastErr error
+	for i := 0; i < 10; i++ {
+		if err := h.LoadModuleFromBytes(ctx, "trap", buildToolModule(echoManifest, callTrap), "test"); err != nil {
+			t.Fatalf("load: %v", err)
+		}
+		_, lastErr = h.InvokeTool(ctx, "trap", json.RawMessage(`{}`))
+		var fault *wasm.SkillFault
+		if !errors.As(lastErr, &fault) {
+			t.Fatalf("expected SkillFault, got %v", lastErr)
+		}
+		if fault.Reason == wasm.FaultQuarantined {
+			break
+		}
+		if fault.Reason != wasm.FaultExecError {
+			t.Fatalf("reason = %s, want %s", fault.Reason, wasm.FaultExecError)
+		}
+	}
+	if quarantined, _ := store.IsSkillQuarantined(ctx, "trap"); !quarantined {
+		t.Fatalf("expected skill to be quarantined after repeated faults, last error: %v", lastErr)
+	}
+}
+
+func TestHost_ToolABI_Timeout(t *testing.T) {
+	h, _ := newToolTestHost(t, wasm.Config{InvokeTimeout: 100 * time.Millisecond})
+	ctx := context.Background()
+	if err := h.LoadModuleFromBytes(ctx, "spin", buildToolModule(echoManifest, callLoop), "test"); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	_, err := h.InvokeTool(ctx, "spin", json.RawMessage(`{}`))
+	var fault *wasm.SkillFault
+	if !errors.As(err, &fault) || fault.Reason != wasm.FaultTimeout {
+		t.Fatalf("expected %s fault, got %v", wasm.FaultTimeout, err)
+	}
+}
+
+func TestHost_ToolABI_ReloadReplacesManifest(t *testing.T) {
+	h, _ := newToolTestHost(t, wasm.Config{})
+	ctx := context.Background()
+	if err := h.LoadModuleFromBytes(ctx, "echo", buildToolModule(echoManifest, callEcho), "test"); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	updated := strings.Replace(echoManifest, "Echo the input back", "Echo v2", 1)
+	if err := h.LoadModuleFromBytes(ctx, "echo", buildToolModule(updated, callEcho), "test"); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if m, _ := h.ToolManifest("echo"); m.Description != "Echo v2" {
+		t.Fatalf("description = %q, want Echo v2", m.Description)
+	}
+
+	// Replacing with a module without the ABI drops the tool.
+	plain := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	if err := h.LoadModuleFromBytes(ctx, "echo", plain, "test"); err != nil {
+		t.Fatalf("reload plain: %v", err)
+	}
+	if _, ok := h.ToolManifest("echo"); ok {
+		t.Fatal("tool manifest should be removed when the module no longer exports the ABI")
+	}
+}
+
+func TestHost_ToolABI_FixtureUpToDate(t *testing.T) {
+	// testdata/echo_tool.wasm is used by the tools package; keep it in sync.
+	want := buildToolModule(echoManifest, callEcho)
+	got, err := os.ReadFile(filepath.Join("testdata", "echo_tool.wasm"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("testdata/echo_tool.wasm is stale; regenerate it from buildToolModule(echoManifest, callEcho)")
+	}
+}
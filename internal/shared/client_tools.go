@@ -0,0 +1,88 @@
+package shared
+
+import (
+	"context"
+	"sync"
+)
+
+type clientToolsKey struct{}
+
+// Tool choices of a request with client tools, as in the OpenAI API.
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceRequired = "required"
+	ToolChoiceNone     = "none"
+)
+
+// ClientTool is a function tool defined by an API client. The model may call
+// it, but GoClaw does not run it: the call is handed back to the client, which
+// sends the result in a follow-up request.
+type ClientTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"` // JSON Schema of the arguments
+}
+
+// ClientToolCall is a call of a client tool made by the model.
+type ClientToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON object
+}
+
+// ClientToolResult is the client's result of one ClientToolCall.
+type ClientToolResult struct {
+	CallID  string `json:"call_id"`
+	Content string `json:"content"`
+}
+
+// ClientToolRound is one exchange of the current turn: the calls the model
+// made (with any text it wrote alongside) and the results the client sent.
+type ClientToolRound struct {
+	Text    string             `json:"text,omitempty"`
+	Calls   []ClientToolCall   `json:"calls"`
+	Results []ClientToolResult `json:"results"`
+}
+
+// ClientTools is the client tool state of one request. It is serialized into
+// queued task payloads; the pending calls are filled in by the brain.
+type ClientTools struct {
+	Tools []ClientTool `json:"tools"`
+	// Choice is ToolChoiceAuto (the default), ToolChoiceRequired or
+	// ToolChoiceNone, which disables every tool for the request.
+	Choice string `json:"choice,omitempty"`
+	// NoBuiltinTools exposes only the client tools, not the agent's own.
+	NoBuiltinTools bool `json:"no_builtin_tools,omitempty"`
+	// Rounds continue a turn that stopped on client tool calls.
+	Rounds []ClientToolRound `json:"rounds,omitempty"`
+
+	mu      sync.Mutex
+	pending []ClientToolCall
+}
+
+// SetPending records the client tool calls the model stopped on.
+func (c *ClientTools) SetPending(calls []ClientToolCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = calls
+}
+
+// Pending returns the client tool calls the model stopped on, if any.
+func (c *ClientTools) Pending() []ClientToolCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pending
+}
+
+// WithClientTools attaches the client tools of a request to the context.
+func WithClientTools(ctx context.Context, tools *ClientTools) context.Context {
+	return context.WithValue(ctx, clientToolsKey{}, tools)
+}
+
+// GetClientTools extracts the client tools from context. Returns nil if absent.
+func GetClientTools(ctx context.Context) *ClientTools {
+	if v, ok := ctx.Value(clientToolsKey{}).(*ClientTools); ok {
+		return v
+	}
+	return nil
+}
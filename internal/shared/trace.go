@@ -14,6 +14,9 @@ type runIDKey struct{}
 type delegationHopKey struct{}
 type messageDepthKey struct{}
 type samplingConfigKey struct{}
+type maxToolTurnsKey struct{}
+type attachmentsKey struct{}
+type apiKeyIDKey struct{}
 
 // WithTraceID attaches a trace_id to the context.
 func WithTraceID(ctx context.Context, traceID string) context.Context {
@@ -116,6 +119,20 @@ func MessageDepth(ctx context.Context) int {
 	return 0
 }
 
+// WithMaxToolTurns attaches a per-request override of the agent's tool-call
+// turn budget to the context.
+func WithMaxToolTurns(ctx context.Context, turns int) context.Context {
+	return context.WithValue(ctx, maxToolTurnsKey{}, turns)
+}
+
+// MaxToolTurns extracts the per-request tool-call turn budget (0 if absent).
+func MaxToolTurns(ctx context.Context) int {
+	if v, ok := ctx.Value(maxToolTurnsKey{}).(int); ok {
+		return v
+	}
+	return 0
+}
+
 // SamplingConfig holds per-request LLM sampling parameters.
 type SamplingConfig struct {
 	Temperature     *float64
@@ -138,4 +155,40 @@ func GetSamplingConfig(ctx context.Context) *SamplingConfig {
 	return nil
 }
 
+// Attachment is an image or document sent along with a chat message.
+type Attachment struct {
+	MimeType string `json:"mime_type"`
+	Name     string `json:"name,omitempty"`
+	Data     []byte `json:"-"`
+}
+
+// WithAttachments attaches the files of the message being submitted to the
+// context, so the task intake can store them with the message.
+func WithAttachments(ctx context.Context, atts []Attachment) context.Context {
+	return context.WithValue(ctx, attachmentsKey{}, atts)
+}
+
+// Attachments extracts the message attachments from context. Returns nil if absent.
+func Attachments(ctx context.Context) []Attachment {
+	if v, ok := ctx.Value(attachmentsKey{}).([]Attachment); ok {
+		return v
+	}
+	return nil
+}
+
+// WithAPIKeyID attaches the ID of the gateway API key a request was
+// authenticated with to the context, so its tasks count against the key's
+// spend budget.
+func WithAPIKeyID(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, apiKeyIDKey{}, keyID)
+}
+
+// APIKeyID extracts the gateway API key ID from context. Returns "" if absent.
+func APIKeyID(ctx context.Context) string {
+	if v, ok := ctx.Value(apiKeyIDKey{}).(string); ok {
+		return v
+	}
+	return ""
+}
+
 const DefaultAgentID = "default"
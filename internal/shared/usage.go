@@ -0,0 +1,59 @@
+package shared
+
+import (
+	"context"
+	"sync"
+)
+
+type usageMeterKey struct{}
+
+// TokenUsage is the token usage and cost of model calls. CachedTokens is the
+// part of InputTokens served from the provider's cache; ReasoningTokens the
+// part of OutputTokens spent on reasoning.
+type TokenUsage struct {
+	InputTokens     int
+	OutputTokens    int
+	CachedTokens    int
+	ReasoningTokens int
+	CostUSD         float64
+}
+
+// UsageMeter sums the usage of the model calls made for a task. It is safe
+// for concurrent use.
+type UsageMeter struct {
+	mu    sync.Mutex
+	total TokenUsage
+	calls int
+}
+
+// Add records the usage of one model call.
+func (m *UsageMeter) Add(u TokenUsage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.total.InputTokens += u.InputTokens
+	m.total.OutputTokens += u.OutputTokens
+	m.total.CachedTokens += u.CachedTokens
+	m.total.ReasoningTokens += u.ReasoningTokens
+	m.total.CostUSD += u.CostUSD
+	m.calls++
+}
+
+// Total returns the summed usage and the number of model calls recorded.
+func (m *UsageMeter) Total() (TokenUsage, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.total, m.calls
+}
+
+// WithUsageMeter attaches a usage meter to the context.
+func WithUsageMeter(ctx context.Context, m *UsageMeter) context.Context {
+	return context.WithValue(ctx, usageMeterKey{}, m)
+}
+
+// GetUsageMeter extracts the usage meter from context. Returns nil if absent.
+func GetUsageMeter(ctx context.Context) *UsageMeter {
+	if v, ok := ctx.Value(usageMeterKey{}).(*UsageMeter); ok {
+		return v
+	}
+	return nil
+}
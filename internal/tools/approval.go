@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/basket/go-claw/internal/approval"
+	"github.com/basket/go-claw/internal/audit"
+	"github.com/basket/go-claw/internal/policy"
+	"github.com/basket/go-claw/internal/shared"
+)
+
+// ApprovalBroker parks a tool call until a human decides on it
+// (GC-SPEC-SEC-008). *approval.Broker implements it.
+type ApprovalBroker interface {
+	Request(ctx context.Context, req approval.Request) (approval.Decision, error)
+}
+
+// awaitApproval blocks on a human decision when the policy lists tool under
+// require_approval. A non-empty result means the call must not run: tools
+// return it to the model as their output, because a tool error would abort
+// the whole turn instead of letting the model adapt to the denial.
+func (r *Registry) awaitApproval(ctx context.Context, tool string, args any) (string, error) {
+	ac, ok := r.Policy.(policy.ApprovalChecker)
+	if !ok || !ac.RequiresApproval(tool) {
+		return "", nil
+	}
+	if r.Approvals == nil {
+		audit.Record("deny", "approval.tool", "approval_unavailable", policyVersion(r.Policy), tool)
+		return fmt.Sprintf("%s requires human approval but no approver is configured", tool), nil
+	}
+	d, err := r.Approvals.Request(ctx, approval.Request{
+		TaskID:    shared.TaskID(ctx),
+		RunID:     shared.RunID(ctx),
+		SessionID: shared.SessionID(ctx),
+		AgentID:   shared.AgentID(ctx),
+		Tool:      tool,
+		Args:      args,
+		Timeout:   ac.ApprovalTimeout(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s approval: %w", tool, err)
+	}
+	if d.Approved {
+		return "", nil
+	}
+	return fmt.Sprintf("%s was not approved (%s): %s", tool, strings.ToLower(d.Status), d.Reason), nil
+}
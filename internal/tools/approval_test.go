@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/basket/go-claw/internal/approval"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/policy"
+	"github.com/basket/go-claw/internal/shared"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+type stubApprovals struct {
+	decision approval.Decision
+	requests []approval.Request
+}
+
+func (s *stubApprovals) Request(_ context.Context, req approval.Request) (approval.Decision, error) {
+	s.requests = append(s.requests, req)
+	return s.decision, nil
+}
+
+func TestExec_RequireApproval(t *testing.T) {
+	pol := policy.Policy{
+		AllowCapabilities:      []string{"tools.exec"},
+		RequireApproval:        []string{"exec"},
+		ApprovalTimeoutSeconds: 30,
+	}
+	tests := []struct {
+		name       string
+		approvals  ApprovalBroker
+		wantRuns   int
+		wantDenied string
+	}{
+		{"approved", &stubApprovals{decision: approval.Decision{Approved: true, Status: persistence.ApprovalApproved}}, 1, ""},
+		{"denied", &stubApprovals{decision: approval.Decision{Status: persistence.ApprovalDenied, Reason: "not now"}}, 0, "exec was not approved (denied): not now"},
+		{"no approver", nil, 0, "exec requires human approval but no approver is configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := &recordingExecutor{stdout: "ok"}
+			reg := &Registry{Policy: pol, ShellExecutor: exec, Approvals: tt.approvals}
+			tool := registerShell(genkit.Init(context.Background()), reg).(rawRunner)
+			ctx := shared.WithAgentID(context.Background(), "coder")
+
+			out, err := tool.RunRaw(ctx, map[string]any{"command": "make deploy"})
+			if err != nil {
+				t.Fatalf("RunRaw: %v", err)
+			}
+			if len(exec.cmds) != tt.wantRuns {
+				t.Errorf("executor ran %d commands, want %d", len(exec.cmds), tt.wantRuns)
+			}
+			denied, _ := out.(map[string]any)["denied"].(string)
+			if denied != tt.wantDenied {
+				t.Errorf("denied = %q, want %q", denied, tt.wantDenied)
+			}
+			if stub, ok := tt.approvals.(*stubApprovals); ok {
+				if len(stub.requests) != 1 {
+					t.Fatalf("approval requests = %d, want 1", len(stub.requests))
+				}
+				req := stub.requests[0]
+				if req.Tool != "exec" || req.AgentID != "coder" || req.Timeout.Seconds() != 30 || !strings.Contains(req.Args.(ShellInput).Command, "deploy") {
+					t.Errorf("unexpected approval request: %+v", req)
+				}
+			}
+		})
+	}
+}
+
+func TestExec_NoApprovalRequired(t *testing.T) {
+	stub := &stubApprovals{}
+	exec := &recordingExecutor{stdout: "ok"}
+	reg := &Registry{
+		Policy:        policy.Policy{AllowCapabilities: []string{"tools.exec"}, RequireApproval: []string{"write_file"}},
+		ShellExecutor: exec,
+		Approvals:     stub,
+	}
+	tool := registerShell(genkit.Init(context.Background()), reg).(rawRunner)
+	if _, err := tool.RunRaw(context.Background(), map[string]any{"command": "ls"}); err != nil {
+		t.Fatalf("RunRaw: %v", err)
+	}
+	if len(stub.requests) != 0 || len(exec.cmds) != 1 {
+		t.Errorf("requests=%d runs=%d, want 0 and 1", len(stub.requests), len(exec.cmds))
+	}
+}
@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/basket/go-claw/internal/audit"
+	"github.com/basket/go-claw/internal/policy"
+	"github.com/basket/go-claw/internal/shared"
+)
+
+// checkToolArgs evaluates the policy's argument rules for call and audits the
+// decision with the matching rule. It returns the rule's output limit (0 when
+// none applies). Checkers without argument rules allow every call.
+func checkToolArgs(ctx context.Context, pol policy.Checker, capability string, call policy.ToolCall, subject string) (int, error) {
+	ac, ok := pol.(policy.ArgChecker)
+	if !ok {
+		return 0, nil
+	}
+	d := ac.CheckToolArgs(shared.AgentID(ctx), call)
+	if d.Rule == "" {
+		return 0, nil
+	}
+	if !d.Allowed {
+		audit.RecordRule("deny", capability, d.Reason, pol.PolicyVersion(), subject, d.Rule)
+		return 0, fmt.Errorf("policy rule %q denied %s: %s", d.Rule, call.Tool, d.Reason)
+	}
+	audit.RecordRule("allow", capability, d.Reason, pol.PolicyVersion(), subject, d.Rule)
+	return d.MaxOutputBytes, nil
+}
+
+// outputLimit returns the tighter of the tool default and a rule limit.
+func outputLimit(def, rule int) int {
+	if rule > 0 && rule < def {
+		return rule
+	}
+	return def
+}
@@ -42,12 +42,14 @@ type DelegateTaskInput struct {
 type DelegateTaskOutput struct {
 	// TaskID is the ID of the delegated task.
 	TaskID string `json:"task_id"`
-	// Status is the terminal status (SUCCEEDED or FAILED).
+	// Status is the terminal status (SUCCEEDED or FAILED), or DENIED when an approver refused the call.
 	Status string `json:"status"`
 	// Result is the agent's response.
 	Result string `json:"result,omitempty"`
 	// Error is the error message if the task failed.
 	Error string `json:"error,omitempty"`
+	// Denied is set when an approver refused the delegation.
+	Denied string `json:"denied,omitempty"`
 }
 
 // AsyncDelegateTaskInput is the input for the delegate_task_async tool (PDR v7 Phase 2).
@@ -64,8 +66,10 @@ type AsyncDelegateTaskInput struct {
 type AsyncDelegateTaskOutput struct {
 	// DelegationID is the ID of the delegation record.
 	DelegationID string `json:"delegation_id"`
-	// Status is "queued" for async delegations.
+	// Status is "queued" for async delegations, or "denied" when an approver refused the call.
 	Status string `json:"status"`
+	// Denied is set when an approver refused the delegation.
+	Denied string `json:"denied,omitempty"`
 }
 
 // chatPayload mirrors engine.chatTaskPayload for encoding delegated task payloads.
@@ -355,35 +359,46 @@ func delegateTaskAsync(ctx context.Context, input *AsyncDelegateTaskInput, store
 func registerDelegate(g *genkit.Genkit, reg *Registry) ai.ToolRef {
 	return genkit.DefineTool(g, "delegate_task",
 		"Delegate a task to another agent and wait for its result. The calling agent's turn pauses until the target agent completes. Requires tools.delegate_task capability.",
-		func(ctx *ai.ToolContext, input DelegateTaskInput) (DelegateTaskOutput, error) {
+		Traced(reg, "delegate_task", func(ctx *ai.ToolContext, input DelegateTaskInput) (DelegateTaskOutput, error) {
 			reg.publishToolCall(ctx, "delegate_task")
 			maxHops := reg.DelegationMaxHops
 			if maxHops <= 0 {
 				maxHops = 2 // Fallback default
 			}
+			// Ask only for calls the policy would let through.
+			if reg.Policy != nil && reg.Policy.AllowCapability(capDelegateTask) {
+				if denied, err := reg.awaitApproval(ctx, policy.ToolDelegateTask, input); err != nil || denied != "" {
+					return DelegateTaskOutput{Status: "DENIED", Denied: denied}, err
+				}
+			}
 			out, err := delegateTask(ctx, &input, reg.Store, reg.Policy, maxHops)
 			if err != nil {
 				return DelegateTaskOutput{}, err
 			}
 			return *out, nil
-		},
+		}),
 	)
 }
 
 func registerDelegateAsync(g *genkit.Genkit, reg *Registry) ai.ToolRef {
 	return genkit.DefineTool(g, "delegate_task_async",
 		"Delegate a task to another agent without waiting for its result. Returns immediately with a delegation ID. Requires tools.delegate_task_async capability.",
-		func(ctx *ai.ToolContext, input AsyncDelegateTaskInput) (AsyncDelegateTaskOutput, error) {
+		Traced(reg, "delegate_task_async", func(ctx *ai.ToolContext, input AsyncDelegateTaskInput) (AsyncDelegateTaskOutput, error) {
 			reg.publishToolCall(ctx, "delegate_task_async")
 			maxHops := reg.DelegationMaxHops
 			if maxHops <= 0 {
 				maxHops = 2 // Fallback default
 			}
+			if reg.Policy != nil && reg.Policy.AllowCapability(capDelegateTaskAsync) {
+				if denied, err := reg.awaitApproval(ctx, policy.ToolDelegateTaskAsync, input); err != nil || denied != "" {
+					return AsyncDelegateTaskOutput{Status: "denied", Denied: denied}, err
+				}
+			}
 			out, err := delegateTaskAsync(ctx, &input, reg.Store, reg.Policy, maxHops)
 			if err != nil {
 				return AsyncDelegateTaskOutput{}, err
 			}
 			return *out, nil
-		},
+		}),
 	)
 }
@@ -39,6 +39,7 @@ type WriteFileOutput struct {
 	Written bool   `json:"written"`
 	Path    string `json:"path"`
 	Size    int    `json:"size"`
+	Denied  string `json:"denied,omitempty"` // set when an approver refused the write
 }
 
 // ListDirectoryInput is the input for the list_directory tool.
@@ -70,6 +71,7 @@ type EditFileInput struct {
 type EditFileOutput struct {
 	Edited bool   `json:"edited"`
 	Path   string `json:"path"`
+	Denied string `json:"denied,omitempty"` // set when an approver refused the edit
 }
 
 // isPathAllowed checks that the resolved path is safe (no traversal out of allowed dirs).
@@ -94,7 +96,7 @@ func isPathAllowed(rawPath string) (string, error) {
 func registerFileTools(g *genkit.Genkit, reg *Registry) []ai.ToolRef {
 	readFile := genkit.DefineTool(g, "read_file",
 		"Read the contents of a file at the given path. Returns the file content as text. Maximum 100KB.",
-		func(ctx *ai.ToolContext, input ReadFileInput) (ReadFileOutput, error) {
+		Traced(reg, "read_file", func(ctx *ai.ToolContext, input ReadFileInput) (ReadFileOutput, error) {
 			reg.publishToolCall(ctx, "read_file")
 			if reg.Policy == nil || !reg.Policy.AllowCapability("tools.read_file") {
 				pv := policyVersion(reg.Policy)
@@ -132,12 +134,12 @@ func registerFileTools(g *genkit.Genkit, reg *Registry) []ai.ToolRef {
 				Content: string(data),
 				Size:    info.Size(),
 			}, nil
-		},
+		}),
 	)
 
 	writeFile := genkit.DefineTool(g, "write_file",
 		"Write content to a file at the given path. Creates parent directories if needed. Uses atomic write.",
-		func(ctx *ai.ToolContext, input WriteFileInput) (WriteFileOutput, error) {
+		Traced(reg, "write_file", func(ctx *ai.ToolContext, input WriteFileInput) (WriteFileOutput, error) {
 			reg.publishToolCall(ctx, "write_file")
 			if reg.Policy == nil || !reg.Policy.AllowCapability("tools.write_file") {
 				pv := policyVersion(reg.Policy)
@@ -154,6 +156,12 @@ func registerFileTools(g *genkit.Genkit, reg *Registry) []ai.ToolRef {
 				audit.Record("deny", "tools.write_file", "path_denied", policyVersion(reg.Policy), resolved)
 				return WriteFileOutput{}, fmt.Errorf("policy denied path %q", resolved)
 			}
+			if _, err := checkToolArgs(ctx, reg.Policy, "tools.write_file", policy.ToolCall{Tool: policy.ToolWriteFile, Path: resolved}, resolved); err != nil {
+				return WriteFileOutput{}, err
+			}
+			if denied, err := reg.awaitApproval(ctx, policy.ToolWriteFile, input); err != nil || denied != "" {
+				return WriteFileOutput{Path: resolved, Denied: denied}, err
+			}
 
 			// Create parent directories.
 			if err := os.MkdirAll(filepath.Dir(resolved), 0o755); err != nil {
@@ -175,12 +183,12 @@ func registerFileTools(g *genkit.Genkit, reg *Registry) []ai.ToolRef {
 				Path:    resolved,
 				Size:    len(input.Content),
 			}, nil
-		},
+		}),
 	)
 
 	listDir := genkit.DefineTool(g, "list_directory",
 		"List the contents of a directory. Returns file names, types, and sizes. Maximum 200 entries.",
-		func(ctx *ai.ToolContext, input ListDirectoryInput) (ListDirectoryOutput, error) {
+		Traced(reg, "list_directory", func(ctx *ai.ToolContext, input ListDirectoryInput) (ListDirectoryOutput, error) {
 			reg.publishToolCall(ctx, "list_directory")
 			if reg.Policy == nil || !reg.Policy.AllowCapability("tools.read_file") {
 				pv := policyVersion(reg.Policy)
@@ -223,12 +231,12 @@ func registerFileTools(g *genkit.Genkit, reg *Registry) []ai.ToolRef {
 				Entries: result,
 				Path:    resolved,
 			}, nil
-		},
+		}),
 	)
 
 	editFile := genkit.DefineTool(g, "edit_file",
 		"Edit a file by replacing old_text with new_text. The old_text must appear exactly once in the file.",
-		func(ctx *ai.ToolContext, input EditFileInput) (EditFileOutput, error) {
+		Traced(reg, "edit_file", func(ctx *ai.ToolContext, input EditFileInput) (EditFileOutput, error) {
 			reg.publishToolCall(ctx, "edit_file")
 			if reg.Policy == nil || !reg.Policy.AllowCapability("tools.write_file") {
 				pv := policyVersion(reg.Policy)
@@ -244,6 +252,12 @@ func registerFileTools(g *genkit.Genkit, reg *Registry) []ai.ToolRef {
 				audit.Record("deny", "tools.write_file", "path_denied", policyVersion(reg.Policy), resolved)
 				return EditFileOutput{}, fmt.Errorf("policy denied path %q", resolved)
 			}
+			if _, err := checkToolArgs(ctx, reg.Policy, "tools.write_file", policy.ToolCall{Tool: policy.ToolEditFile, Path: resolved}, resolved); err != nil {
+				return EditFileOutput{}, err
+			}
+			if denied, err := reg.awaitApproval(ctx, policy.ToolEditFile, input); err != nil || denied != "" {
+				return EditFileOutput{Path: resolved, Denied: denied}, err
+			}
 
 			data, err := os.ReadFile(resolved)
 			if err != nil {
@@ -272,7 +286,7 @@ func registerFileTools(g *genkit.Genkit, reg *Registry) []ai.ToolRef {
 			}
 
 			return EditFileOutput{Edited: true, Path: resolved}, nil
-		},
+		}),
 	)
 
 	return []ai.ToolRef{readFile, writeFile, listDir, editFile}
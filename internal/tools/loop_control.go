@@ -35,17 +35,17 @@ type LoopStatusOutput struct {
 func RegisterLoopControlTools(g *genkit.Genkit, reg *Registry, eventBus *bus.Bus) []ai.ToolRef {
 	checkpointTool := genkit.DefineTool(g, "checkpoint_now",
 		"Force an immediate checkpoint of the current loop state. Use before risky operations.",
-		func(ctx *ai.ToolContext, input CheckpointInput) (CheckpointOutput, error) {
+		Traced(reg, "checkpoint_now", func(ctx *ai.ToolContext, input CheckpointInput) (CheckpointOutput, error) {
 			reg.publishToolCall(ctx, "checkpoint_now")
 			// The engine intercepts this tool call to trigger a checkpoint save.
 			// The tool itself simply acknowledges the request.
 			return CheckpointOutput{Saved: true}, nil
-		},
+		}),
 	)
 
 	statusTool := genkit.DefineTool(g, "set_loop_status",
 		"Update the loop's status message visible to users. Use to report progress.",
-		func(ctx *ai.ToolContext, input LoopStatusInput) (LoopStatusOutput, error) {
+		Traced(reg, "set_loop_status", func(ctx *ai.ToolContext, input LoopStatusInput) (LoopStatusOutput, error) {
 			reg.publishToolCall(ctx, "set_loop_status")
 			if eventBus != nil {
 				eventBus.Publish("loop.status_update", map[string]string{
@@ -53,7 +53,7 @@ func RegisterLoopControlTools(g *genkit.Genkit, reg *Registry, eventBus *bus.Bus
 				})
 			}
 			return LoopStatusOutput{Updated: true}, nil
-		},
+		}),
 	)
 
 	return []ai.ToolRef{checkpointTool, statusTool}
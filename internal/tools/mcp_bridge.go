@@ -8,14 +8,29 @@ import (
 
 	"github.com/basket/go-claw/internal/audit"
 	"github.com/basket/go-claw/internal/mcp"
+	"github.com/basket/go-claw/internal/policy"
 	"github.com/firebase/genkit/go/ai"
-	"github.com/firebase/genkit/go/genkit"
 )
 
-// RegisterMCPTools discovers MCP tools for a specific agent and registers them.
-// Calls Manager.DiscoverTools to enumerate tools allowed by policy.
-// Tool invocations route through Manager.InvokeTool for per-agent policy enforcement and timeouts.
-func RegisterMCPTools(g *genkit.Genkit, agentID string, manager *mcp.Manager) []ai.ToolRef {
+// MCPToolRefs discovers MCP tools for a specific agent and builds tools for them.
+// Calls Manager.DiscoverTools to enumerate tools allowed by policy. The
+// returned tools are not registered with Genkit; install them with
+// Registry.SetMCPTools. Tool invocations route through Manager.InvokeTool for
+// per-agent policy enforcement and timeouts.
+func MCPToolRefs(agentID string, manager *mcp.Manager) []ai.ToolRef {
+	return buildMCPTools(nil, agentID, manager)
+}
+
+// MCPToolRefs is the package-level MCPToolRefs with invocations gated by r's
+// require_approval policy ("mcp:<server>/<tool>") and recorded in r's
+// tool-call trace.
+func (r *Registry) MCPToolRefs(agentID string, manager *mcp.Manager) []ai.ToolRef {
+	return buildMCPTools(r, agentID, manager)
+}
+
+// buildMCPTools builds the agent's MCP tools; gate may be nil (no approval
+// gate, no trace).
+func buildMCPTools(gate *Registry, agentID string, manager *mcp.Manager) []ai.ToolRef {
 	ctx := context.Background()
 
 	tools, err := manager.DiscoverTools(ctx, agentID)
@@ -44,14 +59,20 @@ func RegisterMCPTools(g *genkit.Genkit, agentID string, manager *mcp.Manager) []
 			schema = map[string]any{"type": "object"}
 		}
 
-		// Append schema to description so LLM sees it since we can't easily patch Genkit schema
-		schemaJSON, _ := json.MarshalIndent(schema, "", "  ")
-		description := fmt.Sprintf("%s\n\nInput Schema:\n%s", tool.Description, string(schemaJSON))
+		// Tools are created unregistered (ai.NewTool) so a tools/list_changed
+		// refresh can redefine them without colliding in the Genkit registry.
+		t := ai.NewTool(toolName, tool.Description,
+			Traced(gate, toolName, func(ctx *ai.ToolContext, input any) (any, error) {
+				if gate != nil {
+					denied, err := gate.awaitApproval(ctx, policy.MCPToolName(serverName, mcpToolName), input)
+					if err != nil {
+						return nil, err
+					}
+					if denied != "" {
+						return map[string]any{"denied": denied}, nil
+					}
+				}
 
-		// Define generic tool
-		// Genkit generates schema from Input type. map[string]any -> object with no properties?
-		t := genkit.DefineTool(g, toolName, description,
-			func(ctx *ai.ToolContext, input map[string]any) (any, error) {
 				// Audit record handled here since this is the execution entry point
 
 				argsJSON, err := json.Marshal(input)
@@ -76,7 +97,8 @@ func RegisterMCPTools(g *genkit.Genkit, agentID string, manager *mcp.Manager) []
 					return string(res), nil
 				}
 				return resObj, nil
-			},
+			}),
+			ai.WithInputSchema(schema),
 		)
 
 		refs = append(refs, t)
@@ -85,3 +107,19 @@ func RegisterMCPTools(g *genkit.Genkit, agentID string, manager *mcp.Manager) []
 	slog.Info("registered mcp tools", "agent", agentID, "count", len(refs))
 	return refs
 }
+
+// SetMCPTools replaces the registry's MCP tools with refs, e.g. after an MCP
+// server reported a tool list change.
+func (r *Registry) SetMCPTools(refs []ai.ToolRef) {
+	r.toolsMu.Lock()
+	defer r.toolsMu.Unlock()
+	for name := range r.mcpTools {
+		r.Tools = removeToolRef(r.Tools, name)
+	}
+	r.mcpTools = make(map[string]struct{}, len(refs))
+	for _, ref := range refs {
+		r.Tools = removeToolRef(r.Tools, ref.Name())
+		r.Tools = append(r.Tools, ref)
+		r.mcpTools[ref.Name()] = struct{}{}
+	}
+}
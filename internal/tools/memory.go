@@ -1,12 +1,15 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/basket/go-claw/internal/audit"
 	"github.com/basket/go-claw/internal/memory"
+	"github.com/basket/go-claw/internal/shared"
 	"github.com/firebase/genkit/go/ai"
 	"github.com/firebase/genkit/go/genkit"
 )
@@ -37,13 +40,41 @@ type MemoryWriteOutput struct {
 // MemorySearchInput is the input for the memory_search tool.
 type MemorySearchInput struct {
 	Query string `json:"query"`
+	Limit int    `json:"limit,omitempty"` // per kind of result; 0 = 10
 }
 
 // MemorySearchOutput is the output for the memory_search tool.
 type MemorySearchOutput struct {
-	Hits []memory.SearchHit `json:"hits"`
+	Memories []MemorySearchMemory  `json:"memories"`
+	Pins     []MemorySearchPin     `json:"pins,omitempty"`
+	Messages []MemorySearchMessage `json:"messages,omitempty"`
+	Hits     []memory.SearchHit    `json:"hits"` // workspace files
 }
 
+// MemorySearchMemory is a stored agent memory matched by memory_search.
+type MemorySearchMemory struct {
+	Key   string  `json:"key"`
+	Value string  `json:"value"`
+	Score float64 `json:"score"`
+}
+
+// MemorySearchPin is a pinned file or text matched by memory_search.
+type MemorySearchPin struct {
+	Source  string `json:"source"`
+	Snippet string `json:"snippet"`
+}
+
+// MemorySearchMessage is a past conversation message matched by memory_search.
+type MemorySearchMessage struct {
+	SessionID string `json:"session_id"`
+	Role      string `json:"role"`
+	Snippet   string `json:"snippet"`
+	CreatedAt string `json:"created_at"`
+}
+
+// memorySnippetChars bounds the pin and message text returned by memory_search.
+const memorySnippetChars = 300
+
 // workspaceRoot returns the workspace directory under GOCLAW_HOME.
 func workspaceRoot() string {
 	home := os.Getenv("GOCLAW_HOME")
@@ -58,7 +89,7 @@ func workspaceRoot() string {
 func registerMemoryTools(g *genkit.Genkit, reg *Registry) []ai.ToolRef {
 	readTool := genkit.DefineTool(g, "memory_read",
 		"Read a file from the agent memory workspace. Path is relative to the workspace root.",
-		func(ctx *ai.ToolContext, input MemoryReadInput) (MemoryReadOutput, error) {
+		Traced(reg, "memory_read", func(ctx *ai.ToolContext, input MemoryReadInput) (MemoryReadOutput, error) {
 			reg.publishToolCall(ctx, "memory_read")
 			if reg.Policy == nil || !reg.Policy.AllowCapability("tools.memory_read") {
 				pv := policyVersion(reg.Policy)
@@ -76,12 +107,12 @@ func registerMemoryTools(g *genkit.Genkit, reg *Registry) []ai.ToolRef {
 				return MemoryReadOutput{}, err
 			}
 			return MemoryReadOutput{Content: content}, nil
-		},
+		}),
 	)
 
 	writeTool := genkit.DefineTool(g, "memory_write",
 		"Write or append content to a file in the agent memory workspace. Path is relative to the workspace root. Set append=true to append instead of overwrite.",
-		func(ctx *ai.ToolContext, input MemoryWriteInput) (MemoryWriteOutput, error) {
+		Traced(reg, "memory_write", func(ctx *ai.ToolContext, input MemoryWriteInput) (MemoryWriteOutput, error) {
 			reg.publishToolCall(ctx, "memory_write")
 			if reg.Policy == nil || !reg.Policy.AllowCapability("tools.memory_write") {
 				pv := policyVersion(reg.Policy)
@@ -104,12 +135,12 @@ func registerMemoryTools(g *genkit.Genkit, reg *Registry) []ai.ToolRef {
 				return MemoryWriteOutput{}, err
 			}
 			return MemoryWriteOutput{Written: true, Path: input.Path}, nil
-		},
+		}),
 	)
 
 	searchTool := genkit.DefineTool(g, "memory_search",
-		"Search the agent memory workspace for files containing the query string. Returns matching lines with file paths and line numbers.",
-		func(ctx *ai.ToolContext, input MemorySearchInput) (MemorySearchOutput, error) {
+		"Search what the agent knows: its stored memories, pinned files and past conversation messages (ranked by relevance), and the files of the memory workspace.",
+		Traced(reg, "memory_search", func(ctx *ai.ToolContext, input MemorySearchInput) (MemorySearchOutput, error) {
 			reg.publishToolCall(ctx, "memory_search")
 			if reg.Policy == nil || !reg.Policy.AllowCapability("tools.memory_read") {
 				pv := policyVersion(reg.Policy)
@@ -118,6 +149,11 @@ func registerMemoryTools(g *genkit.Genkit, reg *Registry) []ai.ToolRef {
 			}
 			audit.Record("allow", "tools.memory_read", "capability_granted", policyVersion(reg.Policy), input.Query)
 
+			out, err := reg.searchIndexedMemory(ctx, input)
+			if err != nil {
+				return MemorySearchOutput{}, err
+			}
+
 			ws, err := memory.NewWorkspace(workspaceRoot())
 			if err != nil {
 				return MemorySearchOutput{}, fmt.Errorf("memory workspace: %w", err)
@@ -129,9 +165,68 @@ func registerMemoryTools(g *genkit.Genkit, reg *Registry) []ai.ToolRef {
 			if hits == nil {
 				hits = []memory.SearchHit{}
 			}
-			return MemorySearchOutput{Hits: hits}, nil
-		},
+			out.Hits = hits
+			return out, nil
+		}),
 	)
 
 	return []ai.ToolRef{readTool, writeTool, searchTool}
 }
+
+// searchIndexedMemory searches the calling agent's memories, pins and messages
+// through the full-text index. Without a store it returns no results.
+func (r *Registry) searchIndexedMemory(ctx context.Context, input MemorySearchInput) (MemorySearchOutput, error) {
+	out := MemorySearchOutput{Memories: []MemorySearchMemory{}}
+	if r.Store == nil {
+		return out, nil
+	}
+	agentID := shared.AgentID(ctx)
+	if agentID == "" {
+		agentID = shared.DefaultAgentID
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	retriever := r.Memory
+	if retriever == nil {
+		retriever = memory.NewRetriever(r.Store, nil)
+	}
+
+	memories, err := retriever.Search(ctx, agentID, input.Query, limit)
+	if err != nil {
+		return out, fmt.Errorf("search memories: %w", err)
+	}
+	for _, m := range memories {
+		out.Memories = append(out.Memories, MemorySearchMemory{Key: m.Key, Value: m.Value, Score: m.Score})
+	}
+	pins, err := r.Store.SearchPins(ctx, agentID, input.Query, limit)
+	if err != nil {
+		return out, fmt.Errorf("search pins: %w", err)
+	}
+	for _, p := range pins {
+		out.Pins = append(out.Pins, MemorySearchPin{Source: p.Source, Snippet: snippet(p.Content)})
+	}
+	messages, err := r.Store.SearchMessages(ctx, agentID, input.Query, limit)
+	if err != nil {
+		return out, fmt.Errorf("search messages: %w", err)
+	}
+	for _, m := range messages {
+		out.Messages = append(out.Messages, MemorySearchMessage{
+			SessionID: m.SessionID,
+			Role:      m.Role,
+			Snippet:   snippet(m.Content),
+			CreatedAt: m.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return out, nil
+}
+
+// snippet shortens text to memorySnippetChars runes.
+func snippet(text string) string {
+	r := []rune(text)
+	if len(r) <= memorySnippetChars {
+		return text
+	}
+	return string(r[:memorySnippetChars]) + "…"
+}
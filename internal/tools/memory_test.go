@@ -1,9 +1,14 @@
 package tools
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/shared"
 )
 
 func TestWorkspaceRoot_DefaultPath(t *testing.T) {
@@ -102,3 +107,49 @@ func TestWorkspaceRoot_DirectoryCreatable(t *testing.T) {
 		t.Error("workspace root should be a directory")
 	}
 }
+
+func TestSearchIndexedMemory(t *testing.T) {
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "goclaw.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	ctx := shared.WithAgentID(context.Background(), "coder")
+	const sessionID = "f0f0f0f0-1111-2222-3333-444444444444"
+
+	if err := store.SetMemory(ctx, "coder", "staging_db", "Postgres on db-staging-2", "user"); err != nil {
+		t.Fatalf("SetMemory: %v", err)
+	}
+	if err := store.SetMemory(ctx, "writer", "staging_db", "not coder's", "user"); err != nil {
+		t.Fatalf("SetMemory: %v", err)
+	}
+	if err := store.AddPin(ctx, "coder", "text", "runbook", strings.Repeat("Restart staging after deploys. ", 20), false); err != nil {
+		t.Fatalf("AddPin: %v", err)
+	}
+	if err := store.EnsureSession(ctx, sessionID); err != nil {
+		t.Fatalf("EnsureSession: %v", err)
+	}
+	if err := store.AddHistory(ctx, sessionID, "coder", "user", "staging is down again", 0); err != nil {
+		t.Fatalf("AddHistory: %v", err)
+	}
+
+	reg := &Registry{Store: store}
+	out, err := reg.searchIndexedMemory(ctx, MemorySearchInput{Query: "staging"})
+	if err != nil {
+		t.Fatalf("searchIndexedMemory: %v", err)
+	}
+	if len(out.Memories) != 1 || out.Memories[0].Value != "Postgres on db-staging-2" || out.Memories[0].Score <= 0 {
+		t.Errorf("memories = %+v, want coder's staging_db", out.Memories)
+	}
+	if len(out.Pins) != 1 || len([]rune(out.Pins[0].Snippet)) != memorySnippetChars+1 {
+		t.Errorf("pins = %+v, want the runbook pin as a snippet", out.Pins)
+	}
+	if len(out.Messages) != 1 || out.Messages[0].SessionID != sessionID {
+		t.Errorf("messages = %+v, want the user message", out.Messages)
+	}
+
+	out, err = (&Registry{}).searchIndexedMemory(ctx, MemorySearchInput{Query: "staging"})
+	if err != nil || len(out.Memories) != 0 {
+		t.Errorf("without a store = %+v, %v; want no results", out, err)
+	}
+}
@@ -165,26 +165,26 @@ func readMessages(ctx context.Context, input *ReadMessagesInput, store *persiste
 func registerMessaging(g *genkit.Genkit, reg *Registry) []ai.ToolRef {
 	sendTool := genkit.DefineTool(g, "send_message",
 		"Send a message to another agent. The message will be stored and the recipient can read it with read_messages. Requires tools.send_message capability.",
-		func(ctx *ai.ToolContext, input SendMessageInput) (SendMessageOutput, error) {
+		Traced(reg, "send_message", func(ctx *ai.ToolContext, input SendMessageInput) (SendMessageOutput, error) {
 			reg.publishToolCall(ctx, "send_message")
 			out, err := sendMessage(ctx, &input, reg.Store, reg.Policy)
 			if err != nil {
 				return SendMessageOutput{}, err
 			}
 			return *out, nil
-		},
+		}),
 	)
 
 	readTool := genkit.DefineTool(g, "read_messages",
 		"Read unread messages from other agents. Messages are marked as read after retrieval. Requires tools.read_messages capability.",
-		func(ctx *ai.ToolContext, input ReadMessagesInput) (ReadMessagesOutput, error) {
+		Traced(reg, "read_messages", func(ctx *ai.ToolContext, input ReadMessagesInput) (ReadMessagesOutput, error) {
 			reg.publishToolCall(ctx, "read_messages")
 			out, err := readMessages(ctx, &input, reg.Store, reg.Policy)
 			if err != nil {
 				return ReadMessagesOutput{}, err
 			}
 			return *out, nil
-		},
+		}),
 	)
 
 	return []ai.ToolRef{sendTool, readTool}
@@ -0,0 +1,319 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/basket/go-claw/internal/approval"
+	"github.com/basket/go-claw/internal/audit"
+	"github.com/basket/go-claw/internal/config"
+	"github.com/basket/go-claw/internal/coordinator"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/policy"
+	"github.com/basket/go-claw/internal/shared"
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"gopkg.in/yaml.v3"
+)
+
+const capCreatePlan = "tools.create_plan"
+
+// PlanRunner starts generated plans. main adapts *gateway.Server to it,
+// because the gateway is created after the agents.
+type PlanRunner interface {
+	// StartGeneratedPlan runs plan's definition as given: the snapshot that
+	// was approved, whatever is stored under its name by now.
+	StartGeneratedPlan(ctx context.Context, plan *persistence.GeneratedPlan, sessionID string, inputs map[string]any) (executionID, session string, err error)
+	// IsConfiguredPlan reports whether name is a plan from config.yaml.
+	// Generated plans may not shadow those.
+	IsConfiguredPlan(name string) bool
+}
+
+// PlanAgent is an agent that generated plans may assign steps to.
+type PlanAgent struct {
+	ID           string
+	Capabilities []string
+}
+
+// Planner configures the create_plan tool. The tool is registered only for
+// registries with a Store and a Planner.
+type Planner struct {
+	// Gate is what happens to a new plan: config.PlanGateAuto runs it,
+	// config.PlanGateApprove runs it once an approver agrees, and
+	// config.PlanGateReview saves a draft to be approved with /plan approve.
+	Gate string
+	// ApprovalTimeout bounds the wait for an approver (0 = policy.DefaultApprovalTimeout).
+	ApprovalTimeout time.Duration
+	// Agents lists the agents steps may be assigned to.
+	Agents func(ctx context.Context) []PlanAgent
+	// Runner starts approved plans. Without one, plans are only saved.
+	Runner PlanRunner
+}
+
+// PlanInputSpec declares an input of a generated plan.
+type PlanInputSpec struct {
+	Name string `json:"name" yaml:"name"`
+	// Type is string (default), number, integer or boolean.
+	Type        string `json:"type,omitempty" yaml:"type,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool   `json:"required,omitempty" yaml:"required,omitempty"`
+	Default     any    `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// PlanStepSpec is one step of a generated plan.
+type PlanStepSpec struct {
+	// ID names the step; later prompts read its result as {<id>.output}.
+	ID string `json:"id" yaml:"id"`
+	// AgentID runs the step (either this or Capability must be provided).
+	AgentID string `json:"agent_id,omitempty" yaml:"agent_id"`
+	// Capability picks an agent that declares it when AgentID is empty.
+	Capability string `json:"capability,omitempty" yaml:"-"`
+	// Prompt is the step's task; it may use {inputs.<name>} and {<step>.output}.
+	Prompt string `json:"prompt" yaml:"prompt"`
+	// DependsOn lists steps that must finish first.
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+	// When skips the step unless the condition on an earlier step holds, e.g. "review.output.verdict == approve".
+	When string `json:"when,omitempty" yaml:"when,omitempty"`
+	// OnFailure is fail_plan (default), continue or skip_dependents.
+	OnFailure string `json:"on_failure,omitempty" yaml:"on_failure,omitempty"`
+	// MaxRetries is how often a failed attempt is retried (unset = 2).
+	MaxRetries *int `json:"max_retries,omitempty" yaml:"max_retries,omitempty"`
+	// TimeoutSeconds bounds one attempt (0 = executor default).
+	TimeoutSeconds int `json:"timeout_seconds,omitempty" yaml:"timeout_seconds,omitempty"`
+	// RequireApproval holds the step until a human approves it.
+	RequireApproval bool `json:"require_approval,omitempty" yaml:"require_approval,omitempty"`
+	// OutputSchema is a JSON Schema the step's output must match.
+	OutputSchema map[string]any `json:"output_schema,omitempty" yaml:"output_schema,omitempty"`
+}
+
+// CreatePlanInput is the input for the create_plan tool.
+type CreatePlanInput struct {
+	// Name saves the plan for re-runs: letters, digits, '-' and '_'.
+	Name string `json:"name"`
+	// Goal is the natural-language goal the plan achieves.
+	Goal string `json:"goal"`
+	// Inputs are parameters supplied when the plan is started.
+	Inputs []PlanInputSpec `json:"inputs,omitempty"`
+	// Steps form a DAG through their depends_on lists.
+	Steps []PlanStepSpec `json:"steps"`
+	// MaxConcurrency caps how many steps run at once (0 = unlimited).
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+	// Args are the input values for running the plan now.
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// CreatePlanOutput is the output for the create_plan tool.
+type CreatePlanOutput struct {
+	// Name is the name the plan was saved under.
+	Name string `json:"name"`
+	// Status is draft, approved or rejected, or invalid when the plan was not saved.
+	Status string `json:"status"`
+	// ExecutionID is set when the plan was started.
+	ExecutionID string `json:"execution_id,omitempty"`
+	// Message explains the outcome.
+	Message string `json:"message"`
+}
+
+// planDefinition is the saved form of a generated plan, in the YAML layout of
+// a plan in config.yaml.
+type planDefinition struct {
+	Name           string          `yaml:"name"`
+	MaxConcurrency int             `yaml:"max_concurrency,omitempty"`
+	Inputs         []PlanInputSpec `yaml:"inputs,omitempty"`
+	Steps          []PlanStepSpec  `yaml:"steps"`
+}
+
+var planNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// createPlan validates, saves and gates a plan written by an agent. Problems
+// with the plan itself come back as an "invalid" result rather than an error,
+// so the model can correct the plan and call the tool again.
+func createPlan(ctx context.Context, input CreatePlanInput, reg *Registry) (CreatePlanOutput, error) {
+	pol := reg.Policy
+	if pol == nil || !pol.AllowCapability(capCreatePlan) {
+		audit.Record("deny", capCreatePlan, "missing_capability", policyVersion(pol), "create_plan")
+		return CreatePlanOutput{}, fmt.Errorf("policy denied capability %q", capCreatePlan)
+	}
+	audit.Record("allow", capCreatePlan, "capability_granted", pol.PolicyVersion(), "create_plan")
+
+	planner := reg.Planner
+	input.Name = strings.TrimSpace(input.Name)
+	invalid := func(format string, args ...any) (CreatePlanOutput, error) {
+		return CreatePlanOutput{Name: input.Name, Status: "invalid", Message: fmt.Sprintf(format, args...)}, nil
+	}
+	if !planNamePattern.MatchString(input.Name) {
+		return invalid("name %q must be 1-64 letters, digits, '-' or '_'", input.Name)
+	}
+	if strings.TrimSpace(input.Goal) == "" {
+		return invalid("goal must be non-empty")
+	}
+	if len(input.Steps) == 0 {
+		return invalid("a plan needs at least one step")
+	}
+	if planner.Runner != nil && planner.Runner.IsConfiguredPlan(input.Name) {
+		return invalid("a plan called %q is already configured; choose another name", input.Name)
+	}
+
+	var roster []PlanAgent
+	if planner.Agents != nil {
+		roster = planner.Agents(ctx)
+	}
+	sort.Slice(roster, func(i, j int) bool { return roster[i].ID < roster[j].ID })
+	agentIDs := make([]string, 0, len(roster))
+	for _, a := range roster {
+		agentIDs = append(agentIDs, a.ID)
+	}
+	steps := make([]PlanStepSpec, len(input.Steps))
+	for i, step := range input.Steps {
+		if step.AgentID == "" {
+			if step.Capability == "" {
+				return invalid("step %q needs agent_id or capability", step.ID)
+			}
+			step.AgentID = agentWithCapability(roster, step.Capability)
+			if step.AgentID == "" {
+				return invalid("step %q: no agent has capability %q (agents: %s)", step.ID, step.Capability, describeRoster(roster))
+			}
+		}
+		steps[i] = step
+	}
+
+	var definition strings.Builder
+	enc := yaml.NewEncoder(&definition)
+	enc.SetIndent(2)
+	if err := enc.Encode(planDefinition{
+		Name:           input.Name,
+		MaxConcurrency: input.MaxConcurrency,
+		Inputs:         input.Inputs,
+		Steps:          steps,
+	}); err != nil {
+		return CreatePlanOutput{}, fmt.Errorf("create_plan: encode plan: %w", err)
+	}
+	plan, err := coordinator.ParsePlanDefinition(definition.String(), agentIDs)
+	if err != nil {
+		return invalid("%v", err)
+	}
+	if _, err := plan.BindInputs(input.Args); err != nil {
+		return invalid("%v", err)
+	}
+
+	saved := &persistence.GeneratedPlan{
+		Name:       input.Name,
+		Goal:       strings.TrimSpace(input.Goal),
+		Definition: definition.String(),
+		Status:     persistence.GeneratedPlanDraft,
+		CreatedBy:  shared.AgentID(ctx),
+		SessionID:  shared.SessionID(ctx),
+	}
+	if planner.Gate == config.PlanGateAuto {
+		saved.Status = persistence.GeneratedPlanApproved
+	}
+	if err := reg.Store.SaveGeneratedPlan(ctx, saved); err != nil {
+		return CreatePlanOutput{}, fmt.Errorf("create_plan: %w", err)
+	}
+	out := CreatePlanOutput{Name: saved.Name, Status: saved.Status}
+
+	switch planner.Gate {
+	case config.PlanGateAuto:
+	case config.PlanGateReview:
+		out.Message = fmt.Sprintf("Plan saved as a draft for review. A human can inspect it with /plan show %s and run it with /plan approve %s.", saved.Name, saved.Name)
+		return out, nil
+	default:
+		if reg.Approvals == nil {
+			out.Message = fmt.Sprintf("Plan saved as a draft: no approver is configured. A human can run it with /plan approve %s.", saved.Name)
+			return out, nil
+		}
+		timeout := planner.ApprovalTimeout
+		if timeout <= 0 {
+			timeout = policy.DefaultApprovalTimeout
+		}
+		d, err := reg.Approvals.Request(ctx, approval.Request{
+			TaskID:    shared.TaskID(ctx),
+			RunID:     shared.RunID(ctx),
+			SessionID: shared.SessionID(ctx),
+			AgentID:   shared.AgentID(ctx),
+			Tool:      "create_plan",
+			Args:      map[string]any{"name": saved.Name, "goal": saved.Goal, "plan": saved.Definition, "args": input.Args},
+			Timeout:   timeout,
+		})
+		if err != nil {
+			return CreatePlanOutput{}, fmt.Errorf("create_plan approval: %w", err)
+		}
+		status := persistence.GeneratedPlanRejected
+		if d.Approved {
+			status = persistence.GeneratedPlanApproved
+		}
+		// The decision covers the definition the approver saw; a plan saved
+		// under the same name in the meantime needs its own review.
+		err = reg.Store.SetGeneratedPlanStatusIf(ctx, saved.Name, saved.Definition, status)
+		if errors.Is(err, persistence.ErrGeneratedPlanChanged) {
+			out.Status = persistence.GeneratedPlanDraft
+			out.Message = fmt.Sprintf("Plan %s was replaced while waiting for approval; the decision was not applied and the new version needs its own approval.", saved.Name)
+			return out, nil
+		}
+		if err != nil {
+			return CreatePlanOutput{}, fmt.Errorf("create_plan: %w", err)
+		}
+		out.Status = status
+		if !d.Approved {
+			out.Message = fmt.Sprintf("Plan was not approved (%s): %s", strings.ToLower(d.Status), d.Reason)
+			return out, nil
+		}
+	}
+
+	if planner.Runner == nil {
+		out.Message = "Plan approved and saved; no plan runner is available to start it."
+		return out, nil
+	}
+	executionID, _, err := planner.Runner.StartGeneratedPlan(ctx, saved, shared.SessionID(ctx), input.Args)
+	if err != nil {
+		slog.Warn("create_plan: start plan failed", "plan", saved.Name, "error", err)
+		out.Message = fmt.Sprintf("Plan approved and saved, but starting it failed: %v", err)
+		return out, nil
+	}
+	out.ExecutionID = executionID
+	out.Message = fmt.Sprintf("Plan approved and started. Re-run it later with /plan run %s.", saved.Name)
+	return out, nil
+}
+
+// describeRoster lists agents with their capabilities for the model.
+func describeRoster(roster []PlanAgent) string {
+	parts := make([]string, 0, len(roster))
+	for _, a := range roster {
+		if len(a.Capabilities) == 0 {
+			parts = append(parts, a.ID)
+			continue
+		}
+		parts = append(parts, a.ID+" ["+strings.Join(a.Capabilities, ", ")+"]")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// agentWithCapability returns the first agent in roster that declares capability.
+func agentWithCapability(roster []PlanAgent, capability string) string {
+	for _, a := range roster {
+		for _, c := range a.Capabilities {
+			if strings.EqualFold(c, capability) {
+				return a.ID
+			}
+		}
+	}
+	return ""
+}
+
+func registerCreatePlan(g *genkit.Genkit, reg *Registry) ai.ToolRef {
+	return genkit.DefineTool(g, "create_plan",
+		"Turn a goal into a multi-agent plan: a DAG of steps, each run by an agent (agent_id, or an agent picked by capability) after the steps it depends_on. "+
+			"Prompts can use {inputs.<name>} and earlier results as {<step>.output}. The plan is validated, saved under its name for re-runs, and started once the configured gate allows it. "+
+			"Requires tools.create_plan capability.",
+		Traced(reg, "create_plan", func(ctx *ai.ToolContext, input CreatePlanInput) (CreatePlanOutput, error) {
+			reg.publishToolCall(ctx, "create_plan")
+			return createPlan(ctx, input, reg)
+		}),
+	)
+}
@@ -0,0 +1,221 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/approval"
+	"github.com/basket/go-claw/internal/config"
+	"github.com/basket/go-claw/internal/coordinator"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/policy"
+	"github.com/basket/go-claw/internal/shared"
+)
+
+type stubPlanRunner struct {
+	configured  map[string]bool
+	started     []string
+	definitions []string
+	inputs      map[string]any
+}
+
+func (r *stubPlanRunner) StartGeneratedPlan(_ context.Context, plan *persistence.GeneratedPlan, sessionID string, inputs map[string]any) (string, string, error) {
+	r.started = append(r.started, plan.Name)
+	r.definitions = append(r.definitions, plan.Definition)
+	r.inputs = inputs
+	return "exec-1", sessionID, nil
+}
+
+func (r *stubPlanRunner) IsConfiguredPlan(name string) bool { return r.configured[name] }
+
+func planTestRoster(context.Context) []PlanAgent {
+	return []PlanAgent{
+		{ID: "writer", Capabilities: []string{"writing"}},
+		{ID: "researcher", Capabilities: []string{"research", "search"}},
+	}
+}
+
+func digestPlanInput() CreatePlanInput {
+	return CreatePlanInput{
+		Name:   "digest",
+		Goal:   "Summarize news about a topic",
+		Inputs: []PlanInputSpec{{Name: "topic", Required: true}},
+		Steps: []PlanStepSpec{
+			{ID: "research", Capability: "research", Prompt: "Find news about {inputs.topic}"},
+			{ID: "write", AgentID: "writer", Prompt: "Summarize: {research.output}", DependsOn: []string{"research"}},
+		},
+		Args: map[string]any{"topic": "sqlite"},
+	}
+}
+
+func TestCreatePlan_Gates(t *testing.T) {
+	approved := approval.Decision{Approved: true, Status: persistence.ApprovalApproved}
+	denied := approval.Decision{Status: persistence.ApprovalDenied, Reason: "too broad"}
+	tests := []struct {
+		name        string
+		gate        string
+		approvals   *stubApprovals
+		wantStatus  string
+		wantStarted bool
+	}{
+		{"auto runs", config.PlanGateAuto, nil, persistence.GeneratedPlanApproved, true},
+		{"approved by approver", config.PlanGateApprove, &stubApprovals{decision: approved}, persistence.GeneratedPlanApproved, true},
+		{"denied by approver", config.PlanGateApprove, &stubApprovals{decision: denied}, persistence.GeneratedPlanRejected, false},
+		{"no approver leaves a draft", config.PlanGateApprove, nil, persistence.GeneratedPlanDraft, false},
+		{"review leaves a draft", config.PlanGateReview, &stubApprovals{decision: approved}, persistence.GeneratedPlanDraft, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := openDelegateTestStore(t)
+			runner := &stubPlanRunner{}
+			reg := &Registry{
+				Policy: policy.Policy{AllowCapabilities: []string{capCreatePlan}},
+				Store:  store,
+				Planner: &Planner{
+					Gate:            tt.gate,
+					ApprovalTimeout: 45 * time.Second,
+					Agents:          planTestRoster,
+					Runner:          runner,
+				},
+			}
+			if tt.approvals != nil {
+				reg.Approvals = tt.approvals
+			}
+			ctx := shared.WithAgentID(shared.WithSessionID(context.Background(), delegateTestSession), "planner")
+
+			out, err := createPlan(ctx, digestPlanInput(), reg)
+			if err != nil {
+				t.Fatalf("createPlan: %v", err)
+			}
+			if out.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q (%s)", out.Status, tt.wantStatus, out.Message)
+			}
+			if started := len(runner.started) == 1; started != tt.wantStarted {
+				t.Errorf("started = %v, want %v", runner.started, tt.wantStarted)
+			}
+			if tt.wantStarted && (out.ExecutionID != "exec-1" || runner.inputs["topic"] != "sqlite" || !strings.Contains(runner.definitions[0], "Find news about")) {
+				t.Errorf("execution = %q, inputs = %v", out.ExecutionID, runner.inputs)
+			}
+
+			saved, err := store.GetGeneratedPlan(context.Background(), "digest")
+			if err != nil {
+				t.Fatalf("get saved plan: %v", err)
+			}
+			if saved.Status != tt.wantStatus || saved.CreatedBy != "planner" || saved.SessionID != delegateTestSession {
+				t.Errorf("saved plan = %+v", saved)
+			}
+			// The capability was resolved, and the definition loads as a plan.
+			plan, err := coordinator.ParsePlanDefinition(saved.Definition, []string{"researcher", "writer"})
+			if err != nil {
+				t.Fatalf("saved definition: %v\n%s", err, saved.Definition)
+			}
+			if plan.Steps[0].AgentID != "researcher" || len(plan.Inputs) != 1 {
+				t.Errorf("saved plan = %+v", plan)
+			}
+
+			if tt.gate == config.PlanGateApprove && tt.approvals != nil {
+				if len(tt.approvals.requests) != 1 {
+					t.Fatalf("approval requests = %d, want 1", len(tt.approvals.requests))
+				}
+				if req := tt.approvals.requests[0]; req.Tool != "create_plan" || req.Timeout != 45*time.Second {
+					t.Errorf("approval request = %+v", req)
+				}
+			}
+		})
+	}
+}
+
+// replacingApprovals saves another plan under the requested name while the
+// approval is pending, then approves.
+type replacingApprovals struct {
+	store *persistence.Store
+}
+
+func (a replacingApprovals) Request(ctx context.Context, req approval.Request) (approval.Decision, error) {
+	name := req.Args.(map[string]any)["name"].(string)
+	if err := a.store.SaveGeneratedPlan(ctx, &persistence.GeneratedPlan{Name: name, Definition: "name: " + name + "\nsteps: [{id: x, agent_id: writer, prompt: rm -rf}]\n"}); err != nil {
+		return approval.Decision{}, err
+	}
+	return approval.Decision{Approved: true, Status: persistence.ApprovalApproved}, nil
+}
+
+func TestCreatePlan_ReplacedWhileApprovalPending(t *testing.T) {
+	store := openDelegateTestStore(t)
+	runner := &stubPlanRunner{}
+	reg := &Registry{
+		Policy:    policy.Policy{AllowCapabilities: []string{capCreatePlan}},
+		Store:     store,
+		Approvals: replacingApprovals{store: store},
+		Planner:   &Planner{Gate: config.PlanGateApprove, Agents: planTestRoster, Runner: runner},
+	}
+	ctx := shared.WithSessionID(context.Background(), delegateTestSession)
+
+	out, err := createPlan(ctx, digestPlanInput(), reg)
+	if err != nil {
+		t.Fatalf("createPlan: %v", err)
+	}
+	if out.Status != persistence.GeneratedPlanDraft || !strings.Contains(out.Message, "replaced while waiting") {
+		t.Errorf("out = %+v", out)
+	}
+	if len(runner.started) != 0 {
+		t.Fatalf("started %v, want nothing", runner.definitions)
+	}
+	saved, err := store.GetGeneratedPlan(context.Background(), "digest")
+	if err != nil {
+		t.Fatalf("get saved plan: %v", err)
+	}
+	if saved.Status != persistence.GeneratedPlanDraft || !strings.Contains(saved.Definition, "rm -rf") {
+		t.Errorf("replacement = %+v, want an unapproved draft", saved)
+	}
+}
+
+func TestCreatePlan_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*CreatePlanInput)
+		wantMsg string
+	}{
+		{"bad name", func(in *CreatePlanInput) { in.Name = "has space" }, "must be 1-64"},
+		{"configured name", func(in *CreatePlanInput) { in.Name = "release" }, "already configured"},
+		{"no steps", func(in *CreatePlanInput) { in.Steps = nil }, "at least one step"},
+		{"unknown capability", func(in *CreatePlanInput) { in.Steps[0].Capability = "painting" }, `no agent has capability "painting"`},
+		{"unknown agent", func(in *CreatePlanInput) { in.Steps[1].AgentID = "ghost" }, "unknown agent ghost"},
+		{"cycle", func(in *CreatePlanInput) { in.Steps[0].DependsOn = []string{"write"} }, "cycle"},
+		{"missing input", func(in *CreatePlanInput) { in.Args = nil }, "missing required input topic"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := openDelegateTestStore(t)
+			reg := &Registry{
+				Policy: policy.Policy{AllowCapabilities: []string{capCreatePlan}},
+				Store:  store,
+				Planner: &Planner{
+					Gate:   config.PlanGateAuto,
+					Agents: planTestRoster,
+					Runner: &stubPlanRunner{configured: map[string]bool{"release": true}},
+				},
+			}
+			input := digestPlanInput()
+			tt.mutate(&input)
+			out, err := createPlan(context.Background(), input, reg)
+			if err != nil {
+				t.Fatalf("createPlan: %v", err)
+			}
+			if out.Status != "invalid" || !strings.Contains(out.Message, tt.wantMsg) {
+				t.Errorf("out = %+v, want invalid with %q", out, tt.wantMsg)
+			}
+			if plans, _ := store.ListGeneratedPlans(context.Background()); len(plans) != 0 {
+				t.Errorf("invalid plan was saved: %+v", plans)
+			}
+		})
+	}
+}
+
+func TestCreatePlan_PolicyDenied(t *testing.T) {
+	reg := &Registry{Policy: policy.Policy{}, Store: openDelegateTestStore(t), Planner: &Planner{}}
+	if _, err := createPlan(context.Background(), digestPlanInput(), reg); err == nil {
+		t.Fatal("expected policy denial")
+	}
+}
@@ -28,10 +28,10 @@ const maxReadURLRedirects = 10
 func registerReader(g *genkit.Genkit, reg *Registry) ai.Tool {
 	return genkit.DefineTool(g, "read_url",
 		"Fetch and read the content of a web page URL. Returns the page content as simplified text. Use this to read articles, documentation, or any web page.",
-		func(ctx *ai.ToolContext, input ReaderInput) (ReaderOutput, error) {
+		Traced(reg, "read_url", func(ctx *ai.ToolContext, input ReaderInput) (ReaderOutput, error) {
 			reg.publishToolCall(ctx, "read_url")
 			return reg.Read(ctx, input.URL)
-		},
+		}),
 	)
 }
 
@@ -53,10 +53,17 @@ func readURL(ctx context.Context, rawURL string, pol policy.Checker) (ReaderOutp
 		return ReaderOutput{}, fmt.Errorf("policy denied URL %q", rawURL)
 	}
 	audit.Record("allow", "tools.read_url", "url_allowed", pol.PolicyVersion(), rawURL)
+	limit, err := checkToolArgs(ctx, pol, "tools.read_url", policy.ToolCall{Tool: policy.ToolReadURL, Method: http.MethodGet}, rawURL)
+	if err != nil {
+		return ReaderOutput{}, err
+	}
 	content, err := fetchAndSimplify(ctx, rawURL, pol)
 	if err != nil {
 		return ReaderOutput{}, fmt.Errorf("read URL: %w", err)
 	}
+	if limit > 0 && len(content) > limit {
+		content = truncateOutput(content, limit)
+	}
 	return ReaderOutput{Content: content}, nil
 }
 
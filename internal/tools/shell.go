@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/basket/go-claw/internal/audit"
+	"github.com/basket/go-claw/internal/policy"
 	"github.com/basket/go-claw/internal/shared"
 	"github.com/firebase/genkit/go/ai"
 	"github.com/firebase/genkit/go/genkit"
@@ -100,6 +101,7 @@ type ShellOutput struct {
 	Stdout   string `json:"stdout"`
 	Stderr   string `json:"stderr"`
 	ExitCode int    `json:"exit_code"`
+	Denied   string `json:"denied,omitempty"` // set when an approver refused the command
 }
 
 func registerShell(g *genkit.Genkit, reg *Registry) ai.ToolRef {
@@ -107,7 +109,7 @@ func registerShell(g *genkit.Genkit, reg *Registry) ai.ToolRef {
 
 	return genkit.DefineTool(g, "exec",
 		"Execute a shell command and return its output. Commands on the deny list (rm, sudo, kill, etc.) are blocked. Output is truncated to 8KB and secrets are redacted.",
-		func(ctx *ai.ToolContext, input ShellInput) (ShellOutput, error) {
+		Traced(reg, "exec", func(ctx *ai.ToolContext, input ShellInput) (ShellOutput, error) {
 			reg.publishToolCall(ctx, "exec")
 			if reg.Policy == nil || !reg.Policy.AllowCapability("tools.exec") {
 				pv := policyVersion(reg.Policy)
@@ -137,6 +139,19 @@ func registerShell(g *genkit.Genkit, reg *Registry) ai.ToolRef {
 					}
 				}
 			}
+			// Policy argument rules apply to every segment so a pipe cannot
+			// smuggle in a command the rule does not allow.
+			outLimit := maxShellOutput
+			for _, seg := range segments {
+				limit, err := checkToolArgs(ctx, reg.Policy, "tools.exec", policy.ToolCall{Tool: policy.ToolExec, Command: seg}, seg)
+				if err != nil {
+					return ShellOutput{}, err
+				}
+				outLimit = outputLimit(outLimit, limit)
+			}
+			if denied, err := reg.awaitApproval(ctx, policy.ToolExec, input); err != nil || denied != "" {
+				return ShellOutput{Denied: denied, ExitCode: -1}, err
+			}
 
 			// Determine timeout.
 			timeout := defaultShellTimeout
@@ -168,8 +183,8 @@ func registerShell(g *genkit.Genkit, reg *Registry) ai.ToolRef {
 				return ShellOutput{}, fmt.Errorf("exec: %w", err)
 			}
 
-			outStr := truncateOutput(stdout, maxShellOutput)
-			errStr := truncateOutput(stderr, maxShellOutput)
+			outStr := truncateOutput(stdout, outLimit)
+			errStr := truncateOutput(stderr, outLimit)
 
 			// Redact secrets from output.
 			outStr = shared.Redact(outStr)
@@ -180,7 +195,7 @@ func registerShell(g *genkit.Genkit, reg *Registry) ai.ToolRef {
 				Stderr:   errStr,
 				ExitCode: exitCode,
 			}, nil
-		},
+		}),
 	)
 }
 
@@ -45,10 +45,10 @@ var dollarRE = regexp.MustCompile(`\$\s?[0-9][0-9,]*(?:\.[0-9]+)?`)
 func registerShopping(g *genkit.Genkit, reg *Registry) ai.Tool {
 	return genkit.DefineTool(g, "price_comparison",
 		"Compare prices of two products by searching the web. Input should contain a comparison prompt like 'compare price of X vs Y'. Returns structured price data with sources.",
-		func(ctx *ai.ToolContext, input PriceComparisonInput) (PriceComparisonOutput, error) {
+		Traced(reg, "price_comparison", func(ctx *ai.ToolContext, input PriceComparisonInput) (PriceComparisonOutput, error) {
 			reg.publishToolCall(ctx, "price_comparison")
 			return comparePrices(ctx, input, reg)
-		},
+		}),
 	)
 }
 
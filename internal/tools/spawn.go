@@ -114,13 +114,13 @@ func spawnTask(ctx context.Context, input *SpawnTaskInput, store *persistence.St
 func registerSpawn(g *genkit.Genkit, reg *Registry) ai.ToolRef {
 	return genkit.DefineTool(g, "spawn_task",
 		"Create a subtask linked to a parent task. Requires tools.spawn_task capability.",
-		func(ctx *ai.ToolContext, input SpawnTaskInput) (SpawnTaskOutput, error) {
+		Traced(reg, "spawn_task", func(ctx *ai.ToolContext, input SpawnTaskInput) (SpawnTaskOutput, error) {
 			reg.publishToolCall(ctx, "spawn_task")
 			out, err := spawnTask(ctx, &input, reg.Store, reg.Policy)
 			if err != nil {
 				return SpawnTaskOutput{}, err
 			}
 			return *out, nil
-		},
+		}),
 	)
 }
@@ -2,8 +2,10 @@ package tools
 
 import (
 	"context"
+	"sync"
 
 	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/memory"
 	"github.com/basket/go-claw/internal/persistence"
 	"github.com/basket/go-claw/internal/policy"
 	"github.com/basket/go-claw/internal/shared"
@@ -22,6 +24,21 @@ type Registry struct {
 	Store             *persistence.Store // Optional: enables spawn_task tool
 	DelegationMaxHops int                // Max delegation chain depth (default 2)
 	Bus               *bus.Bus           // Optional: publishes tool call events for visibility
+	Approvals         ApprovalBroker     // Optional: answers require_approval gates; nil denies gated calls
+	Planner           *Planner           // Optional: enables create_plan (with Store)
+	Memory            *memory.Retriever  // Optional: ranks agent memories for memory_search
+
+	toolsMu   sync.RWMutex        // guards Tools once runtime (WASM) tools can change
+	wasmTools map[string]string   // WASM module name -> registered tool name
+	mcpTools  map[string]struct{} // registered MCP tool names
+}
+
+// ToolRefs returns a snapshot of the registered tools, safe to use while
+// WASM tools are being hot-swapped.
+func (r *Registry) ToolRefs() []ai.ToolRef {
+	r.toolsMu.RLock()
+	defer r.toolsMu.RUnlock()
+	return append([]ai.ToolRef(nil), r.Tools...)
 }
 
 // publishToolCall publishes a StreamToolCallEvent if Bus is non-nil.
@@ -108,6 +125,9 @@ func (r *Registry) RegisterAll(g *genkit.Genkit) {
 		r.Tools = append(r.Tools, delegateAsyncTool)
 		msgTools := registerMessaging(g, r)
 		r.Tools = append(r.Tools, msgTools...)
+		if r.Planner != nil {
+			r.Tools = append(r.Tools, registerCreatePlan(g, r))
+		}
 	}
 }
 
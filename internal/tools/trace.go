@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/shared"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// maxTracedBytes bounds the stored arguments and output of one traced call.
+const maxTracedBytes = 4000
+
+// Traced wraps a tool function so every call is appended to the tool-call
+// trace in reg.Store, linked to the task, run and session in the context.
+// Arguments, output and error are redacted before they are stored. With no
+// registry or store the function is called as is.
+func Traced[In, Out any](reg *Registry, name string, fn ai.ToolFunc[In, Out]) ai.ToolFunc[In, Out] {
+	return func(tc *ai.ToolContext, input In) (Out, error) {
+		start := time.Now()
+		out, err := fn(tc, input)
+		reg.recordToolCall(tc, name, input, out, err, start)
+		return out, err
+	}
+}
+
+func (r *Registry) recordToolCall(ctx context.Context, name string, input, output any, callErr error, start time.Time) {
+	if r == nil || r.Store == nil {
+		return
+	}
+	call := &persistence.ToolCall{
+		TaskID:     shared.TaskID(ctx),
+		RunID:      shared.RunID(ctx),
+		SessionID:  shared.SessionID(ctx),
+		AgentID:    shared.AgentID(ctx),
+		Tool:       name,
+		Args:       traceJSON(input),
+		DurationMS: time.Since(start).Milliseconds(),
+		StartedAt:  start.UTC(),
+	}
+	if callErr != nil {
+		call.Error = truncateOutput(shared.Redact(callErr.Error()), maxTracedBytes)
+	} else {
+		call.Output = traceJSON(output)
+	}
+	// The call may have ended because the task context expired; the trace
+	// should still show it.
+	if err := r.Store.RecordToolCall(context.WithoutCancel(ctx), call); err != nil {
+		slog.Warn("failed to record tool call", "tool", name, "task_id", call.TaskID, "error", err)
+	}
+}
+
+func traceJSON(v any) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return truncateOutput(shared.Redact(string(raw)), maxTracedBytes)
+}
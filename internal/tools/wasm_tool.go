@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/basket/go-claw/internal/audit"
+	"github.com/basket/go-claw/internal/sandbox/wasm"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// wasmToolPrefix namespaces WASM tools so modules cannot shadow built-ins.
+const wasmToolPrefix = "wasm_"
+
+// WASMToolName returns the Genkit tool name for a WASM tool manifest name.
+func WASMToolName(manifestName string) string {
+	return wasmToolPrefix + manifestName
+}
+
+// RegisterWASMTool exposes a loaded WASM module implementing the tool ABI as
+// a Genkit tool. Calling it again for the same module (e.g. after a hot-swap
+// rebuild) replaces the previous definition. Returns false if the module does
+// not implement the tool ABI.
+//
+// Tools are created unregistered (ai.NewTool) so that redefinition does not
+// collide in the Genkit registry; Generate resolves them dynamically.
+func (r *Registry) RegisterWASMTool(host *wasm.Host, moduleName string) (ai.ToolRef, bool) {
+	if host == nil {
+		return nil, false
+	}
+	manifest, ok := host.ToolManifest(moduleName)
+	if !ok {
+		// Module was rebuilt without the tool ABI: drop any stale definition.
+		r.RemoveWASMTool(moduleName)
+		return nil, false
+	}
+
+	toolName := WASMToolName(manifest.Name)
+	schema := map[string]any{"type": "object"}
+	if len(manifest.InputSchema) > 0 {
+		if err := json.Unmarshal(manifest.InputSchema, &schema); err != nil {
+			slog.Warn("failed to parse wasm tool input schema", "tool", toolName, "error", err)
+			schema = map[string]any{"type": "object"}
+		}
+	}
+
+	t := ai.NewTool(toolName, manifest.Description,
+		Traced(r, toolName, func(ctx *ai.ToolContext, input any) (any, error) {
+			r.publishToolCall(ctx, toolName)
+			if r.Policy == nil || !r.Policy.AllowCapability("wasm.tool") {
+				audit.Record("deny", "wasm.tool", "missing_capability", policyVersion(r.Policy), toolName)
+				return nil, fmt.Errorf("policy denied capability %q", "wasm.tool")
+			}
+			audit.Record("allow", "wasm.tool", "capability_granted", policyVersion(r.Policy), toolName)
+
+			argsJSON, err := json.Marshal(input)
+			if err != nil {
+				return nil, fmt.Errorf("wasm tool %s: marshal args: %w", toolName, err)
+			}
+			out, err := host.InvokeTool(ctx, moduleName, argsJSON)
+			if err != nil {
+				return nil, fmt.Errorf("wasm tool %s: %w", toolName, err)
+			}
+
+			var res any
+			if err := json.Unmarshal(out, &res); err != nil {
+				return string(out), nil
+			}
+			return res, nil
+		}),
+		ai.WithInputSchema(schema),
+	)
+
+	r.toolsMu.Lock()
+	defer r.toolsMu.Unlock()
+	if r.wasmTools == nil {
+		r.wasmTools = make(map[string]string)
+	}
+	// Drop the previous definition (the manifest name may have changed).
+	if prev, ok := r.wasmTools[moduleName]; ok {
+		r.Tools = removeToolRef(r.Tools, prev)
+	}
+	r.Tools = removeToolRef(r.Tools, toolName)
+	r.Tools = append(r.Tools, t)
+	r.wasmTools[moduleName] = toolName
+	slog.Info("registered wasm tool", "module", moduleName, "tool", toolName)
+	return t, true
+}
+
+// RemoveWASMTool removes the tool registered for moduleName, if any.
+func (r *Registry) RemoveWASMTool(moduleName string) {
+	r.toolsMu.Lock()
+	defer r.toolsMu.Unlock()
+	if name, ok := r.wasmTools[moduleName]; ok {
+		r.Tools = removeToolRef(r.Tools, name)
+		delete(r.wasmTools, moduleName)
+	}
+}
+
+// removeToolRef returns a copy of refs without the tool named name.
+// A copy is returned so snapshots handed out by ToolRefs stay intact.
+func removeToolRef(refs []ai.ToolRef, name string) []ai.ToolRef {
+	out := make([]ai.ToolRef, 0, len(refs))
+	for _, ref := range refs {
+		if ref.Name() != name {
+			out = append(out, ref)
+		}
+	}
+	return out
+}
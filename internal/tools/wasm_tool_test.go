@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/basket/go-claw/internal/sandbox/wasm"
+)
+
+func loadEchoToolHost(t *testing.T) *wasm.Host {
+	t.Helper()
+	ctx := context.Background()
+	h, err := wasm.NewHost(ctx, wasm.Config{})
+	if err != nil {
+		t.Fatalf("new host: %v", err)
+	}
+	t.Cleanup(func() { _ = h.Close(ctx) })
+
+	b, err := os.ReadFile(filepath.Join("..", "sandbox", "wasm", "testdata", "echo_tool.wasm"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	if err := h.LoadModuleFromBytes(ctx, "echo", b, "echo_tool.wasm"); err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	return h
+}
+
+func TestRegisterWASMTool_InvokesModule(t *testing.T) {
+	host := loadEchoToolHost(t)
+	reg := &Registry{Policy: &mcpTestPolicy{allowed: true}}
+
+	ref, ok := reg.RegisterWASMTool(host, "echo")
+	if !ok {
+		t.Fatal("expected echo module to register as a tool")
+	}
+	if ref.Name() != "wasm_echo" {
+		t.Fatalf("tool name = %q, want wasm_echo", ref.Name())
+	}
+
+	tool, ok := ref.(interface {
+		RunRaw(ctx context.Context, input any) (any, error)
+	})
+	if !ok {
+		t.Fatalf("tool ref %T does not support RunRaw", ref)
+	}
+	out, err := tool.RunRaw(context.Background(), map[string]any{"text": "hi"})
+	if err != nil {
+		t.Fatalf("RunRaw: %v", err)
+	}
+	if want := map[string]any{"text": "hi"}; !reflect.DeepEqual(out, want) {
+		t.Fatalf("output = %#v, want %#v", out, want)
+	}
+}
+
+func TestRegisterWASMTool_PolicyDenied(t *testing.T) {
+	host := loadEchoToolHost(t)
+	reg := &Registry{Policy: &mcpTestPolicy{allowed: false}}
+
+	ref, _ := reg.RegisterWASMTool(host, "echo")
+	tool := ref.(interface {
+		RunRaw(ctx context.Context, input any) (any, error)
+	})
+	_, err := tool.RunRaw(context.Background(), map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), "wasm.tool") {
+		t.Fatalf("expected policy denial, got %v", err)
+	}
+}
+
+func TestRegisterWASMTool_ReregisterReplaces(t *testing.T) {
+	host := loadEchoToolHost(t)
+	reg := &Registry{Policy: &mcpTestPolicy{allowed: true}}
+
+	reg.RegisterWASMTool(host, "echo")
+	reg.RegisterWASMTool(host, "echo") // hot-swap rebuild
+	count := 0
+	for _, ref := range reg.ToolRefs() {
+		if ref.Name() == "wasm_echo" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Fatalf("expected exactly one wasm_echo tool after re-registration, got %d", count)
+	}
+
+	// Rebuilding without the ABI removes the tool.
+	plain := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	if err := host.LoadModuleFromBytes(context.Background(), "echo", plain, "plain.wasm"); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if _, ok := reg.RegisterWASMTool(host, "echo"); ok {
+		t.Fatal("plain module should not register as a tool")
+	}
+	if len(reg.ToolRefs()) != 0 {
+		t.Fatalf("expected stale tool to be removed, have %d tools", len(reg.ToolRefs()))
+	}
+}
@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/basket/go-claw/internal/approval"
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+// shortApprovalID is the ID prefix shown in the chat; commands accept any
+// unambiguous prefix.
+const shortApprovalID = 8
+
+// isApprovalCommand reports whether line is a command that must work while a
+// turn is in flight, since that turn may be the one waiting for approval.
+func isApprovalCommand(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+	cmd := strings.ToLower(fields[0])
+	return cmd == "/approve" || cmd == "/deny" || cmd == "/approvals"
+}
+
+func requireApprovals(cc *ChatConfig, out io.Writer) bool {
+	if cc.Approvals == nil {
+		fmt.Fprintln(out, "  Tool approvals not available.")
+		fmt.Fprintln(out)
+		return false
+	}
+	return true
+}
+
+// handleApprovalsCommand processes /approvals.
+func handleApprovalsCommand(ctx context.Context, cc *ChatConfig, out io.Writer) {
+	if !requireApprovals(cc, out) {
+		return
+	}
+	pending, err := cc.Approvals.Pending(ctx)
+	if err != nil {
+		fmt.Fprintf(out, "  Error listing approvals: %v\n\n", err)
+		return
+	}
+	if len(pending) == 0 {
+		fmt.Fprintln(out, "  No tool calls waiting for approval.")
+		fmt.Fprintln(out)
+		return
+	}
+	fmt.Fprintf(out, "  Tool calls waiting for approval (%d):\n", len(pending))
+	for _, a := range pending {
+		fmt.Fprintf(out, "    • %s @%s %s %s\n", shortID(a.ID), a.AgentID, a.Tool, a.Args)
+	}
+	fmt.Fprintln(out)
+}
+
+// handleApproveCommand processes /approve <id> [session].
+func handleApproveCommand(ctx context.Context, arg string, cc *ChatConfig, out io.Writer) {
+	if !requireApprovals(cc, out) {
+		return
+	}
+	fields := strings.Fields(arg)
+	if len(fields) == 0 || len(fields) > 2 || (len(fields) == 2 && !strings.EqualFold(fields[1], "session")) {
+		fmt.Fprintln(out, "  Usage: /approve <id> [session]")
+		fmt.Fprintln(out)
+		return
+	}
+	action := approval.ActionApprove
+	if len(fields) == 2 {
+		action = approval.ActionApproveSession
+	}
+	respondToApproval(ctx, cc, fields[0], action, "", out)
+}
+
+// handleDenyCommand processes /deny <id> [reason].
+func handleDenyCommand(ctx context.Context, arg string, cc *ChatConfig, out io.Writer) {
+	if !requireApprovals(cc, out) {
+		return
+	}
+	id, reason, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	if id == "" {
+		fmt.Fprintln(out, "  Usage: /deny <id> [reason]")
+		fmt.Fprintln(out)
+		return
+	}
+	respondToApproval(ctx, cc, id, approval.ActionDeny, strings.TrimSpace(reason), out)
+}
+
+func respondToApproval(ctx context.Context, cc *ChatConfig, prefix, action, reason string, out io.Writer) {
+	id, err := resolveApprovalID(ctx, cc.Approvals, prefix)
+	if err != nil {
+		fmt.Fprintf(out, "  %v\n\n", err)
+		return
+	}
+	rec, err := cc.Approvals.Respond(ctx, id, action, reason)
+	if err != nil {
+		fmt.Fprintf(out, "  Error: %v\n\n", err)
+		return
+	}
+	fmt.Fprintf(out, "  %s %s for @%s: %s\n\n", rec.Tool, shortID(rec.ID), rec.AgentID, strings.ToLower(rec.Status))
+}
+
+// resolveApprovalID expands an ID prefix against the pending approvals.
+func resolveApprovalID(ctx context.Context, b *approval.Broker, prefix string) (string, error) {
+	pending, err := b.Pending(ctx)
+	if err != nil {
+		return "", fmt.Errorf("list approvals: %w", err)
+	}
+	var matches []*persistence.ToolApproval
+	for _, a := range pending {
+		if a.ID == prefix {
+			return a.ID, nil
+		}
+		if strings.HasPrefix(a.ID, prefix) {
+			matches = append(matches, a)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no pending approval matches %q", prefix)
+	case 1:
+		return matches[0].ID, nil
+	default:
+		return "", fmt.Errorf("approval ID %q is ambiguous (%d matches)", prefix, len(matches))
+	}
+}
+
+// formatApprovalRequest renders the chat notice for a parked tool call.
+func formatApprovalRequest(req bus.ToolApprovalRequest) string {
+	id := shortID(req.RequestID)
+	return fmt.Sprintf("Approval needed: @%s wants to run %s %s\n/approve %s · /approve %s session · /deny %s [reason]",
+		req.AgentID, req.Tool, req.Args, id, id, id)
+}
+
+func shortID(id string) string {
+	if len(id) > shortApprovalID {
+		return id[:shortApprovalID]
+	}
+	return id
+}
@@ -0,0 +1,102 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basket/go-claw/internal/approval"
+	"github.com/basket/go-claw/internal/bus"
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+func TestIsApprovalCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"/approve abc", true},
+		{"/DENY abc too risky", true},
+		{"/approvals", true},
+		{"/approved", false},
+		{"/help", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isApprovalCommand(tt.line); got != tt.want {
+			t.Errorf("isApprovalCommand(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestApprovalCommands(t *testing.T) {
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "goclaw.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	eventBus := bus.New()
+	sub := eventBus.Subscribe(bus.TopicToolApprovalRequested)
+	defer eventBus.Unsubscribe(sub)
+	cc := &ChatConfig{Approvals: approval.NewBroker(store, eventBus)}
+	ctx := context.Background()
+
+	done := make(chan approval.Decision, 1)
+	go func() {
+		d, _ := cc.Approvals.Request(ctx, approval.Request{TaskID: "t1", AgentID: "coder", Tool: "exec", Args: "make deploy", Timeout: time.Minute})
+		done <- d
+	}()
+	var req bus.ToolApprovalRequest
+	select {
+	case ev := <-sub.Ch():
+		req = ev.Payload.(bus.ToolApprovalRequest)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for approval request")
+	}
+	if notice := formatApprovalRequest(req); !strings.Contains(notice, "/approve "+shortID(req.RequestID)) {
+		t.Errorf("notice missing approve hint: %q", notice)
+	}
+
+	var out bytes.Buffer
+	handleApprovalsCommand(ctx, cc, &out)
+	if !strings.Contains(out.String(), shortID(req.RequestID)) || !strings.Contains(out.String(), "@coder exec") {
+		t.Errorf("/approvals output = %q", out.String())
+	}
+
+	out.Reset()
+	handleApproveCommand(ctx, "abc extra words", cc, &out)
+	if !strings.Contains(out.String(), "Usage: /approve") {
+		t.Errorf("bad /approve args: %q", out.String())
+	}
+
+	out.Reset()
+	handleDenyCommand(ctx, shortID(req.RequestID)+" use the staging target", cc, &out)
+	if !strings.Contains(out.String(), "denied") {
+		t.Errorf("/deny output = %q", out.String())
+	}
+	select {
+	case d := <-done:
+		if d.Approved || d.Reason != "use the staging target" {
+			t.Errorf("decision = %+v", d)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for decision")
+	}
+
+	out.Reset()
+	handleApproveCommand(ctx, "zzz", cc, &out)
+	if !strings.Contains(out.String(), "no pending approval") {
+		t.Errorf("unknown id output = %q", out.String())
+	}
+}
+
+func TestApprovalCommands_Unavailable(t *testing.T) {
+	var out bytes.Buffer
+	handleApprovalsCommand(context.Background(), &ChatConfig{}, &out)
+	if !strings.Contains(out.String(), "not available") {
+		t.Errorf("output = %q", out.String())
+	}
+}
@@ -1,30 +1,39 @@
 package tui
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/google/uuid"
 
+	"github.com/basket/go-claw/internal/approval"
 	"github.com/basket/go-claw/internal/bus"
 	"github.com/basket/go-claw/internal/config"
+	"github.com/basket/go-claw/internal/coordinator"
 	"github.com/basket/go-claw/internal/engine"
+	"github.com/basket/go-claw/internal/mcp"
 	"github.com/basket/go-claw/internal/memory"
 	"github.com/basket/go-claw/internal/persistence"
 	"github.com/basket/go-claw/internal/policy"
+	"github.com/basket/go-claw/internal/shared"
 	"github.com/basket/go-claw/internal/tools"
 )
 
 // AgentInfo holds display information about an agent.
 type AgentInfo struct {
-	ID          string
-	DisplayName string
-	Emoji       string
-	Model       string
+	ID            string
+	DisplayName   string
+	Emoji         string
+	Model         string
+	PolicyProfile string // empty = global policy
 }
 
 // AgentSwitcher allows the TUI to list and switch between agents.
@@ -51,9 +60,12 @@ type ChatConfig struct {
 	AgentEmoji   string
 	Switcher     AgentSwitcher // nil = single agent mode (backward compat)
 	CurrentAgent string
-	EventBus     *bus.Bus // nil = no plan event tracking
-	BindAddr     string   // gateway address for /plan execution
-	AuthToken    string   // auth token for gateway API calls
+	EventBus     *bus.Bus           // nil = no plan event tracking
+	BindAddr     string             // gateway address for /plan execution
+	AuthToken    string             // auth token for gateway API calls
+	MCP          *mcp.Manager       // nil = MCP prompts/resources unavailable
+	Approvals    *approval.Broker   // nil = tool approvals unavailable
+	Usage        *shared.UsageMeter // token usage of this chat's model calls; set by RunChat
 }
 
 // RunChat runs an interactive chat UI on stdin/stdout.
@@ -64,6 +76,10 @@ func RunChat(ctx context.Context, cc ChatConfig) error {
 		return fmt.Errorf("create session: %w", err)
 	}
 
+	if cc.Usage == nil {
+		cc.Usage = &shared.UsageMeter{}
+	}
+
 	model := cc.ModelName
 	if model == "" {
 		model = "Gemini 2.5 Flash"
@@ -119,9 +135,19 @@ func handleCommand(ctx context.Context, line string, cc *ChatConfig, sessionID s
 		fmt.Fprintln(out, "    /model                       Interactive provider/model selector")
 		fmt.Fprintln(out, "    /model list                  List all providers and models")
 		fmt.Fprintln(out, "    /model set <provider/model>  Set model (e.g. /model set gemini/gemini-2.5-pro)")
-		fmt.Fprintln(out, "    /plan [<name>]               Run a configured plan (GC-SPEC-PDR-v4-Phase-4)")
+		fmt.Fprintln(out, "    /plan run <name> [k=v ...]   Run a configured plan with inputs (GC-SPEC-PDR-v4-Phase-4)")
+		fmt.Fprintln(out, "    /plan list                   List configured and agent-generated plans")
+		fmt.Fprintln(out, "    /plan show <name>            Show a plan's definition")
+		fmt.Fprintln(out, "    /plan approve <name> [k=v]   Approve a generated plan and run it")
+		fmt.Fprintln(out, "    /plan reject <name>          Reject a generated plan")
+		fmt.Fprintln(out, "    /plan edit <name> <file>     Replace a generated plan with a YAML file")
+		fmt.Fprintln(out, "    /plan status <exec>          Show a plan execution's steps")
+		fmt.Fprintln(out, "    /plan pause|resume <exec>    Pause or resume a plan execution")
+		fmt.Fprintln(out, "    /plan cancel <exec>          Cancel a plan execution")
+		fmt.Fprintln(out, "    /plan rerun <exec> <step>    Re-run a finished execution from a step")
 		fmt.Fprintln(out, "    /plans                       Show active plan executions (any key to exit)")
 		fmt.Fprintln(out, "    /session                     Show current session ID")
+		fmt.Fprintln(out, "    /trace [task_id]             Show tool calls of the last response (or a task)")
 		fmt.Fprintln(out)
 		fmt.Fprintln(out, "  Memory & Context:")
 		fmt.Fprintln(out, "    /memory list                 List stored facts for current agent")
@@ -131,6 +157,7 @@ func handleCommand(ctx context.Context, line string, cc *ChatConfig, sessionID s
 		fmt.Fprintln(out, "    /forget <key>                Remove a fact")
 		fmt.Fprintln(out, "    /pin <filepath>              Pin file to agent's context")
 		fmt.Fprintln(out, "    /pin text <label> <text>     Pin arbitrary text/notes")
+		fmt.Fprintln(out, "    /pin mcp <server> <uri>      Pin an MCP resource (refreshed on change)")
 		fmt.Fprintln(out, "    /unpin <source>              Remove a pinned item")
 		fmt.Fprintln(out, "    /pinned                      List all pinned files for agent")
 		fmt.Fprintln(out, "    /context                     Show token budget and context allocation")
@@ -138,6 +165,16 @@ func handleCommand(ctx context.Context, line string, cc *ChatConfig, sessionID s
 		fmt.Fprintln(out, "    /unshare <key> from <agent>  Revoke memory sharing")
 		fmt.Fprintln(out, "    /shared                      List shared knowledge available to agent")
 		fmt.Fprintln(out, "    /clear                       Clear conversation history")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "  MCP:")
+		fmt.Fprintln(out, "    /prompts                     List prompt templates from MCP servers")
+		fmt.Fprintln(out, "    /prompt <server>/<name> [k=v] Render a prompt and send it as your message")
+		fmt.Fprintln(out, "    /resources                   List resources from MCP servers")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "  Approvals:")
+		fmt.Fprintln(out, "    /approvals                   List tool calls waiting for approval")
+		fmt.Fprintln(out, "    /approve <id> [session]      Approve once, or for the rest of the session")
+		fmt.Fprintln(out, "    /deny <id> [reason]          Deny; the reason is shown to the agent")
 		fmt.Fprintln(out, "    /quit                        Exit the chat")
 		fmt.Fprintln(out)
 		fmt.Fprintln(out, "  Shortcuts:")
@@ -185,6 +222,9 @@ func handleCommand(ctx context.Context, line string, cc *ChatConfig, sessionID s
 	case "/session":
 		fmt.Fprintf(out, "  Session: %s\n\n", sessionID)
 
+	case "/trace":
+		handleTraceCommand(ctx, arg, cc, sessionID, out)
+
 	case "/agent", "/agents":
 		handleAgentCommand(ctx, arg, cc, out)
 
@@ -231,7 +271,25 @@ func handleCommand(ctx context.Context, line string, cc *ChatConfig, sessionID s
 		handleSharedCommand(ctx, cc, out)
 
 	case "/context":
-		handleContextCommand(ctx, cc, out)
+		handleContextCommand(ctx, cc, sessionID, out)
+
+	case "/prompts":
+		handlePromptsCommand(ctx, cc, out)
+
+	case "/prompt":
+		handlePromptCommand(ctx, arg, cc, out)
+
+	case "/resources":
+		handleResourcesCommand(ctx, cc, out)
+
+	case "/approvals":
+		handleApprovalsCommand(ctx, cc, out)
+
+	case "/approve":
+		handleApproveCommand(ctx, arg, cc, out)
+
+	case "/deny":
+		handleDenyCommand(ctx, arg, cc, out)
 
 	default:
 		fmt.Fprintf(out, "  Unknown command: %s (type /help for available commands)\n\n", cmd)
@@ -240,11 +298,33 @@ func handleCommand(ctx context.Context, line string, cc *ChatConfig, sessionID s
 	return false
 }
 
-// handlePlanCommand executes a plan via the gateway REST API.
+// handlePlanCommand manages plans via the gateway REST API:
+//
+//	/plan [run] <name> [key=value ...]   run a plan with inputs
+//	/plan list                           list configured and generated plans
+//	/plan show <name>                    show a plan's definition
+//	/plan approve <name> [key=value ...] approve a generated plan and run it
+//	/plan reject <name>                  reject a generated plan
+//	/plan edit <name> <file.yaml>        replace a generated plan's definition
+//	/plan status <execution_id>          show an execution's steps
+//	/plan pause|resume|cancel <exec_id>  control a running execution
+//	/plan rerun <execution_id> <step>    re-run a finished execution from a step
 func handlePlanCommand(arg string, cc *ChatConfig, out io.Writer) {
-	name := strings.TrimSpace(arg)
-	if name == "" {
-		fmt.Fprintln(out, "  Usage: /plan <name>")
+	arg = strings.TrimSpace(arg)
+	sub, rest, _ := strings.Cut(arg, " ")
+	switch sub {
+	case "run", "list", "show", "approve", "reject", "edit",
+		"status", "pause", "resume", "cancel", "rerun":
+		rest = strings.TrimSpace(rest)
+	default:
+		sub, rest = "run", arg
+	}
+	name, args, _ := strings.Cut(rest, " ")
+	args = strings.TrimSpace(args)
+	if name == "" && sub != "list" {
+		fmt.Fprintln(out, "  Usage: /plan run <name> [key=value ...]")
+		fmt.Fprintln(out, "         /plan list | show <name> | approve <name> [key=value ...] | reject <name> | edit <name> <file.yaml>")
+		fmt.Fprintln(out, "         /plan status|pause|resume|cancel <execution_id> | rerun <execution_id> <step>")
 		fmt.Fprintln(out)
 		return
 	}
@@ -254,38 +334,190 @@ func handlePlanCommand(arg string, cc *ChatConfig, out io.Writer) {
 		return
 	}
 
-	url := fmt.Sprintf("http://%s/api/plans/%s/execute", cc.BindAddr, name)
-	req, err := http.NewRequest(http.MethodPost, url, nil)
+	switch sub {
+	case "list":
+		var result struct {
+			Plans []struct {
+				Name      string `json:"name"`
+				StepCount int    `json:"step_count"`
+				Generated bool   `json:"generated"`
+				Goal      string `json:"goal"`
+				Status    string `json:"status"`
+			} `json:"plans"`
+		}
+		if err := planAPI(cc, http.MethodGet, "/api/plans", nil, &result); err != nil {
+			fmt.Fprintf(out, "  Error: %s\n\n", err)
+			return
+		}
+		if len(result.Plans) == 0 {
+			fmt.Fprintln(out, "  No plans.")
+			fmt.Fprintln(out)
+			return
+		}
+		sort.Slice(result.Plans, func(i, j int) bool { return result.Plans[i].Name < result.Plans[j].Name })
+		for _, p := range result.Plans {
+			if p.Generated {
+				fmt.Fprintf(out, "  %-20s %d steps  [%s] %s\n", p.Name, p.StepCount, p.Status, p.Goal)
+			} else {
+				fmt.Fprintf(out, "  %-20s %d steps  [configured]\n", p.Name, p.StepCount)
+			}
+		}
+		fmt.Fprintln(out)
+
+	case "show":
+		var result struct {
+			StepCount  int      `json:"step_count"`
+			AgentIDs   []string `json:"agent_ids"`
+			Goal       string   `json:"goal"`
+			Status     string   `json:"status"`
+			Definition string   `json:"definition"`
+		}
+		if err := planAPI(cc, http.MethodGet, "/api/plans/"+name, nil, &result); err != nil {
+			fmt.Fprintf(out, "  Error: %s\n\n", err)
+			return
+		}
+		if result.Definition == "" {
+			fmt.Fprintf(out, "  Plan '%s' (configured): %d steps, agents %s\n\n", name, result.StepCount, strings.Join(result.AgentIDs, ", "))
+			return
+		}
+		fmt.Fprintf(out, "  Plan '%s' [%s]\n  Goal: %s\n\n", name, result.Status, result.Goal)
+		for _, line := range strings.Split(strings.TrimRight(result.Definition, "\n"), "\n") {
+			fmt.Fprintf(out, "    %s\n", line)
+		}
+		fmt.Fprintln(out)
+
+	case "reject":
+		if err := planAPI(cc, http.MethodPost, "/api/plans/"+name+"/reject", nil, nil); err != nil {
+			fmt.Fprintf(out, "  Error: %s\n\n", err)
+			return
+		}
+		fmt.Fprintf(out, "  Plan '%s' rejected.\n\n", name)
+
+	case "edit":
+		if args == "" {
+			fmt.Fprintln(out, "  Usage: /plan edit <name> <file.yaml>")
+			fmt.Fprintln(out)
+			return
+		}
+		data, err := os.ReadFile(args)
+		if err != nil {
+			fmt.Fprintf(out, "  Error: %s\n\n", err)
+			return
+		}
+		if err := planAPI(cc, http.MethodPut, "/api/plans/"+name, map[string]string{"definition": string(data)}, nil); err != nil {
+			fmt.Fprintf(out, "  Error: %s\n\n", err)
+			return
+		}
+		fmt.Fprintf(out, "  Plan '%s' updated. Run it with /plan approve %s\n\n", name, name)
+
+	case "status":
+		var result struct {
+			PlanName       string `json:"plan_name"`
+			Status         string `json:"status"`
+			TotalSteps     int    `json:"total_steps"`
+			CompletedSteps int    `json:"completed_steps"`
+			Steps          []struct {
+				StepID string `json:"step_id"`
+				Status string `json:"status"`
+				Error  string `json:"error"`
+			} `json:"steps"`
+		}
+		if err := planAPI(cc, http.MethodGet, "/api/plans/executions/"+name, nil, &result); err != nil {
+			fmt.Fprintf(out, "  Error: %s\n\n", err)
+			return
+		}
+		fmt.Fprintf(out, "  Plan '%s' [%s] %d/%d steps done\n", result.PlanName, result.Status, result.CompletedSteps, result.TotalSteps)
+		for _, step := range result.Steps {
+			if step.Error != "" {
+				fmt.Fprintf(out, "    %-20s %-10s %s\n", step.StepID, step.Status, step.Error)
+			} else {
+				fmt.Fprintf(out, "    %-20s %s\n", step.StepID, step.Status)
+			}
+		}
+		fmt.Fprintln(out)
+
+	case "pause", "resume", "cancel":
+		if err := planAPI(cc, http.MethodPost, "/api/plans/executions/"+name+"/"+sub, nil, nil); err != nil {
+			fmt.Fprintf(out, "  Error: %s\n\n", err)
+			return
+		}
+		done := map[string]string{"pause": "paused", "resume": "resumed", "cancel": "canceled"}[sub]
+		fmt.Fprintf(out, "  Execution %s %s.\n\n", name, done)
+
+	case "rerun":
+		if args == "" {
+			fmt.Fprintln(out, "  Usage: /plan rerun <execution_id> <step>")
+			fmt.Fprintln(out)
+			return
+		}
+		if err := planAPI(cc, http.MethodPost, "/api/plans/executions/"+name+"/rerun", map[string]string{"step_id": args}, nil); err != nil {
+			fmt.Fprintf(out, "  Error: %s\n\n", err)
+			return
+		}
+		fmt.Fprintf(out, "  Execution %s re-running from step '%s'.\n\n", name, args)
+
+	default: // run, approve
+		inputs, err := coordinator.ParseInputArgs(args)
+		if err != nil {
+			fmt.Fprintf(out, "  Error: %s\n\n", err)
+			return
+		}
+		action, verb := "execute", "started"
+		if sub == "approve" {
+			action, verb = "approve", "approved and started"
+		}
+		var result map[string]any
+		if err := planAPI(cc, http.MethodPost, "/api/plans/"+name+"/"+action, map[string]any{"inputs": inputs}, &result); err != nil {
+			fmt.Fprintf(out, "  Error: %s\n\n", err)
+			return
+		}
+		execID, _ := result["execution_id"].(string)
+		fmt.Fprintf(out, "  Plan '%s' %s (execution_id: %s)\n\n", name, verb, execID)
+	}
+}
+
+// planAPI sends a request to the gateway's plan API and decodes the JSON
+// reply into result when it is non-nil. Errors carry the gateway's message.
+func planAPI(cc *ChatConfig, method, path string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, "http://"+cc.BindAddr+path, reader)
 	if err != nil {
-		fmt.Fprintf(out, "  Error: %s\n\n", err)
-		return
+		return err
 	}
 	req.Header.Set("Authorization", "Bearer "+cc.AuthToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		fmt.Fprintf(out, "  Error: %s\n\n", err)
-		return
+		return err
 	}
 	defer resp.Body.Close()
 
-	var result map[string]any
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		fmt.Fprintf(out, "  Error decoding response: %s\n\n", err)
-		return
-	}
-
-	if resp.StatusCode != http.StatusAccepted {
-		msg := "unknown error"
-		if e, ok := result["error"].(string); ok {
-			msg = e
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// The gateway answers errors in plain text.
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		text := strings.TrimSpace(string(msg))
+		if text == "" {
+			text = resp.Status
 		}
-		fmt.Fprintf(out, "  Error: %s\n\n", msg)
-		return
+		return errors.New(text)
 	}
-
-	execID, _ := result["execution_id"].(string)
-	fmt.Fprintf(out, "  Plan '%s' started (execution_id: %s)\n\n", name, execID)
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
 }
 
 // handleSkillsCommand processes /skills and /skills setup <name>.
@@ -915,6 +1147,7 @@ func handleConfigCommand(arg string, cc *ChatConfig, out io.Writer) {
 
 	switch sub {
 	case "list":
+		fmt.Fprintf(out, "  Policy profile for @%s: %s\n", effectiveAgentID(cc), policyProfileLabel(cc))
 		if cc.Cfg == nil || len(cc.Cfg.APIKeys) == 0 {
 			fmt.Fprintln(out, "  No API keys configured.")
 			fmt.Fprintln(out, "  Use: /config set <key> <value>")
@@ -956,6 +1189,19 @@ func handleConfigCommand(arg string, cc *ChatConfig, out io.Writer) {
 	}
 }
 
+// policyProfileLabel names the policy profile in effect for the current agent.
+func policyProfileLabel(cc *ChatConfig) string {
+	if cc.Switcher != nil {
+		agentID := effectiveAgentID(cc)
+		for _, info := range cc.Switcher.ListAgentInfo() {
+			if info.ID == agentID && info.PolicyProfile != "" {
+				return info.PolicyProfile
+			}
+		}
+	}
+	return "(global)"
+}
+
 // maskValue shows the first 4 chars and masks the rest.
 func maskValue(v string) string {
 	if len(v) <= 4 {
@@ -1136,7 +1382,7 @@ func handlePinCommand(ctx context.Context, arg string, cc *ChatConfig, out io.Wr
 
 	arg = strings.TrimSpace(arg)
 	if arg == "" {
-		fmt.Fprintln(out, "  Usage: /pin <filepath> or /pin text <label> <content>")
+		fmt.Fprintln(out, "  Usage: /pin <filepath>, /pin text <label> <content> or /pin mcp <server> <uri>")
 		fmt.Fprintln(out)
 		return
 	}
@@ -1160,6 +1406,12 @@ func handlePinCommand(ctx context.Context, arg string, cc *ChatConfig, out io.Wr
 		return
 	}
 
+	// MCP resource pin
+	if strings.HasPrefix(arg, "mcp ") {
+		handlePinMCPResource(ctx, strings.TrimPrefix(arg, "mcp "), agentID, cc, out)
+		return
+	}
+
 	// File pin
 	filepath := arg
 	pinMgr := memory.NewPinManager(cc.Store)
@@ -1185,6 +1437,11 @@ func handleUnpinCommand(ctx context.Context, arg string, cc *ChatConfig, out io.
 
 	agentID := effectiveAgentID(cc)
 
+	// Stop live updates for pinned MCP resources.
+	if server, uri, ok := mcp.ParseResourcePinSource(arg); ok && cc.MCP != nil {
+		_ = cc.MCP.UnpinResource(ctx, agentID, server, uri)
+	}
+
 	if err := cc.Store.RemovePin(ctx, agentID, arg); err != nil {
 		fmt.Fprintf(out, "  Error unpinning: %v\n\n", err)
 		return
@@ -1353,8 +1610,9 @@ func handleSharedCommand(ctx context.Context, cc *ChatConfig, out io.Writer) {
 	fmt.Fprintln(out)
 }
 
-// handleContextCommand displays the token budget for the current agent.
-func handleContextCommand(ctx context.Context, cc *ChatConfig, out io.Writer) {
+// handleContextCommand displays the token budget for the current agent and
+// the tokens and cost spent in this session.
+func handleContextCommand(ctx context.Context, cc *ChatConfig, sessionID string, out io.Writer) {
 	if !requireStore(cc, out) {
 		return
 	}
@@ -1458,5 +1716,23 @@ func handleContextCommand(ctx context.Context, cc *ChatConfig, out io.Writer) {
 
 	fmt.Fprintln(out)
 	fmt.Fprint(out, budget.Format(agentID, modelName))
+
+	// Session spend: model calls made by this chat directly plus the tasks
+	// run in its session, as reported by the providers.
+	spend, err := cc.Store.UsageTotals(ctx, sessionID)
+	if err != nil {
+		spend = persistence.TaskUsage{}
+	}
+	if cc.Usage != nil {
+		u, _ := cc.Usage.Total()
+		spend.PromptTokens += u.InputTokens
+		spend.CompletionTokens += u.OutputTokens
+		spend.CachedTokens += u.CachedTokens
+		spend.ReasoningTokens += u.ReasoningTokens
+		spend.CostUSD += u.CostUSD
+	}
+	fmt.Fprintf(out, "Session Usage:    %7d in / %d out tokens (%d cached, %d reasoning)\n",
+		spend.PromptTokens, spend.CompletionTokens, spend.CachedTokens, spend.ReasoningTokens)
+	fmt.Fprintf(out, "Session Cost:     $%.4f\n", spend.CostUSD)
 	fmt.Fprintln(out)
 }
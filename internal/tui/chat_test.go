@@ -3,12 +3,19 @@ package tui
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/basket/go-claw/internal/config"
 	"github.com/basket/go-claw/internal/engine"
+	"github.com/basket/go-claw/internal/persistence"
+	"github.com/basket/go-claw/internal/shared"
 )
 
 // mockSwitcher implements AgentSwitcher for tests.
@@ -70,6 +77,7 @@ func TestHandleCommand_Dispatch(t *testing.T) {
 		{"allow no policy", "/allow example.com", false, "Policy not available"},
 		{"config no sub", "/config", false, "Usage: /config list"},
 		{"config list no keys", "/config list", false, "No API keys configured"},
+		{"config list global profile", "/config list", false, "Policy profile for @default: (global)"},
 		{"config set missing args", "/config set", false, "Usage: /config set"},
 		{"model unknown sub", "/model foo", false, "Usage: /model"},
 		{"plan no arg", "/plan", false, "Usage: /plan"},
@@ -86,6 +94,9 @@ func TestHandleCommand_Dispatch(t *testing.T) {
 		{"shared no store", "/shared", false, "Store not available"},
 		{"context no store", "/context", false, "Store not available"},
 		{"agents no switcher", "/agents", false, "Multi-agent not available"},
+		{"prompts no mcp", "/prompts", false, "MCP not available"},
+		{"prompt no mcp", "/prompt docs/review", false, "mcp not available"},
+		{"resources no mcp", "/resources", false, "MCP not available"},
 	}
 
 	for _, tt := range tests {
@@ -103,6 +114,21 @@ func TestHandleCommand_Dispatch(t *testing.T) {
 	}
 }
 
+func TestConfigList_ShowsAgentPolicyProfile(t *testing.T) {
+	var buf bytes.Buffer
+	cc := ChatConfig{
+		CurrentAgent: "researcher",
+		Switcher: &mockSwitcher{agents: []AgentInfo{
+			{ID: "default"},
+			{ID: "researcher", PolicyProfile: "readonly"},
+		}},
+	}
+	handleCommand(context.Background(), "/config list", &cc, "s1", &buf)
+	if !strings.Contains(buf.String(), "Policy profile for @researcher: readonly") {
+		t.Fatalf("output = %q, want researcher's profile", buf.String())
+	}
+}
+
 func TestHandleCommand_CaseInsensitive(t *testing.T) {
 	var buf bytes.Buffer
 	cc := ChatConfig{}
@@ -120,6 +146,9 @@ func TestHandlePlanCommand(t *testing.T) {
 		wantOutput string
 	}{
 		{"empty arg", "", ChatConfig{}, "Usage: /plan"},
+		{"run without name", "run", ChatConfig{}, "Usage: /plan"},
+		{"approve without name", "approve", ChatConfig{}, "Usage: /plan"},
+		{"list without gateway", "list", ChatConfig{}, "gateway not configured"},
 		{"no gateway", "deploy", ChatConfig{}, "gateway not configured"},
 		{"no auth token", "deploy", ChatConfig{BindAddr: "127.0.0.1:18789"}, "gateway not configured"},
 	}
@@ -135,6 +164,148 @@ func TestHandlePlanCommand(t *testing.T) {
 	}
 }
 
+func TestHandlePlanCommand_SendsInputs(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		if gotBody["inputs"]["topic"] == nil {
+			http.Error(w, "invalid plan inputs: missing required input topic", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"execution_id":"exec-1"}`))
+	}))
+	defer srv.Close()
+	cc := ChatConfig{BindAddr: strings.TrimPrefix(srv.URL, "http://"), AuthToken: "t"}
+
+	var buf bytes.Buffer
+	handlePlanCommand(`run research topic="go generics" limit=3`, &cc, &buf)
+	if gotPath != "/api/plans/research/execute" || !strings.Contains(buf.String(), "exec-1") {
+		t.Fatalf("path = %q, output = %q", gotPath, buf.String())
+	}
+	if in := gotBody["inputs"]; in["topic"] != "go generics" || in["limit"] != "3" {
+		t.Errorf("inputs = %v", in)
+	}
+
+	buf.Reset()
+	handlePlanCommand("research", &cc, &buf)
+	if !strings.Contains(buf.String(), "missing required input topic") {
+		t.Errorf("output = %q, want the gateway's error", buf.String())
+	}
+}
+
+func TestHandlePlanCommand_Review(t *testing.T) {
+	var calls []string
+	var edited map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/plans":
+			_, _ = w.Write([]byte(`{"plans":[{"name":"digest","step_count":2,"generated":true,"goal":"daily news","status":"draft"},{"name":"deploy","step_count":3}]}`))
+		case "GET /api/plans/digest":
+			_, _ = w.Write([]byte(`{"name":"digest","goal":"daily news","status":"draft","definition":"name: digest\nsteps: []\n"}`))
+		case "PUT /api/plans/digest":
+			_ = json.NewDecoder(r.Body).Decode(&edited)
+			_, _ = w.Write([]byte(`{}`))
+		case "POST /api/plans/digest/approve":
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"execution_id":"exec-2"}`))
+		case "POST /api/plans/digest/reject":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.Error(w, "plan \"other\" not found", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	cc := ChatConfig{BindAddr: strings.TrimPrefix(srv.URL, "http://"), AuthToken: "t"}
+	file := filepath.Join(t.TempDir(), "digest.yaml")
+	if err := os.WriteFile(file, []byte("name: digest\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		arg        string
+		wantOutput string
+	}{
+		{"list", "[draft] daily news"},
+		{"show digest", "    name: digest"},
+		{"edit digest " + file, "Plan 'digest' updated"},
+		{"approve digest topic=go", "approved and started (execution_id: exec-2)"},
+		{"reject digest", "Plan 'digest' rejected"},
+		{"show other", `Error: plan "other" not found`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		handlePlanCommand(tt.arg, &cc, &buf)
+		if !strings.Contains(buf.String(), tt.wantOutput) {
+			t.Errorf("/plan %s: output = %q, want substring %q", tt.arg, buf.String(), tt.wantOutput)
+		}
+	}
+	if edited["definition"] != "name: digest\n" {
+		t.Errorf("edit sent %v", edited)
+	}
+	if len(calls) != len(tests) {
+		t.Errorf("calls = %v", calls)
+	}
+}
+
+func TestHandlePlanCommand_ExecutionControl(t *testing.T) {
+	var calls []string
+	var rerun map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/plans/executions/exec-1":
+			_, _ = w.Write([]byte(`{"plan_name":"digest","status":"paused","total_steps":2,"completed_steps":1,
+				"steps":[{"step_id":"fetch","status":"succeeded"},{"step_id":"write","status":"failed","error":"boom"}]}`))
+		case "POST /api/plans/executions/exec-1/pause", "POST /api/plans/executions/exec-1/resume",
+			"POST /api/plans/executions/exec-1/cancel":
+			_, _ = w.Write([]byte(`{}`))
+		case "POST /api/plans/executions/exec-1/rerun":
+			_ = json.NewDecoder(r.Body).Decode(&rerun)
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.Error(w, "plan execution not running: exec-2", http.StatusConflict)
+		}
+	}))
+	defer srv.Close()
+	cc := ChatConfig{BindAddr: strings.TrimPrefix(srv.URL, "http://"), AuthToken: "t"}
+
+	tests := []struct {
+		arg        string
+		wantOutput string
+	}{
+		{"status exec-1", "Plan 'digest' [paused] 1/2 steps done"},
+		{"pause exec-1", "Execution exec-1 paused"},
+		{"resume exec-1", "Execution exec-1 resumed"},
+		{"cancel exec-1", "Execution exec-1 canceled"},
+		{"rerun exec-1 write", "re-running from step 'write'"},
+		{"pause exec-2", "Error: plan execution not running"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		handlePlanCommand(tt.arg, &cc, &buf)
+		if !strings.Contains(buf.String(), tt.wantOutput) {
+			t.Errorf("/plan %s: output = %q, want substring %q", tt.arg, buf.String(), tt.wantOutput)
+		}
+	}
+	if rerun["step_id"] != "write" {
+		t.Errorf("rerun sent %v", rerun)
+	}
+	if len(calls) != len(tests) {
+		t.Errorf("calls = %v", calls)
+	}
+
+	var buf bytes.Buffer
+	handlePlanCommand("rerun exec-1", &cc, &buf)
+	if !strings.Contains(buf.String(), "Usage: /plan rerun") {
+		t.Errorf("rerun without step: output = %q", buf.String())
+	}
+}
+
 // Note: handleSkillsCommand tests are omitted because ResolveStatus
 // requires a non-nil *LivePolicy. Testing skills commands requires
 // full policy setup which is tested in the tools package.
@@ -508,12 +679,40 @@ func TestHandleSharedCommand_NoStore(t *testing.T) {
 func TestHandleContextCommand_NoStore(t *testing.T) {
 	var buf bytes.Buffer
 	cc := ChatConfig{}
-	handleContextCommand(context.Background(), &cc, &buf)
+	handleContextCommand(context.Background(), &cc, "s1", &buf)
 	if !strings.Contains(buf.String(), "Store not available") {
 		t.Errorf("expected 'Store not available', got: %q", buf.String())
 	}
 }
 
+func TestHandleContextCommand_SessionUsage(t *testing.T) {
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "goclaw.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	const sessionID = "a1b2c3d4-0000-4000-8000-0000000000c1"
+	_ = store.EnsureSession(ctx, sessionID)
+	taskID, err := store.CreateTask(ctx, sessionID, `{"content":"hi"}`)
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	_ = store.UpdateTaskTokens(ctx, taskID, persistence.TaskUsage{PromptTokens: 1000, CompletionTokens: 200, CostUSD: 0.01})
+
+	meter := &shared.UsageMeter{}
+	meter.Add(shared.TokenUsage{InputTokens: 500, OutputTokens: 50, CachedTokens: 400, CostUSD: 0.0025})
+	cc := ChatConfig{Store: store, Usage: meter}
+
+	var buf bytes.Buffer
+	handleContextCommand(ctx, &cc, sessionID, &buf)
+	for _, want := range []string{"1500 in / 250 out tokens (400 cached, 0 reasoning)", "Session Cost:     $0.0125"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
 func TestMaskValue(t *testing.T) {
 	tests := []struct {
 		input string
@@ -560,3 +759,27 @@ func TestIsMissing(t *testing.T) {
 		t.Error("expected isMissing to find substring 'brave'")
 	}
 }
+
+func TestSplitPromptArgs(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"docs/review", []string{"docs/review"}, false},
+		{" docs/review  lang=go ", []string{"docs/review", "lang=go"}, false},
+		{`docs/review topic="error handling" lang=go`, []string{"docs/review", "topic=error handling", "lang=go"}, false},
+		{`docs/review empty=""`, []string{"docs/review", "empty="}, false},
+		{`docs/review topic="unterminated`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := splitPromptArgs(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitPromptArgs(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("splitPromptArgs(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
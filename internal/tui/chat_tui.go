@@ -77,6 +77,16 @@ type toolCallMsg struct {
 	event bus.StreamToolCallEvent
 }
 
+// mcpToolsChangedMsg delivers an MCP tool list change to the TUI update loop.
+type mcpToolsChangedMsg struct {
+	event bus.MCPToolsChangedEvent
+}
+
+// toolApprovalMsg delivers a tool approval request to the TUI update loop.
+type toolApprovalMsg struct {
+	event bus.ToolApprovalRequest
+}
+
 // PlanExecutionState tracks an active plan execution for display in the TUI.
 type PlanExecutionState struct {
 	ExecutionID    string
@@ -172,6 +182,12 @@ type chatModel struct {
 	// Tool call events for activity feed visibility.
 	toolSub *bus.Subscription
 
+	// MCP tool list changes for activity feed visibility.
+	mcpSub *bus.Subscription
+
+	// Tool calls waiting for approval (nil when approvals are unavailable).
+	approvalSub *bus.Subscription
+
 	// Activity feed for task/delegation/plan events.
 	activityFeed *ActivityFeed
 }
@@ -193,6 +209,10 @@ func newChatModel(ctx context.Context, cc ChatConfig, sessionID, agentPrefix, mo
 		m.planSub = cc.EventBus.Subscribe("plan.")
 		m.msgSub = cc.EventBus.Subscribe(bus.TopicAgentMessage)
 		m.toolSub = cc.EventBus.Subscribe(bus.TopicStreamToolCall)
+		m.mcpSub = cc.EventBus.Subscribe(bus.TopicMCPToolsChanged)
+		if cc.Approvals != nil {
+			m.approvalSub = cc.EventBus.Subscribe(bus.TopicToolApprovalRequested)
+		}
 	}
 	// Small intro line inside the UI (kept minimal; avoids printing to stdout).
 	m.history = append(m.history, chatEntry{
@@ -214,8 +234,9 @@ func runChatTUI(ctx context.Context, m chatModel, cancel context.CancelFunc) err
 	_, err := p.Run()
 
 	// Unsubscribe bus subscriptions so blocked Cmd goroutines (waitForPlanEvent,
-	// waitForAgentMsg, waitForToolCall) unblock and exit. Without this, the
-	// goroutines and bus references leak after every TUI exit.
+	// waitForAgentMsg, waitForToolCall, waitForMCPToolsChanged) unblock and
+	// exit. Without this, the goroutines and bus references leak after every
+	// TUI exit.
 	if m.cc.EventBus != nil {
 		if m.planSub != nil {
 			m.cc.EventBus.Unsubscribe(m.planSub)
@@ -226,6 +247,12 @@ func runChatTUI(ctx context.Context, m chatModel, cancel context.CancelFunc) err
 		if m.toolSub != nil {
 			m.cc.EventBus.Unsubscribe(m.toolSub)
 		}
+		if m.mcpSub != nil {
+			m.cc.EventBus.Unsubscribe(m.mcpSub)
+		}
+		if m.approvalSub != nil {
+			m.cc.EventBus.Unsubscribe(m.approvalSub)
+		}
 	}
 
 	if cancel != nil {
@@ -249,6 +276,12 @@ func (m chatModel) Init() tea.Cmd {
 	if m.toolSub != nil {
 		cmds = append(cmds, waitForToolCall(m.toolSub))
 	}
+	if m.mcpSub != nil {
+		cmds = append(cmds, waitForMCPToolsChanged(m.mcpSub))
+	}
+	if m.approvalSub != nil {
+		cmds = append(cmds, waitForToolApproval(m.approvalSub))
+	}
 	return tea.Batch(cmds...)
 }
 
@@ -314,6 +347,29 @@ func (m chatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, cmd
 
+	case mcpToolsChangedMsg:
+		now := time.Now()
+		m.activityFeed.Add(ActivityItem{
+			ID:        fmt.Sprintf("mcp-%s-%d", msg.event.Server, now.UnixNano()),
+			Icon:      "~~",
+			Message:   fmt.Sprintf("MCP server %s now exposes %d tools", msg.event.Server, msg.event.ToolCount),
+			StartedAt: now,
+			DoneAt:    &now,
+		})
+		var cmd tea.Cmd
+		if m.mcpSub != nil {
+			cmd = waitForMCPToolsChanged(m.mcpSub)
+		}
+		return m, cmd
+
+	case toolApprovalMsg:
+		m.addSystemEntry(formatApprovalRequest(msg.event))
+		var cmd tea.Cmd
+		if m.approvalSub != nil {
+			cmd = waitForToolApproval(m.approvalSub)
+		}
+		return m, cmd
+
 	case statusTickMsg:
 		// GC-SPEC-TUI-002: Refresh operational metrics for the status bar.
 		if m.cc.Store != nil {
@@ -443,7 +499,8 @@ func (m chatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 
 		case "enter", "ctrl+m", "ctrl+j":
-			if m.thinking {
+			// Approval commands stay usable mid-turn: the turn may be waiting on one.
+			if m.thinking && !isApprovalCommand(strings.TrimSpace(string(m.input))) {
 				return m, nil
 			}
 			line := strings.TrimSpace(string(m.input))
@@ -459,8 +516,16 @@ func (m chatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.inputHistory = append(m.inputHistory, line)
 			m.histIdx = len(m.inputHistory)
 
-			// Slash commands.
-			if strings.HasPrefix(line, "/") {
+			// /prompt renders an MCP prompt template and sends it as the user message.
+			if line == "/prompt" || strings.HasPrefix(line, "/prompt ") {
+				text, err := renderPromptCommand(m.ctx, strings.TrimPrefix(line, "/prompt"), &m.cc)
+				if err != nil {
+					m.history = append(m.history, chatEntry{role: chatRoleSystem, text: fmt.Sprintf("Error: %v", err)})
+					return m, nil
+				}
+				line = text
+			} else if strings.HasPrefix(line, "/") {
+				// Slash commands.
 				trimmed := strings.TrimSpace(line)
 
 				// /plans toggles the plan execution view.
@@ -502,7 +567,7 @@ func (m chatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				shouldExit := handleCommand(m.ctx, line, &m.cc, m.sessionID, &buf)
 				out := strings.TrimSpace(buf.String())
 				if out != "" {
-					m.history = append(m.history, chatEntry{role: chatRoleSystem, text: out})
+					m.addSystemEntry(out)
 				}
 				if shouldExit {
 					return m, tea.Quit
@@ -765,6 +830,10 @@ func startStream(ctx context.Context, cc ChatConfig, sessionID, prompt string) <
 		agentCtx := shared.WithAgentID(ctx, cc.CurrentAgent)
 		agentCtx = shared.WithTraceID(agentCtx, traceID)
 		agentCtx = shared.WithRunID(agentCtx, runID)
+		agentCtx = shared.WithSessionID(agentCtx, sessionID)
+		if cc.Usage != nil {
+			agentCtx = shared.WithUsageMeter(agentCtx, cc.Usage)
+		}
 		slog.Debug("tui: stream request", "agent_id", cc.CurrentAgent, "session_id", sessionID, "trace_id", traceID, "run_id", runID)
 
 		var buf strings.Builder
@@ -798,6 +867,10 @@ func respondCmd(ctx context.Context, cc ChatConfig, sessionID, prompt string) te
 		agentCtx := shared.WithAgentID(ctx, cc.CurrentAgent)
 		agentCtx = shared.WithTraceID(agentCtx, traceID)
 		agentCtx = shared.WithRunID(agentCtx, runID)
+		agentCtx = shared.WithSessionID(agentCtx, sessionID)
+		if cc.Usage != nil {
+			agentCtx = shared.WithUsageMeter(agentCtx, cc.Usage)
+		}
 		slog.Debug("tui: chat request", "agent_id", cc.CurrentAgent, "session_id", sessionID, "trace_id", traceID, "run_id", runID)
 		reply, err := cc.Brain.Respond(agentCtx, sessionID, prompt)
 		if err != nil {
@@ -1106,6 +1179,52 @@ func waitForToolCall(sub *bus.Subscription) tea.Cmd {
 	}
 }
 
+// waitForMCPToolsChanged blocks until an MCP tool list change arrives on the subscription channel.
+func waitForMCPToolsChanged(sub *bus.Subscription) tea.Cmd {
+	return func() tea.Msg {
+		for {
+			event, ok := <-sub.Ch()
+			if !ok {
+				return nil // channel closed
+			}
+			tc, ok := event.Payload.(bus.MCPToolsChangedEvent)
+			if !ok {
+				continue // skip non-matching payloads
+			}
+			return mcpToolsChangedMsg{event: tc}
+		}
+	}
+}
+
+// addSystemEntry appends a system line. While a reply is streaming the line
+// goes before the assistant placeholder, which must stay last for chunks.
+func (m *chatModel) addSystemEntry(text string) {
+	entry := chatEntry{role: chatRoleSystem, text: text}
+	if !m.streaming || len(m.history) == 0 {
+		m.history = append(m.history, entry)
+		return
+	}
+	last := m.history[len(m.history)-1]
+	m.history = append(m.history[:len(m.history)-1], entry, last)
+}
+
+// waitForToolApproval blocks until a tool approval request arrives on the subscription channel.
+func waitForToolApproval(sub *bus.Subscription) tea.Cmd {
+	return func() tea.Msg {
+		for {
+			event, ok := <-sub.Ch()
+			if !ok {
+				return nil // channel closed
+			}
+			req, ok := event.Payload.(bus.ToolApprovalRequest)
+			if !ok {
+				continue // skip non-matching payloads
+			}
+			return toolApprovalMsg{event: req}
+		}
+	}
+}
+
 // handlePlanEvent processes plan bus events and updates the planTracker.
 func (pt *planTracker) handleEvent(event bus.Event) {
 	pt.mu.Lock()
@@ -1142,16 +1261,27 @@ func (pt *planTracker) handleEvent(event bus.Event) {
 			pe.Status = status
 			pe.CompletedSteps = pe.TotalSteps
 		}
+
+	case bus.TopicPlanExecutionPaused, bus.TopicPlanExecutionResumed, bus.TopicPlanExecutionRerun:
+		execID, _ := payload["execution_id"].(string)
+		pe, exists := pt.executions[execID]
+		if !exists {
+			return
+		}
+		pe.Status = "running"
+		if event.Topic == bus.TopicPlanExecutionPaused {
+			pe.Status = "paused"
+		}
 	}
 }
 
-// cleanup removes completed plans older than 2 seconds.
+// cleanup removes finished plans older than 2 seconds.
 func (pt *planTracker) cleanup() {
 	pt.mu.Lock()
 	defer pt.mu.Unlock()
 	now := time.Now()
 	for id, pe := range pt.executions {
-		if pe.Status != "running" && now.Sub(pe.StartedAt) > 2*time.Second {
+		if pe.Status != "running" && pe.Status != "paused" && now.Sub(pe.StartedAt) > 2*time.Second {
 			delete(pt.executions, id)
 		}
 	}
@@ -1194,6 +1324,10 @@ func (m chatModel) renderPlanView() string {
 			statusLabel = "OK"
 		case "failed":
 			statusLabel = "FAILED"
+		case "paused":
+			statusLabel = "PAUSED"
+		case "canceled":
+			statusLabel = "CANCELED"
 		}
 
 		b.WriteString(fmt.Sprintf("  %s\n", pe.PlanName))
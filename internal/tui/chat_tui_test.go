@@ -101,6 +101,30 @@ func TestPlanTracker_HandleEvent(t *testing.T) {
 	}
 }
 
+func TestPlanTracker_PauseAndResume(t *testing.T) {
+	pt := &planTracker{executions: map[string]*PlanExecutionState{
+		"exec-1": {ExecutionID: "exec-1", Status: "running", StartedAt: time.Now().Add(-time.Minute)},
+	}}
+	for _, tt := range []struct {
+		topic string
+		want  string
+	}{
+		{bus.TopicPlanExecutionPaused, "paused"},
+		{bus.TopicPlanExecutionResumed, "running"},
+		{bus.TopicPlanExecutionPaused, "paused"},
+	} {
+		pt.handleEvent(bus.Event{Topic: tt.topic, Payload: map[string]interface{}{"execution_id": "exec-1"}})
+		if got := pt.executions["exec-1"].Status; got != tt.want {
+			t.Fatalf("after %s status = %q, want %q", tt.topic, got, tt.want)
+		}
+	}
+	// Paused executions are still active and stay in the view.
+	pt.cleanup()
+	if _, ok := pt.executions["exec-1"]; !ok {
+		t.Fatal("cleanup removed a paused execution")
+	}
+}
+
 func TestPlanTracker_Cleanup(t *testing.T) {
 	pt := &planTracker{executions: make(map[string]*PlanExecutionState)}
 
@@ -392,3 +416,17 @@ func TestDeleteWordLeft_MidWord(t *testing.T) {
 		t.Errorf("got out=%q cur=%d, want out='hello rld' cur=6", string(out), cur)
 	}
 }
+
+func TestMCPToolsChanged_AddsActivityItem(t *testing.T) {
+	m := newChatModel(context.Background(), ChatConfig{}, "sess", "test", "model")
+
+	updated, _ := m.Update(mcpToolsChangedMsg{event: bus.MCPToolsChangedEvent{Server: "github", ToolCount: 7}})
+	um := updated.(chatModel)
+
+	if um.activityFeed.Len() != 1 {
+		t.Fatalf("expected 1 activity item, got %d", um.activityFeed.Len())
+	}
+	if view := um.activityFeed.View(); !strings.Contains(view, "github now exposes 7 tools") {
+		t.Errorf("expected tool change in activity feed, got: %q", view)
+	}
+}
@@ -0,0 +1,185 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/basket/go-claw/internal/mcp"
+	"github.com/basket/go-claw/internal/memory"
+)
+
+// requireMCP checks that cc.MCP is non-nil and prints an error if not.
+func requireMCP(cc *ChatConfig, out io.Writer) bool {
+	if cc.MCP == nil {
+		fmt.Fprintln(out, "  MCP not available.")
+		fmt.Fprintln(out)
+		return false
+	}
+	return true
+}
+
+// handlePromptsCommand processes /prompts.
+func handlePromptsCommand(ctx context.Context, cc *ChatConfig, out io.Writer) {
+	if !requireMCP(cc, out) {
+		return
+	}
+	agentID := effectiveAgentID(cc)
+	prompts, err := cc.MCP.ListPrompts(ctx, agentID)
+	if err != nil {
+		fmt.Fprintf(out, "  Error listing prompts: %v\n\n", err)
+		return
+	}
+	if len(prompts) == 0 {
+		fmt.Fprintf(out, "  No MCP prompts available for @%s.\n\n", agentID)
+		return
+	}
+	fmt.Fprintf(out, "  MCP prompts for @%s (%d):\n", agentID, len(prompts))
+	for _, p := range prompts {
+		fmt.Fprintf(out, "    • %s/%s", p.ServerName, p.Name)
+		if len(p.Arguments) > 0 {
+			args := make([]string, 0, len(p.Arguments))
+			for _, a := range p.Arguments {
+				if a.Required {
+					args = append(args, a.Name+"=")
+				} else {
+					args = append(args, "["+a.Name+"=]")
+				}
+			}
+			fmt.Fprintf(out, " %s", strings.Join(args, " "))
+		}
+		if p.Description != "" {
+			fmt.Fprintf(out, " - %s", p.Description)
+		}
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintln(out)
+}
+
+// handlePromptCommand processes /prompt outside the interactive chat by
+// printing the rendered prompt instead of sending it.
+func handlePromptCommand(ctx context.Context, arg string, cc *ChatConfig, out io.Writer) {
+	text, err := renderPromptCommand(ctx, arg, cc)
+	if err != nil {
+		fmt.Fprintf(out, "  Error: %v\n\n", err)
+		return
+	}
+	fmt.Fprintf(out, "  %s\n\n", text)
+}
+
+var errPromptUsage = errors.New("usage: /prompt <server>/<name> [key=value ...]")
+
+// renderPromptCommand resolves "/prompt <server>/<name> [key=value ...]" to
+// the prompt text. Values may be double-quoted to include spaces.
+func renderPromptCommand(ctx context.Context, arg string, cc *ChatConfig) (string, error) {
+	if cc.MCP == nil {
+		return "", fmt.Errorf("mcp not available")
+	}
+	fields, err := splitPromptArgs(arg)
+	if err != nil {
+		return "", err
+	}
+	if len(fields) == 0 {
+		return "", errPromptUsage
+	}
+	server, name, ok := strings.Cut(fields[0], "/")
+	if !ok || server == "" || name == "" {
+		return "", errPromptUsage
+	}
+	args := make(map[string]string, len(fields)-1)
+	for _, f := range fields[1:] {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok || k == "" {
+			return "", fmt.Errorf("invalid argument %q (want key=value)", f)
+		}
+		args[k] = v
+	}
+	res, err := cc.MCP.GetPrompt(ctx, effectiveAgentID(cc), server, name, args)
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(res.Text())
+	if text == "" {
+		return "", fmt.Errorf("prompt %s/%s rendered no text", server, name)
+	}
+	return text, nil
+}
+
+// splitPromptArgs splits on whitespace, keeping double-quoted runs together.
+func splitPromptArgs(s string) ([]string, error) {
+	var fields []string
+	var cur strings.Builder
+	inQuote, hasField := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			hasField = true
+		case (r == ' ' || r == '\t') && !inQuote:
+			if hasField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				hasField = false
+			}
+		default:
+			cur.WriteRune(r)
+			hasField = true
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if hasField {
+		fields = append(fields, cur.String())
+	}
+	return fields, nil
+}
+
+// handleResourcesCommand processes /resources.
+func handleResourcesCommand(ctx context.Context, cc *ChatConfig, out io.Writer) {
+	if !requireMCP(cc, out) {
+		return
+	}
+	agentID := effectiveAgentID(cc)
+	resources, err := cc.MCP.ListResources(ctx, agentID)
+	if err != nil {
+		fmt.Fprintf(out, "  Error listing resources: %v\n\n", err)
+		return
+	}
+	if len(resources) == 0 {
+		fmt.Fprintf(out, "  No MCP resources available for @%s.\n\n", agentID)
+		return
+	}
+	fmt.Fprintf(out, "  MCP resources for @%s (%d):\n", agentID, len(resources))
+	for _, r := range resources {
+		fmt.Fprintf(out, "    • %s %s", r.ServerName, r.URI)
+		if r.Name != "" && r.Name != r.URI {
+			fmt.Fprintf(out, " (%s)", r.Name)
+		}
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintln(out, "  Pin one with /pin mcp <server> <uri>")
+	fmt.Fprintln(out)
+}
+
+// handlePinMCPResource processes /pin mcp <server> <uri>.
+func handlePinMCPResource(ctx context.Context, arg, agentID string, cc *ChatConfig, out io.Writer) {
+	if !requireMCP(cc, out) {
+		return
+	}
+	parts := strings.Fields(arg)
+	if len(parts) != 2 {
+		fmt.Fprintln(out, "  Usage: /pin mcp <server> <uri>")
+		fmt.Fprintln(out)
+		return
+	}
+	server, uri := parts[0], parts[1]
+	pinMgr := memory.NewPinManager(cc.Store)
+	if err := cc.MCP.PinResource(ctx, agentID, server, uri, false, pinMgr); err != nil {
+		fmt.Fprintf(out, "  Error pinning resource: %v\n\n", err)
+		return
+	}
+	fmt.Fprintf(out, "  Pinned resource: %s\n\n", mcp.ResourcePinSource(server, uri))
+}
@@ -0,0 +1,60 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+// traceSnippet bounds the arguments and output shown per call; the full
+// (redacted) values are available from /api/tasks/{id}.
+const traceSnippet = 120
+
+// handleTraceCommand processes /trace [task_id]: the tool calls of a task,
+// or of the most recent response in this session that used tools.
+func handleTraceCommand(ctx context.Context, arg string, cc *ChatConfig, sessionID string, out io.Writer) {
+	if !requireStore(cc, out) {
+		return
+	}
+	var (
+		calls []persistence.ToolCall
+		err   error
+	)
+	if taskID := strings.TrimSpace(arg); taskID != "" {
+		calls, err = cc.Store.ListToolCalls(ctx, taskID)
+	} else {
+		calls, err = cc.Store.ListLastRunToolCalls(ctx, sessionID)
+	}
+	if err != nil {
+		fmt.Fprintf(out, "  Error loading tool calls: %v\n\n", err)
+		return
+	}
+	if len(calls) == 0 {
+		fmt.Fprintln(out, "  No tool calls recorded.")
+		fmt.Fprintln(out)
+		return
+	}
+	fmt.Fprintf(out, "  Tool calls (%d):\n", len(calls))
+	for i, c := range calls {
+		dur := (time.Duration(c.DurationMS) * time.Millisecond).String()
+		fmt.Fprintf(out, "    %d. %s (%s) %s\n", i+1, c.Tool, dur, snippet(c.Args))
+		if c.Error != "" {
+			fmt.Fprintf(out, "       ✗ %s\n", snippet(c.Error))
+		} else {
+			fmt.Fprintf(out, "       → %s\n", snippet(c.Output))
+		}
+	}
+	fmt.Fprintln(out)
+}
+
+func snippet(s string) string {
+	r := []rune(strings.Join(strings.Fields(s), " "))
+	if len(r) > traceSnippet {
+		return string(r[:traceSnippet]) + "…"
+	}
+	return string(r)
+}
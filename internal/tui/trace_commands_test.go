@@ -0,0 +1,55 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basket/go-claw/internal/persistence"
+)
+
+func TestTraceCommand(t *testing.T) {
+	store, err := persistence.Open(filepath.Join(t.TempDir(), "goclaw.db"), nil)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	for _, tc := range []persistence.ToolCall{
+		{RunID: "r1", SessionID: "s1", Tool: "exec", Args: `{"command":"ls"}`, Output: "old"},
+		{RunID: "r2", SessionID: "s1", Tool: "read_file", Args: `{"path":"go.mod"}`, Output: "module x", DurationMS: 4},
+		{RunID: "r2", SessionID: "s1", Tool: "exec", Args: `{"command":"false"}`, Error: "exit status 1"},
+		{TaskID: "t9", RunID: "r3", SessionID: "s2", Tool: "web_search", Args: strings.Repeat("q", 500)},
+	} {
+		if err := store.RecordToolCall(ctx, &tc); err != nil {
+			t.Fatalf("RecordToolCall: %v", err)
+		}
+	}
+	cc := &ChatConfig{Store: store}
+
+	var out bytes.Buffer
+	handleTraceCommand(ctx, "", cc, "s1", &out)
+	got := out.String()
+	for _, want := range []string{"Tool calls (2):", "1. read_file (4ms)", "→ module x", "2. exec", "✗ exit status 1"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("last-run trace missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "old") {
+		t.Errorf("last-run trace includes an earlier run:\n%s", got)
+	}
+
+	out.Reset()
+	handleTraceCommand(ctx, "t9", cc, "s1", &out)
+	if got := out.String(); !strings.Contains(got, "web_search") || !strings.Contains(got, "…") {
+		t.Errorf("task trace = %q, want a truncated web_search call", got)
+	}
+
+	out.Reset()
+	handleTraceCommand(ctx, "", cc, "empty", &out)
+	if !strings.Contains(out.String(), "No tool calls recorded.") {
+		t.Errorf("empty trace = %q", out.String())
+	}
+}